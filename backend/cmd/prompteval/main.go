@@ -0,0 +1,338 @@
+// Command prompteval runs rule draft prompt versions against the rule-email
+// fixtures through a real LLM provider and reports how often each version
+// produces valid regexes that extract the expected fields.
+//
+// A -prompt source is a prompt YAML file, "embedded" for the bundled prompt,
+// or db:<workflow>/<purpose>@<version> for a version stored through the admin
+// prompt API. Stored versions are read from the database described by the
+// server's configuration.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ArionMiles/expensor/backend/internal/app"
+	"github.com/ArionMiles/expensor/backend/internal/assistant"
+	"github.com/ArionMiles/expensor/backend/internal/catalog"
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/internal/rules"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/internal/store/postgres"
+	"github.com/ArionMiles/expensor/backend/pkg/config"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	ruleDraftWorkflow  = "rule_drafting"
+	ruleDraftPurpose   = "draft_rule"
+	embeddedPrompt     = "embedded"
+	storedPromptScheme = "db:"
+)
+
+// promptVersionLister reads stored prompt versions.
+type promptVersionLister interface {
+	ListLLMPromptVersions(ctx context.Context, workflow, purpose string) ([]store.LLMPromptVersion, error)
+}
+
+type promptFlags []string
+
+func (p *promptFlags) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *promptFlags) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+type options struct {
+	fixtures  string
+	provider  string
+	config    string
+	apiKeyEnv string
+	prompts   promptFlags
+	jsonOut   bool
+	verbose   bool
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseOptions(args, stderr)
+	if err != nil {
+		return 2
+	}
+	evaluations, err := evaluate(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "prompteval: %v\n", err)
+		return 1
+	}
+	if opts.jsonOut {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(evaluations); err != nil {
+			fmt.Fprintf(stderr, "prompteval: encoding report: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	writeReport(stdout, evaluations, opts.verbose)
+	return 0
+}
+
+func parseOptions(args []string, stderr io.Writer) (options, error) {
+	var opts options
+	fs := flag.NewFlagSet("prompteval", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.fixtures, "fixtures", "../tests/data/rule-emails", "directory of .rule.fixture files")
+	fs.StringVar(&opts.provider, "provider", "", "LLM provider name (openai, anthropic, ollama, openai_compatible)")
+	fs.StringVar(&opts.config, "config", "{}", "provider config JSON, for example {\"model\":\"gpt-4.1-mini\"}")
+	fs.StringVar(&opts.apiKeyEnv, "api-key-env", "EXPENSOR_LLM_API_KEY", "environment variable holding the provider API key")
+	fs.Var(&opts.prompts, "prompt", "prompt YAML file, \"embedded\", or db:<workflow>/<purpose>@<version>; repeat to compare versions")
+	fs.BoolVar(&opts.jsonOut, "json", false, "print the report as JSON")
+	fs.BoolVar(&opts.verbose, "v", false, "print per-fixture results")
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	if opts.provider == "" {
+		fmt.Fprintln(stderr, "prompteval: -provider is required")
+		fs.Usage()
+		return options{}, errors.E(errors.InvalidArgument, "provider is required")
+	}
+	if len(opts.prompts) == 0 {
+		opts.prompts = promptFlags{embeddedPrompt}
+	}
+	return opts, nil
+}
+
+func evaluate(ctx context.Context, opts options) ([]assistant.RuleDraftEvaluation, error) {
+	const op = "prompteval.evaluate"
+
+	content, err := catalog.Load()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	registry, err := app.NewLLMRegistry(content)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	runtime, err := providerRuntime(opts)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	fixtures, err := rules.LoadEmailFixtures(opts.fixtures)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if len(fixtures) == 0 {
+		return nil, errors.E(op, errors.InvalidArgument, "no rule-email fixtures found in "+opts.fixtures)
+	}
+	cases := evalCases(fixtures)
+
+	var versions promptVersionLister
+	if opts.storedPrompts() {
+		backend, err := openPromptStore(ctx)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		defer backend.Close()
+		versions = backend
+	}
+
+	evaluations := make([]assistant.RuleDraftEvaluation, 0, len(opts.prompts))
+	for _, source := range opts.prompts {
+		prompt, err := loadPrompt(ctx, content.PromptCatalog, versions, source)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		prompts, err := llm.NewPromptCatalog(prompt)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		router := llm.NewRouter(llm.RouterConfig{
+			Registry: registry,
+			Runtime:  staticRuntime{runtime: runtime},
+			Prompts:  prompts,
+			Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		label := "v" + strconv.Itoa(prompt.Version) + " (" + source + ")"
+		evaluations = append(evaluations, assistant.EvaluateRuleDrafts(
+			ctx, assistant.NewRuleDraftService(router), store.Tenant{ID: "prompteval"}, label, cases,
+		))
+	}
+	return evaluations, nil
+}
+
+// storedPrompts reports whether any -prompt source names a stored version.
+func (o options) storedPrompts() bool {
+	for _, source := range o.prompts {
+		if strings.HasPrefix(source, storedPromptScheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// openPromptStore connects to the server's configured Postgres database.
+func openPromptStore(ctx context.Context) (*postgres.Store, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, errors.E("loading configuration for stored prompts", err)
+	}
+	if cfg.Database.Backend != config.DatabaseBackendPostgres {
+		return nil, errors.E(errors.FailedPrecondition, "stored prompts need EXPENSOR_DB_BACKEND=postgres")
+	}
+	return postgres.New(ctx, postgres.Options{
+		Config:   cfg.Database.Postgres,
+		Security: cfg.Security,
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+}
+
+func providerRuntime(opts options) (store.LLMProviderRuntime, error) {
+	if !json.Valid([]byte(opts.config)) {
+		return store.LLMProviderRuntime{}, errors.E(errors.InvalidArgument, "-config must be valid JSON")
+	}
+	runtime := store.LLMProviderRuntime{
+		Provider: opts.provider,
+		Config:   json.RawMessage(opts.config),
+		Active:   true,
+	}
+	if apiKey := strings.TrimSpace(os.Getenv(opts.apiKeyEnv)); apiKey != "" {
+		credentials, err := json.Marshal(map[string]string{"api_key": apiKey})
+		if err != nil {
+			return store.LLMProviderRuntime{}, errors.E("encoding provider credentials", err)
+		}
+		runtime.Credentials = credentials
+		runtime.HasCredentials = true
+	}
+	return runtime, nil
+}
+
+func loadPrompt(
+	ctx context.Context,
+	embedded *llm.PromptCatalog,
+	versions promptVersionLister,
+	source string,
+) (llm.PromptDefinition, error) {
+	if source == embeddedPrompt {
+		prompt, ok := embedded.Get(ruleDraftWorkflow, ruleDraftPurpose)
+		if !ok {
+			return llm.PromptDefinition{}, errors.E(errors.NotFound, "embedded rule draft prompt not found")
+		}
+		return prompt, nil
+	}
+	var body []byte
+	if strings.HasPrefix(source, storedPromptScheme) {
+		definition, err := loadStoredPrompt(ctx, versions, source)
+		if err != nil {
+			return llm.PromptDefinition{}, err
+		}
+		body = definition
+	} else {
+		file, err := os.ReadFile(source)
+		if err != nil {
+			return llm.PromptDefinition{}, errors.E("reading prompt "+source, err)
+		}
+		body = file
+	}
+	prompt, err := llm.ParsePromptDefinition(body, source)
+	if err != nil {
+		return llm.PromptDefinition{}, err
+	}
+	if prompt.Workflow != ruleDraftWorkflow || prompt.Purpose != ruleDraftPurpose {
+		return llm.PromptDefinition{}, errors.E(
+			errors.InvalidArgument,
+			"prompt "+source+" must use workflow "+ruleDraftWorkflow+" and purpose "+ruleDraftPurpose,
+		)
+	}
+	return prompt, nil
+}
+
+// loadStoredPrompt returns the definition of the stored version that source,
+// db:<workflow>/<purpose>@<version>, names.
+func loadStoredPrompt(ctx context.Context, versions promptVersionLister, source string) ([]byte, error) {
+	name, rawVersion, ok := strings.Cut(strings.TrimPrefix(source, storedPromptScheme), "@")
+	workflow, purpose, hasPurpose := strings.Cut(name, "/")
+	version, err := strconv.Atoi(rawVersion)
+	if !ok || !hasPurpose || workflow == "" || purpose == "" || err != nil || version < 1 {
+		return nil, errors.E(errors.InvalidArgument, "prompt "+source+" must look like db:<workflow>/<purpose>@<version>")
+	}
+	if versions == nil {
+		return nil, errors.E(errors.FailedPrecondition, "no prompt store to read "+source+" from")
+	}
+	stored, err := versions.ListLLMPromptVersions(ctx, workflow, purpose)
+	if err != nil {
+		return nil, errors.E("listing stored prompt versions", err)
+	}
+	for _, v := range stored {
+		if v.Version == version {
+			return v.Definition, nil
+		}
+	}
+	return nil, errors.E(errors.NotFound, "prompt "+source+" is not stored")
+}
+
+func evalCases(fixtures []rules.EmailFixture) []assistant.RuleDraftEvalCase {
+	cases := make([]assistant.RuleDraftEvalCase, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		cases = append(cases, assistant.RuleDraftEvalCase{
+			Name:     fixture.TestName,
+			Sender:   fixture.Sender,
+			Subject:  fixture.Subject,
+			Body:     fixture.Body,
+			Amount:   fixture.Expected.Amount,
+			Merchant: fixture.Expected.Merchant,
+			Currency: fixture.Expected.Currency,
+		})
+	}
+	return cases
+}
+
+func writeReport(w io.Writer, evaluations []assistant.RuleDraftEvaluation, verbose bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROMPT\tCASES\tERRORS\tVALID REGEX\tAMOUNT\tMERCHANT\tCURRENCY\tALL FIELDS")
+	for _, e := range evaluations {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			e.Label, e.Cases, e.Errors,
+			percent(e, e.ValidRegex), percent(e, e.AmountMatches), percent(e, e.MerchantMatches),
+			percent(e, e.CurrencyMatches), percent(e, e.FullMatches),
+		)
+	}
+	_ = tw.Flush()
+	if !verbose {
+		return
+	}
+	for _, e := range evaluations {
+		fmt.Fprintf(w, "\n%s\n", e.Label)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FIXTURE\tVALID REGEX\tAMOUNT\tMERCHANT\tCURRENCY\tERROR")
+		for _, r := range e.Results {
+			fmt.Fprintf(tw, "%s\t%t\t%t\t%t\t%t\t%s\n", r.Name, r.ValidRegex, r.AmountMatch, r.MerchantMatch, r.CurrencyMatch, r.Error)
+		}
+		_ = tw.Flush()
+	}
+}
+
+func percent(e assistant.RuleDraftEvaluation, count int) string {
+	return strconv.FormatFloat(e.Rate(count)*100, 'f', 1, 64) + "%"
+}
+
+type staticRuntime struct {
+	runtime store.LLMProviderRuntime
+}
+
+func (s staticRuntime) GetActiveLLMProviderRuntime(context.Context, store.Tenant) (store.LLMProviderRuntime, bool, error) {
+	return s.runtime, true, nil
+}
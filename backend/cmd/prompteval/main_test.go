@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/catalog"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type fakePromptVersions map[string][]store.LLMPromptVersion
+
+func (f fakePromptVersions) ListLLMPromptVersions(_ context.Context, workflow, purpose string) ([]store.LLMPromptVersion, error) {
+	return f[workflow+"/"+purpose], nil
+}
+
+func TestLoadPromptReadsStoredVersion(t *testing.T) {
+	ctx := context.Background()
+	content, err := catalog.Load()
+	if err != nil {
+		t.Fatalf("catalog.Load: %v", err)
+	}
+	prompt, ok := content.PromptCatalog.Get(ruleDraftWorkflow, ruleDraftPurpose)
+	if !ok {
+		t.Fatal("embedded rule draft prompt not found")
+	}
+	prompt.Version = 7
+	definition, err := json.Marshal(prompt)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	versions := fakePromptVersions{
+		ruleDraftWorkflow + "/" + ruleDraftPurpose: {{Workflow: ruleDraftWorkflow, Purpose: ruleDraftPurpose, Version: 7, Definition: definition}},
+	}
+
+	got, err := loadPrompt(ctx, content.PromptCatalog, versions, "db:rule_drafting/draft_rule@7")
+	if err != nil || got.Version != 7 || got.Workflow != ruleDraftWorkflow {
+		t.Fatalf("loadPrompt stored = %#v, err = %v", got, err)
+	}
+
+	tests := []struct {
+		source string
+		kind   errors.Kind
+	}{
+		{source: "db:rule_drafting/draft_rule@8", kind: errors.NotFound},
+		{source: "db:rule_drafting/draft_rule", kind: errors.InvalidArgument},
+		{source: "db:rule_drafting@7", kind: errors.InvalidArgument},
+		{source: "db:rule_drafting/draft_rule@latest", kind: errors.InvalidArgument},
+	}
+	for _, tt := range tests {
+		if _, err := loadPrompt(ctx, content.PromptCatalog, versions, tt.source); errors.WhatKind(err) != tt.kind {
+			t.Errorf("loadPrompt(%q) err = %v, want %v", tt.source, err, tt.kind)
+		}
+	}
+	if _, err := loadPrompt(ctx, content.PromptCatalog, nil, "db:rule_drafting/draft_rule@7"); errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Errorf("loadPrompt without store err = %v, want failed precondition", err)
+	}
+}
+
+func TestParseOptionsAcceptsStoredPrompts(t *testing.T) {
+	opts, err := parseOptions([]string{"-provider", "openai", "-prompt", "embedded", "-prompt", "db:rule_drafting/draft_rule@2"}, nil)
+	if err != nil {
+		t.Fatalf("parseOptions: %v", err)
+	}
+	if len(opts.prompts) != 2 || !opts.storedPrompts() {
+		t.Fatalf("parseOptions prompts = %v, want embedded and a stored version", opts.prompts)
+	}
+	if opts, _ := parseOptions([]string{"-provider", "openai"}, nil); opts.storedPrompts() {
+		t.Fatal("storedPrompts() = true for the embedded default")
+	}
+}
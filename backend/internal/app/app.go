@@ -5,12 +5,18 @@ import (
 	"log/slog"
 	"sync"
 
+	"github.com/ArionMiles/expensor/backend/internal/audit"
+	"github.com/ArionMiles/expensor/backend/internal/backup"
 	"github.com/ArionMiles/expensor/backend/internal/catalog"
 	"github.com/ArionMiles/expensor/backend/internal/community"
 	"github.com/ArionMiles/expensor/backend/internal/daemon"
 	"github.com/ArionMiles/expensor/backend/internal/daemon/scheduler"
+	"github.com/ArionMiles/expensor/backend/internal/events"
+	"github.com/ArionMiles/expensor/backend/internal/notify"
 	"github.com/ArionMiles/expensor/backend/internal/observability"
 	"github.com/ArionMiles/expensor/backend/internal/plugins"
+	"github.com/ArionMiles/expensor/backend/internal/rekey"
+	"github.com/ArionMiles/expensor/backend/internal/webhook"
 	"github.com/ArionMiles/expensor/backend/pkg/config"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
@@ -27,9 +33,14 @@ type App struct {
 	logger          *slog.Logger
 	schedulerRun    func(context.Context) error
 	communityRun    func(context.Context) error
+	backupRun       func(context.Context) error
+	auditRun        func(context.Context) error
+	webhookRun      func(context.Context) error
+	notifyRun       func(context.Context) error
 	serverRun       func(context.Context) error
 	controllerClose func(context.Context) error
 	communityClose  func(context.Context) error
+	rekeyClose      func(context.Context) error
 	storeClose      func()
 
 	runMu      sync.Mutex
@@ -63,7 +74,10 @@ func newApp(ctx context.Context, opts Options, openStore func(context.Context, S
 		"merchant_categories", len(content.Seed.MerchantCategories))
 	logger.Info("loaded llm prompt catalog", "prompts", content.PromptCatalog.Len())
 
-	storeRuntime, err := openStore(ctx, StoreOptions{Database: opts.Config.Database, Security: opts.Config.Security, Logger: logger})
+	broker := events.NewBroker(events.DefaultBufferSize, logger.With("component", "events"))
+	storeRuntime, err := openStore(ctx, StoreOptions{
+		Database: opts.Config.Database, Security: opts.Config.Security, Blob: opts.Config.Blob, Events: broker, Logger: logger,
+	})
 	if err != nil {
 		return nil, errors.E("app.new", "opening store", err)
 	}
@@ -92,7 +106,9 @@ func newApp(ctx context.Context, opts Options, openStore func(context.Context, S
 	if err != nil {
 		return nil, errors.E("app.new", err)
 	}
-	controller, err := daemon.NewController(daemon.ControllerDependencies{Context: ctx, Scanner: scanService, Store: st, Logger: logger})
+	controller, err := daemon.NewController(daemon.ControllerDependencies{
+		Context: ctx, Scanner: scanService, Store: st, Events: broker, Logger: logger,
+	})
 	if err != nil {
 		return nil, errors.E("app.new", err)
 	}
@@ -105,29 +121,91 @@ func newApp(ctx context.Context, opts Options, openStore func(context.Context, S
 		PollInterval:   opts.Config.Scheduler.PollInterval,
 		BaseRetryDelay: opts.Config.Scheduler.BaseRetryDelay,
 		MaxRetryDelay:  opts.Config.Scheduler.MaxRetryDelay,
+		Events:         broker,
 		Logger:         logger.With("component", "scheduler"),
 	})
 	if err != nil {
 		return nil, errors.E("app.new", "constructing scan scheduler", err)
 	}
 	communityService, err := community.New(ctx, community.Dependencies{
-		Config: opts.Config.Community, Store: st, Runtime: st, Resolver: controller, Logger: logger,
+		Config: opts.Config.Community, Store: st, Runtime: st, Resolver: controller, Events: broker, Logger: logger,
+	})
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	backupTarget, backupPrefix, err := backup.OpenTarget(opts.Config.Backup, opts.Config.Blob.S3)
+	if err != nil {
+		return nil, errors.E("app.new", "opening backup target", err)
+	}
+	backupService, err := backup.New(backup.Dependencies{
+		Config: opts.Config.Backup, Target: backupTarget, Prefix: backupPrefix, Store: st,
+		SecretKey: opts.Config.Security.SecretKey, PreviousSecretKeys: opts.Config.Security.PreviousSecretKeys,
+		AppVersion: config.Version, Resolver: controller, Logger: logger,
+	})
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	rekeyService, err := rekey.New(ctx, rekey.Dependencies{
+		Store: st, SecretKey: opts.Config.Security.SecretKey, PreviousSecretKeys: opts.Config.Security.PreviousSecretKeys,
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	auditPruner, err := audit.New(audit.Dependencies{Store: st, Logger: logger})
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	outboundNetworks, err := opts.Config.Security.GetOutboundAllowedNetworks()
+	if err != nil {
+		return nil, errors.E("app.new", errors.InvalidArgument, err)
+	}
+	webhookDispatcher, err := webhook.New(webhook.Dependencies{Store: st, Logger: logger, AllowedNetworks: outboundNetworks})
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	notifier, err := notify.New(notify.Dependencies{
+		Store: st, Logger: logger, SMTP: opts.Config.Notifications.SMTP, TelegramAPIURL: opts.Config.Notifications.TelegramAPIURL,
+		AllowedNetworks: outboundNetworks,
 	})
 	if err != nil {
 		return nil, errors.E("app.new", err)
 	}
+	oidcProvider, err := newOIDCProvider(opts.Config.OIDC)
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	proxyAuth, err := newProxyAuthConfig(opts.Config.ProxyAuth)
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	relyingParty, err := newWebAuthn(opts.Config)
+	if err != nil {
+		return nil, errors.E("app.new", err)
+	}
+	trustedProxies, err := opts.Config.Security.GetTrustedProxies()
+	if err != nil {
+		return nil, errors.E("app.new", errors.InvalidArgument, err)
+	}
 	server := newHTTPServer(httpDependencies{
 		config: opts.Config, content: content, registry: registry, llm: llmComponents, store: st,
-		controller: controller, community: communityService, logger: logger, logLevel: opts.LogLevel,
+		controller: controller, community: communityService, backups: backupService, rekey: rekeyService, notifier: notifier,
+		events: broker, oidc: oidcProvider, proxyAuth: proxyAuth, trustedProxies: trustedProxies, webauthn: relyingParty, logger: logger,
+		logLevel: opts.LogLevel,
 	})
 
 	application := &App{
 		logger:          logger,
 		schedulerRun:    sched.Start,
 		communityRun:    communityService.Run,
+		backupRun:       backupService.Run,
+		auditRun:        auditPruner.Run,
+		webhookRun:      webhookDispatcher.Run,
+		notifyRun:       notifier.Run,
 		serverRun:       server.Start,
 		controllerClose: controller.Close,
 		communityClose:  communityService.Close,
+		rekeyClose:      rekeyService.Close,
 		storeClose:      storeRuntime.Close,
 	}
 	constructed = true
@@ -144,12 +222,16 @@ func (a *App) Run(ctx context.Context) error {
 	runCtx, cancel := context.WithCancel(ctx)
 	a.runStarted = true
 	a.runCancel = cancel
-	a.workers.Add(2)
+	a.workers.Add(6)
 	a.runMu.Unlock()
 
 	defer cancel()
 	go a.runWorker(runCtx, "scheduler", a.schedulerRun)
 	go a.runWorker(runCtx, "community sync", a.communityRun)
+	go a.runWorker(runCtx, "backup scheduler", a.backupRun)
+	go a.runWorker(runCtx, "audit retention", a.auditRun)
+	go a.runWorker(runCtx, "webhook delivery", a.webhookRun)
+	go a.runWorker(runCtx, "notifications", a.notifyRun)
 	a.logger.Info("multi-tenant scanning scheduler started")
 	if err := a.serverRun(runCtx); err != nil && !errors.Is(err, context.Canceled) {
 		return errors.E("app.run", errors.Unavailable, "HTTP server failed", err)
@@ -169,6 +251,9 @@ func (a *App) Close(ctx context.Context) error {
 		if a.communityClose != nil {
 			a.closeErr = errors.Join(a.closeErr, a.communityClose(ctx))
 		}
+		if a.rekeyClose != nil {
+			a.closeErr = errors.Join(a.closeErr, a.rekeyClose(ctx))
+		}
 		if a.controllerClose != nil {
 			a.closeErr = errors.Join(a.closeErr, a.controllerClose(ctx))
 		}
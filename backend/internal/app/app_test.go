@@ -33,8 +33,8 @@ func TestRunCancelsWorkers(t *testing.T) {
 		return ctx.Err()
 	}
 	application := &App{
-		logger: discardLogger(), schedulerRun: waitForCancel, communityRun: waitForCancel,
-		serverRun: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
+		logger: discardLogger(), schedulerRun: waitForCancel, communityRun: waitForCancel, backupRun: waitForCancel,
+		auditRun: waitForCancel, webhookRun: waitForCancel, notifyRun: waitForCancel, serverRun: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan error, 1)
@@ -55,6 +55,10 @@ func TestRunLogsWorkerFailureWhileHTTPContinues(t *testing.T) {
 		logger:       logger,
 		schedulerRun: func(context.Context) error { return errors.New("scheduler failed") },
 		communityRun: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
+		backupRun:    func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
+		auditRun:     func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
+		webhookRun:   func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
+		notifyRun:    func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
 		serverRun:    func(ctx context.Context) error { close(serverStarted); <-ctx.Done(); return ctx.Err() },
 	}
 	ctx, cancel := context.WithCancel(context.Background())
@@ -78,8 +82,8 @@ func TestRunPropagatesHTTPFailure(t *testing.T) {
 	httpErr := errors.New("listen failed")
 	waitForCancel := func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
 	application := &App{
-		logger: discardLogger(), schedulerRun: waitForCancel, communityRun: waitForCancel,
-		serverRun: func(context.Context) error { return httpErr },
+		logger: discardLogger(), schedulerRun: waitForCancel, communityRun: waitForCancel, backupRun: waitForCancel,
+		auditRun: waitForCancel, webhookRun: waitForCancel, notifyRun: waitForCancel, serverRun: func(context.Context) error { return httpErr },
 	}
 	if err := application.Run(context.Background()); !errors.Is(err, httpErr) {
 		t.Fatalf("Run() error = %v, want wrapped HTTP error", err)
@@ -2,14 +2,23 @@ package app
 
 import (
 	"log/slog"
+	"net/netip"
+	"strings"
 
+	"github.com/ArionMiles/expensor/backend/internal/backup"
 	"github.com/ArionMiles/expensor/backend/internal/catalog"
 	"github.com/ArionMiles/expensor/backend/internal/community"
 	"github.com/ArionMiles/expensor/backend/internal/daemon"
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/httpapi"
+	"github.com/ArionMiles/expensor/backend/internal/notify"
+	"github.com/ArionMiles/expensor/backend/internal/oidc"
 	"github.com/ArionMiles/expensor/backend/internal/plugins"
+	"github.com/ArionMiles/expensor/backend/internal/rekey"
 	"github.com/ArionMiles/expensor/backend/internal/store/instrumented"
+	"github.com/ArionMiles/expensor/backend/internal/webauthn"
 	"github.com/ArionMiles/expensor/backend/pkg/config"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
 type httpDependencies struct {
@@ -20,18 +29,81 @@ type httpDependencies struct {
 	store      *instrumented.Store
 	controller *daemon.Controller
 	community  *community.Service
-	logger     *slog.Logger
-	logLevel   *slog.LevelVar
+	backups    *backup.Service
+	rekey      *rekey.Service
+	notifier   *notify.Service
+	events     *events.Broker
+	oidc       httpapi.OIDCProvider
+	proxyAuth  httpapi.ProxyAuthConfig
+	// trustedProxies are the reverse proxies whose X-Forwarded-For is believed.
+	trustedProxies []netip.Prefix
+	webauthn       *webauthn.RelyingParty
+	logger         *slog.Logger
+	logLevel       *slog.LevelVar
 }
 
 func newHTTPServer(deps httpDependencies) *httpapi.Server {
 	handlers := httpapi.NewHandlers(httpapi.HandlersConfig{
 		Registry: deps.registry, LLMRegistry: deps.llm.registry, LLMRouter: deps.llm.router,
-		RuleDrafts: deps.llm.ruleDrafts, LLMScope: deps.llm.scope, Store: deps.store,
-		Daemon: deps.controller, Community: deps.community, Version: config.Version,
-		BaseURL: deps.config.BaseURL, FrontendURL: deps.config.FrontendURL, ThunderbirdDataDir: deps.config.Thunderbird.DataDir,
-		ScanInterval: deps.config.ScanInterval, LookbackDays: deps.config.LookbackDays, BanksData: deps.content.BanksJSON,
-		Logger: deps.logger.With("component", "api"), LogLevel: deps.logLevel,
+		RuleDrafts: deps.llm.ruleDrafts, TransactionQueries: deps.llm.queries, LLMScope: deps.llm.scope, Store: deps.store,
+		Daemon: deps.controller, Community: deps.community, Backups: deps.backups, SecretRotation: deps.rekey, Events: deps.events,
+		Notifier: deps.notifier, Version: config.Version, OIDC: deps.oidc, OIDCAutoProvision: deps.config.OIDC.AutoProvision, ProxyAuth: deps.proxyAuth,
+		WebAuthn: deps.webauthn, TrustedProxies: deps.trustedProxies, BaseURL: deps.config.BaseURL, FrontendURL: deps.config.FrontendURL,
+		ThunderbirdDataDir: deps.config.Thunderbird.DataDir, ScanInterval: deps.config.ScanInterval,
+		LookbackDays: deps.config.LookbackDays, BanksData: deps.content.BanksJSON,
+		MaxAttachmentSize: deps.config.Blob.MaxAttachmentSize, Logger: deps.logger.With("component", "api"), LogLevel: deps.logLevel,
 	})
 	return httpapi.NewServer(deps.config.Port, handlers, deps.config.StaticDir, deps.logger.With("component", "http"))
 }
+
+func newProxyAuthConfig(cfg config.ProxyAuth) (httpapi.ProxyAuthConfig, error) {
+	if !cfg.Enabled() {
+		return httpapi.ProxyAuthConfig{}, nil
+	}
+	proxies, err := cfg.GetTrustedProxies()
+	if err != nil {
+		return httpapi.ProxyAuthConfig{}, errors.E("app.new_proxy_auth_config", errors.InvalidArgument, err)
+	}
+	return httpapi.ProxyAuthConfig{
+		Header:           strings.TrimSpace(cfg.Header),
+		NameHeader:       strings.TrimSpace(cfg.NameHeader),
+		TrustedProxies:   proxies,
+		Provision:        cfg.Provision == "user",
+		ProvisionDomains: cfg.GetProvisionDomains(),
+	}, nil
+}
+
+// newWebAuthn returns the passkey relying party for the frontend, which
+// browsers see as the origin of every ceremony.
+func newWebAuthn(cfg config.App) (*webauthn.RelyingParty, error) {
+	rp, err := webauthn.New("Expensor", cfg.FrontendURL, cfg.BaseURL)
+	if err != nil {
+		return nil, errors.E("app.new_webauthn", "configuring passkeys", err)
+	}
+	return rp, nil
+}
+
+// newOIDCProvider returns nil when single sign-on is not configured, keeping
+// the interface nil rather than holding a nil *oidc.Provider.
+func newOIDCProvider(cfg config.OIDC) (httpapi.OIDCProvider, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	provider, err := oidc.New(oidc.Config{
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Scopes:       cfg.GetScopes(),
+		Name:         cfg.ProviderName,
+		Claims: oidc.ClaimMapping{
+			Email:       cfg.EmailClaim,
+			Name:        cfg.NameClaim,
+			Role:        cfg.RoleClaim,
+			AdminValues: cfg.GetAdminValues(),
+		},
+	})
+	if err != nil {
+		return nil, errors.E("app.new_oidc_provider", "configuring OIDC", err)
+	}
+	return provider, nil
+}
@@ -6,7 +6,10 @@ import (
 	"github.com/ArionMiles/expensor/backend/internal/assistant"
 	"github.com/ArionMiles/expensor/backend/internal/catalog"
 	"github.com/ArionMiles/expensor/backend/internal/llm"
+	anthropicProvider "github.com/ArionMiles/expensor/backend/internal/llm/anthropic"
+	ollamaProvider "github.com/ArionMiles/expensor/backend/internal/llm/ollama"
 	openaiProvider "github.com/ArionMiles/expensor/backend/internal/llm/openai"
+	openaiCompatProvider "github.com/ArionMiles/expensor/backend/internal/llm/openaicompat"
 	"github.com/ArionMiles/expensor/backend/internal/observability"
 	"github.com/ArionMiles/expensor/backend/internal/store/instrumented"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
@@ -16,21 +19,44 @@ type llmRuntime struct {
 	registry   *llm.Registry
 	router     *llm.Router
 	ruleDrafts assistant.RuleDrafter
+	queries    assistant.TransactionQuerier
 	scope      *observability.Scope
 }
 
 func newLLMRuntime(content catalog.Content, st *instrumented.Store, logger *slog.Logger) (llmRuntime, error) {
-	registry := llm.NewRegistry()
-	if err := registry.RegisterProvider(openaiProvider.Provider(content.OpenAIModelOptions)); err != nil {
-		return llmRuntime{}, errors.E("app.llm.new", errors.Internal, "registering OpenAI provider", err)
+	registry, err := NewLLMRegistry(content)
+	if err != nil {
+		return llmRuntime{}, err
 	}
 	llmLogger := logger.With("component", "llm")
 	llmScope := observability.NewScope(llmLogger, "github.com/ArionMiles/expensor/backend/internal/llm")
 	router := llm.NewRouter(llm.RouterConfig{
-		Registry: registry, Runtime: st, Prompts: content.PromptCatalog, Scope: llmScope, Logger: llmLogger,
+		Registry: registry, Runtime: st, Usage: st, Prompts: content.PromptCatalog, PromptOverrides: st,
+		Scope: llmScope, Logger: llmLogger,
 	})
 	assistantLogger := logger.With("component", "assistant")
 	assistantScope := observability.NewScope(assistantLogger, "github.com/ArionMiles/expensor/backend/internal/assistant")
 	ruleDrafts := assistant.NewInstrumentedRuleDrafter(assistant.NewRuleDraftService(router), assistantScope, assistantLogger)
-	return llmRuntime{registry: registry, router: router, ruleDrafts: ruleDrafts, scope: llmScope}, nil
+	queries := assistant.NewInstrumentedTransactionQuerier(
+		assistant.NewTransactionQueryService(router, st), assistantScope, assistantLogger,
+	)
+	return llmRuntime{registry: registry, router: router, ruleDrafts: ruleDrafts, queries: queries, scope: llmScope}, nil
+}
+
+// NewLLMRegistry registers the built-in LLM providers.
+func NewLLMRegistry(content catalog.Content) (*llm.Registry, error) {
+	registry := llm.NewRegistry()
+	for _, provider := range []llm.Provider{
+		openaiProvider.Provider(content.OpenAIModelOptions),
+		anthropicProvider.Provider(content.AnthropicModelOptions),
+		ollamaProvider.Provider(),
+		openaiCompatProvider.Provider(),
+	} {
+		if err := registry.RegisterProvider(provider); err != nil {
+			return nil, errors.E(
+				"app.llm.new_registry", errors.Internal, "registering "+provider.Metadata.DisplayName+" provider", err,
+			)
+		}
+	}
+	return registry, nil
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/ArionMiles/expensor/backend/internal/blob"
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/observability"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/internal/store/instrumented"
@@ -18,6 +20,8 @@ import (
 type StoreOptions struct {
 	Database config.Database
 	Security config.Security
+	Blob     config.Blob
+	Events   events.Publisher
 	Logger   *slog.Logger
 }
 
@@ -47,15 +51,27 @@ func NewStore(ctx context.Context, opts StoreOptions) (Store, error) {
 	storeLogger := logger.With("component", "store")
 	storeScope := observability.NewScope(storeLogger, "github.com/ArionMiles/expensor/backend/internal/store")
 	instrumentedStore := instrumented.NewStore(instrumented.StoreDeps{
-		Auth:         backend,
-		Analytics:    backend,
-		Community:    backend,
-		Diagnostics:  backend,
-		Rules:        backend,
-		Runtime:      backend,
-		Scanning:     backend,
-		Taxonomy:     backend,
-		Transactions: backend,
+		Auth:          backend,
+		Analytics:     backend,
+		Archives:      backend,
+		Backups:       backend,
+		Attachments:   backend,
+		Audit:         backend,
+		Community:     backend,
+		Diagnostics:   backend,
+		LLMUsage:      backend,
+		LLMPrompts:    backend,
+		MFA:           backend,
+		Notifications: backend,
+		Rules:         backend,
+		Runtime:       backend,
+		Scanning:      backend,
+		Secrets:       backend,
+		SharedLedgers: backend,
+		Taxonomy:      backend,
+		Tenants:       backend,
+		Transactions:  backend,
+		Webhooks:      backend,
 	}, storeScope, storeLogger)
 	instrumentedIngestion := instrumented.NewTransactionBatchWriter(backend, storeScope, storeLogger)
 
@@ -85,10 +101,17 @@ func (s Store) Seed(ctx context.Context, content store.SeedContent) (api.Categor
 func openStoreBackend(ctx context.Context, opts StoreOptions) (store.Backend, error) {
 	switch opts.Database.Backend {
 	case config.DatabaseBackendPostgres:
+		blobs, err := blob.New(opts.Blob)
+		if err != nil {
+			return nil, errors.E("app.store.new", "opening blob store", err)
+		}
 		return postgres.New(ctx, postgres.Options{
-			Config:   opts.Database.Postgres,
-			Security: opts.Security,
-			Logger:   opts.Logger,
+			Config:            opts.Database.Postgres,
+			Security:          opts.Security,
+			Blobs:             blobs,
+			MaxAttachmentSize: opts.Blob.MaxAttachmentSize,
+			Events:            opts.Events,
+			Logger:            opts.Logger,
 		})
 	case "", config.DatabaseBackendSQLite:
 		return nil, errors.E("app.store.new", errors.FailedPrecondition, "sqlite database backend is not supported yet")
@@ -13,7 +13,7 @@ import (
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
-const ruleDraftOutcomeError = "error"
+const assistantOutcomeError = "error"
 
 // RuleDrafter is implemented by services that generate rule drafts from email samples.
 type RuleDrafter interface {
@@ -54,7 +54,7 @@ func (d *InstrumentedRuleDrafter) DraftRule(ctx context.Context, tenant store.Te
 	outcome := "ok"
 	issueCount := len(result.ValidationIssues)
 	if err != nil {
-		outcome = ruleDraftOutcomeError
+		outcome = assistantOutcomeError
 		if kind := errors.WhatKind(err); kind.Code != "" {
 			attrs = append(attrs, attribute.String("error_kind", kind.Code))
 		}
@@ -110,7 +110,82 @@ func countDraftSamples(input RuleDraftInput) int {
 	return count
 }
 
+// TransactionQuerier is implemented by services that answer questions about stored transactions.
+type TransactionQuerier interface {
+	QueryTransactions(ctx context.Context, tenant store.Tenant, input TransactionQueryInput) (TransactionQueryResult, error)
+}
+
+// InstrumentedTransactionQuerier records workflow telemetry around transaction queries.
+type InstrumentedTransactionQuerier struct {
+	next   TransactionQuerier
+	scope  *observability.Scope
+	logger *slog.Logger
+}
+
+func NewInstrumentedTransactionQuerier(next TransactionQuerier, scope *observability.Scope, logger *slog.Logger) *InstrumentedTransactionQuerier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if scope == nil {
+		scope = observability.NewScope(logger, "github.com/ArionMiles/expensor/backend/internal/assistant")
+	}
+	return &InstrumentedTransactionQuerier{next: next, scope: scope, logger: logger}
+}
+
+func (q *InstrumentedTransactionQuerier) QueryTransactions(
+	ctx context.Context,
+	tenant store.Tenant,
+	input TransactionQueryInput,
+) (TransactionQueryResult, error) {
+	start := time.Now()
+	ctx, span := q.scope.Start(ctx, "assistant.transaction_query")
+	defer span.End()
+
+	attrs := []attribute.KeyValue{
+		attribute.String("assistant.workflow", transactionQueryWorkflow),
+		attribute.String("assistant.purpose", transactionQueryPurpose),
+	}
+	span.SetAttributes(attrs...)
+
+	result, err := q.next.QueryTransactions(ctx, tenant, input)
+	outcome := "ok"
+	if err != nil {
+		outcome = assistantOutcomeError
+		if kind := errors.WhatKind(err); kind.Code != "" {
+			attrs = append(attrs, attribute.String("error_kind", kind.Code))
+		}
+		logAttrs := []slog.Attr{
+			slog.String("namespace", "assistant"),
+			slog.String("operation", "transaction_query"),
+		}
+		logAttrs = append(logAttrs, errors.LogDetailAttrs(err)...)
+		if spanContext := trace.SpanFromContext(ctx).SpanContext(); spanContext.IsValid() {
+			logAttrs = append(logAttrs,
+				slog.String("trace_id", spanContext.TraceID().String()),
+				slog.String("span_id", spanContext.SpanID().String()),
+			)
+		}
+		q.logger.LogAttrs(ctx, slog.LevelError, "transaction query failed", logAttrs...)
+	}
+	attrs = append(attrs,
+		attribute.String("assistant.outcome", outcome),
+		attribute.Int("assistant.tool_call_count", len(result.Queries)),
+	)
+	span.SetAttributes(attrs...)
+
+	q.scope.RecordDuration(ctx, observability.DurationOperation{
+		Namespace:  "assistant",
+		Name:       "transaction_query",
+		Duration:   time.Since(start),
+		Err:        err,
+		Attributes: attrs,
+	})
+	return result, err
+}
+
 var (
-	_ RuleDrafter = (*RuleDraftService)(nil)
-	_ RuleDrafter = (*InstrumentedRuleDrafter)(nil)
+	_ RuleDrafter        = (*RuleDraftService)(nil)
+	_ RuleDrafter        = (*InstrumentedRuleDrafter)(nil)
+	_ TransactionQuerier = (*TransactionQueryService)(nil)
+	_ TransactionQuerier = (*InstrumentedTransactionQuerier)(nil)
 )
@@ -99,7 +99,10 @@ func (s *RuleDraftService) DraftRule(ctx context.Context, tenant store.Tenant, i
 	if err != nil {
 		return RuleDraftResult{}, errors.E(op, err)
 	}
-	prompt, ok := s.router.PromptCatalog().Get(ruleDraftWorkflow, ruleDraftPurpose)
+	prompt, ok, err := s.router.Prompt(ctx, ruleDraftWorkflow, ruleDraftPurpose)
+	if err != nil {
+		return RuleDraftResult{}, errors.E(op, err)
+	}
 	if !ok {
 		return RuleDraftResult{}, errors.E(op, KindRuleDraftPromptMissing, "rule draft prompt is not configured")
 	}
@@ -0,0 +1,151 @@
+package assistant
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/extractor"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+// defaultEvalCurrency mirrors store ingestion, which records INR when a rule
+// extracts no currency.
+const defaultEvalCurrency = "INR"
+
+// RuleDraftEvalCase is one labelled email used to score a rule draft prompt.
+type RuleDraftEvalCase struct {
+	Name     string
+	Sender   string
+	Subject  string
+	Body     string
+	Amount   float64
+	Merchant string
+	Currency string
+}
+
+// RuleDraftEvalResult is the outcome of drafting a rule for one case.
+type RuleDraftEvalResult struct {
+	Name          string `json:"name"`
+	Error         string `json:"error,omitempty"`
+	ValidRegex    bool   `json:"valid_regex"`
+	AmountMatch   bool   `json:"amount_match"`
+	MerchantMatch bool   `json:"merchant_match"`
+	CurrencyMatch bool   `json:"currency_match"`
+}
+
+// RuleDraftEvaluation summarises how one prompt version performs across cases.
+type RuleDraftEvaluation struct {
+	Label           string                `json:"label"`
+	Cases           int                   `json:"cases"`
+	Errors          int                   `json:"errors"`
+	ValidRegex      int                   `json:"valid_regex"`
+	AmountMatches   int                   `json:"amount_matches"`
+	MerchantMatches int                   `json:"merchant_matches"`
+	CurrencyMatches int                   `json:"currency_matches"`
+	FullMatches     int                   `json:"full_matches"`
+	Results         []RuleDraftEvalResult `json:"results"`
+}
+
+// Rate returns count as a fraction of evaluated cases.
+func (e RuleDraftEvaluation) Rate(count int) float64 {
+	if e.Cases == 0 {
+		return 0
+	}
+	return float64(count) / float64(e.Cases)
+}
+
+// EvaluateRuleDrafts drafts one rule per case and scores each draft with the
+// production extractor. Provider failures are counted rather than returned so
+// one bad case does not hide the rest of the run.
+func EvaluateRuleDrafts(
+	ctx context.Context,
+	drafter RuleDrafter,
+	tenant store.Tenant,
+	label string,
+	cases []RuleDraftEvalCase,
+) RuleDraftEvaluation {
+	evaluation := RuleDraftEvaluation{Label: label, Cases: len(cases), Results: make([]RuleDraftEvalResult, 0, len(cases))}
+	for _, evalCase := range cases {
+		result := evaluateRuleDraftCase(ctx, drafter, tenant, evalCase)
+		if result.Error != "" {
+			evaluation.Errors++
+		}
+		if result.ValidRegex {
+			evaluation.ValidRegex++
+		}
+		if result.AmountMatch {
+			evaluation.AmountMatches++
+		}
+		if result.MerchantMatch {
+			evaluation.MerchantMatches++
+		}
+		if result.CurrencyMatch {
+			evaluation.CurrencyMatches++
+		}
+		if result.AmountMatch && result.MerchantMatch && result.CurrencyMatch {
+			evaluation.FullMatches++
+		}
+		evaluation.Results = append(evaluation.Results, result)
+	}
+	return evaluation
+}
+
+func evaluateRuleDraftCase(ctx context.Context, drafter RuleDrafter, tenant store.Tenant, evalCase RuleDraftEvalCase) RuleDraftEvalResult {
+	result := RuleDraftEvalResult{Name: evalCase.Name}
+	draft, err := drafter.DraftRule(ctx, tenant, RuleDraftInput{
+		Samples: []Sample{{
+			Name:    evalCase.Name,
+			Sender:  evalCase.Sender,
+			Subject: evalCase.Subject,
+			Body:    evalCase.Body,
+			Expected: Expected{
+				Amount:   strconv.FormatFloat(evalCase.Amount, 'f', 2, 64),
+				Merchant: evalCase.Merchant,
+				Currency: evalCase.Currency,
+			},
+		}},
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	amount, merchant, currency, ok := compileDraftRegexes(draft.Draft)
+	if !ok {
+		return result
+	}
+	result.ValidRegex = true
+
+	tx := extractor.ExtractTransactionDetails(evalCase.Body, amount, merchant, currency, time.Time{})
+	gotCurrency := tx.Currency
+	if gotCurrency == "" {
+		gotCurrency = defaultEvalCurrency
+	}
+	result.AmountMatch = tx.Amount == evalCase.Amount
+	result.MerchantMatch = tx.MerchantInfo == evalCase.Merchant
+	result.CurrencyMatch = strings.EqualFold(gotCurrency, evalCase.Currency)
+	return result
+}
+
+// compileDraftRegexes reports whether the draft carries usable amount and
+// merchant regexes plus an optional currency regex.
+func compileDraftRegexes(draft RuleDraft) (amount, merchant, currency *regexp.Regexp, ok bool) {
+	if draft.AmountRegex == "" || draft.MerchantRegex == "" {
+		return nil, nil, nil, false
+	}
+	var err error
+	if amount, err = regexp.Compile(draft.AmountRegex); err != nil {
+		return nil, nil, nil, false
+	}
+	if merchant, err = regexp.Compile(draft.MerchantRegex); err != nil {
+		return nil, nil, nil, false
+	}
+	if draft.CurrencyRegex != "" {
+		if currency, err = regexp.Compile(draft.CurrencyRegex); err != nil {
+			return nil, nil, nil, false
+		}
+	}
+	return amount, merchant, currency, true
+}
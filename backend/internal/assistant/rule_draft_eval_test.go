@@ -0,0 +1,54 @@
+package assistant
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+func TestEvaluateRuleDraftsScoresEachCase(t *testing.T) {
+	invalid := matchingDraft()
+	invalid.AmountRegex = `INR\s+([0-9.]+`
+	wrongMerchant := matchingDraft()
+	wrongMerchant.MerchantRegex = `(INR)`
+	client := &queuedRuleDraftClient{
+		responses: []string{
+			draftJSON(t, matchingDraft()),
+			draftJSON(t, invalid),
+			draftJSON(t, invalid),
+			draftJSON(t, wrongMerchant),
+			draftJSON(t, wrongMerchant),
+		},
+		errs: []error{nil, nil, nil, nil, nil, stderrors.New("provider unavailable")},
+	}
+	service := newRuleDraftServiceForTest(t, client, ruleDraftPromptCatalog(t))
+	evalCase := RuleDraftEvalCase{
+		Sender:   "alerts@example.com",
+		Subject:  "Card alert",
+		Body:     "INR 1522.00 spent at Amazon",
+		Amount:   1522,
+		Merchant: "Amazon",
+		Currency: "INR",
+	}
+	cases := []RuleDraftEvalCase{evalCase, evalCase, evalCase, evalCase}
+	for i, name := range []string{"matching", "invalid", "wrong-merchant", "failed"} {
+		cases[i].Name = name
+	}
+
+	evaluation := EvaluateRuleDrafts(context.Background(), service, store.Tenant{ID: "tenant-a"}, "v1", cases)
+
+	if evaluation.Cases != 4 || evaluation.Errors != 1 {
+		t.Fatalf("cases/errors = %d/%d, want 4/1", evaluation.Cases, evaluation.Errors)
+	}
+	if evaluation.ValidRegex != 2 || evaluation.AmountMatches != 2 || evaluation.MerchantMatches != 1 || evaluation.FullMatches != 1 {
+		t.Fatalf("evaluation = %+v, want two valid drafts and one full match", evaluation)
+	}
+	if got := evaluation.Rate(evaluation.ValidRegex); got != 0.5 {
+		t.Fatalf("valid regex rate = %v, want 0.5", got)
+	}
+	if evaluation.Results[1].ValidRegex || evaluation.Results[3].Error == "" {
+		t.Fatalf("results = %+v, want invalid regex and provider error recorded", evaluation.Results)
+	}
+}
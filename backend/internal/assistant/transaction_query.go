@@ -0,0 +1,541 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	transactionQueryWorkflow        = "transaction_query"
+	transactionQueryPurpose         = "answer_question"
+	maxTransactionQuestionBytes     = 1_000
+	maxTransactionQuerySteps        = 6
+	maxTransactionQueryToolCalls    = 12
+	defaultTransactionQueryPageSize = 20
+	maxTransactionQueryPageSize     = 50
+	defaultTransactionQueryMonths   = 6
+	maxTransactionQueryMonths       = 24
+	maxTransactionQueryFacetValues  = 100
+	transactionQueryDateLayout      = "2006-01-02"
+)
+
+var (
+	KindTransactionQueryPromptMissing = errors.Kind{Code: "transaction_query_prompt_missing", Status: http.StatusInternalServerError}
+	KindTransactionQueryInvalidInput  = errors.Kind{Code: "transaction_query_invalid_input", Status: http.StatusUnprocessableEntity}
+	KindTransactionQueryStepLimit     = errors.Kind{Code: "transaction_query_step_limit", Status: http.StatusUnprocessableEntity}
+)
+
+// transactionQueryPolicy keeps the query workflow read-only: every tool call is
+// checked against it before the tool runs.
+var transactionQueryPolicy = llm.MutationPolicy{AllowMutations: false}
+
+// transactionQueryReadOnlyTools lists the tools reviewed as read-only. Any
+// other tool counts as a mutation and is refused by transactionQueryPolicy,
+// so a tool added without review cannot run.
+var transactionQueryReadOnlyTools = map[string]bool{
+	"list_transactions": true,
+	"get_facets":        true,
+	"monthly_breakdown": true,
+}
+
+// TransactionQueryStore is the read-only data surface exposed to the model.
+type TransactionQueryStore interface {
+	ListTransactions(ctx context.Context, tenant store.Tenant, f store.ListFilter) ([]store.Transaction, store.TransactionListResult, error)
+	GetFacets(ctx context.Context, tenant store.Tenant) (*store.Facets, error)
+	GetMonthlyBreakdownSpend(ctx context.Context, tenant store.Tenant, dimension string, months int) (*store.MonthlyBreakdownData, error)
+}
+
+type TransactionQueryService struct {
+	router *llm.Router
+	store  TransactionQueryStore
+	now    func() time.Time
+}
+
+type TransactionQueryInput struct {
+	Question     string `json:"question"`
+	Timezone     string `json:"timezone"`
+	BaseCurrency string `json:"base_currency"`
+}
+
+type TransactionQueryResult struct {
+	Answer  string                 `json:"answer"`
+	Queries []TransactionQueryStep `json:"queries"`
+}
+
+// TransactionQueryStep records one tool invocation and the exact data returned
+// to the model, so answers can be traced back to the numbers they used.
+type TransactionQueryStep struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type transactionQueryTool struct {
+	definition llm.Tool
+	run        func(ctx context.Context, s *TransactionQueryService, call transactionQueryCall) (any, error)
+}
+
+type transactionQueryCall struct {
+	tenant    store.Tenant
+	location  *time.Location
+	arguments json.RawMessage
+}
+
+func NewTransactionQueryService(router *llm.Router, queryStore TransactionQueryStore) *TransactionQueryService {
+	return &TransactionQueryService{router: router, store: queryStore, now: time.Now}
+}
+
+func (s *TransactionQueryService) QueryTransactions(
+	ctx context.Context,
+	tenant store.Tenant,
+	input TransactionQueryInput,
+) (TransactionQueryResult, error) {
+	const op = "assistant.TransactionQueryService.QueryTransactions"
+
+	if s == nil || s.router == nil || s.store == nil {
+		return TransactionQueryResult{}, errors.E(op, llm.KindNoProviderConfigured, "no llm provider configured")
+	}
+	input, location, err := normalizeTransactionQueryInput(input)
+	if err != nil {
+		return TransactionQueryResult{}, errors.E(op, err)
+	}
+	prompt, ok, err := s.router.Prompt(ctx, transactionQueryWorkflow, transactionQueryPurpose)
+	if err != nil {
+		return TransactionQueryResult{}, errors.E(op, err)
+	}
+	if !ok {
+		return TransactionQueryResult{}, errors.E(op, KindTransactionQueryPromptMissing, "transaction query prompt is not configured")
+	}
+
+	messages := renderPromptMessages(prompt.Messages, map[string]string{
+		"question":      input.Question,
+		"today":         s.now().In(location).Format(transactionQueryDateLayout),
+		"timezone":      location.String(),
+		"base_currency": input.BaseCurrency,
+	})
+	tools := transactionQueryTools()
+	definitions := orderedTransactionQueryTools(tools)
+	capabilities := append([]llm.Capability(nil), prompt.RequiredCapabilities...)
+	if !containsCapability(capabilities, llm.CapabilityTools) {
+		capabilities = append(capabilities, llm.CapabilityTools)
+	}
+
+	result := TransactionQueryResult{Queries: []TransactionQueryStep{}}
+	for range maxTransactionQuerySteps {
+		response, err := s.router.Complete(ctx, tenant, llm.Request{
+			Workflow:             prompt.Workflow,
+			Purpose:              prompt.Purpose,
+			Messages:             messages,
+			Tools:                definitions,
+			RequiredCapabilities: capabilities,
+			MaxOutputTokens:      1200,
+		})
+		if err != nil {
+			return TransactionQueryResult{}, errors.E(op, err)
+		}
+		if len(response.ToolCalls) == 0 {
+			result.Answer = strings.TrimSpace(response.Text)
+			return result, nil
+		}
+		if len(result.Queries)+len(response.ToolCalls) > maxTransactionQueryToolCalls {
+			break
+		}
+		messages = append(messages, llm.Message{
+			Role:      llm.RoleAssistant,
+			Content:   response.Text,
+			ToolCalls: response.ToolCalls,
+		})
+		for _, call := range response.ToolCalls {
+			step, err := s.runTool(ctx, tools, call, transactionQueryCall{tenant: tenant, location: location})
+			if err != nil {
+				return TransactionQueryResult{}, errors.E(op, err)
+			}
+			result.Queries = append(result.Queries, step)
+			content, err := step.toolMessageContent()
+			if err != nil {
+				return TransactionQueryResult{}, errors.E(op, err)
+			}
+			messages = append(messages, llm.Message{
+				Role:       llm.RoleTool,
+				Name:       call.Name,
+				ToolCallID: call.ID,
+				Content:    content,
+			})
+		}
+	}
+	msg := "the question needed too many lookups to answer; try a narrower question"
+	return TransactionQueryResult{}, errors.E(op, KindTransactionQueryStepLimit, errors.User(msg), msg)
+}
+
+// runTool executes a single model tool call. Tool failures caused by the model
+// (unknown tools, bad arguments) are returned to the model as error results;
+// store failures and mutation attempts abort the workflow.
+func (s *TransactionQueryService) runTool(
+	ctx context.Context,
+	tools map[string]transactionQueryTool,
+	call llm.ToolCall,
+	input transactionQueryCall,
+) (TransactionQueryStep, error) {
+	const op = "assistant.TransactionQueryService.runTool"
+
+	arguments := call.Arguments
+	if len(strings.TrimSpace(string(arguments))) == 0 {
+		arguments = json.RawMessage(`{}`)
+	}
+	if !json.Valid(arguments) {
+		step := TransactionQueryStep{Tool: call.Name, Arguments: json.RawMessage(`{}`), Error: "tool arguments must be a JSON object"}
+		return step, nil
+	}
+	input.arguments = arguments
+	step := TransactionQueryStep{Tool: call.Name, Arguments: arguments}
+
+	tool, ok := tools[call.Name]
+	if !ok {
+		step.Error = "unknown tool " + call.Name
+		return step, nil
+	}
+	if err := llm.ValidateMutationSafety(transactionQueryPolicy, transactionQueryToolMutations(call.Name)); err != nil {
+		return step, errors.E(op, errors.PermissionDenied, err)
+	}
+
+	output, err := tool.run(ctx, s, input)
+	if err != nil {
+		if errors.WhatKind(err) == KindTransactionQueryInvalidInput {
+			step.Error = errors.UserMsg(err)
+			return step, nil
+		}
+		return step, errors.E(op, err)
+	}
+	body, err := json.Marshal(output)
+	if err != nil {
+		return step, errors.E(op, "encoding tool result", err)
+	}
+	step.Result = body
+	return step, nil
+}
+
+// transactionQueryToolMutations returns the mutations a tool may make: none
+// for reviewed read-only tools, and otherwise an update of transactions.
+func transactionQueryToolMutations(name string) []llm.MutationRequest {
+	if transactionQueryReadOnlyTools[name] {
+		return nil
+	}
+	return []llm.MutationRequest{{Resource: "transactions", Operation: name}}
+}
+
+// redactToolText masks emails and card numbers in free text returned to the
+// model. Tools redact their text fields one by one: identifiers and amounts
+// are left alone, since the card pattern also matches digit runs in UUIDs.
+func redactToolText(value string) string {
+	return llm.RedactText(value, llm.DefaultRedactionPolicy())
+}
+
+func redactToolTexts(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	redacted := make([]string, len(values))
+	for i, value := range values {
+		redacted[i] = redactToolText(value)
+	}
+	return redacted
+}
+
+func transactionQueryTools() map[string]transactionQueryTool {
+	tools := []transactionQueryTool{
+		{
+			definition: llm.Tool{
+				Name: "list_transactions",
+				Description: "List transactions matching filters. Returns the total count and total amount across all matches " +
+					"(in the base currency) plus up to limit individual transactions, newest first.",
+				Parameters: json.RawMessage(`{
+					"type":"object",
+					"additionalProperties":false,
+					"properties":{
+						"from":{"type":"string","description":"Inclusive start date, YYYY-MM-DD, in the user's timezone."},
+						"to":{"type":"string","description":"Inclusive end date, YYYY-MM-DD, in the user's timezone."},
+						"category":{"type":"string"},
+						"bucket":{"type":"string"},
+						"label":{"type":"string"},
+						"merchant":{"type":"string","description":"Case-insensitive merchant substring."},
+						"currency":{"type":"string"},
+						"source":{"type":"string"},
+						"limit":{"type":"integer","minimum":0,"maximum":50}
+					}
+				}`),
+			},
+			run: runListTransactionsTool,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "get_facets",
+				Description: "List the categories, buckets, labels, currencies, sources and merchants that exist in the user's data.",
+				Parameters:  json.RawMessage(`{"type":"object","additionalProperties":false,"properties":{}}`),
+			},
+			run: runGetFacetsTool,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "monthly_breakdown",
+				Description: "Monthly spend totals in the base currency, grouped by labels, categories or buckets, for the most recent months.",
+				Parameters: json.RawMessage(`{
+					"type":"object",
+					"additionalProperties":false,
+					"required":["dimension"],
+					"properties":{
+						"dimension":{"type":"string","enum":["labels","categories","buckets"]},
+						"months":{"type":"integer","minimum":1,"maximum":24}
+					}
+				}`),
+			},
+			run: runMonthlyBreakdownTool,
+		},
+	}
+	out := make(map[string]transactionQueryTool, len(tools))
+	for _, tool := range tools {
+		out[tool.definition.Name] = tool
+	}
+	return out
+}
+
+// orderedTransactionQueryTools returns the tool definitions in a stable order.
+func orderedTransactionQueryTools(tools map[string]transactionQueryTool) []llm.Tool {
+	names := []string{"list_transactions", "get_facets", "monthly_breakdown"}
+	out := make([]llm.Tool, 0, len(names))
+	for _, name := range names {
+		if tool, ok := tools[name]; ok {
+			out = append(out, tool.definition)
+		}
+	}
+	return out
+}
+
+type listTransactionsArguments struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Category string `json:"category"`
+	Bucket   string `json:"bucket"`
+	Label    string `json:"label"`
+	Merchant string `json:"merchant"`
+	Currency string `json:"currency"`
+	Source   string `json:"source"`
+	Limit    *int   `json:"limit"`
+}
+
+type listedTransaction struct {
+	ID        string   `json:"id"`
+	Timestamp string   `json:"timestamp"`
+	Amount    float64  `json:"amount"`
+	Currency  string   `json:"currency"`
+	Merchant  string   `json:"merchant"`
+	Category  string   `json:"category,omitempty"`
+	Bucket    string   `json:"bucket,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Source    string   `json:"source,omitempty"`
+}
+
+func runListTransactionsTool(ctx context.Context, s *TransactionQueryService, call transactionQueryCall) (any, error) {
+	const op = "assistant.runListTransactionsTool"
+
+	var args listTransactionsArguments
+	if err := decodeToolArguments(call.arguments, &args); err != nil {
+		return nil, errors.E(op, err)
+	}
+	limit := defaultTransactionQueryPageSize
+	if args.Limit != nil {
+		limit = min(max(*args.Limit, 0), maxTransactionQueryPageSize)
+	}
+	filter := store.ListFilter{
+		Category: strings.TrimSpace(args.Category),
+		Bucket:   strings.TrimSpace(args.Bucket),
+		Label:    strings.TrimSpace(args.Label),
+		Merchant: strings.TrimSpace(args.Merchant),
+		Currency: strings.TrimSpace(args.Currency),
+		Source:   strings.TrimSpace(args.Source),
+		Timezone: call.location.String(),
+		Page:     1,
+		PageSize: max(limit, 1),
+	}
+	if args.From != "" {
+		from, err := parseToolDate(args.From, call.location)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		filter.From = &from
+	}
+	if args.To != "" {
+		to, err := parseToolDate(args.To, call.location)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		end := to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		filter.To = &end
+	}
+
+	txns, totals, err := s.store.ListTransactions(ctx, call.tenant, filter)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	listed := make([]listedTransaction, 0, min(len(txns), limit))
+	for _, txn := range txns {
+		if len(listed) == limit {
+			break
+		}
+		listed = append(listed, listedTransaction{
+			ID:        txn.ID,
+			Timestamp: txn.Timestamp.In(call.location).Format(time.RFC3339),
+			Amount:    txn.Amount,
+			Currency:  txn.Currency,
+			Merchant:  redactToolText(txn.MerchantInfo),
+			Category:  redactToolText(txn.Category),
+			Bucket:    redactToolText(txn.Bucket),
+			Labels:    redactToolTexts(txn.Labels),
+			Source:    redactToolText(txn.Source.Display()),
+		})
+	}
+	return map[string]any{
+		"total":        totals.Total,
+		"total_amount": totals.TotalAmount,
+		"transactions": listed,
+	}, nil
+}
+
+func runGetFacetsTool(ctx context.Context, s *TransactionQueryService, call transactionQueryCall) (any, error) {
+	const op = "assistant.runGetFacetsTool"
+
+	facets, err := s.store.GetFacets(ctx, call.tenant)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if facets == nil {
+		facets = &store.Facets{}
+	}
+	merchants := facets.Merchants
+	if len(merchants) > maxTransactionQueryFacetValues {
+		merchants = merchants[:maxTransactionQueryFacetValues]
+	}
+	return map[string]any{
+		"categories": redactToolTexts(facets.Categories),
+		"buckets":    redactToolTexts(facets.Buckets),
+		"labels":     redactToolTexts(facets.Labels),
+		"currencies": facets.Currencies,
+		"sources":    redactToolTexts(facets.Sources),
+		"merchants":  redactToolTexts(merchants),
+	}, nil
+}
+
+type monthlyBreakdownArguments struct {
+	Dimension string `json:"dimension"`
+	Months    *int   `json:"months"`
+}
+
+func runMonthlyBreakdownTool(ctx context.Context, s *TransactionQueryService, call transactionQueryCall) (any, error) {
+	const op = "assistant.runMonthlyBreakdownTool"
+
+	var args monthlyBreakdownArguments
+	if err := decodeToolArguments(call.arguments, &args); err != nil {
+		return nil, errors.E(op, err)
+	}
+	switch args.Dimension {
+	case "labels", "categories", "buckets":
+	default:
+		msg := "dimension must be one of labels, categories, buckets"
+		return nil, errors.E(op, KindTransactionQueryInvalidInput, errors.User(msg), msg)
+	}
+	months := defaultTransactionQueryMonths
+	if args.Months != nil {
+		months = min(max(*args.Months, 1), maxTransactionQueryMonths)
+	}
+	data, err := s.store.GetMonthlyBreakdownSpend(ctx, call.tenant, args.Dimension, months)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if data == nil {
+		data = &store.MonthlyBreakdownData{}
+	}
+	breakdown := store.MonthlyBreakdownData{
+		Labels: redactToolTexts(data.Labels),
+		Months: data.Months,
+		Series: make([]store.MonthlyBreakdownSeries, len(data.Series)),
+	}
+	for i, series := range data.Series {
+		breakdown.Series[i] = store.MonthlyBreakdownSeries{Label: redactToolText(series.Label), Data: series.Data}
+	}
+	return breakdown, nil
+}
+
+func decodeToolArguments(raw json.RawMessage, target any) error {
+	const op = "assistant.decodeToolArguments"
+
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		msg := "invalid tool arguments: " + err.Error()
+		return errors.E(op, KindTransactionQueryInvalidInput, errors.User(msg), msg)
+	}
+	return nil
+}
+
+func parseToolDate(value string, location *time.Location) (time.Time, error) {
+	parsed, err := time.ParseInLocation(transactionQueryDateLayout, strings.TrimSpace(value), location)
+	if err != nil {
+		msg := "dates must use YYYY-MM-DD: " + value
+		return time.Time{}, errors.E("assistant.parseToolDate", KindTransactionQueryInvalidInput, errors.User(msg), msg, err)
+	}
+	return parsed, nil
+}
+
+func normalizeTransactionQueryInput(input TransactionQueryInput) (TransactionQueryInput, *time.Location, error) {
+	const op = "assistant.normalizeTransactionQueryInput"
+
+	input.Question = strings.TrimSpace(input.Question)
+	if input.Question == "" {
+		msg := "ask a question about your transactions"
+		return TransactionQueryInput{}, nil, errors.E(op, KindTransactionQueryInvalidInput, errors.User(msg), msg)
+	}
+	if len(input.Question) > maxTransactionQuestionBytes {
+		msg := "question is too long"
+		return TransactionQueryInput{}, nil, errors.E(op, KindTransactionQueryInvalidInput, errors.User(msg), msg)
+	}
+	input.BaseCurrency = strings.ToUpper(strings.TrimSpace(input.BaseCurrency))
+	location := time.UTC
+	if tz := strings.TrimSpace(input.Timezone); tz != "" {
+		loaded, err := time.LoadLocation(tz)
+		if err != nil {
+			msg := "unknown timezone " + tz
+			return TransactionQueryInput{}, nil, errors.E(op, KindTransactionQueryInvalidInput, errors.User(msg), msg)
+		}
+		location = loaded
+	}
+	input.Timezone = location.String()
+	return input, location, nil
+}
+
+func containsCapability(capabilities []llm.Capability, want llm.Capability) bool {
+	for _, capability := range capabilities {
+		if capability == want {
+			return true
+		}
+	}
+	return false
+}
+
+// toolMessageContent renders the step as the tool message returned to the model.
+func (step TransactionQueryStep) toolMessageContent() (string, error) {
+	if step.Error == "" {
+		return string(step.Result), nil
+	}
+	body, err := json.Marshal(map[string]string{"error": step.Error})
+	if err != nil {
+		return "", errors.E("assistant.TransactionQueryStep.toolMessageContent", "encoding tool error", err)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,347 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type scriptedToolClient struct {
+	responses []llm.Response
+	requests  []llm.Request
+}
+
+func (c *scriptedToolClient) Complete(_ context.Context, req llm.Request) (llm.Response, error) {
+	c.requests = append(c.requests, req)
+	if len(c.responses) == 0 {
+		return llm.Response{}, stderrors.New("unexpected transaction query request")
+	}
+	response := c.responses[0]
+	c.responses = c.responses[1:]
+	return response, nil
+}
+
+func (c *scriptedToolClient) HealthCheck(context.Context) error {
+	return nil
+}
+
+type transactionQueryStoreStub struct {
+	filters    []store.ListFilter
+	txns       []store.Transaction
+	totals     store.TransactionListResult
+	facets     *store.Facets
+	breakdowns []string
+}
+
+func (s *transactionQueryStoreStub) ListTransactions(
+	_ context.Context,
+	_ store.Tenant,
+	f store.ListFilter,
+) ([]store.Transaction, store.TransactionListResult, error) {
+	s.filters = append(s.filters, f)
+	return s.txns, s.totals, nil
+}
+
+func (s *transactionQueryStoreStub) GetFacets(context.Context, store.Tenant) (*store.Facets, error) {
+	return s.facets, nil
+}
+
+func (s *transactionQueryStoreStub) GetMonthlyBreakdownSpend(
+	_ context.Context,
+	_ store.Tenant,
+	dimension string,
+	months int,
+) (*store.MonthlyBreakdownData, error) {
+	s.breakdowns = append(s.breakdowns, dimension)
+	return &store.MonthlyBreakdownData{Labels: []string{"Food"}, Months: make([]string, months)}, nil
+}
+
+func newTransactionQueryServiceForTest(
+	t *testing.T,
+	client *scriptedToolClient,
+	queryStore TransactionQueryStore,
+	capabilities []llm.Capability,
+) *TransactionQueryService {
+	t.Helper()
+	registry := llm.NewRegistry()
+	if err := registry.RegisterProvider(llm.Provider{
+		Metadata: llm.ProviderMetadata{
+			Name:         "test",
+			DisplayName:  "Test LLM",
+			Auth:         llm.AuthSpec{Type: llm.AuthTypeAPIKey, Required: true},
+			Capabilities: capabilities,
+		},
+		NewClient: func(llm.ClientConfig) (llm.Client, error) {
+			return client, nil
+		},
+	}); err != nil {
+		t.Fatalf("RegisterProvider() error = %v", err)
+	}
+	catalog, err := llm.LoadPromptCatalog(fstest.MapFS{
+		"prompts/transaction_query.yaml": &fstest.MapFile{Data: []byte(`
+id: transaction_query_test
+version: 1
+workflow: transaction_query
+purpose: answer_question
+required_capabilities:
+  - text_generation
+  - tools
+messages:
+  - role: system
+    content: "Today is {{today}} in {{timezone}}; totals in {{base_currency}}."
+  - role: user
+    content: "{{question}}"
+`)},
+	}, "prompts")
+	if err != nil {
+		t.Fatalf("LoadPromptCatalog() error = %v", err)
+	}
+	router := llm.NewRouter(llm.RouterConfig{
+		Registry: registry,
+		Runtime: &ruleDraftRuntimeStore{
+			found: true,
+			runtime: store.LLMProviderRuntime{
+				Provider:       "test",
+				Config:         json.RawMessage(`{}`),
+				Credentials:    []byte(`{"api_key":"test"}`),
+				HasCredentials: true,
+				Active:         true,
+			},
+		},
+		Prompts: catalog,
+	})
+	service := NewTransactionQueryService(router, queryStore)
+	service.now = func() time.Time { return time.Date(2026, 4, 2, 1, 0, 0, 0, time.UTC) }
+	return service
+}
+
+func toolCallResponse(calls ...llm.ToolCall) llm.Response {
+	return llm.Response{ToolCalls: calls, FinishReason: "tool_calls"}
+}
+
+func TestTransactionQueryServiceRunsToolsAndReturnsQueries(t *testing.T) {
+	client := &scriptedToolClient{responses: []llm.Response{
+		toolCallResponse(llm.ToolCall{
+			ID:        "call_1",
+			Name:      "list_transactions",
+			Arguments: json.RawMessage(`{"from":"2026-03-01","to":"2026-03-31","category":"Food Delivery","limit":1}`),
+		}),
+		toolCallResponse(llm.ToolCall{
+			ID:        "call_2",
+			Name:      "monthly_breakdown",
+			Arguments: json.RawMessage(`{"dimension":"categories","months":3}`),
+		}),
+		{Text: " You spent INR 450.00 on food delivery in March. "},
+	}}
+	queryStore := &transactionQueryStoreStub{
+		txns: []store.Transaction{
+			{ID: "11111111-2222-3333-4444-555555555555", Amount: 250, Currency: "INR", MerchantInfo: "Swiggy billing@swiggy.in 4111 1111 1111 1111", Category: "Food Delivery"},
+			{ID: "t2", Amount: 200, Currency: "INR", MerchantInfo: "Zomato billing@zomato.com", Category: "Food Delivery"},
+		},
+		totals: store.TransactionListResult{Total: 2, TotalAmount: 450},
+	}
+	service := newTransactionQueryServiceForTest(t, client, queryStore,
+		[]llm.Capability{llm.CapabilityTextGeneration, llm.CapabilityTools})
+
+	result, err := service.QueryTransactions(context.Background(), store.Tenant{ID: "tenant-a"}, TransactionQueryInput{
+		Question:     " How much did we spend on food delivery in March? ",
+		Timezone:     "Asia/Kolkata",
+		BaseCurrency: "inr",
+	})
+	if err != nil {
+		t.Fatalf("QueryTransactions() error = %v", err)
+	}
+	if result.Answer != "You spent INR 450.00 on food delivery in March." {
+		t.Fatalf("answer = %q", result.Answer)
+	}
+	if len(result.Queries) != 2 || result.Queries[0].Tool != "list_transactions" || result.Queries[1].Tool != "monthly_breakdown" {
+		t.Fatalf("queries = %+v, want list_transactions then monthly_breakdown", result.Queries)
+	}
+	if !strings.Contains(string(result.Queries[0].Result), `"total_amount":450`) {
+		t.Fatalf("list result = %s, want total amount", result.Queries[0].Result)
+	}
+	if !strings.Contains(string(result.Queries[0].Result), `"merchant":"Swiggy [REDACTED] [REDACTED]"`) {
+		t.Fatalf("list result = %s, want redacted email and card number", result.Queries[0].Result)
+	}
+	if !json.Valid(result.Queries[0].Result) || !strings.Contains(string(result.Queries[0].Result), `"id":"11111111-2222-3333-4444-555555555555"`) {
+		t.Fatalf("list result = %s, want valid JSON with the transaction ID intact", result.Queries[0].Result)
+	}
+	if strings.Count(string(result.Queries[0].Result), `"id":`) != 1 {
+		t.Fatalf("list result = %s, want limit applied", result.Queries[0].Result)
+	}
+
+	if len(queryStore.filters) != 1 {
+		t.Fatalf("list calls = %d, want 1", len(queryStore.filters))
+	}
+	filter := queryStore.filters[0]
+	location, _ := time.LoadLocation("Asia/Kolkata")
+	wantFrom := time.Date(2026, 3, 1, 0, 0, 0, 0, location)
+	wantTo := time.Date(2026, 4, 1, 0, 0, 0, 0, location).Add(-time.Nanosecond)
+	if filter.From == nil || !filter.From.Equal(wantFrom) || filter.To == nil || !filter.To.Equal(wantTo) {
+		t.Fatalf("filter range = %v..%v, want %v..%v", filter.From, filter.To, wantFrom, wantTo)
+	}
+	if filter.Category != "Food Delivery" || filter.Timezone != "Asia/Kolkata" {
+		t.Fatalf("filter = %+v, want category and timezone", filter)
+	}
+	if strings.Join(queryStore.breakdowns, ",") != "categories" {
+		t.Fatalf("breakdowns = %#v, want categories", queryStore.breakdowns)
+	}
+
+	if len(client.requests) != 3 {
+		t.Fatalf("requests = %d, want 3", len(client.requests))
+	}
+	first := client.requests[0]
+	if len(first.Tools) != 3 || first.Tools[0].Name != "list_transactions" {
+		t.Fatalf("tools = %+v, want read-only tool set", first.Tools)
+	}
+	if !strings.Contains(first.Messages[0].Content, "Today is 2026-04-02 in Asia/Kolkata; totals in INR.") {
+		t.Fatalf("system prompt = %q", first.Messages[0].Content)
+	}
+	last := client.requests[2].Messages
+	toolMessage := last[len(last)-1]
+	if toolMessage.Role != llm.RoleTool || toolMessage.ToolCallID != "call_2" || toolMessage.Name != "monthly_breakdown" {
+		t.Fatalf("last message = %+v, want monthly_breakdown tool result", toolMessage)
+	}
+}
+
+func TestTransactionQueryServiceReturnsToolErrorsToModel(t *testing.T) {
+	client := &scriptedToolClient{responses: []llm.Response{
+		toolCallResponse(
+			llm.ToolCall{ID: "call_1", Name: "delete_transactions", Arguments: json.RawMessage(`{}`)},
+			llm.ToolCall{ID: "call_2", Name: "list_transactions", Arguments: json.RawMessage(`{"from":"March"}`)},
+		),
+		{Text: "I can only read transactions."},
+	}}
+	queryStore := &transactionQueryStoreStub{}
+	service := newTransactionQueryServiceForTest(t, client, queryStore,
+		[]llm.Capability{llm.CapabilityTextGeneration, llm.CapabilityTools})
+
+	result, err := service.QueryTransactions(context.Background(), store.Tenant{ID: "tenant-a"}, TransactionQueryInput{
+		Question: "Delete my March transactions",
+	})
+	if err != nil {
+		t.Fatalf("QueryTransactions() error = %v", err)
+	}
+	if len(result.Queries) != 2 {
+		t.Fatalf("queries = %+v, want two failed steps", result.Queries)
+	}
+	if result.Queries[0].Error != "unknown tool delete_transactions" {
+		t.Fatalf("unknown tool error = %q", result.Queries[0].Error)
+	}
+	if !strings.Contains(result.Queries[1].Error, "YYYY-MM-DD") {
+		t.Fatalf("argument error = %q, want date format hint", result.Queries[1].Error)
+	}
+	if len(queryStore.filters) != 0 {
+		t.Fatalf("list calls = %d, want none", len(queryStore.filters))
+	}
+	toolMessages := client.requests[1].Messages
+	if got := toolMessages[len(toolMessages)-2].Content; got != `{"error":"unknown tool delete_transactions"}` {
+		t.Fatalf("tool message = %s, want error payload", got)
+	}
+}
+
+func TestTransactionQueryServiceRejectsMutatingTools(t *testing.T) {
+	queryStore := &transactionQueryStoreStub{}
+	service := newTransactionQueryServiceForTest(t, &scriptedToolClient{}, queryStore,
+		[]llm.Capability{llm.CapabilityTextGeneration, llm.CapabilityTools})
+	tools := map[string]transactionQueryTool{
+		"mute_transaction": {
+			definition: llm.Tool{Name: "mute_transaction"},
+			run: func(context.Context, *TransactionQueryService, transactionQueryCall) (any, error) {
+				t.Fatal("mutating tool must not run")
+				return nil, nil
+			},
+		},
+	}
+
+	_, err := service.runTool(context.Background(), tools, llm.ToolCall{ID: "call_1", Name: "mute_transaction"}, transactionQueryCall{
+		tenant:   store.Tenant{ID: "tenant-a"},
+		location: time.UTC,
+	})
+	if errors.WhatKind(err) != errors.PermissionDenied {
+		t.Fatalf("runTool() error = %v, want PermissionDenied", err)
+	}
+}
+
+func TestTransactionQueryServiceAllowsOnlyReadOnlyTools(t *testing.T) {
+	service := newTransactionQueryServiceForTest(t, &scriptedToolClient{}, &transactionQueryStoreStub{},
+		[]llm.Capability{llm.CapabilityTextGeneration, llm.CapabilityTools})
+	tools := transactionQueryTools()
+	tools["mute_transaction"] = transactionQueryTool{
+		definition: llm.Tool{Name: "mute_transaction"},
+		run: func(context.Context, *TransactionQueryService, transactionQueryCall) (any, error) {
+			t.Fatal("mutating tool must not run")
+			return nil, nil
+		},
+	}
+
+	for name := range tools {
+		t.Run(name, func(t *testing.T) {
+			arguments := json.RawMessage(`{}`)
+			if name == "monthly_breakdown" {
+				arguments = json.RawMessage(`{"dimension":"categories"}`)
+			}
+			call := llm.ToolCall{ID: "call_1", Name: name, Arguments: arguments}
+			_, err := service.runTool(context.Background(), tools, call, transactionQueryCall{
+				tenant:   store.Tenant{ID: "tenant-a"},
+				location: time.UTC,
+			})
+			denied := errors.WhatKind(err) == errors.PermissionDenied
+			if denied != (name == "mute_transaction") {
+				t.Fatalf("runTool(%s) error = %v, want PermissionDenied only for the mutating tool", name, err)
+			}
+		})
+	}
+}
+
+func TestTransactionQueryServiceStopsAfterStepLimit(t *testing.T) {
+	responses := make([]llm.Response, 0, maxTransactionQuerySteps)
+	for range maxTransactionQuerySteps {
+		responses = append(responses, toolCallResponse(llm.ToolCall{ID: "call", Name: "get_facets"}))
+	}
+	client := &scriptedToolClient{responses: responses}
+	service := newTransactionQueryServiceForTest(t, client, &transactionQueryStoreStub{},
+		[]llm.Capability{llm.CapabilityTextGeneration, llm.CapabilityTools})
+
+	_, err := service.QueryTransactions(context.Background(), store.Tenant{ID: "tenant-a"}, TransactionQueryInput{Question: "What categories exist?"})
+	if errors.WhatKind(err) != KindTransactionQueryStepLimit {
+		t.Fatalf("QueryTransactions() error = %v, want KindTransactionQueryStepLimit", err)
+	}
+	if len(client.requests) != maxTransactionQuerySteps {
+		t.Fatalf("requests = %d, want %d", len(client.requests), maxTransactionQuerySteps)
+	}
+}
+
+func TestTransactionQueryServiceValidatesInputAndCapabilities(t *testing.T) {
+	t.Run("empty question", func(t *testing.T) {
+		client := &scriptedToolClient{}
+		service := newTransactionQueryServiceForTest(t, client, &transactionQueryStoreStub{},
+			[]llm.Capability{llm.CapabilityTextGeneration, llm.CapabilityTools})
+
+		_, err := service.QueryTransactions(context.Background(), store.Tenant{ID: "tenant-a"}, TransactionQueryInput{Question: "  "})
+		if errors.WhatKind(err) != KindTransactionQueryInvalidInput {
+			t.Fatalf("QueryTransactions() error = %v, want KindTransactionQueryInvalidInput", err)
+		}
+		if len(client.requests) != 0 {
+			t.Fatalf("requests = %d, want no provider call", len(client.requests))
+		}
+	})
+
+	t.Run("provider without tools", func(t *testing.T) {
+		service := newTransactionQueryServiceForTest(t, &scriptedToolClient{}, &transactionQueryStoreStub{},
+			[]llm.Capability{llm.CapabilityTextGeneration})
+
+		_, err := service.QueryTransactions(context.Background(), store.Tenant{ID: "tenant-a"}, TransactionQueryInput{Question: "Total spend?"})
+		if errors.WhatKind(err) != llm.KindCapabilityUnsupported {
+			t.Fatalf("QueryTransactions() error = %v, want KindCapabilityUnsupported", err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+// Package audit enforces the retention setting of the instance audit log.
+// Events themselves are recorded by the HTTP handlers through the store.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// pruneInterval is how often retention is applied. Events outlive their
+// retention by at most this long.
+const pruneInterval = time.Hour
+
+// Dependencies configures a Pruner.
+type Dependencies struct {
+	Store  store.AuditStore
+	Logger *slog.Logger
+	Now    func() time.Time
+}
+
+// Pruner removes audit events older than the admin's retention setting.
+type Pruner struct {
+	store  store.AuditStore
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// New constructs a Pruner without starting it.
+func New(deps Dependencies) (*Pruner, error) {
+	if deps.Store == nil {
+		return nil, errors.E("audit.new", errors.FailedPrecondition, "audit store is required")
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &Pruner{store: deps.Store, logger: logger.With("component", "audit"), now: now}, nil
+}
+
+// Run blocks while pruning on startup and then every pruneInterval.
+func (p *Pruner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		if removed, err := p.Prune(ctx); err != nil {
+			p.logger.Warn("failed to prune audit events", "error", err)
+		} else if removed > 0 {
+			p.logger.Info("pruned audit events", "removed", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune removes events older than the retention setting and reports how
+// many were removed. Nothing is removed while retention is zero.
+func (p *Pruner) Prune(ctx context.Context) (int64, error) {
+	settings, err := p.store.GetAuditSettings(ctx)
+	if err != nil {
+		return 0, errors.E("audit.prune", err)
+	}
+	if settings.RetentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := p.now().AddDate(0, 0, -settings.RetentionDays)
+	removed, err := p.store.PruneAuditEvents(ctx, cutoff)
+	if err != nil {
+		return 0, errors.E("audit.prune", err)
+	}
+	return removed, nil
+}
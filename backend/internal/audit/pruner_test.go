@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type fakeStore struct {
+	store.AuditStore
+	settings store.AuditSettings
+	err      error
+	cutoffs  []time.Time
+}
+
+func (s *fakeStore) GetAuditSettings(context.Context) (store.AuditSettings, error) {
+	return s.settings, s.err
+}
+
+func (s *fakeStore) PruneAuditEvents(_ context.Context, cutoff time.Time) (int64, error) {
+	s.cutoffs = append(s.cutoffs, cutoff)
+	return 4, nil
+}
+
+var now = time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+func newTestPruner(t *testing.T, st *fakeStore) *Pruner {
+	t.Helper()
+	p, err := New(Dependencies{Store: st, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return p
+}
+
+func TestPruneRemovesEventsPastRetention(t *testing.T) {
+	st := &fakeStore{settings: store.AuditSettings{RetentionDays: 30}}
+
+	removed, err := newTestPruner(t, st).Prune(context.Background())
+
+	if err != nil || removed != 4 {
+		t.Fatalf("Prune() = %d, %v; want 4, nil", removed, err)
+	}
+	want := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if len(st.cutoffs) != 1 || !st.cutoffs[0].Equal(want) {
+		t.Fatalf("cutoffs = %v, want [%v]", st.cutoffs, want)
+	}
+}
+
+func TestPruneKeepsEverythingWithoutRetention(t *testing.T) {
+	st := &fakeStore{settings: store.AuditSettings{RetentionDays: 0}}
+
+	removed, err := newTestPruner(t, st).Prune(context.Background())
+
+	if err != nil || removed != 0 || len(st.cutoffs) != 0 {
+		t.Fatalf("Prune() = %d, %v with cutoffs %v; want nothing pruned", removed, err, st.cutoffs)
+	}
+}
+
+func TestPruneReportsSettingsFailure(t *testing.T) {
+	st := &fakeStore{err: errors.E(errors.Unavailable, "database down")}
+
+	if _, err := newTestPruner(t, st).Prune(context.Background()); errors.WhatKind(err) != errors.Unavailable {
+		t.Fatalf("Prune() error = %v, want unavailable", err)
+	}
+	if len(st.cutoffs) != 0 {
+		t.Fatalf("cutoffs = %v, want no prune after a failed read", st.cutoffs)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	st := &fakeStore{settings: store.AuditSettings{RetentionDays: 7}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := newTestPruner(t, st).Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+	if len(st.cutoffs) != 1 {
+		t.Fatalf("cutoffs = %v, want one prune on startup", st.cutoffs)
+	}
+}
@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/ArionMiles/expensor/backend/internal/auth"
@@ -12,7 +13,8 @@ func TestPrincipalContextRoundTrip(t *testing.T) {
 		UserID:     "user-1",
 		TenantID:   "tenant-1",
 		Role:       auth.RoleAdmin,
-		AuthMethod: "session",
+		AuthMethod: "bearer",
+		Scopes:     []auth.Scope{auth.ScopeStatsRead},
 	}
 
 	ctx := auth.WithPrincipal(context.Background(), principal)
@@ -20,7 +22,7 @@ func TestPrincipalContextRoundTrip(t *testing.T) {
 	if !ok {
 		t.Fatal("PrincipalFromContext() ok = false")
 	}
-	if got != principal {
+	if !reflect.DeepEqual(got, principal) {
 		t.Fatalf("PrincipalFromContext() = %#v, want %#v", got, principal)
 	}
 }
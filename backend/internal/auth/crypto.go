@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 
@@ -13,6 +17,13 @@ import (
 // SecretKeySize is the required key length for AES-256-GCM.
 const SecretKeySize = 32
 
+// sealedMagic starts every ciphertext that names its key. Ciphertext from
+// before key rotation is a bare nonce and sealed payload.
+const sealedMagic = "EK1"
+
+// secretKeyIDSize is the length of the key ID embedded after sealedMagic.
+const secretKeyIDSize = 8
+
 // SecretAssociatedData binds ciphertext to a tenant, subject, and credential kind.
 type SecretAssociatedData struct {
 	TenantID string
@@ -21,57 +32,172 @@ type SecretAssociatedData struct {
 	Kind     string
 }
 
-// SecretBox seals and opens secrets using authenticated encryption.
+// SecretBox seals and opens secrets using authenticated encryption. It seals
+// with its active key and opens with any key of its keyring, so values
+// sealed before a key rotation stay readable until they are resealed.
 type SecretBox struct {
+	// keys holds the active key first.
+	keys []secretKey
+}
+
+type secretKey struct {
+	id   []byte
 	aead cipher.AEAD
 }
 
-// NewSecretBox creates a SecretBox from a 32-byte key.
-func NewSecretBox(key []byte) (*SecretBox, error) {
-	if len(key) != SecretKeySize {
-		return nil, errors.E(errors.InvalidInput, fmt.Sprintf("secret key must be %d bytes", SecretKeySize))
+// NewSecretBox creates a SecretBox that seals with the 32-byte active key
+// and also opens values sealed with any of the previous keys.
+func NewSecretBox(active []byte, previous ...[]byte) (*SecretBox, error) {
+	box := &SecretBox{}
+	for _, key := range append([][]byte{active}, previous...) {
+		if len(key) != SecretKeySize {
+			return nil, errors.E(errors.InvalidInput, fmt.Sprintf("secret key must be %d bytes", SecretKeySize))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.E("auth.crypto.new_secret_box", "creating AES cipher", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.E("auth.crypto.new_secret_box", "creating GCM", err)
+		}
+		id := secretKeyID(key)
+		if box.key(id) != nil {
+			return nil, errors.E(errors.InvalidInput, "secret keys must be distinct")
+		}
+		box.keys = append(box.keys, secretKey{id: id, aead: aead})
 	}
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, errors.E("auth.crypto.new_secret_box", "creating AES cipher", err)
+	return box, nil
+}
+
+// SecretKeyID returns the identifier embedded in values sealed with key. It
+// is derived from the key without revealing it.
+func SecretKeyID(key []byte) string {
+	return hex.EncodeToString(secretKeyID(key))
+}
+
+func secretKeyID(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("expensor secret key id"))
+	return mac.Sum(nil)[:secretKeyIDSize]
+}
+
+// ActiveKeyID identifies the key new values are sealed with.
+func (b *SecretBox) ActiveKeyID() string {
+	if b == nil || len(b.keys) == 0 {
+		return ""
 	}
-	aead, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, errors.E("auth.crypto.new_secret_box", "creating GCM", err)
+	return hex.EncodeToString(b.keys[0].id)
+}
+
+// SealedKeyID returns the key ID named by ciphertext, or "" for ciphertext
+// sealed before keys had IDs.
+func (b *SecretBox) SealedKeyID(ciphertext []byte) string {
+	if id := sealedKeyID(ciphertext); id != nil {
+		return hex.EncodeToString(id)
 	}
-	return &SecretBox{aead: aead}, nil
+	return ""
 }
 
-// Seal encrypts plaintext and authenticates associated data.
+// Seal encrypts plaintext with the active key and authenticates associated
+// data and the key ID.
 func (b *SecretBox) Seal(plaintext []byte, associated SecretAssociatedData) ([]byte, error) {
-	if b == nil || b.aead == nil {
+	if b == nil || len(b.keys) == 0 {
 		return nil, errors.E(errors.FailedPrecondition, "secret box is not initialized")
 	}
-	nonce := make([]byte, b.aead.NonceSize())
+	key := b.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, errors.E("auth.crypto.seal", "generating nonce", err)
 	}
-	out := make([]byte, 0, len(nonce)+len(plaintext)+b.aead.Overhead())
+	header := append([]byte(sealedMagic), key.id...)
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+key.aead.Overhead())
+	out = append(out, header...)
 	out = append(out, nonce...)
-	out = b.aead.Seal(out, nonce, plaintext, associated.bytes())
+	out = key.aead.Seal(out, nonce, plaintext, append(header, associated.bytes()...))
 	return out, nil
 }
 
-// Open decrypts ciphertext only when associated data matches.
+// Open decrypts ciphertext only when associated data matches. Ciphertext
+// without a key ID is tried with every key.
 func (b *SecretBox) Open(ciphertext []byte, associated SecretAssociatedData) ([]byte, error) {
-	if b == nil || b.aead == nil {
+	if b == nil || len(b.keys) == 0 {
 		return nil, errors.E(errors.FailedPrecondition, "secret box is not initialized")
 	}
-	nonceSize := b.aead.NonceSize()
+	if id := sealedKeyID(ciphertext); id != nil {
+		if key := b.key(id); key != nil {
+			header := ciphertext[:len(sealedMagic)+secretKeyIDSize]
+			plaintext, err := key.open(ciphertext[len(header):], append(bytes.Clone(header), associated.bytes()...))
+			if err != nil {
+				return nil, errors.E("auth.crypto.open", "decrypting secret", err)
+			}
+			return plaintext, nil
+		}
+	}
+	// Legacy ciphertext names no key, and a random nonce can look like a
+	// header with an unknown key ID.
+	var lastErr error
+	for _, key := range b.keys {
+		plaintext, err := key.open(ciphertext, associated.bytes())
+		if err == nil {
+			return plaintext, nil
+		}
+		lastErr = err
+	}
+	if id := sealedKeyID(ciphertext); id != nil {
+		return nil, errors.E("auth.crypto.open", errors.FailedPrecondition,
+			fmt.Sprintf("secret was sealed with unknown key %s", hex.EncodeToString(id)))
+	}
+	return nil, errors.E("auth.crypto.open", "decrypting secret", lastErr)
+}
+
+// Reseal opens ciphertext and seals it again with the active key. It reports
+// false and returns ciphertext unchanged when the active key already sealed
+// it.
+func (b *SecretBox) Reseal(ciphertext []byte, associated SecretAssociatedData) ([]byte, bool, error) {
+	if b == nil || len(b.keys) == 0 {
+		return nil, false, errors.E(errors.FailedPrecondition, "secret box is not initialized")
+	}
+	if id := sealedKeyID(ciphertext); id != nil && bytes.Equal(id, b.keys[0].id) {
+		return ciphertext, false, nil
+	}
+	plaintext, err := b.Open(ciphertext, associated)
+	if err != nil {
+		return nil, false, err
+	}
+	resealed, err := b.Seal(plaintext, associated)
+	if err != nil {
+		return nil, false, err
+	}
+	return resealed, true, nil
+}
+
+func (b *SecretBox) key(id []byte) *secretKey {
+	for i := range b.keys {
+		if bytes.Equal(b.keys[i].id, id) {
+			return &b.keys[i]
+		}
+	}
+	return nil
+}
+
+func (k secretKey) open(ciphertext, additional []byte) ([]byte, error) {
+	nonceSize := k.aead.NonceSize()
 	if len(ciphertext) < nonceSize {
 		return nil, errors.E(errors.InvalidInput, "ciphertext too short")
 	}
 	nonce, sealed := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := b.aead.Open(nil, nonce, sealed, associated.bytes())
-	if err != nil {
-		return nil, errors.E("auth.crypto.open", "decrypting secret", err)
+	return k.aead.Open(nil, nonce, sealed, additional)
+}
+
+// sealedKeyID returns the key ID in ciphertext's header, or nil when it has
+// none.
+func sealedKeyID(ciphertext []byte) []byte {
+	headerSize := len(sealedMagic) + secretKeyIDSize
+	if len(ciphertext) < headerSize || string(ciphertext[:len(sealedMagic)]) != sealedMagic {
+		return nil
 	}
-	return plaintext, nil
+	return ciphertext[len(sealedMagic):headerSize]
 }
 
 func (a SecretAssociatedData) bytes() []byte {
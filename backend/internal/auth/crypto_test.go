@@ -2,6 +2,9 @@ package auth_test
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"strings"
 	"testing"
 
 	"github.com/ArionMiles/expensor/backend/internal/auth"
@@ -25,3 +28,98 @@ func TestSealOpenBindsAssociatedData(t *testing.T) {
 		t.Fatal("Open() succeeded with wrong tenant associated data")
 	}
 }
+
+func TestSecretBoxOpensValuesSealedWithPreviousKeys(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{7}, auth.SecretKeySize)
+	newKey := bytes.Repeat([]byte{9}, auth.SecretKeySize)
+	oldBox, err := auth.NewSecretBox(oldKey)
+	if err != nil {
+		t.Fatalf("NewSecretBox(old) error = %v", err)
+	}
+	rotated, err := auth.NewSecretBox(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewSecretBox(new, old) error = %v", err)
+	}
+	associated := auth.SecretAssociatedData{TenantID: "tenant-a", Scope: "reader", Name: "gmail", Kind: "client_secret"}
+	sealed, err := oldBox.Seal([]byte("secret"), associated)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if got := rotated.SealedKeyID(sealed); got != auth.SecretKeyID(oldKey) {
+		t.Fatalf("SealedKeyID() = %q, want old key %q", got, auth.SecretKeyID(oldKey))
+	}
+	if plaintext, err := rotated.Open(sealed, associated); err != nil || string(plaintext) != "secret" {
+		t.Fatalf("Open() = %q, %v; want secret", plaintext, err)
+	}
+
+	resealed, changed, err := rotated.Reseal(sealed, associated)
+	if err != nil || !changed {
+		t.Fatalf("Reseal() changed = %v, err = %v; want resealed", changed, err)
+	}
+	if got := rotated.SealedKeyID(resealed); got != rotated.ActiveKeyID() || got != auth.SecretKeyID(newKey) {
+		t.Fatalf("resealed key ID = %q, want active %q", got, rotated.ActiveKeyID())
+	}
+	if _, changed, err := rotated.Reseal(resealed, associated); err != nil || changed {
+		t.Fatalf("second Reseal() changed = %v, err = %v; want unchanged", changed, err)
+	}
+	if _, err := oldBox.Open(resealed, associated); err == nil {
+		t.Fatal("old key opened a value resealed with the new key")
+	}
+	if _, err := rotated.Open(resealed, auth.SecretAssociatedData{TenantID: "tenant-b", Scope: "reader", Name: "gmail", Kind: "client_secret"}); err == nil {
+		t.Fatal("Open() succeeded with wrong tenant associated data")
+	}
+}
+
+func TestSecretBoxOpensLegacyCiphertext(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, auth.SecretKeySize)
+	associated := auth.SecretAssociatedData{TenantID: "tenant-a", Scope: "llm_provider", Name: "openai", Kind: "credentials"}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("NewGCM() error = %v", err)
+	}
+	// Before key IDs, ciphertext was the nonce followed by the sealed payload.
+	nonce := bytes.Repeat([]byte{1}, aead.NonceSize())
+	legacy := aead.Seal(bytes.Clone(nonce), nonce, []byte("api-key"), []byte("tenant-a\x00llm_provider\x00openai\x00credentials"))
+
+	box, err := auth.NewSecretBox(bytes.Repeat([]byte{9}, auth.SecretKeySize), key)
+	if err != nil {
+		t.Fatalf("NewSecretBox() error = %v", err)
+	}
+	if got := box.SealedKeyID(legacy); got != "" {
+		t.Fatalf("SealedKeyID(legacy) = %q, want empty", got)
+	}
+	if plaintext, err := box.Open(legacy, associated); err != nil || string(plaintext) != "api-key" {
+		t.Fatalf("Open(legacy) = %q, %v; want api-key", plaintext, err)
+	}
+	if _, changed, err := box.Reseal(legacy, associated); err != nil || !changed {
+		t.Fatalf("Reseal(legacy) changed = %v, err = %v; want resealed", changed, err)
+	}
+}
+
+func TestSecretBoxReportsUnknownKey(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{7}, auth.SecretKeySize)
+	oldBox, err := auth.NewSecretBox(oldKey)
+	if err != nil {
+		t.Fatalf("NewSecretBox(old) error = %v", err)
+	}
+	associated := auth.SecretAssociatedData{TenantID: "tenant-a", Scope: "user", Name: "user-a", Kind: "totp_secret"}
+	sealed, err := oldBox.Seal([]byte("totp"), associated)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	newBox, err := auth.NewSecretBox(bytes.Repeat([]byte{9}, auth.SecretKeySize))
+	if err != nil {
+		t.Fatalf("NewSecretBox(new) error = %v", err)
+	}
+	_, err = newBox.Open(sealed, associated)
+	if err == nil || !strings.Contains(err.Error(), auth.SecretKeyID(oldKey)) {
+		t.Fatalf("Open() error = %v, want it to name key %s", err, auth.SecretKeyID(oldKey))
+	}
+	if _, err := auth.NewSecretBox(oldKey, oldKey); err == nil {
+		t.Fatal("NewSecretBox() accepted the same key twice")
+	}
+}
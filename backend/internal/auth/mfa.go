@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 TOTP uses HMAC-SHA1, which authenticator apps assume.
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// TOTP parameters are the defaults every authenticator app assumes: SHA-1,
+// six digits and 30-second steps.
+const (
+	totpSecretBytes = 20
+	totpDigits      = 6
+	totpPeriod      = 30
+	// totpSkew is how many steps either side of now a code is accepted,
+	// allowing for clock drift and slow typing.
+	totpSkew = 1
+)
+
+const (
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	recoveryCodeLength   = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random TOTP shared secret.
+func NewTOTPSecret() ([]byte, error) {
+	secret := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.E("auth.mfa.new_totp_secret", "generating TOTP secret", err)
+	}
+	return secret, nil
+}
+
+// TOTPSecretString encodes secret for typing into an authenticator app.
+func TOTPSecretString(secret []byte) string {
+	return totpEncoding.EncodeToString(secret)
+}
+
+// TOTPURL returns the otpauth:// URL that enrollment shows as a QR code.
+func TOTPURL(issuer, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", TOTPSecretString(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// TOTPStep returns the TOTP time step t falls in.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// TOTPCode returns the code for secret at step.
+func TOTPCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step)) //nolint:gosec // Steps are positive Unix time divisions.
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// MatchTOTP returns the time step code was generated for, trying the steps
+// within totpSkew of now. Callers must refuse steps at or before the last
+// one accepted so that a code cannot be replayed.
+func MatchTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := TOTPStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(TOTPCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// NewRecoveryCodes returns n single-use recovery codes formatted as
+// xxxxx-xxxxx.
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	random := make([]byte, recoveryCodeLength)
+	for range n {
+		if _, err := rand.Read(random); err != nil {
+			return nil, errors.E("auth.mfa.new_recovery_codes", "generating recovery codes", err)
+		}
+		var b strings.Builder
+		for i, v := range random {
+			if i == recoveryCodeLength/2 {
+				b.WriteByte('-')
+			}
+			// 256 is a multiple of the alphabet size, so this is unbiased.
+			b.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+		}
+		codes = append(codes, b.String())
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the stored lookup hash for a recovery code. Case,
+// spaces and dashes are ignored so codes can be typed loosely.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	return HashOpaqueToken(normalized)
+}
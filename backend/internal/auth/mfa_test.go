@@ -0,0 +1,87 @@
+package auth_test
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+)
+
+// rfc6238Secret is the SHA-1 key from RFC 6238 appendix B. The expected
+// codes are the last six digits of its eight-digit test vectors.
+var rfc6238Secret = []byte("12345678901234567890")
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range tests {
+		if got := auth.TOTPCode(rfc6238Secret, auth.TOTPStep(time.Unix(unix, 0))); got != want {
+			t.Errorf("TOTPCode(%d) = %q, want %q", unix, got, want)
+		}
+	}
+}
+
+func TestMatchTOTPAllowsOneStepOfDrift(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := auth.TOTPStep(now)
+
+	for _, offset := range []int64{-1, 0, 1} {
+		got, ok := auth.MatchTOTP(rfc6238Secret, auth.TOTPCode(rfc6238Secret, step+offset), now)
+		if !ok || got != step+offset {
+			t.Fatalf("MatchTOTP(step%+d) = %d, %v", offset, got, ok)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		if _, ok := auth.MatchTOTP(rfc6238Secret, auth.TOTPCode(rfc6238Secret, step+offset), now); ok {
+			t.Fatalf("MatchTOTP(step%+d) accepted", offset)
+		}
+	}
+	if _, ok := auth.MatchTOTP(rfc6238Secret, "081 804", now); !ok {
+		t.Fatal("MatchTOTP rejected a code typed with a space")
+	}
+	if _, ok := auth.MatchTOTP(rfc6238Secret, "81804", now); ok {
+		t.Fatal("MatchTOTP accepted a short code")
+	}
+}
+
+func TestTOTPURL(t *testing.T) {
+	parsed, err := url.Parse(auth.TOTPURL("Expensor", "asha@example.com", rfc6238Secret))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/Expensor:asha@example.com" {
+		t.Fatalf("URL = %s", parsed)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" || query.Get("issuer") != "Expensor" || query.Get("digits") != "6" {
+		t.Fatalf("query = %v", query)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := auth.NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !format.MatchString(code) || seen[code] {
+			t.Fatalf("codes = %v", codes)
+		}
+		seen[code] = true
+	}
+	if len(codes) != 10 {
+		t.Fatalf("len(codes) = %d", len(codes))
+	}
+	if auth.HashRecoveryCode(" ABCDE fghij ") != auth.HashRecoveryCode("abcde-fghij") {
+		t.Fatal("HashRecoveryCode is sensitive to case or separators")
+	}
+}
@@ -11,10 +11,36 @@ const (
 	RoleUser Role = "user"
 )
 
+// TenantRole identifies what a user may do inside one tenant.
+type TenantRole string
+
+const (
+	// TenantRoleOwner can change tenant data and manage its members.
+	TenantRoleOwner TenantRole = "owner"
+	// TenantRoleEditor can change tenant data.
+	TenantRoleEditor TenantRole = "editor"
+	// TenantRoleViewer can only read tenant data.
+	TenantRoleViewer TenantRole = "viewer"
+)
+
 // Principal is the authenticated account identity attached to a request.
+// TenantID is the tenant selected for the request and TenantRole the user's
+// role inside it.
 type Principal struct {
 	UserID     string
 	TenantID   string
+	TenantRole TenantRole
 	Role       Role
+	// AuthMethod is how the request authenticated: session, bearer or proxy.
 	AuthMethod string
+	// Scopes restricts bearer token requests; nil allows every route.
+	Scopes []Scope
+	// SessionID is the browser session behind a session request.
+	SessionID string
+}
+
+// CanWriteTenant reports whether the principal may change data in the
+// selected tenant.
+func (p Principal) CanWriteTenant() bool {
+	return p.TenantRole != TenantRoleViewer
 }
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+)
+
+// Scope limits what a personal access token may do.
+type Scope string
+
+const (
+	// ScopeTransactionsRead reads transactions, attachments, exports and
+	// shared ledgers.
+	ScopeTransactionsRead Scope = "transactions:read"
+	// ScopeTransactionsWrite changes transactions, shared ledgers and muted
+	// merchants.
+	ScopeTransactionsWrite Scope = "transactions:write"
+	// ScopeStatsRead reads dashboard statistics and charts.
+	ScopeStatsRead Scope = "stats:read"
+	// ScopeRulesRead reads rules, labels, categories and buckets.
+	ScopeRulesRead Scope = "rules:read"
+	// ScopeRulesWrite changes rules, labels, categories and buckets.
+	ScopeRulesWrite Scope = "rules:write"
+	// ScopeReadersRead reads reader configuration, status and diagnostics.
+	ScopeReadersRead Scope = "readers:read"
+	// ScopeReadersWrite configures readers and starts scans.
+	ScopeReadersWrite Scope = "readers:write"
+	// ScopeSettingsRead reads tenant preferences and LLM providers.
+	ScopeSettingsRead Scope = "settings:read"
+	// ScopeSettingsWrite changes tenant preferences and LLM providers.
+	ScopeSettingsWrite Scope = "settings:write"
+	// ScopeAccount manages the user's profile, tokens and tenants.
+	ScopeAccount Scope = "account"
+	// ScopeAdmin reaches instance administration routes. It grants nothing
+	// to users who are not admins.
+	ScopeAdmin Scope = "admin"
+)
+
+// AllScopes lists every scope, in the order they are documented.
+var AllScopes = []Scope{
+	ScopeTransactionsRead,
+	ScopeTransactionsWrite,
+	ScopeStatsRead,
+	ScopeRulesRead,
+	ScopeRulesWrite,
+	ScopeReadersRead,
+	ScopeReadersWrite,
+	ScopeSettingsRead,
+	ScopeSettingsWrite,
+	ScopeAccount,
+	ScopeAdmin,
+}
+
+// WritesTenant reports whether routes under s change data in the active
+// tenant, which viewers may not. Account and admin routes act on the user or
+// the instance instead.
+func (s Scope) WritesTenant() bool {
+	switch s {
+	case ScopeTransactionsWrite, ScopeRulesWrite, ScopeReadersWrite, ScopeSettingsWrite:
+		return true
+	default:
+		return false
+	}
+}
+
+// Valid reports whether s is a known scope.
+func (s Scope) Valid() bool {
+	return slices.Contains(AllScopes, s)
+}
+
+// Covers reports whether holding s grants want. Write scopes include the
+// matching read scope.
+func (s Scope) Covers(want Scope) bool {
+	if s == want {
+		return true
+	}
+	resource, access, ok := strings.Cut(string(want), ":")
+	return ok && access == "read" && s == Scope(resource+":write")
+}
+
+// HasScope reports whether the principal may use routes that need scope.
+// Sessions carry no scope list and may use every route.
+func (p Principal) HasScope(scope Scope) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	return slices.ContainsFunc(p.Scopes, func(held Scope) bool { return held.Covers(scope) })
+}
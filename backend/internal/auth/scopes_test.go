@@ -0,0 +1,57 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+)
+
+func TestPrincipalHasScope(t *testing.T) {
+	session := auth.Principal{}
+	readOnly := auth.Principal{Scopes: []auth.Scope{auth.ScopeStatsRead, auth.ScopeTransactionsRead}}
+	writer := auth.Principal{Scopes: []auth.Scope{auth.ScopeRulesWrite}}
+	none := auth.Principal{Scopes: []auth.Scope{}}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		scope     auth.Scope
+		want      bool
+	}{
+		{name: "session allows everything", principal: session, scope: auth.ScopeAdmin, want: true},
+		{name: "held scope", principal: readOnly, scope: auth.ScopeStatsRead, want: true},
+		{name: "missing write scope", principal: readOnly, scope: auth.ScopeTransactionsWrite, want: false},
+		{name: "write covers read", principal: writer, scope: auth.ScopeRulesRead, want: true},
+		{name: "write covers only its resource", principal: writer, scope: auth.ScopeTransactionsRead, want: false},
+		{name: "empty list allows nothing", principal: none, scope: auth.ScopeStatsRead, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.HasScope(tt.scope); got != tt.want {
+				t.Fatalf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeValid(t *testing.T) {
+	for _, scope := range auth.AllScopes {
+		if !scope.Valid() {
+			t.Fatalf("%q is not valid", scope)
+		}
+	}
+	if auth.Scope("transactions:delete").Valid() {
+		t.Fatal("unknown scope is valid")
+	}
+}
+
+func TestScopeWritesTenant(t *testing.T) {
+	for _, scope := range []auth.Scope{auth.ScopeTransactionsRead, auth.ScopeStatsRead, auth.ScopeAccount, auth.ScopeAdmin} {
+		if scope.WritesTenant() {
+			t.Fatalf("%q writes tenant data", scope)
+		}
+	}
+	if !auth.ScopeSettingsWrite.WritesTenant() {
+		t.Fatalf("%q does not write tenant data", auth.ScopeSettingsWrite)
+	}
+}
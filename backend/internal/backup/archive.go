@@ -0,0 +1,271 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// Format identifies an Expensor database backup.
+	Format = "expensor.database-backup"
+	// FormatVersion is the archive layout this build writes and reads.
+	FormatVersion = 1
+
+	manifestEntry    = "manifest.json"
+	tableEntryPrefix = "tables/"
+	tableEntrySuffix = ".copy"
+)
+
+// Manifest is the first entry of a backup archive. It describes the schema
+// the rows were copied from and the SHA-256 of every table entry.
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// AppVersion is the Expensor build that wrote the backup.
+	AppVersion string `json:"app_version"`
+	// KeyFingerprint identifies the secret key that encrypted credentials in
+	// the backup, without revealing it.
+	KeyFingerprint string               `json:"key_fingerprint,omitempty"`
+	Schema         store.DatabaseSchema `json:"schema"`
+	Checksums      map[string]string    `json:"checksums"`
+}
+
+// keyFingerprint derives a stable, non-reversible identifier for key.
+func keyFingerprint(key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("expensor backup key fingerprint"))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+func tableEntry(table string) string {
+	return tableEntryPrefix + table + tableEntrySuffix
+}
+
+// spool collects table copies in a temporary directory, since a tar header
+// needs the entry size before the content.
+type spool struct {
+	dir    string
+	files  map[string]*spoolFile
+	tables []string
+	// archiveFile holds a whole archive on its way to or from the target.
+	archiveFile *os.File
+}
+
+type spoolFile struct {
+	path string
+	file *os.File
+	hash hash.Hash
+	size int64
+}
+
+func (f *spoolFile) Write(p []byte) (int, error) {
+	n, err := f.file.Write(p)
+	f.hash.Write(p[:n])
+	f.size += int64(n)
+	return n, err
+}
+
+func newSpool() (*spool, error) {
+	dir, err := os.MkdirTemp("", "expensor-backup-*")
+	if err != nil {
+		return nil, errors.E("backup.spool", "creating temporary directory", err)
+	}
+	return &spool{dir: dir, files: map[string]*spoolFile{}}, nil
+}
+
+func (s *spool) open(table store.BackupTable) (io.Writer, error) {
+	if _, ok := s.files[table.Name]; ok {
+		return nil, errors.E("backup.spool", errors.Internal, fmt.Sprintf("table %s copied twice", table.Name))
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%d%s", len(s.tables), tableEntrySuffix))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.E("backup.spool", "creating table file", err)
+	}
+	f := &spoolFile{path: path, file: file, hash: sha256.New()}
+	s.files[table.Name] = f
+	s.tables = append(s.tables, table.Name)
+	return f, nil
+}
+
+func (s *spool) reader(table store.BackupTable) (io.Reader, error) {
+	f, ok := s.files[table.Name]
+	if !ok {
+		return nil, errors.E("backup.spool", errors.InvalidInput, fmt.Sprintf("table %s is missing from the backup", table.Name))
+	}
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, errors.E("backup.spool", "opening table file", err)
+	}
+	f.file = file
+	return file, nil
+}
+
+// archive creates the file an archive is kept in between the target and the
+// database, so it never has to fit in memory.
+func (s *spool) archive() (*os.File, error) {
+	if s.archiveFile != nil {
+		return nil, errors.E("backup.spool", errors.Internal, "archive spooled twice")
+	}
+	file, err := os.OpenFile(filepath.Join(s.dir, "archive.tar.gz"), os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, errors.E("backup.spool", "creating archive file", err)
+	}
+	s.archiveFile = file
+	return file, nil
+}
+
+func (s *spool) checksum(table string) string {
+	return hex.EncodeToString(s.files[table].hash.Sum(nil))
+}
+
+func (s *spool) close() {
+	for _, f := range s.files {
+		if f.file != nil {
+			_ = f.file.Close()
+		}
+	}
+	if s.archiveFile != nil {
+		_ = s.archiveFile.Close()
+	}
+	_ = os.RemoveAll(s.dir)
+}
+
+// writeArchive writes manifest and the spooled tables as a gzip-compressed
+// tar to w.
+func (s *spool) writeArchive(w io.Writer, manifest Manifest) error {
+	const op = "backup.write_archive"
+
+	manifest.Checksums = make(map[string]string, len(s.tables))
+	for _, table := range s.tables {
+		manifest.Checksums[table] = s.checksum(table)
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.E(op, "encoding manifest", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	header := func(name string, size int64) *tar.Header {
+		return &tar.Header{Name: name, Mode: 0o600, Size: size, ModTime: manifest.CreatedAt, Typeflag: tar.TypeReg}
+	}
+	if err := tw.WriteHeader(header(manifestEntry, int64(len(manifestJSON)))); err != nil {
+		return errors.E(op, "writing manifest header", err)
+	}
+	if _, err := tw.Write(manifestJSON); err != nil {
+		return errors.E(op, "writing manifest", err)
+	}
+	for _, table := range s.tables {
+		f := s.files[table]
+		if err := f.file.Close(); err != nil {
+			return errors.E(op, "closing table file", err)
+		}
+		f.file = nil
+		if err := tw.WriteHeader(header(tableEntry(table), f.size)); err != nil {
+			return errors.E(op, fmt.Sprintf("writing %s header", table), err)
+		}
+		file, err := os.Open(f.path)
+		if err != nil {
+			return errors.E(op, "opening table file", err)
+		}
+		_, err = io.Copy(tw, file)
+		_ = file.Close()
+		if err != nil {
+			return errors.E(op, fmt.Sprintf("writing %s", table), err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return errors.E(op, "closing archive", err)
+	}
+	if err := gz.Close(); err != nil {
+		return errors.E(op, "compressing archive", err)
+	}
+	return nil
+}
+
+// readArchive unpacks an archive into s and returns its manifest after
+// checking the format and every table checksum.
+func (s *spool) readArchive(r io.Reader) (Manifest, error) {
+	const op = "backup.read_archive"
+	invalid := func(msg string, args ...any) error {
+		return errors.E(op, errors.InvalidInput, errors.User(fmt.Sprintf(msg, args...)))
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, errors.E(op, errors.InvalidInput, errors.User("The backup is not a gzip archive."), err)
+	}
+	tr := tar.NewReader(gz)
+
+	first, err := tr.Next()
+	if err != nil || first.Name != manifestEntry {
+		return Manifest{}, invalid("The backup does not start with %s.", manifestEntry)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return Manifest{}, errors.E(op, errors.InvalidInput, errors.User("The backup manifest is not valid JSON."), err)
+	}
+	if manifest.Format != Format {
+		return Manifest{}, invalid("Not an Expensor database backup: format is %q.", manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return Manifest{}, invalid("Backup format version %d is not supported; this build reads versions 1 to %d.", manifest.Version, FormatVersion)
+	}
+
+	tables := make(map[string]store.BackupTable, len(manifest.Schema.Tables))
+	for _, table := range manifest.Schema.Tables {
+		tables[table.Name] = table
+	}
+	for {
+		entry, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, errors.E(op, errors.InvalidInput, errors.User("The backup archive is truncated or corrupt."), err)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(entry.Name, tableEntryPrefix), tableEntrySuffix)
+		table, ok := tables[name]
+		if !ok || entry.Name != tableEntry(name) {
+			return Manifest{}, invalid("The backup contains an unexpected entry %q.", entry.Name)
+		}
+		w, err := s.open(table)
+		if err != nil {
+			return Manifest{}, err
+		}
+		if _, err := io.Copy(w, tr); err != nil {
+			return Manifest{}, errors.E(op, errors.InvalidInput, errors.User("The backup archive is truncated or corrupt."), err)
+		}
+		if got := s.checksum(name); got != manifest.Checksums[name] {
+			return Manifest{}, invalid("Table %s failed checksum verification.", name)
+		}
+	}
+	for name := range tables {
+		if _, ok := s.files[name]; !ok {
+			return Manifest{}, invalid("Table %s is listed in the manifest but missing from the backup.", name)
+		}
+	}
+	return manifest, nil
+}
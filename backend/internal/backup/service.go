@@ -0,0 +1,489 @@
+// Package backup writes consistent logical backups of the database to a
+// local directory or an S3-compatible bucket, prunes them by the admin's
+// retention settings, and restores them.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/blob"
+	"github.com/ArionMiles/expensor/backend/internal/observability"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/config"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	namePrefix     = "expensor-backup-"
+	nameSuffix     = ".tar.gz"
+	checksumSuffix = ".sha256"
+	nameTimeLayout = "20060102T150405Z"
+)
+
+var namePattern = regexp.MustCompile(`^expensor-backup-\d{8}T\d{6}Z\.tar\.gz$`)
+
+// Target is where backups are kept.
+type Target interface {
+	blob.Store
+	blob.Lister
+	blob.Streamer
+}
+
+// OpenTarget returns the Target cfg selects and the key prefix backups are
+// stored under. The s3 backend reuses the attachment S3 connection settings.
+func OpenTarget(cfg config.Backup, s3 config.BlobS3) (Target, string, error) {
+	switch cfg.Backend {
+	case "", config.BlobBackendLocal:
+		target, err := blob.NewLocal(cfg.Dir)
+		return target, "", err
+	case config.BlobBackendS3:
+		bucket := cfg.S3Bucket
+		if bucket == "" {
+			bucket = s3.Bucket
+		}
+		target, err := blob.NewS3(blob.S3Options{
+			Endpoint:        s3.Endpoint,
+			Region:          s3.Region,
+			Bucket:          bucket,
+			AccessKeyID:     s3.AccessKeyID,
+			SecretAccessKey: s3.SecretAccessKey,
+			PathStyle:       s3.PathStyle,
+		})
+		prefix := strings.Trim(cfg.S3Prefix, "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+		return target, prefix, err
+	default:
+		return nil, "", errors.E("backup.open_target", errors.InvalidArgument, fmt.Sprintf("unsupported backup backend %q", cfg.Backend))
+	}
+}
+
+// ResolverRefresher reloads category mappings after a restore replaced them.
+type ResolverRefresher interface {
+	RefreshResolver(ctx context.Context)
+}
+
+// Dependencies configures a Service.
+type Dependencies struct {
+	Config config.Backup
+	Target Target
+	// Prefix is prepended to every backup key in Target.
+	Prefix    string
+	Store     store.BackupStore
+	SecretKey []byte
+	// PreviousSecretKeys are rotated-out keys that still decrypt values, so
+	// backups taken with them restore without a key warning.
+	PreviousSecretKeys [][]byte
+	AppVersion         string
+	Resolver           ResolverRefresher
+	Logger             *slog.Logger
+	Scope              *observability.Scope
+	Now                func() time.Time
+}
+
+// Info describes a stored backup.
+type Info struct {
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// RestoreResult reports what a restore checked and, unless it was a dry run,
+// did.
+type RestoreResult struct {
+	Backup               string
+	DryRun               bool
+	CreatedAt            time.Time
+	AppVersion           string
+	SchemaVersion        int64
+	CurrentSchemaVersion int64
+	Tables               int
+	Rows                 int64
+	// Problems are schema mismatches; a backup with problems is not restored.
+	Problems []string
+	// Warnings do not block a restore.
+	Warnings []string
+	// SafetyBackup is the backup of the replaced data, taken just before
+	// restoring.
+	SafetyBackup string
+	Restored     bool
+}
+
+// Service owns scheduled and manual backups and restores.
+type Service struct {
+	config      config.Backup
+	target      Target
+	prefix      string
+	store       store.BackupStore
+	fingerprint string
+	// previousFingerprints identify PreviousSecretKeys.
+	previousFingerprints []string
+	appVersion           string
+	resolver             ResolverRefresher
+	logger               *slog.Logger
+	scope                *observability.Scope
+	now                  func() time.Time
+	// busy serializes backups and restores within this process.
+	busy sync.Mutex
+}
+
+// New constructs a Service without starting workers.
+func New(deps Dependencies) (*Service, error) {
+	if deps.Store == nil || deps.Target == nil {
+		return nil, errors.E("backup.new", errors.FailedPrecondition, "backup store and target are required")
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	scope := deps.Scope
+	if scope == nil {
+		scope = observability.NewScope(logger, "github.com/ArionMiles/expensor/backend/internal/backup")
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	var previousFingerprints []string
+	for _, key := range deps.PreviousSecretKeys {
+		previousFingerprints = append(previousFingerprints, keyFingerprint(key))
+	}
+	return &Service{
+		config:               deps.Config,
+		target:               deps.Target,
+		prefix:               deps.Prefix,
+		store:                deps.Store,
+		fingerprint:          keyFingerprint(deps.SecretKey),
+		previousFingerprints: previousFingerprints,
+		appVersion:           deps.AppVersion,
+		resolver:             deps.Resolver,
+		logger:               logger.With("component", "backup"),
+		scope:                scope,
+		now:                  now,
+	}, nil
+}
+
+// Run blocks while taking scheduled backups. The schedule is checked every
+// poll interval; the store makes sure only one instance takes each backup.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.runScheduled(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) runScheduled(ctx context.Context) {
+	claimed, err := s.store.ClaimScheduledBackup(ctx, s.now())
+	if err != nil {
+		s.logger.Warn("failed to check backup schedule", "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	if _, err := s.Create(ctx); err != nil {
+		s.logger.Warn("scheduled backup failed", "error", err)
+	}
+}
+
+// Create takes a backup now, then applies the retention settings.
+func (s *Service) Create(ctx context.Context) (Info, error) {
+	if !s.busy.TryLock() {
+		return Info{}, errors.E("backup.create", errors.Conflict, errors.User("Another backup or restore is running."))
+	}
+	defer s.busy.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	info, err := s.write(ctx)
+	run := store.BackupRun{FinishedAt: s.now().UTC(), Backup: info.Name}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if recordErr := s.store.RecordBackupRun(ctx, run); recordErr != nil {
+		s.logger.Warn("failed to record backup run", "error", recordErr)
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	s.logger.Info("backup written", "backup", info.Name, "bytes", info.Size)
+
+	if err := s.prune(ctx); err != nil {
+		s.logger.Warn("failed to apply backup retention", "error", err)
+	}
+	return info, nil
+}
+
+// List returns the stored backups, newest first.
+func (s *Service) List(ctx context.Context) ([]Info, error) {
+	objects, err := s.target.List(ctx, s.prefix)
+	if err != nil {
+		return nil, errors.E("backup.list", err)
+	}
+	backups := []Info{}
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, s.prefix)
+		createdAt, ok := parseName(name)
+		if !ok {
+			continue
+		}
+		backups = append(backups, Info{Name: name, Size: object.Size, CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// Restore verifies backup name and checks it against the current schema.
+// Unless dryRun is set it then takes a safety backup and replaces the
+// database contents with the backup.
+func (s *Service) Restore(ctx context.Context, name string, dryRun bool) (RestoreResult, error) {
+	const op = "backup.restore"
+	if _, ok := parseName(name); !ok {
+		return RestoreResult{}, errors.E(op, errors.NotFound, errors.User("backup not found"))
+	}
+	if !s.busy.TryLock() {
+		return RestoreResult{}, errors.E(op, errors.Conflict, errors.User("Another backup or restore is running."))
+	}
+	defer s.busy.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	ctx, span := s.scope.Start(ctx, "backup.restore")
+	defer span.End()
+
+	sp, err := newSpool()
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	defer sp.close()
+	archive, err := sp.archive()
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	if err := s.fetchVerified(ctx, name, archive); err != nil {
+		return RestoreResult{}, err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return RestoreResult{}, errors.E(op, "rewinding backup archive", err)
+	}
+	manifest, err := sp.readArchive(archive)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	check, err := s.store.CheckBackupSchema(ctx, manifest.Schema)
+	if err != nil {
+		return RestoreResult{}, errors.E(op, err)
+	}
+
+	result := RestoreResult{
+		Backup:               name,
+		DryRun:               dryRun,
+		CreatedAt:            manifest.CreatedAt,
+		AppVersion:           manifest.AppVersion,
+		SchemaVersion:        manifest.Schema.Version,
+		CurrentSchemaVersion: check.CurrentVersion,
+		Tables:               len(manifest.Schema.Tables),
+		Problems:             check.Problems,
+		Warnings:             []string{},
+	}
+	if result.Problems == nil {
+		result.Problems = []string{}
+	}
+	for _, table := range manifest.Schema.Tables {
+		result.Rows += table.Rows
+	}
+	if manifest.KeyFingerprint != "" && manifest.KeyFingerprint != s.fingerprint &&
+		!slices.Contains(s.previousFingerprints, manifest.KeyFingerprint) {
+		result.Warnings = append(result.Warnings,
+			"The backup was taken with a different secret key; reader credentials and attachments in it cannot be decrypted.")
+	}
+	if dryRun {
+		return result, nil
+	}
+	if len(result.Problems) > 0 {
+		return RestoreResult{}, errors.E(op, errors.FailedPrecondition,
+			errors.User("The backup does not match the current schema: "+strings.Join(result.Problems, "; ")))
+	}
+
+	safety, err := s.write(ctx)
+	if err != nil {
+		return RestoreResult{}, errors.E(op, "taking safety backup", err)
+	}
+	result.SafetyBackup = safety.Name
+	if err := s.store.RestoreDatabase(ctx, manifest.Schema, sp.reader); err != nil {
+		return RestoreResult{}, errors.E(op, err)
+	}
+	result.Restored = true
+	s.logger.Info("backup restored", "backup", name, "safety_backup", safety.Name, "rows", result.Rows)
+	if s.resolver != nil {
+		s.resolver.RefreshResolver(ctx)
+	}
+	return result, nil
+}
+
+// write dumps the database, uploads the archive and its checksum, and reads
+// the archive back to verify the upload. The archive is spooled to disk on
+// the way.
+func (s *Service) write(ctx context.Context) (Info, error) {
+	const op = "backup.write"
+	ctx, span := s.scope.Start(ctx, "backup.write")
+	defer span.End()
+	started := time.Now()
+	var writeErr error
+	defer func() {
+		s.scope.RecordDuration(ctx, observability.DurationOperation{
+			Namespace: "backup", Name: "write", Duration: time.Since(started), Err: writeErr,
+		})
+	}()
+
+	createdAt := s.now().UTC().Truncate(time.Second)
+	name := namePrefix + createdAt.Format(nameTimeLayout) + nameSuffix
+	sp, err := newSpool()
+	if err != nil {
+		writeErr = err
+		return Info{}, err
+	}
+	defer sp.close()
+
+	schema, err := s.store.DumpDatabase(ctx, sp.open)
+	if err != nil {
+		writeErr = errors.E(op, err)
+		return Info{}, writeErr
+	}
+	archive, err := sp.archive()
+	if err != nil {
+		writeErr = err
+		return Info{}, err
+	}
+	manifest := Manifest{
+		Format:         Format,
+		Version:        FormatVersion,
+		CreatedAt:      createdAt,
+		AppVersion:     s.appVersion,
+		KeyFingerprint: s.fingerprint,
+		Schema:         schema,
+	}
+	hash := sha256.New()
+	if err := sp.writeArchive(io.MultiWriter(archive, hash), manifest); err != nil {
+		writeErr = err
+		return Info{}, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = archive.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		writeErr = errors.E(op, "rewinding backup archive", err)
+		return Info{}, writeErr
+	}
+
+	key := s.prefix + name
+	if err := s.target.PutStream(ctx, key, archive, size); err != nil {
+		writeErr = errors.E(op, "uploading backup", err)
+		return Info{}, writeErr
+	}
+	if err := s.target.Put(ctx, key+checksumSuffix, []byte(sum+"  "+name+"\n")); err != nil {
+		s.remove(ctx, name)
+		writeErr = errors.E(op, "uploading backup checksum", err)
+		return Info{}, writeErr
+	}
+	if err := s.fetchVerified(ctx, name, io.Discard); err != nil {
+		s.remove(ctx, name)
+		writeErr = errors.E(op, "verifying uploaded backup", err)
+		return Info{}, writeErr
+	}
+	return Info{Name: name, Size: size, CreatedAt: createdAt}, nil
+}
+
+// fetchVerified streams a backup into w and checks it against its checksum
+// file. w receives the backup before it is checked, so what was written to it
+// must be discarded when fetchVerified fails.
+func (s *Service) fetchVerified(ctx context.Context, name string, w io.Writer) error {
+	const op = "backup.fetch"
+	key := s.prefix + name
+	r, err := s.target.GetStream(ctx, key)
+	if errors.WhatKind(err) == errors.NotFound {
+		return errors.E(op, errors.NotFound, errors.User("backup not found"), err)
+	}
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer r.Close()
+	checksum, err := s.target.Get(ctx, key+checksumSuffix)
+	if errors.WhatKind(err) == errors.NotFound {
+		return errors.E(op, errors.FailedPrecondition, errors.User(fmt.Sprintf("Backup %s has no checksum file.", name)), err)
+	}
+	if err != nil {
+		return errors.E(op, err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), r); err != nil {
+		return errors.E(op, "downloading backup", err)
+	}
+	fields := strings.Fields(string(checksum))
+	if len(fields) == 0 || fields[0] != hex.EncodeToString(hash.Sum(nil)) {
+		return errors.E(op, errors.InvalidInput, errors.User(fmt.Sprintf("Backup %s failed checksum verification.", name)))
+	}
+	return nil
+}
+
+// prune deletes backups beyond the newest KeepLast that are older than
+// KeepDays, or all of them when KeepDays is zero.
+func (s *Service) prune(ctx context.Context) error {
+	settings, err := s.store.GetBackupSettings(ctx)
+	if err != nil {
+		return err
+	}
+	backups, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	cutoff := s.now().Add(-time.Duration(settings.KeepDays) * 24 * time.Hour)
+	for i, backup := range backups {
+		if i < settings.KeepLast {
+			continue
+		}
+		if settings.KeepDays > 0 && backup.CreatedAt.After(cutoff) {
+			continue
+		}
+		s.remove(ctx, backup.Name)
+		s.logger.Info("backup removed by retention", "backup", backup.Name)
+	}
+	return nil
+}
+
+func (s *Service) remove(ctx context.Context, name string) {
+	for _, key := range []string{s.prefix + name, s.prefix + name + checksumSuffix} {
+		if err := s.target.Delete(ctx, key); err != nil {
+			s.logger.Warn("failed to delete backup object", "key", key, "error", err)
+		}
+	}
+}
+
+func parseName(name string) (time.Time, bool) {
+	if !namePattern.MatchString(name) {
+		return time.Time{}, false
+	}
+	createdAt, err := time.Parse(nameTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, namePrefix), nameSuffix))
+	return createdAt, err == nil
+}
@@ -0,0 +1,331 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/blob"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/config"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type fakeStore struct {
+	settings store.BackupSettings
+	claim    bool
+	runs     []store.BackupRun
+	tables   map[string]string
+	schema   store.DatabaseSchema
+	problems []string
+	restored map[string]string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		settings: store.BackupSettings{Enabled: true, IntervalHours: 24, KeepLast: 7, KeepDays: 30},
+		tables:   map[string]string{"accounts": "1\tCash\n2\tCard\n", "tags": "1\tfood\n"},
+		schema: store.DatabaseSchema{Version: 19, Tables: []store.BackupTable{
+			{Name: "accounts", Columns: []string{"id", "name"}},
+			{Name: "tags", Columns: []string{"id", "name"}},
+		}},
+	}
+}
+
+func (s *fakeStore) GetBackupSettings(context.Context) (store.BackupSettings, error) {
+	return s.settings, nil
+}
+
+func (s *fakeStore) PatchBackupSettings(context.Context, store.BackupSettingsPatch) (store.BackupSettings, error) {
+	return s.settings, nil
+}
+
+func (s *fakeStore) ClaimScheduledBackup(context.Context, time.Time) (bool, error) {
+	claimed := s.claim
+	s.claim = false
+	return claimed, nil
+}
+
+func (s *fakeStore) RecordBackupRun(_ context.Context, run store.BackupRun) error {
+	s.runs = append(s.runs, run)
+	return nil
+}
+
+func (s *fakeStore) DumpDatabase(_ context.Context, open store.BackupTableWriter) (store.DatabaseSchema, error) {
+	schema := store.DatabaseSchema{Version: s.schema.Version}
+	for _, table := range s.schema.Tables {
+		w, err := open(table)
+		if err != nil {
+			return store.DatabaseSchema{}, err
+		}
+		if _, err := io.WriteString(w, s.tables[table.Name]); err != nil {
+			return store.DatabaseSchema{}, err
+		}
+		table.Rows = int64(strings.Count(s.tables[table.Name], "\n"))
+		schema.Tables = append(schema.Tables, table)
+	}
+	return schema, nil
+}
+
+func (s *fakeStore) CheckBackupSchema(context.Context, store.DatabaseSchema) (store.BackupSchemaCheck, error) {
+	return store.BackupSchemaCheck{CurrentVersion: s.schema.Version, Problems: s.problems}, nil
+}
+
+func (s *fakeStore) RestoreDatabase(_ context.Context, schema store.DatabaseSchema, open store.BackupTableReader) error {
+	s.restored = map[string]string{}
+	for _, table := range schema.Tables {
+		r, err := open(table)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		s.restored[table.Name] = string(data)
+	}
+	return nil
+}
+
+type fakeRefresher struct{ calls int }
+
+func (r *fakeRefresher) RefreshResolver(context.Context) { r.calls++ }
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestService(t *testing.T, st *fakeStore) (*Service, *blob.Local, *testClock, *fakeRefresher) {
+	t.Helper()
+	target, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	clock := &testClock{now: time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)}
+	refresher := &fakeRefresher{}
+	svc, err := New(Dependencies{
+		Config:     config.Backup{PollInterval: time.Minute, Timeout: time.Minute},
+		Target:     target,
+		Store:      st,
+		SecretKey:  []byte("0123456789abcdef0123456789abcdef"),
+		AppVersion: "test",
+		Resolver:   refresher,
+		Now:        clock.Now,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return svc, target, clock, refresher
+}
+
+func TestCreateListAndRestore(t *testing.T) {
+	st := newFakeStore()
+	svc, _, clock, refresher := newTestService(t, st)
+	ctx := context.Background()
+
+	first, err := svc.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if first.Name != "expensor-backup-20260301T020000Z.tar.gz" || first.Size == 0 {
+		t.Fatalf("backup = %+v", first)
+	}
+	if len(st.runs) != 1 || st.runs[0].Backup != first.Name || st.runs[0].Error != "" {
+		t.Fatalf("runs = %+v", st.runs)
+	}
+	clock.advance(time.Hour)
+	if _, err := svc.Create(ctx); err != nil {
+		t.Fatalf("second Create: %v", err)
+	}
+
+	backups, err := svc.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(backups) != 2 || backups[1].Name != first.Name || !backups[0].CreatedAt.After(backups[1].CreatedAt) {
+		t.Fatalf("backups = %+v", backups)
+	}
+
+	dry, err := svc.Restore(ctx, first.Name, true)
+	if err != nil {
+		t.Fatalf("Restore dry run: %v", err)
+	}
+	if dry.Restored || dry.Tables != 2 || dry.Rows != 3 || dry.SchemaVersion != 19 || dry.AppVersion != "test" {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	if st.restored != nil {
+		t.Fatal("dry run restored the database")
+	}
+
+	clock.advance(time.Hour)
+	st.tables["tags"] = "1\tfood\n2\trent\n"
+	result, err := svc.Restore(ctx, first.Name, false)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if !result.Restored || result.SafetyBackup != "expensor-backup-20260301T040000Z.tar.gz" {
+		t.Fatalf("restore = %+v", result)
+	}
+	if st.restored["tags"] != "1\tfood\n" || st.restored["accounts"] != "1\tCash\n2\tCard\n" {
+		t.Fatalf("restored = %q", st.restored)
+	}
+	if refresher.calls != 1 {
+		t.Fatalf("resolver refreshed %d times, want 1", refresher.calls)
+	}
+	if backups, _ := svc.List(ctx); len(backups) != 3 {
+		t.Fatalf("safety backup missing: %+v", backups)
+	}
+}
+
+func TestRestoreRefusesSchemaProblems(t *testing.T) {
+	st := newFakeStore()
+	svc, _, _, _ := newTestService(t, st)
+	ctx := context.Background()
+	info, err := svc.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.problems = []string{"table tags is not in the current database"}
+
+	dry, err := svc.Restore(ctx, info.Name, true)
+	if err != nil {
+		t.Fatalf("Restore dry run: %v", err)
+	}
+	if len(dry.Problems) != 1 {
+		t.Fatalf("problems = %v", dry.Problems)
+	}
+	_, err = svc.Restore(ctx, info.Name, false)
+	if errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Fatalf("Restore error = %v, want FailedPrecondition", err)
+	}
+	if st.restored != nil {
+		t.Fatal("restored despite schema problems")
+	}
+}
+
+func TestRestoreWarnsAboutDifferentKey(t *testing.T) {
+	st := newFakeStore()
+	svc, _, _, _ := newTestService(t, st)
+	ctx := context.Background()
+	info, err := svc.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	svc.fingerprint = keyFingerprint([]byte("another key"))
+
+	dry, err := svc.Restore(ctx, info.Name, true)
+	if err != nil {
+		t.Fatalf("Restore dry run: %v", err)
+	}
+	if len(dry.Warnings) != 1 || !strings.Contains(dry.Warnings[0], "different secret key") {
+		t.Fatalf("warnings = %v", dry.Warnings)
+	}
+}
+
+func TestRestoreAcceptsPreviousKey(t *testing.T) {
+	st := newFakeStore()
+	svc, _, _, _ := newTestService(t, st)
+	ctx := context.Background()
+	info, err := svc.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	svc.previousFingerprints = []string{svc.fingerprint}
+	svc.fingerprint = keyFingerprint([]byte("rotated key"))
+
+	dry, err := svc.Restore(ctx, info.Name, true)
+	if err != nil {
+		t.Fatalf("Restore dry run: %v", err)
+	}
+	if len(dry.Warnings) != 0 {
+		t.Fatalf("warnings = %v, want none for a previous key", dry.Warnings)
+	}
+}
+
+func TestRestoreVerifiesChecksum(t *testing.T) {
+	st := newFakeStore()
+	svc, target, _, _ := newTestService(t, st)
+	ctx := context.Background()
+	info, err := svc.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	data, err := target.Get(ctx, info.Name)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data[len(data)/2] ^= 0xff
+	if err := target.Put(ctx, info.Name, data); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	_, err = svc.Restore(ctx, info.Name, true)
+	if errors.WhatKind(err) != errors.InvalidInput || !strings.Contains(errors.UserMsg(err), "checksum") {
+		t.Fatalf("Restore error = %v, want checksum failure", err)
+	}
+}
+
+func TestRestoreUnknownBackup(t *testing.T) {
+	svc, _, _, _ := newTestService(t, newFakeStore())
+	for _, name := range []string{"expensor-backup-20200101T000000Z.tar.gz", "../etc/passwd"} {
+		if _, err := svc.Restore(context.Background(), name, true); errors.WhatKind(err) != errors.NotFound {
+			t.Fatalf("Restore(%q) error = %v, want NotFound", name, err)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	st := newFakeStore()
+	st.settings.KeepLast = 2
+	st.settings.KeepDays = 3
+	svc, _, clock, _ := newTestService(t, st)
+	ctx := context.Background()
+
+	for range 5 {
+		if _, err := svc.Create(ctx); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		clock.advance(24 * time.Hour)
+	}
+	backups, err := svc.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	// The last prune ran when the fifth backup was taken: the newest two are
+	// kept by count, the third is under three days old, the rest are not.
+	var names []string
+	for _, b := range backups {
+		names = append(names, b.CreatedAt.Format("02"))
+	}
+	if got := fmt.Sprint(names); got != "[05 04 03]" {
+		t.Fatalf("kept backups = %s", got)
+	}
+
+	st.settings.KeepDays = 0
+	if _, err := svc.Create(ctx); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if backups, _ := svc.List(ctx); len(backups) != 2 {
+		t.Fatalf("kept %d backups with KeepDays 0, want 2", len(backups))
+	}
+}
+
+func TestRunTakesClaimedBackup(t *testing.T) {
+	st := newFakeStore()
+	st.claim = true
+	svc, _, _, _ := newTestService(t, st)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := svc.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run error = %v", err)
+	}
+	if len(st.runs) != 1 {
+		t.Fatalf("runs = %+v, want one scheduled backup", st.runs)
+	}
+}
@@ -0,0 +1,79 @@
+// Package blob stores opaque objects such as transaction attachments on a
+// pluggable backend: the local filesystem or an S3-compatible object store.
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/config"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// Store persists objects by key. Keys are slash-separated relative paths.
+type Store interface {
+	// Put writes data under key, replacing any existing object.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the object under key, or an error of kind NotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object under key. Deleting a missing key succeeds.
+	Delete(ctx context.Context, key string) error
+}
+
+// Object describes a stored object.
+type Object struct {
+	Key        string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// Lister is implemented by stores that can enumerate their objects.
+type Lister interface {
+	// List returns every object whose key starts with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Streamer is implemented by stores that can move objects too large to
+// hold in memory, such as database backups.
+type Streamer interface {
+	// PutStream writes the size bytes read from r under key, replacing any
+	// existing object.
+	PutStream(ctx context.Context, key string, r io.Reader, size int64) error
+	// GetStream opens the object under key for reading, or returns an error
+	// of kind NotFound. The caller closes the reader.
+	GetStream(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// New returns the Store selected by cfg.
+func New(cfg config.Blob) (Store, error) {
+	switch cfg.Backend {
+	case "", config.BlobBackendLocal:
+		return NewLocal(cfg.Local.Dir)
+	case config.BlobBackendS3:
+		return NewS3(S3Options{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PathStyle:       cfg.S3.PathStyle,
+		})
+	default:
+		return nil, errors.E("blob.new", errors.InvalidArgument, fmt.Sprintf("unsupported blob backend %q", cfg.Backend))
+	}
+}
+
+func validateKey(op, key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return errors.E(op, errors.InvalidArgument, fmt.Sprintf("invalid blob key %q", key))
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return errors.E(op, errors.InvalidArgument, fmt.Sprintf("invalid blob key %q", key))
+		}
+	}
+	return nil
+}
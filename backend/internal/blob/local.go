@@ -0,0 +1,169 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// Local stores objects as files below a root directory.
+type Local struct {
+	root string
+}
+
+var (
+	_ Store    = (*Local)(nil)
+	_ Lister   = (*Local)(nil)
+	_ Streamer = (*Local)(nil)
+)
+
+// NewLocal returns a Local store rooted at dir, creating it if needed.
+func NewLocal(dir string) (*Local, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.E("blob.local.new", errors.InvalidArgument, "local blob directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.E("blob.local.new", "creating local blob directory", err)
+	}
+	return &Local{root: dir}, nil
+}
+
+// Put writes data to a temporary file and renames it into place, so readers
+// never observe a partially written object.
+func (l *Local) Put(_ context.Context, key string, data []byte) error {
+	return l.write("blob.local.put", key, bytes.NewReader(data), int64(len(data)))
+}
+
+// PutStream is Put for data read from r.
+func (l *Local) PutStream(_ context.Context, key string, r io.Reader, size int64) error {
+	return l.write("blob.local.put_stream", key, r, size)
+}
+
+func (l *Local) write(op, key string, r io.Reader, size int64) error {
+	path, err := l.path(op, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.E(op, "creating blob directory", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return errors.E(op, "creating temporary blob file", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	n, err := io.Copy(tmp, r)
+	if err == nil && n != size {
+		err = fmt.Errorf("read %d bytes, want %d", n, size)
+	}
+	if err != nil {
+		_ = tmp.Close()
+		return errors.E(op, "writing blob", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.E(op, "closing blob", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.E(op, "storing blob", err)
+	}
+	return nil
+}
+
+func (l *Local) Get(_ context.Context, key string) ([]byte, error) {
+	const op = "blob.local.get"
+	path, err := l.path(op, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.E(op, errors.NotFound, "blob not found", err)
+	}
+	if err != nil {
+		return nil, errors.E(op, "reading blob", err)
+	}
+	return data, nil
+}
+
+func (l *Local) GetStream(_ context.Context, key string) (io.ReadCloser, error) {
+	const op = "blob.local.get_stream"
+	path, err := l.path(op, key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.E(op, errors.NotFound, "blob not found", err)
+	}
+	if err != nil {
+		return nil, errors.E(op, "opening blob", err)
+	}
+	return file, nil
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	const op = "blob.local.delete"
+	path, err := l.path(op, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.E(op, "deleting blob", err)
+	}
+	return nil
+}
+
+// List walks the root directory. Temporary files left by an interrupted Put
+// are not listed.
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	const op = "blob.local.list"
+	objects := []Object{}
+	err := filepath.WalkDir(l.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), ModifiedAt: info.ModTime().UTC()})
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return []Object{}, nil
+	}
+	if err != nil {
+		return nil, errors.E(op, "listing blobs", err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (l *Local) path(op, key string) (string, error) {
+	if err := validateKey(op, key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,116 @@
+package blob
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestLocalRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	if err := store.Put(ctx, "attachments/tenant/one", []byte("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := store.Put(ctx, "attachments/tenant/one", []byte("second")); err != nil {
+		t.Fatalf("Put overwrite: %v", err)
+	}
+	got, err := store.Get(ctx, "attachments/tenant/one")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(got) != "second" {
+		t.Fatalf("Get = %q, want second", got)
+	}
+	info, err := os.Stat(filepath.Join(dir, "attachments", "tenant", "one"))
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		t.Fatalf("blob file mode = %v, want owner-only", info.Mode().Perm())
+	}
+
+	if err := store.Delete(ctx, "attachments/tenant/one"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "attachments/tenant/one"); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+	if _, err := store.Get(ctx, "attachments/tenant/one"); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("Get deleted kind = %v, want not found", errors.WhatKind(err))
+	}
+}
+
+func TestLocalStreams(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	exerciseStreamer(t, store)
+	if err := store.PutStream(context.Background(), "backups/short", strings.NewReader("abc"), 4); err == nil {
+		t.Fatal("PutStream of a short reader succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(store.root, "backups", "short")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("short PutStream left an object: %v", err)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../b", "a//b", `a\b`} {
+		if err := store.Put(context.Background(), key, []byte("x")); errors.WhatKind(err) != errors.InvalidArgument {
+			t.Errorf("Put(%q) kind = %v, want invalid argument", key, errors.WhatKind(err))
+		}
+	}
+}
+
+func TestLocalList(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	for _, key := range []string{"backups/b", "backups/a", "attachments/t/x"} {
+		if err := store.Put(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "backups", ".tmp-123"), []byte("partial"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	objects, err := store.List(ctx, "backups/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 2 || objects[0].Key != "backups/a" || objects[1].Key != "backups/b" {
+		t.Fatalf("List = %#v, want backups/a and backups/b", objects)
+	}
+	if objects[0].Size != int64(len("backups/a")) || objects[0].ModifiedAt.IsZero() {
+		t.Fatalf("List object = %#v, want size and modification time", objects[0])
+	}
+
+	missing, err := NewLocal(filepath.Join(dir, "empty"))
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "empty")); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if objects, err := missing.List(ctx, ""); err != nil || len(objects) != 0 {
+		t.Fatalf("List missing root = %#v, %v; want empty", objects, err)
+	}
+}
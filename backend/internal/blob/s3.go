@@ -0,0 +1,382 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// S3Options configures an S3-compatible object store.
+type S3Options struct {
+	// Endpoint is the service URL, e.g. https://s3.eu-west-1.amazonaws.com or
+	// http://localhost:9000 for MinIO.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses objects as <endpoint>/<bucket>/<key> instead of
+	// <bucket>.<endpoint host>/<key>. MinIO and most self-hosted stores need it.
+	PathStyle bool
+	// HTTPClient defaults to a client with a 60 second timeout.
+	HTTPClient *http.Client
+}
+
+// S3 stores objects in a bucket of an S3-compatible service. Requests are
+// signed with AWS Signature Version 4.
+type S3 struct {
+	endpoint *url.URL
+	opts     S3Options
+	client   *http.Client
+	now      func() time.Time
+}
+
+var (
+	_ Store    = (*S3)(nil)
+	_ Lister   = (*S3)(nil)
+	_ Streamer = (*S3)(nil)
+)
+
+// NewS3 validates opts and returns an S3 store.
+func NewS3(opts S3Options) (*S3, error) {
+	const op = "blob.s3.new"
+	endpoint, err := url.Parse(strings.TrimRight(opts.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, errors.E(op, errors.InvalidArgument, fmt.Sprintf("invalid S3 endpoint %q", opts.Endpoint))
+	}
+	if opts.Bucket == "" {
+		return nil, errors.E(op, errors.InvalidArgument, "S3 bucket is required")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, errors.E(op, errors.InvalidArgument, "S3 access key ID and secret access key are required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &S3{endpoint: endpoint, opts: opts, client: client, now: time.Now}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte) error {
+	const op = "blob.s3.put"
+	if err := validateKey(op, key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.E(op, responseError(resp))
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	const op = "blob.s3.get"
+	if err := validateKey(op, key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.E(op, errors.NotFound, "blob not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.E(op, responseError(resp))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.E(op, errors.Unavailable, "reading S3 object", err)
+	}
+	return data, nil
+}
+
+// PutStream uploads the size bytes read from r. The payload is sent
+// unsigned, since signing it would mean reading it twice.
+func (s *S3) PutStream(ctx context.Context, key string, r io.Reader, size int64) error {
+	const op = "blob.s3.put_stream"
+	if err := validateKey(op, key); err != nil {
+		return err
+	}
+	resp, err := s.send(ctx, http.MethodPut, s.objectURL(key), r, size, unsignedPayload)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.E(op, responseError(resp))
+	}
+	return nil
+}
+
+func (s *S3) GetStream(ctx context.Context, key string) (io.ReadCloser, error) {
+	const op = "blob.s3.get_stream"
+	if err := validateKey(op, key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errors.E(op, errors.NotFound, "blob not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, errors.E(op, responseError(resp))
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	const op = "blob.s3.delete"
+	if err := validateKey(op, key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer resp.Body.Close()
+	// S3 answers 204 whether or not the object existed.
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return errors.E(op, responseError(resp))
+	}
+	return nil
+}
+
+// listBucketResult is the subset of a ListObjectsV2 response that List reads.
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through ListObjectsV2 until the listing is complete.
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	const op = "blob.s3.list"
+	objects := []Object{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		page, err := s.listPage(ctx, query)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, item := range page.Contents {
+			objects = append(objects, Object{Key: item.Key, Size: item.Size, ModifiedAt: item.LastModified.UTC()})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *S3) listPage(ctx context.Context, query url.Values) (listBucketResult, error) {
+	u := s.bucketURL()
+	u.RawQuery = query.Encode()
+	resp, err := s.send(ctx, http.MethodGet, u.String(), nil, 0, sha256Hex(nil))
+	if err != nil {
+		return listBucketResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return listBucketResult{}, responseError(resp)
+	}
+	var page listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return listBucketResult{}, errors.E(errors.Unavailable, "decoding S3 listing", err)
+	}
+	return page, nil
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	return s.send(ctx, method, s.objectURL(key), bytes.NewReader(body), int64(len(body)), sha256Hex(body))
+}
+
+func (s *S3) send(ctx context.Context, method, rawURL string, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	if size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, errors.E(errors.Internal, "building S3 request", err)
+	}
+	req.ContentLength = size
+	signV4(req, payloadHash, s.opts.AccessKeyID, s.opts.SecretAccessKey, s.opts.Region, s.now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.E(errors.Unavailable, fmt.Sprintf("S3 %s request failed", method), err)
+	}
+	return resp, nil
+}
+
+func (s *S3) objectURL(key string) string {
+	u := *s.endpoint
+	prefix := strings.TrimSuffix(s.endpoint.Path, "/")
+	escapedPrefix := strings.TrimSuffix(s.endpoint.EscapedPath(), "/")
+	if s.opts.PathStyle {
+		u.Path = prefix + "/" + s.opts.Bucket + "/" + key
+		u.RawPath = escapedPrefix + "/" + url.PathEscape(s.opts.Bucket) + "/" + escapeKey(key)
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = prefix + "/" + key
+		u.RawPath = escapedPrefix + "/" + escapeKey(key)
+	}
+	return u.String()
+}
+
+func (s *S3) bucketURL() url.URL {
+	u := *s.endpoint
+	prefix := strings.TrimSuffix(s.endpoint.Path, "/")
+	escapedPrefix := strings.TrimSuffix(s.endpoint.EscapedPath(), "/")
+	if s.opts.PathStyle {
+		u.Path = prefix + "/" + s.opts.Bucket
+		u.RawPath = escapedPrefix + "/" + url.PathEscape(s.opts.Bucket)
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = prefix + "/"
+		u.RawPath = escapedPrefix + "/"
+	}
+	return u
+}
+
+// escapeKey URI-encodes every byte of key except unreserved characters and
+// the path separator, as S3 signing requires.
+func escapeKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	msg := fmt.Sprintf("S3 responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusUnauthorized:
+		return errors.E(errors.PermissionDenied, msg)
+	case http.StatusNotFound:
+		return errors.E(errors.NotFound, msg)
+	default:
+		return errors.E(errors.Unavailable, msg)
+	}
+}
+
+// unsignedPayload stands in for the payload hash of a request whose body is
+// not signed.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// signV4 adds AWS Signature Version 4 headers to req, whose body hashes to
+// payloadHash. The host and every header already set on req are signed.
+func signV4(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region string, now time.Time) {
+	const service = "s3"
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		vals := append([]string(nil), values[key]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, escapeKey(key)+"="+strings.ReplaceAll(escapeKey(v), "/", "%2F"))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -21,6 +21,7 @@ const (
 	thunderbirdGuidePath = "content/readers/thunderbird/guide.json"
 	promptPath           = "content/llm/prompts"
 	openAIModelsPath     = "content/llm/providers/openai_models.json"
+	anthropicModelsPath  = "content/llm/providers/anthropic_models.json"
 )
 
 //go:embed content
@@ -28,12 +29,13 @@ var contentFS embed.FS
 
 // Content is the validated, typed application content loaded at startup.
 type Content struct {
-	Seed               store.SeedContent
-	SystemRules        []api.Rule
-	BanksJSON          []byte
-	ReaderGuides       map[string][]byte
-	PromptCatalog      *llm.PromptCatalog
-	OpenAIModelOptions []llm.ModelOption
+	Seed                  store.SeedContent
+	SystemRules           []api.Rule
+	BanksJSON             []byte
+	ReaderGuides          map[string][]byte
+	PromptCatalog         *llm.PromptCatalog
+	OpenAIModelOptions    []llm.ModelOption
+	AnthropicModelOptions []llm.ModelOption
 }
 
 // Load parses and validates all bundled application content.
@@ -99,7 +101,14 @@ func loadFromFS(fsys fs.FS) (Content, error) {
 	if err != nil {
 		return Content{}, err
 	}
-	if err := validateModels(models); err != nil {
+	if err := validateModels("OpenAI", models); err != nil {
+		return Content{}, err
+	}
+	anthropicModels, err := decode[[]llm.ModelOption](fsys, anthropicModelsPath)
+	if err != nil {
+		return Content{}, err
+	}
+	if err := validateModels("Anthropic", anthropicModels); err != nil {
 		return Content{}, err
 	}
 
@@ -115,8 +124,9 @@ func loadFromFS(fsys fs.FS) (Content, error) {
 			"gmail":       gmailGuide,
 			"thunderbird": thunderbirdGuide,
 		},
-		PromptCatalog:      prompts,
-		OpenAIModelOptions: models,
+		PromptCatalog:         prompts,
+		OpenAIModelOptions:    models,
+		AnthropicModelOptions: anthropicModels,
 	}, nil
 }
 
@@ -187,13 +197,13 @@ func loadGuide(fsys fs.FS, name string) ([]byte, error) {
 	return body, nil
 }
 
-func validateModels(models []llm.ModelOption) error {
+func validateModels(provider string, models []llm.ModelOption) error {
 	if len(models) == 0 {
-		return invalid("OpenAI model catalog is empty")
+		return invalid(provider + " model catalog is empty")
 	}
 	for _, model := range models {
 		if blank(model.ID) || blank(model.DisplayName) || blank(model.Quality) || blank(model.Cost) {
-			return invalid("OpenAI model catalog contains an incomplete entry")
+			return invalid(provider + " model catalog contains an incomplete entry")
 		}
 	}
 	return nil
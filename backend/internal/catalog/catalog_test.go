@@ -18,7 +18,8 @@ func TestLoadValidatesBundledContent(t *testing.T) {
 	if len(content.BanksJSON) == 0 || len(content.ReaderGuides["gmail"]) == 0 || len(content.ReaderGuides["thunderbird"]) == 0 {
 		t.Fatal("Load() returned incomplete HTTP and reader content")
 	}
-	if content.PromptCatalog == nil || content.PromptCatalog.Len() == 0 || len(content.OpenAIModelOptions) == 0 {
+	if content.PromptCatalog == nil || content.PromptCatalog.Len() == 0 || len(content.OpenAIModelOptions) == 0 ||
+		len(content.AnthropicModelOptions) == 0 {
 		t.Fatal("Load() returned incomplete LLM content")
 	}
 }
@@ -35,6 +36,7 @@ func TestLoadRejectsStructurallyInvalidContent(t *testing.T) {
 		{name: "invalid banks shape", path: "content/banks.json", body: `{}`},
 		{name: "empty guide", path: gmailGuidePath, body: `{"sections":[]}`},
 		{name: "empty models", path: openAIModelsPath, body: `[]`},
+		{name: "incomplete anthropic models", path: anthropicModelsPath, body: `[{"id":"claude"}]`},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
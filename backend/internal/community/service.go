@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/observability"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/config"
@@ -40,8 +41,11 @@ type Dependencies struct {
 	Store    syncStore
 	Runtime  runtimeStore
 	Resolver ResolverRefresher
-	Logger   *slog.Logger
-	Scope    *observability.Scope
+	// Events receives a community.sync event, sent to every tenant, when a
+	// sync finishes. Nil drops them.
+	Events events.Publisher
+	Logger *slog.Logger
+	Scope  *observability.Scope
 }
 
 // Service owns automatic, periodic, and manually triggered community syncs.
@@ -52,6 +56,7 @@ type Service struct {
 	client    *http.Client
 	store     syncStore
 	resolver  ResolverRefresher
+	events    events.Publisher
 	logger    *slog.Logger
 	scope     *observability.Scope
 	newTicker func(time.Duration) serviceTicker
@@ -92,6 +97,10 @@ func New(ctx context.Context, deps Dependencies) (*Service, error) {
 			logger.Warn("failed to seed default community URL", "error", setErr)
 		}
 	}
+	publisher := deps.Events
+	if publisher == nil {
+		publisher = events.Discard
+	}
 	serviceCtx, cancel := context.WithCancel(ctx)
 	return &Service{
 		rootCtx:  serviceCtx,
@@ -100,6 +109,7 @@ func New(ctx context.Context, deps Dependencies) (*Service, error) {
 		client:   client,
 		store:    deps.Store,
 		resolver: deps.Resolver,
+		events:   publisher,
 		logger:   logger.With("component", "sync"),
 		scope:    scope,
 		newTicker: func(interval time.Duration) serviceTicker {
@@ -230,6 +240,7 @@ func (s *Service) sync(ctx context.Context) {
 	if err := s.store.SetSyncStatus(ctx, store.SyncStatus{LastSyncedAt: &now, EntriesUpdated: updated}); err != nil {
 		s.logger.Warn("failed to persist sync status", "error", err)
 	}
+	s.events.Publish("", events.TypeCommunitySync, events.CommunitySync{SyncedAt: &now, EntriesUpdated: updated})
 	s.logger.Info("community sync complete", "entries_updated", updated)
 }
 
@@ -259,6 +270,7 @@ func (s *Service) recordError(ctx context.Context, syncErr error) {
 	if err := s.store.SetSyncStatus(ctx, store.SyncStatus{Error: &message}); err != nil {
 		s.logger.Warn("failed to persist sync error status", "error", err)
 	}
+	s.events.Publish("", events.TypeCommunitySync, events.CommunitySync{Error: message})
 }
 
 func (s *Service) refresh(ctx context.Context) {
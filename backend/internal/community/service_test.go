@@ -6,10 +6,12 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/config"
 )
@@ -88,10 +90,23 @@ func TestSyncPersistsSuccessfulContent(t *testing.T) {
 	server := contentServer(t)
 	defer server.Close()
 	st := &fakeStore{}
-	newTestService(t, server.URL, st, nil).sync(context.Background())
+	broker := events.NewBroker(8, nil)
+	sub, _ := broker.Subscribe("tenant-a", "")
+	defer sub.Close()
+	service := newTestService(t, server.URL, st, nil)
+	service.events = broker
+	service.sync(context.Background())
 	if len(st.statuses) != 1 || st.statuses[0].LastSyncedAt == nil || st.statuses[0].EntriesUpdated != 2 {
 		t.Fatalf("statuses = %#v", st.statuses)
 	}
+	select {
+	case event := <-sub.Events():
+		if event.Type != events.TypeCommunitySync || !strings.Contains(string(event.Data), `"entries_updated":2`) {
+			t.Fatalf("event = %s %s", event.Type, event.Data)
+		}
+	default:
+		t.Fatal("no community.sync event published")
+	}
 	if len(st.mccEntries) != 1 || st.mccEntries[0].Code != "5411" || st.mccEntries[0].Category != "Groceries" {
 		t.Fatalf("MCC entries = %#v", st.mccEntries)
 	}
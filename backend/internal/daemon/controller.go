@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
@@ -37,7 +38,10 @@ type ControllerDependencies struct {
 	Context context.Context
 	Scanner scanExecutor
 	Store   activeReaderStore
-	Logger  *slog.Logger
+	// Events receives a daemon.status event whenever a run starts or stops.
+	// Nil drops them.
+	Events events.Publisher
+	Logger *slog.Logger
 }
 
 // Controller owns interactive daemon start, stop, restart, rescan, and status behavior.
@@ -47,6 +51,7 @@ type Controller struct {
 	cancel  context.CancelFunc
 	scanner scanExecutor
 	store   activeReaderStore
+	events  events.Publisher
 	logger  *slog.Logger
 
 	queueMu       sync.Mutex
@@ -75,9 +80,13 @@ func NewController(deps ControllerDependencies) (*Controller, error) {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	publisher := deps.Events
+	if publisher == nil {
+		publisher = events.Discard
+	}
 	controllerCtx, cancel := context.WithCancel(deps.Context)
 	controller := &Controller{
-		ctx: controllerCtx, cancel: cancel, scanner: deps.Scanner, store: deps.Store, logger: logger,
+		ctx: controllerCtx, cancel: cancel, scanner: deps.Scanner, store: deps.Store, events: publisher, logger: logger,
 		wake: make(chan struct{}, 1), workerDone: make(chan struct{}),
 	}
 	return controller, nil
@@ -225,22 +234,31 @@ func (c *Controller) launch(request RunRequest, mode ScanMode) {
 	c.startedAt = &startedAt
 	c.lastError = ""
 	c.mu.Unlock()
+	c.events.Publish(request.Tenant.ID, events.TypeDaemonStatus, events.DaemonStatus{
+		Reader: request.Reader, Running: true, StartedAt: &startedAt,
+	})
 
 	go func() {
 		err := c.scanner.Run(runCtx, ScanRequest{Tenant: request.Tenant, Reader: request.Reader, Mode: mode})
 		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
 			c.logger.Error("daemon stopped with error", "error", err)
 		}
+		status := events.DaemonStatus{Reader: request.Reader, StartedAt: &startedAt}
 		c.mu.Lock()
-		if c.done == done {
+		current := c.done == done
+		if current {
 			c.running = false
 			c.runCancel = nil
 			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
 				c.lastError = err.Error()
 			}
+			status.LastError = c.lastError
 		}
 		close(done)
 		c.mu.Unlock()
+		if current {
+			c.events.Publish(request.Tenant.ID, events.TypeDaemonStatus, status)
+		}
 	}()
 }
 
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 )
 
@@ -118,6 +120,43 @@ func TestControllerRecordsRunError(t *testing.T) {
 	}
 }
 
+func TestControllerPublishesRunStatus(t *testing.T) {
+	broker := events.NewBroker(8, nil)
+	sub, _ := broker.Subscribe("tenant-a", "")
+	defer sub.Close()
+	scanner := &controllerScannerStub{run: func(context.Context, ScanRequest) error { return errors.New("reader failed") }}
+	controller, err := NewController(ControllerDependencies{
+		Context: context.Background(), Scanner: scanner, Store: &activeReaderStoreStub{}, Events: broker,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewController() error = %v", err)
+	}
+	defer closeController(t, controller)
+
+	controller.Start(RunRequest{Tenant: store.Tenant{ID: "tenant-a"}, Reader: "gmail"})
+
+	var statuses []events.DaemonStatus
+	for range 2 {
+		select {
+		case event := <-sub.Events():
+			var status events.DaemonStatus
+			if err := json.Unmarshal(event.Data, &status); err != nil || event.Type != events.TypeDaemonStatus {
+				t.Fatalf("event = %s %s, %v", event.Type, event.Data, err)
+			}
+			statuses = append(statuses, status)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out after %d events", len(statuses))
+		}
+	}
+	if !statuses[0].Running || statuses[0].Reader != "gmail" || statuses[0].StartedAt == nil {
+		t.Fatalf("start status = %+v", statuses[0])
+	}
+	if statuses[1].Running || statuses[1].LastError != "reader failed" {
+		t.Fatalf("stop status = %+v", statuses[1])
+	}
+}
+
 func TestControllerCloseHonorsContextTimeout(t *testing.T) {
 	started := make(chan struct{})
 	release := make(chan struct{})
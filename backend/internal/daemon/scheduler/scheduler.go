@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ArionMiles/expensor/backend/internal/daemon"
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/oauth"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
@@ -14,7 +15,7 @@ import (
 
 // StateStore is the scheduler persistence surface.
 type StateStore interface {
-	ListUsers(ctx context.Context) ([]store.User, error)
+	ListActiveTenants(ctx context.Context) ([]store.Tenant, error)
 	EnsureScanningStateForTenant(ctx context.Context, tenant store.Tenant) error
 	GetSchedulerConfig(ctx context.Context) (store.SchedulerConfig, error)
 	ListRunnableScanningStates(ctx context.Context) ([]store.TenantScanningState, error)
@@ -45,6 +46,7 @@ type Scheduler struct {
 	pollInterval   time.Duration
 	baseRetryDelay time.Duration
 	maxRetryDelay  time.Duration
+	events         events.Publisher
 	logger         *slog.Logger
 
 	mu      sync.Mutex
@@ -60,7 +62,10 @@ type Config struct {
 	PollInterval   time.Duration
 	BaseRetryDelay time.Duration
 	MaxRetryDelay  time.Duration
-	Logger         *slog.Logger
+	// Events receives a scan.state event for every state the scheduler
+	// moves a tenant to. Nil drops them.
+	Events events.Publisher
+	Logger *slog.Logger
 }
 
 func New(cfg Config) (*Scheduler, error) {
@@ -84,6 +89,10 @@ func New(cfg Config) (*Scheduler, error) {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	publisher := cfg.Events
+	if publisher == nil {
+		publisher = events.Discard
+	}
 	return &Scheduler{
 		store:          cfg.Store,
 		runner:         cfg.Runner,
@@ -91,6 +100,7 @@ func New(cfg Config) (*Scheduler, error) {
 		pollInterval:   cfg.PollInterval,
 		baseRetryDelay: cfg.BaseRetryDelay,
 		maxRetryDelay:  cfg.MaxRetryDelay,
+		events:         publisher,
 		logger:         logger,
 		running:        make(map[string]context.CancelFunc),
 	}, nil
@@ -166,15 +176,12 @@ func (s *Scheduler) Reconcile(ctx context.Context) error {
 }
 
 func (s *Scheduler) ensureTenantStates(ctx context.Context) error {
-	users, err := s.store.ListUsers(ctx)
+	tenants, err := s.store.ListActiveTenants(ctx)
 	if err != nil {
 		return err
 	}
-	for _, user := range users {
-		if user.DisabledAt != nil {
-			continue
-		}
-		if err := s.store.EnsureScanningStateForTenant(ctx, store.Tenant{ID: user.TenantID}); err != nil {
+	for _, tenant := range tenants {
+		if err := s.store.EnsureScanningStateForTenant(ctx, tenant); err != nil {
 			return err
 		}
 	}
@@ -198,9 +205,8 @@ func (s *Scheduler) isRunning(tenantID string) bool {
 }
 
 func (s *Scheduler) startTenant(ctx context.Context, state store.TenantScanningState) error {
-	tenant := store.Tenant{ID: state.TenantID}
 	now := s.clock.Now()
-	if err := s.store.UpdateScanningState(ctx, tenant, store.ScanningStateUpdate{
+	if err := s.setState(ctx, state, store.ScanningStateUpdate{
 		State:         store.ScanningStateStarting,
 		ReasonCode:    store.ScanningReasonNone,
 		PublicMessage: "",
@@ -228,7 +234,7 @@ func (s *Scheduler) runTenant(ctx context.Context, state store.TenantScanningSta
 
 	tenant := store.Tenant{ID: state.TenantID}
 	startedAt := s.clock.Now()
-	if err := s.store.UpdateScanningState(ctx, tenant, store.ScanningStateUpdate{
+	if err := s.setState(ctx, state, store.ScanningStateUpdate{
 		State:         store.ScanningStateRunning,
 		ReasonCode:    store.ScanningReasonNone,
 		PublicMessage: "",
@@ -241,7 +247,7 @@ func (s *Scheduler) runTenant(ctx context.Context, state store.TenantScanningSta
 	err := s.runner.Run(ctx, tenant, state.ActiveReader)
 	finishedAt := s.clock.Now()
 	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-		if updateErr := s.store.UpdateScanningState(ctx, tenant, store.ScanningStateUpdate{
+		if updateErr := s.setState(ctx, state, store.ScanningStateUpdate{
 			State:         store.ScanningStateQueued,
 			ReasonCode:    store.ScanningReasonNone,
 			PublicMessage: "",
@@ -254,11 +260,26 @@ func (s *Scheduler) runTenant(ctx context.Context, state store.TenantScanningSta
 	}
 
 	update := s.failureStateUpdate(err, state.RetryCount, finishedAt)
-	if updateErr := s.store.UpdateScanningState(contextWithoutCancel(ctx), tenant, update); updateErr != nil {
+	if updateErr := s.setState(contextWithoutCancel(ctx), state, update); updateErr != nil {
 		s.logger.Error("failed to mark scan failed", "error", updateErr)
 	}
 }
 
+// setState persists update and publishes it to the tenant's event stream.
+func (s *Scheduler) setState(ctx context.Context, state store.TenantScanningState, update store.ScanningStateUpdate) error {
+	if err := s.store.UpdateScanningState(ctx, store.Tenant{ID: state.TenantID}, update); err != nil {
+		return err
+	}
+	s.events.Publish(state.TenantID, events.TypeScanState, events.ScanState{
+		Reader:        state.ActiveReader,
+		State:         string(update.State),
+		ReasonCode:    string(update.ReasonCode),
+		PublicMessage: update.PublicMessage,
+		NextRetryAt:   update.NextRetryAt,
+	})
+	return nil
+}
+
 func (s *Scheduler) clearRunning(tenantID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/ArionMiles/expensor/backend/internal/daemon"
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/oauth"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	apperrors "github.com/ArionMiles/expensor/backend/pkg/errors"
@@ -71,6 +72,23 @@ func TestReconcileStartsRunnableTenantsUpToLimit(t *testing.T) {
 	fakeStore.waitForState(t, "tenant-b", store.ScanningStateQueued)
 }
 
+func TestReconcileEnsuresStateForSharedTenants(t *testing.T) {
+	fakeStore := newFakeStore(nil)
+	fakeStore.tenants = []store.Tenant{{ID: "personal"}, {ID: "household"}}
+	scheduler := newScheduler(t, Config{Store: fakeStore, Runner: newBlockingRunner()})
+
+	if err := scheduler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	fakeStore.mu.Lock()
+	defer fakeStore.mu.Unlock()
+	for _, tenant := range fakeStore.tenants {
+		if state, ok := fakeStore.states[tenant.ID]; !ok || state.State != store.ScanningStateStopped {
+			t.Fatalf("state for %s = %#v, %v; want a stopped row", tenant.ID, state, ok)
+		}
+	}
+}
+
 func TestRunTenantMapsAuthFailureToNeedsAuth(t *testing.T) {
 	now := time.Date(2026, 7, 4, 12, 0, 0, 0, time.UTC)
 	fakeStore := newFakeStore([]store.TenantScanningState{
@@ -94,6 +112,36 @@ func TestRunTenantMapsAuthFailureToNeedsAuth(t *testing.T) {
 	}
 }
 
+func TestRunTenantPublishesStateTransitions(t *testing.T) {
+	fakeStore := newFakeStore([]store.TenantScanningState{
+		{TenantID: "tenant-a", ActiveReader: "gmail", Enabled: true, State: store.ScanningStateQueued},
+	})
+	broker := events.NewBroker(16, nil)
+	sub, _ := broker.Subscribe("tenant-a", "")
+	defer sub.Close()
+	runner := &staticRunner{err: apperrors.E(apperrors.User("Reconnect your reader account."), errors.New("oauth2: invalid_grant"))}
+	scheduler := newScheduler(t, Config{Store: fakeStore, Runner: runner, Events: broker})
+
+	if err := scheduler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	for _, want := range []string{
+		`{"reader":"gmail","state":"starting"}`,
+		`{"reader":"gmail","state":"running"}`,
+		`{"reader":"gmail","state":"needs_auth","reason_code":"needs_auth_invalid_grant","public_message":"Reconnect your reader account."}`,
+	} {
+		select {
+		case event := <-sub.Events():
+			if event.Type != events.TypeScanState || string(event.Data) != want {
+				t.Fatalf("event = %s %s, want %s", event.Type, event.Data, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+}
+
 func TestRunTenantMapsStructuredFailuresToScanState(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -295,7 +343,7 @@ func (c fixedClock) Now() time.Time {
 type fakeSchedulerStore struct {
 	mu      sync.Mutex
 	cfg     store.SchedulerConfig
-	users   []store.User
+	tenants []store.Tenant
 	order   []string
 	states  map[string]store.TenantScanningState
 	updates chan store.TenantScanningState
@@ -308,17 +356,17 @@ func newFakeStore(states []store.TenantScanningState) *fakeSchedulerStore {
 		updates: make(chan store.TenantScanningState, 32),
 	}
 	for _, state := range states {
-		fake.users = append(fake.users, store.User{ID: state.TenantID, TenantID: state.TenantID})
+		fake.tenants = append(fake.tenants, store.Tenant{ID: state.TenantID})
 		fake.order = append(fake.order, state.TenantID)
 		fake.states[state.TenantID] = state
 	}
 	return fake
 }
 
-func (s *fakeSchedulerStore) ListUsers(_ context.Context) ([]store.User, error) {
+func (s *fakeSchedulerStore) ListActiveTenants(_ context.Context) ([]store.Tenant, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return append([]store.User(nil), s.users...), nil
+	return append([]store.Tenant(nil), s.tenants...), nil
 }
 
 func (s *fakeSchedulerStore) EnsureScanningStateForTenant(_ context.Context, tenant store.Tenant) error {
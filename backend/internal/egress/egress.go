@@ -0,0 +1,75 @@
+// Package egress builds HTTP clients for URLs that users configure, such as
+// webhook endpoints and notification servers. The clients refuse to connect
+// to loopback, link-local, private and other internal addresses, so such a
+// URL cannot be used to reach services on the instance's own host or
+// network. Admins allow LAN targets, like a Home Assistant server, by
+// network.
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked is wrapped by the dial errors of connections to addresses that
+// are not allowed.
+var ErrBlocked = errors.New("connecting to this address is not allowed")
+
+// internal are blocked networks that netip.Addr has no predicate for.
+var internal = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Carrier-grade NAT, also used by Tailscale and other overlay networks.
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// Blocked reports whether connecting to addr is refused when only allowed
+// networks may be reached beyond the public internet.
+func Blocked(addr netip.Addr, allowed []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsMulticast() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, prefix := range internal {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient returns a client that times out after timeout and only connects
+// to public addresses and the allowed networks. Addresses are checked as each
+// connection is made, after name resolution, so a name that later resolves
+// somewhere else is caught too. Environment proxies are not used, since they
+// would hide the address being reached.
+func NewClient(timeout time.Duration, allowed []netip.Prefix) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrBlocked, address)
+			}
+			if Blocked(addrPort.Addr(), allowed) {
+				return fmt.Errorf("%w: %s", ErrBlocked, addrPort.Addr().Unmap())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,68 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestBlocked(t *testing.T) {
+	lan := []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+	tests := []struct {
+		addr    string
+		allowed []netip.Prefix
+		want    bool
+	}{
+		{addr: "93.184.216.34", want: false},
+		{addr: "2606:4700::1111", want: false},
+		{addr: "127.0.0.1", want: true},
+		{addr: "::1", want: true},
+		{addr: "::ffff:127.0.0.1", want: true},
+		{addr: "169.254.169.254", want: true},
+		{addr: "fe80::1", want: true},
+		{addr: "10.0.0.5", want: true},
+		{addr: "172.18.0.2", want: true},
+		{addr: "fd00::1", want: true},
+		{addr: "0.0.0.0", want: true},
+		{addr: "100.100.100.100", want: true},
+		{addr: "224.0.0.1", want: true},
+		{addr: "192.168.1.20", want: true},
+		{addr: "192.168.1.20", allowed: lan, want: false},
+		{addr: "192.168.2.20", allowed: lan, want: true},
+	}
+	for _, tt := range tests {
+		if got := Blocked(netip.MustParseAddr(tt.addr), tt.allowed); got != tt.want {
+			t.Errorf("Blocked(%s, %v) = %v, want %v", tt.addr, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientRefusesBlockedAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	get := func(client *http.Client) error {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(NewClient(time.Second, nil)); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("request to loopback error = %v, want ErrBlocked", err)
+	}
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	if err := get(NewClient(time.Second, loopback)); err != nil {
+		t.Fatalf("request to allowed loopback error = %v", err)
+	}
+}
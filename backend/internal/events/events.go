@@ -0,0 +1,261 @@
+// Package events fans out live application events to the clients streaming
+// them. A bounded buffer of recent events lets a client that reconnects
+// resume from the last event it received.
+package events
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	// TypeScanState is published when the scheduler moves a tenant's
+	// scanning to another state. Its data is a ScanState.
+	TypeScanState = "scan.state"
+	// TypeDaemonStatus is published when an interactive daemon run starts
+	// or stops. Its data is a DaemonStatus.
+	TypeDaemonStatus = "daemon.status"
+	// TypeTransactionBatch is published when a batch of transactions is
+	// stored. Its data is a TransactionBatch.
+	TypeTransactionBatch = "transactions.batch"
+	// TypeDiagnosticCreated is published when an email first fails
+	// extraction. Its data is a Diagnostic.
+	TypeDiagnosticCreated = "diagnostic.created"
+	// TypeCommunitySync is published to every tenant when a community sync
+	// finishes. Its data is a CommunitySync.
+	TypeCommunitySync = "community.sync"
+	// TypeReset tells a client that the events it missed are no longer
+	// buffered, so it has to reload its state.
+	TypeReset = "reset"
+)
+
+// ScanState is the data of scan.state events.
+type ScanState struct {
+	Reader        string     `json:"reader"`
+	State         string     `json:"state"`
+	ReasonCode    string     `json:"reason_code,omitempty"`
+	PublicMessage string     `json:"public_message,omitempty"`
+	NextRetryAt   *time.Time `json:"next_retry_at,omitempty"`
+}
+
+// DaemonStatus is the data of daemon.status events.
+type DaemonStatus struct {
+	Reader    string     `json:"reader"`
+	Running   bool       `json:"running"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// TransactionBatch is the data of transactions.batch events.
+type TransactionBatch struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+}
+
+// Diagnostic is the data of diagnostic.created events.
+type Diagnostic struct {
+	ID      string `json:"id"`
+	Reader  string `json:"reader"`
+	Source  string `json:"source"`
+	Subject string `json:"subject"`
+}
+
+// CommunitySync is the data of community.sync events. Error is set when the
+// sync failed.
+type CommunitySync struct {
+	SyncedAt       *time.Time `json:"synced_at,omitempty"`
+	EntriesUpdated int64      `json:"entries_updated"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// Event is one published event.
+type Event struct {
+	// ID is unique for the life of the process; a client sends the last
+	// one it received to resume.
+	ID   string
+	Type string
+	// TenantID is the tenant the event belongs to, or empty for events
+	// every tenant receives.
+	TenantID string
+	Data     json.RawMessage
+
+	seq uint64
+}
+
+// Publisher publishes events. Publishing never blocks on slow clients.
+type Publisher interface {
+	Publish(tenantID, eventType string, data any)
+}
+
+// Discard is a Publisher that drops every event.
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(string, string, any) {}
+
+// DefaultBufferSize is how many recent events a Broker keeps for clients
+// that reconnect.
+const DefaultBufferSize = 1024
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped.
+const subscriberBuffer = 64
+
+// Broker delivers published events to subscribers of their tenant and keeps
+// the most recent ones in a ring buffer.
+type Broker struct {
+	logger *slog.Logger
+	// epoch tells events of this process apart from those of an earlier
+	// one, whose IDs a reconnecting client may still send.
+	epoch string
+
+	mu     sync.Mutex
+	seq    uint64
+	buffer []Event
+	next   int
+	full   bool
+	subs   map[*Subscription]struct{}
+}
+
+// NewBroker returns a Broker that buffers the last size events.
+func NewBroker(size int, logger *slog.Logger) *Broker {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Broker{
+		logger: logger,
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer: make([]Event, size),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish delivers an event with data encoded as JSON to the subscribers of
+// tenantID, or of every tenant when tenantID is empty. A subscriber too far
+// behind to take the event is closed, so that its client reconnects and
+// resumes from the buffer.
+func (b *Broker) Publish(tenantID, eventType string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		b.logger.Warn("failed to encode event", "type", eventType, "error", err)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	event := Event{ID: b.id(b.seq), Type: eventType, TenantID: tenantID, Data: payload, seq: b.seq}
+	b.buffer[b.next] = event
+	b.next = (b.next + 1) % len(b.buffer)
+	b.full = b.full || b.next == 0
+
+	for sub := range b.subs {
+		if !sub.wants(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.logger.Info("dropping slow event subscriber", "tenant_id", sub.tenantID)
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts delivering the events of tenantID and returns those
+// published after lastEventID that are still buffered. When lastEventID is
+// set but the events after it are gone, or it came from an earlier process,
+// a reset event takes their place. The caller must close the subscription.
+func (b *Broker) Subscribe(tenantID, lastEventID string) (*Subscription, []Event) {
+	sub := &Subscription{broker: b, tenantID: tenantID, ch: make(chan Event, subscriberBuffer)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	if lastEventID == "" {
+		return sub, nil
+	}
+	last, ok := b.parseID(lastEventID)
+	if !ok || last > b.seq || last < b.oldest()-1 {
+		return sub, []Event{{ID: b.id(b.seq), Type: TypeReset, Data: json.RawMessage(`{}`), seq: b.seq}}
+	}
+	var missed []Event
+	for i := range b.buffered() {
+		event := b.buffer[(b.start()+i)%len(b.buffer)]
+		if event.seq > last && sub.wants(event) {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed
+}
+
+func (b *Broker) id(seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+func (b *Broker) parseID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+func (b *Broker) buffered() int {
+	if b.full {
+		return len(b.buffer)
+	}
+	return b.next
+}
+
+func (b *Broker) start() int {
+	if b.full {
+		return b.next
+	}
+	return 0
+}
+
+// oldest is the sequence number of the oldest buffered event.
+func (b *Broker) oldest() uint64 {
+	return b.seq - uint64(b.buffered()) + 1
+}
+
+// remove must be called with b.mu held.
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscription receives the events of one tenant.
+type Subscription struct {
+	broker   *Broker
+	tenantID string
+	ch       chan Event
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is closed or falls too far behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close stops delivering events.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+func (s *Subscription) wants(event Event) bool {
+	return event.TenantID == "" || event.TenantID == s.tenantID
+}
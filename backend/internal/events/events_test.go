@@ -0,0 +1,108 @@
+package events
+
+import (
+	"testing"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return event
+	default:
+		t.Fatal("no event delivered")
+		return Event{}
+	}
+}
+
+func TestPublishDeliversToTenantSubscribers(t *testing.T) {
+	b := NewBroker(8, nil)
+	a, _ := b.Subscribe("tenant-a", "")
+	defer a.Close()
+	other, _ := b.Subscribe("tenant-b", "")
+	defer other.Close()
+
+	b.Publish("tenant-a", TypeTransactionBatch, TransactionBatch{Created: []string{"txn-a"}})
+	b.Publish("", TypeCommunitySync, CommunitySync{EntriesUpdated: 3})
+
+	if got := receive(t, a); got.Type != TypeTransactionBatch || string(got.Data) != `{"created":["txn-a"],"updated":null}` {
+		t.Fatalf("tenant-a first event = %+v, data %s", got, got.Data)
+	}
+	if got := receive(t, a); got.Type != TypeCommunitySync {
+		t.Fatalf("tenant-a second event = %+v", got)
+	}
+	if got := receive(t, other); got.Type != TypeCommunitySync {
+		t.Fatalf("tenant-b event = %+v, want only the instance-wide event", got)
+	}
+}
+
+func TestSubscribeResumesAfterLastEventID(t *testing.T) {
+	b := NewBroker(8, nil)
+	sub, _ := b.Subscribe("tenant-a", "")
+	b.Publish("tenant-a", TypeScanState, ScanState{State: "starting"})
+	last := receive(t, sub)
+	sub.Close()
+	b.Publish("tenant-a", TypeScanState, ScanState{State: "running"})
+	b.Publish("tenant-b", TypeScanState, ScanState{State: "running"})
+	b.Publish("tenant-a", TypeScanState, ScanState{State: "queued"})
+
+	sub, missed := b.Subscribe("tenant-a", last.ID)
+	defer sub.Close()
+
+	if len(missed) != 2 || string(missed[0].Data) != `{"reader":"","state":"running"}` ||
+		string(missed[1].Data) != `{"reader":"","state":"queued"}` {
+		t.Fatalf("missed = %+v", missed)
+	}
+}
+
+func TestSubscribeResetsWhenEventsAreGone(t *testing.T) {
+	b := NewBroker(2, nil)
+	sub, _ := b.Subscribe("tenant-a", "")
+	b.Publish("tenant-a", TypeScanState, ScanState{State: "starting"})
+	first := receive(t, sub)
+	sub.Close()
+	for range 3 {
+		b.Publish("tenant-a", TypeScanState, ScanState{State: "running"})
+	}
+
+	for name, lastEventID := range map[string]string{
+		"evicted":       first.ID,
+		"other process": "0abc-1",
+		"malformed":     "nonsense",
+	} {
+		sub, missed := b.Subscribe("tenant-a", lastEventID)
+		sub.Close()
+		if len(missed) != 1 || missed[0].Type != TypeReset || missed[0].ID != b.id(4) {
+			t.Errorf("%s: missed = %+v, want one reset event", name, missed)
+		}
+	}
+
+	// The reset event's ID resumes from the current position.
+	b.Publish("tenant-a", TypeScanState, ScanState{State: "queued"})
+	sub, missed := b.Subscribe("tenant-a", b.id(4))
+	defer sub.Close()
+	if len(missed) != 1 || missed[0].Type != TypeScanState {
+		t.Fatalf("missed after reset = %+v", missed)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(DefaultBufferSize, nil)
+	sub, _ := b.Subscribe("tenant-a", "")
+
+	for range subscriberBuffer + 1 {
+		b.Publish("tenant-a", TypeScanState, ScanState{State: "running"})
+	}
+
+	for range subscriberBuffer {
+		receive(t, sub)
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("subscription still open, want it closed")
+	}
+	// Closing a dropped subscription is harmless.
+	sub.Close()
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// maxAccountMappings bounds each mapping table.
+	maxAccountMappings = 500
+	maxAccountLength   = 200
+)
+
+// Accounts maps Expensor sources and categories to journal accounts, e.g.
+// the source "HDFC credit-card Regalia" to "Liabilities:HDFC:Regalia" and the
+// category "Food & Dining" to "Expenses:Food". Lookups ignore case.
+// Unmapped sources and categories fall back to accounts derived from their
+// names.
+type Accounts struct {
+	// Sources is keyed by the source display name, as shown in the UI.
+	Sources map[string]string `json:"sources"`
+	// Categories is keyed by category name. Split allocations use their own
+	// category.
+	Categories map[string]string `json:"categories"`
+	// DefaultSource is used for transactions without a source.
+	DefaultSource string `json:"default_source,omitempty"`
+	// DefaultExpense is used for transactions without a category.
+	DefaultExpense string `json:"default_expense,omitempty"`
+}
+
+// Validate reports the first malformed account name or oversized table.
+func (a Accounts) Validate() error {
+	const op = "export.accounts.validate"
+	for name, table := range map[string]map[string]string{"sources": a.Sources, "categories": a.Categories} {
+		if len(table) > maxAccountMappings {
+			return errors.E(op, errors.InvalidInput, errors.User(fmt.Sprintf("%s maps more than %d accounts", name, maxAccountMappings)))
+		}
+		for key, account := range table {
+			if strings.TrimSpace(key) == "" {
+				return errors.E(op, errors.InvalidInput, errors.User(name+" keys must not be empty"))
+			}
+			if err := validateAccount(account); err != nil {
+				return errors.E(op, errors.InvalidInput, errors.User(fmt.Sprintf("%s[%q]: %v", name, key, err)))
+			}
+		}
+	}
+	for field, account := range map[string]string{"default_source": a.DefaultSource, "default_expense": a.DefaultExpense} {
+		if account == "" {
+			continue
+		}
+		if err := validateAccount(account); err != nil {
+			return errors.E(op, errors.InvalidInput, errors.User(fmt.Sprintf("%s: %v", field, err)))
+		}
+	}
+	return nil
+}
+
+// validateAccount accepts colon-separated account names that Ledger and
+// hledger can parse. Beancount's stricter rules are applied when writing.
+func validateAccount(account string) error {
+	if account == "" {
+		return fmt.Errorf("account must not be empty")
+	}
+	if len(account) > maxAccountLength {
+		return fmt.Errorf("account is longer than %d characters", maxAccountLength)
+	}
+	if strings.Contains(account, "  ") || strings.ContainsAny(account, "\t;") {
+		return fmt.Errorf("account must not contain tabs, semicolons or double spaces")
+	}
+	for _, r := range account {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("account must not contain control characters")
+		}
+	}
+	for _, segment := range strings.Split(account, ":") {
+		if strings.TrimSpace(segment) == "" || segment != strings.TrimSpace(segment) {
+			return fmt.Errorf("account segments must be non-empty and not padded with spaces")
+		}
+	}
+	return nil
+}
+
+// sourceAccount returns the account money leaves from when paying with the
+// transaction's source. Credit cards default to liabilities.
+func (a Accounts) sourceAccount(txn store.Transaction) string {
+	display := strings.TrimSpace(txn.Source.Display())
+	if account, ok := lookupAccount(a.Sources, display); ok {
+		return account
+	}
+	if display == "" {
+		if a.DefaultSource != "" {
+			return a.DefaultSource
+		}
+		return "Assets:Unknown"
+	}
+	root := "Assets"
+	if strings.Contains(strings.ToLower(txn.Source.Type), "credit") {
+		root = "Liabilities"
+	}
+	return root + ":" + accountSegment(display)
+}
+
+// expenseAccount returns the account a category's spending is booked to.
+func (a Accounts) expenseAccount(category string) string {
+	category = strings.TrimSpace(category)
+	if account, ok := lookupAccount(a.Categories, category); ok {
+		return account
+	}
+	if category == "" {
+		if a.DefaultExpense != "" {
+			return a.DefaultExpense
+		}
+		return "Expenses:Uncategorized"
+	}
+	return "Expenses:" + accountSegment(category)
+}
+
+func lookupAccount(table map[string]string, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	if account, ok := table[key]; ok {
+		return account, true
+	}
+	for name, account := range table {
+		if strings.EqualFold(name, key) {
+			return account, true
+		}
+	}
+	return "", false
+}
+
+// accountSegment turns a free-form name into one account segment: colons
+// would nest accounts and runs of spaces end an account name in Ledger.
+func accountSegment(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == ':' || r == ';' || unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
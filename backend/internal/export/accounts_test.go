@@ -0,0 +1,33 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestAccountsValidate(t *testing.T) {
+	valid := Accounts{
+		Sources:        map[string]string{"HDFC credit-card Regalia": "Liabilities:HDFC:Regalia Card"},
+		Categories:     map[string]string{"Food": "Expenses:Food"},
+		DefaultExpense: "Expenses:Misc",
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate(valid): %v", err)
+	}
+	for name, accounts := range map[string]Accounts{
+		"empty segment":  {Categories: map[string]string{"Food": "Expenses::Food"}},
+		"double space":   {Categories: map[string]string{"Food": "Expenses:Eating  Out"}},
+		"semicolon":      {Sources: map[string]string{"Card": "Liabilities:Card;x"}},
+		"empty key":      {Sources: map[string]string{" ": "Assets:Cash"}},
+		"newline":        {DefaultSource: "Assets:\nCash"},
+		"too long":       {DefaultExpense: "Expenses:" + strings.Repeat("x", maxAccountLength)},
+		"padded segment": {DefaultSource: "Assets: Cash"},
+		"empty account":  {Categories: map[string]string{"Food": ""}},
+	} {
+		if err := accounts.Validate(); errors.WhatKind(err) != errors.InvalidInput {
+			t.Errorf("%s: kind = %v, want invalid input", name, errors.WhatKind(err))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+type csvWriter struct {
+	w      *csv.Writer
+	opts   Options
+	header bool
+}
+
+func newCSVWriter(w io.Writer, opts Options) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), opts: opts}
+}
+
+func (c *csvWriter) Write(txn store.Transaction) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	r := newRow(txn, c.opts.Location)
+	return c.w.Write([]string{
+		r.id,
+		r.timestamp.Format(time.RFC3339),
+		spreadsheetText(r.merchant),
+		strconv.FormatFloat(r.amount, 'f', -1, 64),
+		r.currency,
+		formatOptionalFloat(r.originalAmount),
+		r.originalCurrency,
+		formatOptionalFloat(r.exchangeRate),
+		spreadsheetText(r.category),
+		spreadsheetText(r.bucket),
+		spreadsheetText(r.source),
+		spreadsheetText(r.sourceType),
+		spreadsheetText(r.sourceLabel),
+		spreadsheetText(r.bank),
+		spreadsheetText(r.labels),
+		spreadsheetText(r.description),
+		strconv.FormatBool(r.muted),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	// An export with no transactions still gets its header row.
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(columns)
+}
+
+// spreadsheetText neutralises values a spreadsheet would evaluate as a
+// formula. Merchant names and descriptions come from emails, so a value like
+// "=HYPERLINK(...)" must not run when the accountant opens the file.
+func spreadsheetText(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
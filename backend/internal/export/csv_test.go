@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVWriter(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	records, err := csv.NewReader(strings.NewReader(render(t, FormatCSV, Options{Location: ist}))).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(columns, ",") {
+		t.Fatalf("records = %#v", records)
+	}
+	first := records[1]
+	if first[1] != "2026-04-02T01:45:00+05:30" || first[3] != "450.5" || first[10] != "HDFC credit-card Regalia" || first[14] != "work trip;reimbursable" {
+		t.Fatalf("first row = %#v", first)
+	}
+	second := records[2]
+	if second[2] != `'=HYPERLINK("http://x")` {
+		t.Fatalf("formula-like merchant = %q, want it neutralised", second[2])
+	}
+	if second[5] != "12.5" || second[6] != "USD" || second[7] != "83.2" || second[16] != "true" {
+		t.Fatalf("second row = %#v", second)
+	}
+}
+
+func TestCSVWriterWritesHeaderWithoutRows(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != strings.Join(columns, ",") {
+		t.Fatalf("empty export = %q", got)
+	}
+}
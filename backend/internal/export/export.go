@@ -0,0 +1,171 @@
+// Package export writes transactions in formats other tools can import:
+// spreadsheets (CSV, XLSX), bank statements (OFX) and plain-text accounting
+// journals (Ledger, hledger, Beancount). Every writer streams, so exports of
+// any size are produced one transaction at a time.
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// Format names an export format.
+type Format string
+
+const (
+	FormatCSV       Format = "csv"
+	FormatXLSX      Format = "xlsx"
+	FormatOFX       Format = "ofx"
+	FormatLedger    Format = "ledger"
+	FormatHledger   Format = "hledger"
+	FormatBeancount Format = "beancount"
+)
+
+// Formats lists every supported format.
+var Formats = []Format{FormatCSV, FormatXLSX, FormatOFX, FormatLedger, FormatHledger, FormatBeancount}
+
+// ContentType returns the MIME type of files in format f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatOFX:
+		return "application/x-ofx"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Extension returns the conventional file extension for format f.
+func (f Format) Extension() string {
+	switch f {
+	case FormatLedger:
+		return "ledger"
+	case FormatHledger:
+		return "journal"
+	default:
+		return string(f)
+	}
+}
+
+// Options configures a Writer.
+type Options struct {
+	// Accounts maps sources and categories to journal accounts.
+	Accounts Accounts
+	// BaseCurrency is the OFX statement currency. Defaults to INR.
+	BaseCurrency string
+	// PeriodStart and PeriodEnd bound the OFX statement. When unset, the
+	// start is the first exported transaction and the end is Now.
+	PeriodStart *time.Time
+	PeriodEnd   *time.Time
+	// Location renders dates in the tenant's timezone. Defaults to UTC.
+	Location *time.Location
+	// Now is the export time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Writer encodes transactions in one format.
+type Writer interface {
+	// Write encodes one transaction.
+	Write(txn store.Transaction) error
+	// Close writes any trailer and flushes buffered output. It does not close
+	// the underlying io.Writer.
+	Close() error
+}
+
+// NewWriter returns a Writer for format f that writes to w.
+func NewWriter(f Format, w io.Writer, opts Options) (Writer, error) {
+	if opts.BaseCurrency == "" {
+		opts.BaseCurrency = "INR"
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w, opts), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, opts)
+	case FormatOFX:
+		return newOFXWriter(w, opts), nil
+	case FormatLedger, FormatHledger, FormatBeancount:
+		return newJournalWriter(f, w, opts), nil
+	default:
+		return nil, errors.E("export.new_writer", errors.InvalidArgument, errors.User(fmt.Sprintf("unsupported export format %q", f)))
+	}
+}
+
+// columns are the CSV and XLSX column headings, in order.
+var columns = []string{
+	"id", "date", "merchant", "amount", "currency", "original_amount", "original_currency", "exchange_rate",
+	"category", "bucket", "source", "source_type", "source_label", "bank", "labels", "description", "muted",
+}
+
+// row is one transaction as spreadsheet cells. Amount cells hold numbers so
+// spreadsheets can sum them; the rest are text.
+type row struct {
+	id, merchant, currency, originalCurrency          string
+	category, bucket, source, sourceType, sourceLabel string
+	bank, labels, description                         string
+	timestamp                                         time.Time
+	amount                                            float64
+	originalAmount, exchangeRate                      *float64
+	muted                                             bool
+}
+
+func newRow(txn store.Transaction, loc *time.Location) row {
+	r := row{
+		id:             txn.ID,
+		merchant:       txn.MerchantInfo,
+		currency:       txn.Currency,
+		category:       txn.Category,
+		bucket:         txn.Bucket,
+		source:         txn.Source.Display(),
+		sourceType:     txn.Source.Type,
+		sourceLabel:    txn.Source.Label,
+		bank:           txn.Source.Bank,
+		labels:         strings.Join(txn.Labels, ";"),
+		description:    txn.Description,
+		timestamp:      txn.Timestamp.In(loc),
+		amount:         txn.Amount,
+		originalAmount: txn.OriginalAmount,
+		exchangeRate:   txn.ExchangeRate,
+		muted:          txn.Muted,
+	}
+	if txn.OriginalCurrency != nil {
+		r.originalCurrency = *txn.OriginalCurrency
+	}
+	return r
+}
+
+// formatAmount renders an amount with two decimals, which every supported
+// journal format accepts.
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(math.Round(amount*100)/100, 'f', 2, 64)
+}
+
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+// singleLine collapses whitespace and control characters so a value fits on
+// one line of output.
+func singleLine(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }), " ")
+}
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/api"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+var exportNow = time.Date(2026, time.April, 30, 18, 0, 0, 0, time.UTC)
+
+func fixtureTransactions() []store.Transaction {
+	usd := "USD"
+	original, rate := 12.5, 83.2
+	return []store.Transaction{
+		{
+			ID:           "00000000-0000-0000-0000-000000000001",
+			Amount:       450.5,
+			Currency:     "INR",
+			Timestamp:    time.Date(2026, time.April, 1, 20, 15, 0, 0, time.UTC),
+			MerchantInfo: "Swiggy; Koramangala",
+			Category:     "Food & Dining",
+			Source:       api.Source{Type: "credit-card", Label: "Regalia", Bank: "HDFC"},
+			Description:  "team dinner\nwith \"friends\"",
+			Labels:       []string{"work trip", "reimbursable"},
+		},
+		{
+			ID:               "00000000-0000-0000-0000-000000000002",
+			Amount:           1040,
+			Currency:         "INR",
+			OriginalAmount:   &original,
+			OriginalCurrency: &usd,
+			ExchangeRate:     &rate,
+			Timestamp:        time.Date(2026, time.April, 2, 9, 0, 0, 0, time.UTC),
+			MerchantInfo:     "=HYPERLINK(\"http://x\")",
+			Source:           api.Source{Type: "upi", Bank: "ICICI"},
+			Muted:            true,
+			Splits: []store.TransactionSplit{
+				{Amount: 640, Category: "Groceries"},
+				{Amount: 400},
+			},
+		},
+	}
+}
+
+func render(t *testing.T, f Format, opts Options) string {
+	t.Helper()
+	if opts.Now == nil {
+		opts.Now = func() time.Time { return exportNow }
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(f, &buf, opts)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", f, err)
+	}
+	for _, txn := range fixtureTransactions() {
+		if err := w.Write(txn); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.String()
+}
+
+func TestNewWriterRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewWriter("qif", &bytes.Buffer{}, Options{}); errors.WhatKind(err) != errors.InvalidArgument {
+		t.Fatalf("kind = %v, want invalid argument", errors.WhatKind(err))
+	}
+}
@@ -0,0 +1,272 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+// journalWriter writes plain-text accounting entries. Each transaction books
+// its amount (or each split allocation) to an expense account and balances
+// against the source account, whose amount is left for the tool to infer.
+type journalWriter struct {
+	format Format
+	w      *bufio.Writer
+	opts   Options
+	header bool
+	// opened records the first date each account is used, for Beancount's
+	// open directives.
+	opened map[string]time.Time
+}
+
+type posting struct {
+	account string
+	amount  float64
+}
+
+func newJournalWriter(f Format, w io.Writer, opts Options) *journalWriter {
+	return &journalWriter{format: f, w: bufio.NewWriter(w), opts: opts, opened: map[string]time.Time{}}
+}
+
+func (j *journalWriter) Write(txn store.Transaction) error {
+	j.writeHeader()
+	date := txn.Timestamp.In(j.opts.Location)
+	postings, source := j.postings(txn)
+
+	switch j.format {
+	case FormatBeancount:
+		j.writeBeancount(txn, date, postings, source)
+	default:
+		j.writeLedger(txn, date, postings, source)
+	}
+	_, err := j.w.WriteString("\n")
+	return err
+}
+
+func (j *journalWriter) Close() error {
+	j.writeHeader()
+	if j.format == FormatBeancount && len(j.opened) > 0 {
+		accounts := make([]string, 0, len(j.opened))
+		for account := range j.opened {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+		for _, account := range accounts {
+			fmt.Fprintf(j.w, "%s open %s\n", j.opened[account].Format("2006-01-02"), account)
+		}
+	}
+	return j.w.Flush()
+}
+
+func (j *journalWriter) writeHeader() {
+	if j.header {
+		return
+	}
+	j.header = true
+	fmt.Fprintf(j.w, "; Exported from Expensor on %s\n\n", j.opts.Now().In(j.opts.Location).Format(time.RFC3339))
+}
+
+func (j *journalWriter) postings(txn store.Transaction) ([]posting, string) {
+	source := j.opts.Accounts.sourceAccount(txn)
+	if len(txn.Splits) == 0 {
+		return []posting{{account: j.opts.Accounts.expenseAccount(txn.Category), amount: txn.Amount}}, source
+	}
+	postings := make([]posting, 0, len(txn.Splits))
+	for _, split := range txn.Splits {
+		category := split.Category
+		if category == "" {
+			category = txn.Category
+		}
+		postings = append(postings, posting{account: j.opts.Accounts.expenseAccount(category), amount: split.Amount})
+	}
+	return postings, source
+}
+
+// writeLedger writes a Ledger or hledger entry. The two share a syntax except
+// for the date separator and how tags are spelled.
+func (j *journalWriter) writeLedger(txn store.Transaction, date time.Time, postings []posting, source string) {
+	layout := "2006/01/02"
+	if j.format == FormatHledger {
+		layout = "2006-01-02"
+	}
+	fmt.Fprintf(j.w, "%s * %s\n", date.Format(layout), ledgerText(payee(txn)))
+	if description := ledgerText(txn.Description); description != "" {
+		fmt.Fprintf(j.w, "    ; %s\n", description)
+	}
+	fmt.Fprintf(j.w, "    ; expensor_id: %s\n", txn.ID)
+	if txn.Muted {
+		fmt.Fprintf(j.w, "    ; muted: true\n")
+	}
+	if tags := j.ledgerTags(txn.Labels); tags != "" {
+		fmt.Fprintf(j.w, "    ; %s\n", tags)
+	}
+	currency := ledgerCommodity(txn.Currency)
+	for _, p := range postings {
+		fmt.Fprintf(j.w, "    %s  %s %s\n", p.account, formatAmount(p.amount), currency)
+	}
+	fmt.Fprintf(j.w, "    %s\n", source)
+}
+
+func (j *journalWriter) ledgerTags(labels []string) string {
+	tags := make([]string, 0, len(labels))
+	for _, label := range labels {
+		tag := tagName(label)
+		if tag == "" {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	if j.format == FormatHledger {
+		return strings.Join(tags, ":, ") + ":"
+	}
+	return ":" + strings.Join(tags, ":") + ":"
+}
+
+func (j *journalWriter) writeBeancount(txn store.Transaction, date time.Time, postings []posting, source string) {
+	fmt.Fprintf(j.w, "%s * %s %s", date.Format("2006-01-02"), beancountString(payee(txn)), beancountString(txn.Description))
+	for _, label := range txn.Labels {
+		if tag := tagName(label); tag != "" {
+			fmt.Fprintf(j.w, " #%s", tag)
+		}
+	}
+	j.w.WriteString("\n")
+	fmt.Fprintf(j.w, "  expensor_id: %s\n", beancountString(txn.ID))
+	if txn.Muted {
+		j.w.WriteString("  muted: TRUE\n")
+	}
+	currency := beancountCurrency(txn.Currency)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	for _, p := range postings {
+		account := beancountAccount(p.account, "Expenses")
+		j.open(account, day)
+		fmt.Fprintf(j.w, "  %s  %s %s\n", account, formatAmount(p.amount), currency)
+	}
+	account := beancountAccount(source, "Assets")
+	j.open(account, day)
+	fmt.Fprintf(j.w, "  %s\n", account)
+}
+
+func (j *journalWriter) open(account string, day time.Time) {
+	if first, ok := j.opened[account]; !ok || day.Before(first) {
+		j.opened[account] = day
+	}
+}
+
+func payee(txn store.Transaction) string {
+	if strings.TrimSpace(txn.MerchantInfo) != "" {
+		return txn.MerchantInfo
+	}
+	return "Unknown merchant"
+}
+
+// ledgerText flattens a value onto one line. Semicolons start a comment in
+// Ledger, so they are replaced.
+func ledgerText(value string) string {
+	return singleLine(strings.ReplaceAll(value, ";", ","))
+}
+
+func ledgerCommodity(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return "INR"
+	}
+	for _, r := range currency {
+		if !unicode.IsLetter(r) {
+			// Commodities with digits or symbols must be quoted.
+			return `"` + strings.ReplaceAll(currency, `"`, "") + `"`
+		}
+	}
+	return currency
+}
+
+// tagName reduces a label to characters every journal format accepts in a tag.
+func tagName(label string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(label) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == ':' || r == '/' || r == '.':
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func beancountString(value string) string {
+	value = ledgerText(value)
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+func beancountCurrency(currency string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(strings.TrimSpace(currency)) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	out := b.String()
+	if out == "" || out[0] < 'A' || out[0] > 'Z' {
+		return "INR"
+	}
+	return out
+}
+
+var beancountRoots = map[string]bool{"Assets": true, "Liabilities": true, "Equity": true, "Income": true, "Expenses": true}
+
+// beancountAccount rewrites account into Beancount's stricter grammar: a
+// standard root followed by components that start with a capital letter or
+// digit and contain only letters, digits and dashes. Accounts outside the
+// standard roots are nested under fallbackRoot.
+func beancountAccount(account, fallbackRoot string) string {
+	segments := strings.Split(account, ":")
+	components := make([]string, 0, len(segments)+1)
+	if !beancountRoots[segments[0]] {
+		components = append(components, fallbackRoot)
+	}
+	for i, segment := range segments {
+		if i == 0 && beancountRoots[segment] {
+			components = append(components, segment)
+			continue
+		}
+		if component := beancountComponent(segment); component != "" {
+			components = append(components, component)
+		}
+	}
+	if len(components) == 1 {
+		components = append(components, "Unknown")
+	}
+	return strings.Join(components, ":")
+}
+
+func beancountComponent(segment string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range segment {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	out := []rune(b.String())
+	if len(out) == 0 {
+		return ""
+	}
+	out[0] = unicode.ToUpper(out[0])
+	return string(out)
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLedgerWriter(t *testing.T) {
+	out := render(t, FormatLedger, Options{Accounts: Accounts{
+		Sources:    map[string]string{"hdfc CREDIT-CARD regalia": "Liabilities:HDFC:Regalia"},
+		Categories: map[string]string{"Groceries": "Expenses:Home:Groceries"},
+	}})
+	want := `; Exported from Expensor on 2026-04-30T18:00:00Z
+
+2026/04/01 * Swiggy, Koramangala
+    ; team dinner with "friends"
+    ; expensor_id: 00000000-0000-0000-0000-000000000001
+    ; :work-trip:reimbursable:
+    Expenses:Food & Dining  450.50 INR
+    Liabilities:HDFC:Regalia
+
+2026/04/02 * =HYPERLINK("http://x")
+    ; expensor_id: 00000000-0000-0000-0000-000000000002
+    ; muted: true
+    Expenses:Home:Groceries  640.00 INR
+    Expenses:Uncategorized  400.00 INR
+    Assets:ICICI upi
+
+`
+	if out != want {
+		t.Fatalf("ledger output =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestHledgerWriterUsesISODatesAndTagSyntax(t *testing.T) {
+	out := render(t, FormatHledger, Options{})
+	for _, want := range []string{"2026-04-01 * Swiggy, Koramangala", "    ; work-trip:, reimbursable:", "    Liabilities:HDFC credit-card Regalia"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("hledger output does not contain %q\n%s", want, out)
+		}
+	}
+}
+
+func TestBeancountWriter(t *testing.T) {
+	out := render(t, FormatBeancount, Options{Accounts: Accounts{
+		Sources:        map[string]string{"ICICI upi": "Bank:ICICI Savings"},
+		DefaultExpense: "Expenses:Misc",
+	}})
+	for _, want := range []string{
+		`2026-04-01 * "Swiggy, Koramangala" "team dinner with \"friends\"" #work-trip #reimbursable`,
+		`  expensor_id: "00000000-0000-0000-0000-000000000001"`,
+		"  Expenses:Food-Dining  450.50 INR\n  Liabilities:HDFC-credit-card-Regalia\n",
+		"  muted: TRUE\n",
+		"  Expenses:Groceries  640.00 INR\n  Expenses:Misc  400.00 INR\n  Assets:Bank:ICICI-Savings\n",
+		"2026-04-01 open Expenses:Food-Dining\n",
+		"2026-04-02 open Assets:Bank:ICICI-Savings\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("beancount output does not contain %q\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+const ofxTimeLayout = "20060102150405"
+
+// ofxWriter writes one OFX 2.2 bank statement holding every exported
+// transaction as a debit. The statement header needs the period start, so it
+// is written lazily with the first transaction.
+type ofxWriter struct {
+	w      *bufio.Writer
+	opts   Options
+	header bool
+}
+
+func newOFXWriter(w io.Writer, opts Options) *ofxWriter {
+	return &ofxWriter{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (o *ofxWriter) Write(txn store.Transaction) error {
+	o.writeHeader(txn.Timestamp)
+	o.w.WriteString("<STMTTRN>")
+	o.w.WriteString("<TRNTYPE>DEBIT</TRNTYPE>")
+	o.element("DTPOSTED", ofxTime(txn.Timestamp))
+	o.element("TRNAMT", formatAmount(-txn.Amount))
+	o.element("FITID", txn.ID)
+	o.element("NAME", truncateRunes(payee(txn), 32))
+	if memo := ofxMemo(txn); memo != "" {
+		o.element("MEMO", truncateRunes(memo, 255))
+	}
+	if txn.OriginalCurrency != nil && txn.ExchangeRate != nil && *txn.OriginalCurrency != "" {
+		o.w.WriteString("<ORIGCURRENCY>")
+		o.element("CURRATE", formatOptionalFloat(txn.ExchangeRate))
+		o.element("CURSYM", strings.ToUpper(*txn.OriginalCurrency))
+		o.w.WriteString("</ORIGCURRENCY>")
+	}
+	_, err := o.w.WriteString("</STMTTRN>\n")
+	return err
+}
+
+func (o *ofxWriter) Close() error {
+	o.writeHeader(o.opts.Now())
+	o.w.WriteString("</BANKTRANLIST>")
+	// Expensor does not track account balances; OFX requires one anyway.
+	o.w.WriteString("<LEDGERBAL><BALAMT>0.00</BALAMT>")
+	o.element("DTASOF", ofxTime(o.periodEnd()))
+	o.w.WriteString("</LEDGERBAL>")
+	o.w.WriteString("</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>\n")
+	return o.w.Flush()
+}
+
+func (o *ofxWriter) writeHeader(first time.Time) {
+	if o.header {
+		return
+	}
+	o.header = true
+	start := first
+	if o.opts.PeriodStart != nil {
+		start = *o.opts.PeriodStart
+	}
+	now := o.opts.Now()
+	o.w.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n")
+	o.w.WriteString(`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n")
+	o.w.WriteString("<OFX><SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>")
+	o.element("DTSERVER", ofxTime(now))
+	o.w.WriteString("<LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n")
+	o.w.WriteString("<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><STMTRS>")
+	o.element("CURDEF", strings.ToUpper(o.opts.BaseCurrency))
+	o.w.WriteString("<BANKACCTFROM><BANKID>EXPENSOR</BANKID><ACCTID>EXPENSOR</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n")
+	o.w.WriteString("<BANKTRANLIST>")
+	o.element("DTSTART", ofxTime(start))
+	o.element("DTEND", ofxTime(o.periodEnd()))
+	o.w.WriteString("\n")
+}
+
+func (o *ofxWriter) periodEnd() time.Time {
+	if o.opts.PeriodEnd != nil {
+		return *o.opts.PeriodEnd
+	}
+	return o.opts.Now()
+}
+
+func (o *ofxWriter) element(name, value string) {
+	fmt.Fprintf(o.w, "<%s>", name)
+	_ = xml.EscapeText(o.w, []byte(value))
+	fmt.Fprintf(o.w, "</%s>", name)
+}
+
+// ofxTime formats t in UTC with the explicit zone suffix OFX readers expect.
+func ofxTime(t time.Time) string {
+	return t.UTC().Format(ofxTimeLayout) + "[0:GMT]"
+}
+
+func ofxMemo(txn store.Transaction) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{txn.Category, txn.Description, txn.Source.Display()} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return singleLine(strings.Join(parts, " | "))
+}
+
+func truncateRunes(value string, limit int) string {
+	if utf8.RuneCountInString(value) <= limit {
+		return value
+	}
+	return string([]rune(value)[:limit])
+}
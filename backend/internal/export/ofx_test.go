@@ -0,0 +1,35 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOFXWriter(t *testing.T) {
+	start := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	out := render(t, FormatOFX, Options{BaseCurrency: "inr", PeriodStart: &start})
+
+	decoder := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("OFX is not well-formed: %v\n%s", err, out)
+		}
+	}
+	for _, want := range []string{
+		`<?OFX OFXHEADER="200" VERSION="220"`,
+		"<CURDEF>INR</CURDEF>",
+		"<DTSTART>20260401000000[0:GMT]</DTSTART><DTEND>20260430180000[0:GMT]</DTEND>",
+		"<TRNAMT>-450.50</TRNAMT><FITID>00000000-0000-0000-0000-000000000001</FITID><NAME>Swiggy; Koramangala</NAME>",
+		"<MEMO>Food &amp; Dining | team dinner with &#34;friends&#34; | HDFC credit-card Regalia</MEMO>",
+		"<ORIGCURRENCY><CURRATE>83.2</CURRATE><CURSYM>USD</CURSYM></ORIGCURRENCY>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("OFX does not contain %q\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+// The workbook is written as a minimal SpreadsheetML package: static parts
+// first, then the single worksheet streamed row by row. Strings are inline,
+// so no shared-string table has to be built up front.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Transactions" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	// Style 1 renders date serials as timestamps; style 2 bolds the header.
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm"/></numFmts>` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`
+	xlsxSheetHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetTail = `</sheetData></worksheet>`
+)
+
+// excelEpoch is day zero of the 1900 date system, adjusted for Excel's
+// fictional 29 February 1900.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+type xlsxWriter struct {
+	zip  *zip.Writer
+	buf  *bufio.Writer
+	opts Options
+	rows int
+}
+
+func newXLSXWriter(w io.Writer, opts Options) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{zip: zw, buf: bufio.NewWriter(sheet), opts: opts}
+	x.buf.WriteString(xlsxSheetHead)
+	x.startRow()
+	for i, heading := range columns {
+		x.stringCell(i, heading, 2)
+	}
+	x.buf.WriteString("</row>")
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(txn store.Transaction) error {
+	r := newRow(txn, x.opts.Location)
+	x.startRow()
+	x.stringCell(0, r.id, 0)
+	x.dateCell(1, r.timestamp)
+	x.stringCell(2, r.merchant, 0)
+	x.numberCell(3, &r.amount)
+	x.stringCell(4, r.currency, 0)
+	x.numberCell(5, r.originalAmount)
+	x.stringCell(6, r.originalCurrency, 0)
+	x.numberCell(7, r.exchangeRate)
+	x.stringCell(8, r.category, 0)
+	x.stringCell(9, r.bucket, 0)
+	x.stringCell(10, r.source, 0)
+	x.stringCell(11, r.sourceType, 0)
+	x.stringCell(12, r.sourceLabel, 0)
+	x.stringCell(13, r.bank, 0)
+	x.stringCell(14, r.labels, 0)
+	x.stringCell(15, r.description, 0)
+	fmt.Fprintf(x.buf, `<c r="%s" t="b"><v>%d</v></c>`, x.ref(16), boolInt(r.muted))
+	_, err := x.buf.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.buf.WriteString(xlsxSheetTail); err != nil {
+		return err
+	}
+	if err := x.buf.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+func (x *xlsxWriter) startRow() {
+	x.rows++
+	fmt.Fprintf(x.buf, `<row r="%d">`, x.rows)
+}
+
+// ref returns the A1-style reference of column col in the current row.
+// There are fewer than 26 columns, so one letter is enough.
+func (x *xlsxWriter) ref(col int) string {
+	return string(rune('A'+col)) + strconv.Itoa(x.rows)
+}
+
+func (x *xlsxWriter) stringCell(col int, value string, style int) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(x.buf, `<c r="%s" t="inlineStr"`, x.ref(col))
+	if style != 0 {
+		fmt.Fprintf(x.buf, ` s="%d"`, style)
+	}
+	x.buf.WriteString(`><is><t xml:space="preserve">`)
+	_ = xml.EscapeText(x.buf, []byte(value))
+	x.buf.WriteString(`</t></is></c>`)
+}
+
+func (x *xlsxWriter) numberCell(col int, value *float64) {
+	if value == nil {
+		return
+	}
+	fmt.Fprintf(x.buf, `<c r="%s"><v>%s</v></c>`, x.ref(col), strconv.FormatFloat(*value, 'f', -1, 64))
+}
+
+func (x *xlsxWriter) dateCell(col int, t time.Time) {
+	// Spreadsheets have no timezones: the serial encodes the local wall clock.
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	serial := wall.Sub(excelEpoch).Hours() / 24
+	fmt.Fprintf(x.buf, `<c r="%s" s="1"><v>%s</v></c>`, x.ref(col), strconv.FormatFloat(serial, 'f', -1, 64))
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
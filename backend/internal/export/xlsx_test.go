@@ -0,0 +1,53 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestXLSXWriterProducesWellFormedWorkbook(t *testing.T) {
+	out := render(t, FormatXLSX, Options{})
+	zr, err := zip.NewReader(bytes.NewReader([]byte(out)), int64(len(out)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		parts[f.Name] = string(body)
+		decoder := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", f.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("workbook is missing %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<row r="3">`,
+		`<c r="B2" s="1"><v>46113.84375</v></c>`,
+		`<c r="D2"><v>450.5</v></c>`,
+		`team dinner`,
+		`<c r="Q3" t="b"><v>1</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %q", want)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package extractor
 
 import (
+	"html"
 	"regexp"
 	"strconv"
 	"strings"
@@ -10,7 +11,10 @@ import (
 	"github.com/ArionMiles/expensor/backend/pkg/api"
 )
 
-var htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+var (
+	htmlTagPattern   = regexp.MustCompile(`<[^>]+>`)
+	htmlBlockPattern = regexp.MustCompile(`(?is)<(?:style|script|head)\b[^>]*>.*?</(?:style|script|head)>`)
+)
 
 // ExtractTransactionDetails extracts transaction details from an email body using regex patterns.
 //
@@ -85,3 +89,11 @@ func extractCurrency(body string, re *regexp.Regexp) string {
 	}
 	return strings.TrimSpace(m[1])
 }
+
+// PlainText reduces an email body to whitespace-collapsed text for search
+// indexing. Markup, style and script blocks are dropped and entities decoded.
+func PlainText(body string) string {
+	withoutBlocks := htmlBlockPattern.ReplaceAllString(body, " ")
+	withoutTags := htmlTagPattern.ReplaceAllString(withoutBlocks, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(withoutTags)), " ")
+}
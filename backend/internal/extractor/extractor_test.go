@@ -491,3 +491,15 @@ func TestExtractTransactionDetails_NilRegex(t *testing.T) {
 		t.Error("expected timestamp to be set even with nil regexes")
 	}
 }
+
+func TestPlainText(t *testing.T) {
+	body := `<html><head><style>p { color: red; }</style></head>
+<body><p>Order&nbsp;#402-1234 shipped</p><script>track()</script>
+<p>to Pune &amp; Mumbai</p></body></html>`
+
+	got := PlainText(body)
+
+	if want := "Order #402-1234 shipped to Pune & Mumbai"; got != want {
+		t.Errorf("PlainText() = %q, want %q", got, want)
+	}
+}
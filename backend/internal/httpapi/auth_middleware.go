@@ -2,7 +2,11 @@ package httpapi
 
 import (
 	"net/http"
+	"net/mail"
+	"net/netip"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ArionMiles/expensor/backend/internal/auth"
@@ -46,12 +50,35 @@ func isPublicRoute(r *http.Request) bool {
 		return true
 	case r.Method == http.MethodGet && r.URL.Path == "/api/auth/callback":
 		return true
+	case r.Method == http.MethodGet && r.URL.Path == "/api/auth/oidc":
+		return true
+	case r.Method == http.MethodGet && r.URL.Path == "/api/auth/oidc/login":
+		return true
+	case r.Method == http.MethodGet && r.URL.Path == "/api/auth/oidc/callback":
+		return true
 	default:
 		return false
 	}
 }
 
+// ProxyAuthConfig lets an authenticating reverse proxy vouch for users by
+// setting Header to their email. The zero value disables it.
+type ProxyAuthConfig struct {
+	Header     string
+	NameHeader string
+	// TrustedProxies are the peers whose Header is believed. The proxy must
+	// overwrite the header so clients cannot supply their own.
+	TrustedProxies []netip.Prefix
+	// Provision creates regular users for unknown emails, limited to
+	// ProvisionDomains when that is not empty.
+	Provision        bool
+	ProvisionDomains []string
+}
+
 func (h *Handlers) authenticateRequest(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	if email, ok := h.proxyIdentity(r); ok {
+		return h.authenticateProxy(w, r, email)
+	}
 	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
 		return h.authenticateSession(w, r, cookie.Value)
 	}
@@ -71,11 +98,33 @@ func (h *Handlers) authenticateSession(w http.ResponseWriter, r *http.Request, r
 		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("authentication required")))
 		return auth.Principal{}, false
 	}
+	if session.MFA != store.SessionMFAComplete && !mfaSessionAllows(session.MFA, r) {
+		writeError(w, r, mfaSessionError(session.MFA))
+		return auth.Principal{}, false
+	}
 	user, ok := h.authenticatedUser(w, r, session.UserID)
 	if !ok {
 		return auth.Principal{}, false
 	}
-	return principalForUser(user, "session"), true
+	h.touchSession(r, session)
+	principal := principalForUser(user, "session")
+	principal.SessionID = session.ID
+	if session.ActiveTenantID == "" || session.ActiveTenantID == user.TenantID {
+		return principal, true
+	}
+	membership, err := h.tenantStore.GetTenantMembership(r.Context(), store.Tenant{ID: session.ActiveTenantID}, user.ID)
+	if err != nil {
+		// A revoked membership falls back to the personal tenant rather than
+		// signing the user out.
+		if errors.WhatKind(err) == errors.NotFound {
+			return principal, true
+		}
+		writeError(w, r, err)
+		return auth.Principal{}, false
+	}
+	principal.TenantID = membership.TenantID
+	principal.TenantRole = auth.TenantRole(membership.Role)
+	return principal, true
 }
 
 func (h *Handlers) authenticateBearer(w http.ResponseWriter, r *http.Request, raw string) (auth.Principal, bool) {
@@ -91,7 +140,116 @@ func (h *Handlers) authenticateBearer(w http.ResponseWriter, r *http.Request, ra
 	if !ok {
 		return auth.Principal{}, false
 	}
-	return principalForUser(user, "bearer"), true
+	principal := principalForUser(user, "bearer")
+	principal.Scopes = make([]auth.Scope, 0, len(token.Scopes))
+	for _, scope := range token.Scopes {
+		principal.Scopes = append(principal.Scopes, auth.Scope(scope))
+	}
+	return principal, true
+}
+
+// proxyIdentity returns the proxy identity header when the request came
+// straight from a trusted proxy. From anyone else the header is ignored.
+func (h *Handlers) proxyIdentity(r *http.Request) (string, bool) {
+	if h.proxyAuth.Header == "" {
+		return "", false
+	}
+	value := strings.TrimSpace(r.Header.Get(h.proxyAuth.Header))
+	if value == "" {
+		return "", false
+	}
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return "", false
+	}
+	addr := peer.Addr().Unmap()
+	for _, prefix := range h.proxyAuth.TrustedProxies {
+		if prefix.Contains(addr) {
+			return value, true
+		}
+	}
+	h.logger.Debug("ignoring proxy identity header from untrusted peer", "peer", addr.String())
+	return "", false
+}
+
+func (h *Handlers) authenticateProxy(w http.ResponseWriter, r *http.Request, value string) (auth.Principal, bool) {
+	email := strings.ToLower(value)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("proxy identity is not an email address")))
+		return auth.Principal{}, false
+	}
+	event := store.NewAuditEvent{Action: store.AuditLogin, AuthMethod: "proxy", TargetType: "email", TargetID: email}
+	denied := errors.E(errors.Unauthenticated, errors.User("authentication required"))
+	user, err := h.authStore.FindUserByEmail(r.Context(), email)
+	if errors.WhatKind(err) == errors.NotFound && h.proxyAuthMayProvision(email) {
+		user, err = h.provisionUser(r.Context(), "proxy", email, strings.TrimSpace(r.Header.Get(h.proxyAuth.NameHeader)), nil)
+	}
+	if err != nil && errors.WhatKind(err) != errors.NotFound {
+		logError(r, responseRequestID(w), err)
+		writeError(w, r, denied)
+		return auth.Principal{}, false
+	}
+	now := time.Now()
+	if err != nil || user.DisabledAt != nil {
+		if h.proxySignIns.due("denied "+email, now) {
+			h.audit(r, event, denied)
+		}
+		writeError(w, r, denied)
+		return auth.Principal{}, false
+	}
+	if h.proxySignIns.due(user.ID, now) {
+		event.ActorUserID, event.TenantID = user.ID, user.TenantID
+		h.audit(r, event, nil)
+	}
+	return principalForUser(user, "proxy"), true
+}
+
+const (
+	// proxySignInInterval is how often a proxy identity is audited as a
+	// sign-in. Every request it makes authenticates, so recording each would
+	// bury the rest of the audit log.
+	proxySignInInterval = 12 * time.Hour
+	proxySignInCapacity = 10_000
+)
+
+// proxySignIns remembers when each proxy identity was last audited.
+type proxySignIns struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// due reports whether identity is due to be audited at now, and if so
+// notes that it is.
+func (p *proxySignIns) due(identity string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if last, ok := p.last[identity]; ok && now.Sub(last) < proxySignInInterval {
+		return false
+	}
+	if len(p.last) >= proxySignInCapacity {
+		for key, last := range p.last {
+			if now.Sub(last) >= proxySignInInterval {
+				delete(p.last, key)
+			}
+		}
+		// Forgetting everyone only audits them again sooner.
+		if len(p.last) >= proxySignInCapacity {
+			clear(p.last)
+		}
+	}
+	p.last[identity] = now
+	return true
+}
+
+func (h *Handlers) proxyAuthMayProvision(email string) bool {
+	if !h.proxyAuth.Provision {
+		return false
+	}
+	if len(h.proxyAuth.ProvisionDomains) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return slices.Contains(h.proxyAuth.ProvisionDomains, domain)
 }
 
 func (h *Handlers) authenticatedUser(w http.ResponseWriter, r *http.Request, userID string) (*store.User, bool) {
@@ -122,6 +280,7 @@ func principalForUser(user *store.User, method string) auth.Principal {
 	return auth.Principal{
 		UserID:     user.ID,
 		TenantID:   user.TenantID,
+		TenantRole: auth.TenantRoleOwner,
 		Role:       auth.Role(user.Role),
 		AuthMethod: method,
 	}
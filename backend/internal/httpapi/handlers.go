@@ -1,8 +1,10 @@
 package httpapi
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"strings"
 	"sync"
 	"time"
@@ -11,11 +13,16 @@ import (
 	"github.com/go-playground/validator/v10"
 
 	"github.com/ArionMiles/expensor/backend/internal/assistant"
+	"github.com/ArionMiles/expensor/backend/internal/backup"
 	"github.com/ArionMiles/expensor/backend/internal/daemon"
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/llm"
 	"github.com/ArionMiles/expensor/backend/internal/observability"
+	"github.com/ArionMiles/expensor/backend/internal/oidc"
 	"github.com/ArionMiles/expensor/backend/internal/plugins"
+	"github.com/ArionMiles/expensor/backend/internal/rekey"
 	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/internal/webauthn"
 )
 
 const (
@@ -28,7 +35,10 @@ const (
 type oauthStateEntry struct {
 	readerName string
 	tenant     store.Tenant
-	expiresAt  time.Time
+	// userID is the user who started the flow; the callback may arrive
+	// without a session.
+	userID    string
+	expiresAt time.Time
 }
 
 // DaemonStatus represents the state of the background daemon.
@@ -52,42 +62,111 @@ type CommunitySyncer interface {
 	Trigger()
 }
 
+// BackupManager takes, lists and restores database backups.
+type BackupManager interface {
+	Create(ctx context.Context) (backup.Info, error)
+	List(ctx context.Context) ([]backup.Info, error)
+	Restore(ctx context.Context, name string, dryRun bool) (backup.RestoreResult, error)
+}
+
+// SecretRotator re-encrypts sealed values with the active secret key.
+type SecretRotator interface {
+	Start() (rekey.Status, error)
+	Status() rekey.Status
+}
+
+// Notifier sends notifications through users' channels.
+type Notifier interface {
+	// Available reports why channels of the given type cannot send.
+	Available(channelType store.NotificationChannelType) error
+	SendTest(ctx context.Context, channel store.NotificationChannel, token string) error
+}
+
+// EventStream subscribes to a tenant's live events.
+type EventStream interface {
+	Subscribe(tenantID, lastEventID string) (*events.Subscription, []events.Event)
+}
+
+// OIDCProvider signs users in through an OpenID Connect identity provider.
+type OIDCProvider interface {
+	Name() string
+	AuthCodeURL(ctx context.Context, redirectURL string, req oidc.AuthRequest) (string, error)
+	Exchange(ctx context.Context, redirectURL, code string, req oidc.AuthRequest) (oidc.Identity, error)
+}
+
 // Handlers holds all dependencies for HTTP endpoint handlers.
 type Handlers struct {
 	registry           *plugins.Registry
 	llmRegistry        *llm.Registry
 	llmRouter          *llm.Router
 	ruleDrafts         ruleDraftService
+	transactionQueries transactionQueryService
 	authStore          authStore
+	mfaStore           mfaStore
 	settingsStore      settingsStore
 	scanningStore      scanningStore
 	analyticsStore     analyticsStore
 	transactionStore   transactionStore
+	sharedLedgerStore  sharedLedgerStore
+	attachmentStore    attachmentStore
+	archiveStore       archiveStore
+	auditStore         auditStore
+	webhookStore       webhookStore
+	notificationStore  notificationStore
+	backupStore        backupStore
+	tenantStore        tenantStore
 	muteStore          muteStore
 	taxonomyStore      taxonomyStore
 	readerRuntimeStore readerRuntimeStore
 	llmRuntimeStore    llmRuntimeStore
+	llmUsageStore      llmUsageStore
+	llmPromptStore     llmPromptStore
 	ruleStore          ruleStore
 	syncStore          syncStore
 	diagnosticStore    diagnosticStore
 	daemon             DaemonController
 	community          CommunitySyncer
+	backups            BackupManager
+	secretRotation     SecretRotator
+	notifier           Notifier
+	events             EventStream
+	eventKeepAlive     time.Duration // how often event streams send a keep-alive and recheck credentials
+	oidc               OIDCProvider
+	oidcAutoProvision  bool
+	proxyAuth          ProxyAuthConfig
+	trustedProxies     []netip.Prefix
+	webauthn           *webauthn.RelyingParty
 	version            string // set at build time via ldflags
 	baseURL            string // e.g. "http://localhost:8080"
 	frontendURL        string // e.g. "http://localhost:5173" — used for OAuth redirects
 	thunderbirdDataDir string
 	scanInterval       int // default scan interval in seconds
 	lookbackDays       int // default lookback in days
+	maxAttachmentSize  int64
 	banksData          []byte
 	logger             *slog.Logger
 	llmScope           *observability.Scope
 	logLevel           *slog.LevelVar
 	validate           *validator.Validate
 	queryDecoder       *form.Decoder
+	// loginThrottle counts failed password sign-ins per account and address.
+	loginThrottle *loginThrottle
+	// mfaThrottle counts failed second factors per user. It is kept apart
+	// so sign-ins sprayed at made-up emails cannot evict its entries.
+	mfaThrottle *loginThrottle
+	// proxySignIns notes when each proxy identity was last audited.
+	proxySignIns *proxySignIns
 
 	// oauthStates maps state token → entry for in-flight OAuth flows.
 	mu          sync.Mutex
 	oauthStates map[string]oauthStateEntry
+	// oidcStates maps state token → entry for in-flight single sign-on.
+	oidcStates map[string]oidcStateEntry
+	// mfaFailures counts failed second-factor attempts per session ID.
+	mfaFailures map[string]mfaFailureCount
+	// webauthnChallenges maps a session ID and ceremony to the challenge it
+	// was last issued.
+	webauthnChallenges map[webauthnChallengeKey]webauthnChallenge
 }
 
 // HandlersConfig holds all dependencies for NewHandlers.
@@ -96,16 +175,33 @@ type HandlersConfig struct {
 	LLMRegistry        *llm.Registry
 	LLMRouter          *llm.Router
 	RuleDrafts         assistant.RuleDrafter
+	TransactionQueries assistant.TransactionQuerier
 	LLMScope           *observability.Scope
 	Store              Storer
 	Daemon             DaemonController
 	Community          CommunitySyncer
+	Backups            BackupManager
+	// SecretRotation re-encrypts values after a key rotation; nil disables it.
+	SecretRotation SecretRotator
+	// Notifier sends test notifications; nil disables them.
+	Notifier Notifier
+	// Events streams live events to clients; nil disables GET /api/events.
+	Events EventStream
+	OIDC   OIDCProvider
+	// OIDCAutoProvision creates accounts for unknown single sign-on users.
+	OIDCAutoProvision bool
+	ProxyAuth         ProxyAuthConfig
+	// TrustedProxies are reverse proxies whose X-Forwarded-For is believed.
+	TrustedProxies []netip.Prefix
+	// WebAuthn verifies passkeys; nil disables them.
+	WebAuthn           *webauthn.RelyingParty
 	Version            string
 	BaseURL            string
 	FrontendURL        string
 	ThunderbirdDataDir string
 	ScanInterval       int
 	LookbackDays       int
+	MaxAttachmentSize  int64
 	BanksData          []byte
 	Logger             *slog.Logger
 	LogLevel           *slog.LevelVar
@@ -123,6 +219,9 @@ func NewHandlers(cfg HandlersConfig) *Handlers {
 	if cfg.LookbackDays <= 0 {
 		cfg.LookbackDays = 180
 	}
+	if cfg.MaxAttachmentSize <= 0 {
+		cfg.MaxAttachmentSize = defaultMaxAttachmentSize
+	}
 	if cfg.LogLevel == nil {
 		cfg.LogLevel = new(slog.LevelVar)
 		cfg.LogLevel.Set(slog.LevelInfo)
@@ -138,33 +237,62 @@ func NewHandlers(cfg HandlersConfig) *Handlers {
 		llmRegistry:        cfg.LLMRegistry,
 		llmRouter:          cfg.LLMRouter,
 		ruleDrafts:         cfg.RuleDrafts,
+		transactionQueries: cfg.TransactionQueries,
 		authStore:          cfg.Store,
+		mfaStore:           cfg.Store,
 		settingsStore:      cfg.Store,
 		scanningStore:      cfg.Store,
 		analyticsStore:     cfg.Store,
 		transactionStore:   cfg.Store,
+		sharedLedgerStore:  cfg.Store,
+		attachmentStore:    cfg.Store,
+		archiveStore:       cfg.Store,
+		auditStore:         cfg.Store,
+		webhookStore:       cfg.Store,
+		notificationStore:  cfg.Store,
+		backupStore:        cfg.Store,
+		tenantStore:        cfg.Store,
 		muteStore:          cfg.Store,
 		taxonomyStore:      cfg.Store,
 		readerRuntimeStore: cfg.Store,
 		llmRuntimeStore:    cfg.Store,
+		llmUsageStore:      cfg.Store,
+		llmPromptStore:     cfg.Store,
 		ruleStore:          cfg.Store,
 		syncStore:          cfg.Store,
 		diagnosticStore:    cfg.Store,
 		daemon:             cfg.Daemon,
 		community:          cfg.Community,
+		backups:            cfg.Backups,
+		secretRotation:     cfg.SecretRotation,
+		notifier:           cfg.Notifier,
+		events:             cfg.Events,
+		eventKeepAlive:     eventKeepAlive,
+		oidc:               cfg.OIDC,
+		oidcAutoProvision:  cfg.OIDCAutoProvision,
+		proxyAuth:          cfg.ProxyAuth,
+		trustedProxies:     cfg.TrustedProxies,
+		webauthn:           cfg.WebAuthn,
 		version:            cfg.Version,
 		baseURL:            strings.TrimRight(cfg.BaseURL, "/"),
 		frontendURL:        strings.TrimRight(cfg.FrontendURL, "/"),
 		thunderbirdDataDir: cfg.ThunderbirdDataDir,
 		scanInterval:       cfg.ScanInterval,
 		lookbackDays:       cfg.LookbackDays,
+		maxAttachmentSize:  cfg.MaxAttachmentSize,
 		banksData:          cfg.BanksData,
 		logger:             cfg.Logger,
 		llmScope:           cfg.LLMScope,
 		logLevel:           cfg.LogLevel,
 		validate:           newRequestValidator(),
 		queryDecoder:       newQueryDecoder(),
+		loginThrottle:      newLoginThrottle(loginThrottleCapacity),
+		mfaThrottle:        newLoginThrottle(loginThrottleCapacity),
+		proxySignIns:       &proxySignIns{last: make(map[string]time.Time)},
 		oauthStates:        make(map[string]oauthStateEntry),
+		oidcStates:         make(map[string]oidcStateEntry),
+		mfaFailures:        make(map[string]mfaFailureCount),
+		webauthnChallenges: make(map[webauthnChallengeKey]webauthnChallenge),
 	}
 }
 
@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// maxTenantArchiveSize bounds an uploaded archive. Email bodies make up most
+// of an archive, so this leaves room for several years of transactions.
+const maxTenantArchiveSize = 256 << 20
+
+// ExportAccount handles GET /api/account/export.
+// Reader credentials, OAuth tokens and LLM provider keys are never included,
+// so readers must be reconnected after importing.
+// @Summary Export all data of the current tenant as a portable archive
+// @Tags Account
+// @Produce json
+// @Success 200 {object} TenantArchiveResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /account/export [get]
+func (h *Handlers) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	archive, err := h.archiveStore.ExportTenantArchive(r.Context(), requestTenant(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	filename := fmt.Sprintf("expensor-archive-%s.json", archive.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeJSON(w, http.StatusOK, archive)
+}
+
+// ImportAccount handles POST /api/account/import.
+// The archive is applied in one transaction: either every section is
+// imported or nothing changes. Only tenant owners may import. Existing items
+// are matched by natural key (message ID, rule name, pattern) and resolved
+// by the policy query parameter; nothing is ever deleted.
+// @Summary Import a tenant archive into the current tenant
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param policy query string false "How to resolve items that already exist; defaults to skip" Enums(skip,overwrite,merge)
+// @Param request body TenantArchiveResponse true "Tenant archive"
+// @Success 200 {object} TenantImportResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /account/import [post]
+func (h *Handlers) ImportAccount(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	if principal.TenantRole != auth.TenantRoleOwner {
+		writeError(w, r, errors.E(errors.PermissionDenied, errors.User("Only tenant owners can import an archive.")))
+		return
+	}
+	query, ok := decodeAndValidateQuery[accountImportQuery](h, w, r)
+	if !ok {
+		return
+	}
+	policy := store.ArchiveConflictPolicy(query.Policy)
+	if policy == "" {
+		policy = store.ArchiveConflictSkip
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTenantArchiveSize)
+	var archive store.TenantArchive
+	if err := json.NewDecoder(r.Body).Decode(&archive); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, errors.E(errors.PayloadTooLarge, errors.User("archive exceeds the 256 MiB limit"), err))
+			return
+		}
+		writeError(w, r, errors.E(errors.InvalidArgument, errors.User("invalid JSON body"), err))
+		return
+	}
+	if err := store.ValidateTenantArchive(archive); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.archiveStore.ImportTenantArchive(r.Context(), requestTenant(r), archive, policy)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditAccountImport, TargetType: "tenant", TargetID: principal.TenantID}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
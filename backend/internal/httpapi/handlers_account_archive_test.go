@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+func archiveRequest(t *testing.T, target, body string, role auth.TenantRole) *http.Request {
+	t.Helper()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", TenantRole: role, Role: auth.RoleUser})
+	return httptest.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(body))
+}
+
+func TestExportAccount_ReturnsArchiveAttachment(t *testing.T) {
+	st := &mockStore{tenantArchive: &store.TenantArchive{
+		Format:     store.TenantArchiveFormat,
+		Version:    store.TenantArchiveVersion,
+		ExportedAt: time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+		Labels:     []store.Label{{Name: "food", Color: "#f59e0b"}},
+	}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.ExportAccount(rr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/account/export", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="expensor-archive-20260304.json"` {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+	var archive store.TenantArchive
+	if err := json.Unmarshal(rr.Body.Bytes(), &archive); err != nil {
+		t.Fatalf("decoding archive: %v", err)
+	}
+	if archive.Format != store.TenantArchiveFormat || len(archive.Labels) != 1 {
+		t.Fatalf("archive = %#v", archive)
+	}
+}
+
+func TestImportAccount_AppliesPolicy(t *testing.T) {
+	st := &mockStore{importResult: store.TenantImportResult{
+		Sections: map[string]store.ArchiveSectionResult{store.ArchiveSectionLabels: {Created: 1}},
+	}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+	body := `{"format":"expensor.tenant-archive","version":1,"labels":[{"name":"food","color":"#f59e0b"}]}`
+
+	h.ImportAccount(rr, archiveRequest(t, "/api/account/import?policy=merge", body, auth.TenantRoleOwner))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.importPolicy != store.ArchiveConflictMerge {
+		t.Fatalf("policy = %q", st.importPolicy)
+	}
+	if len(st.auditEvents) != 1 || st.auditEvents[0].Action != store.AuditAccountImport || st.auditEvents[0].Outcome != store.AuditSuccess {
+		t.Fatalf("audit events = %#v", st.auditEvents)
+	}
+	if st.importedArchive == nil || len(st.importedArchive.Labels) != 1 || st.importedArchive.Labels[0].Name != "food" {
+		t.Fatalf("imported archive = %#v", st.importedArchive)
+	}
+	var result TenantImportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decoding result: %v", err)
+	}
+	if result.Policy != "merge" || result.Sections["labels"].Created != 1 {
+		t.Fatalf("result = %#v", result)
+	}
+}
+
+func TestImportAccount_DefaultsToSkip(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.ImportAccount(rr, archiveRequest(t, "/api/account/import", `{"format":"expensor.tenant-archive","version":1}`, auth.TenantRoleOwner))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.importPolicy != store.ArchiveConflictSkip {
+		t.Fatalf("policy = %q", st.importPolicy)
+	}
+}
+
+func TestImportAccount_RejectsBadRequests(t *testing.T) {
+	const minimal = `{"format":"expensor.tenant-archive","version":1}`
+	cases := []struct {
+		name   string
+		target string
+		body   string
+		role   auth.TenantRole
+		want   int
+	}{
+		{"editor", "/api/account/import", minimal, auth.TenantRoleEditor, http.StatusForbidden},
+		{"unknown policy", "/api/account/import?policy=replace", minimal, auth.TenantRoleOwner, http.StatusUnprocessableEntity},
+		{"malformed JSON", "/api/account/import", `{"format":`, auth.TenantRoleOwner, http.StatusBadRequest},
+		{"wrong format", "/api/account/import", `{"format":"rules","version":1}`, auth.TenantRoleOwner, http.StatusUnprocessableEntity},
+		{"future version", "/api/account/import", `{"format":"expensor.tenant-archive","version":99}`, auth.TenantRoleOwner, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := &mockStore{}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			rr := httptest.NewRecorder()
+
+			h.ImportAccount(rr, archiveRequest(t, tc.target, tc.body, tc.role))
+
+			if rr.Code != tc.want {
+				t.Fatalf("expected %d, got %d (body=%s)", tc.want, rr.Code, rr.Body.String())
+			}
+			if st.importedArchive != nil {
+				t.Fatal("store should not be called")
+			}
+		})
+	}
+}
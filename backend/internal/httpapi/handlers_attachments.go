@@ -0,0 +1,190 @@
+package httpapi
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// defaultMaxAttachmentSize applies when HandlersConfig.MaxAttachmentSize is unset.
+const defaultMaxAttachmentSize = 25 << 20 // 25 MB
+
+// multipartOverhead leaves room for boundaries and part headers around the file.
+const multipartOverhead = 64 << 10
+
+// ListAttachments handles GET /api/transactions/{id}/attachments.
+// @Summary List the files attached to a transaction
+// @Tags Transactions
+// @Produce json
+// @Param id path string true "Transaction ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Success 200 {array} AttachmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/{id}/attachments [get]
+func (h *Handlers) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	attachments, err := h.attachmentStore.ListAttachments(r.Context(), requestTenant(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// UploadAttachment handles POST /api/transactions/{id}/attachments.
+// The file is read from the "file" field of a multipart/form-data body.
+// @Summary Attach a file to a transaction
+// @Tags Transactions
+// @Accept mpfd
+// @Produce json
+// @Param id path string true "Transaction ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Param file formData file true "File to attach"
+// @Success 201 {object} AttachmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/{id}/attachments [post]
+func (h *Handlers) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	input, err := h.readAttachmentUpload(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	attachment, err := h.attachmentStore.CreateAttachment(r.Context(), requestTenant(r), id, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, attachment)
+}
+
+// DownloadAttachment handles GET /api/transactions/{id}/attachments/{attachment_id}.
+// @Summary Download an attached file
+// @Tags Transactions
+// @Produce octet-stream
+// @Param id path string true "Transaction ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Param attachment_id path string true "Attachment ID" format(uuid) example(99999999-9999-9999-9999-999999999999)
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/{id}/attachments/{attachment_id} [get]
+func (h *Handlers) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	attachmentID, ok := uuidPathValue(w, r, "attachment_id", "attachment")
+	if !ok {
+		return
+	}
+	attachment, data, err := h.attachmentStore.GetAttachment(r.Context(), requestTenant(r), id, attachmentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	// Uploaded files are served back as-is; never let the browser sniff them
+	// into something executable.
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// DeleteAttachment handles DELETE /api/transactions/{id}/attachments/{attachment_id}.
+// @Summary Delete an attached file
+// @Tags Transactions
+// @Param id path string true "Transaction ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Param attachment_id path string true "Attachment ID" format(uuid) example(99999999-9999-9999-9999-999999999999)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/{id}/attachments/{attachment_id} [delete]
+func (h *Handlers) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	attachmentID, ok := uuidPathValue(w, r, "attachment_id", "attachment")
+	if !ok {
+		return
+	}
+	if err := h.attachmentStore.DeleteAttachment(r.Context(), requestTenant(r), id, attachmentID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readAttachmentUpload streams the "file" part of a multipart body, so an
+// upload is never buffered to disk and oversized files are rejected early.
+func (h *Handlers) readAttachmentUpload(w http.ResponseWriter, r *http.Request) (store.NewAttachment, error) {
+	tooLarge := errors.E(errors.PayloadTooLarge, errors.User(fmt.Sprintf("attachment exceeds the %d byte limit", h.maxAttachmentSize)))
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxAttachmentSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return store.NewAttachment{}, errors.E(errors.InvalidArgument, errors.User("expected a multipart/form-data body"), err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return store.NewAttachment{}, errors.E(errors.InvalidArgument, errors.User(`missing "file" field`))
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return store.NewAttachment{}, tooLarge
+			}
+			return store.NewAttachment{}, errors.E(errors.InvalidArgument, errors.User("invalid multipart body"), err)
+		}
+		if part.FormName() != "file" {
+			_ = part.Close()
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, h.maxAttachmentSize+1))
+		_ = part.Close()
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return store.NewAttachment{}, tooLarge
+			}
+			return store.NewAttachment{}, errors.E(errors.InvalidArgument, errors.User("invalid multipart body"), err)
+		}
+		if int64(len(data)) > h.maxAttachmentSize {
+			return store.NewAttachment{}, tooLarge
+		}
+		contentType := part.Header.Get("Content-Type")
+		if contentType == "" || contentType == "application/octet-stream" {
+			contentType = http.DetectContentType(data)
+		}
+		return store.NewAttachment{
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			Data:        data,
+			Source:      store.AttachmentSourceUpload,
+		}, nil
+	}
+}
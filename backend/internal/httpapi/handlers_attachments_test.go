@@ -0,0 +1,237 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	testAttachmentTxnID = "11111111-1111-1111-1111-111111111111"
+	testAttachmentID    = "99999999-9999-9999-9999-999999999999"
+)
+
+func newUploadRequest(t *testing.T, filename, contentType string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		t.Fatalf("CreatePart: %v", err)
+	}
+	_, _ = part.Write(data)
+	if err := mw.Close(); err != nil {
+		t.Fatalf("multipart Close: %v", err)
+	}
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/"+testAttachmentTxnID+"/attachments", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetPathValue("id", testAttachmentTxnID)
+	return req
+}
+
+func TestUploadAttachment_StoresFile(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.UploadAttachment(rr, newUploadRequest(t, "invoice.pdf", "application/pdf", []byte("%PDF-1.7 body")))
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.createdAttachment.Filename != "invoice.pdf" || st.createdAttachment.ContentType != "application/pdf" ||
+		string(st.createdAttachment.Data) != "%PDF-1.7 body" || st.createdAttachment.Source != store.AttachmentSourceUpload {
+		t.Fatalf("created attachment = %#v", st.createdAttachment)
+	}
+	var resp AttachmentResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if resp.ID != testAttachmentID || resp.TransactionID != testAttachmentTxnID || resp.Size != 13 {
+		t.Fatalf("response = %#v", resp)
+	}
+}
+
+func TestUploadAttachment_SniffsMissingContentType(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.UploadAttachment(rr, newUploadRequest(t, "scan", "", []byte("%PDF-1.4\n")))
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.createdAttachment.ContentType != "application/pdf" {
+		t.Fatalf("content type = %q, want application/pdf", st.createdAttachment.ContentType)
+	}
+}
+
+func TestUploadAttachment_RejectsOversizedFile(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	h.maxAttachmentSize = 8
+	rr := httptest.NewRecorder()
+
+	h.UploadAttachment(rr, newUploadRequest(t, "big.pdf", "application/pdf", []byte("123456789")))
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.createdAttachment.Data != nil {
+		t.Fatal("oversized file reached the store")
+	}
+}
+
+func TestUploadAttachment_RequiresFileField(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("note", "no file here")
+	_ = mw.Close()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/x/attachments", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetPathValue("id", testAttachmentTxnID)
+	rr := httptest.NewRecorder()
+
+	h.UploadAttachment(rr, req)
+
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `missing \"file\" field`) {
+		t.Fatalf("expected 400 missing file, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUploadAttachment_RejectsNonMultipartBody(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/x/attachments", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", testAttachmentTxnID)
+	rr := httptest.NewRecorder()
+
+	h.UploadAttachment(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestListAttachments_ReturnsMetadata(t *testing.T) {
+	st := &mockStore{attachments: []store.Attachment{{ID: testAttachmentID, TransactionID: testAttachmentTxnID, Filename: "invoice.pdf"}}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/x/attachments", nil)
+	req.SetPathValue("id", testAttachmentTxnID)
+	rr := httptest.NewRecorder()
+
+	h.ListAttachments(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp []AttachmentResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if len(resp) != 1 || resp[0].Filename != "invoice.pdf" {
+		t.Fatalf("response = %#v", resp)
+	}
+}
+
+func TestListAttachments_UnknownTransaction(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{attachmentErr: errStoreNotFound}, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/x/attachments", nil)
+	req.SetPathValue("id", testAttachmentTxnID)
+	rr := httptest.NewRecorder()
+
+	h.ListAttachments(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDownloadAttachment_ServesContent(t *testing.T) {
+	st := &mockStore{
+		attachments: []store.Attachment{{
+			ID: testAttachmentID, TransactionID: testAttachmentTxnID, Filename: "März invoice.pdf", ContentType: "application/pdf",
+		}},
+		attachmentContent: []byte("%PDF-1.7 body"),
+	}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/x/attachments/y", nil)
+	req.SetPathValue("id", testAttachmentTxnID)
+	req.SetPathValue("attachment_id", testAttachmentID)
+	rr := httptest.NewRecorder()
+
+	h.DownloadAttachment(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	body, _ := io.ReadAll(rr.Body)
+	if string(body) != "%PDF-1.7 body" {
+		t.Fatalf("body = %q", body)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename*=utf-8''M%C3%A4rz%20invoice.pdf` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q", got)
+	}
+}
+
+func TestDownloadAttachment_RejectsInvalidAttachmentID(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/x/attachments/y", nil)
+	req.SetPathValue("id", testAttachmentTxnID)
+	req.SetPathValue("attachment_id", "not-a-uuid")
+	rr := httptest.NewRecorder()
+
+	h.DownloadAttachment(rr, req)
+
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "invalid attachment id") {
+		t.Fatalf("expected 400 invalid attachment id, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodDelete, "/api/transactions/x/attachments/y", nil)
+	req.SetPathValue("id", testAttachmentTxnID)
+	req.SetPathValue("attachment_id", testAttachmentID)
+	rr := httptest.NewRecorder()
+
+	h.DeleteAttachment(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.deletedAttachmentID != testAttachmentID {
+		t.Fatalf("deleted attachment = %q", st.deletedAttachmentID)
+	}
+}
+
+func TestUploadAttachment_StoreUnavailable(t *testing.T) {
+	st := &mockStore{attachmentErr: errors.E(errors.FailedPrecondition, errors.User("attachment storage is not configured"))}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.UploadAttachment(rr, newUploadRequest(t, "invoice.pdf", "application/pdf", []byte("%PDF")))
+
+	if rr.Code == http.StatusCreated || !strings.Contains(rr.Body.String(), "attachment storage is not configured") {
+		t.Fatalf("expected failure, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,185 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// audit records event in the audit log. err is the error the action failed
+// with, or nil when it took effect; it decides the outcome. The actor,
+// tenant and auth method default to the request principal. A failure to
+// record is logged and never changes the response.
+func (h *Handlers) audit(r *http.Request, event store.NewAuditEvent, err error) {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		if event.ActorUserID == "" {
+			event.ActorUserID = principal.UserID
+		}
+		if event.TenantID == "" {
+			event.TenantID = principal.TenantID
+		}
+		if event.AuthMethod == "" {
+			event.AuthMethod = principal.AuthMethod
+		}
+	}
+	event.RequestID = requestIDFromContext(r.Context())
+	event.IPAddress = h.clientIP(r)
+	event.Outcome, event.Detail = auditOutcome(err)
+	if err := h.auditStore.RecordAuditEvent(context.WithoutCancel(r.Context()), event); err != nil {
+		h.logger.Warn("failed to record audit event", "action", event.Action, "request_id", event.RequestID, "error", err)
+	}
+}
+
+func auditOutcome(err error) (store.AuditOutcome, string) {
+	if err == nil {
+		return store.AuditSuccess, ""
+	}
+	kind := errors.WhatKind(err)
+	detail := errors.UserMsg(err)
+	if detail == "" {
+		detail = kind.String()
+	}
+	switch kind {
+	case errors.Unauthenticated, errors.PermissionDenied, errors.ResourceExhausted:
+		return store.AuditDenied, detail
+	default:
+		return store.AuditFailure, detail
+	}
+}
+
+// ListAuditEvents handles GET /api/admin/audit/events. Events are newest
+// first.
+// @Summary List audit log events
+// @Tags Admin
+// @Produce json
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Events per page (1-200, default 50)"
+// @Param actor_user_id query string false "Only events by this user"
+// @Param tenant_id query string false "Only events in this tenant"
+// @Param action query string false "Only this action" example(auth.login)
+// @Param target_type query string false "Only events on this kind of target" example(user)
+// @Param target_id query string false "Only events on this target"
+// @Param outcome query string false "Only this outcome" Enums(success, failure, denied)
+// @Param request_id query string false "Only events from this request"
+// @Param from query string false "Inclusive range start (RFC3339)"
+// @Param to query string false "Exclusive range end (RFC3339)"
+// @Success 200 {object} AuditEventListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/audit/events [get]
+func (h *Handlers) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	query, ok := decodeAndValidateQuery[auditEventListQuery](h, w, r)
+	if !ok {
+		return
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		writeError(w, r, errors.E(errors.InvalidInput, errors.User("from must be before to")))
+		return
+	}
+	filter := store.AuditEventFilter{
+		ActorUserID: query.ActorUserID,
+		TenantID:    query.TenantID,
+		Action:      store.AuditAction(query.Action),
+		TargetType:  query.TargetType,
+		TargetID:    query.TargetID,
+		Outcome:     store.AuditOutcome(query.Outcome),
+		RequestID:   query.RequestID,
+		From:        query.From,
+		To:          query.To,
+		Page:        max(query.Page, 1),
+		PageSize:    50,
+	}
+	if query.PageSize != nil {
+		filter.PageSize = *query.PageSize
+	}
+	events, total, err := h.auditStore.ListAuditEvents(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := AuditEventListResponse{
+		Events:   make([]AuditEventResponse, 0, len(events)),
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, AuditEventResponse{
+			ID:          event.ID,
+			OccurredAt:  event.OccurredAt,
+			ActorUserID: event.ActorUserID,
+			ActorEmail:  event.ActorEmail,
+			TenantID:    event.TenantID,
+			AuthMethod:  event.AuthMethod,
+			Action:      string(event.Action),
+			TargetType:  event.TargetType,
+			TargetID:    event.TargetID,
+			RequestID:   event.RequestID,
+			IPAddress:   event.IPAddress,
+			Outcome:     string(event.Outcome),
+			Detail:      event.Detail,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetAuditSettings handles GET /api/admin/audit/settings.
+// @Summary Get audit log retention settings
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} AuditSettingsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/audit/settings [get]
+func (h *Handlers) GetAuditSettings(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	settings, err := h.auditStore.GetAuditSettings(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AuditSettingsResponse{RetentionDays: settings.RetentionDays, UpdatedAt: settings.UpdatedAt})
+}
+
+// PatchAuditSettings handles PATCH /api/admin/audit/settings. Shortening
+// the retention removes older events at the next hourly prune.
+// @Summary Update audit log retention settings
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body AuditSettingsPatchRequest true "Audit settings patch"
+// @Success 200 {object} AuditSettingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/audit/settings [patch]
+func (h *Handlers) PatchAuditSettings(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	body, ok := decodeAndValidateJSON[AuditSettingsPatchRequest](h, w, r)
+	if !ok {
+		return
+	}
+	settings, err := h.auditStore.PatchAuditSettings(r.Context(), store.AuditSettingsPatch{RetentionDays: body.RetentionDays})
+	h.audit(r, store.NewAuditEvent{Action: store.AuditSettingsUpdate, TargetType: "audit_settings"}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AuditSettingsResponse{RetentionDays: settings.RetentionDays, UpdatedAt: settings.UpdatedAt})
+}
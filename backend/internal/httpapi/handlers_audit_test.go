@@ -0,0 +1,198 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func withRequestID(req *http.Request, requestID string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestIDContextKey{}, requestID))
+}
+
+// auditedActions lists the actions ms recorded whose names start with
+// prefix, oldest first.
+func auditedActions(ms *mockStore, prefix string) []store.AuditAction {
+	var actions []store.AuditAction
+	for _, event := range ms.auditEvents {
+		if strings.HasPrefix(string(event.Action), prefix) {
+			actions = append(actions, event.Action)
+		}
+	}
+	return actions
+}
+
+func TestLoginRecordsAuditEvents(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	user := &store.User{ID: "user-a", TenantID: "tenant-a", Email: "a@example.com", PasswordHash: hash, Role: store.UserRoleUser}
+	ms := &mockStore{usersByEmail: map[string]*store.User{user.Email: user}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+
+	h.Login(httptest.NewRecorder(), withRequestID(signInRequest("192.0.2.1:4000", "A@example.com", "wrong"), "req-1"))
+	h.Login(httptest.NewRecorder(), withRequestID(signInRequest("192.0.2.1:4000", "a@example.com", "correct horse battery staple"), "req-2"))
+
+	want := []store.NewAuditEvent{
+		{
+			Action: store.AuditLogin, TargetType: "email", TargetID: "a@example.com",
+			RequestID: "req-1", IPAddress: "192.0.2.1", Outcome: store.AuditDenied, Detail: "invalid email or password",
+		},
+		{
+			ActorUserID: "user-a", TenantID: "tenant-a", Action: store.AuditLogin, TargetType: "email", TargetID: "a@example.com",
+			RequestID: "req-2", IPAddress: "192.0.2.1", Outcome: store.AuditSuccess,
+		},
+	}
+	if len(ms.auditEvents) != len(want) {
+		t.Fatalf("audit events = %#v, want %d", ms.auditEvents, len(want))
+	}
+	for i := range want {
+		if ms.auditEvents[i] != want[i] {
+			t.Errorf("audit event %d = %#v, want %#v", i, ms.auditEvents[i], want[i])
+		}
+	}
+}
+
+func TestDeleteUserAuditsRefusal(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{
+		UserID: "admin", TenantID: "admin", Role: auth.RoleAdmin, AuthMethod: "bearer",
+	})
+	req := httptest.NewRequestWithContext(ctx, http.MethodDelete, "/api/admin/users/admin", nil)
+	req.SetPathValue("id", "admin")
+	rec := httptest.NewRecorder()
+
+	h.DeleteUser(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403; body = %s", rec.Code, rec.Body.String())
+	}
+	if len(ms.auditEvents) != 1 {
+		t.Fatalf("audit events = %#v, want one", ms.auditEvents)
+	}
+	got := ms.auditEvents[0]
+	if got.Action != store.AuditUserDelete || got.ActorUserID != "admin" || got.AuthMethod != "bearer" ||
+		got.TargetID != "admin" || got.Outcome != store.AuditDenied || got.Detail != "cannot delete your own account" {
+		t.Fatalf("audit event = %#v", got)
+	}
+}
+
+func TestAuditFailureDoesNotFailRequest(t *testing.T) {
+	ms := &mockStore{auditErr: errors.E(errors.Unavailable, "database down")}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	req := httptest.NewRequestWithContext(adminContext(), http.MethodDelete, "/api/tokens/token-a", nil)
+	req.SetPathValue("id", "token-a")
+	rec := httptest.NewRecorder()
+
+	h.RevokeAccessToken(rec, req)
+
+	if rec.Code != http.StatusNoContent || ms.revokedAccessTokenID != "token-a" {
+		t.Fatalf("status = %d, revoked = %q; body = %s", rec.Code, ms.revokedAccessTokenID, rec.Body.String())
+	}
+}
+
+func TestAuditEndpointsRequireAdmin(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+	handlers := map[string]http.HandlerFunc{
+		"events":         h.ListAuditEvents,
+		"settings":       h.GetAuditSettings,
+		"patch settings": h.PatchAuditSettings,
+	}
+	for name, handler := range handlers {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequestWithContext(ctx, http.MethodPatch, "/api/admin/audit/settings", strings.NewReader(`{}`)))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s status = %d, want 403; body = %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestListAuditEventsFilters(t *testing.T) {
+	ms := &mockStore{auditEvents: []store.NewAuditEvent{
+		{ActorUserID: "user-a", Action: store.AuditLogin, TargetType: "email", TargetID: "a@example.com", Outcome: store.AuditDenied},
+		{ActorUserID: "user-a", Action: store.AuditLogin, TargetType: "email", TargetID: "a@example.com", Outcome: store.AuditSuccess},
+		{ActorUserID: "admin", Action: store.AuditUserCreate, TargetType: "user", TargetID: "user-b", Outcome: store.AuditSuccess},
+	}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	target := "/api/admin/audit/events?action=auth.login&outcome=denied&page=2&page_size=10" +
+		"&actor_user_id=00000000-0000-0000-0000-00000000c0de&from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z"
+	rec := httptest.NewRecorder()
+
+	h.ListAuditEvents(rec, httptest.NewRequestWithContext(adminContext(), http.MethodGet, target, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	filter := ms.auditFilter
+	if filter.Action != store.AuditLogin || filter.Outcome != store.AuditDenied || filter.Page != 2 || filter.PageSize != 10 ||
+		filter.ActorUserID != "00000000-0000-0000-0000-00000000c0de" || filter.From == nil || filter.To == nil {
+		t.Fatalf("filter = %#v", filter)
+	}
+	var resp AuditEventListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 1 || resp.Page != 2 || resp.PageSize != 10 || len(resp.Events) != 1 {
+		t.Fatalf("response = %#v", resp)
+	}
+	if got := resp.Events[0]; got.Action != "auth.login" || got.Outcome != "denied" || got.TargetID != "a@example.com" {
+		t.Fatalf("event = %#v", got)
+	}
+}
+
+func TestListAuditEventsRejectsInvalidQuery(t *testing.T) {
+	for _, target := range []string{
+		"/api/admin/audit/events?outcome=maybe",
+		"/api/admin/audit/events?actor_user_id=admin",
+		"/api/admin/audit/events?page_size=500",
+		"/api/admin/audit/events?from=2026-04-01T00:00:00Z&to=2026-03-01T00:00:00Z",
+	} {
+		h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+		rec := httptest.NewRecorder()
+		h.ListAuditEvents(rec, httptest.NewRequestWithContext(adminContext(), http.MethodGet, target, nil))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s status = %d, want 422; body = %s", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestPatchAuditSettings(t *testing.T) {
+	ms := &mockStore{auditSettings: store.AuditSettings{RetentionDays: 365}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	req := httptest.NewRequestWithContext(adminContext(), http.MethodPatch, "/api/admin/audit/settings", strings.NewReader(`{"retention_days":30}`))
+	rec := httptest.NewRecorder()
+
+	h.PatchAuditSettings(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp AuditSettingsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.RetentionDays != 30 || ms.auditSettingsPatch.RetentionDays == nil {
+		t.Fatalf("response = %#v, patch = %#v", resp, ms.auditSettingsPatch)
+	}
+	if len(ms.auditEvents) != 1 || ms.auditEvents[0].Action != store.AuditSettingsUpdate || ms.auditEvents[0].ActorUserID != "admin" {
+		t.Fatalf("audit events = %#v, want the settings change recorded", ms.auditEvents)
+	}
+
+	rec = httptest.NewRecorder()
+	h.PatchAuditSettings(rec, httptest.NewRequestWithContext(
+		adminContext(), http.MethodPatch, "/api/admin/audit/settings", strings.NewReader(`{"retention_days":4000}`),
+	))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("out of range status = %d, want 422; body = %s", rec.Code, rec.Body.String())
+	}
+}
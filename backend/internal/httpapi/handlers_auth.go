@@ -1,7 +1,9 @@
 package httpapi
 
 import (
+	"context"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -33,6 +35,8 @@ type loginRequest struct {
 
 type createAccessTokenRequest struct {
 	Name string `json:"name" validate:"required,no_control_chars" example:"contract"`
+	// Scopes defaults to every scope when omitted.
+	Scopes []string `json:"scopes" validate:"omitempty,dive,oneof=transactions:read transactions:write stats:read rules:read rules:write readers:read readers:write settings:read settings:write account admin" enums:"transactions:read,transactions:write,stats:read,rules:read,rules:write,readers:read,readers:write,settings:read,settings:write,account,admin" example:"stats:read"`
 }
 
 type updateProfileRequest struct {
@@ -71,6 +75,7 @@ type bootstrapResponse struct {
 type principalResponse struct {
 	UserID      string `json:"user_id"`
 	TenantID    string `json:"tenant_id"`
+	TenantRole  string `json:"tenant_role"`
 	Email       string `json:"email"`
 	DisplayName string `json:"display_name"`
 	Role        string `json:"role"`
@@ -81,6 +86,7 @@ type accessTokenResponse struct {
 	ID         string     `json:"id"`
 	Name       string     `json:"name"`
 	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes" example:"stats:read"`
 	CreatedAt  time.Time  `json:"created_at"`
 	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
@@ -147,32 +153,45 @@ func (h *Handlers) Bootstrap(w http.ResponseWriter, r *http.Request) {
 		writeError(w, r, err)
 		return
 	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
 	user, err := h.authStore.CreateBootstrapAdmin(r.Context(), store.CreateBootstrapAdminInput{
-		Email:        strings.ToLower(strings.TrimSpace(body.Email)),
+		Email:        email,
 		DisplayName:  strings.TrimSpace(body.DisplayName),
 		PasswordHash: passwordHash,
 		AvatarKey:    normalizeAvatarKey(body.AvatarKey),
 	})
 	if err != nil {
+		h.audit(r, store.NewAuditEvent{Action: store.AuditBootstrap, TargetType: "email", TargetID: email}, err)
 		writeError(w, r, err)
 		return
 	}
+	h.audit(r, store.NewAuditEvent{
+		ActorUserID: user.ID, TenantID: user.TenantID, Action: store.AuditBootstrap, TargetType: "user", TargetID: user.ID,
+	}, nil)
 	if !h.createSessionCookie(w, r, user) {
 		return
 	}
 	writeJSON(w, http.StatusCreated, principalFromUser(user))
 }
 
-// Login creates a browser session.
+// Login creates a browser session. Users with a second factor, and users
+// without one while MFA is required, get a short-lived session that can only
+// complete multi-factor authentication, and a 202 response saying how.
+// Repeated failures for one account from a client address, or from one
+// address for any account, lock further attempts from that address for a
+// growing period, answered with 429 and Retry-After. So do users whose second
+// factor is locked.
 // @Summary Create a browser session
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body loginRequest true "Login credentials"
 // @Success 201 {object} principalResponse
+// @Success 202 {object} mfaStatusResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 422 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /session [post]
 func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
@@ -180,22 +199,41 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
-	user, err := h.authStore.FindUserByEmail(r.Context(), strings.ToLower(strings.TrimSpace(body.Email)))
-	if err != nil {
-		if errors.WhatKind(err) != errors.NotFound {
-			logError(r, responseRequestID(w), err)
-		}
-		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("invalid email or password")))
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	ip := h.clientIP(r)
+	now := time.Now()
+	event := store.NewAuditEvent{Action: store.AuditLogin, TargetType: "email", TargetID: email}
+	if until := h.loginLockedUntil(email, ip); until.After(now) {
+		h.audit(r, event, errors.E(errors.ResourceExhausted, errors.User("sign-in locked after repeated failures")))
+		writeLoginLocked(w, r, until, now)
 		return
 	}
-	if user.DisabledAt != nil || auth.VerifyPassword(user.PasswordHash, body.Password) != nil {
-		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("invalid email or password")))
+	invalid := errors.E(errors.Unauthenticated, errors.User("invalid email or password"))
+	user, err := h.authStore.FindUserByEmail(r.Context(), email)
+	if err != nil && errors.WhatKind(err) != errors.NotFound {
+		logError(r, responseRequestID(w), err)
+		writeError(w, r, invalid)
 		return
 	}
-	if !h.createSessionCookie(w, r, user) {
+	// A locked second factor stays locked however often the password is
+	// entered, so signing in again does not buy more guesses.
+	if err == nil {
+		if until := h.mfaLockedUntil(user.ID); until.After(now) {
+			h.audit(r, event, errors.E(errors.ResourceExhausted, errors.User("second factor locked after repeated failures")))
+			writeLoginLocked(w, r, until, now)
+			return
+		}
+	}
+	if err != nil || user.DisabledAt != nil || auth.VerifyPassword(user.PasswordHash, body.Password) != nil {
+		h.recordLoginFailure(email, ip, now)
+		h.audit(r, event, invalid)
+		writeError(w, r, invalid)
 		return
 	}
-	writeJSON(w, http.StatusCreated, principalFromUser(user))
+	h.clearLoginFailures(email, ip)
+	event.ActorUserID, event.TenantID = user.ID, user.TenantID
+	h.audit(r, event, nil)
+	h.startPasswordSession(w, r, user)
 }
 
 // GetSession returns the current authenticated user.
@@ -211,7 +249,14 @@ func (h *Handlers) GetSession(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
-	writeJSON(w, http.StatusOK, principalFromUser(user))
+	resp := principalFromUser(user)
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		if principal.TenantID != "" && principal.TenantID != user.TenantID {
+			resp.TenantID = principal.TenantID
+			resp.TenantRole = string(principal.TenantRole)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // Logout revokes the current browser session cookie.
@@ -228,6 +273,9 @@ func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
 				writeError(w, r, err)
 				return
 			}
+			h.audit(r, store.NewAuditEvent{
+				ActorUserID: session.UserID, AuthMethod: "session", Action: store.AuditLogout, TargetType: "session", TargetID: session.ID,
+			}, nil)
 		}
 	}
 	clearSessionCookie(w, r)
@@ -304,8 +352,11 @@ func (h *Handlers) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	event := store.NewAuditEvent{Action: store.AuditPasswordChange, TargetType: "user", TargetID: user.ID}
 	if auth.VerifyPassword(user.PasswordHash, body.CurrentPassword) != nil {
-		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("current password is incorrect")))
+		err := errors.E(errors.Unauthenticated, errors.User("current password is incorrect"))
+		h.audit(r, event, err)
+		writeError(w, r, err)
 		return
 	}
 	passwordHash, err := auth.HashPassword(body.NewPassword)
@@ -313,7 +364,9 @@ func (h *Handlers) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		writeError(w, r, err)
 		return
 	}
-	if err := h.authStore.UpdateUserPassword(r.Context(), user.ID, store.UpdateUserPasswordInput{PasswordHash: passwordHash}); err != nil {
+	err = h.authStore.UpdateUserPassword(r.Context(), user.ID, store.UpdateUserPasswordInput{PasswordHash: passwordHash})
+	h.audit(r, event, err)
+	if err != nil {
 		if errors.WhatKind(err) == errors.NotFound {
 			writeError(w, r, errors.E(errors.Unauthenticated, errors.User("authentication required")))
 			return
@@ -324,7 +377,8 @@ func (h *Handlers) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// CreateAccessToken creates a programmatic access token.
+// CreateAccessToken creates a programmatic access token limited to the
+// requested scopes.
 // @Summary Create a programmatic access token
 // @Tags Auth
 // @Accept json
@@ -333,6 +387,7 @@ func (h *Handlers) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 // @Success 201 {object} accessTokenResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse
 // @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -347,6 +402,10 @@ func (h *Handlers) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	scopes, ok := accessTokenScopes(w, r, principal, body.Scopes)
+	if !ok {
+		return
+	}
 	raw, hash, err := auth.NewOpaqueToken(accessTokenPrefix)
 	if err != nil {
 		writeError(w, r, err)
@@ -356,11 +415,16 @@ func (h *Handlers) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
 		UserID:    principal.UserID,
 		Name:      strings.TrimSpace(body.Name),
 		TokenHash: hash,
+		Scopes:    scopes,
 	})
+	event := store.NewAuditEvent{Action: store.AuditAccessTokenCreate, TargetType: "access_token"}
 	if err != nil {
+		h.audit(r, event, err)
 		writeError(w, r, err)
 		return
 	}
+	event.TargetID = token.ID
+	h.audit(r, event, nil)
 	resp := accessTokenFromStore(token)
 	resp.Token = raw
 	writeJSON(w, http.StatusCreated, resp)
@@ -407,7 +471,10 @@ func (h *Handlers) RevokeAccessToken(w http.ResponseWriter, r *http.Request) {
 		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("authentication required")))
 		return
 	}
-	if err := h.authStore.RevokeAccessToken(r.Context(), r.PathValue("id"), principal.UserID); err != nil {
+	tokenID := r.PathValue("id")
+	err := h.authStore.RevokeAccessToken(r.Context(), tokenID, principal.UserID)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditAccessTokenRevoke, TargetType: "access_token", TargetID: tokenID}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -440,15 +507,18 @@ func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	if role == "" {
 		role = store.UserRoleUser
 	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
 	user, err := h.authStore.CreateUser(r.Context(), store.CreateUserInput{
-		Email:     strings.ToLower(strings.TrimSpace(body.Email)),
+		Email:     email,
 		Role:      role,
 		AvatarKey: "default",
 	})
 	if err != nil {
+		h.audit(r, store.NewAuditEvent{Action: store.AuditUserCreate, TargetType: "email", TargetID: email}, err)
 		writeError(w, r, err)
 		return
 	}
+	h.audit(r, store.NewAuditEvent{Action: store.AuditUserCreate, TargetType: "user", TargetID: user.ID}, nil)
 	writeJSON(w, http.StatusCreated, userFromStore(user))
 }
 
@@ -510,15 +580,21 @@ func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userID := r.PathValue("id")
+	event := store.NewAuditEvent{Action: store.AuditUserUpdate, TargetType: "user", TargetID: userID}
 	if input.Role != nil && userID == principal.UserID {
-		writeError(w, r, errors.E(errors.PermissionDenied, errors.User("cannot change your own role")))
+		err := errors.E(errors.PermissionDenied, errors.User("cannot change your own role"))
+		h.audit(r, event, err)
+		writeError(w, r, err)
 		return
 	}
 	if input.Disabled != nil && *input.Disabled && userID == principal.UserID {
-		writeError(w, r, errors.E(errors.PermissionDenied, errors.User("cannot disable your own account")))
+		err := errors.E(errors.PermissionDenied, errors.User("cannot disable your own account"))
+		h.audit(r, event, err)
+		writeError(w, r, err)
 		return
 	}
 	user, err := h.authStore.UpdateUser(r.Context(), userID, input)
+	h.audit(r, event, err)
 	if err != nil {
 		writeError(w, r, err)
 		return
@@ -546,11 +622,16 @@ func (h *Handlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userID := r.PathValue("id")
+	event := store.NewAuditEvent{Action: store.AuditUserDelete, TargetType: "user", TargetID: userID}
 	if userID == principal.UserID {
-		writeError(w, r, errors.E(errors.PermissionDenied, errors.User("cannot delete your own account")))
+		err := errors.E(errors.PermissionDenied, errors.User("cannot delete your own account"))
+		h.audit(r, event, err)
+		writeError(w, r, err)
 		return
 	}
-	if err := h.authStore.DeleteUser(r.Context(), userID); err != nil {
+	err := h.authStore.DeleteUser(r.Context(), userID)
+	h.audit(r, event, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -594,6 +675,7 @@ func (h *Handlers) CreateSetupToken(w http.ResponseWriter, r *http.Request) {
 		TokenHash: hash,
 		ExpiresAt: expiresAt,
 	})
+	h.audit(r, store.NewAuditEvent{Action: store.AuditSetupTokenCreate, TargetType: "user", TargetID: userID}, err)
 	if err != nil {
 		writeError(w, r, err)
 		return
@@ -628,6 +710,7 @@ func (h *Handlers) GetAccountSetup(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param request body completeAccountSetupRequest true "Setup token and password"
 // @Success 201 {object} principalResponse
+// @Success 202 {object} mfaStatusResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 422 {object} ErrorResponse
@@ -651,16 +734,16 @@ func (h *Handlers) CompleteAccountSetup(w http.ResponseWriter, r *http.Request)
 	})
 	if err != nil {
 		if errors.WhatKind(err) == errors.NotFound {
-			writeError(w, r, errors.E(errors.Unauthenticated, errors.User("invalid or expired setup token")))
-			return
+			err = errors.E(errors.Unauthenticated, errors.User("invalid or expired setup token"))
 		}
+		h.audit(r, store.NewAuditEvent{Action: store.AuditAccountSetup}, err)
 		writeError(w, r, err)
 		return
 	}
-	if !h.createSessionCookie(w, r, user) {
-		return
-	}
-	writeJSON(w, http.StatusCreated, principalFromUser(user))
+	h.audit(r, store.NewAuditEvent{
+		ActorUserID: user.ID, TenantID: user.TenantID, Action: store.AuditAccountSetup, TargetType: "user", TargetID: user.ID,
+	}, nil)
+	h.startPasswordSession(w, r, user)
 }
 
 func (h *Handlers) accountSetupUserFromToken(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
@@ -695,16 +778,23 @@ func (h *Handlers) accountSetupUserFromToken(w http.ResponseWriter, r *http.Requ
 }
 
 func (h *Handlers) createSessionCookie(w http.ResponseWriter, r *http.Request, user *store.User) bool {
+	return h.createSessionCookieWithMFA(w, r, user, store.SessionMFAComplete, sessionTTL)
+}
+
+func (h *Handlers) createSessionCookieWithMFA(w http.ResponseWriter, r *http.Request, user *store.User, state store.SessionMFA, ttl time.Duration) bool {
 	raw, hash, err := auth.NewOpaqueToken(sessionTokenPrefix)
 	if err != nil {
 		writeError(w, r, err)
 		return false
 	}
-	expiresAt := time.Now().Add(sessionTTL)
+	expiresAt := time.Now().Add(ttl)
 	if _, err := h.authStore.CreateSession(r.Context(), store.CreateSessionInput{
 		UserID:    user.ID,
 		TokenHash: hash,
 		ExpiresAt: expiresAt,
+		MFA:       state,
+		IPAddress: h.clientIP(r),
+		UserAgent: truncateUserAgent(r.UserAgent()),
 	}); err != nil {
 		writeError(w, r, err)
 		return false
@@ -713,6 +803,52 @@ func (h *Handlers) createSessionCookie(w http.ResponseWriter, r *http.Request, u
 	return true
 }
 
+// provisionUser creates the account for an email vouched for by single
+// sign-on or a trusted proxy. The first account on a fresh instance becomes
+// the administrator, as with password bootstrap. admin is the provider's role
+// mapping, or nil when it has none.
+func (h *Handlers) provisionUser(ctx context.Context, source, email, displayName string, admin *bool) (*store.User, error) {
+	const op = "httpapi.provision_user"
+	if displayName == "" {
+		displayName, _, _ = strings.Cut(email, "@")
+	}
+	bootstrap, err := h.authStore.BootstrapRequired(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var user *store.User
+	if bootstrap {
+		if admin != nil && !*admin {
+			return nil, errors.E(op, errors.PermissionDenied, "the first account must be an administrator but the role mapping denies it")
+		}
+		user, err = h.authStore.CreateBootstrapAdmin(ctx, store.CreateBootstrapAdminInput{
+			Email:       email,
+			DisplayName: displayName,
+			AvatarKey:   defaultAvatarKey,
+		})
+	} else {
+		role := store.UserRoleUser
+		if admin != nil && *admin {
+			role = store.UserRoleAdmin
+		}
+		user, err = h.authStore.CreateUser(ctx, store.CreateUserInput{
+			Email:       email,
+			DisplayName: displayName,
+			Role:        role,
+			AvatarKey:   defaultAvatarKey,
+		})
+	}
+	if errors.WhatKind(err) == errors.Conflict {
+		// A concurrent first request for the same email won the race.
+		return h.authStore.FindUserByEmail(ctx, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.logger.Info("account provisioned", "source", source, "user_id", user.ID, "role", user.Role)
+	return user, nil
+}
+
 func (h *Handlers) currentUser(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
 	principal, ok := auth.PrincipalFromContext(r.Context())
 	if !ok {
@@ -753,6 +889,7 @@ func principalFromUser(user *store.User) principalResponse {
 	return principalResponse{
 		UserID:      user.ID,
 		TenantID:    user.TenantID,
+		TenantRole:  string(store.TenantRoleOwner),
 		Email:       user.Email,
 		DisplayName: user.DisplayName,
 		Role:        string(user.Role),
@@ -775,10 +912,38 @@ func userFromStore(user *store.User) userResponse {
 	}
 }
 
+// accessTokenScopes resolves the requested scopes, defaulting to all of them.
+// A token cannot be given a scope the requesting token does not hold.
+func accessTokenScopes(w http.ResponseWriter, r *http.Request, principal auth.Principal, requested []string) ([]string, bool) {
+	if requested == nil {
+		requested = make([]string, 0, len(auth.AllScopes))
+		for _, scope := range auth.AllScopes {
+			requested = append(requested, string(scope))
+		}
+	}
+	if len(requested) == 0 {
+		writeValidationErrors(w, []ValidationError{{Field: "scopes", Location: "body", Message: "must list at least one scope"}})
+		return nil, false
+	}
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range auth.AllScopes {
+		if !slices.Contains(requested, string(scope)) {
+			continue
+		}
+		if !principal.HasScope(scope) {
+			writeError(w, r, errors.E(errors.PermissionDenied, errors.User("cannot grant the "+string(scope)+" scope")))
+			return nil, false
+		}
+		scopes = append(scopes, string(scope))
+	}
+	return scopes, true
+}
+
 func accessTokenFromStore(token *store.AccessToken) accessTokenResponse {
 	return accessTokenResponse{
 		ID:         token.ID,
 		Name:       token.Name,
+		Scopes:     token.Scopes,
 		CreatedAt:  token.CreatedAt,
 		ExpiresAt:  token.ExpiresAt,
 		LastUsedAt: token.LastUsedAt,
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"net/url"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -49,7 +51,7 @@ func TestAuthMiddlewareSessionSetsRequestTenant(t *testing.T) {
 			"base_currency": "INR",
 		},
 		sessionsByHash: map[string]*store.Session{
-			hash: {ID: "session-a", UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour)},
+			hash: {ID: "session-a", UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour), MFA: store.SessionMFAComplete},
 		},
 		usersByID: map[string]*store.User{user.ID: user},
 	}
@@ -88,7 +90,7 @@ func TestAuthMiddlewareBearerSetsRequestTenant(t *testing.T) {
 	ms := &mockStore{
 		appConfig: map[string]string{"base_currency": "INR"},
 		accessTokensByHash: map[string]*store.AccessToken{
-			hash: {ID: "token-a", UserID: user.ID, TokenHash: hash},
+			hash: {ID: "token-a", UserID: user.ID, TokenHash: hash, Scopes: []string{"settings:read"}},
 		},
 		usersByID: map[string]*store.User{user.ID: user},
 	}
@@ -111,6 +113,137 @@ func TestAuthMiddlewareBearerSetsRequestTenant(t *testing.T) {
 	}
 }
 
+func proxyAuthHandler(t *testing.T, ms *mockStore, cfg ProxyAuthConfig) (http.Handler, *auth.Principal) {
+	t.Helper()
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	cfg.Header = "X-Forwarded-Email"
+	cfg.NameHeader = "X-Forwarded-User"
+	cfg.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	h.proxyAuth = cfg
+	var got auth.Principal
+	return authMiddleware(h, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = auth.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})), &got
+}
+
+func proxyRequest(remoteAddr, email string) *http.Request {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-Email", email)
+	req.Header.Set("X-Forwarded-User", "Asha K")
+	return req
+}
+
+func TestAuthMiddlewareProxyHeader(t *testing.T) {
+	user := &store.User{ID: "user-a", TenantID: "user-a", Email: "asha@example.com", Role: store.UserRoleAdmin}
+	ms := &mockStore{usersByEmail: map[string]*store.User{user.Email: user}}
+	handler, principal := proxyAuthHandler(t, ms, ProxyAuthConfig{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, proxyRequest("10.1.2.3:41234", "Asha@Example.com"))
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204; body = %s", rec.Code, rec.Body.String())
+	}
+	if principal.UserID != user.ID || principal.AuthMethod != "proxy" || principal.Role != auth.RoleAdmin {
+		t.Fatalf("principal = %#v", *principal)
+	}
+
+	// IPv4 peers may arrive as IPv4-mapped IPv6 addresses on dual-stack listeners.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, proxyRequest("[::ffff:10.1.2.3]:41234", "asha@example.com"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("mapped address status = %d, want 204", rec.Code)
+	}
+}
+
+func TestAuthMiddlewareAuditsProxySignInsOnce(t *testing.T) {
+	user := &store.User{ID: "user-a", TenantID: "user-a", Email: "asha@example.com", Role: store.UserRoleUser}
+	ms := &mockStore{usersByEmail: map[string]*store.User{user.Email: user}}
+	handler, _ := proxyAuthHandler(t, ms, ProxyAuthConfig{})
+
+	for _, email := range []string{user.Email, user.Email, "ravi@example.com", "ravi@example.com"} {
+		handler.ServeHTTP(httptest.NewRecorder(), proxyRequest("10.1.2.3:41234", email))
+	}
+
+	if len(ms.auditEvents) != 2 {
+		t.Fatalf("audit events = %#v, want one per identity", ms.auditEvents)
+	}
+	if got := ms.auditEvents[0]; got.Action != store.AuditLogin || got.AuthMethod != "proxy" || got.ActorUserID != user.ID ||
+		got.Outcome != store.AuditSuccess {
+		t.Fatalf("sign-in event = %#v", got)
+	}
+	if got := ms.auditEvents[1]; got.ActorUserID != "" || got.TargetID != "ravi@example.com" || got.Outcome != store.AuditDenied {
+		t.Fatalf("refusal event = %#v", got)
+	}
+}
+
+func TestAuthMiddlewareIgnoresProxyHeaderFromUntrustedPeer(t *testing.T) {
+	user := &store.User{ID: "user-a", TenantID: "user-a", Email: "asha@example.com", Role: store.UserRoleAdmin}
+	ms := &mockStore{usersByEmail: map[string]*store.User{user.Email: user}}
+	handler, principal := proxyAuthHandler(t, ms, ProxyAuthConfig{Provision: true})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, proxyRequest("192.168.1.20:41234", user.Email))
+
+	if rec.Code != http.StatusUnauthorized || principal.UserID != "" {
+		t.Fatalf("status = %d, principal = %#v; want 401 without a principal", rec.Code, *principal)
+	}
+}
+
+func TestAuthMiddlewareProxyProvisioning(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       ProxyAuthConfig
+		email     string
+		disabled  bool
+		wantCode  int
+		wantEmail string
+	}{
+		{name: "off", cfg: ProxyAuthConfig{}, email: "ravi@example.com", wantCode: http.StatusUnauthorized},
+		{name: "any domain", cfg: ProxyAuthConfig{Provision: true}, email: "ravi@example.com", wantCode: http.StatusNoContent, wantEmail: "ravi@example.com"},
+		{
+			name:      "allowed domain",
+			cfg:       ProxyAuthConfig{Provision: true, ProvisionDomains: []string{"example.com"}},
+			email:     "ravi@example.com",
+			wantCode:  http.StatusNoContent,
+			wantEmail: "ravi@example.com",
+		},
+		{
+			name:     "other domain",
+			cfg:      ProxyAuthConfig{Provision: true, ProvisionDomains: []string{"example.com"}},
+			email:    "ravi@elsewhere.example",
+			wantCode: http.StatusUnauthorized,
+		},
+		{name: "not an email", cfg: ProxyAuthConfig{Provision: true}, email: "ravi", wantCode: http.StatusUnauthorized},
+		{name: "disabled", cfg: ProxyAuthConfig{}, email: "asha@example.com", disabled: true, wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &mockStore{}
+			if tt.disabled {
+				disabledAt := time.Now()
+				ms.usersByEmail = map[string]*store.User{"asha@example.com": {ID: "user-a", Email: "asha@example.com", DisabledAt: &disabledAt}}
+			}
+			handler, principal := proxyAuthHandler(t, ms, tt.cfg)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, proxyRequest("10.0.0.7:5000", tt.email))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if ms.createdUser.Email != tt.wantEmail {
+				t.Fatalf("created user = %#v, want email %q", ms.createdUser, tt.wantEmail)
+			}
+			if tt.wantEmail != "" && (ms.createdUser.DisplayName != "Asha K" || ms.createdUser.Role != store.UserRoleUser || principal.AuthMethod != "proxy") {
+				t.Fatalf("created user = %#v, principal = %#v", ms.createdUser, *principal)
+			}
+		})
+	}
+}
+
 func TestBootstrapCreatesAdminAndSessionCookie(t *testing.T) {
 	ms := &mockStore{bootstrapRequired: true}
 	h := newTestHandlers(t, ms, &mockDaemon{})
@@ -218,6 +351,124 @@ func TestCreateAccessTokenReturnsRawTokenOnce(t *testing.T) {
 	if strings.Contains(ms.createdAccessToken.TokenHash, resp.Token) {
 		t.Fatalf("stored token hash contains raw token")
 	}
+	if len(resp.Scopes) != len(auth.AllScopes) || len(ms.createdAccessToken.Scopes) != len(auth.AllScopes) {
+		t.Fatalf("scopes = %v, stored %v; want every scope", resp.Scopes, ms.createdAccessToken.Scopes)
+	}
+}
+
+func TestCreateAccessTokenScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  auth.Principal
+		body       string
+		wantStatus int
+		wantScopes []string
+	}{
+		{
+			name:       "session picks scopes",
+			principal:  auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser},
+			body:       `{"name":"grafana","scopes":["transactions:read","stats:read"]}`,
+			wantStatus: http.StatusCreated,
+			wantScopes: []string{"transactions:read", "stats:read"},
+		},
+		{
+			name: "token grants a scope it holds through a write scope",
+			principal: auth.Principal{
+				UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser,
+				Scopes: []auth.Scope{auth.ScopeAccount, auth.ScopeRulesWrite},
+			},
+			body:       `{"name":"rules","scopes":["rules:read"]}`,
+			wantStatus: http.StatusCreated,
+			wantScopes: []string{"rules:read"},
+		},
+		{
+			name: "token cannot grant more than it holds",
+			principal: auth.Principal{
+				UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser,
+				Scopes: []auth.Scope{auth.ScopeAccount, auth.ScopeStatsRead},
+			},
+			body:       `{"name":"escalate","scopes":["stats:read","transactions:write"]}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "empty scope list",
+			principal:  auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser},
+			body:       `{"name":"nothing","scopes":[]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown scope",
+			principal:  auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser},
+			body:       `{"name":"unknown","scopes":["transactions:delete"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &mockStore{}
+			h := newTestHandlers(t, ms, &mockDaemon{})
+			ctx := auth.WithPrincipal(context.Background(), tt.principal)
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/tokens", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			h.CreateAccessToken(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if ms.createdAccessToken.TokenHash != "" {
+					t.Fatalf("created token = %#v, want no store write", ms.createdAccessToken)
+				}
+				return
+			}
+			if !slices.Equal(ms.createdAccessToken.Scopes, tt.wantScopes) {
+				t.Fatalf("stored scopes = %v, want %v", ms.createdAccessToken.Scopes, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestBearerTokenScopesLimitRoutes(t *testing.T) {
+	raw, hash, err := auth.NewOpaqueToken(accessTokenPrefix)
+	if err != nil {
+		t.Fatalf("NewOpaqueToken() error = %v", err)
+	}
+	user := &store.User{ID: "user-a", TenantID: "tenant-a", Email: "a@example.com", Role: store.UserRoleAdmin}
+	ms := &mockStore{
+		appConfig: map[string]string{"base_currency": "INR"},
+		accessTokensByHash: map[string]*store.AccessToken{
+			hash: {ID: "token-a", UserID: user.ID, TokenHash: hash, Scopes: []string{"settings:read"}},
+		},
+		usersByID: map[string]*store.User{user.ID: user},
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	mux := http.NewServeMux()
+	registerRoutes(mux, h)
+	handler := authMiddleware(h, mux)
+
+	tests := []struct {
+		method, path string
+		wantStatus   int
+	}{
+		{method: http.MethodGet, path: "/api/config/preferences", wantStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/api/session", wantStatus: http.StatusOK},
+		{method: http.MethodPatch, path: "/api/config/preferences", wantStatus: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/transactions", wantStatus: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/admin/users", wantStatus: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/tokens", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequestWithContext(context.Background(), tt.method, tt.path, strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+raw)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s %s status = %d, want %d; body = %s", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+		}
+	}
 }
 
 func TestCreateAccessTokenNameConflictReturnsConflict(t *testing.T) {
@@ -251,6 +502,7 @@ func TestListAccessTokensReturnsCurrentUserTokenMetadata(t *testing.T) {
 				UserID:     "user-a",
 				Name:       "cli",
 				TokenHash:  "sha256:secret",
+				Scopes:     []string{"stats:read"},
 				CreatedAt:  createdAt,
 				LastUsedAt: &lastUsedAt,
 			},
@@ -273,7 +525,7 @@ func TestListAccessTokensReturnsCurrentUserTokenMetadata(t *testing.T) {
 	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 		t.Fatalf("decode response: %v", err)
 	}
-	if len(resp) != 1 || resp[0].ID != "token-a" || resp[0].Name != "cli" || resp[0].Token != "" {
+	if len(resp) != 1 || resp[0].ID != "token-a" || resp[0].Name != "cli" || resp[0].Token != "" || !slices.Equal(resp[0].Scopes, []string{"stats:read"}) {
 		t.Fatalf("tokens response = %#v", resp)
 	}
 	if !resp[0].CreatedAt.Equal(createdAt) || resp[0].LastUsedAt == nil || !resp[0].LastUsedAt.Equal(lastUsedAt) {
@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/ArionMiles/expensor/backend/internal/backup"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// ListBackups handles GET /api/admin/backups.
+// @Summary List database backups, newest first
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} BackupResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /admin/backups [get]
+func (h *Handlers) ListBackups(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) || !h.requireBackups(w, r) {
+		return
+	}
+	backups, err := h.backups.List(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := make([]BackupResponse, 0, len(backups))
+	for _, info := range backups {
+		out = append(out, backupResponse(info))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// CreateBackup handles POST /api/admin/backups. The backup is written,
+// uploaded and verified before the response; retention runs afterwards.
+// @Summary Take a database backup now
+// @Tags Admin
+// @Produce json
+// @Success 201 {object} BackupResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /admin/backups [post]
+func (h *Handlers) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) || !h.requireBackups(w, r) {
+		return
+	}
+	info, err := h.backups.Create(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, backupResponse(info))
+}
+
+// RestoreBackup handles POST /api/admin/backups/{name}/restore. The archive
+// checksum is verified and its schema compared with the current database;
+// with dry_run=true nothing else happens. Otherwise a safety backup is taken
+// and the whole database, including users and sessions, is replaced.
+// @Summary Restore a database backup
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Backup name" example(expensor-backup-20260301T020000Z.tar.gz)
+// @Param dry_run query bool false "Only validate the backup against the current schema"
+// @Success 200 {object} BackupRestoreResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /admin/backups/{name}/restore [post]
+func (h *Handlers) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) || !h.requireBackups(w, r) {
+		return
+	}
+	query, ok := decodeAndValidateQuery[backupRestoreQuery](h, w, r)
+	if !ok {
+		return
+	}
+	result, err := h.backups.Restore(r.Context(), r.PathValue("name"), query.DryRun)
+	if !query.DryRun {
+		h.audit(r, store.NewAuditEvent{Action: store.AuditBackupRestore, TargetType: "backup", TargetID: r.PathValue("name")}, err)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, BackupRestoreResponse{
+		Backup:               result.Backup,
+		DryRun:               result.DryRun,
+		CreatedAt:            result.CreatedAt,
+		AppVersion:           result.AppVersion,
+		SchemaVersion:        result.SchemaVersion,
+		CurrentSchemaVersion: result.CurrentSchemaVersion,
+		Tables:               result.Tables,
+		Rows:                 result.Rows,
+		Problems:             result.Problems,
+		Warnings:             result.Warnings,
+		SafetyBackup:         result.SafetyBackup,
+		Restored:             result.Restored,
+	})
+}
+
+// GetBackupSettings handles GET /api/admin/backups/settings.
+// @Summary Get backup schedule and retention settings
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} BackupSettingsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/backups/settings [get]
+func (h *Handlers) GetBackupSettings(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	settings, err := h.backupStore.GetBackupSettings(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, backupSettingsResponse(settings))
+}
+
+// PatchBackupSettings handles PATCH /api/admin/backups/settings.
+// @Summary Update backup schedule and retention settings
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body BackupSettingsPatchRequest true "Backup settings patch"
+// @Success 200 {object} BackupSettingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/backups/settings [patch]
+func (h *Handlers) PatchBackupSettings(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	body, ok := decodeAndValidateJSON[BackupSettingsPatchRequest](h, w, r)
+	if !ok {
+		return
+	}
+	settings, err := h.backupStore.PatchBackupSettings(r.Context(), store.BackupSettingsPatch{
+		Enabled:       body.Enabled,
+		IntervalHours: body.IntervalHours,
+		KeepLast:      body.KeepLast,
+		KeepDays:      body.KeepDays,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, backupSettingsResponse(settings))
+}
+
+func (h *Handlers) requireBackups(w http.ResponseWriter, r *http.Request) bool {
+	if h.backups == nil {
+		writeError(w, r, errors.E(errors.Unimplemented, errors.User("backups not configured")))
+		return false
+	}
+	return true
+}
+
+func backupResponse(info backup.Info) BackupResponse {
+	return BackupResponse{Name: info.Name, Size: info.Size, CreatedAt: info.CreatedAt}
+}
+
+func backupSettingsResponse(settings store.BackupSettings) BackupSettingsResponse {
+	return BackupSettingsResponse{
+		Enabled:        settings.Enabled,
+		IntervalHours:  settings.IntervalHours,
+		KeepLast:       settings.KeepLast,
+		KeepDays:       settings.KeepDays,
+		LastStartedAt:  settings.LastStartedAt,
+		LastFinishedAt: settings.LastFinishedAt,
+		LastBackup:     settings.LastBackup,
+		LastError:      settings.LastError,
+		UpdatedAt:      settings.UpdatedAt,
+	}
+}
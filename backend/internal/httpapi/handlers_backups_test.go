@@ -0,0 +1,173 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/backup"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type mockBackups struct {
+	backups    []backup.Info
+	created    int
+	restored   string
+	dryRun     bool
+	restoreErr error
+}
+
+func (m *mockBackups) Create(context.Context) (backup.Info, error) {
+	m.created++
+	return backup.Info{Name: "expensor-backup-20260301T020000Z.tar.gz", Size: 10, CreatedAt: time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)}, nil
+}
+
+func (m *mockBackups) List(context.Context) ([]backup.Info, error) { return m.backups, nil }
+
+func (m *mockBackups) Restore(_ context.Context, name string, dryRun bool) (backup.RestoreResult, error) {
+	if m.restoreErr != nil {
+		return backup.RestoreResult{}, m.restoreErr
+	}
+	m.restored, m.dryRun = name, dryRun
+	return backup.RestoreResult{
+		Backup: name, DryRun: dryRun, SchemaVersion: 19, CurrentSchemaVersion: 19,
+		Problems: []string{}, Warnings: []string{}, Restored: !dryRun,
+	}, nil
+}
+
+func TestBackupEndpointsRequireAdmin(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	h.backups = &mockBackups{}
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+	for name, handler := range map[string]http.HandlerFunc{
+		"list": h.ListBackups, "create": h.CreateBackup, "restore": h.RestoreBackup,
+		"get settings": h.GetBackupSettings, "patch settings": h.PatchBackupSettings,
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/admin/backups", strings.NewReader("{}")))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s status = %d, want 403; body = %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestBackupEndpointsWithoutService(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.ListBackups(rec, httptest.NewRequestWithContext(adminContext(), http.MethodGet, "/api/admin/backups", nil))
+
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("status = %d, want 501; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListAndCreateBackups(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	backups := &mockBackups{backups: []backup.Info{{Name: "expensor-backup-20260301T020000Z.tar.gz", Size: 10}}}
+	h.backups = backups
+
+	rec := httptest.NewRecorder()
+	h.ListBackups(rec, httptest.NewRequestWithContext(adminContext(), http.MethodGet, "/api/admin/backups", nil))
+	var listed []BackupResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, err = %v; body = %s", rec.Code, err, rec.Body.String())
+	}
+	if len(listed) != 1 || listed[0].Size != 10 {
+		t.Fatalf("listed = %+v", listed)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CreateBackup(rec, httptest.NewRequestWithContext(adminContext(), http.MethodPost, "/api/admin/backups", nil))
+	if rec.Code != http.StatusCreated || backups.created != 1 {
+		t.Fatalf("create status = %d, created = %d; body = %s", rec.Code, backups.created, rec.Body.String())
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	const name = "expensor-backup-20260301T020000Z.tar.gz"
+	restoreRequest := func(query string) *http.Request {
+		req := httptest.NewRequestWithContext(adminContext(), http.MethodPost, "/api/admin/backups/"+name+"/restore"+query, nil)
+		req.SetPathValue("name", name)
+		return req
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		ms := &mockStore{}
+		h := newTestHandlers(t, ms, &mockDaemon{})
+		backups := &mockBackups{}
+		h.backups = backups
+		rec := httptest.NewRecorder()
+
+		h.RestoreBackup(rec, restoreRequest("?dry_run=true"))
+
+		var body BackupRestoreResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, err = %v; body = %s", rec.Code, err, rec.Body.String())
+		}
+		if backups.restored != name || !backups.dryRun || body.Restored || !body.DryRun {
+			t.Fatalf("restored %q dry_run=%v, response = %+v", backups.restored, backups.dryRun, body)
+		}
+		if len(ms.auditEvents) != 0 {
+			t.Fatalf("dry run recorded %#v", ms.auditEvents)
+		}
+	})
+
+	t.Run("invalid dry_run", func(t *testing.T) {
+		h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+		h.backups = &mockBackups{}
+		rec := httptest.NewRecorder()
+
+		h.RestoreBackup(rec, restoreRequest("?dry_run=maybe"))
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want 422; body = %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("schema mismatch", func(t *testing.T) {
+		ms := &mockStore{}
+		h := newTestHandlers(t, ms, &mockDaemon{})
+		h.backups = &mockBackups{restoreErr: errors.E(errors.FailedPrecondition, errors.User("The backup does not match the current schema."))}
+		rec := httptest.NewRecorder()
+
+		h.RestoreBackup(rec, restoreRequest(""))
+
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Fatalf("status = %d, want 412; body = %s", rec.Code, rec.Body.String())
+		}
+		if len(ms.auditEvents) != 1 || ms.auditEvents[0].Action != store.AuditBackupRestore ||
+			ms.auditEvents[0].TargetID != name || ms.auditEvents[0].Outcome != store.AuditFailure {
+			t.Fatalf("audit events = %#v", ms.auditEvents)
+		}
+	})
+}
+
+func TestPatchBackupSettings(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.PatchBackupSettings(rec, httptest.NewRequestWithContext(adminContext(), http.MethodPatch, "/api/admin/backups/settings",
+		strings.NewReader(`{"enabled":true,"keep_last":3}`)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if !ms.backupSettings.Enabled || ms.backupSettings.KeepLast != 3 || ms.backupSettingsPatch.KeepDays != nil {
+		t.Fatalf("patch = %+v, settings = %+v", ms.backupSettingsPatch, ms.backupSettings)
+	}
+
+	rec = httptest.NewRecorder()
+	h.PatchBackupSettings(rec, httptest.NewRequestWithContext(adminContext(), http.MethodPatch, "/api/admin/backups/settings",
+		strings.NewReader(`{"interval_hours":0}`)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid interval status = %d, want 422; body = %s", rec.Code, rec.Body.String())
+	}
+}
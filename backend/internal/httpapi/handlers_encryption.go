@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/ArionMiles/expensor/backend/internal/rekey"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// GetEncryptionStatus handles GET /api/admin/encryption.
+// @Summary Show the secret keyring and re-encryption progress
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} EncryptionStatusResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /admin/encryption [get]
+func (h *Handlers) GetEncryptionStatus(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) || !h.requireSecretRotation(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, encryptionStatusResponse(h.secretRotation.Status()))
+}
+
+// StartReencryption handles POST /api/admin/encryption/reencrypt. Reader
+// secrets, reader tokens, LLM credentials, second-factor secrets and
+// attachments are resealed with the active key in the background; poll
+// GET /api/admin/encryption for progress.
+// @Summary Re-encrypt sealed values with the active secret key
+// @Tags Admin
+// @Produce json
+// @Success 202 {object} EncryptionStatusResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /admin/encryption/reencrypt [post]
+func (h *Handlers) StartReencryption(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) || !h.requireSecretRotation(w, r) {
+		return
+	}
+	status, err := h.secretRotation.Start()
+	h.audit(r, store.NewAuditEvent{Action: store.AuditReencryptionStart, TargetType: "secret_key"}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, encryptionStatusResponse(status))
+}
+
+func (h *Handlers) requireSecretRotation(w http.ResponseWriter, r *http.Request) bool {
+	if h.secretRotation == nil {
+		writeError(w, r, errors.E(errors.Unimplemented, errors.User("re-encryption not configured")))
+		return false
+	}
+	return true
+}
+
+func encryptionStatusResponse(status rekey.Status) EncryptionStatusResponse {
+	out := EncryptionStatusResponse{
+		ActiveKeyID:    status.ActiveKeyID,
+		PreviousKeyIDs: status.PreviousKeyIDs,
+		State:          string(status.State),
+		StartedAt:      status.StartedAt,
+		FinishedAt:     status.FinishedAt,
+		Kinds:          make([]EncryptionKindProgressResponse, 0, len(status.Kinds)),
+		Failures:       status.Failures,
+		Error:          status.Error,
+	}
+	if out.PreviousKeyIDs == nil {
+		out.PreviousKeyIDs = []string{}
+	}
+	if out.Failures == nil {
+		out.Failures = []string{}
+	}
+	for _, progress := range status.Kinds {
+		out.Kinds = append(out.Kinds, EncryptionKindProgressResponse{
+			Kind:     string(progress.Kind),
+			Total:    progress.Total,
+			Scanned:  progress.Scanned,
+			Resealed: progress.Resealed,
+			Failed:   progress.Failed,
+		})
+	}
+	return out
+}
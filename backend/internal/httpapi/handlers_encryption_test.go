@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/rekey"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type mockSecretRotator struct {
+	status  rekey.Status
+	started int
+}
+
+func (m *mockSecretRotator) Start() (rekey.Status, error) {
+	if m.status.State == rekey.StateRunning {
+		return rekey.Status{}, errors.E(errors.Conflict, errors.User("Re-encryption is already running."))
+	}
+	m.started++
+	started := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	m.status.State = rekey.StateRunning
+	m.status.StartedAt = &started
+	m.status.Kinds = []rekey.KindProgress{{Kind: store.SealedReaderOAuthToken, Total: 3}}
+	return m.status, nil
+}
+
+func (m *mockSecretRotator) Status() rekey.Status { return m.status }
+
+func TestEncryptionEndpointsRequireAdmin(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	h.secretRotation = &mockSecretRotator{}
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+	for name, handler := range map[string]http.HandlerFunc{"status": h.GetEncryptionStatus, "reencrypt": h.StartReencryption} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/admin/encryption/reencrypt", nil))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s status = %d, want 403; body = %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestEncryptionEndpointsWithoutService(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.GetEncryptionStatus(rec, httptest.NewRequestWithContext(adminContext(), http.MethodGet, "/api/admin/encryption", nil))
+
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("status = %d, want 501; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStartReencryption(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rotator := &mockSecretRotator{status: rekey.Status{State: rekey.StateIdle, ActiveKeyID: "3f9a0c1d27e4b856"}}
+	h.secretRotation = rotator
+
+	rec := httptest.NewRecorder()
+	h.GetEncryptionStatus(rec, httptest.NewRequestWithContext(adminContext(), http.MethodGet, "/api/admin/encryption", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var idle EncryptionStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&idle); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if idle.State != "idle" || idle.ActiveKeyID != "3f9a0c1d27e4b856" || idle.PreviousKeyIDs == nil || idle.Failures == nil {
+		t.Fatalf("status = %#v", idle)
+	}
+
+	rec = httptest.NewRecorder()
+	h.StartReencryption(rec, httptest.NewRequestWithContext(adminContext(), http.MethodPost, "/api/admin/encryption/reencrypt", nil))
+	if rec.Code != http.StatusAccepted || rotator.started != 1 {
+		t.Fatalf("start status = %d, started = %d; body = %s", rec.Code, rotator.started, rec.Body.String())
+	}
+	if len(ms.auditEvents) != 1 || ms.auditEvents[0].Action != store.AuditReencryptionStart || ms.auditEvents[0].Outcome != store.AuditSuccess {
+		t.Fatalf("audit events = %#v", ms.auditEvents)
+	}
+	var running EncryptionStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&running); err != nil {
+		t.Fatalf("decode start: %v", err)
+	}
+	if running.State != "running" || len(running.Kinds) != 1 || running.Kinds[0].Kind != "reader_oauth_token" || running.Kinds[0].Total != 3 {
+		t.Fatalf("start = %#v", running)
+	}
+
+	rec = httptest.NewRecorder()
+	h.StartReencryption(rec, httptest.NewRequestWithContext(adminContext(), http.MethodPost, "/api/admin/encryption/reencrypt", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second start status = %d, want 409; body = %s", rec.Code, rec.Body.String())
+	}
+}
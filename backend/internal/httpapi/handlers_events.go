@@ -0,0 +1,118 @@
+package httpapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/events"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// eventKeepAlive is how often a stream sends a comment, so that proxies
+	// keep it open and a client that went away is noticed. The stream's
+	// credentials are checked again each time.
+	eventKeepAlive = 20 * time.Second
+	// eventWriteWindow bounds each write to a stream; the server's write
+	// timeout would otherwise end every stream after 30 seconds.
+	eventWriteWindow = 10 * time.Second
+	// eventRetry is how long browsers wait before reconnecting, in
+	// milliseconds.
+	eventRetry = 3000
+)
+
+// StreamEvents handles GET /api/events, a server-sent event stream of the
+// active tenant's scan state changes, stored transaction batches, new
+// diagnostics and community syncs. A client reconnecting with Last-Event-ID
+// first receives the events it missed, or a reset event when they are no
+// longer buffered and it has to reload. The stream ends once its session or
+// token is revoked, the user is disabled or it loses access to the tenant.
+// @Summary Stream live events
+// @Tags Events
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received, to resume after it"
+// @Success 200 {string} string "Server-sent events"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /events [get]
+func (h *Handlers) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		writeError(w, r, errors.E(errors.Unavailable, errors.User("live events are not available")))
+		return
+	}
+	tenantID := requestTenant(r).ID
+	sub, missed := h.events.Subscribe(tenantID, r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx and similar proxies from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	send := func(write func(io.Writer) error) bool {
+		_ = controller.SetWriteDeadline(time.Now().Add(eventWriteWindow))
+		return write(w) == nil && controller.Flush() == nil
+	}
+	if !send(func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+		return err
+	}) {
+		return
+	}
+	for _, event := range missed {
+		if !send(eventWriter(event)) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(h.eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			// A closed subscription fell behind; ending the stream makes
+			// the client reconnect and catch up from the buffer.
+			if !ok || !send(eventWriter(event)) {
+				return
+			}
+		case <-keepAlive.C:
+			if !h.streamAuthorized(r, tenantID) || !send(func(w io.Writer) error {
+				_, err := io.WriteString(w, ": keep-alive\n\n")
+				return err
+			}) {
+				return
+			}
+		}
+	}
+}
+
+// streamAuthorized authenticates the request that opened a stream again, and
+// reports whether it still reaches tenantID.
+func (h *Handlers) streamAuthorized(r *http.Request, tenantID string) bool {
+	principal, ok := h.authenticateRequest(discardResponseWriter{header: http.Header{}}, r)
+	return ok && principal.TenantID == tenantID
+}
+
+// discardResponseWriter swallows the error response of a failed
+// re-authentication, which the stream has no way to send.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (d discardResponseWriter) Header() http.Header       { return d.header }
+func (discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (discardResponseWriter) WriteHeader(int)             {}
+
+func eventWriter(event events.Event) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+		return err
+	}
+}
@@ -0,0 +1,166 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/events"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+// readEvent reads one server-sent event block, without its trailing blank
+// line.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v (read %q)", err, lines)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStreamEventsResumesAndStreamsTenantEvents(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	broker := events.NewBroker(16, nil)
+	h.events = broker
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.WithPrincipal(r.Context(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+		h.StreamEvents(w, r.WithContext(ctx))
+	}))
+	defer server.Close()
+
+	seen, _ := broker.Subscribe("tenant-a", "")
+	broker.Publish("tenant-a", events.TypeScanState, events.ScanState{Reader: "gmail", State: "starting"})
+	last := <-seen.Events()
+	seen.Close()
+	broker.Publish("tenant-b", events.TypeScanState, events.ScanState{Reader: "gmail", State: "running"})
+	broker.Publish("tenant-a", events.TypeScanState, events.ScanState{Reader: "gmail", State: "running"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", last.ID)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	body := bufio.NewReader(resp.Body)
+
+	if got := readEvent(t, body); got != "retry: 3000" {
+		t.Fatalf("first block = %q, want the retry interval", got)
+	}
+	missed := readEvent(t, body)
+	if !strings.Contains(missed, "event: scan.state\n") || !strings.HasSuffix(missed, `data: {"reader":"gmail","state":"running"}`) {
+		t.Fatalf("missed event = %q", missed)
+	}
+
+	broker.Publish("tenant-b", events.TypeDiagnosticCreated, events.Diagnostic{ID: "other-tenant"})
+	broker.Publish("tenant-a", events.TypeTransactionBatch, events.TransactionBatch{Created: []string{"txn-a"}})
+	live := readEvent(t, body)
+	if !strings.Contains(live, "event: transactions.batch\n") || !strings.Contains(live, `"created":["txn-a"]`) {
+		t.Fatalf("live event = %q", live)
+	}
+}
+
+// revocableAuthStore forgets every session once revoked, safely while a
+// stream authenticates again from another goroutine.
+type revocableAuthStore struct {
+	authStore
+	mu      sync.Mutex
+	revoked bool
+}
+
+func (s *revocableAuthStore) FindSessionByHash(ctx context.Context, tokenHash string) (*store.Session, error) {
+	s.mu.Lock()
+	revoked := s.revoked
+	s.mu.Unlock()
+	if revoked {
+		return nil, mockStoreErr("store.auth.find_session_by_hash", errStoreNotFound)
+	}
+	return s.authStore.FindSessionByHash(ctx, tokenHash)
+}
+
+func (s *revocableAuthStore) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked = true
+}
+
+func TestStreamEventsEndsWhenSessionIsRevoked(t *testing.T) {
+	raw, hash, err := auth.NewOpaqueToken(sessionTokenPrefix)
+	if err != nil {
+		t.Fatalf("NewOpaqueToken() error = %v", err)
+	}
+	ms := &mockStore{
+		sessionsByHash: map[string]*store.Session{
+			hash: {ID: "session-a", UserID: "user-a", TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour), MFA: store.SessionMFAComplete},
+		},
+		usersByID: map[string]*store.User{"user-a": {ID: "user-a", TenantID: "tenant-a", Role: store.UserRoleUser}},
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.events = events.NewBroker(16, nil)
+	h.eventKeepAlive = 10 * time.Millisecond
+	sessions := &revocableAuthStore{authStore: h.authStore}
+	h.authStore = sessions
+	mux := http.NewServeMux()
+	registerRoutes(mux, h)
+	server := httptest.NewServer(authMiddleware(h, mux))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: raw})
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/events: %v", err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+	readEvent(t, body)
+	if got := readEvent(t, body); got != ": keep-alive" {
+		t.Fatalf("block = %q, want a keep-alive while the session is valid", got)
+	}
+
+	sessions.revoke()
+
+	if _, err := io.ReadAll(body); err != nil {
+		t.Fatalf("stream still open after the session was revoked: %v", err)
+	}
+}
+
+func TestStreamEventsUnavailableWithoutBroker(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.StreamEvents(rec, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503; body = %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,245 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/export"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// exportPageSize is how many transactions an export reads per query.
+	exportPageSize = 500
+	// exportWriteWindow is how long each page may take to reach the client;
+	// the deadline moves forward with every page so long exports are not cut
+	// off by the server-wide write timeout.
+	exportWriteWindow     = 2 * time.Minute
+	exportAccountsKey     = "export.accounts"
+	maxExportAccountsSize = 1 << 20
+)
+
+// ExportTransactions handles GET /api/transactions/export.
+// Accepts every filter and search parameter of GET /api/transactions;
+// pagination parameters are ignored and results default to oldest first.
+// @Summary Export transactions as CSV, XLSX, OFX or an accounting journal
+// @Tags Transactions
+// @Produce octet-stream
+// @Param format query string true "Export format" Enums(csv,xlsx,ofx,ledger,hledger,beancount)
+// @Param merchant query string false "Merchant filter"
+// @Param category query string false "Category filter"
+// @Param category_missing query int false "Only transactions without a category when set to 1" Enums(1)
+// @Param exclude_categories query string false "Comma-separated categories to exclude"
+// @Param currency query string false "Currency filter"
+// @Param source query string false "Source filter"
+// @Param exclude_sources query string false "Comma-separated sources to exclude"
+// @Param source_type query string false "Source type filter"
+// @Param exclude_source_types query string false "Comma-separated source types to exclude"
+// @Param bank query string false "Bank filter"
+// @Param exclude_banks query string false "Comma-separated banks to exclude"
+// @Param label query string false "Label filter"
+// @Param label_missing query int false "Only transactions without labels when set to 1" Enums(1)
+// @Param exclude_labels query string false "Comma-separated labels to exclude"
+// @Param bucket query string false "Bucket filter"
+// @Param bucket_missing query int false "Only transactions without a bucket when set to 1" Enums(1)
+// @Param exclude_buckets query string false "Comma-separated buckets to exclude"
+// @Param date_from query string false "RFC3339 start timestamp"
+// @Param date_to query string false "RFC3339 end timestamp"
+// @Param show_muted query int false "Include muted transactions when set to 1" Enums(1)
+// @Param muted_only query int false "Return only muted transactions when set to 1" Enums(1)
+// @Param individual_only query int false "Return only individually muted transactions when set to 1" Enums(1)
+// @Param weekday query int false "PostgreSQL DOW weekday filter (0=Sunday...6=Saturday)" Enums(0,1,2,3,4,5,6)
+// @Param hour_from query int false "Minimum hour filter (0-23)" minimum(0) maximum(23)
+// @Param hour_to query int false "Maximum hour filter (0-23)" minimum(0) maximum(23)
+// @Param tz query string false "IANA timezone used for filters and exported dates"
+// @Param q query string false "Search text; supports merchant:, description:, subject:, body:, label: and amount:>500 terms"
+// @Param sort_dir query string false "Sort direction; defaults to asc" Enums(asc,desc)
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/export [get]
+func (h *Handlers) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	exportQuery, ok := decodeAndValidateQuery[transactionExportQuery](h, w, r)
+	if !ok {
+		return
+	}
+	query, ok := decodeAndValidateQuery[transactionListQuery](h, w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	tenant := requestTenant(r)
+	format := export.Format(exportQuery.Format)
+
+	timezone := h.resolveTimezone(ctx, tenant, query.Timezone)
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	filter := query.listFilter(timezone)
+	filter.Page = 1
+	filter.PageSize = exportPageSize
+	if filter.SortDir == "" {
+		filter.SortDir = "asc"
+	}
+	search := strings.TrimSpace(query.Query)
+
+	accounts, err := h.exportAccounts(ctx, tenant)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// The first page is read before any output so that filter and store
+	// errors still produce a normal error response.
+	txns, err := h.exportPage(ctx, tenant, search, filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	filename := "expensor-transactions-" + now.In(location).Format("20060102") + "." + format.Extension()
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	writer, err := export.NewWriter(format, w, export.Options{
+		Accounts:     accounts,
+		BaseCurrency: h.currentBaseCurrency(ctx, tenant),
+		PeriodStart:  query.DateFrom,
+		PeriodEnd:    query.DateTo,
+		Location:     location,
+		Now:          func() time.Time { return now },
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	for {
+		_ = controller.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+		for _, txn := range txns {
+			if err := writer.Write(txn); err != nil {
+				h.abortExport(ctx, "writing transaction export", err)
+			}
+		}
+		if len(txns) < filter.PageSize {
+			break
+		}
+		filter.Page++
+		if txns, err = h.exportPage(ctx, tenant, search, filter); err != nil {
+			h.abortExport(ctx, "reading transactions for export", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		h.abortExport(ctx, "finishing transaction export", err)
+	}
+}
+
+func (h *Handlers) exportPage(ctx context.Context, tenant store.Tenant, search string, filter store.ListFilter) ([]store.Transaction, error) {
+	var (
+		txns []store.Transaction
+		err  error
+	)
+	if search == "" {
+		txns, _, err = h.transactionStore.ListTransactions(ctx, tenant, filter)
+	} else {
+		txns, _, err = h.transactionStore.SearchTransactions(ctx, tenant, search, filter)
+	}
+	return txns, err
+}
+
+// abortExport stops an export whose headers are already sent. Aborting drops
+// the connection, so the client sees a failed download rather than a file
+// that silently ends early.
+func (h *Handlers) abortExport(ctx context.Context, msg string, err error) {
+	h.logger.ErrorContext(ctx, msg, "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+// GetExportAccounts handles GET /api/config/export-accounts.
+// @Summary Get the journal account mapping used by transaction exports
+// @Tags Config
+// @Produce json
+// @Success 200 {object} ExportAccountsResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /config/export-accounts [get]
+func (h *Handlers) GetExportAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.exportAccounts(r.Context(), requestTenant(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// PutExportAccounts handles PUT /api/config/export-accounts.
+// @Summary Replace the journal account mapping used by transaction exports
+// @Tags Config
+// @Accept json
+// @Produce json
+// @Param request body ExportAccountsRequest true "Accounts per source and category"
+// @Success 200 {object} ExportAccountsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /config/export-accounts [put]
+func (h *Handlers) PutExportAccounts(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxExportAccountsSize)
+	accounts, ok := decodeJSONRequest[export.Accounts](w, r)
+	if !ok {
+		return
+	}
+	accounts = normalizeExportAccounts(accounts)
+	if err := accounts.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	encoded, err := json.Marshal(accounts)
+	if err != nil {
+		writeError(w, r, errors.E(errors.Internal, "encoding export accounts", err))
+		return
+	}
+	if err := h.settingsStore.SetAppConfig(r.Context(), requestTenant(r), exportAccountsKey, string(encoded)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+func (h *Handlers) exportAccounts(ctx context.Context, tenant store.Tenant) (export.Accounts, error) {
+	var accounts export.Accounts
+	// Like other stored preferences, an unreadable key means "not configured".
+	raw, err := h.settingsStore.GetAppConfig(ctx, tenant, exportAccountsKey)
+	if err != nil || raw == "" {
+		return normalizeExportAccounts(accounts), nil
+	}
+	if err := json.Unmarshal([]byte(raw), &accounts); err != nil {
+		return export.Accounts{}, errors.E("httpapi.export_accounts", errors.Internal, "decoding stored export accounts", err)
+	}
+	return normalizeExportAccounts(accounts), nil
+}
+
+func normalizeExportAccounts(accounts export.Accounts) export.Accounts {
+	trim := func(table map[string]string) map[string]string {
+		out := make(map[string]string, len(table))
+		for key, account := range table {
+			out[strings.TrimSpace(key)] = strings.TrimSpace(account)
+		}
+		return out
+	}
+	accounts.Sources = trim(accounts.Sources)
+	accounts.Categories = trim(accounts.Categories)
+	accounts.DefaultSource = strings.TrimSpace(accounts.DefaultSource)
+	accounts.DefaultExpense = strings.TrimSpace(accounts.DefaultExpense)
+	return accounts
+}
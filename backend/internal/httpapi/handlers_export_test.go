@@ -0,0 +1,169 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/api"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func exportTestTransactions() []store.Transaction {
+	return []store.Transaction{
+		{
+			ID:           "11111111-1111-1111-1111-111111111111",
+			MerchantInfo: "Swiggy",
+			Amount:       450.5,
+			Currency:     "INR",
+			Category:     "Food & Dining",
+			Timestamp:    time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+			Source:       api.Source{Bank: "HDFC", Type: "credit-card", Label: "Regalia"},
+		},
+	}
+}
+
+func TestExportTransactions_StreamsCSVWithFilters(t *testing.T) {
+	st := &mockStore{transactions: exportTestTransactions()}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/api/transactions/export?format=csv&category=Food+%26+Dining&page=7&page_size=5", nil)
+
+	h.ExportTransactions(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment; filename=expensor-transactions-") ||
+		!strings.HasSuffix(got, ".csv") {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+	if st.listFilter.Category != "Food & Dining" {
+		t.Fatalf("category filter = %q", st.listFilter.Category)
+	}
+	if st.listFilter.Page != 1 || st.listFilter.PageSize != exportPageSize || st.listFilter.SortDir != "asc" {
+		t.Fatalf("export should read full pages oldest first, got %#v", st.listFilter)
+	}
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing CSV: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "11111111-1111-1111-1111-111111111111" || records[1][2] != "Swiggy" {
+		t.Fatalf("records = %v", records)
+	}
+}
+
+func TestExportTransactions_UsesSearchAndAccountMapping(t *testing.T) {
+	st := &mockStore{
+		searchResult: exportTestTransactions(),
+		appConfig: map[string]string{
+			exportAccountsKey: `{"sources":{"hdfc credit-card regalia":"Liabilities:HDFC:Regalia"},"categories":{"Food & Dining":"Expenses:Food"}}`,
+		},
+	}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/export?format=hledger&q=swiggy", nil)
+
+	h.ExportTransactions(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.searchCalls != 1 || st.listCalls != 0 {
+		t.Fatalf("expected one search and no list calls, got search=%d list=%d", st.searchCalls, st.listCalls)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"2026-03-04 * Swiggy", "Expenses:Food  450.50 INR", "    Liabilities:HDFC:Regalia\n"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("journal missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestExportTransactions_RejectsMissingOrUnknownFormat(t *testing.T) {
+	for _, target := range []string{"/api/transactions/export", "/api/transactions/export?format=qif"} {
+		st := &mockStore{}
+		h := newTestHandlers(t, st, &mockDaemon{})
+		rr := httptest.NewRecorder()
+
+		h.ExportTransactions(rr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, target, nil))
+
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: expected 422, got %d (body=%s)", target, rr.Code, rr.Body.String())
+		}
+		if st.listCalls != 0 {
+			t.Fatalf("%s: store should not be queried", target)
+		}
+	}
+}
+
+func TestExportTransactions_StoreErrorBeforeOutput(t *testing.T) {
+	st := &mockStore{listErr: errors.E(errors.Unavailable, "database down")}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.ExportTransactions(rr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/export?format=ofx", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("error response Content-Type = %q", got)
+	}
+}
+
+func TestGetExportAccounts_DefaultsToEmptyMapping(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.GetExportAccounts(rr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/config/export-accounts", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if got := strings.TrimSpace(rr.Body.String()); got != `{"sources":{},"categories":{}}` {
+		t.Fatalf("body = %s", got)
+	}
+}
+
+func TestPutExportAccounts_StoresNormalizedMapping(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+	body := `{"sources":{" HDFC credit-card Regalia ":" Liabilities:HDFC:Regalia "},"default_expense":"Expenses:Misc"}`
+
+	h.PutExportAccounts(rr, httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/api/config/export-accounts", strings.NewReader(body)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	want := `{"sources":{"HDFC credit-card Regalia":"Liabilities:HDFC:Regalia"},"categories":{},"default_expense":"Expenses:Misc"}`
+	if got := st.appConfig[exportAccountsKey]; got != want {
+		t.Fatalf("stored = %s, want %s", got, want)
+	}
+}
+
+func TestPutExportAccounts_RejectsInvalidAccount(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+	body := `{"categories":{"Food":"Expenses:;Food"}}`
+
+	h.PutExportAccounts(rr, httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/api/config/export-accounts", strings.NewReader(body)))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if _, ok := st.appConfig[exportAccountsKey]; ok {
+		t.Fatal("invalid mapping should not be stored")
+	}
+}
@@ -88,7 +88,7 @@ func (h *Handlers) GetLLMProviderStatus(w http.ResponseWriter, r *http.Request)
 	if !ok {
 		return
 	}
-	status, err := h.llmProviderStatus(r.Context(), requestTenant(r), provider.Metadata.Name)
+	status, err := h.llmProviderStatus(r.Context(), requestTenant(r), provider)
 	if err != nil {
 		writeError(w, r, err)
 		return
@@ -123,7 +123,9 @@ func (h *Handlers) SaveLLMProviderConfig(w http.ResponseWriter, r *http.Request)
 		writeError(w, r, errors.E(errors.InvalidArgument, errors.User("invalid provider config JSON")))
 		return
 	}
-	if err := h.llmRuntimeStore.SetLLMProviderConfig(r.Context(), requestTenant(r), provider.Metadata.Name, body.Config); err != nil {
+	err := h.llmRuntimeStore.SetLLMProviderConfig(r.Context(), requestTenant(r), provider.Metadata.Name, body.Config)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditLLMConfig, TargetType: "llm_provider", TargetID: provider.Metadata.Name}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -139,6 +141,7 @@ func (h *Handlers) SaveLLMProviderConfig(w http.ResponseWriter, r *http.Request)
 // @Param name path string true "Provider name" example(openai)
 // @Param request body LLMProviderCredentialsRequest true "Provider credentials"
 // @Success 200 {object} StatusOnlyResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -148,6 +151,10 @@ func (h *Handlers) SaveLLMProviderCredentials(w http.ResponseWriter, r *http.Req
 	if !ok {
 		return
 	}
+	if provider.Metadata.Auth.Type == llm.AuthTypeNone {
+		writeError(w, r, errors.E(errors.InvalidArgument, errors.User("This LLM provider does not use credentials.")))
+		return
+	}
 	body, ok := decodeAndValidateJSON[llmProviderCredentialsRequest](h, w, r)
 	if !ok {
 		return
@@ -157,7 +164,9 @@ func (h *Handlers) SaveLLMProviderCredentials(w http.ResponseWriter, r *http.Req
 		writeError(w, r, err)
 		return
 	}
-	if err := h.llmRuntimeStore.SetLLMProviderCredentials(r.Context(), requestTenant(r), provider.Metadata.Name, credentials); err != nil {
+	err = h.llmRuntimeStore.SetLLMProviderCredentials(r.Context(), requestTenant(r), provider.Metadata.Name, credentials)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditLLMCredentials, TargetType: "llm_provider", TargetID: provider.Metadata.Name}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -195,6 +204,44 @@ func (h *Handlers) HealthCheckLLMProvider(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// ListLLMProviderModels handles GET /api/llm/providers/{name}/models.
+//
+// @Summary Discover models available from an LLM provider
+// @Tags LLM
+// @Produce json
+// @Param name path string true "Provider name" example(ollama)
+// @Success 200 {array} LLMProviderModelOption
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /llm/providers/{name}/models [get]
+func (h *Handlers) ListLLMProviderModels(w http.ResponseWriter, r *http.Request) {
+	_, client, ok := h.llmProviderClientFromRuntime(w, r)
+	if !ok {
+		return
+	}
+	lister, ok := client.(llm.ModelLister)
+	if !ok {
+		writeError(w, r, errors.E(errors.Unimplemented, errors.User("This LLM provider does not support model discovery.")))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if models == nil {
+		models = []llm.ModelOption{}
+	}
+	writeJSON(w, http.StatusOK, models)
+}
+
 // ActivateLLMProvider handles POST /api/llm/providers/{name}/activate.
 //
 // @Summary Activate an LLM provider
@@ -215,11 +262,12 @@ func (h *Handlers) ActivateLLMProvider(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), 75*time.Second)
 	defer cancel()
-	if err := client.HealthCheck(ctx); err != nil {
-		writeError(w, r, err)
-		return
+	err := client.HealthCheck(ctx)
+	if err == nil {
+		err = h.llmRuntimeStore.SetActiveLLMProvider(r.Context(), requestTenant(r), provider.Metadata.Name)
 	}
-	if err := h.llmRuntimeStore.SetActiveLLMProvider(r.Context(), requestTenant(r), provider.Metadata.Name); err != nil {
+	h.audit(r, store.NewAuditEvent{Action: store.AuditLLMActivate, TargetType: "llm_provider", TargetID: provider.Metadata.Name}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -240,7 +288,9 @@ func (h *Handlers) DisconnectLLMProvider(w http.ResponseWriter, r *http.Request)
 	if !ok {
 		return
 	}
-	if err := h.llmRuntimeStore.DeleteLLMProviderRuntime(r.Context(), requestTenant(r), provider.Metadata.Name); err != nil {
+	err := h.llmRuntimeStore.DeleteLLMProviderRuntime(r.Context(), requestTenant(r), provider.Metadata.Name)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditLLMDisconnect, TargetType: "llm_provider", TargetID: provider.Metadata.Name}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -261,7 +311,8 @@ func (h *Handlers) llmProviderByPath(w http.ResponseWriter, r *http.Request) (ll
 	return provider, true
 }
 
-func (h *Handlers) llmProviderStatus(ctx context.Context, tenant store.Tenant, provider string) (llmProviderStatusJSON, error) {
+func (h *Handlers) llmProviderStatus(ctx context.Context, tenant store.Tenant, llmProvider llm.Provider) (llmProviderStatusJSON, error) {
+	provider := llmProvider.Metadata.Name
 	config, configPresent, err := h.llmRuntimeStore.GetLLMProviderConfig(ctx, tenant, provider)
 	if err != nil {
 		return llmProviderStatusJSON{}, err
@@ -284,7 +335,7 @@ func (h *Handlers) llmProviderStatus(ctx context.Context, tenant store.Tenant, p
 		ConfigPresent:     configPresent,
 		CredentialsStored: credentialsStored,
 		Active:            active,
-		Ready:             active && credentialsStored,
+		Ready:             active && (credentialsStored || !llmProvider.Metadata.Auth.Required),
 	}, nil
 }
 
@@ -304,7 +355,7 @@ func (h *Handlers) llmProviderClientFromRuntime(w http.ResponseWriter, r *http.R
 		writeError(w, r, err)
 		return llm.Provider{}, nil, false
 	}
-	if !found {
+	if !found && provider.Metadata.Auth.Required {
 		writeError(w, r, errors.E(errors.Conflict, errors.User("LLM provider credentials are not configured")))
 		return llm.Provider{}, nil, false
 	}
@@ -0,0 +1,296 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	promptSourceEmbedded = "embedded"
+	promptSourceOverride = "override"
+)
+
+// ListAdminLLMPrompts handles GET /api/admin/llm/prompts.
+// @Summary List catalog prompts and their active versions
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} AdminLLMPromptSummaryResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/llm/prompts [get]
+func (h *Handlers) ListAdminLLMPrompts(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	embedded := h.embeddedPrompts().List()
+	out := make([]AdminLLMPromptSummaryResponse, 0, len(embedded))
+	for _, prompt := range embedded {
+		summary := AdminLLMPromptSummaryResponse{
+			Workflow:        prompt.Workflow,
+			Purpose:         prompt.Purpose,
+			EmbeddedVersion: prompt.Version,
+			ActiveVersion:   prompt.Version,
+			ActiveSource:    promptSourceEmbedded,
+		}
+		active, found, err := h.llmPromptStore.GetActiveLLMPromptVersion(r.Context(), prompt.Workflow, prompt.Purpose)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if found {
+			summary.ActiveVersion = active.Version
+			summary.ActiveSource = promptSourceOverride
+		}
+		out = append(out, summary)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// ListAdminLLMPromptVersions handles GET /api/admin/llm/prompts/{workflow}/{purpose}/versions.
+// @Summary List the version history of a prompt
+// @Tags Admin
+// @Produce json
+// @Param workflow path string true "Prompt workflow" example(rule_drafting)
+// @Param purpose path string true "Prompt purpose" example(draft_rule)
+// @Success 200 {object} AdminLLMPromptVersionsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/llm/prompts/{workflow}/{purpose}/versions [get]
+func (h *Handlers) ListAdminLLMPromptVersions(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	embedded, ok := h.embeddedPromptFromPath(w, r)
+	if !ok {
+		return
+	}
+	h.writeAdminLLMPromptVersions(w, r, embedded, http.StatusOK)
+}
+
+// CreateAdminLLMPromptVersion handles POST /api/admin/llm/prompts/{workflow}/{purpose}/versions.
+// @Summary Create a prompt override version
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param workflow path string true "Prompt workflow" example(rule_drafting)
+// @Param purpose path string true "Prompt purpose" example(draft_rule)
+// @Param request body AdminLLMPromptVersionRequest true "Prompt definition"
+// @Success 201 {object} AdminLLMPromptVersionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/llm/prompts/{workflow}/{purpose}/versions [post]
+func (h *Handlers) CreateAdminLLMPromptVersion(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	embedded, ok := h.embeddedPromptFromPath(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[AdminLLMPromptVersionRequest](h, w, r)
+	if !ok {
+		return
+	}
+	version, err := h.nextPromptVersion(r.Context(), embedded)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	prompt := promptDefinitionFromRequest(embedded, version, body.Prompt)
+	definition, err := json.Marshal(prompt)
+	if err != nil {
+		writeError(w, r, errors.E(errors.Internal, "encoding prompt definition", err))
+		return
+	}
+	if _, err := llm.ParsePromptDefinition(definition, prompt.ID); err != nil {
+		writeError(w, r, errors.E(errors.InvalidInput, errors.User("prompt definition is invalid"), err))
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if _, err := h.llmPromptStore.CreateLLMPromptVersion(r.Context(), store.CreateLLMPromptVersionInput{
+		Workflow:   embedded.Workflow,
+		Purpose:    embedded.Purpose,
+		Version:    version,
+		Definition: definition,
+		CreatedBy:  principal.UserID,
+		Activate:   body.Activate,
+	}); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeAdminLLMPromptVersions(w, r, embedded, http.StatusCreated)
+}
+
+// ActivateAdminLLMPromptVersion handles PUT /api/admin/llm/prompts/{workflow}/{purpose}/active.
+// Activating the embedded version removes any active override.
+// @Summary Switch the active version of a prompt
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param workflow path string true "Prompt workflow" example(rule_drafting)
+// @Param purpose path string true "Prompt purpose" example(draft_rule)
+// @Param request body AdminLLMPromptActivateRequest true "Version to activate"
+// @Success 200 {object} AdminLLMPromptVersionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/llm/prompts/{workflow}/{purpose}/active [put]
+func (h *Handlers) ActivateAdminLLMPromptVersion(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	embedded, ok := h.embeddedPromptFromPath(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[AdminLLMPromptActivateRequest](h, w, r)
+	if !ok {
+		return
+	}
+	var err error
+	if body.Version == embedded.Version {
+		err = h.llmPromptStore.DeactivateLLMPromptVersions(r.Context(), embedded.Workflow, embedded.Purpose)
+	} else {
+		_, err = h.llmPromptStore.ActivateLLMPromptVersion(r.Context(), embedded.Workflow, embedded.Purpose, body.Version)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeAdminLLMPromptVersions(w, r, embedded, http.StatusOK)
+}
+
+func (h *Handlers) embeddedPrompts() *llm.PromptCatalog {
+	if h.llmRouter == nil {
+		return nil
+	}
+	return h.llmRouter.PromptCatalog()
+}
+
+func (h *Handlers) embeddedPromptFromPath(w http.ResponseWriter, r *http.Request) (llm.PromptDefinition, bool) {
+	workflow := r.PathValue("workflow")
+	purpose := r.PathValue("purpose")
+	prompt, ok := h.embeddedPrompts().Get(workflow, purpose)
+	if !ok {
+		writeError(w, r, errors.E(errors.NotFound, errors.User("prompt "+workflow+"/"+purpose+" not found")))
+		return llm.PromptDefinition{}, false
+	}
+	return prompt, true
+}
+
+// nextPromptVersion numbers overrides after both the embedded prompt and any
+// stored versions, so versions stay comparable across rebuilds.
+func (h *Handlers) nextPromptVersion(ctx context.Context, embedded llm.PromptDefinition) (int, error) {
+	versions, err := h.llmPromptStore.ListLLMPromptVersions(ctx, embedded.Workflow, embedded.Purpose)
+	if err != nil {
+		return 0, err
+	}
+	latest := embedded.Version
+	for _, version := range versions {
+		latest = max(latest, version.Version)
+	}
+	return latest + 1, nil
+}
+
+func (h *Handlers) writeAdminLLMPromptVersions(w http.ResponseWriter, r *http.Request, embedded llm.PromptDefinition, status int) {
+	stored, err := h.llmPromptStore.ListLLMPromptVersions(r.Context(), embedded.Workflow, embedded.Purpose)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := AdminLLMPromptVersionsResponse{
+		Workflow: embedded.Workflow,
+		Purpose:  embedded.Purpose,
+		Versions: make([]AdminLLMPromptVersionResponse, 0, len(stored)+1),
+	}
+	overridden := false
+	for _, version := range stored {
+		prompt, err := llm.ParsePromptDefinition(version.Definition, embedded.ID)
+		if err != nil {
+			writeError(w, r, errors.E(errors.Internal, err))
+			return
+		}
+		createdAt := version.CreatedAt
+		resp.Versions = append(resp.Versions, AdminLLMPromptVersionResponse{
+			Version:     version.Version,
+			Source:      promptSourceOverride,
+			Active:      version.Active,
+			CreatedBy:   version.CreatedBy,
+			CreatedAt:   &createdAt,
+			ActivatedAt: version.ActivatedAt,
+			Prompt:      adminLLMPromptDefinition(prompt),
+		})
+		overridden = overridden || version.Active
+	}
+	resp.Versions = append(resp.Versions, AdminLLMPromptVersionResponse{
+		Version: embedded.Version,
+		Source:  promptSourceEmbedded,
+		Active:  !overridden,
+		Prompt:  adminLLMPromptDefinition(embedded),
+	})
+	writeJSON(w, status, resp)
+}
+
+func promptDefinitionFromRequest(embedded llm.PromptDefinition, version int, req AdminLLMPromptDefinition) llm.PromptDefinition {
+	prompt := llm.PromptDefinition{
+		ID:           strings.TrimSpace(req.ID),
+		Version:      version,
+		Workflow:     embedded.Workflow,
+		Purpose:      embedded.Purpose,
+		Description:  strings.TrimSpace(req.Description),
+		Messages:     make([]llm.Message, 0, len(req.Messages)),
+		ResultLimits: llm.ResultLimits{MaxBytes: req.MaxResultBytes, MaxItems: req.MaxResultItems},
+	}
+	for _, capability := range req.RequiredCapabilities {
+		prompt.RequiredCapabilities = append(prompt.RequiredCapabilities, llm.Capability(capability))
+	}
+	for _, variable := range req.Variables {
+		prompt.Variables = append(prompt.Variables, llm.PromptVariable{
+			Name:        strings.TrimSpace(variable.Name),
+			Description: strings.TrimSpace(variable.Description),
+			Required:    variable.Required,
+		})
+	}
+	for _, message := range req.Messages {
+		prompt.Messages = append(prompt.Messages, llm.Message{Role: llm.Role(message.Role), Content: message.Content})
+	}
+	return prompt
+}
+
+func adminLLMPromptDefinition(prompt llm.PromptDefinition) AdminLLMPromptDefinition {
+	out := AdminLLMPromptDefinition{
+		ID:             prompt.ID,
+		Description:    prompt.Description,
+		Messages:       make([]AdminLLMPromptMessage, 0, len(prompt.Messages)),
+		MaxResultBytes: prompt.ResultLimits.MaxBytes,
+		MaxResultItems: prompt.ResultLimits.MaxItems,
+	}
+	for _, capability := range prompt.RequiredCapabilities {
+		out.RequiredCapabilities = append(out.RequiredCapabilities, string(capability))
+	}
+	for _, variable := range prompt.Variables {
+		out.Variables = append(out.Variables, AdminLLMPromptVariable(variable))
+	}
+	for _, message := range prompt.Messages {
+		out.Messages = append(out.Messages, AdminLLMPromptMessage{Role: string(message.Role), Content: message.Content})
+	}
+	return out
+}
@@ -0,0 +1,193 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+)
+
+func newPromptTestHandlers(t *testing.T, ms *mockStore) *Handlers {
+	t.Helper()
+	catalog, err := llm.NewPromptCatalog(llm.PromptDefinition{
+		ID:       "rule_draft",
+		Version:  1,
+		Workflow: "rule_drafting",
+		Purpose:  "draft_rule",
+		Messages: []llm.Message{{Role: llm.RoleSystem, Content: "Draft rule JSON."}},
+	})
+	if err != nil {
+		t.Fatalf("NewPromptCatalog() error = %v", err)
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.llmRouter = llm.NewRouter(llm.RouterConfig{Prompts: catalog, PromptOverrides: ms})
+	return h
+}
+
+func promptRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequestWithContext(adminContext(), method, target, strings.NewReader(body))
+	req.SetPathValue("workflow", "rule_drafting")
+	req.SetPathValue("purpose", "draft_rule")
+	return req
+}
+
+const promptVersionBody = `{
+	"prompt": {
+		"id": "rule_draft",
+		"required_capabilities": ["json_schema"],
+		"messages": [
+			{"role": "system", "content": "Draft a stricter rule."},
+			{"role": "user", "content": "{{rule_context_json}}"}
+		]
+	},
+	"activate": %s
+}`
+
+func TestCreateAdminLLMPromptVersionStoresNextVersion(t *testing.T) {
+	ms := &mockStore{}
+	h := newPromptTestHandlers(t, ms)
+	rec := httptest.NewRecorder()
+
+	h.CreateAdminLLMPromptVersion(rec, promptRequest(
+		http.MethodPost,
+		"/api/admin/llm/prompts/rule_drafting/draft_rule/versions",
+		strings.Replace(promptVersionBody, "%s", "true", 1),
+	))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+	if len(ms.llmPromptVersions) != 1 {
+		t.Fatalf("stored versions = %d, want 1", len(ms.llmPromptVersions))
+	}
+	stored := ms.llmPromptVersions[0]
+	if stored.Version != 2 || !stored.Active || stored.CreatedBy != "admin" {
+		t.Fatalf("stored version = %+v, want active version 2 by admin", stored)
+	}
+	var resp AdminLLMPromptVersionsResponse
+	decodeJSON(t, rec.Body.String(), &resp)
+	if len(resp.Versions) != 2 {
+		t.Fatalf("versions = %+v, want override and embedded", resp.Versions)
+	}
+	if resp.Versions[0].Source != promptSourceOverride || !resp.Versions[0].Active || resp.Versions[0].Prompt.Messages[0].Content != "Draft a stricter rule." {
+		t.Fatalf("override = %+v, want active override", resp.Versions[0])
+	}
+	if resp.Versions[1].Source != promptSourceEmbedded || resp.Versions[1].Active {
+		t.Fatalf("embedded = %+v, want inactive embedded prompt", resp.Versions[1])
+	}
+
+	prompt, ok, err := h.llmRouter.Prompt(context.Background(), "rule_drafting", "draft_rule")
+	if err != nil || !ok {
+		t.Fatalf("Prompt() = %v, %v", ok, err)
+	}
+	if prompt.Version != 2 || prompt.Messages[0].Content != "Draft a stricter rule." {
+		t.Fatalf("router prompt = %+v, want active override", prompt)
+	}
+}
+
+func TestActivateAdminLLMPromptVersionSwitchesBetweenVersions(t *testing.T) {
+	ms := &mockStore{}
+	h := newPromptTestHandlers(t, ms)
+	h.CreateAdminLLMPromptVersion(httptest.NewRecorder(), promptRequest(
+		http.MethodPost,
+		"/api/admin/llm/prompts/rule_drafting/draft_rule/versions",
+		strings.Replace(promptVersionBody, "%s", "false", 1),
+	))
+
+	rec := httptest.NewRecorder()
+	h.ActivateAdminLLMPromptVersion(rec, promptRequest(http.MethodPut, "/api/admin/llm/prompts/rule_drafting/draft_rule/active", `{"version":2}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("activate status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if prompt, _, _ := h.llmRouter.Prompt(context.Background(), "rule_drafting", "draft_rule"); prompt.Version != 2 {
+		t.Fatalf("router prompt version = %d, want 2", prompt.Version)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ActivateAdminLLMPromptVersion(rec, promptRequest(http.MethodPut, "/api/admin/llm/prompts/rule_drafting/draft_rule/active", `{"version":1}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revert status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if prompt, _, _ := h.llmRouter.Prompt(context.Background(), "rule_drafting", "draft_rule"); prompt.Version != 1 {
+		t.Fatalf("router prompt version = %d, want embedded 1", prompt.Version)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ActivateAdminLLMPromptVersion(rec, promptRequest(http.MethodPut, "/api/admin/llm/prompts/rule_drafting/draft_rule/active", `{"version":7}`))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing version status = %d, want 404; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListAdminLLMPromptsReportsActiveSource(t *testing.T) {
+	ms := &mockStore{}
+	h := newPromptTestHandlers(t, ms)
+	h.CreateAdminLLMPromptVersion(httptest.NewRecorder(), promptRequest(
+		http.MethodPost,
+		"/api/admin/llm/prompts/rule_drafting/draft_rule/versions",
+		strings.Replace(promptVersionBody, "%s", "true", 1),
+	))
+	rec := httptest.NewRecorder()
+
+	h.ListAdminLLMPrompts(rec, promptRequest(http.MethodGet, "/api/admin/llm/prompts", ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp []AdminLLMPromptSummaryResponse
+	decodeJSON(t, rec.Body.String(), &resp)
+	if len(resp) != 1 || resp[0].EmbeddedVersion != 1 || resp[0].ActiveVersion != 2 || resp[0].ActiveSource != promptSourceOverride {
+		t.Fatalf("prompts = %+v, want override v2 over embedded v1", resp)
+	}
+}
+
+func TestCreateAdminLLMPromptVersionRejectsInvalidRequests(t *testing.T) {
+	t.Run("unknown prompt", func(t *testing.T) {
+		h := newPromptTestHandlers(t, &mockStore{})
+		req := promptRequest(http.MethodPost, "/api/admin/llm/prompts/unknown/draft_rule/versions", strings.Replace(promptVersionBody, "%s", "true", 1))
+		req.SetPathValue("workflow", "unknown")
+		rec := httptest.NewRecorder()
+
+		h.CreateAdminLLMPromptVersion(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404; body = %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		ms := &mockStore{}
+		h := newPromptTestHandlers(t, ms)
+		rec := httptest.NewRecorder()
+
+		h.CreateAdminLLMPromptVersion(rec, promptRequest(
+			http.MethodPost,
+			"/api/admin/llm/prompts/rule_drafting/draft_rule/versions",
+			`{"prompt":{"id":"rule_draft","messages":[{"role":"tool","content":"x"}]}}`,
+		))
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want 422; body = %s", rec.Code, rec.Body.String())
+		}
+		if len(ms.llmPromptVersions) != 0 {
+			t.Fatalf("stored versions = %d, want none", len(ms.llmPromptVersions))
+		}
+	})
+
+	t.Run("non admin", func(t *testing.T) {
+		h := newPromptTestHandlers(t, &mockStore{})
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/admin/llm/prompts/rule_drafting/draft_rule/versions", strings.NewReader("{}"))
+		rec := httptest.NewRecorder()
+
+		h.CreateAdminLLMPromptVersion(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403; body = %s", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -157,3 +157,91 @@ func TestActivateLLMProviderDoesNotExposeClientConstructionError(t *testing.T) {
 		t.Fatalf("error body = %s, want safe client construction error", rr.Body.String())
 	}
 }
+
+type modelListingLLMClient struct {
+	testLLMClient
+	models []llm.ModelOption
+}
+
+func (c modelListingLLMClient) ListModels(context.Context) ([]llm.ModelOption, error) {
+	return c.models, nil
+}
+
+func TestLLMProviderWithoutAuthActivatesAndListsModels(t *testing.T) {
+	ms := &mockStore{
+		llmProviderConfigs: map[string]json.RawMessage{"tenant-a/ollama": json.RawMessage(`{"model":"llama3.2"}`)},
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.llmRegistry = llm.NewRegistry()
+	if err := h.llmRegistry.RegisterProvider(llm.Provider{
+		Metadata: llm.ProviderMetadata{
+			Name:         "ollama",
+			DisplayName:  "Ollama",
+			Auth:         llm.AuthSpec{Type: llm.AuthTypeNone},
+			Capabilities: []llm.Capability{llm.CapabilityTextGeneration},
+		},
+		NewClient: func(llm.ClientConfig) (llm.Client, error) {
+			return modelListingLLMClient{models: []llm.ModelOption{{ID: "llama3.2:latest", DisplayName: "llama3.2:latest"}}}, nil
+		},
+	}); err != nil {
+		t.Fatalf("RegisterProvider() error = %v", err)
+	}
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+
+	credentialsReq := httptest.NewRequestWithContext(ctx, http.MethodPut, "/api/llm/providers/ollama/credentials", strings.NewReader(`{"api_key":"unused"}`))
+	credentialsReq.SetPathValue("name", "ollama")
+	credentialsRR := httptest.NewRecorder()
+	h.SaveLLMProviderCredentials(credentialsRR, credentialsReq)
+	if credentialsRR.Code != http.StatusBadRequest {
+		t.Fatalf("save credentials status = %d body=%s, want rejection", credentialsRR.Code, credentialsRR.Body.String())
+	}
+
+	modelsReq := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/llm/providers/ollama/models", nil)
+	modelsReq.SetPathValue("name", "ollama")
+	modelsRR := httptest.NewRecorder()
+	h.ListLLMProviderModels(modelsRR, modelsReq)
+	if modelsRR.Code != http.StatusOK {
+		t.Fatalf("models status = %d body=%s", modelsRR.Code, modelsRR.Body.String())
+	}
+	var models []llm.ModelOption
+	decodeJSON(t, modelsRR.Body.String(), &models)
+	if len(models) != 1 || models[0].ID != "llama3.2:latest" {
+		t.Fatalf("models = %+v", models)
+	}
+
+	activateReq := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/llm/providers/ollama/activate", nil)
+	activateReq.SetPathValue("name", "ollama")
+	activateRR := httptest.NewRecorder()
+	h.ActivateLLMProvider(activateRR, activateReq)
+	if activateRR.Code != http.StatusOK {
+		t.Fatalf("activate status = %d body=%s", activateRR.Code, activateRR.Body.String())
+	}
+
+	statusReq := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/llm/providers/ollama/status", nil)
+	statusReq.SetPathValue("name", "ollama")
+	statusRR := httptest.NewRecorder()
+	h.GetLLMProviderStatus(statusRR, statusReq)
+	var status llmProviderStatusJSON
+	decodeJSON(t, statusRR.Body.String(), &status)
+	if status.CredentialsStored || !status.Active || !status.Ready {
+		t.Fatalf("status = %+v, want active ready provider without credentials", status)
+	}
+}
+
+func TestListLLMProviderModelsReportsUnsupportedDiscovery(t *testing.T) {
+	ms := &mockStore{
+		llmProviderCredentials: map[string][]byte{"tenant-a/openai": []byte(`{"api_key":"sk-test"}`)},
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.llmRegistry = testLLMProvider(t, testLLMClient{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/llm/providers/openai/models", nil)
+	req.SetPathValue("name", "openai")
+	rr := httptest.NewRecorder()
+
+	h.ListLLMProviderModels(rr, req)
+
+	if rr.Code != http.StatusNotImplemented {
+		t.Fatalf("status = %d body=%s, want 501", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	defaultDailyUsageWindow   = 30 * 24 * time.Hour
+	defaultMonthlyUsageMonths = 12
+)
+
+// GetAdminLLMUsage handles GET /api/admin/llm/usage.
+// @Summary List LLM token usage over time
+// @Tags Admin
+// @Produce json
+// @Param from query string false "Inclusive range start (RFC3339)"
+// @Param to query string false "Exclusive range end (RFC3339)"
+// @Param interval query string false "Bucket size" Enums(day, month)
+// @Param tenant_id query string false "Restrict usage to one tenant"
+// @Success 200 {object} AdminLLMUsageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/llm/usage [get]
+func (h *Handlers) GetAdminLLMUsage(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	query, ok := decodeAndValidateQuery[llmUsageQuery](h, w, r)
+	if !ok {
+		return
+	}
+	filter := llmUsageFilterFromQuery(query, time.Now().UTC())
+	if !filter.From.Before(filter.To) {
+		writeError(w, r, errors.E(errors.InvalidInput, errors.User("from must be before to")))
+		return
+	}
+	buckets, err := h.llmUsageStore.ListLLMUsage(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := AdminLLMUsageResponse{
+		Interval: string(filter.Interval),
+		From:     filter.From,
+		To:       filter.To,
+		Buckets:  make([]AdminLLMUsageBucketResponse, 0, len(buckets)),
+	}
+	for _, bucket := range buckets {
+		resp.Buckets = append(resp.Buckets, AdminLLMUsageBucketResponse{
+			PeriodStart:      bucket.PeriodStart,
+			TenantID:         bucket.TenantID,
+			Provider:         bucket.Provider,
+			Model:            bucket.Model,
+			Calls:            bucket.Calls,
+			FailedCalls:      bucket.FailedCalls,
+			InputTokens:      bucket.InputTokens,
+			OutputTokens:     bucket.OutputTokens,
+			TotalTokens:      bucket.TotalTokens,
+			AverageLatencyMS: bucket.AverageLatency,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetAdminLLMQuota handles GET /api/admin/llm/quotas/{tenant_id}.
+// @Summary Get a tenant's LLM token quota
+// @Tags Admin
+// @Produce json
+// @Param tenant_id path string true "Tenant ID" example(00000000-0000-0000-0000-00000000c0de)
+// @Success 200 {object} AdminLLMQuotaResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/llm/quotas/{tenant_id} [get]
+func (h *Handlers) GetAdminLLMQuota(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	tenantID, ok := uuidPathValue(w, r, "tenant_id", "tenant")
+	if !ok {
+		return
+	}
+	tenant := store.Tenant{ID: tenantID}
+	quota, err := h.llmUsageStore.GetLLMUsageQuota(r.Context(), tenant)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeAdminLLMQuota(w, r, tenant, quota)
+}
+
+// PutAdminLLMQuota handles PUT /api/admin/llm/quotas/{tenant_id}.
+// @Summary Set a tenant's LLM token quota
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param tenant_id path string true "Tenant ID" example(00000000-0000-0000-0000-00000000c0de)
+// @Param request body AdminLLMQuotaRequest true "Token limits; zero means unlimited"
+// @Success 200 {object} AdminLLMQuotaResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/llm/quotas/{tenant_id} [put]
+func (h *Handlers) PutAdminLLMQuota(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	tenantID, ok := uuidPathValue(w, r, "tenant_id", "tenant")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[AdminLLMQuotaRequest](h, w, r)
+	if !ok {
+		return
+	}
+	tenant := store.Tenant{ID: tenantID}
+	quota, err := h.llmUsageStore.SetLLMUsageQuota(r.Context(), tenant, store.LLMUsageQuota{
+		TenantID:          tenantID,
+		DailyTokenLimit:   *body.DailyTokenLimit,
+		MonthlyTokenLimit: *body.MonthlyTokenLimit,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeAdminLLMQuota(w, r, tenant, quota)
+}
+
+func (h *Handlers) writeAdminLLMQuota(w http.ResponseWriter, r *http.Request, tenant store.Tenant, quota store.LLMUsageQuota) {
+	daily, monthly, err := h.llmTokensUsed(r.Context(), tenant, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AdminLLMQuotaResponse{
+		TenantID:          tenant.ID,
+		DailyTokenLimit:   quota.DailyTokenLimit,
+		MonthlyTokenLimit: quota.MonthlyTokenLimit,
+		DailyTokensUsed:   daily,
+		MonthlyTokensUsed: monthly,
+		UpdatedAt:         quota.UpdatedAt,
+	})
+}
+
+func (h *Handlers) llmTokensUsed(ctx context.Context, tenant store.Tenant, now time.Time) (int64, int64, error) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	daily, err := h.llmUsageStore.SumLLMUsageTokens(ctx, tenant, dayStart)
+	if err != nil {
+		return 0, 0, err
+	}
+	monthly, err := h.llmUsageStore.SumLLMUsageTokens(ctx, tenant, monthStart)
+	if err != nil {
+		return 0, 0, err
+	}
+	return daily, monthly, nil
+}
+
+func llmUsageFilterFromQuery(query llmUsageQuery, now time.Time) store.LLMUsageFilter {
+	filter := store.LLMUsageFilter{
+		TenantID: query.TenantID,
+		Interval: store.LLMUsageInterval(query.Interval),
+		To:       now,
+	}
+	if filter.Interval == "" {
+		filter.Interval = store.LLMUsageIntervalDay
+	}
+	if query.To != nil {
+		filter.To = query.To.UTC()
+	}
+	switch {
+	case query.From != nil:
+		filter.From = query.From.UTC()
+	case filter.Interval == store.LLMUsageIntervalMonth:
+		filter.From = filter.To.AddDate(0, -defaultMonthlyUsageMonths, 0)
+	default:
+		filter.From = filter.To.Add(-defaultDailyUsageWindow)
+	}
+	return filter
+}
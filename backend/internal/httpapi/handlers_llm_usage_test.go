@@ -0,0 +1,189 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+const llmUsageTestTenant = "00000000-0000-0000-0000-00000000c0de"
+
+func adminContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserID: "admin", TenantID: "admin", Role: auth.RoleAdmin})
+}
+
+func TestGetAdminLLMUsageRequiresAdminRole(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/admin/llm/usage", nil)
+	rec := httptest.NewRecorder()
+
+	h.GetAdminLLMUsage(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetAdminLLMUsageListsBuckets(t *testing.T) {
+	period := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	ms := &mockStore{llmUsageBuckets: []store.LLMUsageBucket{{
+		PeriodStart:    period,
+		TenantID:       llmUsageTestTenant,
+		Provider:       "openai",
+		Model:          "gpt-4.1-mini",
+		Calls:          3,
+		FailedCalls:    1,
+		InputTokens:    900,
+		OutputTokens:   300,
+		TotalTokens:    1200,
+		AverageLatency: 420.5,
+	}}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	req := httptest.NewRequestWithContext(
+		adminContext(),
+		http.MethodGet,
+		"/api/admin/llm/usage?interval=month&from=2026-01-01T00:00:00Z&to=2026-05-01T00:00:00Z&tenant_id="+llmUsageTestTenant,
+		nil,
+	)
+	rec := httptest.NewRecorder()
+
+	h.GetAdminLLMUsage(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if ms.llmUsageFilter.Interval != store.LLMUsageIntervalMonth || ms.llmUsageFilter.TenantID != llmUsageTestTenant {
+		t.Fatalf("filter = %+v, want month interval for tenant", ms.llmUsageFilter)
+	}
+	if !ms.llmUsageFilter.From.Equal(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("from = %s, want 2026-01-01", ms.llmUsageFilter.From)
+	}
+	var resp AdminLLMUsageResponse
+	decodeJSON(t, rec.Body.String(), &resp)
+	if resp.Interval != "month" || len(resp.Buckets) != 1 {
+		t.Fatalf("response = %+v, want one month bucket", resp)
+	}
+	if got := resp.Buckets[0]; got.TotalTokens != 1200 || got.FailedCalls != 1 || got.AverageLatencyMS != 420.5 {
+		t.Fatalf("bucket = %+v, want stored totals", got)
+	}
+}
+
+func TestGetAdminLLMUsageDefaultsToLastThirtyDays(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	req := httptest.NewRequestWithContext(adminContext(), http.MethodGet, "/api/admin/llm/usage", nil)
+	rec := httptest.NewRecorder()
+
+	h.GetAdminLLMUsage(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if ms.llmUsageFilter.Interval != store.LLMUsageIntervalDay {
+		t.Fatalf("interval = %q, want day", ms.llmUsageFilter.Interval)
+	}
+	if got := ms.llmUsageFilter.To.Sub(ms.llmUsageFilter.From); got != defaultDailyUsageWindow {
+		t.Fatalf("window = %s, want %s", got, defaultDailyUsageWindow)
+	}
+}
+
+func TestGetAdminLLMUsageRejectsInvertedRange(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	req := httptest.NewRequestWithContext(
+		adminContext(),
+		http.MethodGet,
+		"/api/admin/llm/usage?from=2026-05-01T00:00:00Z&to=2026-04-01T00:00:00Z",
+		nil,
+	)
+	rec := httptest.NewRecorder()
+
+	h.GetAdminLLMUsage(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPutAdminLLMQuotaStoresLimitsAndReportsUsage(t *testing.T) {
+	ms := &mockStore{llmUsageTokens: map[string]int64{llmUsageTestTenant: 750}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	req := httptest.NewRequestWithContext(
+		adminContext(),
+		http.MethodPut,
+		"/api/admin/llm/quotas/"+llmUsageTestTenant,
+		strings.NewReader(`{"daily_token_limit":1000,"monthly_token_limit":0}`),
+	)
+	req.SetPathValue("tenant_id", llmUsageTestTenant)
+	rec := httptest.NewRecorder()
+
+	h.PutAdminLLMQuota(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	saved := ms.llmUsageQuotas[llmUsageTestTenant]
+	if saved.DailyTokenLimit != 1000 || saved.MonthlyTokenLimit != 0 {
+		t.Fatalf("saved quota = %+v, want daily limit only", saved)
+	}
+	var resp AdminLLMQuotaResponse
+	decodeJSON(t, rec.Body.String(), &resp)
+	if resp.TenantID != llmUsageTestTenant || resp.DailyTokenLimit != 1000 || resp.DailyTokensUsed != 750 || resp.MonthlyTokensUsed != 750 {
+		t.Fatalf("response = %+v, want saved limits with current usage", resp)
+	}
+	if len(ms.llmUsageSince) != 2 || ms.llmUsageSince[0].Hour() != 0 || ms.llmUsageSince[1].Day() != 1 {
+		t.Fatalf("usage windows = %v, want day and month starts", ms.llmUsageSince)
+	}
+}
+
+func TestPutAdminLLMQuotaValidatesBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "negative limit", body: `{"daily_token_limit":-1,"monthly_token_limit":0}`},
+		{name: "missing limit", body: `{"daily_token_limit":10}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &mockStore{}
+			h := newTestHandlers(t, ms, &mockDaemon{})
+			req := httptest.NewRequestWithContext(
+				adminContext(),
+				http.MethodPut,
+				"/api/admin/llm/quotas/"+llmUsageTestTenant,
+				strings.NewReader(tt.body),
+			)
+			req.SetPathValue("tenant_id", llmUsageTestTenant)
+			rec := httptest.NewRecorder()
+
+			h.PutAdminLLMQuota(rec, req)
+
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want 422; body = %s", rec.Code, rec.Body.String())
+			}
+			if len(ms.llmUsageQuotas) != 0 {
+				t.Fatalf("quotas = %+v, want nothing saved", ms.llmUsageQuotas)
+			}
+		})
+	}
+}
+
+func TestGetAdminLLMQuotaRejectsInvalidTenantID(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	req := httptest.NewRequestWithContext(adminContext(), http.MethodGet, "/api/admin/llm/quotas/nope", nil)
+	req.SetPathValue("tenant_id", "nope")
+	rec := httptest.NewRecorder()
+
+	h.GetAdminLLMQuota(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400; body = %s", rec.Code, rec.Body.String())
+	}
+}
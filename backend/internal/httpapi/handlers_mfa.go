@@ -0,0 +1,1056 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/internal/webauthn"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// mfaSessionTTL bounds how long a password sign-in may wait for its
+	// second factor or enrollment.
+	mfaSessionTTL = 15 * time.Minute
+	// mfaMaxFailures wrong second factors revoke the pending session, so
+	// guessing needs the password again. As many for one user across
+	// sessions lock their second factor for a growing period, which signing
+	// in again does not clear.
+	mfaMaxFailures       = 5
+	webauthnChallengeTTL = 5 * time.Minute
+	recoveryCodeCount    = 10
+	totpIssuer           = "Expensor"
+)
+
+// Second-factor methods reported by mfaStatusResponse.
+const (
+	mfaMethodTOTP         = "totp"
+	mfaMethodWebAuthn     = "webauthn"
+	mfaMethodRecoveryCode = "recovery_code"
+)
+
+type webauthnCeremony string
+
+const (
+	webauthnRegister webauthnCeremony = "register"
+	webauthnSignIn   webauthnCeremony = "sign_in"
+)
+
+type webauthnChallengeKey struct {
+	sessionID string
+	ceremony  webauthnCeremony
+}
+
+// webauthnChallenge is the challenge last issued to a session for one
+// ceremony. It is consumed by the first response to it.
+type webauthnChallenge struct {
+	challenge []byte
+	expiresAt time.Time
+}
+
+type mfaFailureCount struct {
+	failures  int
+	expiresAt time.Time
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" validate:"required,max=32" example:"123456"`
+}
+
+type mfaPasskeyRequest struct {
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+type createPasskeyRequest struct {
+	Name       string                        `json:"name" validate:"required,no_control_chars,max=100" example:"Security key"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+type mfaStatusResponse struct {
+	State string `json:"state" example:"pending" enums:"complete,pending,enrollment"`
+	// Methods are the factors a pending session can be verified with, or
+	// the ones an enrollment session can set up.
+	Methods  []string `json:"methods"`
+	Required bool     `json:"required"`
+}
+
+type passkeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type mfaProfileResponse struct {
+	TOTP                   bool              `json:"totp"`
+	Passkeys               []passkeyResponse `json:"passkeys"`
+	PasskeysAvailable      bool              `json:"passkeys_available"`
+	RecoveryCodesRemaining int               `json:"recovery_codes_remaining"`
+	Required               bool              `json:"required"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+type mfaEnrollmentResponse struct {
+	Passkey *passkeyResponse `json:"passkey,omitempty"`
+	// RecoveryCodes are only returned with the user's first factor.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// GetSessionMFA handles GET /api/session/mfa.
+// @Summary Get the second-factor state of the current browser session
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} mfaStatusResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /session/mfa [get]
+func (h *Handlers) GetSessionMFA(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return
+	}
+	status, err := h.mfaStatus(r.Context(), session.UserID, session.MFA)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// VerifySessionTOTP handles POST /api/session/mfa/totp.
+// @Summary Complete sign-in with an authenticator app code
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body mfaCodeRequest true "Authenticator app code"
+// @Success 201 {object} principalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /session/mfa/totp [post]
+func (h *Handlers) VerifySessionTOTP(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.pendingSession(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[mfaCodeRequest](h, w, r)
+	if !ok {
+		return
+	}
+	factor, err := h.mfaStore.GetTOTPFactor(r.Context(), session.UserID)
+	if err != nil && errors.WhatKind(err) != errors.NotFound {
+		writeError(w, r, err)
+		return
+	}
+	if err != nil || factor.ConfirmedAt == nil {
+		h.mfaFailure(w, r, session, "TOTP is not enabled")
+		return
+	}
+	step, ok := auth.MatchTOTP(factor.Secret, body.Code, time.Now())
+	if !ok {
+		h.mfaFailure(w, r, session, "TOTP code does not match")
+		return
+	}
+	if err := h.mfaStore.UseTOTPStep(r.Context(), session.UserID, step); err != nil {
+		if errors.WhatKind(err) == errors.Conflict {
+			h.mfaFailure(w, r, session, "TOTP code already used")
+			return
+		}
+		writeError(w, r, err)
+		return
+	}
+	h.completeMFA(w, r, session)
+}
+
+// VerifySessionRecoveryCode handles POST /api/session/mfa/recovery-code.
+// @Summary Complete sign-in with a one-time recovery code
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body mfaCodeRequest true "Recovery code"
+// @Success 201 {object} principalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /session/mfa/recovery-code [post]
+func (h *Handlers) VerifySessionRecoveryCode(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.pendingSession(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[mfaCodeRequest](h, w, r)
+	if !ok {
+		return
+	}
+	if err := h.mfaStore.UseRecoveryCode(r.Context(), session.UserID, auth.HashRecoveryCode(body.Code)); err != nil {
+		if errors.WhatKind(err) == errors.NotFound {
+			h.mfaFailure(w, r, session, "unknown or used recovery code")
+			return
+		}
+		writeError(w, r, err)
+		return
+	}
+	h.logger.Info("recovery code used", "user_id", session.UserID)
+	h.completeMFA(w, r, session)
+}
+
+// SessionPasskeyOptions handles POST /api/session/mfa/webauthn/options. The
+// options are for navigator.credentials.get; their challenge is good for one
+// attempt within webauthnChallengeTTL.
+// @Summary Start passkey verification of a pending sign-in
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} webauthn.RequestOptions
+// @Failure 401 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /session/mfa/webauthn/options [post]
+func (h *Handlers) SessionPasskeyOptions(w http.ResponseWriter, r *http.Request) {
+	if !h.requireWebAuthn(w, r) {
+		return
+	}
+	session, ok := h.pendingSession(w, r)
+	if !ok {
+		return
+	}
+	passkeys, err := h.mfaStore.ListPasskeys(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	challenge, ok := h.issueWebAuthnChallenge(w, r, session.ID, webauthnSignIn)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, h.webauthn.RequestOptions(challenge, webauthnCredentials(passkeys)))
+}
+
+// VerifySessionPasskey handles POST /api/session/mfa/webauthn.
+// @Summary Complete sign-in with a passkey
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body mfaPasskeyRequest true "Passkey assertion"
+// @Success 201 {object} principalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /session/mfa/webauthn [post]
+func (h *Handlers) VerifySessionPasskey(w http.ResponseWriter, r *http.Request) {
+	if !h.requireWebAuthn(w, r) {
+		return
+	}
+	session, ok := h.pendingSession(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[mfaPasskeyRequest](h, w, r)
+	if !ok {
+		return
+	}
+	challenge, ok := h.takeWebAuthnChallenge(w, r, session.ID, webauthnSignIn)
+	if !ok {
+		return
+	}
+	passkeys, err := h.mfaStore.ListPasskeys(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	for i := range passkeys {
+		passkey := &passkeys[i]
+		if !bytes.Equal(passkey.CredentialID, body.Credential.RawID) {
+			continue
+		}
+		signCount, err := h.webauthn.VerifyAssertion(challenge, body.Credential, webauthnCredential(passkey))
+		if err != nil {
+			h.mfaFailure(w, r, session, err.Error())
+			return
+		}
+		if err := h.mfaStore.UsePasskey(r.Context(), passkey.ID, signCount); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		h.completeMFA(w, r, session)
+		return
+	}
+	h.mfaFailure(w, r, session, "passkey is not registered to the user")
+}
+
+// GetProfileMFA handles GET /api/profile/mfa.
+// @Summary List the current user's second factors
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} mfaProfileResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profile/mfa [get]
+func (h *Handlers) GetProfileMFA(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	factors, err := h.mfaStore.GetMFAFactors(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	passkeys, err := h.mfaStore.ListPasskeys(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	settings, err := h.mfaStore.GetMFASettings(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := mfaProfileResponse{
+		TOTP:                   factors.TOTP,
+		Passkeys:               make([]passkeyResponse, 0, len(passkeys)),
+		PasskeysAvailable:      h.webauthn != nil,
+		RecoveryCodesRemaining: factors.RecoveryCodes,
+		Required:               settings.Required,
+	}
+	for i := range passkeys {
+		resp.Passkeys = append(resp.Passkeys, passkeyFromStore(&passkeys[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// StartTOTPEnrollment handles POST /api/profile/mfa/totp. The new secret
+// replaces any unconfirmed one and is not used for sign-in until confirmed.
+// @Summary Start authenticator app enrollment
+// @Tags Auth
+// @Produce json
+// @Success 201 {object} totpEnrollmentResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profile/mfa/totp [post]
+func (h *Handlers) StartTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.cookieSession(w, r); !ok {
+		return
+	}
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.mfaStore.SetPendingTOTP(r.Context(), user.ID, secret); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, totpEnrollmentResponse{
+		Secret:     auth.TOTPSecretString(secret),
+		OTPAuthURL: auth.TOTPURL(totpIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmTOTPEnrollment handles POST /api/profile/mfa/totp/confirm.
+// @Summary Enable the authenticator app with a first code
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body mfaCodeRequest true "Authenticator app code"
+// @Success 200 {object} mfaEnrollmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profile/mfa/totp/confirm [post]
+func (h *Handlers) ConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[mfaCodeRequest](h, w, r)
+	if !ok {
+		return
+	}
+	factor, err := h.mfaStore.GetTOTPFactor(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if factor.ConfirmedAt != nil {
+		writeError(w, r, errors.E(errors.Conflict, errors.User("TOTP is already enabled")))
+		return
+	}
+	step, ok := auth.MatchTOTP(factor.Secret, body.Code, time.Now())
+	if !ok {
+		writeError(w, r, errors.E(errors.InvalidArgument, errors.User("invalid verification code")))
+		return
+	}
+	factors, err := h.mfaStore.GetMFAFactors(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	err = h.mfaStore.ConfirmTOTP(r.Context(), session.UserID, step)
+	h.audit(r, store.NewAuditEvent{ActorUserID: session.UserID, Action: store.AuditMFAEnroll, TargetType: "totp"}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp, ok := h.factorEnrolled(w, r, session, factors)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteTOTP handles DELETE /api/profile/mfa/totp.
+// @Summary Remove the authenticator app
+// @Tags Auth
+// @Success 204
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profile/mfa/totp [delete]
+func (h *Handlers) DeleteTOTP(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return
+	}
+	factors, err := h.mfaStore.GetMFAFactors(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	last := factors.TOTP && factors.Passkeys == 0
+	if last && !h.allowLastFactorRemoval(w, r) {
+		return
+	}
+	err = h.mfaStore.DeleteTOTP(r.Context(), session.UserID)
+	h.audit(r, store.NewAuditEvent{ActorUserID: session.UserID, Action: store.AuditMFARemove, TargetType: "totp"}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if last && !h.clearRecoveryCodes(w, r, session.UserID) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles POST /api/profile/mfa/recovery-codes. Only
+// hashes are stored, so this response is the one chance to see the codes.
+// @Summary Replace the current user's recovery codes
+// @Tags Auth
+// @Produce json
+// @Success 201 {object} recoveryCodesResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profile/mfa/recovery-codes [post]
+func (h *Handlers) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return
+	}
+	factors, err := h.mfaStore.GetMFAFactors(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !factors.Enrolled() {
+		writeError(w, r, errors.E(errors.FailedPrecondition, errors.User("set up an authenticator app or passkey first")))
+		return
+	}
+	codes, err := h.replaceRecoveryCodes(r.Context(), session.UserID)
+	h.audit(r, store.NewAuditEvent{ActorUserID: session.UserID, Action: store.AuditRecoveryCodesRegenerate, TargetType: "user", TargetID: session.UserID}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// PasskeyRegistrationOptions handles POST /api/profile/mfa/passkeys/options,
+// returning options for navigator.credentials.create.
+// @Summary Start passkey registration
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} webauthn.CreationOptions
+// @Failure 401 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /profile/mfa/passkeys/options [post]
+func (h *Handlers) PasskeyRegistrationOptions(w http.ResponseWriter, r *http.Request) {
+	if !h.requireWebAuthn(w, r) {
+		return
+	}
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	passkeys, err := h.mfaStore.ListPasskeys(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	challenge, ok := h.issueWebAuthnChallenge(w, r, session.ID, webauthnRegister)
+	if !ok {
+		return
+	}
+	options := h.webauthn.CreationOptions(
+		webauthn.User{ID: []byte(user.ID), Name: user.Email, DisplayName: user.DisplayName},
+		challenge,
+		webauthnCredentials(passkeys),
+	)
+	writeJSON(w, http.StatusOK, options)
+}
+
+// CreatePasskey handles POST /api/profile/mfa/passkeys.
+// @Summary Register a passkey
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body createPasskeyRequest true "Passkey name and attestation"
+// @Success 201 {object} mfaEnrollmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /profile/mfa/passkeys [post]
+func (h *Handlers) CreatePasskey(w http.ResponseWriter, r *http.Request) {
+	if !h.requireWebAuthn(w, r) {
+		return
+	}
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[createPasskeyRequest](h, w, r)
+	if !ok {
+		return
+	}
+	challenge, ok := h.takeWebAuthnChallenge(w, r, session.ID, webauthnRegister)
+	if !ok {
+		return
+	}
+	credential, err := h.webauthn.VerifyRegistration(challenge, body.Credential)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	factors, err := h.mfaStore.GetMFAFactors(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	passkey, err := h.mfaStore.CreatePasskey(r.Context(), store.CreatePasskeyInput{
+		UserID:       session.UserID,
+		Name:         strings.TrimSpace(body.Name),
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		Algorithm:    credential.Algorithm,
+		SignCount:    credential.SignCount,
+		Transports:   credential.Transports,
+	})
+	event := store.NewAuditEvent{ActorUserID: session.UserID, Action: store.AuditMFAEnroll, TargetType: "passkey"}
+	if err != nil {
+		h.audit(r, event, err)
+		writeError(w, r, err)
+		return
+	}
+	event.TargetID = passkey.ID
+	h.audit(r, event, nil)
+	resp, ok := h.factorEnrolled(w, r, session, factors)
+	if !ok {
+		return
+	}
+	created := passkeyFromStore(passkey)
+	resp.Passkey = &created
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// DeletePasskey handles DELETE /api/profile/mfa/passkeys/{id}.
+// @Summary Remove a passkey
+// @Tags Auth
+// @Param id path string true "Passkey ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profile/mfa/passkeys/{id} [delete]
+func (h *Handlers) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "passkey")
+	if !ok {
+		return
+	}
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return
+	}
+	factors, err := h.mfaStore.GetMFAFactors(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	last := !factors.TOTP && factors.Passkeys == 1
+	if last && !h.allowLastFactorRemoval(w, r) {
+		return
+	}
+	err = h.mfaStore.DeletePasskey(r.Context(), session.UserID, id)
+	h.audit(r, store.NewAuditEvent{ActorUserID: session.UserID, Action: store.AuditMFARemove, TargetType: "passkey", TargetID: id}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if last && !h.clearRecoveryCodes(w, r, session.UserID) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMFASettings handles GET /api/admin/mfa/settings.
+// @Summary Get the multi-factor authentication policy
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} MFASettingsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/mfa/settings [get]
+func (h *Handlers) GetMFASettings(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	settings, err := h.mfaStore.GetMFASettings(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mfaSettingsResponse(settings))
+}
+
+// PatchMFASettings handles PATCH /api/admin/mfa/settings. Requiring MFA
+// applies from each user's next password sign-in.
+// @Summary Update the multi-factor authentication policy
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body MFASettingsPatchRequest true "MFA settings patch"
+// @Success 200 {object} MFASettingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/mfa/settings [patch]
+func (h *Handlers) PatchMFASettings(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	body, ok := decodeAndValidateJSON[MFASettingsPatchRequest](h, w, r)
+	if !ok {
+		return
+	}
+	settings, err := h.mfaStore.PatchMFASettings(r.Context(), store.MFASettingsPatch{Required: body.Required})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mfaSettingsResponse(settings))
+}
+
+// passwordSessionState decides how far a password sign-in gets: users with
+// a second factor must present it, and while MFA is required users without
+// one must enroll one first.
+func (h *Handlers) passwordSessionState(ctx context.Context, userID string) (store.SessionMFA, error) {
+	factors, err := h.mfaStore.GetMFAFactors(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if factors.Enrolled() {
+		return store.SessionMFAPending, nil
+	}
+	settings, err := h.mfaStore.GetMFASettings(ctx)
+	if err != nil {
+		return "", err
+	}
+	if settings.Required {
+		return store.SessionMFAEnrollment, nil
+	}
+	return store.SessionMFAComplete, nil
+}
+
+// startPasswordSession signs user in after a password check. A fully
+// signed-in user gets 201 and their principal; otherwise the session waits
+// for a second factor and the response is 202 with what to do next.
+func (h *Handlers) startPasswordSession(w http.ResponseWriter, r *http.Request, user *store.User) {
+	state, err := h.passwordSessionState(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if state == store.SessionMFAComplete {
+		if !h.createSessionCookie(w, r, user) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, principalFromUser(user))
+		return
+	}
+	status, err := h.mfaStatus(r.Context(), user.ID, state)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !h.createSessionCookieWithMFA(w, r, user, state, mfaSessionTTL) {
+		return
+	}
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (h *Handlers) mfaStatus(ctx context.Context, userID string, state store.SessionMFA) (mfaStatusResponse, error) {
+	settings, err := h.mfaStore.GetMFASettings(ctx)
+	if err != nil {
+		return mfaStatusResponse{}, err
+	}
+	resp := mfaStatusResponse{State: string(state), Methods: []string{}, Required: settings.Required}
+	switch state {
+	case store.SessionMFAPending:
+		factors, err := h.mfaStore.GetMFAFactors(ctx, userID)
+		if err != nil {
+			return mfaStatusResponse{}, err
+		}
+		if factors.TOTP {
+			resp.Methods = append(resp.Methods, mfaMethodTOTP)
+		}
+		if factors.Passkeys > 0 && h.webauthn != nil {
+			resp.Methods = append(resp.Methods, mfaMethodWebAuthn)
+		}
+		if factors.RecoveryCodes > 0 {
+			resp.Methods = append(resp.Methods, mfaMethodRecoveryCode)
+		}
+	case store.SessionMFAEnrollment:
+		resp.Methods = append(resp.Methods, mfaMethodTOTP)
+		if h.webauthn != nil {
+			resp.Methods = append(resp.Methods, mfaMethodWebAuthn)
+		}
+	}
+	return resp, nil
+}
+
+// mfaSessionAllows reports whether a session that has not finished
+// multi-factor authentication may make request r. Pending sessions can only
+// verify a factor or sign out; enrollment sessions can also set one up.
+func mfaSessionAllows(state store.SessionMFA, r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodDelete && path == "/api/session":
+		return true
+	case path == "/api/session/mfa" || strings.HasPrefix(path, "/api/session/mfa/"):
+		return true
+	case state != store.SessionMFAEnrollment:
+		return false
+	case r.Method == http.MethodGet && path == "/api/session":
+		return true
+	default:
+		return path == "/api/profile/mfa" || strings.HasPrefix(path, "/api/profile/mfa/")
+	}
+}
+
+func mfaSessionError(state store.SessionMFA) error {
+	if state == store.SessionMFAEnrollment {
+		return errors.E(errors.PermissionDenied, errors.User("multi-factor authentication must be set up first"))
+	}
+	return errors.E(errors.Unauthenticated, errors.User("second factor required"))
+}
+
+// cookieSession returns the browser session making r. Second factors are
+// enrolled and verified per session, so access tokens and proxy sign-ins
+// cannot use these endpoints.
+func (h *Handlers) cookieSession(w http.ResponseWriter, r *http.Request) (*store.Session, bool) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return nil, false
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" || principal.AuthMethod != "session" {
+		writeError(w, r, errors.E(errors.FailedPrecondition, errors.User("multi-factor authentication needs a browser session")))
+		return nil, false
+	}
+	session, err := h.authStore.FindSessionByHash(r.Context(), auth.HashOpaqueToken(cookie.Value))
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return session, true
+}
+
+func (h *Handlers) pendingSession(w http.ResponseWriter, r *http.Request) (*store.Session, bool) {
+	session, ok := h.cookieSession(w, r)
+	if !ok {
+		return nil, false
+	}
+	if session.MFA != store.SessionMFAPending {
+		writeError(w, r, errors.E(errors.FailedPrecondition, errors.User("no second factor is pending")))
+		return nil, false
+	}
+	if now, until := time.Now(), h.mfaLockedUntil(session.UserID); until.After(now) {
+		writeLoginLocked(w, r, until, now)
+		return nil, false
+	}
+	return session, true
+}
+
+// completeMFA swaps a verified pending session for a full one under a new
+// token, so nothing learned about the pending cookie carries over.
+func (h *Handlers) completeMFA(w http.ResponseWriter, r *http.Request, session *store.Session) {
+	user, ok := h.authenticatedUser(w, r, session.UserID)
+	if !ok {
+		return
+	}
+	if !h.replaceSession(w, r, session, user) {
+		return
+	}
+	h.mfaThrottle.clear(mfaThrottleKey(user.ID))
+	writeJSON(w, http.StatusCreated, principalFromUser(user))
+}
+
+func (h *Handlers) replaceSession(w http.ResponseWriter, r *http.Request, session *store.Session, user *store.User) bool {
+	if err := h.authStore.RevokeSession(r.Context(), session.ID); err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	h.forgetMFASession(session.ID)
+	return h.createSessionCookie(w, r, user)
+}
+
+// mfaFailure rejects a second factor for reason, which is only logged.
+// Repeated failures revoke the session.
+func (h *Handlers) mfaFailure(w http.ResponseWriter, r *http.Request, session *store.Session, reason string) {
+	now := time.Now()
+	h.mu.Lock()
+	for k, v := range h.mfaFailures {
+		if now.After(v.expiresAt) {
+			delete(h.mfaFailures, k)
+		}
+	}
+	count := h.mfaFailures[session.ID]
+	count.failures++
+	count.expiresAt = session.ExpiresAt
+	h.mfaFailures[session.ID] = count
+	h.mu.Unlock()
+	if failures, lockout := h.mfaThrottle.fail(mfaThrottleKey(session.UserID), mfaMaxFailures, now); lockout > 0 {
+		h.logger.Warn("second factor locked after failed attempts", "user_id", session.UserID, "failures", failures, "lockout", lockout)
+	}
+
+	h.logger.Info("second factor rejected", "user_id", session.UserID, "reason", reason, "failures", count.failures)
+	if count.failures < mfaMaxFailures {
+		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("invalid verification code")))
+		return
+	}
+	if err := h.authStore.RevokeSession(r.Context(), session.ID); err != nil && errors.WhatKind(err) != errors.NotFound {
+		writeError(w, r, err)
+		return
+	}
+	h.forgetMFASession(session.ID)
+	clearSessionCookie(w, r)
+	writeError(w, r, errors.E(errors.Unauthenticated, errors.User("too many failed attempts; sign in again")))
+}
+
+// mfaThrottleKey counts a user's failed second factors across all of their
+// pending sessions.
+func mfaThrottleKey(userID string) loginThrottleKey {
+	return loginThrottleKey{kind: "mfa", value: userID}
+}
+
+// mfaLockedUntil returns when userID may next try a second factor.
+func (h *Handlers) mfaLockedUntil(userID string) time.Time {
+	return h.mfaThrottle.lockedUntil(mfaThrottleKey(userID))
+}
+
+func (h *Handlers) forgetMFASession(sessionID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.mfaFailures, sessionID)
+	delete(h.webauthnChallenges, webauthnChallengeKey{sessionID: sessionID, ceremony: webauthnRegister})
+	delete(h.webauthnChallenges, webauthnChallengeKey{sessionID: sessionID, ceremony: webauthnSignIn})
+}
+
+// factorEnrolled finishes adding a factor. The user's first factor comes
+// with recovery codes, and turns an enrollment session into a full one.
+func (h *Handlers) factorEnrolled(w http.ResponseWriter, r *http.Request, session *store.Session, before store.MFAFactors) (mfaEnrollmentResponse, bool) {
+	var resp mfaEnrollmentResponse
+	if !before.Enrolled() {
+		codes, err := h.replaceRecoveryCodes(r.Context(), session.UserID)
+		if err != nil {
+			writeError(w, r, err)
+			return mfaEnrollmentResponse{}, false
+		}
+		resp.RecoveryCodes = codes
+	}
+	h.logger.Info("second factor enrolled", "user_id", session.UserID)
+	if session.MFA != store.SessionMFAEnrollment {
+		return resp, true
+	}
+	user, ok := h.authenticatedUser(w, r, session.UserID)
+	if !ok {
+		return mfaEnrollmentResponse{}, false
+	}
+	if !h.replaceSession(w, r, session, user) {
+		return mfaEnrollmentResponse{}, false
+	}
+	return resp, true
+}
+
+func (h *Handlers) replaceRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	if err := h.mfaStore.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// allowLastFactorRemoval refuses to drop a user's last factor while MFA is
+// required, since their next sign-in would have to enroll again.
+func (h *Handlers) allowLastFactorRemoval(w http.ResponseWriter, r *http.Request) bool {
+	settings, err := h.mfaStore.GetMFASettings(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	if settings.Required {
+		writeError(w, r, errors.E(errors.FailedPrecondition, errors.User("multi-factor authentication is required; add another factor before removing this one")))
+		return false
+	}
+	return true
+}
+
+// clearRecoveryCodes drops recovery codes once no factor is left for them
+// to stand in for.
+func (h *Handlers) clearRecoveryCodes(w http.ResponseWriter, r *http.Request, userID string) bool {
+	if err := h.mfaStore.ReplaceRecoveryCodes(r.Context(), userID, nil); err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) requireWebAuthn(w http.ResponseWriter, r *http.Request) bool {
+	if h.webauthn == nil {
+		writeError(w, r, errors.E(errors.Unimplemented, errors.User("passkeys not configured")))
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) issueWebAuthnChallenge(w http.ResponseWriter, r *http.Request, sessionID string, ceremony webauthnCeremony) ([]byte, bool) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k, v := range h.webauthnChallenges {
+		if now.After(v.expiresAt) {
+			delete(h.webauthnChallenges, k)
+		}
+	}
+	h.webauthnChallenges[webauthnChallengeKey{sessionID: sessionID, ceremony: ceremony}] = webauthnChallenge{
+		challenge: challenge,
+		expiresAt: now.Add(webauthnChallengeTTL),
+	}
+	return challenge, true
+}
+
+func (h *Handlers) takeWebAuthnChallenge(w http.ResponseWriter, r *http.Request, sessionID string, ceremony webauthnCeremony) ([]byte, bool) {
+	key := webauthnChallengeKey{sessionID: sessionID, ceremony: ceremony}
+	h.mu.Lock()
+	entry, found := h.webauthnChallenges[key]
+	delete(h.webauthnChallenges, key)
+	h.mu.Unlock()
+	if !found || time.Now().After(entry.expiresAt) {
+		writeError(w, r, errors.E(errors.FailedPrecondition, errors.User("passkey challenge expired; try again")))
+		return nil, false
+	}
+	return entry.challenge, true
+}
+
+func webauthnCredential(passkey *store.Passkey) webauthn.Credential {
+	return webauthn.Credential{
+		ID:         passkey.CredentialID,
+		PublicKey:  passkey.PublicKey,
+		Algorithm:  passkey.Algorithm,
+		SignCount:  passkey.SignCount,
+		Transports: passkey.Transports,
+	}
+}
+
+func webauthnCredentials(passkeys []store.Passkey) []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(passkeys))
+	for i := range passkeys {
+		credentials[i] = webauthnCredential(&passkeys[i])
+	}
+	return credentials
+}
+
+func passkeyFromStore(passkey *store.Passkey) passkeyResponse {
+	return passkeyResponse{
+		ID:         passkey.ID,
+		Name:       passkey.Name,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+	}
+}
+
+func mfaSettingsResponse(settings store.MFASettings) MFASettingsResponse {
+	return MFASettingsResponse{Required: settings.Required, UpdatedAt: settings.UpdatedAt}
+}
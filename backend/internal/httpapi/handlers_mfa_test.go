@@ -0,0 +1,363 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/internal/webauthn"
+	"github.com/ArionMiles/expensor/backend/internal/webauthn/webauthntest"
+)
+
+const (
+	mfaTestOrigin   = "http://localhost:5173"
+	mfaTestPassword = "correct horse battery staple"
+)
+
+// mfaBrowser drives the routed API with a single session cookie, the way a
+// browser tab would.
+type mfaBrowser struct {
+	t       *testing.T
+	handler http.Handler
+	cookie  *http.Cookie
+}
+
+func newMFATestBrowser(t *testing.T, ms *mockStore) (*mfaBrowser, *Handlers) {
+	t.Helper()
+	hash, err := auth.HashPassword(mfaTestPassword)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	user := &store.User{ID: "user-a", TenantID: "user-a", Email: "asha@example.com", PasswordHash: hash, Role: store.UserRoleUser}
+	ms.usersByEmail = map[string]*store.User{user.Email: user}
+	ms.usersByID = map[string]*store.User{user.ID: user}
+	ms.sessionsByHash = map[string]*store.Session{}
+	rp, err := webauthn.New("Expensor", mfaTestOrigin)
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.webauthn = rp
+	mux := http.NewServeMux()
+	registerRoutes(mux, h)
+	return &mfaBrowser{t: t, handler: authMiddleware(h, mux)}, h
+}
+
+func (b *mfaBrowser) do(method, path string, body any) *httptest.ResponseRecorder {
+	b.t.Helper()
+	var reader *strings.Reader
+	switch v := body.(type) {
+	case nil:
+		reader = strings.NewReader("")
+	case string:
+		reader = strings.NewReader(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			b.t.Fatalf("marshal request: %v", err)
+		}
+		reader = strings.NewReader(string(data))
+	}
+	req := httptest.NewRequestWithContext(context.Background(), method, path, reader)
+	if b.cookie != nil {
+		req.AddCookie(b.cookie)
+	}
+	rec := httptest.NewRecorder()
+	b.handler.ServeHTTP(rec, req)
+	if cookie := findCookie(rec.Result().Cookies(), sessionCookieName); cookie != nil {
+		b.cookie = cookie
+		if cookie.MaxAge < 0 {
+			b.cookie = nil
+		}
+	}
+	return rec
+}
+
+func (b *mfaBrowser) login() *httptest.ResponseRecorder {
+	b.t.Helper()
+	return b.do(http.MethodPost, "/api/session", `{"email":"asha@example.com","password":"`+mfaTestPassword+`"}`)
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %T: %v; body = %s", v, err, rec.Body.String())
+	}
+	return v
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int, what string) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("%s status = %d, want %d; body = %s", what, rec.Code, want, rec.Body.String())
+	}
+}
+
+func TestTOTPEnrollmentAndSignIn(t *testing.T) {
+	ms := &mockStore{}
+	browser, _ := newMFATestBrowser(t, ms)
+	expectStatus(t, browser.login(), http.StatusCreated, "first login")
+
+	enrollment := decodeBody[totpEnrollmentResponse](t, browser.do(http.MethodPost, "/api/profile/mfa/totp", nil))
+	if !strings.HasPrefix(enrollment.OTPAuthURL, "otpauth://totp/Expensor:asha@example.com?") || enrollment.Secret == "" {
+		t.Fatalf("enrollment = %+v", enrollment)
+	}
+	secret := ms.totpFactor.Secret
+	step := auth.TOTPStep(time.Now())
+	wrong := auth.TOTPCode(secret, step+5)
+	expectStatus(t, browser.do(http.MethodPost, "/api/profile/mfa/totp/confirm", mfaCodeRequest{Code: wrong}), http.StatusBadRequest, "wrong confirm")
+
+	rec := browser.do(http.MethodPost, "/api/profile/mfa/totp/confirm", mfaCodeRequest{Code: auth.TOTPCode(secret, step)})
+	expectStatus(t, rec, http.StatusOK, "confirm")
+	if codes := decodeBody[mfaEnrollmentResponse](t, rec).RecoveryCodes; len(codes) != recoveryCodeCount {
+		t.Fatalf("recovery codes = %q", codes)
+	}
+	expectStatus(t, browser.do(http.MethodDelete, "/api/session", nil), http.StatusNoContent, "logout")
+
+	rec = browser.login()
+	expectStatus(t, rec, http.StatusAccepted, "second login")
+	status := decodeBody[mfaStatusResponse](t, rec)
+	if status.State != string(store.SessionMFAPending) || !slices.Equal(status.Methods, []string{mfaMethodTOTP, mfaMethodRecoveryCode}) {
+		t.Fatalf("status = %+v", status)
+	}
+	if ms.createdSession.MFA != store.SessionMFAPending || time.Until(ms.createdSession.ExpiresAt) > mfaSessionTTL {
+		t.Fatalf("pending session = %+v", ms.createdSession)
+	}
+	expectStatus(t, browser.do(http.MethodGet, "/api/session", nil), http.StatusUnauthorized, "pending session")
+	expectStatus(t, browser.do(http.MethodGet, "/api/session/mfa", nil), http.StatusOK, "pending status")
+
+	// The code used to confirm enrollment cannot be replayed.
+	expectStatus(t, browser.do(http.MethodPost, "/api/session/mfa/totp", mfaCodeRequest{Code: auth.TOTPCode(secret, step)}),
+		http.StatusUnauthorized, "replayed code")
+	pending := browser.cookie
+	rec = browser.do(http.MethodPost, "/api/session/mfa/totp", mfaCodeRequest{Code: auth.TOTPCode(secret, step+1)})
+	expectStatus(t, rec, http.StatusCreated, "verify")
+	if principal := decodeBody[principalResponse](t, rec); principal.UserID != "user-a" {
+		t.Fatalf("principal = %+v", principal)
+	}
+	if browser.cookie == nil || browser.cookie.Value == pending.Value {
+		t.Fatal("verification did not issue a new session cookie")
+	}
+	if session := ms.sessionsByHash[auth.HashOpaqueToken(pending.Value)]; session.RevokedAt == nil {
+		t.Fatal("pending session was not revoked")
+	}
+	expectStatus(t, browser.do(http.MethodGet, "/api/session", nil), http.StatusOK, "signed in")
+
+	expectStatus(t, browser.do(http.MethodPost, "/api/profile/mfa/recovery-codes", nil), http.StatusCreated, "regenerate codes")
+	expectStatus(t, browser.do(http.MethodDelete, "/api/profile/mfa/totp", nil), http.StatusNoContent, "remove")
+	want := []store.AuditAction{store.AuditMFAEnroll, store.AuditRecoveryCodesRegenerate, store.AuditMFARemove}
+	if got := auditedActions(ms, "mfa."); !slices.Equal(got, want) {
+		t.Fatalf("audited %q, want %q", got, want)
+	}
+}
+
+func TestRecoveryCodeSignIn(t *testing.T) {
+	confirmed := time.Now()
+	ms := &mockStore{
+		totpFactor:         &store.TOTPFactor{Secret: []byte("12345678901234567890"), ConfirmedAt: &confirmed},
+		recoveryCodeHashes: map[string]bool{auth.HashRecoveryCode("abcde-fghij"): false},
+	}
+	browser, _ := newMFATestBrowser(t, ms)
+
+	expectStatus(t, browser.login(), http.StatusAccepted, "login")
+	expectStatus(t, browser.do(http.MethodPost, "/api/session/mfa/recovery-code", mfaCodeRequest{Code: "ABCDE FGHIJ"}),
+		http.StatusCreated, "recovery code")
+
+	expectStatus(t, browser.login(), http.StatusAccepted, "login again")
+	expectStatus(t, browser.do(http.MethodPost, "/api/session/mfa/recovery-code", mfaCodeRequest{Code: "abcde-fghij"}),
+		http.StatusUnauthorized, "reused recovery code")
+}
+
+func TestMFAFailuresRevokePendingSession(t *testing.T) {
+	confirmed := time.Now()
+	ms := &mockStore{totpFactor: &store.TOTPFactor{Secret: []byte("12345678901234567890"), ConfirmedAt: &confirmed}}
+	browser, _ := newMFATestBrowser(t, ms)
+	expectStatus(t, browser.login(), http.StatusAccepted, "login")
+
+	wrong := auth.TOTPCode(ms.totpFactor.Secret, auth.TOTPStep(time.Now())+10)
+	for range mfaMaxFailures - 1 {
+		expectStatus(t, browser.do(http.MethodPost, "/api/session/mfa/totp", mfaCodeRequest{Code: wrong}), http.StatusUnauthorized, "wrong code")
+	}
+	rec := browser.do(http.MethodPost, "/api/session/mfa/totp", mfaCodeRequest{Code: wrong})
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "too many failed attempts") {
+		t.Fatalf("last failure = %d %s", rec.Code, rec.Body.String())
+	}
+	if browser.cookie != nil {
+		t.Fatal("session cookie was not cleared")
+	}
+	if len(ms.sessionsByHash) != 1 {
+		t.Fatalf("sessions = %d, want the one pending session", len(ms.sessionsByHash))
+	}
+	for _, session := range ms.sessionsByHash {
+		if session.RevokedAt == nil {
+			t.Fatal("pending session was not revoked")
+		}
+	}
+}
+
+func TestMFAFailuresLockUserAcrossSignIns(t *testing.T) {
+	confirmed := time.Now()
+	ms := &mockStore{totpFactor: &store.TOTPFactor{Secret: []byte("12345678901234567890"), ConfirmedAt: &confirmed}}
+	browser, h := newMFATestBrowser(t, ms)
+	// A second tab with its own pending session, opened before the guessing.
+	other := &mfaBrowser{t: t, handler: browser.handler}
+	expectStatus(t, other.login(), http.StatusAccepted, "other login")
+
+	wrong := auth.TOTPCode(ms.totpFactor.Secret, auth.TOTPStep(time.Now())+10)
+	// Signing in again must not buy another round of guesses.
+	guesses := 0
+	rec := browser.login()
+	for ; rec.Code == http.StatusAccepted; rec = browser.login() {
+		if guesses >= mfaMaxFailures {
+			t.Fatalf("signed in again after %d wrong codes, want the second factor locked", guesses)
+		}
+		for range mfaMaxFailures {
+			browser.do(http.MethodPost, "/api/session/mfa/totp", mfaCodeRequest{Code: wrong})
+			guesses++
+		}
+	}
+	expectStatus(t, rec, http.StatusTooManyRequests, "login after failed second factors")
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("locked login has no Retry-After")
+	}
+	code := auth.TOTPCode(ms.totpFactor.Secret, auth.TOTPStep(time.Now()))
+	expectStatus(t, other.do(http.MethodPost, "/api/session/mfa/totp", mfaCodeRequest{Code: code}),
+		http.StatusTooManyRequests, "other session while locked")
+
+	h.mfaThrottle.prune(time.Now().Add(loginFailureWindow + time.Minute))
+	expectStatus(t, other.do(http.MethodPost, "/api/session/mfa/totp", mfaCodeRequest{Code: code}), http.StatusCreated, "after lockout")
+	if until := h.mfaLockedUntil("user-a"); !until.IsZero() {
+		t.Fatalf("second factor locked until %v after sign-in, want cleared", until)
+	}
+}
+
+func TestPasskeyEnrollmentAndSignIn(t *testing.T) {
+	ms := &mockStore{}
+	browser, _ := newMFATestBrowser(t, ms)
+	authenticator := webauthntest.NewAuthenticator(t)
+	expectStatus(t, browser.login(), http.StatusCreated, "first login")
+
+	creation := decodeBody[webauthn.CreationOptions](t, browser.do(http.MethodPost, "/api/profile/mfa/passkeys/options", nil))
+	if creation.RP.ID != "localhost" || string(creation.User.ID) != "user-a" {
+		t.Fatalf("creation options = %+v", creation)
+	}
+	registration := authenticator.Register(creation, mfaTestOrigin)
+	rec := browser.do(http.MethodPost, "/api/profile/mfa/passkeys", createPasskeyRequest{Name: "Security key", Credential: registration})
+	expectStatus(t, rec, http.StatusCreated, "register")
+	created := decodeBody[mfaEnrollmentResponse](t, rec)
+	if created.Passkey == nil || created.Passkey.Name != "Security key" || len(created.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("created = %+v", created)
+	}
+	// Each challenge answers one ceremony.
+	expectStatus(t, browser.do(http.MethodPost, "/api/profile/mfa/passkeys", createPasskeyRequest{Name: "Again", Credential: registration}),
+		http.StatusPreconditionFailed, "reused challenge")
+
+	expectStatus(t, browser.do(http.MethodDelete, "/api/session", nil), http.StatusNoContent, "logout")
+	rec = browser.login()
+	expectStatus(t, rec, http.StatusAccepted, "second login")
+	if methods := decodeBody[mfaStatusResponse](t, rec).Methods; !slices.Contains(methods, mfaMethodWebAuthn) {
+		t.Fatalf("methods = %q", methods)
+	}
+	request := decodeBody[webauthn.RequestOptions](t, browser.do(http.MethodPost, "/api/session/mfa/webauthn/options", nil))
+	rec = browser.do(http.MethodPost, "/api/session/mfa/webauthn", mfaPasskeyRequest{Credential: authenticator.Assert(request, mfaTestOrigin)})
+	expectStatus(t, rec, http.StatusCreated, "passkey sign-in")
+	if ms.passkeys[0].SignCount != 2 || ms.passkeys[0].LastUsedAt == nil {
+		t.Fatalf("passkey after use = %+v", ms.passkeys[0])
+	}
+
+	expectStatus(t, browser.do(http.MethodDelete, "/api/profile/mfa/passkeys/"+ms.passkeys[0].ID, nil), http.StatusNoContent, "delete")
+	if len(ms.passkeys) != 0 || len(ms.recoveryCodeHashes) != 0 {
+		t.Fatalf("after removing the last factor passkeys = %d, recovery codes = %d", len(ms.passkeys), len(ms.recoveryCodeHashes))
+	}
+	if got, want := auditedActions(ms, "mfa."), []store.AuditAction{store.AuditMFAEnroll, store.AuditMFARemove}; !slices.Equal(got, want) {
+		t.Fatalf("audited %q, want %q", got, want)
+	}
+}
+
+func TestMFARequiredForcesEnrollment(t *testing.T) {
+	ms := &mockStore{mfaSettings: store.MFASettings{Required: true}}
+	browser, _ := newMFATestBrowser(t, ms)
+
+	rec := browser.login()
+	expectStatus(t, rec, http.StatusAccepted, "login")
+	if status := decodeBody[mfaStatusResponse](t, rec); status.State != string(store.SessionMFAEnrollment) || !status.Required {
+		t.Fatalf("status = %+v", status)
+	}
+	expectStatus(t, browser.do(http.MethodGet, "/api/tokens", nil), http.StatusForbidden, "tokens before enrollment")
+	expectStatus(t, browser.do(http.MethodGet, "/api/session", nil), http.StatusOK, "session before enrollment")
+
+	expectStatus(t, browser.do(http.MethodPost, "/api/profile/mfa/totp", nil), http.StatusCreated, "start totp")
+	enrolling := browser.cookie
+	code := auth.TOTPCode(ms.totpFactor.Secret, auth.TOTPStep(time.Now()))
+	expectStatus(t, browser.do(http.MethodPost, "/api/profile/mfa/totp/confirm", mfaCodeRequest{Code: code}), http.StatusOK, "confirm")
+	if browser.cookie == nil || browser.cookie.Value == enrolling.Value {
+		t.Fatal("enrollment did not issue a full session cookie")
+	}
+	expectStatus(t, browser.do(http.MethodGet, "/api/tokens", nil), http.StatusOK, "tokens after enrollment")
+
+	expectStatus(t, browser.do(http.MethodDelete, "/api/profile/mfa/totp", nil), http.StatusPreconditionFailed, "remove last factor")
+}
+
+func TestMFAEndpointsNeedBrowserSession(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "user-a", Role: auth.RoleUser, AuthMethod: "bearer"})
+	rec := httptest.NewRecorder()
+	h.StartTOTPEnrollment(rec, httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/profile/mfa/totp", nil))
+	expectStatus(t, rec, http.StatusPreconditionFailed, "bearer enrollment")
+
+	rec = httptest.NewRecorder()
+	h.SessionPasskeyOptions(rec, httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/session/mfa/webauthn/options", nil))
+	expectStatus(t, rec, http.StatusNotImplemented, "passkeys without a relying party")
+}
+
+func TestMFASessionAllows(t *testing.T) {
+	tests := []struct {
+		method, path string
+		pending      bool
+		enrollment   bool
+	}{
+		{http.MethodDelete, "/api/session", true, true},
+		{http.MethodGet, "/api/session/mfa", true, true},
+		{http.MethodPost, "/api/session/mfa/totp", true, true},
+		{http.MethodGet, "/api/session", false, true},
+		{http.MethodPost, "/api/profile/mfa/totp", false, true},
+		{http.MethodPut, "/api/session/tenant", false, false},
+		{http.MethodPatch, "/api/profile/password", false, false},
+		{http.MethodGet, "/api/profile/mfaextra", false, false},
+		{http.MethodGet, "/api/transactions", false, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequestWithContext(context.Background(), tt.method, tt.path, nil)
+		if got := mfaSessionAllows(store.SessionMFAPending, r); got != tt.pending {
+			t.Errorf("pending %s %s = %v, want %v", tt.method, tt.path, got, tt.pending)
+		}
+		if got := mfaSessionAllows(store.SessionMFAEnrollment, r); got != tt.enrollment {
+			t.Errorf("enrollment %s %s = %v, want %v", tt.method, tt.path, got, tt.enrollment)
+		}
+	}
+}
+
+func TestPatchMFASettings(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+	h.PatchMFASettings(rec, httptest.NewRequestWithContext(adminContext(), http.MethodPatch, "/api/admin/mfa/settings",
+		strings.NewReader(`{"required":true}`)))
+	expectStatus(t, rec, http.StatusOK, "patch")
+	if !ms.mfaSettings.Required || !decodeBody[MFASettingsResponse](t, rec).Required {
+		t.Fatalf("settings = %+v; body = %s", ms.mfaSettings, rec.Body.String())
+	}
+
+	userCtx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "user-a", Role: auth.RoleUser})
+	rec = httptest.NewRecorder()
+	h.GetMFASettings(rec, httptest.NewRequestWithContext(userCtx, http.MethodGet, "/api/admin/mfa/settings", nil))
+	expectStatus(t, rec, http.StatusForbidden, "non-admin")
+}
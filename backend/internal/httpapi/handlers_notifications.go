@@ -0,0 +1,305 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// GetNotificationPreferences handles GET /api/notifications/preferences.
+// @Summary Get what the current user is notified about
+// @Tags Notifications
+// @Produce json
+// @Success 200 {object} NotificationPreferencesResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/preferences [get]
+func (h *Handlers) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	prefs, err := h.notificationStore.GetNotificationPreferences(r.Context(), requestTenant(r), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, notificationPreferencesFromStore(prefs))
+}
+
+// UpdateNotificationPreferences handles PATCH /api/notifications/preferences.
+// Turning the weekly digest on schedules the first digest for its next
+// weekday and hour in the tenant's timezone.
+// @Summary Update what the current user is notified about
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param request body NotificationPreferencesPatchRequest true "Notification preferences patch"
+// @Success 200 {object} NotificationPreferencesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/preferences [patch]
+func (h *Handlers) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[NotificationPreferencesPatchRequest](h, w, r)
+	if !ok {
+		return
+	}
+	patch := store.NotificationPreferencesPatch{
+		WeeklyDigest:              body.WeeklyDigest,
+		DigestHour:                body.DigestHour,
+		LargeTransactionAlerts:    body.LargeTransactionAlerts,
+		LargeTransactionThreshold: body.LargeTransactionThreshold,
+		ScanAlerts:                body.ScanAlerts,
+	}
+	if body.DigestWeekday != nil {
+		weekday := time.Weekday(*body.DigestWeekday)
+		patch.DigestWeekday = &weekday
+	}
+	prefs, err := h.notificationStore.UpdateNotificationPreferences(r.Context(), requestTenant(r), principal.UserID, patch)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, notificationPreferencesFromStore(prefs))
+}
+
+// ListNotificationChannels handles GET /api/notifications/channels.
+// @Summary List the current user's notification channels
+// @Tags Notifications
+// @Produce json
+// @Success 200 {array} NotificationChannelResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/channels [get]
+func (h *Handlers) ListNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	channels, err := h.notificationStore.ListNotificationChannels(r.Context(), requestTenant(r), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := make([]NotificationChannelResponse, 0, len(channels))
+	for i := range channels {
+		resp = append(resp, notificationChannelFromStore(&channels[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateNotificationChannel handles POST /api/notifications/channels.
+// Tokens are encrypted at rest and never returned.
+// @Summary Add a notification channel
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param request body CreateNotificationChannelRequest true "Notification channel"
+// @Success 201 {object} NotificationChannelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/channels [post]
+func (h *Handlers) CreateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[CreateNotificationChannelRequest](h, w, r)
+	if !ok {
+		return
+	}
+	channelType := store.NotificationChannelType(body.Type)
+	if h.notifier != nil {
+		if err := h.notifier.Available(channelType); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		name = body.Type
+	}
+	input := store.NewNotificationChannel{
+		Type:    channelType,
+		Name:    name,
+		Target:  strings.TrimSpace(body.Target),
+		Token:   body.Token,
+		Enabled: body.Enabled == nil || *body.Enabled,
+	}
+	channel, err := h.notificationStore.CreateNotificationChannel(r.Context(), requestTenant(r), principal.UserID, input)
+	event := store.NewAuditEvent{Action: store.AuditNotificationChannelCreate, TargetType: "notification_channel"}
+	if err != nil {
+		h.audit(r, event, err)
+		writeError(w, r, err)
+		return
+	}
+	event.TargetID = channel.ID
+	h.audit(r, event, nil)
+	writeJSON(w, http.StatusCreated, notificationChannelFromStore(channel))
+}
+
+// UpdateNotificationChannel handles PATCH /api/notifications/channels/{id}.
+// Setting token to an empty string removes it.
+// @Summary Update a notification channel
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param id path string true "Notification channel ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Param request body NotificationChannelPatchRequest true "Notification channel patch"
+// @Success 200 {object} NotificationChannelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/channels/{id} [patch]
+func (h *Handlers) UpdateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := uuidPathValue(w, r, "id", "notification channel")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[NotificationChannelPatchRequest](h, w, r)
+	if !ok {
+		return
+	}
+	patch := store.NotificationChannelPatch{Token: body.Token, Enabled: body.Enabled}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			writeError(w, r, errors.E(errors.InvalidInput, errors.User("name must not be empty")))
+			return
+		}
+		patch.Name = &name
+	}
+	if body.Target != nil {
+		target := strings.TrimSpace(*body.Target)
+		patch.Target = &target
+	}
+	channel, err := h.notificationStore.UpdateNotificationChannel(r.Context(), requestTenant(r), principal.UserID, id, patch)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditNotificationChannelUpdate, TargetType: "notification_channel", TargetID: id}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, notificationChannelFromStore(channel))
+}
+
+// DeleteNotificationChannel handles DELETE /api/notifications/channels/{id},
+// discarding notifications still queued for it.
+// @Summary Delete a notification channel
+// @Tags Notifications
+// @Param id path string true "Notification channel ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/channels/{id} [delete]
+func (h *Handlers) DeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := uuidPathValue(w, r, "id", "notification channel")
+	if !ok {
+		return
+	}
+	err := h.notificationStore.DeleteNotificationChannel(r.Context(), requestTenant(r), principal.UserID, id)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditNotificationChannelDelete, TargetType: "notification_channel", TargetID: id}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestNotificationChannel handles POST /api/notifications/channels/{id}/test,
+// sending a test notification right away, even through a disabled channel.
+// @Summary Send a test notification
+// @Tags Notifications
+// @Param id path string true "Notification channel ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /notifications/channels/{id}/test [post]
+func (h *Handlers) TestNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := uuidPathValue(w, r, "id", "notification channel")
+	if !ok {
+		return
+	}
+	if h.notifier == nil {
+		writeError(w, r, errors.E(errors.Unavailable, errors.User("notifications are not available")))
+		return
+	}
+	channel, token, err := h.notificationStore.OpenNotificationChannel(r.Context(), requestTenant(r), principal.UserID, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.notifier.SendTest(r.Context(), *channel, token); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func notificationPreferencesFromStore(prefs *store.NotificationPreferences) NotificationPreferencesResponse {
+	resp := NotificationPreferencesResponse{
+		WeeklyDigest:              prefs.WeeklyDigest,
+		DigestWeekday:             int(prefs.DigestWeekday),
+		DigestHour:                prefs.DigestHour,
+		LargeTransactionAlerts:    prefs.LargeTransactionAlerts,
+		LargeTransactionThreshold: prefs.LargeTransactionThreshold,
+		ScanAlerts:                prefs.ScanAlerts,
+	}
+	if !prefs.UpdatedAt.IsZero() {
+		updatedAt := prefs.UpdatedAt
+		resp.UpdatedAt = &updatedAt
+	}
+	return resp
+}
+
+func notificationChannelFromStore(channel *store.NotificationChannel) NotificationChannelResponse {
+	return NotificationChannelResponse{
+		ID:        channel.ID,
+		Type:      string(channel.Type),
+		Name:      channel.Name,
+		Target:    channel.Target,
+		HasToken:  channel.HasToken,
+		Enabled:   channel.Enabled,
+		CreatedAt: channel.CreatedAt,
+		UpdatedAt: channel.UpdatedAt,
+	}
+}
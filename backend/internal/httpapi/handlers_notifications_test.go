@@ -0,0 +1,211 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const testNotificationChannelID = "77777777-7777-7777-7777-777777777777"
+
+type mockNotifier struct {
+	unavailable error
+	sendErr     error
+	sent        []store.NotificationChannel
+	tokens      []string
+}
+
+func (n *mockNotifier) Available(channelType store.NotificationChannelType) error {
+	if channelType == store.NotificationChannelEmail {
+		return n.unavailable
+	}
+	return nil
+}
+
+func (n *mockNotifier) SendTest(_ context.Context, channel store.NotificationChannel, token string) error {
+	n.sent = append(n.sent, channel)
+	n.tokens = append(n.tokens, token)
+	return n.sendErr
+}
+
+func notificationRequest(userID, method, target, body string) *http.Request {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: userID, TenantID: "tenant-a", Role: auth.RoleUser})
+	req := httptest.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	req.SetPathValue("id", testNotificationChannelID)
+	return req
+}
+
+func TestNotificationPreferencesDefaultAndUpdate(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+
+	rec := httptest.NewRecorder()
+	h.GetNotificationPreferences(rec, notificationRequest("user-a", http.MethodGet, "/api/notifications/preferences", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	var prefs NotificationPreferencesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&prefs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if prefs.WeeklyDigest || prefs.DigestWeekday != 1 || prefs.DigestHour != 9 || prefs.UpdatedAt != nil {
+		t.Fatalf("default preferences = %#v", prefs)
+	}
+
+	rec = httptest.NewRecorder()
+	h.UpdateNotificationPreferences(rec, notificationRequest("user-a", http.MethodPatch, "/api/notifications/preferences",
+		`{"weekly_digest":true,"digest_weekday":0,"digest_hour":20}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if patch := ms.notificationPrefsPatch; patch.DigestWeekday == nil || *patch.DigestWeekday != time.Sunday || *patch.DigestHour != 20 {
+		t.Fatalf("patch = %#v", patch)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&prefs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !prefs.WeeklyDigest || prefs.DigestWeekday != 0 || prefs.DigestHour != 20 || prefs.UpdatedAt == nil {
+		t.Fatalf("updated preferences = %#v", prefs)
+	}
+}
+
+func TestUpdateNotificationPreferencesRejectsInvalidSchedule(t *testing.T) {
+	for name, body := range map[string]string{
+		"weekday":   `{"digest_weekday":7}`,
+		"hour":      `{"digest_hour":24}`,
+		"threshold": `{"large_transaction_threshold":0}`,
+	} {
+		ms := &mockStore{}
+		h := newTestHandlers(t, ms, &mockDaemon{})
+		rec := httptest.NewRecorder()
+		h.UpdateNotificationPreferences(rec, notificationRequest("user-a", http.MethodPatch, "/api/notifications/preferences", body))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want 422; body = %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestCreateNotificationChannelHidesToken(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.CreateNotificationChannel(rec, notificationRequest("user-a", http.MethodPost, "/api/notifications/channels",
+		`{"type":"gotify","target":" https://gotify.local ","token":"app-token-123"}`))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "app-token-123") {
+		t.Fatalf("body = %s, want the token left out", rec.Body.String())
+	}
+	created := ms.createdNotificationChannel
+	if created.Name != "gotify" || created.Target != "https://gotify.local" || created.Token != "app-token-123" || !created.Enabled {
+		t.Fatalf("created = %#v", created)
+	}
+	var resp NotificationChannelResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.HasToken || resp.ID != testNotificationChannelID {
+		t.Fatalf("response = %#v", resp)
+	}
+	if len(ms.auditEvents) != 1 || ms.auditEvents[0].Action != store.AuditNotificationChannelCreate ||
+		ms.auditEvents[0].TargetID != testNotificationChannelID {
+		t.Fatalf("audit events = %#v", ms.auditEvents)
+	}
+}
+
+func TestCreateNotificationChannelRejectsInvalidInput(t *testing.T) {
+	for name, body := range map[string]string{
+		"type":      `{"type":"pager","target":"https://example.test"}`,
+		"no target": `{"type":"ntfy"}`,
+	} {
+		ms := &mockStore{}
+		h := newTestHandlers(t, ms, &mockDaemon{})
+		rec := httptest.NewRecorder()
+		h.CreateNotificationChannel(rec, notificationRequest("user-a", http.MethodPost, "/api/notifications/channels", body))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want 422; body = %s", name, rec.Code, rec.Body.String())
+		}
+		if ms.notificationChannels != nil {
+			t.Errorf("%s: channel was created", name)
+		}
+	}
+}
+
+func TestCreateEmailChannelNeedsSMTPServer(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.notifier = &mockNotifier{unavailable: errors.E(errors.FailedPrecondition, errors.User("email notifications need an SMTP server"))}
+	rec := httptest.NewRecorder()
+
+	h.CreateNotificationChannel(rec, notificationRequest("user-a", http.MethodPost, "/api/notifications/channels",
+		`{"type":"email","target":"alex@example.com"}`))
+
+	if rec.Code != http.StatusPreconditionFailed || ms.notificationChannels != nil {
+		t.Fatalf("status = %d, want 412 without a channel; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNotificationChannelsAreUserScoped(t *testing.T) {
+	ms := &mockStore{notificationChannels: map[string]*store.NotificationChannel{
+		testNotificationChannelID: {ID: testNotificationChannelID, TenantID: "tenant-a", UserID: "user-b", Type: store.NotificationChannelNtfy},
+	}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.notifier = &mockNotifier{}
+
+	rec := httptest.NewRecorder()
+	h.ListNotificationChannels(rec, notificationRequest("user-a", http.MethodGet, "/api/notifications/channels", ""))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("list status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	for name, call := range map[string]func(http.ResponseWriter, *http.Request){
+		"patch": h.UpdateNotificationChannel, "delete": h.DeleteNotificationChannel, "test": h.TestNotificationChannel,
+	} {
+		rec := httptest.NewRecorder()
+		call(rec, notificationRequest("user-a", http.MethodPatch, "/api/notifications/channels/"+testNotificationChannelID, `{"enabled":false}`))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want 404; body = %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if ms.notificationChannels[testNotificationChannelID] == nil {
+		t.Fatal("other user's channel was deleted")
+	}
+}
+
+func TestTestNotificationChannelSendsWithToken(t *testing.T) {
+	ms := &mockStore{
+		notificationChannels: map[string]*store.NotificationChannel{
+			testNotificationChannelID: {ID: testNotificationChannelID, TenantID: "tenant-a", UserID: "user-a", Type: store.NotificationChannelTelegram},
+		},
+		notificationTokens: map[string]string{testNotificationChannelID: "123:bot-token"},
+	}
+	notifier := &mockNotifier{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.notifier = notifier
+
+	rec := httptest.NewRecorder()
+	h.TestNotificationChannel(rec, notificationRequest("user-a", http.MethodPost, "/api/notifications/channels/"+testNotificationChannelID+"/test", ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204; body = %s", rec.Code, rec.Body.String())
+	}
+	if len(notifier.sent) != 1 || notifier.tokens[0] != "123:bot-token" {
+		t.Fatalf("sent = %#v, tokens = %q", notifier.sent, notifier.tokens)
+	}
+
+	notifier.sendErr = errors.E(errors.BadGateway, errors.User("sending the test notification failed: telegram responded 400 Bad Request"))
+	rec = httptest.NewRecorder()
+	h.TestNotificationChannel(rec, notificationRequest("user-a", http.MethodPost, "/api/notifications/channels/"+testNotificationChannelID+"/test", ""))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "telegram responded") {
+		t.Fatalf("status = %d, want 502; body = %s", rec.Code, rec.Body.String())
+	}
+}
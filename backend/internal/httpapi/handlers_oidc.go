@@ -0,0 +1,281 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/oidc"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const oidcStateCookieName = "expensor_oidc_state"
+
+// Error codes appended to the frontend login URL as ?oidc_error= when
+// single sign-on fails. Details are only logged.
+const (
+	oidcErrorState       = "state"
+	oidcErrorDenied      = "denied"
+	oidcErrorUnavailable = "unavailable"
+	oidcErrorRejected    = "rejected"
+	oidcErrorNoAccount   = "no_account"
+	oidcErrorForbidden   = "forbidden"
+	oidcErrorInternal    = "internal"
+)
+
+// oidcStateEntry holds a pending single sign-on attempt.
+type oidcStateEntry struct {
+	request   oidc.AuthRequest
+	returnTo  string
+	expiresAt time.Time
+}
+
+type oidcStatusResponse struct {
+	Enabled      bool   `json:"enabled"`
+	ProviderName string `json:"provider_name,omitempty" example:"Authentik"`
+}
+
+// GetOIDCStatus reports whether single sign-on is configured so the login
+// page can offer it.
+// @Summary Get single sign-on availability
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} oidcStatusResponse
+// @Router /auth/oidc [get]
+func (h *Handlers) GetOIDCStatus(w http.ResponseWriter, _ *http.Request) {
+	if h.oidc == nil {
+		writeJSON(w, http.StatusOK, oidcStatusResponse{})
+		return
+	}
+	writeJSON(w, http.StatusOK, oidcStatusResponse{Enabled: true, ProviderName: h.oidc.Name()})
+}
+
+// OIDCLogin starts single sign-on by redirecting the browser to the identity
+// provider. return_to is a frontend path to land on after signing in.
+// @Summary Start single sign-on
+// @Tags Auth
+// @Param return_to query string false "Frontend path to open after signing in" example(/transactions)
+// @Success 302
+// @Failure 501 {object} ErrorResponse
+// @Router /auth/oidc/login [get]
+func (h *Handlers) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if !h.requireOIDC(w, r) {
+		return
+	}
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		h.oidcFailure(w, r, oidcErrorInternal, err)
+		return
+	}
+	authURL, err := h.oidc.AuthCodeURL(r.Context(), h.oidcRedirectURL(), req)
+	if err != nil {
+		h.oidcFailure(w, r, oidcErrorUnavailable, err)
+		return
+	}
+
+	h.mu.Lock()
+	for k, v := range h.oidcStates {
+		if time.Now().After(v.expiresAt) {
+			delete(h.oidcStates, k)
+		}
+	}
+	h.oidcStates[req.State] = oidcStateEntry{
+		request:   req,
+		returnTo:  oidcReturnPath(r.URL.Query().Get("return_to")),
+		expiresAt: time.Now().Add(oauthStateTTL),
+	}
+	h.mu.Unlock()
+
+	// The cookie ties the state to this browser so a callback URL carrying
+	// someone else's code cannot sign the victim into the attacker's account.
+	http.SetCookie(w, oidcStateCookie(r, req.State, int(oauthStateTTL.Seconds())))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes single sign-on. The user is matched by the
+// provider's subject or, on first sign-in, by verified email, and created
+// then when auto-provisioning is enabled. On success the browser gets a
+// session cookie and is sent back to the frontend; on failure it lands on
+// the login page with an oidc_error code.
+// @Summary Complete single sign-on
+// @Tags Auth
+// @Param state query string true "OIDC state"
+// @Param code query string true "OIDC authorization code"
+// @Success 302
+// @Failure 501 {object} ErrorResponse
+// @Router /auth/oidc/callback [get]
+func (h *Handlers) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if !h.requireOIDC(w, r) {
+		return
+	}
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	h.mu.Lock()
+	entry, ok := h.oidcStates[state]
+	if ok {
+		delete(h.oidcStates, state)
+	}
+	h.mu.Unlock()
+	http.SetCookie(w, oidcStateCookie(r, "", -1))
+
+	// Every outcome of a callback is an attempt to sign in, so all of them
+	// are audited.
+	event := store.NewAuditEvent{Action: store.AuditLogin, AuthMethod: "oidc"}
+	fail := func(code string, err error) {
+		h.audit(r, event, err)
+		h.oidcFailure(w, r, code, err)
+	}
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if !ok || time.Now().After(entry.expiresAt) || err != nil || cookie.Value != state {
+		fail(oidcErrorState, errors.E("httpapi.oidc_callback", errors.InvalidArgument, "invalid or expired OIDC state"))
+		return
+	}
+	if providerErr := query.Get("error"); providerErr != "" {
+		fail(oidcErrorDenied, errors.E("httpapi.oidc_callback", errors.Unauthenticated,
+			"identity provider returned "+providerErr+": "+query.Get("error_description")))
+		return
+	}
+
+	identity, err := h.oidc.Exchange(r.Context(), h.oidcRedirectURL(), query.Get("code"), entry.request)
+	if err != nil {
+		code := oidcErrorRejected
+		if errors.WhatKind(err) == errors.Unavailable || errors.WhatKind(err) == errors.FailedPrecondition {
+			code = oidcErrorUnavailable
+		}
+		fail(code, err)
+		return
+	}
+	event.TargetType, event.TargetID = "email", identity.Email
+	user, err := h.oidcUser(r.Context(), identity)
+	if err != nil {
+		code := oidcErrorInternal
+		switch errors.WhatKind(err) {
+		case errors.NotFound:
+			code = oidcErrorNoAccount
+		case errors.PermissionDenied:
+			code = oidcErrorForbidden
+		}
+		fail(code, err)
+		return
+	}
+	event.ActorUserID, event.TenantID = user.ID, user.TenantID
+	h.audit(r, event, nil)
+	if !h.createSessionCookie(w, r, user) {
+		return
+	}
+	h.logger.Info("OIDC login", "user_id", user.ID, "subject", identity.Subject)
+	http.Redirect(w, r, h.frontendURL+entry.returnTo, http.StatusFound)
+}
+
+// oidcUser finds the account linked to identity, linking or provisioning
+// one by email on first sign-in, and applies the provider's role mapping to
+// it.
+func (h *Handlers) oidcUser(ctx context.Context, identity oidc.Identity) (*store.User, error) {
+	const op = "httpapi.oidc_user"
+	user, err := h.authStore.FindUserByOIDCSubject(ctx, identity.Issuer, identity.Subject)
+	if errors.WhatKind(err) == errors.NotFound {
+		user, err = h.linkOIDCUser(ctx, identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, errors.E(op, errors.PermissionDenied, "account "+user.ID+" is disabled")
+	}
+	if identity.Admin == nil {
+		return user, nil
+	}
+	role := oidcRole(*identity.Admin)
+	if user.Role == role {
+		return user, nil
+	}
+	return h.authStore.UpdateUser(ctx, user.ID, store.UpdateUserInput{Role: &role})
+}
+
+// linkOIDCUser links identity to the account with its email, provisioning one
+// when that is allowed. Some providers let users claim any address, so the
+// email only counts when the provider verified it, and an account already
+// linked to another identity of the provider is never taken over.
+func (h *Handlers) linkOIDCUser(ctx context.Context, identity oidc.Identity) (*store.User, error) {
+	const op = "httpapi.link_oidc_user"
+	if !identity.EmailVerified {
+		return nil, errors.E(op, errors.PermissionDenied, "provider did not verify "+identity.Email)
+	}
+	user, err := h.authStore.FindUserByEmail(ctx, identity.Email)
+	if err != nil {
+		if errors.WhatKind(err) != errors.NotFound {
+			return nil, err
+		}
+		if !h.oidcAutoProvision {
+			return nil, errors.E(op, errors.NotFound, "no account for "+identity.Email)
+		}
+		if user, err = h.provisionUser(ctx, "oidc", identity.Email, identity.DisplayName, identity.Admin); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.authStore.LinkOIDCSubject(ctx, user.ID, identity.Issuer, identity.Subject); err != nil {
+		if errors.WhatKind(err) != errors.Conflict {
+			return nil, err
+		}
+		// A concurrent first sign-in of the same identity may have linked it.
+		if linked, findErr := h.authStore.FindUserByOIDCSubject(ctx, identity.Issuer, identity.Subject); findErr == nil && linked.ID == user.ID {
+			return linked, nil
+		}
+		return nil, errors.E(op, errors.PermissionDenied, "account "+user.ID+" is linked to another identity", err)
+	}
+	h.logger.Info("OIDC identity linked", "user_id", user.ID, "subject", identity.Subject)
+	return user, nil
+}
+
+func (h *Handlers) requireOIDC(w http.ResponseWriter, r *http.Request) bool {
+	if h.oidc == nil {
+		writeError(w, r, errors.E(errors.Unimplemented, errors.User("single sign-on not configured")))
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) oidcRedirectURL() string {
+	return h.baseURL + "/api/auth/oidc/callback"
+}
+
+func (h *Handlers) oidcFailure(w http.ResponseWriter, r *http.Request, code string, err error) {
+	h.logger.Warn("OIDC login failed", "reason", code, "error", err)
+	http.Redirect(w, r, h.frontendURL+"/login?oidc_error="+url.QueryEscape(code), http.StatusFound)
+}
+
+func oidcStateCookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil,
+	}
+}
+
+// oidcReturnPath keeps return_to on the frontend: anything but a plain
+// absolute path would make the callback an open redirect.
+func oidcReturnPath(raw string) string {
+	if !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.Contains(raw, `\`) {
+		return "/"
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+		return "/"
+	}
+	return raw
+}
+
+func oidcRole(admin bool) store.UserRole {
+	if admin {
+		return store.UserRoleAdmin
+	}
+	return store.UserRoleUser
+}
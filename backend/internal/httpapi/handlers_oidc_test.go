@@ -0,0 +1,289 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/oidc"
+	"github.com/ArionMiles/expensor/backend/internal/oidc/oidctest"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+func newOIDCTestHandlers(t *testing.T, ms *mockStore, autoProvision bool) (*Handlers, *oidctest.Server) {
+	t.Helper()
+	srv := oidctest.NewServer(t, "expensor", "secret")
+	provider, err := oidc.New(oidc.Config{
+		Issuer:       srv.URL,
+		ClientID:     srv.ClientID,
+		ClientSecret: srv.ClientSecret,
+		Name:         "Authentik",
+		Claims:       oidc.ClaimMapping{Role: "groups", AdminValues: []string{"expensor-admins"}},
+	})
+	if err != nil {
+		t.Fatalf("oidc.New: %v", err)
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	h.oidc = provider
+	h.oidcAutoProvision = autoProvision
+	return h, srv
+}
+
+// oidcSignIn runs the browser through login, the provider and the callback,
+// and returns the callback response.
+func oidcSignIn(t *testing.T, h *Handlers, srv *oidctest.Server, returnTo string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.OIDCLogin(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/api/auth/oidc/login?return_to="+url.QueryEscape(returnTo), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want 302; body = %s", rec.Code, rec.Body.String())
+	}
+	stateCookie := findCookie(rec.Result().Cookies(), oidcStateCookieName)
+	if stateCookie == nil || !stateCookie.HttpOnly {
+		t.Fatalf("state cookie = %#v", stateCookie)
+	}
+	callback, err := srv.Login(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("provider login: %v", err)
+	}
+	if !strings.HasPrefix(callback.String(), "http://localhost:8080/api/auth/oidc/callback?") {
+		t.Fatalf("callback URL = %s", callback)
+	}
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	rec = httptest.NewRecorder()
+	h.OIDCCallback(rec, req)
+	return rec
+}
+
+func TestGetOIDCStatus(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	rec := httptest.NewRecorder()
+	h.GetOIDCStatus(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/auth/oidc", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"enabled":false}` {
+		t.Fatalf("disabled status = %d %s", rec.Code, rec.Body.String())
+	}
+
+	h, _ = newOIDCTestHandlers(t, &mockStore{}, false)
+	rec = httptest.NewRecorder()
+	h.GetOIDCStatus(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/auth/oidc", nil))
+	var resp oidcStatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !resp.Enabled || resp.ProviderName != "Authentik" {
+		t.Fatalf("enabled status = %s, err = %v", rec.Body.String(), err)
+	}
+}
+
+func TestOIDCLoginSignsInExistingUser(t *testing.T) {
+	user := &store.User{ID: "user-a", TenantID: "user-a", Email: "asha@example.com", Role: store.UserRoleUser, AvatarKey: "default"}
+	ms := &mockStore{usersByEmail: map[string]*store.User{user.Email: user}}
+	h, srv := newOIDCTestHandlers(t, ms, false)
+	srv.SetClaims(map[string]any{
+		"sub": "abc", "email": "Asha@example.com", "email_verified": true, "name": "Asha", "groups": []string{"expensor-admins"},
+	})
+
+	rec := oidcSignIn(t, h, srv, "/transactions?view=list")
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "http://localhost:5173/transactions?view=list" {
+		t.Fatalf("callback status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if cookie := findCookie(rec.Result().Cookies(), sessionCookieName); cookie == nil || cookie.Value == "" {
+		t.Fatalf("session cookie = %#v", cookie)
+	}
+	if ms.createdSession.UserID != user.ID {
+		t.Fatalf("session user = %q, want %q", ms.createdSession.UserID, user.ID)
+	}
+	if ms.updatedUserID != user.ID || ms.updatedUser.Role == nil || *ms.updatedUser.Role != store.UserRoleAdmin {
+		t.Fatalf("role sync = %q %#v, want admin", ms.updatedUserID, ms.updatedUser)
+	}
+	if linked := ms.oidcLinks[srv.URL+" abc"]; linked != user.ID {
+		t.Fatalf("linked user = %q, want %q", linked, user.ID)
+	}
+	if len(ms.auditEvents) != 1 {
+		t.Fatalf("audit events = %#v, want one", ms.auditEvents)
+	}
+	if got := ms.auditEvents[0]; got.Action != store.AuditLogin || got.AuthMethod != "oidc" || got.ActorUserID != user.ID ||
+		got.TargetID != "asha@example.com" || got.Outcome != store.AuditSuccess {
+		t.Fatalf("audit event = %#v", got)
+	}
+}
+
+func TestOIDCLoginMatchesLinkedIdentity(t *testing.T) {
+	user := &store.User{ID: "user-a", TenantID: "user-a", Email: "asha@example.com", Role: store.UserRoleUser, AvatarKey: "default"}
+	other := &store.User{ID: "user-b", TenantID: "user-b", Email: "ravi@example.com", Role: store.UserRoleAdmin, AvatarKey: "default"}
+
+	t.Run("signs in by subject", func(t *testing.T) {
+		ms := &mockStore{usersByID: map[string]*store.User{user.ID: user}}
+		h, srv := newOIDCTestHandlers(t, ms, false)
+		ms.oidcLinks = map[string]string{srv.URL + " abc": user.ID}
+		// The provider account changed its email since it was linked.
+		srv.SetClaims(map[string]any{"sub": "abc", "email": "asha@elsewhere.example"})
+
+		rec := oidcSignIn(t, h, srv, "/")
+
+		if rec.Code != http.StatusFound || ms.createdSession.UserID != user.ID {
+			t.Fatalf("status = %d, location = %q, session user = %q", rec.Code, rec.Header().Get("Location"), ms.createdSession.UserID)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		links  func(issuer string) map[string]string
+	}{
+		{
+			name:   "unverified email",
+			claims: map[string]any{"sub": "abc", "email": other.Email},
+		},
+		{
+			name:   "account linked to another identity",
+			claims: map[string]any{"sub": "abc", "email": other.Email, "email_verified": true},
+			links:  func(issuer string) map[string]string { return map[string]string{issuer + " def": other.ID} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &mockStore{usersByEmail: map[string]*store.User{other.Email: other}}
+			h, srv := newOIDCTestHandlers(t, ms, true)
+			if tt.links != nil {
+				ms.oidcLinks = tt.links(srv.URL)
+			}
+			srv.SetClaims(tt.claims)
+
+			rec := oidcSignIn(t, h, srv, "/")
+
+			if got := rec.Header().Get("Location"); got != "http://localhost:5173/login?oidc_error=forbidden" {
+				t.Fatalf("location = %q", got)
+			}
+			if ms.createdSession.UserID != "" {
+				t.Fatalf("created session %#v for an identity that does not own the account", ms.createdSession)
+			}
+			if len(ms.auditEvents) != 1 || ms.auditEvents[0].ActorUserID != "" || ms.auditEvents[0].TargetID != other.Email ||
+				ms.auditEvents[0].Outcome != store.AuditDenied {
+				t.Fatalf("audit events = %#v", ms.auditEvents)
+			}
+		})
+	}
+}
+
+func TestOIDCAutoProvisioning(t *testing.T) {
+	t.Run("creates a user", func(t *testing.T) {
+		ms := &mockStore{}
+		h, srv := newOIDCTestHandlers(t, ms, true)
+		srv.SetClaims(map[string]any{"sub": "abc", "email": "ravi@example.com", "email_verified": true, "groups": []string{"family"}})
+
+		rec := oidcSignIn(t, h, srv, "")
+
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "http://localhost:5173/" {
+			t.Fatalf("callback status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+		}
+		created := ms.createdUser
+		if created.Email != "ravi@example.com" || created.DisplayName != "ravi" || created.Role != store.UserRoleUser || created.PasswordHash != "" {
+			t.Fatalf("created user = %#v", created)
+		}
+		if ms.createdSession.UserID != "user-id" {
+			t.Fatalf("session user = %q", ms.createdSession.UserID)
+		}
+	})
+
+	t.Run("bootstraps the first admin", func(t *testing.T) {
+		ms := &mockStore{bootstrapRequired: true}
+		h, srv := newOIDCTestHandlers(t, ms, true)
+		srv.SetClaims(map[string]any{"sub": "abc", "email": "asha@example.com", "email_verified": true, "name": "Asha", "groups": "expensor-admins"})
+
+		rec := oidcSignIn(t, h, srv, "/")
+
+		if rec.Code != http.StatusFound || ms.createdBootstrapAdmin.Email != "asha@example.com" || ms.createdUser.Email != "" {
+			t.Fatalf("status = %d, bootstrap = %#v, user = %#v", rec.Code, ms.createdBootstrapAdmin, ms.createdUser)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ms := &mockStore{}
+		h, srv := newOIDCTestHandlers(t, ms, false)
+		srv.SetClaims(map[string]any{"sub": "abc", "email": "ravi@example.com", "email_verified": true})
+
+		rec := oidcSignIn(t, h, srv, "/")
+
+		if got := rec.Header().Get("Location"); got != "http://localhost:5173/login?oidc_error=no_account" {
+			t.Fatalf("location = %q", got)
+		}
+		if ms.createdUser.Email != "" || ms.createdSession.UserID != "" {
+			t.Fatalf("created user %#v / session %#v without auto-provisioning", ms.createdUser, ms.createdSession)
+		}
+	})
+}
+
+func TestOIDCCallbackRejectsDisabledUser(t *testing.T) {
+	disabledAt := time.Now()
+	user := &store.User{ID: "user-a", Email: "asha@example.com", Role: store.UserRoleUser, DisabledAt: &disabledAt}
+	ms := &mockStore{usersByEmail: map[string]*store.User{user.Email: user}}
+	h, srv := newOIDCTestHandlers(t, ms, true)
+	srv.SetClaims(map[string]any{"sub": "abc", "email": "asha@example.com", "email_verified": true})
+
+	rec := oidcSignIn(t, h, srv, "/")
+
+	if got := rec.Header().Get("Location"); got != "http://localhost:5173/login?oidc_error=forbidden" {
+		t.Fatalf("location = %q", got)
+	}
+	if ms.createdSession.UserID != "" {
+		t.Fatalf("created session for disabled user: %#v", ms.createdSession)
+	}
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	ms := &mockStore{}
+	h, srv := newOIDCTestHandlers(t, ms, true)
+	rec := httptest.NewRecorder()
+	h.OIDCLogin(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/auth/oidc/login", nil))
+	callback, err := srv.Login(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("provider login: %v", err)
+	}
+
+	// A callback URL opened in another browser carries no state cookie.
+	rec = httptest.NewRecorder()
+	h.OIDCCallback(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, callback.RequestURI(), nil))
+
+	if got := rec.Header().Get("Location"); got != "http://localhost:5173/login?oidc_error=state" {
+		t.Fatalf("location = %q", got)
+	}
+	if ms.createdSession.UserID != "" {
+		t.Fatalf("created session without state cookie: %#v", ms.createdSession)
+	}
+}
+
+func TestOIDCEndpointsWithoutProvider(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	for name, handler := range map[string]http.HandlerFunc{"login": h.OIDCLogin, "callback": h.OIDCCallback} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/auth/oidc/"+name, nil))
+		if rec.Code != http.StatusNotImplemented {
+			t.Fatalf("%s status = %d, want 501", name, rec.Code)
+		}
+	}
+}
+
+func TestOIDCReturnPath(t *testing.T) {
+	tests := map[string]string{
+		"":                        "/",
+		"/transactions?page=2":    "/transactions?page=2",
+		"//evil.example/path":     "/",
+		"/\\evil.example":         "/",
+		"https://evil.example":    "/",
+		"transactions":            "/",
+		"/ok#fragment":            "/ok#fragment",
+		"/%0d%0aLocation:%20evil": "/%0d%0aLocation:%20evil",
+	}
+	for raw, want := range tests {
+		if got := oidcReturnPath(raw); got != want {
+			t.Errorf("oidcReturnPath(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
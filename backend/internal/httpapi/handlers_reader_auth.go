@@ -14,6 +14,7 @@ import (
 
 	"golang.org/x/oauth2"
 
+	"github.com/ArionMiles/expensor/backend/internal/auth"
 	"github.com/ArionMiles/expensor/backend/internal/daemon"
 	"github.com/ArionMiles/expensor/backend/internal/oauth"
 	"github.com/ArionMiles/expensor/backend/internal/plugins"
@@ -89,7 +90,9 @@ func (h *Handlers) UploadCredentials(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.readerRuntimeStore.SetReaderSecret(r.Context(), requestTenant(r), name, body); err != nil {
+	err = h.readerRuntimeStore.SetReaderSecret(r.Context(), requestTenant(r), name, body)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditReaderCredentials, TargetType: "reader", TargetID: name}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -195,9 +198,11 @@ func (h *Handlers) AuthStart(w http.ResponseWriter, r *http.Request) {
 			delete(h.oauthStates, k)
 		}
 	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
 	h.oauthStates[state] = oauthStateEntry{
 		readerName: name,
 		tenant:     tenant,
+		userID:     principal.UserID,
 		expiresAt:  time.Now().Add(oauthStateTTL),
 	}
 	h.mu.Unlock()
@@ -300,7 +305,11 @@ func (h *Handlers) AuthCallback(w http.ResponseWriter, r *http.Request) {
 		writeError(w, r, errors.E(errors.InvalidArgument, errors.User("invalid or expired OAuth state")))
 		return
 	}
-	if err := h.exchangeAndSaveToken(r.Context(), tenant, name, code, redirectURL); err != nil {
+	err := h.exchangeAndSaveToken(r.Context(), tenant, name, code, redirectURL)
+	h.audit(r, store.NewAuditEvent{
+		ActorUserID: entry.userID, TenantID: tenant.ID, Action: store.AuditReaderConnect, TargetType: "reader", TargetID: name,
+	}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -394,7 +403,11 @@ func (h *Handlers) AuthExchange(w http.ResponseWriter, r *http.Request) {
 		writeError(w, r, errors.E(errors.InvalidArgument, errors.User("invalid or expired OAuth state")))
 		return
 	}
-	if err := h.exchangeAndSaveToken(r.Context(), tenant, name, code, redirectURL); err != nil {
+	err = h.exchangeAndSaveToken(r.Context(), tenant, name, code, redirectURL)
+	h.audit(r, store.NewAuditEvent{
+		ActorUserID: entry.userID, TenantID: tenant.ID, Action: store.AuditReaderConnect, TargetType: "reader", TargetID: name,
+	}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -558,7 +571,9 @@ func (h *Handlers) DisconnectReader(w http.ResponseWriter, r *http.Request) {
 		writeError(w, r, err)
 		return
 	}
-	if err := h.readerRuntimeStore.DeleteReaderRuntime(r.Context(), tenant, name); err != nil {
+	err = h.readerRuntimeStore.DeleteReaderRuntime(r.Context(), tenant, name)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditReaderDisconnect, TargetType: "reader", TargetID: name}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -600,7 +615,9 @@ func (h *Handlers) RevokeToken(w http.ResponseWriter, r *http.Request) {
 		writeError(w, r, errors.E(errors.NotFound, errors.User("no token found")))
 		return
 	}
-	if err := h.readerRuntimeStore.DeleteReaderToken(r.Context(), requestTenant(r), name); err != nil {
+	err := h.readerRuntimeStore.DeleteReaderToken(r.Context(), requestTenant(r), name)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditReaderTokenRevoke, TargetType: "reader", TargetID: name}, err)
+	if err != nil {
 		writeError(w, r, err)
 		return
 	}
@@ -47,29 +47,33 @@ func (h *Handlers) PatchScanningSettings(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	tenant := requestTenant(r)
+	err := h.applyScanningSettings(r.Context(), tenant, body)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditScanningSettings, TargetType: "tenant", TargetID: tenant.ID}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.GetScanningSettings(w, r)
+}
+
+func (h *Handlers) applyScanningSettings(ctx context.Context, tenant store.Tenant, body ScanningSettingsPatchRequest) error {
 	if body.ActiveReader != nil {
 		reader := strings.TrimSpace(*body.ActiveReader)
 		if reader != "" {
 			if _, err := h.registry.GetProvider(reader); err != nil {
-				writeError(w, r, errors.E(errors.InvalidArgument, errors.User(fmt.Sprintf("reader %q not found", reader)), err))
-				return
+				return errors.E(errors.InvalidArgument, errors.User(fmt.Sprintf("reader %q not found", reader)), err)
 			}
-			if err := h.scanningStore.SetActiveScanningReader(r.Context(), tenant, reader); err != nil {
-				writeError(w, r, err)
-				return
+			if err := h.scanningStore.SetActiveScanningReader(ctx, tenant, reader); err != nil {
+				return err
 			}
-		} else if err := h.scanningStore.ClearActiveScanningReader(r.Context(), tenant); err != nil {
-			writeError(w, r, err)
-			return
+		} else if err := h.scanningStore.ClearActiveScanningReader(ctx, tenant); err != nil {
+			return err
 		}
 	}
 	if body.Enabled != nil {
-		if err := h.applyScanningEnabled(r.Context(), tenant, *body.Enabled); err != nil {
-			writeError(w, r, err)
-			return
-		}
+		return h.applyScanningEnabled(ctx, tenant, *body.Enabled)
 	}
-	h.GetScanningSettings(w, r)
+	return nil
 }
 
 // GetScanningStatus handles GET /api/scanning/status.
@@ -113,6 +117,7 @@ func (h *Handlers) CreateScanningRescan(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	h.daemon.Rescan(daemon.RunRequest{Tenant: requestTenant(r), Reader: body.Reader})
+	h.audit(r, store.NewAuditEvent{Action: store.AuditScanningRescan, TargetType: "reader", TargetID: body.Reader}, nil)
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "rescanning"})
 }
 
@@ -151,6 +156,7 @@ func (h *Handlers) PatchAdminScanningSettings(w http.ResponseWriter, r *http.Req
 	cfg, err := h.scanningStore.PatchSchedulerConfig(r.Context(), store.SchedulerConfigPatch{
 		MaxConcurrentScans: body.MaxConcurrentScans,
 	})
+	h.audit(r, store.NewAuditEvent{Action: store.AuditScanningAdminSettings, TargetType: "scheduler"}, err)
 	if err != nil {
 		writeError(w, r, err)
 		return
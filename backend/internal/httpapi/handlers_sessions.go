@@ -0,0 +1,158 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+const (
+	// sessionTouchInterval limits how often a session's last use is written.
+	sessionTouchInterval = 5 * time.Minute
+	maxUserAgentBytes    = 512
+)
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	IPAddress  string    `json:"ip_address" example:"192.0.2.10"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64) Firefox/140.0"`
+	// MFAState is pending while a password sign-in awaits its second factor.
+	MFAState string `json:"mfa_state" enums:"complete,pending,enrollment" example:"complete"`
+	// Current marks the session making the request.
+	Current bool `json:"current"`
+}
+
+type revokedSessionsResponse struct {
+	Revoked int64 `json:"revoked" example:"2"`
+}
+
+// ListSessions handles GET /api/sessions.
+// @Summary List the current user's active browser sessions
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} sessionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions [get]
+func (h *Handlers) ListSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := h.authStore.ListSessions(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, sessionFromStore(&session, principal.SessionID))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeOtherSessions handles DELETE /api/sessions. It signs out every
+// session of the user except the one making the request; from an access
+// token that is all of them.
+// @Summary Revoke the current user's other browser sessions
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} revokedSessionsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions [delete]
+func (h *Handlers) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	revoked, err := h.authStore.RevokeOtherSessions(r.Context(), principal.UserID, principal.SessionID)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditSessionRevoke, TargetType: "user", TargetID: principal.UserID}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.logger.Info("revoked other sessions", "user_id", principal.UserID, "revoked", revoked)
+	writeJSON(w, http.StatusOK, revokedSessionsResponse{Revoked: revoked})
+}
+
+// RevokeSession handles DELETE /api/sessions/{id}. Revoking the current
+// session signs it out like DELETE /api/session.
+// @Summary Revoke one of the current user's browser sessions
+// @Tags Auth
+// @Param id path string true "Session ID" format(uuid) example(00000000-0000-0000-0000-00000000c0de)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions/{id} [delete]
+func (h *Handlers) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := uuidPathValue(w, r, "id", "session")
+	if !ok {
+		return
+	}
+	err := h.authStore.RevokeUserSession(r.Context(), principal.UserID, id)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditSessionRevoke, TargetType: "session", TargetID: id}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if id == principal.SessionID {
+		clearSessionCookie(w, r)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// touchSession records that session was used, at most once per
+// sessionTouchInterval unless the client address changed. Failures only cost
+// accuracy and are logged.
+func (h *Handlers) touchSession(r *http.Request, session *store.Session) {
+	ip := h.clientIP(r)
+	if session.LastUsedAt != nil && time.Since(*session.LastUsedAt) < sessionTouchInterval && session.IPAddress == ip {
+		return
+	}
+	if err := h.authStore.TouchSession(r.Context(), session.ID, ip); err != nil {
+		h.logger.Warn("recording session use failed", "session_id", session.ID, "error", err)
+	}
+}
+
+func sessionFromStore(session *store.Session, currentID string) sessionResponse {
+	lastUsedAt := session.CreatedAt
+	if session.LastUsedAt != nil {
+		lastUsedAt = *session.LastUsedAt
+	}
+	return sessionResponse{
+		ID:         session.ID,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: lastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		IPAddress:  session.IPAddress,
+		UserAgent:  session.UserAgent,
+		MFAState:   string(session.MFA),
+		Current:    session.ID == currentID,
+	}
+}
+
+// truncateUserAgent keeps stored user agents to a bounded, valid UTF-8
+// prefix.
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) <= maxUserAgentBytes {
+		return strings.ToValidUTF8(userAgent, "")
+	}
+	cut := maxUserAgentBytes
+	for cut > 0 && !utf8.RuneStart(userAgent[cut]) {
+		cut--
+	}
+	return strings.ToValidUTF8(userAgent[:cut], "")
+}
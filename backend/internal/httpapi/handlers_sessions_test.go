@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+const (
+	currentSessionID = "00000000-0000-0000-0000-00000000000a"
+	otherSessionID   = "00000000-0000-0000-0000-00000000000b"
+	foreignSessionID = "00000000-0000-0000-0000-00000000000c"
+)
+
+// sessionsFixture signs user-a in with currentSessionID and gives them a second
+// browser session plus one belonging to user-b.
+func sessionsFixture(t *testing.T) (http.Handler, *mockStore, string) {
+	t.Helper()
+	raw, hash, err := auth.NewOpaqueToken(sessionTokenPrefix)
+	if err != nil {
+		t.Fatalf("NewOpaqueToken() error = %v", err)
+	}
+	expires := time.Now().Add(time.Hour)
+	lastUsed := time.Now().Add(-time.Hour)
+	ms := &mockStore{
+		sessionsByHash: map[string]*store.Session{
+			hash: {
+				ID: currentSessionID, UserID: "user-a", TokenHash: hash, ExpiresAt: expires, MFA: store.SessionMFAComplete,
+				LastUsedAt: &lastUsed, IPAddress: "192.0.2.1",
+			},
+			"other": {
+				ID: otherSessionID, UserID: "user-a", TokenHash: "other", ExpiresAt: expires, MFA: store.SessionMFAComplete,
+				IPAddress: "198.51.100.7", UserAgent: "Firefox", CreatedAt: lastUsed,
+			},
+			"foreign": {ID: foreignSessionID, UserID: "user-b", TokenHash: "foreign", ExpiresAt: expires, MFA: store.SessionMFAComplete},
+		},
+		usersByID: map[string]*store.User{"user-a": {ID: "user-a", TenantID: "tenant-a", Role: store.UserRoleUser}},
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	mux := http.NewServeMux()
+	registerRoutes(mux, h)
+	return authMiddleware(h, mux), ms, raw
+}
+
+func serveSessionRequest(handler http.Handler, method, target, raw string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), method, target, nil)
+	req.RemoteAddr = "192.0.2.1:4000"
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: raw})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestListSessionsMarksCurrent(t *testing.T) {
+	handler, ms, raw := sessionsFixture(t)
+
+	rec := serveSessionRequest(handler, http.MethodGet, "/api/sessions", raw)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var got []sessionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("sessions = %#v, want the two user-a sessions", got)
+	}
+	if got[0].ID != currentSessionID || !got[0].Current || got[1].Current {
+		t.Fatalf("sessions = %#v, want only the current session marked", got)
+	}
+	if got[1].UserAgent != "Firefox" || got[1].IPAddress != "198.51.100.7" || got[1].LastUsedAt.IsZero() {
+		t.Fatalf("other session = %#v", got[1])
+	}
+	if ms.touchedSessionID != currentSessionID || ms.touchedSessionIP != "192.0.2.1" {
+		t.Fatalf("touched session = %q from %q, want the current session from 192.0.2.1", ms.touchedSessionID, ms.touchedSessionIP)
+	}
+}
+
+func TestRevokeOtherSessionsKeepsCurrent(t *testing.T) {
+	handler, ms, raw := sessionsFixture(t)
+
+	rec := serveSessionRequest(handler, http.MethodDelete, "/api/sessions", raw)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var got revokedSessionsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Revoked != 1 {
+		t.Fatalf("revoked = %d, want 1", got.Revoked)
+	}
+	if len(ms.auditEvents) != 1 || ms.auditEvents[0].Action != store.AuditSessionRevoke || ms.auditEvents[0].ActorUserID != "user-a" {
+		t.Fatalf("audit events = %#v", ms.auditEvents)
+	}
+	for _, session := range ms.sessionsByHash {
+		if revoked := session.RevokedAt != nil; revoked != (session.ID == otherSessionID) {
+			t.Fatalf("session %s revoked = %v", session.ID, revoked)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	handler, ms, raw := sessionsFixture(t)
+
+	rec := serveSessionRequest(handler, http.MethodDelete, "/api/sessions/00000000-0000-0000-0000-000000000001", raw)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown session status = %d, want 404; body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveSessionRequest(handler, http.MethodDelete, "/api/sessions/"+foreignSessionID, raw)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("other user's session status = %d, want 404", rec.Code)
+	}
+
+	rec = serveSessionRequest(handler, http.MethodDelete, "/api/sessions/"+otherSessionID, raw)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204; body = %s", rec.Code, rec.Body.String())
+	}
+	if cookie := findCookie(rec.Result().Cookies(), sessionCookieName); cookie != nil {
+		t.Fatalf("revoking another session set cookie %#v", cookie)
+	}
+	if last := ms.auditEvents[len(ms.auditEvents)-1]; last.Action != store.AuditSessionRevoke ||
+		last.TargetID != otherSessionID || last.Outcome != store.AuditSuccess {
+		t.Fatalf("audit event = %#v", last)
+	}
+
+	rec = serveSessionRequest(handler, http.MethodDelete, "/api/sessions/"+currentSessionID, raw)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("current session status = %d, want 204; body = %s", rec.Code, rec.Body.String())
+	}
+	if cookie := findCookie(rec.Result().Cookies(), sessionCookieName); cookie == nil || cookie.MaxAge >= 0 {
+		t.Fatalf("session cookie = %#v, want it cleared", cookie)
+	}
+}
@@ -0,0 +1,410 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// sharedLedgerDetail is a ledger with its current balances and the payments
+// that would settle them.
+type sharedLedgerDetail struct {
+	store.SharedLedger
+	Balances    []store.LedgerBalance        `json:"balances"`
+	Suggestions []store.SettlementSuggestion `json:"suggestions"`
+}
+
+// ListSharedLedgers handles GET /api/shared-ledgers.
+// @Summary List the shared ledgers the current user belongs to
+// @Tags Shared Ledgers
+// @Produce json
+// @Success 200 {array} SharedLedgerResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers [get]
+func (h *Handlers) ListSharedLedgers(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgers, err := h.sharedLedgerStore.ListSharedLedgers(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ledgers)
+}
+
+// CreateSharedLedger handles POST /api/shared-ledgers. The caller owns the
+// new ledger and is its only member until the invited users accept.
+// @Summary Create a shared ledger and invite other instance users
+// @Tags Shared Ledgers
+// @Accept json
+// @Produce json
+// @Param request body CreateSharedLedgerRequest true "Ledger name and invitee emails"
+// @Success 201 {object} SharedLedgerResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers [post]
+func (h *Handlers) CreateSharedLedger(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[CreateSharedLedgerRequest](h, w, r)
+	if !ok {
+		return
+	}
+	ledger, err := h.sharedLedgerStore.CreateSharedLedger(r.Context(), principal.UserID, store.CreateSharedLedgerInput{
+		Name:         strings.TrimSpace(body.Name),
+		InviteEmails: body.InviteEmails,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ledger)
+}
+
+// GetSharedLedger handles GET /api/shared-ledgers/{id}.
+// @Summary Get a shared ledger with balances and settle-up suggestions
+// @Tags Shared Ledgers
+// @Produce json
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Success 200 {object} SharedLedgerDetailResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id} [get]
+func (h *Handlers) GetSharedLedger(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	ledger, err := h.sharedLedgerStore.GetSharedLedger(r.Context(), principal.UserID, ledgerID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	balances, err := h.sharedLedgerStore.ListLedgerBalances(r.Context(), principal.UserID, ledgerID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sharedLedgerDetail{
+		SharedLedger: ledger,
+		Balances:     balances,
+		Suggestions:  store.SuggestSettlements(balances),
+	})
+}
+
+// InviteSharedLedgerMember handles POST /api/shared-ledgers/{id}/members.
+// Only the ledger owner can invite. The response is the same whether or not
+// the email belongs to a user, so it cannot be used to probe for accounts.
+// @Summary Invite an instance user to a shared ledger you own
+// @Tags Shared Ledgers
+// @Accept json
+// @Produce json
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Param request body SharedLedgerMemberRequest true "Invitee email"
+// @Success 202 {object} StatusOnlyResponse "Invitation sent if the email belongs to a user"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/members [post]
+func (h *Handlers) InviteSharedLedgerMember(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[SharedLedgerMemberRequest](h, w, r)
+	if !ok {
+		return
+	}
+	if err := h.sharedLedgerStore.InviteSharedLedgerMember(r.Context(), principal.UserID, ledgerID, strings.TrimSpace(body.Email)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "invited"})
+}
+
+// ListSharedLedgerInvitations handles GET /api/shared-ledgers/invitations.
+// @Summary List the shared ledgers the current user is invited to
+// @Tags Shared Ledgers
+// @Produce json
+// @Success 200 {array} SharedLedgerInvitationResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/invitations [get]
+func (h *Handlers) ListSharedLedgerInvitations(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	invitations, err := h.sharedLedgerStore.ListSharedLedgerInvitations(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invitations)
+}
+
+// AcceptSharedLedgerInvitation handles POST /api/shared-ledgers/{id}/invitation.
+// @Summary Accept an invitation and join a shared ledger
+// @Tags Shared Ledgers
+// @Produce json
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Success 200 {object} SharedLedgerResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/invitation [post]
+func (h *Handlers) AcceptSharedLedgerInvitation(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	ledger, err := h.sharedLedgerStore.AcceptSharedLedgerInvitation(r.Context(), principal.UserID, ledgerID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ledger)
+}
+
+// DeclineSharedLedgerInvitation handles DELETE /api/shared-ledgers/{id}/invitation.
+// @Summary Decline an invitation to a shared ledger
+// @Tags Shared Ledgers
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/invitation [delete]
+func (h *Handlers) DeclineSharedLedgerInvitation(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	if err := h.sharedLedgerStore.DeclineSharedLedgerInvitation(r.Context(), principal.UserID, ledgerID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSharedExpenses handles GET /api/shared-ledgers/{id}/expenses.
+// @Summary List the expenses shared in a ledger
+// @Tags Shared Ledgers
+// @Produce json
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Success 200 {array} SharedExpenseResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/expenses [get]
+func (h *Handlers) ListSharedExpenses(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	expenses, err := h.sharedLedgerStore.ListSharedExpenses(r.Context(), principal.UserID, ledgerID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, expenses)
+}
+
+// ShareExpense handles POST /api/shared-ledgers/{id}/expenses.
+// @Summary Share one of your transactions or splits with ledger members
+// @Tags Shared Ledgers
+// @Accept json
+// @Produce json
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Param request body ShareExpenseRequest true "Transaction and member shares"
+// @Success 201 {object} SharedExpenseResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/expenses [post]
+func (h *Handlers) ShareExpense(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[ShareExpenseRequest](h, w, r)
+	if !ok {
+		return
+	}
+	shares := make([]store.ExpenseShareInput, 0, len(body.Shares))
+	for _, share := range body.Shares {
+		shares = append(shares, store.ExpenseShareInput{UserID: share.UserID, Ratio: share.Ratio})
+	}
+	expense, err := h.sharedLedgerStore.ShareExpense(r.Context(), requestTenant(r), principal.UserID, ledgerID, store.ShareExpenseInput{
+		TransactionID: body.TransactionID,
+		SplitID:       body.SplitID,
+		Shares:        shares,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, expense)
+}
+
+// DeleteSharedExpense handles DELETE /api/shared-ledgers/{id}/expenses/{expense_id}.
+// @Summary Stop sharing an expense you paid for
+// @Tags Shared Ledgers
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Param expense_id path string true "Shared expense ID" format(uuid) example(55555555-5555-5555-5555-555555555555)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/expenses/{expense_id} [delete]
+func (h *Handlers) DeleteSharedExpense(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	expenseID, ok := uuidPathValue(w, r, "expense_id", "shared expense")
+	if !ok {
+		return
+	}
+	if err := h.sharedLedgerStore.DeleteSharedExpense(r.Context(), principal.UserID, ledgerID, expenseID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSettlements handles GET /api/shared-ledgers/{id}/settlements.
+// @Summary List the settlements recorded in a ledger
+// @Tags Shared Ledgers
+// @Produce json
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Success 200 {array} SettlementResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/settlements [get]
+func (h *Handlers) ListSettlements(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	settlements, err := h.sharedLedgerStore.ListSettlements(r.Context(), principal.UserID, ledgerID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, settlements)
+}
+
+// RecordSettlement handles POST /api/shared-ledgers/{id}/settlements.
+// @Summary Record a payment between two ledger members
+// @Tags Shared Ledgers
+// @Accept json
+// @Produce json
+// @Param id path string true "Ledger ID" format(uuid) example(33333333-3333-3333-3333-333333333333)
+// @Param request body RecordSettlementRequest true "Settlement"
+// @Success 201 {object} SettlementResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared-ledgers/{id}/settlements [post]
+func (h *Handlers) RecordSettlement(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	ledgerID, ok := uuidPathValue(w, r, "id", "ledger")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[RecordSettlementRequest](h, w, r)
+	if !ok {
+		return
+	}
+	var settledAt time.Time
+	if body.SettledAt != nil {
+		settledAt = body.SettledAt.UTC()
+	}
+	settlement, err := h.sharedLedgerStore.RecordSettlement(r.Context(), principal.UserID, ledgerID, store.RecordSettlementInput{
+		FromUserID: body.FromUserID,
+		ToUserID:   body.ToUserID,
+		Amount:     body.Amount,
+		Currency:   body.Currency,
+		Note:       strings.TrimSpace(body.Note),
+		SettledAt:  settledAt,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, settlement)
+}
+
+func requirePrincipal(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		writeError(w, r, errors.E(errors.Unauthenticated, errors.User("authentication required")))
+		return auth.Principal{}, false
+	}
+	return principal, true
+}
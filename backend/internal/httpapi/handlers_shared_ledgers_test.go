@@ -0,0 +1,260 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	testLedgerID      = "33333333-3333-3333-3333-333333333333"
+	testLedgerUserA   = "11111111-1111-1111-1111-111111111111"
+	testLedgerUserB   = "22222222-2222-2222-2222-222222222222"
+	testSharedExpense = "55555555-5555-5555-5555-555555555555"
+)
+
+func newLedgerRequest(t *testing.T, method, target, body string) *http.Request {
+	t.Helper()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testLedgerUserA, TenantID: testLedgerUserA, Role: auth.RoleUser})
+	req := httptest.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if strings.HasPrefix(target, "/api/shared-ledgers/") {
+		req.SetPathValue("id", testLedgerID)
+	}
+	return req
+}
+
+func TestCreateSharedLedger_InvitesMemberEmails(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers", `{"name":" Flat 4B ","invite_emails":["bob@example.com"]}`)
+	rr := httptest.NewRecorder()
+
+	h.CreateSharedLedger(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	want := store.CreateSharedLedgerInput{Name: "Flat 4B", InviteEmails: []string{"bob@example.com"}}
+	if !reflect.DeepEqual(st.createdSharedLedger, want) || st.sharedLedgerUserID != testLedgerUserA {
+		t.Fatalf("created ledger = %#v by %q, want %#v by %q", st.createdSharedLedger, st.sharedLedgerUserID, want, testLedgerUserA)
+	}
+	var resp SharedLedgerResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if len(resp.Members) != 1 {
+		t.Fatalf("members = %#v, want only the creator until bob accepts", resp.Members)
+	}
+}
+
+func TestInviteSharedLedgerMember_DoesNotRevealAccounts(t *testing.T) {
+	for _, email := range []string{"bob@example.com", "nobody@example.com"} {
+		t.Run(email, func(t *testing.T) {
+			st := &mockStore{usersByEmail: map[string]*store.User{
+				"bob@example.com": {ID: testLedgerUserB, Email: "bob@example.com"},
+			}}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			rr := httptest.NewRecorder()
+
+			h.InviteSharedLedgerMember(rr, newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers/"+testLedgerID+"/members", `{"email":"`+email+`"}`))
+
+			if rr.Code != http.StatusAccepted || strings.TrimSpace(rr.Body.String()) != `{"status":"invited"}` {
+				t.Fatalf("got %d %s, want 202 with the same body for every email", rr.Code, rr.Body.String())
+			}
+			if st.invitedLedgerEmail != email {
+				t.Fatalf("invited email = %q, want %q", st.invitedLedgerEmail, email)
+			}
+		})
+	}
+}
+
+func TestInviteSharedLedgerMember_RequiresOwner(t *testing.T) {
+	st := &mockStore{sharedLedgerErr: errors.E(errors.PermissionDenied, errors.User("Only the ledger owner can invite members."))}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.InviteSharedLedgerMember(rr, newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers/"+testLedgerID+"/members", `{"email":"bob@example.com"}`))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAcceptSharedLedgerInvitation(t *testing.T) {
+	st := &mockStore{ledgerInvitations: []store.SharedLedgerInvitation{{LedgerID: testLedgerID, LedgerName: "Flat 4B", InvitedBy: testLedgerUserB}}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.AcceptSharedLedgerInvitation(rr, newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers/"+testLedgerID+"/invitation", ""))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp SharedLedgerResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if resp.ID != testLedgerID || st.sharedLedgerUserID != testLedgerUserA {
+		t.Fatalf("accepted ledger = %#v by %q", resp, st.sharedLedgerUserID)
+	}
+}
+
+func TestAcceptSharedLedgerInvitation_RequiresInvitation(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.AcceptSharedLedgerInvitation(rr, newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers/"+testLedgerID+"/invitation", ""))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeclineSharedLedgerInvitation(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.DeclineSharedLedgerInvitation(rr, newLedgerRequest(t, http.MethodDelete, "/api/shared-ledgers/"+testLedgerID+"/invitation", ""))
+
+	if rr.Code != http.StatusNoContent || st.declinedLedgerInvitation != testLedgerID {
+		t.Fatalf("got %d, declined %q (body=%s)", rr.Code, st.declinedLedgerInvitation, rr.Body.String())
+	}
+}
+
+func TestSharedLedgerHandlers_RequireAuthentication(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/shared-ledgers", nil)
+	rr := httptest.NewRecorder()
+
+	h.ListSharedLedgers(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetSharedLedger_IncludesBalancesAndSuggestions(t *testing.T) {
+	st := &mockStore{
+		sharedLedgers: []store.SharedLedger{{ID: testLedgerID, Name: "Flat 4B"}},
+		ledgerBalances: []store.LedgerBalance{
+			{UserID: testLedgerUserA, Currency: "INR", Amount: 450},
+			{UserID: testLedgerUserB, Currency: "INR", Amount: -450},
+		},
+	}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.GetSharedLedger(rr, newLedgerRequest(t, http.MethodGet, "/api/shared-ledgers/"+testLedgerID, ""))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp SharedLedgerDetailResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	want := []SettlementSuggestionResponse{{FromUserID: testLedgerUserB, ToUserID: testLedgerUserA, Currency: "INR", Amount: 450}}
+	if resp.Name != "Flat 4B" || len(resp.Balances) != 2 || !reflect.DeepEqual(resp.Suggestions, want) {
+		t.Fatalf("response = %#v", resp)
+	}
+}
+
+func TestGetSharedLedger_HidesLedgersOfOtherUsers(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	rr := httptest.NewRecorder()
+
+	h.GetSharedLedger(rr, newLedgerRequest(t, http.MethodGet, "/api/shared-ledgers/"+testLedgerID, ""))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestShareExpense_PassesSharesToStore(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	body := `{"transaction_id":"` + testTransactionID + `","shares":[{"user_id":"` + testLedgerUserB + `","ratio":0.5}]}`
+	rr := httptest.NewRecorder()
+
+	h.ShareExpense(rr, newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers/"+testLedgerID+"/expenses", body))
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	want := store.ShareExpenseInput{
+		TransactionID: testTransactionID,
+		Shares:        []store.ExpenseShareInput{{UserID: testLedgerUserB, Ratio: 0.5}},
+	}
+	if !reflect.DeepEqual(st.sharedExpenseInput, want) {
+		t.Fatalf("share input = %#v, want %#v", st.sharedExpenseInput, want)
+	}
+	var resp SharedExpenseResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if resp.PaidBy != testLedgerUserA || len(resp.Shares) != 1 || resp.Shares[0].Amount != 50 {
+		t.Fatalf("response = %#v", resp)
+	}
+}
+
+func TestShareExpense_RejectsInvalidPayloads(t *testing.T) {
+	share := func(ratio string) string { return `[{"user_id":"` + testLedgerUserB + `","ratio":` + ratio + `}]` }
+	tests := []struct {
+		name  string
+		body  string
+		field string
+		msg   string
+	}{
+		{name: "missing shares", body: `{"transaction_id":"` + testTransactionID + `","shares":[]}`, field: "shares", msg: "must be at least 1"},
+		{name: "bad transaction", body: `{"transaction_id":"txn","shares":` + share("0.5") + `}`, field: "transaction_id", msg: "must be a valid UUID"},
+		{name: "ratio above whole", body: `{"transaction_id":"` + testTransactionID + `","shares":` + share("1.5") + `}`, field: "shares[0].ratio", msg: "must be at most 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			rr := httptest.NewRecorder()
+
+			h.ShareExpense(rr, newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers/"+testLedgerID+"/expenses", tt.body))
+
+			assertValidationError(t, rr, tt.field, "body", tt.msg)
+			if st.sharedExpenseInput.TransactionID != "" {
+				t.Fatalf("share input = %#v, want no store write", st.sharedExpenseInput)
+			}
+		})
+	}
+}
+
+func TestDeleteSharedExpense_SurfacesPermissionDenied(t *testing.T) {
+	st := &mockStore{sharedLedgerErr: errors.E(errors.PermissionDenied, errors.User("Only the member who paid can unshare an expense."))}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := newLedgerRequest(t, http.MethodDelete, "/api/shared-ledgers/"+testLedgerID+"/expenses/"+testSharedExpense, "")
+	req.SetPathValue("expense_id", testSharedExpense)
+	rr := httptest.NewRecorder()
+
+	h.DeleteSharedExpense(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.deletedSharedExpenseID != testSharedExpense {
+		t.Fatalf("deleted expense = %q, want %q", st.deletedSharedExpenseID, testSharedExpense)
+	}
+}
+
+func TestRecordSettlement_TrimsNote(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	body := `{"from_user_id":"` + testLedgerUserB + `","to_user_id":"` + testLedgerUserA + `","amount":450,"currency":"INR","note":" UPI "}`
+	rr := httptest.NewRecorder()
+
+	h.RecordSettlement(rr, newLedgerRequest(t, http.MethodPost, "/api/shared-ledgers/"+testLedgerID+"/settlements", body))
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	want := store.RecordSettlementInput{FromUserID: testLedgerUserB, ToUserID: testLedgerUserA, Amount: 450, Currency: "INR", Note: "UPI"}
+	if !reflect.DeepEqual(st.recordedSettlement, want) {
+		t.Fatalf("settlement = %#v, want %#v", st.recordedSettlement, want)
+	}
+}
@@ -0,0 +1,399 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// tenantMembershipView marks which of the caller's tenants is selected.
+type tenantMembershipView struct {
+	store.TenantMembership
+	Active bool `json:"active"`
+}
+
+// ListTenants handles GET /api/tenants.
+// @Summary List the tenants the current user belongs to
+// @Tags Tenants
+// @Produce json
+// @Success 200 {array} TenantMembershipResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants [get]
+func (h *Handlers) ListTenants(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	memberships, err := h.tenantStore.ListTenantMemberships(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	views := make([]tenantMembershipView, 0, len(memberships))
+	for _, membership := range memberships {
+		views = append(views, tenantMembershipView{
+			TenantMembership: membership,
+			Active:           membership.TenantID == principal.TenantID,
+		})
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// CreateTenant handles POST /api/tenants.
+// @Summary Create a tenant owned by the current user
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Param request body CreateTenantRequest true "Tenant name"
+// @Success 201 {object} TenantMembershipResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants [post]
+func (h *Handlers) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[CreateTenantRequest](h, w, r)
+	if !ok {
+		return
+	}
+	membership, err := h.tenantStore.CreateTenant(r.Context(), principal.UserID, store.CreateTenantInput{
+		Name: strings.TrimSpace(body.Name),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tenantMembershipView{TenantMembership: membership})
+}
+
+// ListTenantMembers handles GET /api/tenants/{id}/members.
+// @Summary List the users with access to a tenant
+// @Tags Tenants
+// @Produce json
+// @Param id path string true "Tenant ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Success 200 {array} TenantMemberResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants/{id}/members [get]
+func (h *Handlers) ListTenantMembers(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	tenant, ok := h.memberTenant(w, r, principal)
+	if !ok {
+		return
+	}
+	members, err := h.tenantStore.ListTenantMembers(r.Context(), tenant)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+// InviteTenantMember handles POST /api/tenants/{id}/members.
+// Only tenant owners can invite. The response is the same whether or not the
+// email belongs to a user, so it cannot be used to probe for accounts.
+// @Summary Invite an instance user to a tenant
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Param request body TenantMemberRequest true "Invitee email and role"
+// @Success 202 {object} StatusOnlyResponse "Invitation sent if the email belongs to a user"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants/{id}/members [post]
+func (h *Handlers) InviteTenantMember(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	tenant, ok := h.ownerTenant(w, r, principal, "Only tenant owners can invite members.")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[TenantMemberRequest](h, w, r)
+	if !ok {
+		return
+	}
+	email := strings.TrimSpace(body.Email)
+	err := h.tenantStore.InviteTenantMember(r.Context(), tenant, principal.UserID, email, store.TenantRole(body.Role))
+	h.audit(r, store.NewAuditEvent{TenantID: tenant.ID, Action: store.AuditTenantMemberInvite, TargetType: "email", TargetID: strings.ToLower(email)}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "invited"})
+}
+
+// ListTenantInvitations handles GET /api/tenants/invitations.
+// @Summary List the tenants the current user is invited to
+// @Tags Tenants
+// @Produce json
+// @Success 200 {array} TenantInvitationResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants/invitations [get]
+func (h *Handlers) ListTenantInvitations(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	invitations, err := h.tenantStore.ListTenantInvitations(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invitations)
+}
+
+// AcceptTenantInvitation handles POST /api/tenants/{id}/invitation.
+// @Summary Accept an invitation and join a tenant
+// @Tags Tenants
+// @Produce json
+// @Param id path string true "Tenant ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Success 200 {object} TenantMembershipResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants/{id}/invitation [post]
+func (h *Handlers) AcceptTenantInvitation(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	tenantID, ok := uuidPathValue(w, r, "id", "tenant")
+	if !ok {
+		return
+	}
+	membership, err := h.tenantStore.AcceptTenantInvitation(r.Context(), store.Tenant{ID: tenantID}, principal.UserID)
+	h.audit(r, store.NewAuditEvent{TenantID: tenantID, Action: store.AuditTenantMemberJoin, TargetType: "user", TargetID: principal.UserID}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tenantMembershipView{TenantMembership: membership})
+}
+
+// DeclineTenantInvitation handles DELETE /api/tenants/{id}/invitation.
+// @Summary Decline an invitation to a tenant
+// @Tags Tenants
+// @Param id path string true "Tenant ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants/{id}/invitation [delete]
+func (h *Handlers) DeclineTenantInvitation(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	tenantID, ok := uuidPathValue(w, r, "id", "tenant")
+	if !ok {
+		return
+	}
+	if err := h.tenantStore.DeclineTenantInvitation(r.Context(), store.Tenant{ID: tenantID}, principal.UserID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateTenantMemberRole handles PATCH /api/tenants/{id}/members/{user_id}.
+// @Summary Change a member's role in a tenant you own
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Param user_id path string true "Member user ID" format(uuid) example(22222222-2222-2222-2222-222222222222)
+// @Param request body TenantMemberRoleRequest true "New role"
+// @Success 200 {object} TenantMemberResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants/{id}/members/{user_id} [patch]
+func (h *Handlers) UpdateTenantMemberRole(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	tenant, ok := h.ownerTenant(w, r, principal, "Only tenant owners can change member roles.")
+	if !ok {
+		return
+	}
+	userID, ok := uuidPathValue(w, r, "user_id", "user")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[TenantMemberRoleRequest](h, w, r)
+	if !ok {
+		return
+	}
+	member, err := h.tenantStore.UpdateTenantMemberRole(r.Context(), tenant, userID, store.TenantRole(body.Role))
+	h.audit(r, store.NewAuditEvent{TenantID: tenant.ID, Action: store.AuditTenantMemberRoleUpdate, TargetType: "user", TargetID: userID}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, member)
+}
+
+// RemoveTenantMember handles DELETE /api/tenants/{id}/members/{user_id}.
+// Owners can remove any member; any member can remove themselves to leave.
+// @Summary Remove a member from a tenant, or leave it
+// @Tags Tenants
+// @Param id path string true "Tenant ID" format(uuid) example(77777777-7777-7777-7777-777777777777)
+// @Param user_id path string true "Member user ID" format(uuid) example(22222222-2222-2222-2222-222222222222)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tenants/{id}/members/{user_id} [delete]
+func (h *Handlers) RemoveTenantMember(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	tenantID, ok := uuidPathValue(w, r, "id", "tenant")
+	if !ok {
+		return
+	}
+	userID, ok := uuidPathValue(w, r, "user_id", "user")
+	if !ok {
+		return
+	}
+	tenant := store.Tenant{ID: tenantID}
+	membership, err := h.tenantStore.GetTenantMembership(r.Context(), tenant, principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if userID != principal.UserID && membership.Role != store.TenantRoleOwner {
+		writeError(w, r, errors.E(errors.PermissionDenied, errors.User("Only tenant owners can remove other members.")))
+		return
+	}
+	err = h.tenantStore.RemoveTenantMember(r.Context(), tenant, userID)
+	h.audit(r, store.NewAuditEvent{TenantID: tenant.ID, Action: store.AuditTenantMemberRemove, TargetType: "user", TargetID: userID}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SelectSessionTenant handles PUT /api/session/tenant.
+// @Summary Switch the tenant the current browser session works in
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body SelectTenantRequest true "Tenant to select"
+// @Success 200 {object} principalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /session/tenant [put]
+func (h *Handlers) SelectSessionTenant(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" || principal.AuthMethod != "session" {
+		writeError(w, r, errors.E(errors.FailedPrecondition, errors.User("Access tokens always act on the personal tenant.")))
+		return
+	}
+	body, ok := decodeAndValidateJSON[SelectTenantRequest](h, w, r)
+	if !ok {
+		return
+	}
+	membership, err := h.tenantStore.GetTenantMembership(r.Context(), store.Tenant{ID: body.TenantID}, principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	session, err := h.authStore.FindSessionByHash(r.Context(), auth.HashOpaqueToken(cookie.Value))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	active := store.Tenant{ID: membership.TenantID}
+	if membership.Personal {
+		active = store.Tenant{}
+	}
+	if err := h.tenantStore.SetSessionTenant(r.Context(), session.ID, active); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	resp := principalFromUser(user)
+	resp.TenantID = membership.TenantID
+	resp.TenantRole = string(membership.Role)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// memberTenant resolves the {id} path tenant, hiding tenants the caller does
+// not belong to behind a 404.
+func (h *Handlers) memberTenant(w http.ResponseWriter, r *http.Request, principal auth.Principal) (store.Tenant, bool) {
+	tenantID, ok := uuidPathValue(w, r, "id", "tenant")
+	if !ok {
+		return store.Tenant{}, false
+	}
+	tenant := store.Tenant{ID: tenantID}
+	if _, err := h.tenantStore.GetTenantMembership(r.Context(), tenant, principal.UserID); err != nil {
+		writeError(w, r, err)
+		return store.Tenant{}, false
+	}
+	return tenant, true
+}
+
+// ownerTenant resolves the {id} path tenant for an action only its owners may
+// take. Non-members get a 404 and other members a 403 with denied.
+func (h *Handlers) ownerTenant(w http.ResponseWriter, r *http.Request, principal auth.Principal, denied string) (store.Tenant, bool) {
+	tenantID, ok := uuidPathValue(w, r, "id", "tenant")
+	if !ok {
+		return store.Tenant{}, false
+	}
+	tenant := store.Tenant{ID: tenantID}
+	membership, err := h.tenantStore.GetTenantMembership(r.Context(), tenant, principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return store.Tenant{}, false
+	}
+	if membership.Role != store.TenantRoleOwner {
+		writeError(w, r, errors.E(errors.PermissionDenied, errors.User(denied)))
+		return store.Tenant{}, false
+	}
+	return tenant, true
+}
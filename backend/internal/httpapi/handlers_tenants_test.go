@@ -0,0 +1,367 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+const (
+	testHouseholdTenant = "77777777-7777-7777-7777-777777777777"
+	testTenantUser      = "11111111-1111-1111-1111-111111111111"
+	testTenantPartner   = "22222222-2222-2222-2222-222222222222"
+)
+
+func newTenantSessionServer(t *testing.T, role store.TenantRole, activeTenantID string) (http.Handler, *mockStore, string) {
+	t.Helper()
+	raw, hash, err := auth.NewOpaqueToken(sessionTokenPrefix)
+	if err != nil {
+		t.Fatalf("NewOpaqueToken() error = %v", err)
+	}
+	user := &store.User{ID: testTenantUser, TenantID: testTenantUser, Email: "a@example.com", Role: store.UserRoleUser}
+	ms := &mockStore{
+		appConfig: map[string]string{"base_currency": "INR"},
+		sessionsByHash: map[string]*store.Session{
+			hash: {
+				ID: "session-a", UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour),
+				ActiveTenantID: activeTenantID, MFA: store.SessionMFAComplete,
+			},
+		},
+		usersByID: map[string]*store.User{user.ID: user},
+	}
+	if role != "" {
+		ms.tenantMemberships = []store.TenantMembership{{TenantID: testHouseholdTenant, TenantName: "Household", Role: role}}
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	mux := http.NewServeMux()
+	registerRoutes(mux, h)
+	return authMiddleware(h, mux), ms, raw
+}
+
+func TestAuthMiddlewareSessionUsesActiveTenant(t *testing.T) {
+	handler, ms, raw := newTenantSessionServer(t, store.TenantRoleEditor, testHouseholdTenant)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/config/preferences", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: raw})
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if ms.lastAppConfigTenant.ID != testHouseholdTenant {
+		t.Fatalf("request tenant = %q, want %q", ms.lastAppConfigTenant.ID, testHouseholdTenant)
+	}
+}
+
+func TestAuthMiddlewareFallsBackToPersonalTenantAfterMembershipRemoved(t *testing.T) {
+	handler, ms, raw := newTenantSessionServer(t, "", testHouseholdTenant)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/config/preferences", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: raw})
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if ms.lastAppConfigTenant.ID != testTenantUser {
+		t.Fatalf("request tenant = %q, want personal tenant", ms.lastAppConfigTenant.ID)
+	}
+}
+
+func TestAuthMiddlewareViewerIsReadOnly(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{method: http.MethodGet, path: "/api/config/preferences", want: http.StatusOK},
+		{method: http.MethodPatch, path: "/api/config/preferences", body: `{"base_currency":"USD"}`, want: http.StatusForbidden},
+		{method: http.MethodDelete, path: "/api/transactions/" + testTransactionID, want: http.StatusForbidden},
+		{method: http.MethodPut, path: "/api/session/tenant", body: `{"tenant_id":"` + testTenantUser + `"}`, want: http.StatusOK},
+		{method: http.MethodPatch, path: "/api/notifications/preferences", body: `{"weekly_digest":false}`, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			handler, _, raw := newTenantSessionServer(t, store.TenantRoleViewer, testHouseholdTenant)
+			req := httptest.NewRequestWithContext(context.Background(), tt.method, tt.path, strings.NewReader(tt.body))
+			req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: raw})
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSelectSessionTenantStoresMembershipTenant(t *testing.T) {
+	handler, ms, raw := newTenantSessionServer(t, store.TenantRoleViewer, "")
+	body := `{"tenant_id":"` + testHouseholdTenant + `"}`
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/api/session/tenant", strings.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: raw})
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if ms.sessionTenantID != testHouseholdTenant {
+		t.Fatalf("session tenant = %q, want %q", ms.sessionTenantID, testHouseholdTenant)
+	}
+	var resp principalResponse
+	decodeJSON(t, rec.Body.String(), &resp)
+	if resp.TenantID != testHouseholdTenant || resp.TenantRole != "viewer" {
+		t.Fatalf("principal = %#v, want household viewer", resp)
+	}
+}
+
+func TestSelectSessionTenantRejectsUnknownTenant(t *testing.T) {
+	handler, ms, raw := newTenantSessionServer(t, "", "")
+	body := `{"tenant_id":"` + testHouseholdTenant + `"}`
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/api/session/tenant", strings.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: raw})
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404; body = %s", rec.Code, rec.Body.String())
+	}
+	if ms.sessionTenantSet {
+		t.Fatalf("session tenant = %q, want no store write", ms.sessionTenantID)
+	}
+}
+
+func TestSelectSessionTenantRequiresSessionCookie(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantUser, TenantID: testTenantUser, AuthMethod: "bearer"})
+	req := httptest.NewRequestWithContext(ctx, http.MethodPut, "/api/session/tenant", strings.NewReader(`{"tenant_id":"`+testHouseholdTenant+`"}`))
+	rec := httptest.NewRecorder()
+
+	h.SelectSessionTenant(rec, req)
+
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("status = %d, want 412; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListTenantsMarksActiveTenant(t *testing.T) {
+	st := &mockStore{tenantMemberships: []store.TenantMembership{{TenantID: testHouseholdTenant, TenantName: "Household", Role: store.TenantRoleEditor}}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantUser, TenantID: testHouseholdTenant})
+	rec := httptest.NewRecorder()
+
+	h.ListTenants(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/tenants", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp []TenantMembershipResponse
+	decodeJSON(t, rec.Body.String(), &resp)
+	if len(resp) != 2 || resp[0].Active || !resp[0].Personal || !resp[1].Active {
+		t.Fatalf("tenants = %#v, want personal then active household", resp)
+	}
+}
+
+func TestInviteTenantMember(t *testing.T) {
+	tests := []struct {
+		name string
+		role store.TenantRole
+		want int
+	}{
+		{name: "owner invites", role: store.TenantRoleOwner, want: http.StatusAccepted},
+		{name: "editor cannot invite", role: store.TenantRoleEditor, want: http.StatusForbidden},
+		{name: "non-member sees nothing", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{}
+			if tt.role != "" {
+				st.tenantMemberships = []store.TenantMembership{{TenantID: testHouseholdTenant, Role: tt.role}}
+			}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantUser, TenantID: testTenantUser})
+			body := `{"email":"partner@example.com","role":"viewer"}`
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/tenants/"+testHouseholdTenant+"/members", strings.NewReader(body))
+			req.SetPathValue("id", testHouseholdTenant)
+			rec := httptest.NewRecorder()
+
+			h.InviteTenantMember(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+			invited := st.invitedTenantEmail == "partner@example.com" && st.invitedTenantRole == store.TenantRoleViewer
+			if invited != (tt.want == http.StatusAccepted) {
+				t.Fatalf("invited = %q as %q", st.invitedTenantEmail, st.invitedTenantRole)
+			}
+			if audited := len(auditedActions(st, string(store.AuditTenantMemberInvite))) == 1; audited != invited {
+				t.Fatalf("audit events = %#v", st.auditEvents)
+			}
+		})
+	}
+}
+
+// The store ignores unknown emails, so the response cannot reveal whether an
+// account exists.
+func TestInviteTenantMemberRespondsTheSameForUnknownEmails(t *testing.T) {
+	var bodies []string
+	for _, email := range []string{"partner@example.com", "nobody@example.com"} {
+		st := &mockStore{
+			tenantMemberships: []store.TenantMembership{{TenantID: testHouseholdTenant, Role: store.TenantRoleOwner}},
+			usersByEmail:      map[string]*store.User{"partner@example.com": {ID: testTenantPartner, Email: "partner@example.com"}},
+		}
+		h := newTestHandlers(t, st, &mockDaemon{})
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantUser, TenantID: testTenantUser})
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/tenants/"+testHouseholdTenant+"/members",
+			strings.NewReader(`{"email":"`+email+`","role":"editor"}`))
+		req.SetPathValue("id", testHouseholdTenant)
+		rec := httptest.NewRecorder()
+
+		h.InviteTenantMember(rec, req)
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("%s: status = %d, want 202; body = %s", email, rec.Code, rec.Body.String())
+		}
+		bodies = append(bodies, rec.Body.String())
+	}
+	if bodies[0] != bodies[1] {
+		t.Fatalf("responses differ: %q vs %q", bodies[0], bodies[1])
+	}
+}
+
+func TestInviteTenantMemberRejectsUnknownRole(t *testing.T) {
+	st := &mockStore{tenantMemberships: []store.TenantMembership{{TenantID: testHouseholdTenant, Role: store.TenantRoleOwner}}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantUser, TenantID: testTenantUser})
+	body := `{"email":"partner@example.com","role":"admin"}`
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/tenants/"+testHouseholdTenant+"/members", strings.NewReader(body))
+	req.SetPathValue("id", testHouseholdTenant)
+	rec := httptest.NewRecorder()
+
+	h.InviteTenantMember(rec, req)
+
+	assertValidationError(t, rec, "role", "body", "must be one of: owner, editor, viewer")
+}
+
+func TestAcceptTenantInvitation(t *testing.T) {
+	st := &mockStore{tenantInvitations: []store.TenantInvitation{{TenantID: testHouseholdTenant, TenantName: "Household", Role: store.TenantRoleEditor}}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantPartner, TenantID: testTenantPartner})
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/tenants/"+testHouseholdTenant+"/invitation", nil)
+	req.SetPathValue("id", testHouseholdTenant)
+	rec := httptest.NewRecorder()
+
+	h.AcceptTenantInvitation(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp TenantMembershipResponse
+	decodeJSON(t, rec.Body.String(), &resp)
+	if resp.TenantID != testHouseholdTenant || resp.Role != "editor" {
+		t.Fatalf("membership = %#v, want household editor", resp)
+	}
+	if len(auditedActions(st, string(store.AuditTenantMemberJoin))) != 1 {
+		t.Fatalf("audit events = %#v", st.auditEvents)
+	}
+
+	rec = httptest.NewRecorder()
+	h.AcceptTenantInvitation(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("second accept status = %d, want 404; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUpdateTenantMemberRole(t *testing.T) {
+	tests := []struct {
+		name string
+		role store.TenantRole
+		want int
+	}{
+		{name: "owner changes role", role: store.TenantRoleOwner, want: http.StatusOK},
+		{name: "editor cannot change roles", role: store.TenantRoleEditor, want: http.StatusForbidden},
+		{name: "non-member sees nothing", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{}
+			if tt.role != "" {
+				st.tenantMemberships = []store.TenantMembership{{TenantID: testHouseholdTenant, Role: tt.role}}
+			}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantUser, TenantID: testTenantUser})
+			req := httptest.NewRequestWithContext(ctx, http.MethodPatch, "/api/tenants/"+testHouseholdTenant+"/members/"+testTenantPartner,
+				strings.NewReader(`{"role":"editor"}`))
+			req.SetPathValue("id", testHouseholdTenant)
+			req.SetPathValue("user_id", testTenantPartner)
+			rec := httptest.NewRecorder()
+
+			h.UpdateTenantMemberRole(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+			updated := st.updatedTenantMember.UserID == testTenantPartner && st.updatedTenantMember.Role == store.TenantRoleEditor
+			if updated != (tt.want == http.StatusOK) {
+				t.Fatalf("updated member = %#v", st.updatedTenantMember)
+			}
+			if audited := len(auditedActions(st, string(store.AuditTenantMemberRoleUpdate))) == 1; audited != updated {
+				t.Fatalf("audit events = %#v", st.auditEvents)
+			}
+		})
+	}
+}
+
+func TestRemoveTenantMember(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   store.TenantRole
+		target string
+		want   int
+	}{
+		{name: "owner removes a member", role: store.TenantRoleOwner, target: testTenantPartner, want: http.StatusNoContent},
+		{name: "viewer leaves", role: store.TenantRoleViewer, target: testTenantUser, want: http.StatusNoContent},
+		{name: "editor cannot remove others", role: store.TenantRoleEditor, target: testTenantPartner, want: http.StatusForbidden},
+		{name: "non-member sees nothing", target: testTenantUser, want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{}
+			if tt.role != "" {
+				st.tenantMemberships = []store.TenantMembership{{TenantID: testHouseholdTenant, Role: tt.role}}
+			}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: testTenantUser, TenantID: testTenantUser})
+			req := httptest.NewRequestWithContext(ctx, http.MethodDelete, "/api/tenants/"+testHouseholdTenant+"/members/"+tt.target, nil)
+			req.SetPathValue("id", testHouseholdTenant)
+			req.SetPathValue("user_id", tt.target)
+			rec := httptest.NewRecorder()
+
+			h.RemoveTenantMember(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+			removed := st.removedTenantMember == tt.target
+			if removed != (tt.want == http.StatusNoContent) {
+				t.Fatalf("removed member = %q", st.removedTenantMember)
+			}
+			if audited := len(auditedActions(st, string(store.AuditTenantMemberRemove))) == 1; audited != removed {
+				t.Fatalf("audit events = %#v", st.auditEvents)
+			}
+		})
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	stderrors "errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -101,6 +104,78 @@ type mockStore struct {
 	getErr                     error
 	updateErr                  error
 	updatedTransaction         store.TransactionUpdate
+	createdTransaction         store.CreateTransactionInput
+	createTxErr                error
+	deletedTransactionID       string
+	deleteTxErr                error
+	transactionSplits          []store.TransactionSplitInput
+	setSplitsErr               error
+	bulkInput                  store.BulkTransactionInput
+	bulkResult                 store.BulkTransactionResult
+	bulkErr                    error
+	transactionHistory         []store.TransactionChange
+	historyErr                 error
+	revertResult               store.ChangeBatchRevert
+	revertedBatchID            string
+	revertErr                  error
+	attachments                []store.Attachment
+	attachmentContent          []byte
+	createdAttachment          store.NewAttachment
+	deletedAttachmentID        string
+	attachmentErr              error
+	tenantArchive              *store.TenantArchive
+	importedArchive            *store.TenantArchive
+	importPolicy               store.ArchiveConflictPolicy
+	importResult               store.TenantImportResult
+	archiveErr                 error
+	backupSettings             store.BackupSettings
+	backupSettingsPatch        store.BackupSettingsPatch
+	backupSettingsErr          error
+	auditEvents                []store.NewAuditEvent
+	auditFilter                store.AuditEventFilter
+	auditSettings              store.AuditSettings
+	auditSettingsPatch         store.AuditSettingsPatch
+	auditErr                   error
+	webhooks                   map[string]*store.Webhook
+	createdWebhook             store.NewWebhook
+	webhookPatch               store.WebhookPatch
+	webhookDeliveries          []store.WebhookDelivery
+	webhookDeliveryLimit       int
+	webhookErr                 error
+	notificationPrefs          *store.NotificationPreferences
+	notificationPrefsPatch     store.NotificationPreferencesPatch
+	notificationChannels       map[string]*store.NotificationChannel
+	notificationTokens         map[string]string
+	createdNotificationChannel store.NewNotificationChannel
+	notificationChannelPatch   store.NotificationChannelPatch
+	totpFactor                 *store.TOTPFactor
+	recoveryCodeHashes         map[string]bool
+	passkeys                   []store.Passkey
+	mfaSettings                store.MFASettings
+	mfaErr                     error
+	sharedLedgers              []store.SharedLedger
+	sharedLedgerUserID         string
+	createdSharedLedger        store.CreateSharedLedgerInput
+	invitedLedgerEmail         string
+	ledgerInvitations          []store.SharedLedgerInvitation
+	declinedLedgerInvitation   string
+	sharedExpenses             []store.SharedExpense
+	sharedExpenseInput         store.ShareExpenseInput
+	deletedSharedExpenseID     string
+	ledgerBalances             []store.LedgerBalance
+	settlements                []store.Settlement
+	recordedSettlement         store.RecordSettlementInput
+	sharedLedgerErr            error
+	tenantMemberships          []store.TenantMembership
+	createdTenant              store.CreateTenantInput
+	invitedTenantEmail         string
+	invitedTenantRole          store.TenantRole
+	tenantInvitations          []store.TenantInvitation
+	updatedTenantMember        store.TenantMember
+	removedTenantMember        string
+	sessionTenantID            string
+	sessionTenantSet           bool
+	tenantErr                  error
 	muteTransactionID          string
 	muteTransactionValue       bool
 	muteTransactionReason      string
@@ -131,6 +206,12 @@ type mockStore struct {
 	llmProviderConfigs         map[string]json.RawMessage
 	llmProviderCredentials     map[string][]byte
 	activeLLMProvider          string
+	llmUsageTokens             map[string]int64
+	llmUsageSince              []time.Time
+	llmUsageBuckets            []store.LLMUsageBucket
+	llmUsageFilter             store.LLMUsageFilter
+	llmUsageQuotas             map[string]store.LLMUsageQuota
+	llmPromptVersions          []store.LLMPromptVersion
 	getFacetsErr               error
 	facets                     *store.Facets
 	labels                     []store.Label
@@ -182,9 +263,12 @@ type mockStore struct {
 	deletedUserID              string
 	usersByEmail               map[string]*store.User
 	usersByID                  map[string]*store.User
+	oidcLinks                  map[string]string // user IDs by issuer and subject
 	createdSession             store.CreateSessionInput
 	sessionsByHash             map[string]*store.Session
 	revokedSessionID           string
+	touchedSessionID           string
+	touchedSessionIP           string
 	createdAccessToken         store.CreateAccessTokenInput
 	createAccessTokenErr       error
 	accessTokens               []store.AccessToken
@@ -302,9 +386,41 @@ func (m *mockStore) FindUserByID(_ context.Context, id string) (*store.User, err
 	return nil, mockStoreErr("store.auth.find_user_by_id", errStoreNotFound)
 }
 
+func (m *mockStore) FindUserByOIDCSubject(ctx context.Context, issuer, subject string) (*store.User, error) {
+	userID, ok := m.oidcLinks[issuer+" "+subject]
+	if !ok {
+		return nil, mockStoreErr("store.auth.find_user_by_oidc_subject", errStoreNotFound)
+	}
+	return m.FindUserByID(ctx, userID)
+}
+
+func (m *mockStore) LinkOIDCSubject(_ context.Context, userID, issuer, subject string) error {
+	for key, linked := range m.oidcLinks {
+		if key == issuer+" "+subject || (linked == userID && strings.HasPrefix(key, issuer+" ")) {
+			return errors.E("store.auth.link_oidc_subject", errors.Conflict, "already linked")
+		}
+	}
+	if m.oidcLinks == nil {
+		m.oidcLinks = map[string]string{}
+	}
+	m.oidcLinks[issuer+" "+subject] = userID
+	return nil
+}
+
 func (m *mockStore) CreateSession(_ context.Context, input store.CreateSessionInput) (*store.Session, error) {
 	m.createdSession = input
-	return &store.Session{ID: "session-id", UserID: input.UserID, TokenHash: input.TokenHash, ExpiresAt: input.ExpiresAt}, nil
+	session := &store.Session{
+		ID: "session-id", UserID: input.UserID, TokenHash: input.TokenHash, ExpiresAt: input.ExpiresAt, MFA: input.MFA,
+		IPAddress: input.IPAddress, UserAgent: input.UserAgent, CreatedAt: time.Now(),
+	}
+	if session.MFA == "" {
+		session.MFA = store.SessionMFAComplete
+	}
+	if m.sessionsByHash != nil {
+		session.ID = fmt.Sprintf("session-%d", len(m.sessionsByHash)+1)
+		m.sessionsByHash[input.TokenHash] = session
+	}
+	return session, nil
 }
 
 func (m *mockStore) FindSessionByHash(_ context.Context, tokenHash string) (*store.Session, error) {
@@ -318,6 +434,53 @@ func (m *mockStore) FindSessionByHash(_ context.Context, tokenHash string) (*sto
 
 func (m *mockStore) RevokeSession(_ context.Context, id string) error {
 	m.revokedSessionID = id
+	for _, session := range m.sessionsByHash {
+		if session.ID == id {
+			now := time.Now()
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *mockStore) ListSessions(_ context.Context, userID string) ([]store.Session, error) {
+	var sessions []store.Session
+	for _, session := range m.sessionsByHash {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(time.Now()) {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+	return sessions, nil
+}
+
+func (m *mockStore) RevokeUserSession(_ context.Context, userID, id string) error {
+	for _, session := range m.sessionsByHash {
+		if session.ID == id && session.UserID == userID && session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+			m.revokedSessionID = id
+			return nil
+		}
+	}
+	return mockStoreErr("store.auth.revoke_user_session", errStoreNotFound)
+}
+
+func (m *mockStore) RevokeOtherSessions(_ context.Context, userID, keepID string) (int64, error) {
+	var revoked int64
+	for _, session := range m.sessionsByHash {
+		if session.UserID == userID && session.ID != keepID && session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (m *mockStore) TouchSession(_ context.Context, id, ipAddress string) error {
+	m.touchedSessionID = id
+	m.touchedSessionIP = ipAddress
 	return nil
 }
 
@@ -326,7 +489,10 @@ func (m *mockStore) CreateAccessToken(_ context.Context, input store.CreateAcces
 	if m.createAccessTokenErr != nil {
 		return nil, mockStoreErr("store.auth.create_access_token", m.createAccessTokenErr)
 	}
-	return &store.AccessToken{ID: "access-token-id", UserID: input.UserID, Name: input.Name, TokenHash: input.TokenHash, ExpiresAt: input.ExpiresAt}, nil
+	return &store.AccessToken{
+		ID: "access-token-id", UserID: input.UserID, Name: input.Name, TokenHash: input.TokenHash,
+		ExpiresAt: input.ExpiresAt, Scopes: input.Scopes,
+	}, nil
 }
 
 func (m *mockStore) ListAccessTokens(_ context.Context, userID string) ([]store.AccessToken, error) {
@@ -716,6 +882,96 @@ func (m *mockStore) GetActiveLLMProviderRuntime(
 	}, true, nil
 }
 
+func (m *mockStore) CreateLLMPromptVersion(_ context.Context, input store.CreateLLMPromptVersionInput) (store.LLMPromptVersion, error) {
+	if input.Activate {
+		m.deactivatePromptVersions(input.Workflow, input.Purpose)
+	}
+	version := store.LLMPromptVersion{
+		ID:         "prompt-version-" + strconv.Itoa(len(m.llmPromptVersions)+1),
+		Workflow:   input.Workflow,
+		Purpose:    input.Purpose,
+		Version:    input.Version,
+		Definition: append(json.RawMessage(nil), input.Definition...),
+		Active:     input.Activate,
+		CreatedBy:  input.CreatedBy,
+		CreatedAt:  time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC),
+	}
+	m.llmPromptVersions = append([]store.LLMPromptVersion{version}, m.llmPromptVersions...)
+	return version, nil
+}
+
+func (m *mockStore) ListLLMPromptVersions(_ context.Context, workflow, purpose string) ([]store.LLMPromptVersion, error) {
+	out := make([]store.LLMPromptVersion, 0, len(m.llmPromptVersions))
+	for _, version := range m.llmPromptVersions {
+		if version.Workflow == workflow && version.Purpose == purpose {
+			out = append(out, version)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) GetActiveLLMPromptVersion(_ context.Context, workflow, purpose string) (store.LLMPromptVersion, bool, error) {
+	for _, version := range m.llmPromptVersions {
+		if version.Workflow == workflow && version.Purpose == purpose && version.Active {
+			return version, true, nil
+		}
+	}
+	return store.LLMPromptVersion{}, false, nil
+}
+
+func (m *mockStore) ActivateLLMPromptVersion(_ context.Context, workflow, purpose string, version int) (store.LLMPromptVersion, error) {
+	for i := range m.llmPromptVersions {
+		candidate := &m.llmPromptVersions[i]
+		if candidate.Workflow == workflow && candidate.Purpose == purpose && candidate.Version == version {
+			m.deactivatePromptVersions(workflow, purpose)
+			candidate.Active = true
+			return *candidate, nil
+		}
+	}
+	return store.LLMPromptVersion{}, errors.E(errors.NotFound, errors.User("Prompt version "+strconv.Itoa(version)+" was not found."))
+}
+
+func (m *mockStore) DeactivateLLMPromptVersions(_ context.Context, workflow, purpose string) error {
+	m.deactivatePromptVersions(workflow, purpose)
+	return nil
+}
+
+func (m *mockStore) deactivatePromptVersions(workflow, purpose string) {
+	for i := range m.llmPromptVersions {
+		if m.llmPromptVersions[i].Workflow == workflow && m.llmPromptVersions[i].Purpose == purpose {
+			m.llmPromptVersions[i].Active = false
+		}
+	}
+}
+
+func (m *mockStore) SumLLMUsageTokens(_ context.Context, tenant store.Tenant, since time.Time) (int64, error) {
+	m.llmUsageSince = append(m.llmUsageSince, since)
+	return m.llmUsageTokens[tenant.ID], nil
+}
+
+func (m *mockStore) ListLLMUsage(_ context.Context, filter store.LLMUsageFilter) ([]store.LLMUsageBucket, error) {
+	m.llmUsageFilter = filter
+	return m.llmUsageBuckets, nil
+}
+
+func (m *mockStore) GetLLMUsageQuota(_ context.Context, tenant store.Tenant) (store.LLMUsageQuota, error) {
+	if quota, ok := m.llmUsageQuotas[tenant.ID]; ok {
+		return quota, nil
+	}
+	return store.LLMUsageQuota{TenantID: tenant.ID}, nil
+}
+
+func (m *mockStore) SetLLMUsageQuota(_ context.Context, tenant store.Tenant, quota store.LLMUsageQuota) (store.LLMUsageQuota, error) {
+	if m.llmUsageQuotas == nil {
+		m.llmUsageQuotas = make(map[string]store.LLMUsageQuota)
+	}
+	updatedAt := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)
+	quota.TenantID = tenant.ID
+	quota.UpdatedAt = &updatedAt
+	m.llmUsageQuotas[tenant.ID] = quota
+	return quota, nil
+}
+
 func (m *mockStore) GetFacets(_ context.Context, _ store.Tenant) (*store.Facets, error) {
 	if m.getFacetsErr != nil {
 		return nil, mockStoreErr("store.transactions.facets", m.getFacetsErr)
@@ -868,6 +1124,775 @@ func (m *mockStore) RemoveBucketByMerchant(_ context.Context, _ store.Tenant, _,
 	return 1, nil
 }
 
+func (m *mockStore) CreateTransaction(_ context.Context, _ store.Tenant, input store.CreateTransactionInput) (*store.Transaction, error) {
+	if m.createTxErr != nil {
+		return nil, mockStoreErr("store.transactions.create", m.createTxErr)
+	}
+	m.createdTransaction = input
+	return &store.Transaction{
+		ID:           "00000000-0000-0000-0000-0000000000aa",
+		MessageID:    "manual:00000000-0000-0000-0000-0000000000aa",
+		Amount:       input.Amount,
+		Currency:     input.Currency,
+		Timestamp:    input.Timestamp,
+		MerchantInfo: input.MerchantInfo,
+		Category:     input.Category,
+		Bucket:       input.Bucket,
+		Source:       api.Source{Type: store.SourceTypeManual, Label: input.SourceLabel},
+		Description:  input.Description,
+		Labels:       input.Labels,
+	}, nil
+}
+
+func (m *mockStore) DeleteTransaction(_ context.Context, _ store.Tenant, id string) error {
+	m.deletedTransactionID = id
+	return mockStoreErr("store.transactions.delete", m.deleteTxErr)
+}
+
+func (m *mockStore) SetTransactionSplits(_ context.Context, _ store.Tenant, _ string, splits []store.TransactionSplitInput) error {
+	if m.setSplitsErr != nil {
+		return mockStoreErr("store.transactions.set_splits", m.setSplitsErr)
+	}
+	m.transactionSplits = splits
+	return nil
+}
+
+func (m *mockStore) CreateAttachment(_ context.Context, _ store.Tenant, transactionID string, input store.NewAttachment) (*store.Attachment, error) {
+	if m.attachmentErr != nil {
+		return nil, mockStoreErr("store.attachments.create", m.attachmentErr)
+	}
+	m.createdAttachment = input
+	attachment := store.Attachment{
+		ID:            "99999999-9999-9999-9999-999999999999",
+		TransactionID: transactionID,
+		Filename:      input.Filename,
+		ContentType:   input.ContentType,
+		Size:          int64(len(input.Data)),
+		Source:        input.Source,
+	}
+	m.attachments = append(m.attachments, attachment)
+	return &attachment, nil
+}
+
+func (m *mockStore) ListAttachments(_ context.Context, _ store.Tenant, _ string) ([]store.Attachment, error) {
+	if m.attachmentErr != nil {
+		return nil, mockStoreErr("store.attachments.list", m.attachmentErr)
+	}
+	return m.attachments, nil
+}
+
+func (m *mockStore) GetAttachment(_ context.Context, _ store.Tenant, _, attachmentID string) (*store.Attachment, []byte, error) {
+	if m.attachmentErr != nil {
+		return nil, nil, mockStoreErr("store.attachments.get", m.attachmentErr)
+	}
+	for i := range m.attachments {
+		if m.attachments[i].ID == attachmentID {
+			return &m.attachments[i], m.attachmentContent, nil
+		}
+	}
+	return nil, nil, mockStoreErr("store.attachments.get", errStoreNotFound)
+}
+
+func (m *mockStore) DeleteAttachment(_ context.Context, _ store.Tenant, _, attachmentID string) error {
+	if m.attachmentErr != nil {
+		return mockStoreErr("store.attachments.delete", m.attachmentErr)
+	}
+	m.deletedAttachmentID = attachmentID
+	return nil
+}
+
+func (m *mockStore) ExportTenantArchive(_ context.Context, tenant store.Tenant) (*store.TenantArchive, error) {
+	if m.archiveErr != nil {
+		return nil, mockStoreErr("store.archives.export", m.archiveErr)
+	}
+	if m.tenantArchive != nil {
+		return m.tenantArchive, nil
+	}
+	archive := &store.TenantArchive{
+		Format:  store.TenantArchiveFormat,
+		Version: store.TenantArchiveVersion,
+		Tenant:  store.ArchiveTenant{ID: tenant.ID},
+	}
+	archive.CountSections()
+	return archive, nil
+}
+
+func (m *mockStore) ImportTenantArchive(
+	_ context.Context,
+	_ store.Tenant,
+	archive store.TenantArchive,
+	policy store.ArchiveConflictPolicy,
+) (store.TenantImportResult, error) {
+	if m.archiveErr != nil {
+		return store.TenantImportResult{}, mockStoreErr("store.archives.import", m.archiveErr)
+	}
+	m.importedArchive = &archive
+	m.importPolicy = policy
+	result := m.importResult
+	result.Policy = policy
+	return result, nil
+}
+
+func (m *mockStore) GetBackupSettings(context.Context) (store.BackupSettings, error) {
+	if m.backupSettingsErr != nil {
+		return store.BackupSettings{}, mockStoreErr("store.backups.get_settings", m.backupSettingsErr)
+	}
+	return m.backupSettings, nil
+}
+
+func (m *mockStore) PatchBackupSettings(_ context.Context, patch store.BackupSettingsPatch) (store.BackupSettings, error) {
+	if m.backupSettingsErr != nil {
+		return store.BackupSettings{}, mockStoreErr("store.backups.patch_settings", m.backupSettingsErr)
+	}
+	m.backupSettingsPatch = patch
+	if patch.Enabled != nil {
+		m.backupSettings.Enabled = *patch.Enabled
+	}
+	if patch.KeepLast != nil {
+		m.backupSettings.KeepLast = *patch.KeepLast
+	}
+	return m.backupSettings, nil
+}
+
+func (m *mockStore) RecordAuditEvent(_ context.Context, event store.NewAuditEvent) error {
+	if m.auditErr != nil {
+		return mockStoreErr("store.audit.record", m.auditErr)
+	}
+	m.auditEvents = append(m.auditEvents, event)
+	return nil
+}
+
+func (m *mockStore) ListAuditEvents(_ context.Context, filter store.AuditEventFilter) ([]store.AuditEvent, int, error) {
+	if m.auditErr != nil {
+		return nil, 0, mockStoreErr("store.audit.list", m.auditErr)
+	}
+	m.auditFilter = filter
+	events := make([]store.AuditEvent, 0, len(m.auditEvents))
+	for i, event := range m.auditEvents {
+		if filter.Action != "" && event.Action != filter.Action {
+			continue
+		}
+		if filter.Outcome != "" && event.Outcome != filter.Outcome {
+			continue
+		}
+		events = append(events, store.AuditEvent{
+			ID:          fmt.Sprintf("audit-%d", i+1),
+			ActorUserID: event.ActorUserID,
+			TenantID:    event.TenantID,
+			AuthMethod:  event.AuthMethod,
+			Action:      event.Action,
+			TargetType:  event.TargetType,
+			TargetID:    event.TargetID,
+			RequestID:   event.RequestID,
+			IPAddress:   event.IPAddress,
+			Outcome:     event.Outcome,
+			Detail:      event.Detail,
+		})
+	}
+	return events, len(events), nil
+}
+
+func (m *mockStore) GetAuditSettings(context.Context) (store.AuditSettings, error) {
+	if m.auditErr != nil {
+		return store.AuditSettings{}, mockStoreErr("store.audit.get_settings", m.auditErr)
+	}
+	return m.auditSettings, nil
+}
+
+func (m *mockStore) PatchAuditSettings(_ context.Context, patch store.AuditSettingsPatch) (store.AuditSettings, error) {
+	if m.auditErr != nil {
+		return store.AuditSettings{}, mockStoreErr("store.audit.patch_settings", m.auditErr)
+	}
+	m.auditSettingsPatch = patch
+	if patch.RetentionDays != nil {
+		m.auditSettings.RetentionDays = *patch.RetentionDays
+	}
+	return m.auditSettings, nil
+}
+
+func (m *mockStore) PruneAuditEvents(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockStore) ListWebhooks(_ context.Context, tenant store.Tenant) ([]store.Webhook, error) {
+	if m.webhookErr != nil {
+		return nil, mockStoreErr("store.webhooks.list", m.webhookErr)
+	}
+	webhooks := []store.Webhook{}
+	for _, webhook := range m.webhooks {
+		if webhook.TenantID == tenant.ID {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (m *mockStore) GetWebhook(_ context.Context, tenant store.Tenant, id string) (*store.Webhook, error) {
+	if m.webhookErr != nil {
+		return nil, mockStoreErr("store.webhooks.get", m.webhookErr)
+	}
+	webhook, ok := m.webhooks[id]
+	if !ok || webhook.TenantID != tenant.ID {
+		return nil, errors.E("store.webhooks.get", errors.NotFound, errors.User("webhook not found"))
+	}
+	return webhook, nil
+}
+
+func (m *mockStore) CreateWebhook(_ context.Context, tenant store.Tenant, input store.NewWebhook) (*store.Webhook, error) {
+	if m.webhookErr != nil {
+		return nil, mockStoreErr("store.webhooks.create", m.webhookErr)
+	}
+	m.createdWebhook = input
+	webhook := &store.Webhook{
+		ID:          "55555555-5555-5555-5555-555555555555",
+		TenantID:    tenant.ID,
+		URL:         input.URL,
+		Description: input.Description,
+		Events:      input.Events,
+		Enabled:     input.Enabled,
+	}
+	if m.webhooks == nil {
+		m.webhooks = map[string]*store.Webhook{}
+	}
+	m.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+func (m *mockStore) UpdateWebhook(ctx context.Context, tenant store.Tenant, id string, patch store.WebhookPatch) (*store.Webhook, error) {
+	webhook, err := m.GetWebhook(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	m.webhookPatch = patch
+	if patch.URL != nil {
+		webhook.URL = *patch.URL
+	}
+	if patch.Description != nil {
+		webhook.Description = *patch.Description
+	}
+	if patch.Events != nil {
+		webhook.Events = patch.Events
+	}
+	if patch.Enabled != nil {
+		webhook.Enabled = *patch.Enabled
+	}
+	return webhook, nil
+}
+
+func (m *mockStore) DeleteWebhook(ctx context.Context, tenant store.Tenant, id string) error {
+	if _, err := m.GetWebhook(ctx, tenant, id); err != nil {
+		return err
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *mockStore) ListWebhookDeliveries(_ context.Context, _ store.Tenant, _ string, limit int) ([]store.WebhookDelivery, error) {
+	if m.webhookErr != nil {
+		return nil, mockStoreErr("store.webhooks.list_deliveries", m.webhookErr)
+	}
+	m.webhookDeliveryLimit = limit
+	return m.webhookDeliveries, nil
+}
+
+func (m *mockStore) GetNotificationPreferences(context.Context, store.Tenant, string) (*store.NotificationPreferences, error) {
+	if m.notificationPrefs == nil {
+		prefs := store.DefaultNotificationPreferences()
+		return &prefs, nil
+	}
+	return m.notificationPrefs, nil
+}
+
+func (m *mockStore) UpdateNotificationPreferences(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	patch store.NotificationPreferencesPatch,
+) (*store.NotificationPreferences, error) {
+	prefs, err := m.GetNotificationPreferences(ctx, tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.notificationPrefsPatch = patch
+	if patch.WeeklyDigest != nil {
+		prefs.WeeklyDigest = *patch.WeeklyDigest
+	}
+	if patch.DigestWeekday != nil {
+		prefs.DigestWeekday = *patch.DigestWeekday
+	}
+	if patch.DigestHour != nil {
+		prefs.DigestHour = *patch.DigestHour
+	}
+	prefs.UpdatedAt = time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	m.notificationPrefs = prefs
+	return prefs, nil
+}
+
+func (m *mockStore) ListNotificationChannels(_ context.Context, tenant store.Tenant, userID string) ([]store.NotificationChannel, error) {
+	channels := []store.NotificationChannel{}
+	for _, channel := range m.notificationChannels {
+		if channel.TenantID == tenant.ID && channel.UserID == userID {
+			channels = append(channels, *channel)
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].ID < channels[j].ID })
+	return channels, nil
+}
+
+func (m *mockStore) OpenNotificationChannel(_ context.Context, tenant store.Tenant, userID, id string) (*store.NotificationChannel, string, error) {
+	channel, ok := m.notificationChannels[id]
+	if !ok || channel.TenantID != tenant.ID || channel.UserID != userID {
+		return nil, "", errors.E("store.notifications.open_channel", errors.NotFound, errors.User("notification channel not found"))
+	}
+	return channel, m.notificationTokens[id], nil
+}
+
+func (m *mockStore) CreateNotificationChannel(
+	_ context.Context,
+	tenant store.Tenant,
+	userID string,
+	input store.NewNotificationChannel,
+) (*store.NotificationChannel, error) {
+	m.createdNotificationChannel = input
+	channel := &store.NotificationChannel{
+		ID:       "77777777-7777-7777-7777-777777777777",
+		TenantID: tenant.ID,
+		UserID:   userID,
+		Type:     input.Type,
+		Name:     input.Name,
+		Target:   input.Target,
+		HasToken: input.Token != "",
+		Enabled:  input.Enabled,
+	}
+	if m.notificationChannels == nil {
+		m.notificationChannels = map[string]*store.NotificationChannel{}
+	}
+	m.notificationChannels[channel.ID] = channel
+	return channel, nil
+}
+
+func (m *mockStore) UpdateNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, id string,
+	patch store.NotificationChannelPatch,
+) (*store.NotificationChannel, error) {
+	channel, _, err := m.OpenNotificationChannel(ctx, tenant, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	m.notificationChannelPatch = patch
+	if patch.Name != nil {
+		channel.Name = *patch.Name
+	}
+	if patch.Target != nil {
+		channel.Target = *patch.Target
+	}
+	if patch.Token != nil {
+		channel.HasToken = *patch.Token != ""
+	}
+	if patch.Enabled != nil {
+		channel.Enabled = *patch.Enabled
+	}
+	return channel, nil
+}
+
+func (m *mockStore) DeleteNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) error {
+	if _, _, err := m.OpenNotificationChannel(ctx, tenant, userID, id); err != nil {
+		return err
+	}
+	delete(m.notificationChannels, id)
+	return nil
+}
+
+func (m *mockStore) GetMFAFactors(context.Context, string) (store.MFAFactors, error) {
+	if m.mfaErr != nil {
+		return store.MFAFactors{}, mockStoreErr("store.mfa.get_factors", m.mfaErr)
+	}
+	factors := store.MFAFactors{TOTP: m.totpFactor != nil && m.totpFactor.ConfirmedAt != nil, Passkeys: len(m.passkeys)}
+	for _, used := range m.recoveryCodeHashes {
+		if !used {
+			factors.RecoveryCodes++
+		}
+	}
+	return factors, nil
+}
+
+func (m *mockStore) GetTOTPFactor(context.Context, string) (*store.TOTPFactor, error) {
+	if m.totpFactor == nil {
+		return nil, mockStoreErr("store.mfa.get_totp_factor", errStoreNotFound)
+	}
+	factor := *m.totpFactor
+	return &factor, nil
+}
+
+func (m *mockStore) SetPendingTOTP(_ context.Context, _ string, secret []byte) error {
+	if m.totpFactor != nil && m.totpFactor.ConfirmedAt != nil {
+		return mockStoreErr("store.mfa.set_pending_totp", errors.E(errors.Conflict, errors.User("TOTP is already enabled")))
+	}
+	m.totpFactor = &store.TOTPFactor{Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m *mockStore) ConfirmTOTP(_ context.Context, _ string, step int64) error {
+	if m.totpFactor == nil || m.totpFactor.ConfirmedAt != nil {
+		return mockStoreErr("store.mfa.confirm_totp", errStoreNotFound)
+	}
+	now := time.Now()
+	m.totpFactor.ConfirmedAt = &now
+	m.totpFactor.LastUsedStep = step
+	return nil
+}
+
+func (m *mockStore) UseTOTPStep(_ context.Context, _ string, step int64) error {
+	if m.totpFactor == nil || m.totpFactor.ConfirmedAt == nil || m.totpFactor.LastUsedStep >= step {
+		return mockStoreErr("store.mfa.use_totp_step", errors.E(errors.Conflict, errors.User("code already used")))
+	}
+	m.totpFactor.LastUsedStep = step
+	return nil
+}
+
+func (m *mockStore) DeleteTOTP(context.Context, string) error {
+	if m.totpFactor == nil {
+		return mockStoreErr("store.mfa.delete_totp", errStoreNotFound)
+	}
+	m.totpFactor = nil
+	return nil
+}
+
+func (m *mockStore) ReplaceRecoveryCodes(_ context.Context, _ string, codeHashes []string) error {
+	m.recoveryCodeHashes = make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		m.recoveryCodeHashes[hash] = false
+	}
+	return nil
+}
+
+func (m *mockStore) UseRecoveryCode(_ context.Context, _ string, codeHash string) error {
+	if used, ok := m.recoveryCodeHashes[codeHash]; !ok || used {
+		return mockStoreErr("store.mfa.use_recovery_code", errStoreNotFound)
+	}
+	m.recoveryCodeHashes[codeHash] = true
+	return nil
+}
+
+func (m *mockStore) CreatePasskey(_ context.Context, input store.CreatePasskeyInput) (*store.Passkey, error) {
+	passkey := store.Passkey{
+		ID:           fmt.Sprintf("00000000-0000-0000-0000-%012d", len(m.passkeys)+1),
+		UserID:       input.UserID,
+		Name:         input.Name,
+		CredentialID: input.CredentialID,
+		PublicKey:    input.PublicKey,
+		Algorithm:    input.Algorithm,
+		SignCount:    input.SignCount,
+		Transports:   input.Transports,
+		CreatedAt:    time.Now(),
+	}
+	m.passkeys = append(m.passkeys, passkey)
+	return &passkey, nil
+}
+
+func (m *mockStore) ListPasskeys(context.Context, string) ([]store.Passkey, error) {
+	return append([]store.Passkey(nil), m.passkeys...), nil
+}
+
+func (m *mockStore) UsePasskey(_ context.Context, id string, signCount uint32) error {
+	for i := range m.passkeys {
+		if m.passkeys[i].ID == id {
+			now := time.Now()
+			m.passkeys[i].SignCount = signCount
+			m.passkeys[i].LastUsedAt = &now
+			return nil
+		}
+	}
+	return mockStoreErr("store.mfa.use_passkey", errStoreNotFound)
+}
+
+func (m *mockStore) DeletePasskey(_ context.Context, _ string, id string) error {
+	for i := range m.passkeys {
+		if m.passkeys[i].ID == id {
+			m.passkeys = append(m.passkeys[:i], m.passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return mockStoreErr("store.mfa.delete_passkey", errStoreNotFound)
+}
+
+func (m *mockStore) GetMFASettings(context.Context) (store.MFASettings, error) {
+	if m.mfaErr != nil {
+		return store.MFASettings{}, mockStoreErr("store.mfa.get_settings", m.mfaErr)
+	}
+	return m.mfaSettings, nil
+}
+
+func (m *mockStore) PatchMFASettings(_ context.Context, patch store.MFASettingsPatch) (store.MFASettings, error) {
+	if patch.Required != nil {
+		m.mfaSettings.Required = *patch.Required
+	}
+	m.mfaSettings.UpdatedAt = time.Now()
+	return m.mfaSettings, nil
+}
+
+func (m *mockStore) CreateSharedLedger(_ context.Context, userID string, input store.CreateSharedLedgerInput) (store.SharedLedger, error) {
+	if m.sharedLedgerErr != nil {
+		return store.SharedLedger{}, mockStoreErr("store.shared_ledgers.create", m.sharedLedgerErr)
+	}
+	m.sharedLedgerUserID = userID
+	m.createdSharedLedger = input
+	ledger := store.SharedLedger{
+		ID:        "33333333-3333-3333-3333-333333333333",
+		Name:      input.Name,
+		CreatedBy: userID,
+		Members:   []store.SharedLedgerMember{{UserID: userID}},
+	}
+	m.sharedLedgers = append(m.sharedLedgers, ledger)
+	return ledger, nil
+}
+
+func (m *mockStore) ListSharedLedgers(_ context.Context, userID string) ([]store.SharedLedger, error) {
+	m.sharedLedgerUserID = userID
+	if m.sharedLedgerErr != nil {
+		return nil, mockStoreErr("store.shared_ledgers.list", m.sharedLedgerErr)
+	}
+	return m.sharedLedgers, nil
+}
+
+func (m *mockStore) GetSharedLedger(_ context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	m.sharedLedgerUserID = userID
+	if m.sharedLedgerErr != nil {
+		return store.SharedLedger{}, mockStoreErr("store.shared_ledgers.get", m.sharedLedgerErr)
+	}
+	for _, ledger := range m.sharedLedgers {
+		if ledger.ID == ledgerID {
+			return ledger, nil
+		}
+	}
+	return store.SharedLedger{}, mockStoreErr("store.shared_ledgers.get", errStoreNotFound)
+}
+
+func (m *mockStore) InviteSharedLedgerMember(_ context.Context, userID, _, email string) error {
+	m.sharedLedgerUserID = userID
+	if m.sharedLedgerErr != nil {
+		return mockStoreErr("store.shared_ledgers.invite_member", m.sharedLedgerErr)
+	}
+	m.invitedLedgerEmail = email
+	return nil
+}
+
+func (m *mockStore) ListSharedLedgerInvitations(_ context.Context, userID string) ([]store.SharedLedgerInvitation, error) {
+	m.sharedLedgerUserID = userID
+	return m.ledgerInvitations, nil
+}
+
+func (m *mockStore) AcceptSharedLedgerInvitation(_ context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	m.sharedLedgerUserID = userID
+	for _, inv := range m.ledgerInvitations {
+		if inv.LedgerID == ledgerID {
+			return store.SharedLedger{ID: ledgerID, Name: inv.LedgerName, CreatedBy: inv.InvitedBy}, nil
+		}
+	}
+	return store.SharedLedger{}, mockStoreErr("store.shared_ledgers.accept_invitation", errStoreNotFound)
+}
+
+func (m *mockStore) DeclineSharedLedgerInvitation(_ context.Context, userID, ledgerID string) error {
+	m.sharedLedgerUserID = userID
+	m.declinedLedgerInvitation = ledgerID
+	return nil
+}
+
+func (m *mockStore) ShareExpense(
+	_ context.Context,
+	_ store.Tenant,
+	userID, ledgerID string,
+	input store.ShareExpenseInput,
+) (store.SharedExpense, error) {
+	if m.sharedLedgerErr != nil {
+		return store.SharedExpense{}, mockStoreErr("store.shared_ledgers.share_expense", m.sharedLedgerErr)
+	}
+	m.sharedLedgerUserID = userID
+	m.sharedExpenseInput = input
+	expense := store.SharedExpense{
+		ID:            "55555555-5555-5555-5555-555555555555",
+		LedgerID:      ledgerID,
+		TransactionID: input.TransactionID,
+		SplitID:       input.SplitID,
+		PaidBy:        userID,
+		Amount:        100,
+		Currency:      "INR",
+		Shares:        store.ShareAmounts(100, input.Shares),
+	}
+	m.sharedExpenses = append(m.sharedExpenses, expense)
+	return expense, nil
+}
+
+func (m *mockStore) ListSharedExpenses(_ context.Context, userID, _ string) ([]store.SharedExpense, error) {
+	m.sharedLedgerUserID = userID
+	if m.sharedLedgerErr != nil {
+		return nil, mockStoreErr("store.shared_ledgers.list_expenses", m.sharedLedgerErr)
+	}
+	return m.sharedExpenses, nil
+}
+
+func (m *mockStore) DeleteSharedExpense(_ context.Context, userID, _, expenseID string) error {
+	m.sharedLedgerUserID = userID
+	m.deletedSharedExpenseID = expenseID
+	return mockStoreErr("store.shared_ledgers.delete_expense", m.sharedLedgerErr)
+}
+
+func (m *mockStore) RecordSettlement(_ context.Context, userID, ledgerID string, input store.RecordSettlementInput) (store.Settlement, error) {
+	if m.sharedLedgerErr != nil {
+		return store.Settlement{}, mockStoreErr("store.shared_ledgers.record_settlement", m.sharedLedgerErr)
+	}
+	m.sharedLedgerUserID = userID
+	m.recordedSettlement = input
+	settlement := store.Settlement{
+		ID:         "66666666-6666-6666-6666-666666666666",
+		LedgerID:   ledgerID,
+		FromUserID: input.FromUserID,
+		ToUserID:   input.ToUserID,
+		Amount:     input.Amount,
+		Currency:   input.Currency,
+		Note:       input.Note,
+		SettledAt:  input.SettledAt,
+		CreatedBy:  userID,
+	}
+	m.settlements = append(m.settlements, settlement)
+	return settlement, nil
+}
+
+func (m *mockStore) ListSettlements(_ context.Context, userID, _ string) ([]store.Settlement, error) {
+	m.sharedLedgerUserID = userID
+	if m.sharedLedgerErr != nil {
+		return nil, mockStoreErr("store.shared_ledgers.list_settlements", m.sharedLedgerErr)
+	}
+	return m.settlements, nil
+}
+
+func (m *mockStore) ListLedgerBalances(_ context.Context, _, _ string) ([]store.LedgerBalance, error) {
+	if m.sharedLedgerErr != nil {
+		return nil, mockStoreErr("store.shared_ledgers.list_balances", m.sharedLedgerErr)
+	}
+	return m.ledgerBalances, nil
+}
+
+func (m *mockStore) CreateTenant(_ context.Context, _ string, input store.CreateTenantInput) (store.TenantMembership, error) {
+	if m.tenantErr != nil {
+		return store.TenantMembership{}, mockStoreErr("store.tenants.create", m.tenantErr)
+	}
+	m.createdTenant = input
+	membership := store.TenantMembership{TenantID: "77777777-7777-7777-7777-777777777777", TenantName: input.Name, Role: store.TenantRoleOwner}
+	m.tenantMemberships = append(m.tenantMemberships, membership)
+	return membership, nil
+}
+
+func (m *mockStore) ListActiveTenants(context.Context) ([]store.Tenant, error) {
+	return nil, nil
+}
+
+func (m *mockStore) ListTenantMemberships(_ context.Context, userID string) ([]store.TenantMembership, error) {
+	if m.tenantErr != nil {
+		return nil, mockStoreErr("store.tenants.list_memberships", m.tenantErr)
+	}
+	memberships := []store.TenantMembership{{TenantID: userID, TenantName: "Personal", Role: store.TenantRoleOwner, Personal: true}}
+	return append(memberships, m.tenantMemberships...), nil
+}
+
+// GetTenantMembership treats every user as the owner of their personal
+// tenant, matching the row insertUser writes.
+func (m *mockStore) GetTenantMembership(_ context.Context, tenant store.Tenant, userID string) (store.TenantMembership, error) {
+	if m.tenantErr != nil {
+		return store.TenantMembership{}, mockStoreErr("store.tenants.get_membership", m.tenantErr)
+	}
+	if tenant.ID == userID {
+		return store.TenantMembership{TenantID: userID, TenantName: "Personal", Role: store.TenantRoleOwner, Personal: true}, nil
+	}
+	for _, membership := range m.tenantMemberships {
+		if membership.TenantID == tenant.ID {
+			return membership, nil
+		}
+	}
+	return store.TenantMembership{}, mockStoreErr("store.tenants.get_membership", errStoreNotFound)
+}
+
+func (m *mockStore) ListTenantMembers(_ context.Context, _ store.Tenant) ([]store.TenantMember, error) {
+	if m.tenantErr != nil {
+		return nil, mockStoreErr("store.tenants.list_members", m.tenantErr)
+	}
+	return []store.TenantMember{m.updatedTenantMember}, nil
+}
+
+func (m *mockStore) InviteTenantMember(_ context.Context, _ store.Tenant, _, email string, role store.TenantRole) error {
+	m.invitedTenantEmail = email
+	m.invitedTenantRole = role
+	return mockStoreErr("store.tenants.invite_member", m.tenantErr)
+}
+
+func (m *mockStore) ListTenantInvitations(context.Context, string) ([]store.TenantInvitation, error) {
+	return append([]store.TenantInvitation{}, m.tenantInvitations...), mockStoreErr("store.tenants.list_invitations", m.tenantErr)
+}
+
+func (m *mockStore) AcceptTenantInvitation(_ context.Context, tenant store.Tenant, _ string) (store.TenantMembership, error) {
+	for i, invitation := range m.tenantInvitations {
+		if invitation.TenantID == tenant.ID {
+			m.tenantInvitations = append(m.tenantInvitations[:i], m.tenantInvitations[i+1:]...)
+			membership := store.TenantMembership{TenantID: tenant.ID, TenantName: invitation.TenantName, Role: invitation.Role}
+			m.tenantMemberships = append(m.tenantMemberships, membership)
+			return membership, nil
+		}
+	}
+	return store.TenantMembership{}, mockStoreErr("store.tenants.invitation", errStoreNotFound)
+}
+
+func (m *mockStore) DeclineTenantInvitation(_ context.Context, tenant store.Tenant, _ string) error {
+	for i, invitation := range m.tenantInvitations {
+		if invitation.TenantID == tenant.ID {
+			m.tenantInvitations = append(m.tenantInvitations[:i], m.tenantInvitations[i+1:]...)
+			return nil
+		}
+	}
+	return mockStoreErr("store.tenants.invitation", errStoreNotFound)
+}
+
+func (m *mockStore) UpdateTenantMemberRole(_ context.Context, _ store.Tenant, userID string, role store.TenantRole) (store.TenantMember, error) {
+	m.updatedTenantMember = store.TenantMember{UserID: userID, Role: role}
+	return m.updatedTenantMember, mockStoreErr("store.tenants.update_member_role", m.tenantErr)
+}
+
+func (m *mockStore) RemoveTenantMember(_ context.Context, _ store.Tenant, userID string) error {
+	m.removedTenantMember = userID
+	return mockStoreErr("store.tenants.remove_member", m.tenantErr)
+}
+
+func (m *mockStore) SetSessionTenant(_ context.Context, _ string, tenant store.Tenant) error {
+	m.sessionTenantID = tenant.ID
+	m.sessionTenantSet = true
+	return mockStoreErr("store.tenants.set_session_tenant", m.tenantErr)
+}
+
+func (m *mockStore) BulkUpdateTransactions(_ context.Context, _ store.Tenant, input store.BulkTransactionInput) (store.BulkTransactionResult, error) {
+	m.bulkInput = input
+	if m.bulkErr != nil {
+		return store.BulkTransactionResult{}, mockStoreErr("store.transactions.bulk_update", m.bulkErr)
+	}
+	return m.bulkResult, nil
+}
+
+func (m *mockStore) ListTransactionHistory(_ context.Context, _ store.Tenant, _ string) ([]store.TransactionChange, error) {
+	if m.historyErr != nil {
+		return nil, mockStoreErr("store.transactions.list_history", m.historyErr)
+	}
+	return m.transactionHistory, nil
+}
+
+func (m *mockStore) RevertChangeBatch(_ context.Context, _ store.Tenant, batchID string) (store.ChangeBatchRevert, error) {
+	m.revertedBatchID = batchID
+	if m.revertErr != nil {
+		return store.ChangeBatchRevert{}, mockStoreErr("store.transactions.revert_change_batch", m.revertErr)
+	}
+	return m.revertResult, nil
+}
+
 func (m *mockStore) UpdateTransaction(_ context.Context, _ store.Tenant, _ string, update store.TransactionUpdate) error {
 	m.updatedTransaction = update
 	return mockStoreErr("store.transactions.update", m.updateTxErr)
@@ -1177,6 +2202,25 @@ func (s *stubRuleDraftService) DraftRule(
 	return s.result, s.err
 }
 
+type stubTransactionQueryService struct {
+	result assistant.TransactionQueryResult
+	err    error
+	input  assistant.TransactionQueryInput
+	tenant store.Tenant
+	called bool
+}
+
+func (s *stubTransactionQueryService) QueryTransactions(
+	_ context.Context,
+	tenant store.Tenant,
+	input assistant.TransactionQueryInput,
+) (assistant.TransactionQueryResult, error) {
+	s.called = true
+	s.tenant = tenant
+	s.input = input
+	return s.result, s.err
+}
+
 func get(h http.HandlerFunc, target string) *httptest.ResponseRecorder {
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
 	rr := httptest.NewRecorder()
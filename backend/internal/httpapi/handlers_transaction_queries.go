@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ArionMiles/expensor/backend/internal/assistant"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type transactionQueryService interface {
+	QueryTransactions(ctx context.Context, tenant store.Tenant, input assistant.TransactionQueryInput) (assistant.TransactionQueryResult, error)
+}
+
+type transactionQueryRequestJSON struct {
+	Question string `json:"question" validate:"required,max=1000,no_control_chars"`
+	Timezone string `json:"timezone" validate:"omitempty,iana_timezone"`
+}
+
+type transactionQueryResponseJSON struct {
+	Answer  string                     `json:"answer"`
+	Queries []transactionQueryStepJSON `json:"queries"`
+}
+
+type transactionQueryStepJSON struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// CreateTransactionQuery handles POST /api/transaction-queries.
+// Answers a natural-language question using read-only transaction lookups and
+// returns the exact tool calls and results the answer was based on.
+//
+// @Summary Ask a question about transactions using the active LLM provider
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param request body TransactionQueryRequest true "Transaction question"
+// @Success 200 {object} TransactionQueryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transaction-queries [post]
+func (h *Handlers) CreateTransactionQuery(w http.ResponseWriter, r *http.Request) {
+	if h.transactionQueries == nil {
+		writeError(w, r, errors.E(errors.Unavailable, errors.User("transaction questions are not configured")))
+		return
+	}
+	body, ok := decodeAndValidateJSON[transactionQueryRequestJSON](h, w, r)
+	if !ok {
+		return
+	}
+	tenant := requestTenant(r)
+	result, err := h.transactionQueries.QueryTransactions(r.Context(), tenant, assistant.TransactionQueryInput{
+		Question:     body.Question,
+		Timezone:     h.resolveTimezone(r.Context(), tenant, body.Timezone),
+		BaseCurrency: h.currentBaseCurrency(r.Context(), tenant),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	queries := make([]transactionQueryStepJSON, 0, len(result.Queries))
+	for _, step := range result.Queries {
+		queries = append(queries, transactionQueryStepJSON{
+			Tool:      step.Tool,
+			Arguments: step.Arguments,
+			Result:    step.Result,
+			Error:     step.Error,
+		})
+	}
+	writeJSON(w, http.StatusOK, transactionQueryResponseJSON{Answer: result.Answer, Queries: queries})
+}
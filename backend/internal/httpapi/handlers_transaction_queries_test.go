@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/assistant"
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+)
+
+func TestCreateTransactionQueryReturnsAnswerAndQueries(t *testing.T) {
+	service := &stubTransactionQueryService{result: assistant.TransactionQueryResult{
+		Answer: "You spent INR 450.00 on food delivery in March.",
+		Queries: []assistant.TransactionQueryStep{{
+			Tool:      "list_transactions",
+			Arguments: json.RawMessage(`{"category":"Food Delivery","from":"2026-03-01","to":"2026-03-31"}`),
+			Result:    json.RawMessage(`{"total":2,"total_amount":450,"transactions":[]}`),
+		}},
+	}}
+	h := newTestHandlers(t, &mockStore{appConfig: map[string]string{"base_currency": "INR", "app.timezone": "Asia/Kolkata"}}, &mockDaemon{})
+	h.transactionQueries = service
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/transaction-queries",
+		strings.NewReader(`{"question":"How much did we spend on food delivery in March?"}`))
+	rr := httptest.NewRecorder()
+
+	h.CreateTransactionQuery(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rr.Code, rr.Body.String())
+	}
+	if service.tenant.ID != "tenant-a" {
+		t.Fatalf("tenant = %+v, want tenant-a", service.tenant)
+	}
+	if service.input.Timezone != "Asia/Kolkata" || service.input.BaseCurrency != "INR" {
+		t.Fatalf("input = %+v, want stored timezone and base currency", service.input)
+	}
+	var resp transactionQueryResponseJSON
+	decodeJSON(t, rr.Body.String(), &resp)
+	if resp.Answer != service.result.Answer || len(resp.Queries) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if resp.Queries[0].Tool != "list_transactions" || !strings.Contains(string(resp.Queries[0].Result), `"total_amount":450`) {
+		t.Fatalf("query = %+v, want list_transactions result", resp.Queries[0])
+	}
+}
+
+func TestCreateTransactionQueryValidatesRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{name: "missing question", body: `{"question":""}`, field: "question"},
+		{name: "invalid timezone", body: `{"question":"Total?","timezone":"Mars/Olympus"}`, field: "timezone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &stubTransactionQueryService{}
+			h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+			h.transactionQueries = service
+			req := httptest.NewRequest(http.MethodPost, "/api/transaction-queries", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			h.CreateTransactionQuery(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d body=%s", rr.Code, rr.Body.String())
+			}
+			if service.called {
+				t.Fatal("service was called, want validation to stop request")
+			}
+			if !strings.Contains(rr.Body.String(), `"field":"`+tt.field+`"`) {
+				t.Fatalf("body = %s, want %s validation error", rr.Body.String(), tt.field)
+			}
+		})
+	}
+}
+
+func TestCreateTransactionQueryUnavailableWithoutService(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	req := httptest.NewRequest(http.MethodPost, "/api/transaction-queries", strings.NewReader(`{"question":"Total?"}`))
+	rr := httptest.NewRecorder()
+
+	h.CreateTransactionQuery(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d body=%s", rr.Code, rr.Body.String())
+	}
+}
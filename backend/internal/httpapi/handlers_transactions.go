@@ -1,8 +1,10 @@
 package httpapi
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/ArionMiles/expensor/backend/internal/store"
 )
@@ -41,7 +43,7 @@ import (
 // @Param hour_from query int false "Minimum hour filter (0-23)" minimum(0) maximum(23)
 // @Param hour_to query int false "Maximum hour filter (0-23)" minimum(0) maximum(23)
 // @Param tz query string false "IANA timezone used for weekday/hour filters"
-// @Param q query string false "Free-text search over merchant and description"
+// @Param q query string false "Search text; supports merchant:, description:, subject:, body:, label: and amount:>500 terms"
 // @Param sort_by query string false "Sort field" Enums(timestamp)
 // @Param sort_dir query string false "Sort direction" Enums(asc,desc)
 // @Success 200 {object} TransactionsListResponse
@@ -173,6 +175,246 @@ func (h *Handlers) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, txn)
 }
 
+// GetTransactionHistory handles GET /api/transactions/{id}/history.
+// @Summary List the logged changes of a transaction, newest first
+// @Tags Transactions
+// @Produce json
+// @Param id path string true "Transaction ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Success 200 {array} TransactionChangeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/{id}/history [get]
+func (h *Handlers) GetTransactionHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	changes, err := h.transactionStore.ListTransactionHistory(r.Context(), requestTenant(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// RevertChangeBatch handles POST /api/transactions/history/{batch_id}/revert.
+// Fields edited again after the batch keep their newer value.
+// @Summary Revert every change made by one write
+// @Tags Transactions
+// @Produce json
+// @Param batch_id path string true "Change batch ID" format(uuid) example(88888888-8888-8888-8888-888888888888)
+// @Success 200 {object} ChangeBatchRevertResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/history/{batch_id}/revert [post]
+func (h *Handlers) RevertChangeBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := uuidPathValue(w, r, "batch_id", "change batch")
+	if !ok {
+		return
+	}
+	result, err := h.transactionStore.RevertChangeBatch(r.Context(), requestTenant(r), batchID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// CreateTransaction handles POST /api/transactions.
+// Records a cash or otherwise email-less expense with the manual source type.
+// @Summary Create a manual transaction
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param request body TransactionCreateRequest true "Manual transaction payload"
+// @Success 201 {object} TransactionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions [post]
+func (h *Handlers) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodeAndValidateJSON[TransactionCreateRequest](h, w, r)
+	if !ok {
+		return
+	}
+	if body.Category != "" && !h.validateCategory(w, r, body.Category) {
+		return
+	}
+	if body.Bucket != "" && !h.validateBucket(w, r, body.Bucket) {
+		return
+	}
+	labels, ok := h.validateLabels(w, r, body.Labels)
+	if !ok {
+		return
+	}
+
+	input := store.CreateTransactionInput{
+		Amount:       body.Amount,
+		Currency:     body.Currency,
+		Timestamp:    time.Now().UTC(),
+		MerchantInfo: strings.TrimSpace(body.MerchantInfo),
+		Category:     body.Category,
+		Bucket:       body.Bucket,
+		Description:  strings.TrimSpace(body.Description),
+		SourceLabel:  strings.TrimSpace(body.SourceLabel),
+		Labels:       labels,
+	}
+	if input.Currency == "" {
+		input.Currency = h.currentBaseCurrency(r.Context(), requestTenant(r))
+	}
+	if body.Timestamp != nil {
+		input.Timestamp = *body.Timestamp
+	}
+	txn, err := h.transactionStore.CreateTransaction(r.Context(), requestTenant(r), input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, txn)
+}
+
+// DeleteTransaction handles DELETE /api/transactions/{id}.
+// Only manual transactions can be deleted; email-derived rows are muted instead.
+// @Summary Delete a manual transaction
+// @Tags Transactions
+// @Param id path string true "Transaction ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/{id} [delete]
+func (h *Handlers) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	if err := h.transactionStore.DeleteTransaction(r.Context(), requestTenant(r), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetTransactionSplits handles PUT /api/transactions/{id}/splits.
+// Replaces the allocations of a transaction; an empty list removes the split.
+// @Summary Split a transaction
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID" format(uuid) example(00000000-0000-0000-0000-000000000001)
+// @Param request body TransactionSplitsRequest true "Split allocations"
+// @Success 200 {object} TransactionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/{id}/splits [put]
+func (h *Handlers) SetTransactionSplits(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[TransactionSplitsRequest](h, w, r)
+	if !ok {
+		return
+	}
+	if !h.validateSplitTaxonomy(w, r, body.Splits) {
+		return
+	}
+
+	splits := make([]store.TransactionSplitInput, 0, len(body.Splits))
+	for _, split := range body.Splits {
+		splits = append(splits, store.TransactionSplitInput{
+			Amount:       split.Amount,
+			Category:     split.Category,
+			Bucket:       split.Bucket,
+			Labels:       split.Labels,
+			Counterparty: strings.TrimSpace(split.Counterparty),
+		})
+	}
+	if err := h.transactionStore.SetTransactionSplits(r.Context(), requestTenant(r), id, splits); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	txn, err := h.transactionStore.GetTransaction(r.Context(), requestTenant(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, txn)
+}
+
+// validateSplitTaxonomy checks every split category and bucket against the
+// store and reports unknown names per split.
+func (h *Handlers) validateSplitTaxonomy(w http.ResponseWriter, r *http.Request, splits []TransactionSplitRequest) bool {
+	categories := make(map[string]bool)
+	buckets := make(map[string]bool)
+	for _, split := range splits {
+		if split.Category != "" {
+			categories[split.Category] = false
+		}
+		if split.Bucket != "" {
+			buckets[split.Bucket] = false
+		}
+	}
+	if len(categories) > 0 {
+		cats, err := h.taxonomyStore.ListCategories(r.Context(), requestTenant(r))
+		if err != nil {
+			writeError(w, r, err)
+			return false
+		}
+		for _, c := range cats {
+			if _, ok := categories[c.Name]; ok {
+				categories[c.Name] = true
+			}
+		}
+	}
+	if len(buckets) > 0 {
+		bkts, err := h.taxonomyStore.ListBuckets(r.Context(), requestTenant(r))
+		if err != nil {
+			writeError(w, r, err)
+			return false
+		}
+		for _, b := range bkts {
+			if _, ok := buckets[b.Name]; ok {
+				buckets[b.Name] = true
+			}
+		}
+	}
+
+	var details []ValidationError
+	for i, split := range splits {
+		if split.Category != "" && !categories[split.Category] {
+			details = append(details, ValidationError{
+				Field:    fmt.Sprintf("splits[%d].category", i),
+				Location: "body",
+				Message:  "does not exist",
+			})
+		}
+		if split.Bucket != "" && !buckets[split.Bucket] {
+			details = append(details, ValidationError{
+				Field:    fmt.Sprintf("splits[%d].bucket", i),
+				Location: "body",
+				Message:  "does not exist",
+			})
+		}
+	}
+	if len(details) > 0 {
+		writeValidationErrors(w, details)
+		return false
+	}
+	return true
+}
+
 // validateCategory checks that the given category name exists in the store.
 // Returns false and writes an error response if validation fails.
 func (h *Handlers) validateCategory(w http.ResponseWriter, r *http.Request, name string) bool {
@@ -215,6 +457,54 @@ func (h *Handlers) validateBucket(w http.ResponseWriter, r *http.Request, name s
 	return false
 }
 
+// validateLabels trims and deduplicates names and checks that each exists in
+// the store. Returns false and writes an error response if validation fails.
+func (h *Handlers) validateLabels(w http.ResponseWriter, r *http.Request, names []string) ([]string, bool) {
+	if len(names) == 0 {
+		return nil, true
+	}
+	existing, err := h.taxonomyStore.ListLabels(r.Context(), requestTenant(r))
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	known := make(map[string]bool, len(existing))
+	for _, l := range existing {
+		known[l.Name] = true
+	}
+
+	labels := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	var details []ValidationError
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		message := ""
+		switch {
+		case name == "":
+			message = "must not be blank"
+		case !known[name]:
+			message = "does not exist"
+		}
+		if message != "" {
+			details = append(details, ValidationError{
+				Field:    fmt.Sprintf("labels[%d]", i),
+				Location: "body",
+				Message:  message,
+			})
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			labels = append(labels, name)
+		}
+	}
+	if len(details) > 0 {
+		writeValidationErrors(w, details)
+		return nil, false
+	}
+	return labels, true
+}
+
 // UpdateTransaction handles PATCH /api/transactions/{id}.
 // Body: {"description": "...", "category": "...", "bucket": "...", "muted": true, "mute_reason": "..."}
 // All fields are optional; only non-nil fields are written.
@@ -295,6 +585,101 @@ func (h *Handlers) patchTransaction(
 	return true
 }
 
+// BulkUpdateTransactions handles POST /api/transactions/bulk.
+// All changes run in one database transaction, so either every selected row
+// is updated or none is.
+//
+// @Summary Update many transactions at once
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param request body BulkTransactionRequest true "Selection and changes"
+// @Success 200 {object} BulkTransactionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /transactions/bulk [post]
+func (h *Handlers) BulkUpdateTransactions(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodeAndValidateJSON[BulkTransactionRequest](h, w, r)
+	if !ok {
+		return
+	}
+	if body.MuteReason != nil && (body.Muted == nil || !*body.Muted) {
+		writeValidationErrors(w, []ValidationError{{
+			Field:    "mute_reason",
+			Location: "body",
+			Message:  "requires muted to be true",
+		}})
+		return
+	}
+	if body.Category != nil && *body.Category != "" && !h.validateCategory(w, r, *body.Category) {
+		return
+	}
+	if body.Bucket != nil && *body.Bucket != "" && !h.validateBucket(w, r, *body.Bucket) {
+		return
+	}
+
+	tenant := requestTenant(r)
+	input := store.BulkTransactionInput{
+		IDs: body.IDs,
+		Update: store.TransactionUpdate{
+			Description: body.Description,
+			Category:    body.Category,
+			Bucket:      body.Bucket,
+		},
+		AddLabels:    body.AddLabels,
+		RemoveLabels: body.RemoveLabels,
+		Muted:        body.Muted,
+		DryRun:       body.DryRun,
+	}
+	if body.MuteReason != nil {
+		input.MuteReason = *body.MuteReason
+	}
+	if body.Filter != nil {
+		f := body.Filter.listFilter(h.resolveTimezone(r.Context(), tenant, body.Filter.Timezone))
+		input.Filter = &f
+	}
+
+	result, err := h.transactionStore.BulkUpdateTransactions(r.Context(), tenant, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (filter BulkTransactionFilter) listFilter(timezone string) store.ListFilter {
+	return store.ListFilter{
+		Merchant:           filter.Merchant,
+		Category:           filter.Category,
+		CategoryMissing:    filter.CategoryMissing,
+		ExcludeCategories:  filter.ExcludeCategories,
+		Currency:           filter.Currency,
+		Source:             filter.Source,
+		ExcludeSources:     filter.ExcludeSources,
+		SourceType:         filter.SourceType,
+		ExcludeSourceTypes: filter.ExcludeSourceTypes,
+		Bank:               filter.Bank,
+		ExcludeBanks:       filter.ExcludeBanks,
+		Label:              filter.Label,
+		LabelMissing:       filter.LabelMissing,
+		ExcludeLabels:      filter.ExcludeLabels,
+		Bucket:             filter.Bucket,
+		BucketMissing:      filter.BucketMissing,
+		ExcludeBuckets:     filter.ExcludeBuckets,
+		ShowMuted:          filter.ShowMuted,
+		MutedOnly:          filter.MutedOnly,
+		IndividualOnly:     filter.IndividualOnly,
+		Weekday:            filter.Weekday,
+		HourFrom:           filter.HourFrom,
+		HourTo:             filter.HourTo,
+		Timezone:           timezone,
+		From:               filter.DateFrom,
+		To:                 filter.DateTo,
+	}
+}
+
 // ListMutedMerchants handles GET /api/muted-merchants.
 //
 // @Summary List muted merchant patterns
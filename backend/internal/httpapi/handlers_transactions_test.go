@@ -381,6 +381,219 @@ func TestUpdateTransaction_ReportsUnknownCategoryAsValidationError(t *testing.T)
 	assertValidationError(t, rr, "category", "body", "does not exist")
 }
 
+func TestCreateTransaction_RecordsManualTransaction(t *testing.T) {
+	st := &mockStore{
+		categories: []store.Category{{Name: "Food"}},
+		buckets:    []store.Bucket{{Name: "Needs"}},
+		labels:     []store.Label{{Name: "reimbursable"}, {Name: "work"}},
+	}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions", strings.NewReader(`{
+		"amount": 120.5,
+		"timestamp": "2026-03-01T12:30:00Z",
+		"merchant_info": " Corner Chai Stall ",
+		"category": "Food",
+		"bucket": "Needs",
+		"source_label": "Cash",
+		"labels": [" reimbursable", "work", "reimbursable "]
+	}`))
+	rr := httptest.NewRecorder()
+
+	h.CreateTransaction(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	want := store.CreateTransactionInput{
+		Amount:       120.5,
+		Currency:     defaultBaseCurrency,
+		Timestamp:    time.Date(2026, time.March, 1, 12, 30, 0, 0, time.UTC),
+		MerchantInfo: "Corner Chai Stall",
+		Category:     "Food",
+		Bucket:       "Needs",
+		SourceLabel:  "Cash",
+		Labels:       []string{"reimbursable", "work"},
+	}
+	if !reflect.DeepEqual(st.createdTransaction, want) {
+		t.Fatalf("created transaction = %#v, want %#v", st.createdTransaction, want)
+	}
+	var response TransactionResponse
+	decodeJSON(t, rr.Body.String(), &response)
+	if response.Source.Type != store.SourceTypeManual || response.MerchantInfo != "Corner Chai Stall" {
+		t.Fatalf("response = %#v, want manual transaction", response)
+	}
+}
+
+func TestCreateTransaction_RejectsInvalidPayloads(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+		msg   string
+	}{
+		{name: "non-positive amount", body: `{"amount":0,"merchant_info":"Cash"}`, field: "amount", msg: "must be greater than 0"},
+		{name: "missing merchant", body: `{"amount":10}`, field: "merchant_info", msg: "is required"},
+		{name: "invalid currency", body: `{"amount":10,"merchant_info":"Cash","currency":"rupees"}`, field: "currency", msg: "must be a 3-letter ISO 4217 code"},
+		{name: "unknown category", body: `{"amount":10,"merchant_info":"Cash","category":"Unknown"}`, field: "category", msg: "does not exist"},
+		{name: "unknown bucket", body: `{"amount":10,"merchant_info":"Cash","bucket":"Unknown"}`, field: "bucket", msg: "does not exist"},
+		{name: "unknown label", body: `{"amount":10,"merchant_info":"Cash","labels":["work","Unknown"]}`, field: "labels[1]", msg: "does not exist"},
+		{name: "blank label", body: `{"amount":10,"merchant_info":"Cash","labels":["  "]}`, field: "labels[0]", msg: "must not be blank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{
+				categories: []store.Category{{Name: "Food"}},
+				buckets:    []store.Bucket{{Name: "Needs"}},
+				labels:     []store.Label{{Name: "work"}},
+			}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			h.CreateTransaction(rr, req)
+
+			assertValidationError(t, rr, tt.field, "body", tt.msg)
+			if st.createdTransaction.MerchantInfo != "" {
+				t.Fatalf("created transaction = %#v, want none", st.createdTransaction)
+			}
+		})
+	}
+}
+
+func TestDeleteTransaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "manual", wantCode: http.StatusNoContent},
+		{name: "missing", err: errStoreNotFound, wantCode: http.StatusNotFound},
+		{
+			name:     "email derived",
+			err:      errors.E(errors.Conflict, errors.User("Only manually entered transactions can be deleted. Mute email transactions instead.")),
+			wantCode: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{deleteTxErr: tt.err}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodDelete, "/api/transactions/"+testTransactionID, nil)
+			req.SetPathValue("id", testTransactionID)
+			rr := httptest.NewRecorder()
+
+			h.DeleteTransaction(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d (body=%s)", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if st.deletedTransactionID != testTransactionID {
+				t.Fatalf("deleted id = %q, want %q", st.deletedTransactionID, testTransactionID)
+			}
+		})
+	}
+}
+
+func TestSetTransactionSplits_StoresAllocations(t *testing.T) {
+	st := &mockStore{
+		categories: []store.Category{{Name: "Groceries"}, {Name: "Household"}},
+		buckets:    []store.Bucket{{Name: "Needs"}},
+		getResult: &store.Transaction{
+			ID:     testTransactionID,
+			Amount: 1200,
+			Splits: []store.TransactionSplit{
+				{ID: "split-1", Amount: 800, Category: "Groceries", Labels: []string{}},
+				{ID: "split-2", Amount: 400, Category: "Household", Labels: []string{"home"}, Counterparty: "Priya"},
+			},
+		},
+	}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	body := `{"splits":[
+		{"amount":800,"category":"Groceries","bucket":"Needs"},
+		{"amount":400,"category":"Household","labels":["home"],"counterparty":" Priya "}
+	]}`
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/api/transactions/"+testTransactionID+"/splits", strings.NewReader(body))
+	req.SetPathValue("id", testTransactionID)
+	rr := httptest.NewRecorder()
+
+	h.SetTransactionSplits(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	want := []store.TransactionSplitInput{
+		{Amount: 800, Category: "Groceries", Bucket: "Needs"},
+		{Amount: 400, Category: "Household", Labels: []string{"home"}, Counterparty: "Priya"},
+	}
+	if !reflect.DeepEqual(st.transactionSplits, want) {
+		t.Fatalf("splits = %#v, want %#v", st.transactionSplits, want)
+	}
+	var resp TransactionResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if len(resp.Splits) != 2 || resp.Splits[1].Counterparty != "Priya" {
+		t.Fatalf("response splits = %#v", resp.Splits)
+	}
+}
+
+func TestSetTransactionSplits_RejectsInvalidPayloads(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+		msg   string
+	}{
+		{name: "zero amount", body: `{"splits":[{"amount":0},{"amount":10}]}`, field: "splits[0].amount", msg: "must be greater than 0"},
+		{name: "unknown category", body: `{"splits":[{"amount":5},{"amount":5,"category":"Unknown"}]}`, field: "splits[1].category", msg: "does not exist"},
+		{name: "unknown bucket", body: `{"splits":[{"amount":5,"bucket":"Unknown"},{"amount":5}]}`, field: "splits[0].bucket", msg: "does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/api/transactions/"+testTransactionID+"/splits", strings.NewReader(tt.body))
+			req.SetPathValue("id", testTransactionID)
+			rr := httptest.NewRecorder()
+
+			h.SetTransactionSplits(rr, req)
+
+			assertValidationError(t, rr, tt.field, "body", tt.msg)
+			if st.transactionSplits != nil {
+				t.Fatalf("stored splits = %#v, want none", st.transactionSplits)
+			}
+		})
+	}
+}
+
+func TestSetTransactionSplits_SurfacesStoreErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "missing", err: errStoreNotFound, wantCode: http.StatusNotFound},
+		{
+			name:     "sum mismatch",
+			err:      errors.E(errors.InvalidInput, errors.User("Splits add up to 1000.00 but the transaction amount is 1200.00.")),
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandlers(t, &mockStore{setSplitsErr: tt.err}, &mockDaemon{})
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/api/transactions/"+testTransactionID+"/splits",
+				strings.NewReader(`{"splits":[{"amount":600},{"amount":400}]}`))
+			req.SetPathValue("id", testTransactionID)
+			rr := httptest.NewRecorder()
+
+			h.SetTransactionSplits(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d (body=%s)", tt.wantCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestAddLabels_Success(t *testing.T) {
 	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
 	body := `{"labels":["food","work"]}`
@@ -800,3 +1013,182 @@ func TestListTransactions_MissingTimezoneFallsBackToAppTimezone(t *testing.T) {
 		t.Fatalf("expected fallback timezone Asia/Kolkata, got %q", st.listFilter.Timezone)
 	}
 }
+
+func TestBulkUpdateTransactions_PassesIDsAndChanges(t *testing.T) {
+	st := &mockStore{
+		categories: []store.Category{{Name: "Food"}},
+		bulkResult: store.BulkTransactionResult{Matched: 1, Updated: 1, Results: []store.BulkTransactionRowResult{
+			{ID: testTransactionID, Status: store.BulkRowUpdated},
+		}},
+	}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	body := `{"ids":["` + testTransactionID + `"],"category":"Food","add_labels":["trip"],"remove_labels":["work"],"muted":true,"mute_reason":"dup"}`
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/bulk", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.BulkUpdateTransactions(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	got := st.bulkInput
+	if len(got.IDs) != 1 || got.Filter != nil || got.Update.Category == nil || *got.Update.Category != "Food" ||
+		!reflect.DeepEqual(got.AddLabels, []string{"trip"}) || !reflect.DeepEqual(got.RemoveLabels, []string{"work"}) ||
+		got.Muted == nil || !*got.Muted || got.MuteReason != "dup" {
+		t.Fatalf("bulk input = %#v", got)
+	}
+	var resp BulkTransactionResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if resp.Updated != 1 || len(resp.Results) != 1 || resp.Results[0].Status != "updated" {
+		t.Fatalf("response = %#v", resp)
+	}
+}
+
+func TestBulkUpdateTransactions_MapsFilterAndDryRun(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	body := `{"filter":{"merchant":"uber","category_missing":true,"exclude_banks":["HDFC"],"tz":"Asia/Kolkata"},"add_labels":["travel"],"dry_run":true}`
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/bulk", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.BulkUpdateTransactions(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	f := st.bulkInput.Filter
+	if f == nil || f.Merchant != "uber" || !f.CategoryMissing || !reflect.DeepEqual(f.ExcludeBanks, []string{"HDFC"}) || f.Timezone != "Asia/Kolkata" {
+		t.Fatalf("bulk filter = %#v", f)
+	}
+	if !st.bulkInput.DryRun {
+		t.Fatal("expected dry run to be passed to the store")
+	}
+}
+
+func TestBulkUpdateTransactions_RejectsInvalidPayloads(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+		msg   string
+	}{
+		{name: "bad id", body: `{"ids":["txn"],"muted":true}`, field: "ids[0]", msg: "must be a valid UUID"},
+		{name: "unknown category", body: `{"ids":["` + testTransactionID + `"],"category":"Unknown"}`, field: "category", msg: "does not exist"},
+		{name: "reason without mute", body: `{"ids":["` + testTransactionID + `"],"mute_reason":"dup"}`, field: "mute_reason", msg: "requires muted to be true"},
+		{name: "bad filter hour", body: `{"filter":{"hour_from":24},"muted":true}`, field: "filter.hour_from", msg: "must be at most 23"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &mockStore{categories: []store.Category{{Name: "Food"}}}
+			h := newTestHandlers(t, st, &mockDaemon{})
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/bulk", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			h.BulkUpdateTransactions(rr, req)
+
+			assertValidationError(t, rr, tt.field, "body", tt.msg)
+			if st.bulkInput.IDs != nil || st.bulkInput.Filter != nil {
+				t.Fatalf("bulk input = %#v, want no store call", st.bulkInput)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateTransactions_SurfacesStoreValidation(t *testing.T) {
+	st := &mockStore{bulkErr: errors.E(errors.InvalidInput, errors.User("A bulk operation needs at least one change."))}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/bulk", strings.NewReader(`{"ids":["`+testTransactionID+`"]}`))
+	rr := httptest.NewRecorder()
+
+	h.BulkUpdateTransactions(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetTransactionHistory(t *testing.T) {
+	oldCategory, newCategory := "Shopping", "Food"
+	st := &mockStore{transactionHistory: []store.TransactionChange{{
+		ID:            7,
+		BatchID:       "88888888-8888-8888-8888-888888888888",
+		TransactionID: testTransactionID,
+		Field:         "category",
+		OldValue:      &oldCategory,
+		NewValue:      &newCategory,
+		Cause:         store.ChangeCauseMerchantMapping,
+	}}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/"+testTransactionID+"/history", nil)
+	req.SetPathValue("id", testTransactionID)
+	rr := httptest.NewRecorder()
+
+	h.GetTransactionHistory(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp []TransactionChangeResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if len(resp) != 1 || resp[0].Cause != "merchant_mapping" || resp[0].OldValue == nil || *resp[0].OldValue != "Shopping" {
+		t.Fatalf("history = %#v", resp)
+	}
+}
+
+func TestGetTransactionHistory_NotFound(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{historyErr: errStoreNotFound}, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/transactions/"+testTransactionID+"/history", nil)
+	req.SetPathValue("id", testTransactionID)
+	rr := httptest.NewRecorder()
+
+	h.GetTransactionHistory(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRevertChangeBatch(t *testing.T) {
+	const batchID = "88888888-8888-8888-8888-888888888888"
+	st := &mockStore{revertResult: store.ChangeBatchRevert{
+		BatchID:         "99999999-9999-9999-9999-999999999999",
+		RevertedBatchID: batchID,
+		Reverted:        1,
+		Results:         []store.ChangeRevertResult{{ChangeID: 7, TransactionID: testTransactionID, Field: "category", Status: store.ChangeReverted}},
+	}}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/history/"+batchID+"/revert", nil)
+	req.SetPathValue("batch_id", batchID)
+	rr := httptest.NewRecorder()
+
+	h.RevertChangeBatch(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.revertedBatchID != batchID {
+		t.Fatalf("reverted batch = %q, want %q", st.revertedBatchID, batchID)
+	}
+	var resp ChangeBatchRevertResponse
+	decodeJSON(t, rr.Body.String(), &resp)
+	if resp.Reverted != 1 || len(resp.Results) != 1 || resp.Results[0].Status != "reverted" {
+		t.Fatalf("revert = %#v", resp)
+	}
+}
+
+func TestRevertChangeBatch_RejectsInvalidBatchID(t *testing.T) {
+	st := &mockStore{}
+	h := newTestHandlers(t, st, &mockDaemon{})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/transactions/history/latest/revert", nil)
+	req.SetPathValue("batch_id", "latest")
+	rr := httptest.NewRecorder()
+
+	h.RevertChangeBatch(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d (body=%s)", rr.Code, rr.Body.String())
+	}
+	if st.revertedBatchID != "" {
+		t.Fatalf("reverted batch = %q, want no store call", st.revertedBatchID)
+	}
+}
@@ -0,0 +1,258 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+// webhookSecretPrefix marks generated webhook signing secrets.
+const webhookSecretPrefix = "whsec"
+
+const defaultWebhookDeliveryLimit = 50
+
+// ListWebhooks handles GET /api/webhooks.
+// @Summary List the tenant's webhooks
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} WebhookResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks [get]
+func (h *Handlers) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.webhookStore.ListWebhooks(r.Context(), requestTenant(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := make([]WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		resp = append(resp, webhookFromStore(&webhooks[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateWebhook handles POST /api/webhooks. The response is the only time
+// the signing secret is returned.
+// @Summary Subscribe a URL to events
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param request body CreateWebhookRequest true "Webhook"
+// @Success 201 {object} WebhookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks [post]
+func (h *Handlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodeAndValidateJSON[CreateWebhookRequest](h, w, r)
+	if !ok {
+		return
+	}
+	secret := body.Secret
+	if secret == "" {
+		var err error
+		if secret, _, err = auth.NewOpaqueToken(webhookSecretPrefix); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	input := store.NewWebhook{
+		URL:         body.URL,
+		Description: strings.TrimSpace(body.Description),
+		Events:      webhookEventTypes(body.Events),
+		Enabled:     body.Enabled == nil || *body.Enabled,
+		Secret:      secret,
+	}
+	webhook, err := h.webhookStore.CreateWebhook(r.Context(), requestTenant(r), input)
+	event := store.NewAuditEvent{Action: store.AuditWebhookCreate, TargetType: "webhook"}
+	if err != nil {
+		h.audit(r, event, err)
+		writeError(w, r, err)
+		return
+	}
+	event.TargetID = webhook.ID
+	h.audit(r, event, nil)
+	resp := webhookFromStore(webhook)
+	resp.Secret = secret
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// GetWebhook handles GET /api/webhooks/{id}.
+// @Summary Get a webhook
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID" format(uuid) example(55555555-5555-5555-5555-555555555555)
+// @Success 200 {object} WebhookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [get]
+func (h *Handlers) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "webhook")
+	if !ok {
+		return
+	}
+	webhook, err := h.webhookStore.GetWebhook(r.Context(), requestTenant(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookFromStore(webhook))
+}
+
+// UpdateWebhook handles PATCH /api/webhooks/{id}. Disabling a webhook
+// holds its pending deliveries until it is enabled again.
+// @Summary Update a webhook
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID" format(uuid) example(55555555-5555-5555-5555-555555555555)
+// @Param request body WebhookPatchRequest true "Webhook patch"
+// @Success 200 {object} WebhookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [patch]
+func (h *Handlers) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "webhook")
+	if !ok {
+		return
+	}
+	body, ok := decodeAndValidateJSON[WebhookPatchRequest](h, w, r)
+	if !ok {
+		return
+	}
+	patch := store.WebhookPatch{URL: body.URL, Enabled: body.Enabled, Secret: body.Secret}
+	if body.Description != nil {
+		description := strings.TrimSpace(*body.Description)
+		patch.Description = &description
+	}
+	if body.Events != nil {
+		patch.Events = webhookEventTypes(body.Events)
+	}
+	webhook, err := h.webhookStore.UpdateWebhook(r.Context(), requestTenant(r), id, patch)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditWebhookUpdate, TargetType: "webhook", TargetID: id}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookFromStore(webhook))
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/{id}, discarding its pending
+// deliveries and delivery log.
+// @Summary Delete a webhook
+// @Tags Webhooks
+// @Param id path string true "Webhook ID" format(uuid) example(55555555-5555-5555-5555-555555555555)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [delete]
+func (h *Handlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "webhook")
+	if !ok {
+		return
+	}
+	err := h.webhookStore.DeleteWebhook(r.Context(), requestTenant(r), id)
+	h.audit(r, store.NewAuditEvent{Action: store.AuditWebhookDelete, TargetType: "webhook", TargetID: id}, err)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries handles GET /api/webhooks/{id}/deliveries.
+// Delivered and failed deliveries are kept for 30 days.
+// @Summary List a webhook's recent deliveries
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID" format(uuid) example(55555555-5555-5555-5555-555555555555)
+// @Param limit query int false "Maximum deliveries to return (1-200, default 50)"
+// @Success 200 {array} WebhookDeliveryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (h *Handlers) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidPathValue(w, r, "id", "webhook")
+	if !ok {
+		return
+	}
+	query, ok := decodeAndValidateQuery[webhookDeliveryListQuery](h, w, r)
+	if !ok {
+		return
+	}
+	limit := defaultWebhookDeliveryLimit
+	if query.Limit != nil {
+		limit = *query.Limit
+	}
+	tenant := requestTenant(r)
+	if _, err := h.webhookStore.GetWebhook(r.Context(), tenant, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	deliveries, err := h.webhookStore.ListWebhookDeliveries(r.Context(), tenant, id, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		resp = append(resp, WebhookDeliveryResponse{
+			ID:             delivery.ID,
+			EventType:      string(delivery.EventType),
+			Payload:        delivery.Payload,
+			Status:         string(delivery.Status),
+			Attempts:       delivery.Attempts,
+			NextAttemptAt:  delivery.NextAttemptAt,
+			LastAttemptAt:  delivery.LastAttemptAt,
+			ResponseStatus: delivery.ResponseStatus,
+			LastError:      delivery.LastError,
+			CreatedAt:      delivery.CreatedAt,
+			CompletedAt:    delivery.CompletedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func webhookFromStore(webhook *store.Webhook) WebhookResponse {
+	events := make([]string, len(webhook.Events))
+	for i, event := range webhook.Events {
+		events[i] = string(event)
+	}
+	return WebhookResponse{
+		ID:          webhook.ID,
+		URL:         webhook.URL,
+		Description: webhook.Description,
+		Events:      events,
+		Enabled:     webhook.Enabled,
+		CreatedAt:   webhook.CreatedAt,
+		UpdatedAt:   webhook.UpdatedAt,
+	}
+}
+
+func webhookEventTypes(events []string) []store.WebhookEventType {
+	out := make([]store.WebhookEventType, len(events))
+	for i, event := range events {
+		out[i] = store.WebhookEventType(event)
+	}
+	return out
+}
@@ -0,0 +1,198 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+const testWebhookID = "55555555-5555-5555-5555-555555555555"
+
+func webhookUserContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", TenantID: "tenant-a", Role: auth.RoleUser})
+}
+
+func webhookRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequestWithContext(webhookUserContext(), method, target, strings.NewReader(body))
+	req.SetPathValue("id", testWebhookID)
+	return req
+}
+
+func TestCreateWebhookGeneratesSecret(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.CreateWebhook(rec, webhookRequest(http.MethodPost, "/api/webhooks",
+		`{"url":"https://homeassistant.local/api/webhook/expensor","description":" Home Assistant ","events":["transaction.created","scan.failed"]}`))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp WebhookResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.HasPrefix(resp.Secret, "whsec_") || ms.createdWebhook.Secret != resp.Secret {
+		t.Fatalf("secret = %q, stored %q", resp.Secret, ms.createdWebhook.Secret)
+	}
+	created := ms.createdWebhook
+	if !created.Enabled || created.Description != "Home Assistant" || len(created.Events) != 2 || created.Events[1] != store.WebhookScanFailed {
+		t.Fatalf("created = %#v", created)
+	}
+	if len(ms.auditEvents) != 1 || ms.auditEvents[0].Action != store.AuditWebhookCreate || ms.auditEvents[0].TargetID != testWebhookID {
+		t.Fatalf("audit events = %#v", ms.auditEvents)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetWebhook(rec, webhookRequest(http.MethodGet, "/api/webhooks/"+testWebhookID, ""))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "whsec_") {
+		t.Fatalf("get status = %d, body = %s; want the secret left out", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateWebhookKeepsGivenSecret(t *testing.T) {
+	ms := &mockStore{}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.CreateWebhook(rec, webhookRequest(http.MethodPost, "/api/webhooks",
+		`{"url":"http://192.168.1.10:8123/hook","events":["diagnostic.opened"],"enabled":false,"secret":"0123456789abcdef"}`))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+	if ms.createdWebhook.Secret != "0123456789abcdef" || ms.createdWebhook.Enabled {
+		t.Fatalf("created = %#v", ms.createdWebhook)
+	}
+}
+
+func TestCreateWebhookRejectsInvalidInput(t *testing.T) {
+	for name, body := range map[string]string{
+		"scheme":      `{"url":"ftp://example.test/hook","events":["transaction.created"]}`,
+		"no events":   `{"url":"https://example.test/hook","events":[]}`,
+		"event":       `{"url":"https://example.test/hook","events":["budget.exceeded"]}`,
+		"short token": `{"url":"https://example.test/hook","events":["scan.failed"],"secret":"short"}`,
+	} {
+		ms := &mockStore{}
+		h := newTestHandlers(t, ms, &mockDaemon{})
+		rec := httptest.NewRecorder()
+		h.CreateWebhook(rec, webhookRequest(http.MethodPost, "/api/webhooks", body))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want 422; body = %s", name, rec.Code, rec.Body.String())
+		}
+		if ms.webhooks != nil {
+			t.Errorf("%s: webhook was created", name)
+		}
+	}
+}
+
+func TestWebhooksAreTenantScoped(t *testing.T) {
+	ms := &mockStore{webhooks: map[string]*store.Webhook{
+		testWebhookID: {ID: testWebhookID, TenantID: "tenant-b", URL: "https://example.test/hook"},
+	}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+
+	rec := httptest.NewRecorder()
+	h.ListWebhooks(rec, webhookRequest(http.MethodGet, "/api/webhooks", ""))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("list status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	for name, call := range map[string]func(http.ResponseWriter, *http.Request){
+		"get": h.GetWebhook, "patch": h.UpdateWebhook, "delete": h.DeleteWebhook, "deliveries": h.ListWebhookDeliveries,
+	} {
+		rec := httptest.NewRecorder()
+		call(rec, webhookRequest(http.MethodPatch, "/api/webhooks/"+testWebhookID, `{"enabled":false}`))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want 404; body = %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if !strings.Contains(ms.webhooks[testWebhookID].URL, "example.test") {
+		t.Fatalf("other tenant's webhook changed: %#v", ms.webhooks[testWebhookID])
+	}
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	ms := &mockStore{webhooks: map[string]*store.Webhook{
+		testWebhookID: {
+			ID: testWebhookID, TenantID: "tenant-a", URL: "https://example.test/hook",
+			Events: []store.WebhookEventType{store.WebhookTransactionCreated}, Enabled: true,
+		},
+	}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.UpdateWebhook(rec, webhookRequest(http.MethodPatch, "/api/webhooks/"+testWebhookID,
+		`{"enabled":false,"events":["transaction.updated"],"secret":"fedcba9876543210"}`))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp WebhookResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Enabled || len(resp.Events) != 1 || resp.Events[0] != "transaction.updated" || resp.Secret != "" {
+		t.Fatalf("response = %#v", resp)
+	}
+	if ms.webhookPatch.Secret == nil || *ms.webhookPatch.Secret != "fedcba9876543210" || ms.webhookPatch.URL != nil {
+		t.Fatalf("patch = %#v", ms.webhookPatch)
+	}
+	if len(ms.auditEvents) != 1 || ms.auditEvents[0].Action != store.AuditWebhookUpdate || ms.auditEvents[0].Outcome != store.AuditSuccess {
+		t.Fatalf("audit events = %#v", ms.auditEvents)
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	ms := &mockStore{webhooks: map[string]*store.Webhook{testWebhookID: {ID: testWebhookID, TenantID: "tenant-a"}}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.DeleteWebhook(rec, webhookRequest(http.MethodDelete, "/api/webhooks/"+testWebhookID, ""))
+
+	if rec.Code != http.StatusNoContent || len(ms.webhooks) != 0 {
+		t.Fatalf("status = %d, webhooks = %#v", rec.Code, ms.webhooks)
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	attempted := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	retry := attempted.Add(time.Minute)
+	ms := &mockStore{
+		webhooks: map[string]*store.Webhook{testWebhookID: {ID: testWebhookID, TenantID: "tenant-a"}},
+		webhookDeliveries: []store.WebhookDelivery{{
+			ID: "delivery-a", WebhookID: testWebhookID, TenantID: "tenant-a", EventType: store.WebhookTransactionCreated,
+			Payload: json.RawMessage(`{"id":"txn-a"}`), Status: store.WebhookDeliveryPending, Attempts: 2,
+			NextAttemptAt: &retry, LastAttemptAt: &attempted, ResponseStatus: 502, LastError: "endpoint responded 502 Bad Gateway",
+		}},
+	}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+	rec := httptest.NewRecorder()
+
+	h.ListWebhookDeliveries(rec, webhookRequest(http.MethodGet, "/api/webhooks/"+testWebhookID+"/deliveries", ""))
+
+	if rec.Code != http.StatusOK || ms.webhookDeliveryLimit != 50 {
+		t.Fatalf("status = %d, limit = %d; body = %s", rec.Code, ms.webhookDeliveryLimit, rec.Body.String())
+	}
+	var resp []WebhookDeliveryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Status != "pending" || resp[0].Attempts != 2 || string(resp[0].Payload) != `{"id":"txn-a"}` ||
+		resp[0].ResponseStatus != 502 || resp[0].NextAttemptAt == nil {
+		t.Fatalf("response = %#v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ListWebhookDeliveries(rec, webhookRequest(http.MethodGet, "/api/webhooks/"+testWebhookID+"/deliveries?limit=500", ""))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("limit=500 status = %d, want 422; body = %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,294 @@
+package httpapi
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// loginAccountFreeFailures and loginIPFreeFailures are the failed
+	// sign-ins allowed before each further one locks the account from that
+	// address, or the address for every account.
+	// Addresses get more room because households share one.
+	loginAccountFreeFailures = 5
+	loginIPFreeFailures      = 20
+	// loginFirstLockout doubles with every failure past the free ones, up to
+	// loginMaxLockout.
+	loginFirstLockout = 30 * time.Second
+	loginMaxLockout   = 15 * time.Minute
+	// loginEmailFreeFailures failed sign-ins to one email, from any
+	// address, are allowed before each further one delays the next try by a
+	// backoff that doubles up to loginEmailMaxBackoff. The cap is short so
+	// that failures sprayed from many addresses slow guessing without
+	// locking the account's owner out.
+	loginEmailFreeFailures = 50
+	loginEmailFirstBackoff = time.Second
+	loginEmailMaxBackoff   = 30 * time.Second
+	// loginFailureWindow is how long failures are remembered after the last
+	// one.
+	loginFailureWindow = time.Hour
+)
+
+// loginIPv6PrefixBits is how much of an IPv6 address identifies a client.
+// One host is usually given a whole /64, so counting single addresses would
+// let it sign in from a fresh one for every try.
+const loginIPv6PrefixBits = 64
+
+// loginThrottleCapacity bounds how many accounts and addresses the
+// throttle remembers, so spraying sign-ins with made-up emails cannot grow
+// it without limit. The least recently failing entry is forgotten first.
+const loginThrottleCapacity = 50_000
+
+// loginThrottleSweepInterval is how often forgotten failures are pruned.
+const loginThrottleSweepInterval = time.Minute
+
+type loginThrottleKey struct {
+	// kind is "account", "email" or "ip". Account failures are counted per
+	// address, so failures from one client cannot lock everyone else out;
+	// "email" counts them from every address but only backs off briefly.
+	kind  string
+	value string
+}
+
+type loginFailureCount struct {
+	key         loginThrottleKey
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func loginThrottleKeys(email, ip string) []loginThrottleKey {
+	client := loginClient(ip)
+	keys := []loginThrottleKey{
+		{kind: "account", value: email + " " + client},
+		{kind: "email", value: email},
+	}
+	if client != "" {
+		keys = append(keys, loginThrottleKey{kind: "ip", value: client})
+	}
+	return keys
+}
+
+// loginClient returns the part of ip that failures are counted against: the
+// address itself for IPv4 and its /64 for IPv6.
+func loginClient(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is6() {
+		return ip
+	}
+	prefix, err := addr.WithZone("").Prefix(loginIPv6PrefixBits)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
+
+// loginThrottle counts failed sign-ins in a bounded LRU. It has its own
+// lock, so sign-in failures do not contend with other handler state.
+type loginThrottle struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[loginThrottleKey]*list.Element
+	// order holds *loginFailureCount, most recent failure first.
+	order *list.List
+}
+
+func newLoginThrottle(capacity int) *loginThrottle {
+	return &loginThrottle{capacity: capacity, entries: make(map[loginThrottleKey]*list.Element), order: list.New()}
+}
+
+// lockedUntil returns the latest lockout of keys.
+func (t *loginThrottle) lockedUntil(keys ...loginThrottleKey) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var until time.Time
+	for _, key := range keys {
+		if e, ok := t.entries[key]; ok {
+			if count := e.Value.(*loginFailureCount); count.lockedUntil.After(until) {
+				until = count.lockedUntil
+			}
+		}
+	}
+	return until
+}
+
+// fail counts a failure for key and returns the lockout it started, if any.
+// free is the number of failures allowed before locking.
+func (t *loginThrottle) fail(key loginThrottleKey, free int, now time.Time) (int, time.Duration) {
+	return t.failWith(key, now, func(failures int) time.Duration { return loginLockout(failures, free) })
+}
+
+// failWith is fail with the lockout for a failure count given by lockout.
+func (t *loginThrottle) failWith(key loginThrottleKey, now time.Time, lockout func(failures int) time.Duration) (int, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[key]
+	if ok {
+		t.order.MoveToFront(e)
+	} else {
+		e = t.order.PushFront(&loginFailureCount{key: key})
+		t.entries[key] = e
+		if t.order.Len() > t.capacity {
+			t.remove(t.order.Back())
+		}
+	}
+	count := e.Value.(*loginFailureCount)
+	count.failures++
+	count.lastFailure = now
+	wait := lockout(count.failures)
+	if wait > 0 {
+		count.lockedUntil = now.Add(wait)
+	}
+	return count.failures, wait
+}
+
+func (t *loginThrottle) clear(key loginThrottleKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.entries[key]; ok {
+		t.remove(e)
+	}
+}
+
+// prune forgets failures older than loginFailureWindow. The oldest are at
+// the back of the list, and lockouts end well within the window.
+func (t *loginThrottle) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for e := t.order.Back(); e != nil; e = t.order.Back() {
+		if now.Sub(e.Value.(*loginFailureCount).lastFailure) <= loginFailureWindow {
+			return
+		}
+		t.remove(e)
+	}
+}
+
+// run prunes the throttle until ctx is canceled.
+func (t *loginThrottle) run(ctx context.Context) {
+	ticker := time.NewTicker(loginThrottleSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.prune(now)
+		}
+	}
+}
+
+func (t *loginThrottle) remove(e *list.Element) {
+	delete(t.entries, e.Value.(*loginFailureCount).key)
+	t.order.Remove(e)
+}
+
+// loginLockedUntil returns when email may next try to sign in from ip. It is
+// in the past when a sign-in may be tried right away.
+func (h *Handlers) loginLockedUntil(email, ip string) time.Time {
+	return h.loginThrottle.lockedUntil(loginThrottleKeys(email, ip)...)
+}
+
+// recordLoginFailure counts a failed sign-in for email from ip. Unknown
+// emails are counted too, so lockouts do not reveal which accounts exist.
+func (h *Handlers) recordLoginFailure(email, ip string, now time.Time) {
+	for _, key := range loginThrottleKeys(email, ip) {
+		var failures int
+		var lockout time.Duration
+		switch key.kind {
+		case "email":
+			failures, lockout = h.loginThrottle.failWith(key, now, loginEmailBackoff)
+		case "ip":
+			failures, lockout = h.loginThrottle.fail(key, loginIPFreeFailures, now)
+		default:
+			failures, lockout = h.loginThrottle.fail(key, loginAccountFreeFailures, now)
+		}
+		if lockout > 0 {
+			h.logger.Warn("sign-in locked after failed attempts",
+				"kind", key.kind, "value", key.value, "failures", failures, "lockout", lockout)
+		}
+	}
+}
+
+// clearLoginFailures forgets an account's failures after it signs in. The
+// address keeps its count so one known password cannot reset it.
+func (h *Handlers) clearLoginFailures(email, ip string) {
+	for _, key := range loginThrottleKeys(email, ip) {
+		if key.kind != "ip" {
+			h.loginThrottle.clear(key)
+		}
+	}
+}
+
+func loginLockout(failures, free int) time.Duration {
+	if failures < free {
+		return 0
+	}
+	steps := failures - free
+	if steps >= 16 {
+		return loginMaxLockout
+	}
+	return min(loginFirstLockout<<steps, loginMaxLockout)
+}
+
+// loginEmailBackoff returns how long to wait after the given number of
+// failed sign-ins to one email from any address.
+func loginEmailBackoff(failures int) time.Duration {
+	if failures < loginEmailFreeFailures {
+		return 0
+	}
+	steps := failures - loginEmailFreeFailures
+	if steps >= 16 {
+		return loginEmailMaxBackoff
+	}
+	return min(loginEmailFirstBackoff<<steps, loginEmailMaxBackoff)
+}
+
+func writeLoginLocked(w http.ResponseWriter, r *http.Request, until, now time.Time) {
+	seconds := int(math.Ceil(until.Sub(now).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeError(w, r, errors.E(errors.ResourceExhausted, errors.User("too many failed sign-in attempts; try again later")))
+}
+
+// clientIP returns the address of the client behind r. When the connection
+// comes from a trusted proxy it is the nearest X-Forwarded-For entry that
+// was not added by another trusted proxy.
+func (h *Handlers) clientIP(r *http.Request) string {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	addr := peer.Addr().Unmap()
+	if !h.trustedProxy(addr) {
+		return addr.String()
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !h.trustedProxy(addr) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func (h *Handlers) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range h.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
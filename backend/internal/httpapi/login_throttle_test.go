@@ -0,0 +1,258 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+func TestLoginLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 4, want: 0},
+		{failures: 5, want: 30 * time.Second},
+		{failures: 6, want: time.Minute},
+		{failures: 9, want: 8 * time.Minute},
+		{failures: 10, want: 15 * time.Minute},
+		{failures: 100, want: 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := loginLockout(tt.failures, loginAccountFreeFailures); got != tt.want {
+			t.Errorf("loginLockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func signInRequest(remoteAddr, email, password string) *http.Request {
+	req := httptest.NewRequestWithContext(
+		context.Background(),
+		http.MethodPost,
+		"/api/session",
+		strings.NewReader(`{"email":"`+email+`","password":"`+password+`"}`),
+	)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestLoginLocksAccountAfterFailedAttempts(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	user := &store.User{ID: "user-a", TenantID: "tenant-a", Email: "a@example.com", PasswordHash: hash, Role: store.UserRoleUser}
+	ms := &mockStore{usersByEmail: map[string]*store.User{user.Email: user}}
+	h := newTestHandlers(t, ms, &mockDaemon{})
+
+	for i := range loginAccountFreeFailures {
+		rec := httptest.NewRecorder()
+		h.Login(rec, signInRequest("192.0.2.1:4000", "A@example.com", "wrong"))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d status = %d, want 401; body = %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+
+	// The correct password from the same address is refused while locked.
+	rec := httptest.NewRecorder()
+	h.Login(rec, signInRequest("192.0.2.1:4000", "a@example.com", "correct horse battery staple"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked status = %d, want 429; body = %s", rec.Code, rec.Body.String())
+	}
+	// Hashing the wrong passwords takes real time, so the lockout may have
+	// run for a moment already.
+	if got, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || got < 1 || got > int(loginFirstLockout.Seconds()) {
+		t.Fatalf("Retry-After = %q, want at most %v", rec.Header().Get("Retry-After"), loginFirstLockout)
+	}
+	if ms.createdSession.UserID != "" {
+		t.Fatalf("created session = %#v, want none while locked", ms.createdSession)
+	}
+
+	// The owner signing in from elsewhere is not locked out by them.
+	rec = httptest.NewRecorder()
+	h.Login(rec, signInRequest("198.51.100.7:4000", "a@example.com", "correct horse battery staple"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("other address status = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+
+	h.loginThrottle.prune(time.Now().Add(loginFailureWindow + time.Minute))
+	rec = httptest.NewRecorder()
+	h.Login(rec, signInRequest("192.0.2.1:4000", "a@example.com", "correct horse battery staple"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status after lockout = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+	if until := h.loginLockedUntil(user.Email, "192.0.2.1"); !until.IsZero() {
+		t.Fatalf("account locked until %v after sign-in, want cleared", until)
+	}
+}
+
+func TestLoginCountsUnknownEmails(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+
+	for range loginAccountFreeFailures {
+		h.Login(httptest.NewRecorder(), signInRequest("192.0.2.1:4000", "nobody@example.com", "wrong"))
+	}
+
+	rec := httptest.NewRecorder()
+	h.Login(rec, signInRequest("192.0.2.1:4000", "nobody@example.com", "wrong"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 so unknown accounts look like known ones", rec.Code)
+	}
+}
+
+func TestLoginLocksAddressAcrossAccounts(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+
+	for i := range loginIPFreeFailures {
+		email := "user" + string(rune('a'+i)) + "@example.com"
+		h.Login(httptest.NewRecorder(), signInRequest("192.0.2.1:4000", email, "wrong"))
+	}
+
+	rec := httptest.NewRecorder()
+	h.Login(rec, signInRequest("192.0.2.1:4000", "fresh@example.com", "wrong"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.Login(rec, signInRequest("198.51.100.7:4000", "fresh@example.com", "wrong"))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("other address status = %d, want 401", rec.Code)
+	}
+}
+
+// Failures spread over many addresses back off the account briefly instead
+// of locking its owner out.
+func TestLoginBacksOffAccountAcrossAddresses(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	now := time.Now()
+	for i := range loginEmailFreeFailures {
+		h.recordLoginFailure("a@example.com", "192.0.2."+strconv.Itoa(i+1), now)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Login(rec, signInRequest("198.51.100.7:4000", "a@example.com", "wrong"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429; body = %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != strconv.Itoa(int(loginEmailFirstBackoff.Seconds())) {
+		t.Fatalf("Retry-After = %q, want %v", got, loginEmailFirstBackoff)
+	}
+
+	for i := range 100 {
+		h.recordLoginFailure("a@example.com", "203.0.113."+strconv.Itoa(i+1), now)
+	}
+	if until := h.loginLockedUntil("a@example.com", "198.51.100.7"); until.Sub(now) > loginEmailMaxBackoff {
+		t.Fatalf("account locked for %v, want at most %v", until.Sub(now), loginEmailMaxBackoff)
+	}
+	if until := h.loginLockedUntil("b@example.com", "198.51.100.7"); !until.IsZero() {
+		t.Fatalf("other account locked until %v, want unaffected", until)
+	}
+
+	h.clearLoginFailures("a@example.com", "198.51.100.7")
+	if until := h.loginLockedUntil("a@example.com", "198.51.100.8"); !until.IsZero() {
+		t.Fatalf("account locked until %v after sign-in, want cleared", until)
+	}
+}
+
+func TestLoginEmailBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: loginEmailFreeFailures - 1, want: 0},
+		{failures: loginEmailFreeFailures, want: time.Second},
+		{failures: loginEmailFreeFailures + 3, want: 8 * time.Second},
+		{failures: loginEmailFreeFailures + 5, want: 30 * time.Second},
+		{failures: 1000, want: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := loginEmailBackoff(tt.failures); got != tt.want {
+			t.Errorf("loginEmailBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottleKeysGroupIPv6By64(t *testing.T) {
+	ipKey := func(ip string) loginThrottleKey {
+		t.Helper()
+		for _, key := range loginThrottleKeys("a@example.com", ip) {
+			if key.kind == "ip" {
+				return key
+			}
+		}
+		t.Fatalf("loginThrottleKeys(%q) has no ip key", ip)
+		return loginThrottleKey{}
+	}
+
+	if a, b := ipKey("2001:db8:1:2::1"), ipKey("2001:db8:1:2:ffff:ffff:ffff:ffff"); a != b {
+		t.Fatalf("same /64 keys = %#v and %#v, want equal", a, b)
+	}
+	if a, b := ipKey("2001:db8:1:2::1"), ipKey("2001:db8:1:3::1"); a == b {
+		t.Fatalf("different /64 keys = %#v, want distinct", a)
+	}
+	if got := ipKey("192.0.2.1"); got.value != "192.0.2.1" {
+		t.Fatalf("IPv4 key = %#v, want the address", got)
+	}
+}
+
+func TestLoginThrottleForgetsOldestBeyondCapacity(t *testing.T) {
+	throttle := newLoginThrottle(2)
+	now := time.Now()
+	for _, value := range []string{"a", "b", "c"} {
+		for range loginAccountFreeFailures {
+			throttle.fail(loginThrottleKey{kind: "ip", value: value}, loginAccountFreeFailures, now)
+		}
+	}
+
+	if until := throttle.lockedUntil(loginThrottleKey{kind: "ip", value: "a"}); !until.IsZero() {
+		t.Fatalf("oldest entry locked until %v, want it forgotten", until)
+	}
+	if until := throttle.lockedUntil(loginThrottleKey{kind: "ip", value: "c"}); !until.After(now) {
+		t.Fatalf("newest entry locked until %v, want locked", until)
+	}
+
+	throttle.fail(loginThrottleKey{kind: "ip", value: "c"}, loginAccountFreeFailures, now.Add(loginFailureWindow))
+	throttle.prune(now.Add(loginFailureWindow + time.Second))
+	if len(throttle.entries) != 1 || throttle.order.Len() != 1 {
+		t.Fatalf("entries after prune = %d, want only the recent one", len(throttle.entries))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	h := newTestHandlers(t, &mockStore{}, &mockDaemon{})
+	h.trustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{name: "direct", remoteAddr: "192.0.2.1:4000", want: "192.0.2.1"},
+		{name: "untrusted peer forwarded for", remoteAddr: "192.0.2.1:4000", forwardedFor: "203.0.113.9", want: "192.0.2.1"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:4000", forwardedFor: "203.0.113.9", want: "203.0.113.9"},
+		{name: "proxy chain", remoteAddr: "10.0.0.2:4000", forwardedFor: "198.51.100.1, 203.0.113.9, 10.0.0.3", want: "203.0.113.9"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.2:4000", want: "10.0.0.2"},
+		{name: "malformed hop", remoteAddr: "10.0.0.2:4000", forwardedFor: "unknown", want: "10.0.0.2"},
+		{name: "mapped ipv4", remoteAddr: "[::ffff:192.0.2.1]:4000", want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/session", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if got := h.clientIP(req); got != tt.want {
+				t.Fatalf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
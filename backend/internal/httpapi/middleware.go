@@ -91,6 +91,11 @@ func recoveryMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					// A streaming handler gave up after sending headers; let the
+					// server drop the connection instead of appending an error.
+					panic(rec)
+				}
 				attrs := []slog.Attr{
 					slog.String("request_id", requestIDFromContext(r.Context())),
 					slog.Any("panic", rec),
@@ -119,3 +124,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap exposes the underlying writer to http.ResponseController, which
+// streaming handlers use to flush and extend write deadlines.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
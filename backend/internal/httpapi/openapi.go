@@ -4,4 +4,19 @@
 // @BasePath /api
 // @schemes http https
 
+// @securityDefinitions.oauth2.application AccessToken
+// @tokenUrl /api/tokens
+// @scope.transactions:read Read transactions, attachments, exports and shared ledgers
+// @scope.transactions:write Change transactions, shared ledgers and muted merchants
+// @scope.stats:read Read dashboard statistics and charts
+// @scope.rules:read Read rules, labels, categories and buckets
+// @scope.rules:write Change rules, labels, categories and buckets
+// @scope.readers:read Read reader configuration, status and diagnostics
+// @scope.readers:write Configure readers and start scans
+// @scope.settings:read Read tenant preferences and LLM providers
+// @scope.settings:write Change tenant preferences and LLM providers
+// @scope.account Manage the profile, access tokens and tenants
+// @scope.admin Use instance administration routes
+// @description Personal access tokens are created with POST /tokens and sent as "Authorization: Bearer <token>".
+
 package httpapi
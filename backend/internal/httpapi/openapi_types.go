@@ -1,6 +1,9 @@
 package httpapi
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ErrorResponse is the standard JSON error payload for OpenAPI generation.
 type ErrorResponse struct {
@@ -83,6 +86,10 @@ type transactionListQuery struct {
 	SortDir            string     `form:"sort_dir" validate:"omitempty,oneof=asc desc"`
 }
 
+type transactionExportQuery struct {
+	Format string `form:"format" validate:"required,oneof=csv xlsx ofx ledger hledger beancount"`
+}
+
 // HealthResponse is the health check payload.
 type HealthResponse struct {
 	Status string `json:"status" example:"ok"`
@@ -152,6 +159,101 @@ type AdminScanningSettingsPatchRequest struct {
 	MaxConcurrentScans *int `json:"max_concurrent_scans" validate:"omitempty,min=1,max=64" example:"4"`
 }
 
+type llmUsageQuery struct {
+	From     *time.Time `form:"from"`
+	To       *time.Time `form:"to"`
+	Interval string     `form:"interval" validate:"omitempty,oneof=day month"`
+	TenantID string     `form:"tenant_id" validate:"omitempty,uuid"`
+}
+
+type AdminLLMUsageBucketResponse struct {
+	PeriodStart      time.Time `json:"period_start"`
+	TenantID         string    `json:"tenant_id" example:"7a4c2b1e-8f3d-4a5b-9c6d-1e2f3a4b5c6d"`
+	Provider         string    `json:"provider" example:"openai"`
+	Model            string    `json:"model" example:"gpt-4.1-mini"`
+	Calls            int64     `json:"calls" example:"42"`
+	FailedCalls      int64     `json:"failed_calls" example:"1"`
+	InputTokens      int64     `json:"input_tokens" example:"18000"`
+	OutputTokens     int64     `json:"output_tokens" example:"4200"`
+	TotalTokens      int64     `json:"total_tokens" example:"22200"`
+	AverageLatencyMS float64   `json:"average_latency_ms" example:"850.5"`
+}
+
+type AdminLLMUsageResponse struct {
+	Interval string                        `json:"interval" example:"day" enums:"day,month"`
+	From     time.Time                     `json:"from"`
+	To       time.Time                     `json:"to"`
+	Buckets  []AdminLLMUsageBucketResponse `json:"buckets"`
+}
+
+type AdminLLMQuotaResponse struct {
+	TenantID          string     `json:"tenant_id" example:"7a4c2b1e-8f3d-4a5b-9c6d-1e2f3a4b5c6d"`
+	DailyTokenLimit   int64      `json:"daily_token_limit" example:"200000"`
+	MonthlyTokenLimit int64      `json:"monthly_token_limit" example:"2000000"`
+	DailyTokensUsed   int64      `json:"daily_tokens_used" example:"12500"`
+	MonthlyTokensUsed int64      `json:"monthly_tokens_used" example:"480000"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+}
+
+type AdminLLMQuotaRequest struct {
+	DailyTokenLimit   *int64 `json:"daily_token_limit" validate:"required,min=0" example:"200000"`
+	MonthlyTokenLimit *int64 `json:"monthly_token_limit" validate:"required,min=0" example:"2000000"`
+}
+
+type AdminLLMPromptMessage struct {
+	Role    string `json:"role" validate:"required,oneof=system user assistant" example:"system" enums:"system,user,assistant"`
+	Content string `json:"content" validate:"required,max=20000" example:"Draft an Expensor rule from the samples."`
+}
+
+type AdminLLMPromptVariable struct {
+	Name        string `json:"name" validate:"required,max=100,no_control_chars" example:"rule_context_json"`
+	Description string `json:"description,omitempty" validate:"max=500,no_control_chars"`
+	Required    bool   `json:"required"`
+}
+
+type AdminLLMPromptDefinition struct {
+	ID                   string                   `json:"id" validate:"required,max=100,no_control_chars" example:"rule_draft"`
+	Description          string                   `json:"description,omitempty" validate:"max=500,no_control_chars"`
+	RequiredCapabilities []string                 `json:"required_capabilities,omitempty" validate:"dive,oneof=text_generation tools json_schema streaming" enums:"text_generation,tools,json_schema,streaming"`
+	Variables            []AdminLLMPromptVariable `json:"variables,omitempty" validate:"dive"`
+	Messages             []AdminLLMPromptMessage  `json:"messages" validate:"required,min=1,max=20,dive"`
+	MaxResultBytes       int                      `json:"max_result_bytes,omitempty" validate:"min=0"`
+	MaxResultItems       int                      `json:"max_result_items,omitempty" validate:"min=0"`
+}
+
+type AdminLLMPromptVersionRequest struct {
+	Prompt   AdminLLMPromptDefinition `json:"prompt"`
+	Activate bool                     `json:"activate"`
+}
+
+type AdminLLMPromptActivateRequest struct {
+	Version int `json:"version" validate:"required,min=1" example:"2"`
+}
+
+type AdminLLMPromptVersionResponse struct {
+	Version     int                      `json:"version" example:"2"`
+	Source      string                   `json:"source" example:"override" enums:"embedded,override"`
+	Active      bool                     `json:"active"`
+	CreatedBy   string                   `json:"created_by,omitempty"`
+	CreatedAt   *time.Time               `json:"created_at,omitempty"`
+	ActivatedAt *time.Time               `json:"activated_at,omitempty"`
+	Prompt      AdminLLMPromptDefinition `json:"prompt"`
+}
+
+type AdminLLMPromptSummaryResponse struct {
+	Workflow        string `json:"workflow" example:"rule_drafting"`
+	Purpose         string `json:"purpose" example:"draft_rule"`
+	EmbeddedVersion int    `json:"embedded_version" example:"1"`
+	ActiveVersion   int    `json:"active_version" example:"2"`
+	ActiveSource    string `json:"active_source" example:"override" enums:"embedded,override"`
+}
+
+type AdminLLMPromptVersionsResponse struct {
+	Workflow string                          `json:"workflow" example:"rule_drafting"`
+	Purpose  string                          `json:"purpose" example:"draft_rule"`
+	Versions []AdminLLMPromptVersionResponse `json:"versions"`
+}
+
 type AdminLoggingSettingsResponse struct {
 	Level string `json:"level" example:"info" enums:"debug,info,warn,error"`
 }
@@ -266,8 +368,10 @@ type LLMProviderConfigSaveRequest struct {
 }
 
 type LLMProviderConfigRequest struct {
-	Model   string `json:"model" example:"gpt-5.4-mini"`
-	BaseURL string `json:"base_url" example:"https://api.openai.com/v1"`
+	Model        string   `json:"model" example:"gpt-5.4-mini"`
+	BaseURL      string   `json:"base_url" example:"https://api.openai.com/v1"`
+	MaxTokens    int      `json:"max_tokens,omitempty" example:"1024"`
+	Capabilities []string `json:"capabilities,omitempty" example:"json_schema,tools"`
 }
 
 type LLMProviderCredentialsRequest struct {
@@ -338,6 +442,23 @@ type RuleDraftResponse struct {
 	ValidationIssues []RuleDraftIssueResponse `json:"validation_issues,omitempty"`
 }
 
+type TransactionQueryRequest struct {
+	Question string `json:"question" example:"How much did we spend on food delivery in March vs February?"`
+	Timezone string `json:"timezone,omitempty" example:"Asia/Kolkata"`
+}
+
+type TransactionQueryStepResponse struct {
+	Tool      string         `json:"tool" example:"list_transactions"`
+	Arguments map[string]any `json:"arguments"`
+	Result    map[string]any `json:"result,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+type TransactionQueryResponse struct {
+	Answer  string                         `json:"answer" example:"You spent INR 4,250.00 on food delivery in March, up from INR 3,100.00 in February."`
+	Queries []TransactionQueryStepResponse `json:"queries"`
+}
+
 // ConfigFieldResponse documents provider configuration field metadata.
 type ConfigFieldResponse struct {
 	Name      string `json:"name" example:"profilePath"`
@@ -704,25 +825,44 @@ type BankColorResponse struct {
 
 // TransactionResponse documents a transaction payload.
 type TransactionResponse struct {
-	ID               string             `json:"id" example:"00000000-0000-0000-0000-000000000001"`
-	MessageID        string             `json:"message_id" example:"gmail-message-id"`
-	Amount           float64            `json:"amount" example:"249.50"`
-	Currency         string             `json:"currency" example:"INR"`
-	OriginalAmount   *float64           `json:"original_amount,omitempty"`
-	OriginalCurrency *string            `json:"original_currency,omitempty"`
-	ExchangeRate     *float64           `json:"exchange_rate,omitempty"`
-	Timestamp        time.Time          `json:"timestamp"`
-	MerchantInfo     string             `json:"merchant_info" example:"Swiggy"`
-	Category         string             `json:"category" example:"Food & Dining"`
-	Bucket           string             `json:"bucket" example:"Needs"`
-	Source           RuleSourceResponse `json:"source"`
-	Description      string             `json:"description" example:"Dinner order"`
-	Labels           []string           `json:"labels"`
-	Muted            bool               `json:"muted"`
-	MutedByMerchant  bool               `json:"muted_by_merchant"`
-	MuteReason       string             `json:"mute_reason,omitempty" example:"Internal transfer"`
-	CreatedAt        time.Time          `json:"created_at"`
-	UpdatedAt        time.Time          `json:"updated_at"`
+	ID               string                     `json:"id" example:"00000000-0000-0000-0000-000000000001"`
+	MessageID        string                     `json:"message_id" example:"gmail-message-id"`
+	Amount           float64                    `json:"amount" example:"249.50"`
+	Currency         string                     `json:"currency" example:"INR"`
+	OriginalAmount   *float64                   `json:"original_amount,omitempty"`
+	OriginalCurrency *string                    `json:"original_currency,omitempty"`
+	ExchangeRate     *float64                   `json:"exchange_rate,omitempty"`
+	Timestamp        time.Time                  `json:"timestamp"`
+	MerchantInfo     string                     `json:"merchant_info" example:"Swiggy"`
+	Category         string                     `json:"category" example:"Food & Dining"`
+	Bucket           string                     `json:"bucket" example:"Needs"`
+	Source           RuleSourceResponse         `json:"source"`
+	Description      string                     `json:"description" example:"Dinner order"`
+	Labels           []string                   `json:"labels"`
+	Muted            bool                       `json:"muted"`
+	MutedByMerchant  bool                       `json:"muted_by_merchant"`
+	MuteReason       string                     `json:"mute_reason,omitempty" example:"Internal transfer"`
+	CreatedAt        time.Time                  `json:"created_at"`
+	UpdatedAt        time.Time                  `json:"updated_at"`
+	Splits           []TransactionSplitResponse `json:"splits,omitempty"`
+	Highlights       []SearchHighlight          `json:"highlights,omitempty"`
+}
+
+// TransactionSplitResponse documents one allocation of a split transaction.
+type TransactionSplitResponse struct {
+	ID           string   `json:"id" example:"22222222-2222-2222-2222-222222222222"`
+	Amount       float64  `json:"amount" example:"450"`
+	Category     string   `json:"category" example:"Groceries"`
+	Bucket       string   `json:"bucket" example:"Needs"`
+	Labels       []string `json:"labels"`
+	Counterparty string   `json:"counterparty,omitempty" example:"Priya"`
+}
+
+// SearchHighlight documents a matched search excerpt. Matches are wrapped in
+// <mark></mark> and the surrounding text is HTML-escaped.
+type SearchHighlight struct {
+	Field   string `json:"field" example:"body" enums:"merchant,description,subject,body"`
+	Snippet string `json:"snippet" example:"Delivery to <mark>Pune</mark> by Friday"`
 }
 
 // TransactionsListResponse documents the paginated list payload.
@@ -783,6 +923,134 @@ type TransactionUpdateRequest struct {
 	MuteReason  *string `json:"mute_reason,omitempty" validate:"omitempty,no_control_chars" example:"Duplicate notification"`
 }
 
+// BulkTransactionRequest applies the same changes to many transactions,
+// selected either by ids or by filter. dry_run reports the matches without
+// writing anything.
+type BulkTransactionRequest struct {
+	IDs          []string               `json:"ids,omitempty" validate:"omitempty,max=1000,dive,uuid"`
+	Filter       *BulkTransactionFilter `json:"filter,omitempty"`
+	Description  *string                `json:"description,omitempty" validate:"omitempty,no_control_chars" example:"Office supplies"`
+	Category     *string                `json:"category,omitempty" validate:"omitempty,no_control_chars" example:"Food & Dining"`
+	Bucket       *string                `json:"bucket,omitempty" validate:"omitempty,no_control_chars" example:"Needs"`
+	AddLabels    []string               `json:"add_labels,omitempty" validate:"omitempty,max=20,dive,min=1,no_control_chars"`
+	RemoveLabels []string               `json:"remove_labels,omitempty" validate:"omitempty,max=20,dive,min=1,no_control_chars"`
+	Muted        *bool                  `json:"muted,omitempty" example:"true"`
+	MuteReason   *string                `json:"mute_reason,omitempty" validate:"omitempty,no_control_chars" example:"Duplicate notification"`
+	DryRun       bool                   `json:"dry_run,omitempty" example:"true"`
+}
+
+// BulkTransactionFilter selects transactions with the same filters as
+// GET /api/transactions. Paging is ignored.
+type BulkTransactionFilter struct {
+	Merchant           string     `json:"merchant,omitempty" validate:"no_control_chars" example:"Swiggy"`
+	Category           string     `json:"category,omitempty" validate:"no_control_chars"`
+	CategoryMissing    bool       `json:"category_missing,omitempty"`
+	ExcludeCategories  []string   `json:"exclude_categories,omitempty" validate:"dive,no_control_chars"`
+	Currency           string     `json:"currency,omitempty" validate:"no_control_chars"`
+	Source             string     `json:"source,omitempty" validate:"no_control_chars"`
+	ExcludeSources     []string   `json:"exclude_sources,omitempty" validate:"dive,no_control_chars"`
+	SourceType         string     `json:"source_type,omitempty" validate:"no_control_chars"`
+	ExcludeSourceTypes []string   `json:"exclude_source_types,omitempty" validate:"dive,no_control_chars"`
+	Bank               string     `json:"bank,omitempty" validate:"no_control_chars" example:"HDFC"`
+	ExcludeBanks       []string   `json:"exclude_banks,omitempty" validate:"dive,no_control_chars"`
+	Label              string     `json:"label,omitempty" validate:"no_control_chars"`
+	LabelMissing       bool       `json:"label_missing,omitempty"`
+	ExcludeLabels      []string   `json:"exclude_labels,omitempty" validate:"dive,no_control_chars"`
+	Bucket             string     `json:"bucket,omitempty" validate:"no_control_chars"`
+	BucketMissing      bool       `json:"bucket_missing,omitempty"`
+	ExcludeBuckets     []string   `json:"exclude_buckets,omitempty" validate:"dive,no_control_chars"`
+	DateFrom           *time.Time `json:"date_from,omitempty" example:"2026-03-01T00:00:00Z"`
+	DateTo             *time.Time `json:"date_to,omitempty" example:"2026-03-31T23:59:59Z"`
+	ShowMuted          bool       `json:"show_muted,omitempty"`
+	MutedOnly          bool       `json:"muted_only,omitempty"`
+	IndividualOnly     bool       `json:"individual_only,omitempty"`
+	Weekday            *int       `json:"weekday,omitempty" validate:"omitempty,min=0,max=6"`
+	HourFrom           *int       `json:"hour_from,omitempty" validate:"omitempty,min=0,max=23"`
+	HourTo             *int       `json:"hour_to,omitempty" validate:"omitempty,min=0,max=23"`
+	Timezone           string     `json:"tz,omitempty" validate:"omitempty,iana_timezone" example:"Asia/Kolkata"`
+}
+
+// BulkTransactionResponse reports how many transactions matched and what
+// happened to each one.
+type BulkTransactionResponse struct {
+	DryRun  bool                         `json:"dry_run" example:"false"`
+	BatchID string                       `json:"batch_id,omitempty" example:"88888888-8888-8888-8888-888888888888"`
+	Matched int                          `json:"matched" example:"2"`
+	Updated int                          `json:"updated" example:"2"`
+	Results []BulkTransactionRowResponse `json:"results"`
+}
+
+// BulkTransactionRowResponse is the outcome for one transaction.
+type BulkTransactionRowResponse struct {
+	ID     string `json:"id" example:"00000000-0000-0000-0000-000000000001"`
+	Status string `json:"status" example:"updated" enums:"updated,matched,not_found"`
+}
+
+// TransactionChangeResponse is one logged change to a transaction. Label
+// changes use the field "label": an added label has no old_value and a
+// removed label has no new_value.
+type TransactionChangeResponse struct {
+	ID            int64   `json:"id" example:"42"`
+	BatchID       string  `json:"batch_id" example:"88888888-8888-8888-8888-888888888888"`
+	TransactionID string  `json:"transaction_id" example:"00000000-0000-0000-0000-000000000001"`
+	Field         string  `json:"field" example:"category"`
+	OldValue      *string `json:"old_value" example:"Shopping"`
+	NewValue      *string `json:"new_value" example:"Food & Dining"`
+	Cause         string  `json:"cause" example:"merchant_mapping" enums:"user_edit,bulk_edit,merchant_mapping,rule_engine,re_extraction,revert,import,system"`
+	ActorID       string  `json:"actor_id,omitempty" example:"11111111-1111-1111-1111-111111111111"`
+	ActorEmail    string  `json:"actor_email,omitempty" example:"owner@example.com"`
+	ChangedAt     string  `json:"changed_at" example:"2026-03-01T12:30:00Z"`
+}
+
+// ChangeBatchRevertResponse reports what reverting a change batch did.
+// batch_id names the revert itself, which can be reverted in turn.
+type ChangeBatchRevertResponse struct {
+	BatchID         string                    `json:"batch_id" example:"99999999-9999-9999-9999-999999999999"`
+	RevertedBatchID string                    `json:"reverted_batch_id" example:"88888888-8888-8888-8888-888888888888"`
+	Reverted        int                       `json:"reverted" example:"3"`
+	Skipped         int                       `json:"skipped" example:"1"`
+	Results         []ChangeRevertRowResponse `json:"results"`
+}
+
+// ChangeRevertRowResponse is the outcome for one logged change. Changes whose
+// field was edited again after the batch are skipped.
+type ChangeRevertRowResponse struct {
+	ChangeID      int64  `json:"change_id" example:"42"`
+	TransactionID string `json:"transaction_id" example:"00000000-0000-0000-0000-000000000001"`
+	Field         string `json:"field" example:"category"`
+	Status        string `json:"status" example:"reverted" enums:"reverted,skipped"`
+}
+
+// TransactionCreateRequest is the manual transaction payload. Currency
+// defaults to the base currency and timestamp to the current time. Labels
+// must already exist; duplicates are dropped.
+type TransactionCreateRequest struct {
+	Amount       float64    `json:"amount" validate:"gt=0" example:"250"`
+	Currency     string     `json:"currency,omitempty" validate:"omitempty,currency_code" example:"INR" minLength:"3" maxLength:"3"`
+	Timestamp    *time.Time `json:"timestamp,omitempty" example:"2026-03-01T12:30:00Z"`
+	MerchantInfo string     `json:"merchant_info" validate:"required,max=255,no_control_chars" example:"Corner Chai Stall"`
+	Category     string     `json:"category,omitempty" validate:"omitempty,no_control_chars" example:"Food & Dining"`
+	Bucket       string     `json:"bucket,omitempty" validate:"omitempty,no_control_chars" example:"Needs"`
+	Description  string     `json:"description,omitempty" validate:"omitempty,max=1000,no_control_chars" example:"Reimbursable team snacks"`
+	SourceLabel  string     `json:"source_label,omitempty" validate:"omitempty,max=100,no_control_chars" example:"Cash"`
+	Labels       []string   `json:"labels,omitempty" validate:"omitempty,max=20,dive,min=1,max=100,no_control_chars"`
+}
+
+// TransactionSplitsRequest replaces the splits of a transaction. Amounts must
+// sum to the transaction amount; an empty list removes the split.
+type TransactionSplitsRequest struct {
+	Splits []TransactionSplitRequest `json:"splits" validate:"max=50,dive"`
+}
+
+// TransactionSplitRequest is one allocation in a split payload.
+type TransactionSplitRequest struct {
+	Amount       float64  `json:"amount" validate:"gt=0" example:"450"`
+	Category     string   `json:"category,omitempty" validate:"omitempty,no_control_chars" example:"Groceries"`
+	Bucket       string   `json:"bucket,omitempty" validate:"omitempty,no_control_chars" example:"Needs"`
+	Labels       []string `json:"labels,omitempty" validate:"omitempty,max=20,dive,min=1,max=100,no_control_chars"`
+	Counterparty string   `json:"counterparty,omitempty" validate:"omitempty,max=100,no_control_chars" example:"Priya"`
+}
+
 // TransactionLabelsRequest is the transaction labels mutation payload.
 type TransactionLabelsRequest struct {
 	Labels []string `json:"labels" validate:"required,min=1,dive,required,no_control_chars"`
@@ -829,3 +1097,572 @@ type CategorizeMerchantRequest struct {
 type CategorizeMerchantResponse struct {
 	Updated int64 `json:"updated"`
 }
+
+// CreateSharedLedgerRequest creates a shared ledger owned by the caller.
+// Other users are invited by their account email and join once they accept.
+type CreateSharedLedgerRequest struct {
+	Name         string   `json:"name" validate:"required,max=100,no_control_chars" example:"Flat 4B"`
+	InviteEmails []string `json:"invite_emails,omitempty" validate:"omitempty,max=20,dive,email"`
+}
+
+// SharedLedgerMemberRequest invites an instance user to a shared ledger.
+type SharedLedgerMemberRequest struct {
+	Email string `json:"email" validate:"required,email" example:"flatmate@example.com"`
+}
+
+// ExportAccountsRequest maps sources and categories to journal accounts for
+// Ledger, hledger and Beancount exports. Keys match case-insensitively.
+type ExportAccountsRequest struct {
+	Sources        map[string]string `json:"sources" example:"HDFC credit-card Regalia:Liabilities:HDFC:Regalia"`
+	Categories     map[string]string `json:"categories" example:"Food & Dining:Expenses:Food"`
+	DefaultSource  string            `json:"default_source,omitempty" example:"Assets:Cash"`
+	DefaultExpense string            `json:"default_expense,omitempty" example:"Expenses:Uncategorized"`
+}
+
+// ExportAccountsResponse documents the stored export account mapping.
+type ExportAccountsResponse struct {
+	Sources        map[string]string `json:"sources"`
+	Categories     map[string]string `json:"categories"`
+	DefaultSource  string            `json:"default_source,omitempty" example:"Assets:Cash"`
+	DefaultExpense string            `json:"default_expense,omitempty" example:"Expenses:Uncategorized"`
+}
+
+// AttachmentResponse documents a file attached to a transaction.
+type AttachmentResponse struct {
+	ID            string    `json:"id" example:"99999999-9999-9999-9999-999999999999"`
+	TransactionID string    `json:"transaction_id" example:"00000000-0000-0000-0000-000000000001"`
+	Filename      string    `json:"filename" example:"invoice.pdf"`
+	ContentType   string    `json:"content_type" example:"application/pdf"`
+	Size          int64     `json:"size" example:"48213"`
+	SHA256        string    `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Source        string    `json:"source" example:"upload" enums:"upload,email"`
+	CreatedAt     time.Time `json:"created_at" example:"2026-03-01T12:30:00Z"`
+}
+
+// SharedLedgerResponse documents a shared ledger and its members.
+type SharedLedgerResponse struct {
+	ID        string                       `json:"id" example:"33333333-3333-3333-3333-333333333333"`
+	Name      string                       `json:"name" example:"Flat 4B"`
+	CreatedBy string                       `json:"created_by" example:"11111111-1111-1111-1111-111111111111"`
+	Members   []SharedLedgerMemberResponse `json:"members"`
+	CreatedAt time.Time                    `json:"created_at" example:"2026-03-01T12:30:00Z"`
+}
+
+// SharedLedgerInvitationResponse documents a pending invitation to a ledger.
+type SharedLedgerInvitationResponse struct {
+	LedgerID      string    `json:"ledger_id" example:"33333333-3333-3333-3333-333333333333"`
+	LedgerName    string    `json:"ledger_name" example:"Flat 4B"`
+	InvitedBy     string    `json:"invited_by" example:"11111111-1111-1111-1111-111111111111"`
+	InvitedByName string    `json:"invited_by_name" example:"Priya"`
+	CreatedAt     time.Time `json:"created_at" example:"2026-03-01T12:30:00Z"`
+}
+
+// SharedLedgerMemberResponse documents one ledger member.
+type SharedLedgerMemberResponse struct {
+	UserID      string    `json:"user_id" example:"11111111-1111-1111-1111-111111111111"`
+	DisplayName string    `json:"display_name" example:"Priya"`
+	Email       string    `json:"email" example:"priya@example.com"`
+	JoinedAt    time.Time `json:"joined_at" example:"2026-03-01T12:30:00Z"`
+}
+
+// SharedLedgerDetailResponse documents a ledger with each member's net
+// balance and the fewest payments that would settle them.
+type SharedLedgerDetailResponse struct {
+	ID          string                         `json:"id" example:"33333333-3333-3333-3333-333333333333"`
+	Name        string                         `json:"name" example:"Flat 4B"`
+	CreatedBy   string                         `json:"created_by" example:"11111111-1111-1111-1111-111111111111"`
+	Members     []SharedLedgerMemberResponse   `json:"members"`
+	CreatedAt   time.Time                      `json:"created_at" example:"2026-03-01T12:30:00Z"`
+	Balances    []LedgerBalanceResponse        `json:"balances"`
+	Suggestions []SettlementSuggestionResponse `json:"suggestions"`
+}
+
+// LedgerBalanceResponse documents a member's net balance in one currency.
+// Positive amounts are owed to the member.
+type LedgerBalanceResponse struct {
+	UserID   string  `json:"user_id" example:"11111111-1111-1111-1111-111111111111"`
+	Currency string  `json:"currency" example:"INR"`
+	Amount   float64 `json:"amount" example:"-450"`
+}
+
+// SettlementSuggestionResponse documents one suggested settle-up payment.
+type SettlementSuggestionResponse struct {
+	FromUserID string  `json:"from_user_id" example:"22222222-2222-2222-2222-222222222222"`
+	ToUserID   string  `json:"to_user_id" example:"11111111-1111-1111-1111-111111111111"`
+	Currency   string  `json:"currency" example:"INR"`
+	Amount     float64 `json:"amount" example:"450"`
+}
+
+// ShareExpenseRequest shares a transaction, or one of its splits, owned by
+// the caller. The caller keeps whatever ratio the shares leave over.
+type ShareExpenseRequest struct {
+	TransactionID string                `json:"transaction_id" validate:"required,uuid" example:"44444444-4444-4444-4444-444444444444"`
+	SplitID       string                `json:"split_id,omitempty" validate:"omitempty,uuid" example:"22222222-2222-2222-2222-222222222222"`
+	Shares        []ExpenseShareRequest `json:"shares" validate:"required,min=1,max=50,dive"`
+}
+
+// ExpenseShareRequest assigns a fraction of an expense to a ledger member.
+type ExpenseShareRequest struct {
+	UserID string  `json:"user_id" validate:"required,uuid" example:"22222222-2222-2222-2222-222222222222"`
+	Ratio  float64 `json:"ratio" validate:"gt=0,lte=1" example:"0.5"`
+}
+
+// SharedExpenseResponse documents a shared expense. Other members only see
+// the merchant, time and amount of the underlying transaction.
+type SharedExpenseResponse struct {
+	ID            string                 `json:"id" example:"55555555-5555-5555-5555-555555555555"`
+	LedgerID      string                 `json:"ledger_id" example:"33333333-3333-3333-3333-333333333333"`
+	TransactionID string                 `json:"transaction_id" example:"44444444-4444-4444-4444-444444444444"`
+	SplitID       string                 `json:"split_id,omitempty" example:"22222222-2222-2222-2222-222222222222"`
+	PaidBy        string                 `json:"paid_by" example:"11111111-1111-1111-1111-111111111111"`
+	MerchantInfo  string                 `json:"merchant_info" example:"Big Bazaar"`
+	Timestamp     time.Time              `json:"timestamp" example:"2026-03-01T12:30:00Z"`
+	Amount        float64                `json:"amount" example:"900"`
+	Currency      string                 `json:"currency" example:"INR"`
+	Shares        []ExpenseShareResponse `json:"shares"`
+	CreatedAt     time.Time              `json:"created_at" example:"2026-03-01T12:30:00Z"`
+}
+
+// ExpenseShareResponse documents what one member owes the payer.
+type ExpenseShareResponse struct {
+	UserID string  `json:"user_id" example:"22222222-2222-2222-2222-222222222222"`
+	Ratio  float64 `json:"ratio" example:"0.5"`
+	Amount float64 `json:"amount" example:"450"`
+}
+
+// RecordSettlementRequest records a payment made outside Expensor. The caller
+// must be the payer or the recipient. settled_at defaults to now.
+type RecordSettlementRequest struct {
+	FromUserID string     `json:"from_user_id" validate:"required,uuid" example:"22222222-2222-2222-2222-222222222222"`
+	ToUserID   string     `json:"to_user_id" validate:"required,uuid" example:"11111111-1111-1111-1111-111111111111"`
+	Amount     float64    `json:"amount" validate:"gt=0" example:"450"`
+	Currency   string     `json:"currency" validate:"required,currency_code" example:"INR" minLength:"3" maxLength:"3"`
+	Note       string     `json:"note,omitempty" validate:"omitempty,max=200,no_control_chars" example:"UPI transfer"`
+	SettledAt  *time.Time `json:"settled_at,omitempty" example:"2026-03-02T09:00:00Z"`
+}
+
+// SettlementResponse documents a recorded settlement.
+type SettlementResponse struct {
+	ID         string    `json:"id" example:"66666666-6666-6666-6666-666666666666"`
+	LedgerID   string    `json:"ledger_id" example:"33333333-3333-3333-3333-333333333333"`
+	FromUserID string    `json:"from_user_id" example:"22222222-2222-2222-2222-222222222222"`
+	ToUserID   string    `json:"to_user_id" example:"11111111-1111-1111-1111-111111111111"`
+	Amount     float64   `json:"amount" example:"450"`
+	Currency   string    `json:"currency" example:"INR"`
+	Note       string    `json:"note,omitempty" example:"UPI transfer"`
+	SettledAt  time.Time `json:"settled_at" example:"2026-03-02T09:00:00Z"`
+	CreatedBy  string    `json:"created_by" example:"22222222-2222-2222-2222-222222222222"`
+	CreatedAt  time.Time `json:"created_at" example:"2026-03-02T09:00:00Z"`
+}
+
+// CreateTenantRequest creates a tenant, such as a household, owned by the
+// caller.
+type CreateTenantRequest struct {
+	Name string `json:"name" validate:"required,max=100,no_control_chars" example:"Household"`
+}
+
+// TenantMemberRequest invites an instance user to a tenant with a role.
+// Viewers can read the tenant but not change it.
+type TenantMemberRequest struct {
+	Email string `json:"email" validate:"required,email" example:"partner@example.com"`
+	Role  string `json:"role" validate:"required,oneof=owner editor viewer" example:"editor" enums:"owner,editor,viewer"`
+}
+
+// TenantMemberRoleRequest changes a member's role in a tenant.
+type TenantMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer" example:"viewer" enums:"owner,editor,viewer"`
+}
+
+// SelectTenantRequest switches the active tenant of the browser session.
+type SelectTenantRequest struct {
+	TenantID string `json:"tenant_id" validate:"required,uuid" example:"77777777-7777-7777-7777-777777777777"`
+}
+
+// TenantMembershipResponse documents a tenant the caller belongs to.
+type TenantMembershipResponse struct {
+	TenantID   string    `json:"tenant_id" example:"77777777-7777-7777-7777-777777777777"`
+	TenantName string    `json:"tenant_name" example:"Household"`
+	Role       string    `json:"role" example:"owner" enums:"owner,editor,viewer"`
+	Personal   bool      `json:"personal" example:"false"`
+	JoinedAt   time.Time `json:"joined_at" example:"2026-03-01T12:30:00Z"`
+	Active     bool      `json:"active" example:"true"`
+}
+
+// TenantMemberResponse documents one user with access to a tenant.
+type TenantMemberResponse struct {
+	UserID      string    `json:"user_id" example:"22222222-2222-2222-2222-222222222222"`
+	Email       string    `json:"email" example:"partner@example.com"`
+	DisplayName string    `json:"display_name" example:"Sam"`
+	Role        string    `json:"role" example:"editor" enums:"owner,editor,viewer"`
+	JoinedAt    time.Time `json:"joined_at" example:"2026-03-01T12:30:00Z"`
+}
+
+type accountImportQuery struct {
+	Policy string `form:"policy" validate:"omitempty,oneof=skip overwrite merge"`
+}
+
+// TenantInvitationResponse documents a pending invitation to a tenant.
+type TenantInvitationResponse struct {
+	TenantID      string    `json:"tenant_id" example:"77777777-7777-7777-7777-777777777777"`
+	TenantName    string    `json:"tenant_name" example:"Household"`
+	Role          string    `json:"role" example:"editor" enums:"owner,editor,viewer"`
+	InvitedBy     string    `json:"invited_by" example:"11111111-1111-1111-1111-111111111111"`
+	InvitedByName string    `json:"invited_by_name" example:"Priya"`
+	CreatedAt     time.Time `json:"created_at" example:"2026-03-01T12:30:00Z"`
+}
+
+// TenantArchiveResponse documents a tenant archive. The same document is the
+// body of POST /api/account/import.
+type TenantArchiveResponse struct {
+	Format           string                           `json:"format" example:"expensor.tenant-archive"`
+	Version          int                              `json:"version" example:"1"`
+	ExportedAt       time.Time                        `json:"exported_at"`
+	Tenant           ArchiveTenantResponse            `json:"tenant"`
+	Counts           map[string]int                   `json:"counts"`
+	Excluded         []string                         `json:"excluded" example:"reader_tokens"`
+	Preferences      map[string]string                `json:"preferences"`
+	Labels           []LabelResponse                  `json:"labels"`
+	Categories       []CategoryResponse               `json:"categories"`
+	Buckets          []BucketResponse                 `json:"buckets"`
+	LabelMappings    []ArchiveLabelMappingResponse    `json:"label_mappings"`
+	MerchantMappings []ArchiveMerchantMappingResponse `json:"merchant_mappings"`
+	MutedMerchants   []MutedMerchantResponse          `json:"muted_merchants"`
+	Rules            []ArchiveRuleResponse            `json:"rules"`
+	Readers          []ArchiveReaderResponse          `json:"readers"`
+	Diagnostics      []ExtractionDiagnosticResponse   `json:"diagnostics"`
+	Transactions     []ArchiveTransactionResponse     `json:"transactions"`
+}
+
+// ArchiveTenantResponse names the tenant an archive was taken from.
+type ArchiveTenantResponse struct {
+	ID   string `json:"id" example:"77777777-7777-7777-7777-777777777777"`
+	Name string `json:"name" example:"Household"`
+}
+
+// ArchiveLabelMappingResponse documents an archived label merchant mapping.
+type ArchiveLabelMappingResponse struct {
+	Label   string `json:"label" example:"food"`
+	Pattern string `json:"pattern" example:"Swiggy"`
+}
+
+// ArchiveMerchantMappingResponse documents an archived category or bucket
+// merchant mapping.
+type ArchiveMerchantMappingResponse struct {
+	Pattern  string `json:"pattern" example:"Swiggy"`
+	Category string `json:"category,omitempty" example:"Food & Dining"`
+	Bucket   string `json:"bucket,omitempty" example:"Wants"`
+}
+
+// ArchiveRuleResponse documents an archived user extraction rule.
+type ArchiveRuleResponse struct {
+	ID                string    `json:"id" example:"11111111-1111-1111-1111-111111111111"`
+	Name              string    `json:"name" example:"Contract Rule"`
+	SenderEmail       string    `json:"sender_email" example:"contract@example.com"`
+	SenderEmails      []string  `json:"sender_emails"`
+	SubjectContains   string    `json:"subject_contains" example:"Contract transaction"`
+	AmountRegex       string    `json:"amount_regex" example:"INR\\s+([0-9,.]+)"`
+	MerchantRegex     string    `json:"merchant_regex" example:"at\\s+(.+)$"`
+	CurrencyRegex     string    `json:"currency_regex" example:"(INR)"`
+	TransactionSource string    `json:"transaction_source" example:"Contract"`
+	SourceType        string    `json:"source_type" example:"credit-card"`
+	SourceLabel       string    `json:"source_label" example:"Contract"`
+	Bank              string    `json:"bank" example:"Contract Bank"`
+	Predefined        bool      `json:"predefined"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ArchiveReaderResponse documents a reader's archived settings. Credentials
+// and tokens are never archived.
+type ArchiveReaderResponse struct {
+	Reader string         `json:"reader" example:"gmail"`
+	Config map[string]any `json:"config"`
+}
+
+// ArchiveTransactionResponse documents an archived transaction.
+type ArchiveTransactionResponse struct {
+	TransactionResponse
+	LabelSources []ArchiveLabelSourceResponse `json:"label_sources,omitempty"`
+	Email        *ArchiveEmailResponse        `json:"email,omitempty"`
+}
+
+// ArchiveLabelSourceResponse records why a label applies to a transaction.
+type ArchiveLabelSourceResponse struct {
+	Label           string `json:"label" example:"food"`
+	Type            string `json:"type" example:"merchant" enums:"manual,merchant"`
+	MerchantPattern string `json:"merchant_pattern,omitempty" example:"Swiggy"`
+}
+
+// ArchiveEmailResponse documents the archived text of a source email.
+type ArchiveEmailResponse struct {
+	Subject string `json:"subject" example:"Transaction alert"`
+	Body    string `json:"body" example:"Your card was charged INR 249.50 at Swiggy"`
+}
+
+// TenantImportResponse reports what an archive import did, per section.
+type TenantImportResponse struct {
+	Policy      string                                  `json:"policy" example:"skip" enums:"skip,overwrite,merge"`
+	Sections    map[string]ArchiveSectionResultResponse `json:"sections"`
+	RemappedIDs int                                     `json:"remapped_ids" example:"0"`
+}
+
+// ArchiveSectionResultResponse counts what an import did with one section.
+type ArchiveSectionResultResponse struct {
+	Created int `json:"created" example:"12"`
+	Updated int `json:"updated" example:"0"`
+	Skipped int `json:"skipped" example:"3"`
+}
+
+// BackupResponse describes a stored database backup.
+type BackupResponse struct {
+	Name      string    `json:"name" example:"expensor-backup-20260301T020000Z.tar.gz"`
+	Size      int64     `json:"size" example:"482133"`
+	CreatedAt time.Time `json:"created_at" example:"2026-03-01T02:00:00Z"`
+}
+
+// BackupSettingsResponse describes scheduled backups and retention.
+type BackupSettingsResponse struct {
+	Enabled        bool       `json:"enabled" example:"true"`
+	IntervalHours  int        `json:"interval_hours" example:"24"`
+	KeepLast       int        `json:"keep_last" example:"7"`
+	KeepDays       int        `json:"keep_days" example:"30"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastBackup     string     `json:"last_backup,omitempty" example:"expensor-backup-20260301T020000Z.tar.gz"`
+	LastError      string     `json:"last_error,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type BackupSettingsPatchRequest struct {
+	Enabled       *bool `json:"enabled" example:"true"`
+	IntervalHours *int  `json:"interval_hours" validate:"omitempty,min=1,max=8760" example:"24"`
+	KeepLast      *int  `json:"keep_last" validate:"omitempty,min=1,max=1000" example:"7"`
+	KeepDays      *int  `json:"keep_days" validate:"omitempty,min=0,max=3650" example:"30"`
+}
+
+// EncryptionStatusResponse describes the secret keyring and the latest
+// re-encryption run.
+type EncryptionStatusResponse struct {
+	// Key ID new values are sealed with.
+	ActiveKeyID string `json:"active_key_id" example:"3f9a0c1d27e4b856"`
+	// Key IDs that still decrypt values sealed before a rotation.
+	PreviousKeyIDs []string   `json:"previous_key_ids"`
+	State          string     `json:"state" enums:"idle,running,completed,failed" example:"completed"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	// Progress per sealed column, in the order they are walked.
+	Kinds []EncryptionKindProgressResponse `json:"kinds"`
+	// Values no configured key could open; the first 50 are listed.
+	Failures []string `json:"failures"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// EncryptionKindProgressResponse counts the values of one kind a run walked.
+type EncryptionKindProgressResponse struct {
+	Kind string `json:"kind" enums:"reader_client_secret,reader_oauth_token,llm_credentials,totp_secret,webhook_secret,notification_token,attachment" example:"reader_oauth_token"`
+	// Values present when the run started.
+	Total    int64 `json:"total" example:"12"`
+	Scanned  int64 `json:"scanned" example:"12"`
+	Resealed int64 `json:"resealed" example:"9"`
+	Failed   int64 `json:"failed" example:"0"`
+}
+
+type auditEventListQuery struct {
+	Page        int        `form:"page" validate:"min=0"`
+	PageSize    *int       `form:"page_size" validate:"omitempty,min=1,max=200"`
+	ActorUserID string     `form:"actor_user_id" validate:"omitempty,uuid"`
+	TenantID    string     `form:"tenant_id" validate:"omitempty,uuid"`
+	Action      string     `form:"action" validate:"max=100,no_control_chars"`
+	TargetType  string     `form:"target_type" validate:"max=100,no_control_chars"`
+	TargetID    string     `form:"target_id" validate:"max=320,no_control_chars"`
+	Outcome     string     `form:"outcome" validate:"omitempty,oneof=success failure denied"`
+	RequestID   string     `form:"request_id" validate:"max=100,no_control_chars"`
+	From        *time.Time `form:"from"`
+	To          *time.Time `form:"to"`
+}
+
+// AuditEventResponse is one recorded security-relevant action.
+type AuditEventResponse struct {
+	ID          string    `json:"id" example:"9d0f5c1e-2b3a-4c5d-8e7f-6a5b4c3d2e1f"`
+	OccurredAt  time.Time `json:"occurred_at" example:"2026-03-01T02:00:00Z"`
+	ActorUserID string    `json:"actor_user_id,omitempty" example:"00000000-0000-0000-0000-00000000c0de"`
+	// Current email of the actor; omitted once the user is deleted.
+	ActorEmail string `json:"actor_email,omitempty" example:"admin@example.com"`
+	TenantID   string `json:"tenant_id,omitempty" example:"7a4c2b1e-8f3d-4a5b-9c6d-1e2f3a4b5c6d"`
+	AuthMethod string `json:"auth_method,omitempty" enums:"session,bearer,proxy" example:"session"`
+	Action     string `json:"action" example:"access_token.create"`
+	TargetType string `json:"target_type,omitempty" example:"access_token"`
+	TargetID   string `json:"target_id,omitempty" example:"00000000-0000-0000-0000-0000000070c3"`
+	RequestID  string `json:"request_id,omitempty" example:"3c8e2f4a-1b6d-4e9a-8f2c-5d7b9a1e3c6f"`
+	IPAddress  string `json:"ip_address,omitempty" example:"203.0.113.7"`
+	Outcome    string `json:"outcome" enums:"success,failure,denied" example:"success"`
+	// Why a failed or denied action did not take effect.
+	Detail string `json:"detail,omitempty" example:"invalid email or password"`
+}
+
+type AuditEventListResponse struct {
+	Events   []AuditEventResponse `json:"events"`
+	Total    int                  `json:"total" example:"1"`
+	Page     int                  `json:"page" example:"1"`
+	PageSize int                  `json:"page_size" example:"50"`
+}
+
+// AuditSettingsResponse describes how long audit events are kept.
+type AuditSettingsResponse struct {
+	// Events older than this many days are removed; 0 keeps every event.
+	RetentionDays int       `json:"retention_days" example:"365"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type AuditSettingsPatchRequest struct {
+	RetentionDays *int `json:"retention_days" validate:"omitempty,min=0,max=3650" example:"365"`
+}
+
+// CreateWebhookRequest subscribes a URL to events. Deliveries to internal
+// addresses are refused unless an admin allows their network.
+type CreateWebhookRequest struct {
+	URL         string `json:"url" validate:"required,max=2048,http_url" example:"https://homeassistant.local/api/webhook/expensor"`
+	Description string `json:"description" validate:"max=200,no_control_chars" example:"Home Assistant"`
+	// Events to deliver. There is no budget.exceeded event, because Expensor does not track budgets.
+	Events []string `json:"events" validate:"required,min=1,dive,webhook_event" enums:"transaction.created,transaction.updated,diagnostic.opened,scan.failed" example:"transaction.created"`
+	// Enabled defaults to true.
+	Enabled *bool `json:"enabled" example:"true"`
+	// Secret signs deliveries; one is generated when omitted.
+	Secret string `json:"secret" validate:"omitempty,min=16,max=256,no_control_chars" example:"whsec_0123456789abcdef"`
+}
+
+// WebhookPatchRequest partially updates a webhook.
+type WebhookPatchRequest struct {
+	URL         *string  `json:"url" validate:"omitempty,max=2048,http_url" example:"https://homeassistant.local/api/webhook/expensor"`
+	Description *string  `json:"description" validate:"omitempty,max=200,no_control_chars" example:"Home Assistant"`
+	Events      []string `json:"events" validate:"omitempty,dive,webhook_event" enums:"transaction.created,transaction.updated,diagnostic.opened,scan.failed" example:"transaction.created"`
+	Enabled     *bool    `json:"enabled" example:"false"`
+	// Secret replaces the signing secret.
+	Secret *string `json:"secret" validate:"omitempty,min=16,max=256,no_control_chars" example:"whsec_0123456789abcdef"`
+}
+
+// WebhookResponse describes a webhook subscription.
+type WebhookResponse struct {
+	ID          string   `json:"id" example:"55555555-5555-5555-5555-555555555555"`
+	URL         string   `json:"url" example:"https://homeassistant.local/api/webhook/expensor"`
+	Description string   `json:"description" example:"Home Assistant"`
+	Events      []string `json:"events" enums:"transaction.created,transaction.updated,diagnostic.opened,scan.failed" example:"transaction.created"`
+	Enabled     bool     `json:"enabled" example:"true"`
+	// Secret is only returned when the webhook is created.
+	Secret    string    `json:"secret,omitempty" example:"whsec_0123456789abcdef"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type webhookDeliveryListQuery struct {
+	Limit *int `form:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+// WebhookDeliveryResponse describes one event queued for a webhook and its
+// latest attempt.
+type WebhookDeliveryResponse struct {
+	ID        string          `json:"id" example:"66666666-6666-6666-6666-666666666666"`
+	EventType string          `json:"event_type" enums:"transaction.created,transaction.updated,diagnostic.opened,scan.failed" example:"transaction.created"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	Status    string          `json:"status" enums:"pending,delivered,failed" example:"delivered"`
+	Attempts  int             `json:"attempts" example:"1"`
+	// NextAttemptAt is when a pending delivery is sent next.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	// ResponseStatus is the HTTP status of the latest attempt, absent when
+	// the endpoint could not be reached.
+	ResponseStatus int        `json:"response_status,omitempty" example:"204"`
+	LastError      string     `json:"last_error,omitempty" example:"endpoint responded 502 Bad Gateway"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// NotificationPreferencesResponse describes what the current user is
+// notified about in the active tenant.
+type NotificationPreferencesResponse struct {
+	WeeklyDigest bool `json:"weekly_digest" example:"true"`
+	// Weekday the weekly digest is sent, counting from 0 for Sunday.
+	DigestWeekday int `json:"digest_weekday" example:"1"`
+	// Hour the weekly digest is sent, in the tenant's timezone.
+	DigestHour                int     `json:"digest_hour" example:"9"`
+	LargeTransactionAlerts    bool    `json:"large_transaction_alerts" example:"true"`
+	LargeTransactionThreshold float64 `json:"large_transaction_threshold" example:"10000"`
+	ScanAlerts                bool    `json:"scan_alerts" example:"true"`
+	// Absent until the preferences are first saved.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type NotificationPreferencesPatchRequest struct {
+	WeeklyDigest              *bool    `json:"weekly_digest" example:"true"`
+	DigestWeekday             *int     `json:"digest_weekday" validate:"omitempty,min=0,max=6" example:"1"`
+	DigestHour                *int     `json:"digest_hour" validate:"omitempty,min=0,max=23" example:"9"`
+	LargeTransactionAlerts    *bool    `json:"large_transaction_alerts" example:"true"`
+	LargeTransactionThreshold *float64 `json:"large_transaction_threshold" validate:"omitempty,gt=0" example:"10000"`
+	ScanAlerts                *bool    `json:"scan_alerts" example:"true"`
+}
+
+// CreateNotificationChannelRequest adds a notification channel.
+type CreateNotificationChannelRequest struct {
+	Type string `json:"type" validate:"required,oneof=email ntfy gotify telegram webhook" enums:"email,ntfy,gotify,telegram,webhook" example:"ntfy"`
+	// Name defaults to the channel type.
+	Name string `json:"name" validate:"max=100,no_control_chars" example:"Phone"`
+	// An email address for email, a topic URL for ntfy, a server URL for gotify and webhook, or a chat ID for telegram.
+	Target string `json:"target" validate:"required,max=2048,no_control_chars" example:"https://ntfy.sh/expensor-alerts"`
+	// Required for gotify and telegram; an optional access token for ntfy and signing secret for webhook.
+	Token string `json:"token" validate:"max=512,no_control_chars" example:"tk_0123456789abcdef"`
+	// Enabled defaults to true.
+	Enabled *bool `json:"enabled" example:"true"`
+}
+
+// NotificationChannelPatchRequest partially updates a notification channel.
+type NotificationChannelPatchRequest struct {
+	Name   *string `json:"name" validate:"omitempty,max=100,no_control_chars" example:"Phone"`
+	Target *string `json:"target" validate:"omitempty,max=2048,no_control_chars" example:"https://ntfy.sh/expensor-alerts"`
+	// Token replaces the token; an empty string removes it.
+	Token   *string `json:"token" validate:"omitempty,max=512,no_control_chars" example:"tk_0123456789abcdef"`
+	Enabled *bool   `json:"enabled" example:"false"`
+}
+
+// NotificationChannelResponse describes a notification channel. Its token
+// is never returned.
+type NotificationChannelResponse struct {
+	ID     string `json:"id" example:"77777777-7777-7777-7777-777777777777"`
+	Type   string `json:"type" enums:"email,ntfy,gotify,telegram,webhook" example:"ntfy"`
+	Name   string `json:"name" example:"Phone"`
+	Target string `json:"target" example:"https://ntfy.sh/expensor-alerts"`
+	// The token itself is never returned.
+	HasToken  bool      `json:"has_token" example:"false"`
+	Enabled   bool      `json:"enabled" example:"true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MFASettingsResponse describes the instance-wide multi-factor policy.
+type MFASettingsResponse struct {
+	Required  bool      `json:"required" example:"true"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MFASettingsPatchRequest struct {
+	Required *bool `json:"required" example:"true"`
+}
+
+type backupRestoreQuery struct {
+	DryRun bool `form:"dry_run"`
+}
+
+// BackupRestoreResponse reports a restore or restore dry run.
+type BackupRestoreResponse struct {
+	Backup               string    `json:"backup" example:"expensor-backup-20260301T020000Z.tar.gz"`
+	DryRun               bool      `json:"dry_run" example:"true"`
+	CreatedAt            time.Time `json:"created_at" example:"2026-03-01T02:00:00Z"`
+	AppVersion           string    `json:"app_version" example:"1.4.0"`
+	SchemaVersion        int64     `json:"schema_version" example:"19"`
+	CurrentSchemaVersion int64     `json:"current_schema_version" example:"19"`
+	Tables               int       `json:"tables" example:"42"`
+	Rows                 int64     `json:"rows" example:"18311"`
+	Problems             []string  `json:"problems"`
+	Warnings             []string  `json:"warnings"`
+	SafetyBackup         string    `json:"safety_backup,omitempty" example:"expensor-backup-20260302T091500Z.tar.gz"`
+	Restored             bool      `json:"restored" example:"false"`
+}
@@ -4,13 +4,24 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/ArionMiles/expensor/backend/internal/auth"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
+// noScope marks routes that any authenticated request may use, including
+// public routes and those that only work with a browser session.
+const noScope auth.Scope = ""
+
 // registerRoutes attaches all API routes to mux.
 func registerRoutes(mux *http.ServeMux, h *Handlers) {
 	registerBootstrapRoutes(mux, h)
 	registerScanningRoutes(mux, h)
+	registerBackupRoutes(mux, h)
+	registerEncryptionRoutes(mux, h)
+	registerAuditRoutes(mux, h)
+	registerWebhookRoutes(mux, h)
+	registerNotificationRoutes(mux, h)
+	registerEventRoutes(mux, h)
 	registerLLMProviderRoutes(mux, h)
 	registerReaderRoutes(mux, h)
 	registerStatsRoutes(mux, h)
@@ -18,152 +29,292 @@ func registerRoutes(mux *http.ServeMux, h *Handlers) {
 	registerTaxonomyRoutes(mux, h)
 	registerRuleRoutes(mux, h)
 	registerTransactionRoutes(mux, h)
+	registerSharedLedgerRoutes(mux, h)
+	registerTenantRoutes(mux, h)
 	registerDiagnosticRoutes(mux, h)
 	registerMerchantRoutes(mux, h)
 }
 
+// handle registers handler for pattern. Bearer tokens must hold scope to use
+// the route; sessions are not limited by scopes. Routes under a scope that
+// writes tenant data are closed to viewers of the active tenant.
+func handle(mux *http.ServeMux, pattern string, scope auth.Scope, handler http.HandlerFunc) {
+	if scope == noScope {
+		mux.HandleFunc(pattern, handler)
+		return
+	}
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+			if !principal.HasScope(scope) {
+				writeError(w, r, errors.E(errors.PermissionDenied, errors.User("access token lacks the "+string(scope)+" scope")))
+				return
+			}
+			if scope.WritesTenant() && !principal.CanWriteTenant() {
+				writeError(w, r, errors.E(errors.PermissionDenied, errors.User("viewer role is read-only")))
+				return
+			}
+		}
+		handler(w, r)
+	})
+}
+
 func registerBootstrapRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/health", h.Health)
-	mux.HandleFunc("GET /api/status", h.Status)
-	mux.HandleFunc("GET /api/version", h.Version)
-	mux.HandleFunc("GET /api/bootstrap", h.GetBootstrap)
-	mux.HandleFunc("POST /api/bootstrap", h.Bootstrap)
-	mux.HandleFunc("POST /api/session", h.Login)
-	mux.HandleFunc("GET /api/account-setup", h.GetAccountSetup)
-	mux.HandleFunc("POST /api/account-setup", h.CompleteAccountSetup)
-	mux.HandleFunc("GET /api/session", h.GetSession)
-	mux.HandleFunc("DELETE /api/session", h.Logout)
-	mux.HandleFunc("GET /api/profile", h.GetProfile)
-	mux.HandleFunc("PATCH /api/profile", h.UpdateProfile)
-	mux.HandleFunc("PATCH /api/profile/password", h.UpdatePassword)
-	mux.HandleFunc("GET /api/tokens", h.ListAccessTokens)
-	mux.HandleFunc("POST /api/tokens", h.CreateAccessToken)
-	mux.HandleFunc("DELETE /api/tokens/{id}", h.RevokeAccessToken)
-	mux.HandleFunc("GET /api/admin/users", h.ListUsers)
-	mux.HandleFunc("POST /api/admin/users", h.CreateUser)
-	mux.HandleFunc("PATCH /api/admin/users/{id}", h.UpdateUser)
-	mux.HandleFunc("DELETE /api/admin/users/{id}", h.DeleteUser)
-	mux.HandleFunc("POST /api/admin/users/{id}/setup-tokens", h.CreateSetupToken)
+	handle(mux, "GET /api/health", noScope, h.Health)
+	handle(mux, "GET /api/status", auth.ScopeStatsRead, h.Status)
+	handle(mux, "GET /api/version", noScope, h.Version)
+	handle(mux, "GET /api/bootstrap", noScope, h.GetBootstrap)
+	handle(mux, "POST /api/bootstrap", noScope, h.Bootstrap)
+	handle(mux, "POST /api/session", noScope, h.Login)
+	handle(mux, "GET /api/auth/oidc", noScope, h.GetOIDCStatus)
+	handle(mux, "GET /api/auth/oidc/login", noScope, h.OIDCLogin)
+	handle(mux, "GET /api/auth/oidc/callback", noScope, h.OIDCCallback)
+	handle(mux, "GET /api/account-setup", noScope, h.GetAccountSetup)
+	handle(mux, "POST /api/account-setup", noScope, h.CompleteAccountSetup)
+	handle(mux, "GET /api/session", noScope, h.GetSession)
+	handle(mux, "DELETE /api/session", noScope, h.Logout)
+	handle(mux, "GET /api/profile", auth.ScopeAccount, h.GetProfile)
+	handle(mux, "PATCH /api/profile", auth.ScopeAccount, h.UpdateProfile)
+	handle(mux, "PATCH /api/profile/password", auth.ScopeAccount, h.UpdatePassword)
+	handle(mux, "GET /api/session/mfa", noScope, h.GetSessionMFA)
+	handle(mux, "POST /api/session/mfa/totp", noScope, h.VerifySessionTOTP)
+	handle(mux, "POST /api/session/mfa/recovery-code", noScope, h.VerifySessionRecoveryCode)
+	handle(mux, "POST /api/session/mfa/webauthn/options", noScope, h.SessionPasskeyOptions)
+	handle(mux, "POST /api/session/mfa/webauthn", noScope, h.VerifySessionPasskey)
+	handle(mux, "GET /api/profile/mfa", auth.ScopeAccount, h.GetProfileMFA)
+	handle(mux, "POST /api/profile/mfa/totp", auth.ScopeAccount, h.StartTOTPEnrollment)
+	handle(mux, "POST /api/profile/mfa/totp/confirm", auth.ScopeAccount, h.ConfirmTOTPEnrollment)
+	handle(mux, "DELETE /api/profile/mfa/totp", auth.ScopeAccount, h.DeleteTOTP)
+	handle(mux, "POST /api/profile/mfa/recovery-codes", auth.ScopeAccount, h.RegenerateRecoveryCodes)
+	handle(mux, "POST /api/profile/mfa/passkeys/options", auth.ScopeAccount, h.PasskeyRegistrationOptions)
+	handle(mux, "POST /api/profile/mfa/passkeys", auth.ScopeAccount, h.CreatePasskey)
+	handle(mux, "DELETE /api/profile/mfa/passkeys/{id}", auth.ScopeAccount, h.DeletePasskey)
+	handle(mux, "GET /api/admin/mfa/settings", auth.ScopeAdmin, h.GetMFASettings)
+	handle(mux, "PATCH /api/admin/mfa/settings", auth.ScopeAdmin, h.PatchMFASettings)
+	handle(mux, "GET /api/sessions", auth.ScopeAccount, h.ListSessions)
+	handle(mux, "DELETE /api/sessions", auth.ScopeAccount, h.RevokeOtherSessions)
+	handle(mux, "DELETE /api/sessions/{id}", auth.ScopeAccount, h.RevokeSession)
+	handle(mux, "GET /api/tokens", auth.ScopeAccount, h.ListAccessTokens)
+	handle(mux, "POST /api/tokens", auth.ScopeAccount, h.CreateAccessToken)
+	handle(mux, "DELETE /api/tokens/{id}", auth.ScopeAccount, h.RevokeAccessToken)
+	handle(mux, "GET /api/admin/users", auth.ScopeAdmin, h.ListUsers)
+	handle(mux, "POST /api/admin/users", auth.ScopeAdmin, h.CreateUser)
+	handle(mux, "PATCH /api/admin/users/{id}", auth.ScopeAdmin, h.UpdateUser)
+	handle(mux, "DELETE /api/admin/users/{id}", auth.ScopeAdmin, h.DeleteUser)
+	handle(mux, "POST /api/admin/users/{id}/setup-tokens", auth.ScopeAdmin, h.CreateSetupToken)
 }
 
 func registerScanningRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/admin/scanning/settings", h.GetAdminScanningSettings)
-	mux.HandleFunc("PATCH /api/admin/scanning/settings", h.PatchAdminScanningSettings)
-	mux.HandleFunc("GET /api/admin/logging/settings", h.GetAdminLoggingSettings)
-	mux.HandleFunc("PATCH /api/admin/logging/settings", h.PatchAdminLoggingSettings)
-	mux.HandleFunc("POST /api/daemon/start", h.StartDaemon)
-	mux.HandleFunc("POST /api/daemon/rescan", h.Rescan)
-	mux.HandleFunc("GET /api/scanning/settings", h.GetScanningSettings)
-	mux.HandleFunc("PATCH /api/scanning/settings", h.PatchScanningSettings)
-	mux.HandleFunc("GET /api/scanning/status", h.GetScanningStatus)
-	mux.HandleFunc("POST /api/scanning/rescans", h.CreateScanningRescan)
+	handle(mux, "GET /api/admin/scanning/settings", auth.ScopeAdmin, h.GetAdminScanningSettings)
+	handle(mux, "PATCH /api/admin/scanning/settings", auth.ScopeAdmin, h.PatchAdminScanningSettings)
+	handle(mux, "GET /api/admin/logging/settings", auth.ScopeAdmin, h.GetAdminLoggingSettings)
+	handle(mux, "PATCH /api/admin/logging/settings", auth.ScopeAdmin, h.PatchAdminLoggingSettings)
+	handle(mux, "GET /api/admin/llm/usage", auth.ScopeAdmin, h.GetAdminLLMUsage)
+	handle(mux, "GET /api/admin/llm/quotas/{tenant_id}", auth.ScopeAdmin, h.GetAdminLLMQuota)
+	handle(mux, "PUT /api/admin/llm/quotas/{tenant_id}", auth.ScopeAdmin, h.PutAdminLLMQuota)
+	handle(mux, "GET /api/admin/llm/prompts", auth.ScopeAdmin, h.ListAdminLLMPrompts)
+	handle(mux, "GET /api/admin/llm/prompts/{workflow}/{purpose}/versions", auth.ScopeAdmin, h.ListAdminLLMPromptVersions)
+	handle(mux, "POST /api/admin/llm/prompts/{workflow}/{purpose}/versions", auth.ScopeAdmin, h.CreateAdminLLMPromptVersion)
+	handle(mux, "PUT /api/admin/llm/prompts/{workflow}/{purpose}/active", auth.ScopeAdmin, h.ActivateAdminLLMPromptVersion)
+	handle(mux, "POST /api/daemon/start", auth.ScopeReadersWrite, h.StartDaemon)
+	handle(mux, "POST /api/daemon/rescan", auth.ScopeReadersWrite, h.Rescan)
+	handle(mux, "GET /api/scanning/settings", auth.ScopeReadersRead, h.GetScanningSettings)
+	handle(mux, "PATCH /api/scanning/settings", auth.ScopeReadersWrite, h.PatchScanningSettings)
+	handle(mux, "GET /api/scanning/status", auth.ScopeReadersRead, h.GetScanningStatus)
+	handle(mux, "POST /api/scanning/rescans", auth.ScopeReadersWrite, h.CreateScanningRescan)
+}
+
+func registerBackupRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "GET /api/admin/backups", auth.ScopeAdmin, h.ListBackups)
+	handle(mux, "POST /api/admin/backups", auth.ScopeAdmin, h.CreateBackup)
+	handle(mux, "GET /api/admin/backups/settings", auth.ScopeAdmin, h.GetBackupSettings)
+	handle(mux, "PATCH /api/admin/backups/settings", auth.ScopeAdmin, h.PatchBackupSettings)
+	handle(mux, "POST /api/admin/backups/{name}/restore", auth.ScopeAdmin, h.RestoreBackup)
+}
+
+func registerEncryptionRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "GET /api/admin/encryption", auth.ScopeAdmin, h.GetEncryptionStatus)
+	handle(mux, "POST /api/admin/encryption/reencrypt", auth.ScopeAdmin, h.StartReencryption)
+}
+
+func registerAuditRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "GET /api/admin/audit/events", auth.ScopeAdmin, h.ListAuditEvents)
+	handle(mux, "GET /api/admin/audit/settings", auth.ScopeAdmin, h.GetAuditSettings)
+	handle(mux, "PATCH /api/admin/audit/settings", auth.ScopeAdmin, h.PatchAuditSettings)
+}
+
+func registerWebhookRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "GET /api/webhooks", auth.ScopeSettingsRead, h.ListWebhooks)
+	handle(mux, "POST /api/webhooks", auth.ScopeSettingsWrite, h.CreateWebhook)
+	handle(mux, "GET /api/webhooks/{id}", auth.ScopeSettingsRead, h.GetWebhook)
+	handle(mux, "PATCH /api/webhooks/{id}", auth.ScopeSettingsWrite, h.UpdateWebhook)
+	handle(mux, "DELETE /api/webhooks/{id}", auth.ScopeSettingsWrite, h.DeleteWebhook)
+	handle(mux, "GET /api/webhooks/{id}/deliveries", auth.ScopeSettingsRead, h.ListWebhookDeliveries)
+}
+
+func registerNotificationRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "GET /api/notifications/preferences", auth.ScopeAccount, h.GetNotificationPreferences)
+	handle(mux, "PATCH /api/notifications/preferences", auth.ScopeAccount, h.UpdateNotificationPreferences)
+	handle(mux, "GET /api/notifications/channels", auth.ScopeAccount, h.ListNotificationChannels)
+	handle(mux, "POST /api/notifications/channels", auth.ScopeAccount, h.CreateNotificationChannel)
+	handle(mux, "PATCH /api/notifications/channels/{id}", auth.ScopeAccount, h.UpdateNotificationChannel)
+	handle(mux, "DELETE /api/notifications/channels/{id}", auth.ScopeAccount, h.DeleteNotificationChannel)
+	handle(mux, "POST /api/notifications/channels/{id}/test", auth.ScopeAccount, h.TestNotificationChannel)
+}
+
+func registerEventRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "GET /api/events", auth.ScopeTransactionsRead, h.StreamEvents)
 }
 
 func registerLLMProviderRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/llm/providers", h.ListLLMProviders)
-	mux.HandleFunc("GET /api/llm/providers/{name}/status", h.GetLLMProviderStatus)
-	mux.HandleFunc("PUT /api/llm/providers/{name}/config", h.SaveLLMProviderConfig)
-	mux.HandleFunc("PUT /api/llm/providers/{name}/credentials", h.SaveLLMProviderCredentials)
-	mux.HandleFunc("POST /api/llm/providers/{name}/healthcheck", h.HealthCheckLLMProvider)
-	mux.HandleFunc("POST /api/llm/providers/{name}/activate", h.ActivateLLMProvider)
-	mux.HandleFunc("DELETE /api/llm/providers/{name}", h.DisconnectLLMProvider)
+	handle(mux, "GET /api/llm/providers", auth.ScopeSettingsRead, h.ListLLMProviders)
+	handle(mux, "GET /api/llm/providers/{name}/status", auth.ScopeSettingsRead, h.GetLLMProviderStatus)
+	handle(mux, "PUT /api/llm/providers/{name}/config", auth.ScopeSettingsWrite, h.SaveLLMProviderConfig)
+	handle(mux, "PUT /api/llm/providers/{name}/credentials", auth.ScopeSettingsWrite, h.SaveLLMProviderCredentials)
+	handle(mux, "POST /api/llm/providers/{name}/healthcheck", auth.ScopeSettingsRead, h.HealthCheckLLMProvider)
+	handle(mux, "GET /api/llm/providers/{name}/models", auth.ScopeSettingsRead, h.ListLLMProviderModels)
+	handle(mux, "POST /api/llm/providers/{name}/activate", auth.ScopeSettingsWrite, h.ActivateLLMProvider)
+	handle(mux, "DELETE /api/llm/providers/{name}", auth.ScopeSettingsWrite, h.DisconnectLLMProvider)
 }
 
 func registerReaderRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/providers", h.ListProviders)
-	mux.HandleFunc("GET /api/providers/thunderbird/discover/profiles", h.DiscoverProfiles)
-	mux.HandleFunc("GET /api/providers/thunderbird/discover/mailboxes", h.DiscoverMailboxes)
-	mux.HandleFunc("GET /api/providers/{name}/guide", h.GetProviderGuide)
-	mux.HandleFunc("POST /api/providers/{name}/credentials", h.UploadCredentials)
-	mux.HandleFunc("GET /api/providers/{name}/credentials/status", h.CredentialsStatus)
-	mux.HandleFunc("POST /api/providers/{name}/auth/start", h.AuthStart)
-	mux.HandleFunc("GET /api/auth/callback", h.AuthCallback)
-	mux.HandleFunc("POST /api/providers/{name}/auth/exchange", h.AuthExchange)
-	mux.HandleFunc("GET /api/providers/{name}/auth/status", h.AuthStatus)
-	mux.HandleFunc("DELETE /api/providers/{name}/auth/token", h.RevokeToken)
-	mux.HandleFunc("GET /api/providers/{name}/config", h.GetReaderConfig)
-	mux.HandleFunc("PUT /api/providers/{name}/config", h.SaveReaderConfig)
-	mux.HandleFunc("GET /api/providers/{name}/status", h.ReaderStatus)
-	mux.HandleFunc("GET /api/providers/{name}/messages", h.SearchProviderMessages)
-	mux.HandleFunc("DELETE /api/providers/{name}", h.DisconnectReader)
+	handle(mux, "GET /api/providers", auth.ScopeReadersRead, h.ListProviders)
+	handle(mux, "GET /api/providers/thunderbird/discover/profiles", auth.ScopeReadersRead, h.DiscoverProfiles)
+	handle(mux, "GET /api/providers/thunderbird/discover/mailboxes", auth.ScopeReadersRead, h.DiscoverMailboxes)
+	handle(mux, "GET /api/providers/{name}/guide", auth.ScopeReadersRead, h.GetProviderGuide)
+	handle(mux, "POST /api/providers/{name}/credentials", auth.ScopeReadersWrite, h.UploadCredentials)
+	handle(mux, "GET /api/providers/{name}/credentials/status", auth.ScopeReadersRead, h.CredentialsStatus)
+	handle(mux, "POST /api/providers/{name}/auth/start", auth.ScopeReadersWrite, h.AuthStart)
+	handle(mux, "GET /api/auth/callback", noScope, h.AuthCallback)
+	handle(mux, "POST /api/providers/{name}/auth/exchange", auth.ScopeReadersWrite, h.AuthExchange)
+	handle(mux, "GET /api/providers/{name}/auth/status", auth.ScopeReadersRead, h.AuthStatus)
+	handle(mux, "DELETE /api/providers/{name}/auth/token", auth.ScopeReadersWrite, h.RevokeToken)
+	handle(mux, "GET /api/providers/{name}/config", auth.ScopeReadersRead, h.GetReaderConfig)
+	handle(mux, "PUT /api/providers/{name}/config", auth.ScopeReadersWrite, h.SaveReaderConfig)
+	handle(mux, "GET /api/providers/{name}/status", auth.ScopeReadersRead, h.ReaderStatus)
+	handle(mux, "GET /api/providers/{name}/messages", auth.ScopeReadersRead, h.SearchProviderMessages)
+	handle(mux, "DELETE /api/providers/{name}", auth.ScopeReadersWrite, h.DisconnectReader)
 }
 
 func registerStatsRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/stats/dashboard", h.GetDashboardData)
-	mux.HandleFunc("GET /api/stats/charts", h.GetChartData)
-	mux.HandleFunc("GET /api/stats/labels/monthly", h.GetLabelMonthlySpend)
-	mux.HandleFunc("GET /api/stats/heatmap", h.GetHeatmap)
+	handle(mux, "GET /api/stats/dashboard", auth.ScopeStatsRead, h.GetDashboardData)
+	handle(mux, "GET /api/stats/charts", auth.ScopeStatsRead, h.GetChartData)
+	handle(mux, "GET /api/stats/labels/monthly", auth.ScopeStatsRead, h.GetLabelMonthlySpend)
+	handle(mux, "GET /api/stats/heatmap", auth.ScopeStatsRead, h.GetHeatmap)
 }
 
 func registerConfigurationRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/config/banks", h.ListBanks)
-	mux.HandleFunc("GET /api/config/setup-status", h.GetSetupStatus)
-	mux.HandleFunc("POST /api/config/sync", h.TriggerSync)
-	mux.HandleFunc("GET /api/config/sync/status", h.GetSyncStatus)
-	mux.HandleFunc("GET /api/config/sync/settings", h.GetCommunitySyncSettings)
-	mux.HandleFunc("PATCH /api/config/sync/settings", h.PatchCommunitySyncSettings)
-	mux.HandleFunc("GET /api/config/preferences", h.GetPreferences)
-	mux.HandleFunc("PATCH /api/config/preferences", h.PatchPreferences)
-	mux.HandleFunc("GET /api/config/providers/{name}/checkpoint", h.GetReaderCheckpoint)
-	mux.HandleFunc("DELETE /api/config/providers/{name}/checkpoint", h.ClearReaderCheckpoint)
+	handle(mux, "GET /api/config/banks", auth.ScopeSettingsRead, h.ListBanks)
+	handle(mux, "GET /api/config/setup-status", auth.ScopeSettingsRead, h.GetSetupStatus)
+	handle(mux, "POST /api/config/sync", auth.ScopeSettingsWrite, h.TriggerSync)
+	handle(mux, "GET /api/config/sync/status", auth.ScopeSettingsRead, h.GetSyncStatus)
+	handle(mux, "GET /api/config/sync/settings", auth.ScopeSettingsRead, h.GetCommunitySyncSettings)
+	handle(mux, "PATCH /api/config/sync/settings", auth.ScopeSettingsWrite, h.PatchCommunitySyncSettings)
+	handle(mux, "GET /api/config/preferences", auth.ScopeSettingsRead, h.GetPreferences)
+	handle(mux, "PATCH /api/config/preferences", auth.ScopeSettingsWrite, h.PatchPreferences)
+	handle(mux, "GET /api/config/export-accounts", auth.ScopeSettingsRead, h.GetExportAccounts)
+	handle(mux, "PUT /api/config/export-accounts", auth.ScopeSettingsWrite, h.PutExportAccounts)
+	handle(mux, "GET /api/config/providers/{name}/checkpoint", auth.ScopeReadersRead, h.GetReaderCheckpoint)
+	handle(mux, "DELETE /api/config/providers/{name}/checkpoint", auth.ScopeReadersWrite, h.ClearReaderCheckpoint)
 }
 
 func registerTaxonomyRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/config/labels/export", h.ExportLabels)
-	mux.HandleFunc("GET /api/config/labels/mappings", h.GetLabelMappings)
-	mux.HandleFunc("GET /api/config/labels", h.ListLabels)
-	mux.HandleFunc("POST /api/config/labels", h.CreateLabel)
-	mux.HandleFunc("PUT /api/config/labels/{name}", h.UpdateLabel)
-	mux.HandleFunc("DELETE /api/config/labels/{name}", h.DeleteLabel)
-	mux.HandleFunc("PUT /api/config/labels/{name}/merchant-mappings/{pattern}", h.ApplyLabel)
-	mux.HandleFunc("DELETE /api/config/labels/{name}/merchant-mappings/{pattern}", h.RemoveLabelByMerchant)
-	mux.HandleFunc("GET /api/config/categories/export", h.ExportCategories)
-	mux.HandleFunc("GET /api/config/categories/mappings", h.GetCategoryMappings)
-	mux.HandleFunc("GET /api/config/categories", h.ListCategories)
-	mux.HandleFunc("POST /api/config/categories", h.CreateCategory)
-	mux.HandleFunc("DELETE /api/config/categories/{name}", h.DeleteCategory)
-	mux.HandleFunc("PUT /api/config/categories/{name}/merchant-mappings/{pattern}", h.ApplyCategoryByMerchant)
-	mux.HandleFunc("DELETE /api/config/categories/{name}/merchant-mappings/{pattern}", h.RemoveCategoryByMerchant)
-	mux.HandleFunc("GET /api/config/buckets/export", h.ExportBuckets)
-	mux.HandleFunc("GET /api/config/buckets/mappings", h.GetBucketMappings)
-	mux.HandleFunc("GET /api/config/buckets", h.ListBuckets)
-	mux.HandleFunc("POST /api/config/buckets", h.CreateBucket)
-	mux.HandleFunc("DELETE /api/config/buckets/{name}", h.DeleteBucket)
-	mux.HandleFunc("PUT /api/config/buckets/{name}/merchant-mappings/{pattern}", h.ApplyBucketByMerchant)
-	mux.HandleFunc("DELETE /api/config/buckets/{name}/merchant-mappings/{pattern}", h.RemoveBucketByMerchant)
+	handle(mux, "GET /api/config/labels/export", auth.ScopeRulesRead, h.ExportLabels)
+	handle(mux, "GET /api/config/labels/mappings", auth.ScopeRulesRead, h.GetLabelMappings)
+	handle(mux, "GET /api/config/labels", auth.ScopeRulesRead, h.ListLabels)
+	handle(mux, "POST /api/config/labels", auth.ScopeRulesWrite, h.CreateLabel)
+	handle(mux, "PUT /api/config/labels/{name}", auth.ScopeRulesWrite, h.UpdateLabel)
+	handle(mux, "DELETE /api/config/labels/{name}", auth.ScopeRulesWrite, h.DeleteLabel)
+	handle(mux, "PUT /api/config/labels/{name}/merchant-mappings/{pattern}", auth.ScopeRulesWrite, h.ApplyLabel)
+	handle(mux, "DELETE /api/config/labels/{name}/merchant-mappings/{pattern}", auth.ScopeRulesWrite, h.RemoveLabelByMerchant)
+	handle(mux, "GET /api/config/categories/export", auth.ScopeRulesRead, h.ExportCategories)
+	handle(mux, "GET /api/config/categories/mappings", auth.ScopeRulesRead, h.GetCategoryMappings)
+	handle(mux, "GET /api/config/categories", auth.ScopeRulesRead, h.ListCategories)
+	handle(mux, "POST /api/config/categories", auth.ScopeRulesWrite, h.CreateCategory)
+	handle(mux, "DELETE /api/config/categories/{name}", auth.ScopeRulesWrite, h.DeleteCategory)
+	handle(mux, "PUT /api/config/categories/{name}/merchant-mappings/{pattern}", auth.ScopeRulesWrite, h.ApplyCategoryByMerchant)
+	handle(mux, "DELETE /api/config/categories/{name}/merchant-mappings/{pattern}", auth.ScopeRulesWrite, h.RemoveCategoryByMerchant)
+	handle(mux, "GET /api/config/buckets/export", auth.ScopeRulesRead, h.ExportBuckets)
+	handle(mux, "GET /api/config/buckets/mappings", auth.ScopeRulesRead, h.GetBucketMappings)
+	handle(mux, "GET /api/config/buckets", auth.ScopeRulesRead, h.ListBuckets)
+	handle(mux, "POST /api/config/buckets", auth.ScopeRulesWrite, h.CreateBucket)
+	handle(mux, "DELETE /api/config/buckets/{name}", auth.ScopeRulesWrite, h.DeleteBucket)
+	handle(mux, "PUT /api/config/buckets/{name}/merchant-mappings/{pattern}", auth.ScopeRulesWrite, h.ApplyBucketByMerchant)
+	handle(mux, "DELETE /api/config/buckets/{name}/merchant-mappings/{pattern}", auth.ScopeRulesWrite, h.RemoveBucketByMerchant)
 }
 
 func registerRuleRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/rules", h.ListRules)
-	mux.HandleFunc("GET /api/rules/export", h.ExportRules)
-	mux.HandleFunc("POST /api/rules/import", h.ImportRules)
-	mux.HandleFunc("POST /api/rule-drafts", h.CreateRuleDraft)
-	mux.HandleFunc("POST /api/rules", h.CreateRule)
-	mux.HandleFunc("PUT /api/rules/{id}", h.UpdateRule)
-	mux.HandleFunc("DELETE /api/rules/{id}", h.DeleteRule)
+	handle(mux, "GET /api/rules", auth.ScopeRulesRead, h.ListRules)
+	handle(mux, "GET /api/rules/export", auth.ScopeRulesRead, h.ExportRules)
+	handle(mux, "POST /api/rules/import", auth.ScopeRulesWrite, h.ImportRules)
+	handle(mux, "POST /api/rule-drafts", auth.ScopeRulesWrite, h.CreateRuleDraft)
+	handle(mux, "POST /api/rules", auth.ScopeRulesWrite, h.CreateRule)
+	handle(mux, "PUT /api/rules/{id}", auth.ScopeRulesWrite, h.UpdateRule)
+	handle(mux, "DELETE /api/rules/{id}", auth.ScopeRulesWrite, h.DeleteRule)
 }
 
 func registerTransactionRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/transactions/facets", h.GetFacets)
-	mux.HandleFunc("GET /api/transactions", h.ListTransactions)
-	mux.HandleFunc("GET /api/transactions/{id}", h.GetTransaction)
-	mux.HandleFunc("PATCH /api/transactions/{id}", h.UpdateTransaction)
-	mux.HandleFunc("POST /api/transactions/{id}/labels", h.AddLabels)
-	mux.HandleFunc("DELETE /api/transactions/{id}/labels/{label}", h.RemoveLabel)
+	handle(mux, "GET /api/transactions/facets", auth.ScopeTransactionsRead, h.GetFacets)
+	handle(mux, "POST /api/transaction-queries", auth.ScopeTransactionsRead, h.CreateTransactionQuery)
+	handle(mux, "GET /api/transactions", auth.ScopeTransactionsRead, h.ListTransactions)
+	handle(mux, "POST /api/transactions", auth.ScopeTransactionsWrite, h.CreateTransaction)
+	handle(mux, "POST /api/transactions/bulk", auth.ScopeTransactionsWrite, h.BulkUpdateTransactions)
+	handle(mux, "GET /api/transactions/export", auth.ScopeTransactionsRead, h.ExportTransactions)
+	handle(mux, "GET /api/transactions/{id}", auth.ScopeTransactionsRead, h.GetTransaction)
+	handle(mux, "PATCH /api/transactions/{id}", auth.ScopeTransactionsWrite, h.UpdateTransaction)
+	handle(mux, "DELETE /api/transactions/{id}", auth.ScopeTransactionsWrite, h.DeleteTransaction)
+	handle(mux, "POST /api/transactions/{id}/labels", auth.ScopeTransactionsWrite, h.AddLabels)
+	handle(mux, "DELETE /api/transactions/{id}/labels/{label}", auth.ScopeTransactionsWrite, h.RemoveLabel)
+	handle(mux, "PUT /api/transactions/{id}/splits", auth.ScopeTransactionsWrite, h.SetTransactionSplits)
+	handle(mux, "GET /api/transactions/{id}/history", auth.ScopeTransactionsRead, h.GetTransactionHistory)
+	handle(mux, "GET /api/transactions/{id}/attachments", auth.ScopeTransactionsRead, h.ListAttachments)
+	handle(mux, "POST /api/transactions/{id}/attachments", auth.ScopeTransactionsWrite, h.UploadAttachment)
+	handle(mux, "GET /api/transactions/{id}/attachments/{attachment_id}", auth.ScopeTransactionsRead, h.DownloadAttachment)
+	handle(mux, "DELETE /api/transactions/{id}/attachments/{attachment_id}", auth.ScopeTransactionsWrite, h.DeleteAttachment)
+	handle(mux, "POST /api/transactions/history/{batch_id}/revert", auth.ScopeTransactionsWrite, h.RevertChangeBatch)
+}
+
+func registerSharedLedgerRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "GET /api/shared-ledgers", auth.ScopeTransactionsRead, h.ListSharedLedgers)
+	handle(mux, "POST /api/shared-ledgers", auth.ScopeTransactionsWrite, h.CreateSharedLedger)
+	handle(mux, "GET /api/shared-ledgers/invitations", auth.ScopeTransactionsRead, h.ListSharedLedgerInvitations)
+	handle(mux, "GET /api/shared-ledgers/{id}", auth.ScopeTransactionsRead, h.GetSharedLedger)
+	handle(mux, "POST /api/shared-ledgers/{id}/members", auth.ScopeTransactionsWrite, h.InviteSharedLedgerMember)
+	handle(mux, "POST /api/shared-ledgers/{id}/invitation", auth.ScopeTransactionsWrite, h.AcceptSharedLedgerInvitation)
+	handle(mux, "DELETE /api/shared-ledgers/{id}/invitation", auth.ScopeTransactionsWrite, h.DeclineSharedLedgerInvitation)
+	handle(mux, "GET /api/shared-ledgers/{id}/expenses", auth.ScopeTransactionsRead, h.ListSharedExpenses)
+	handle(mux, "POST /api/shared-ledgers/{id}/expenses", auth.ScopeTransactionsWrite, h.ShareExpense)
+	handle(mux, "DELETE /api/shared-ledgers/{id}/expenses/{expense_id}", auth.ScopeTransactionsWrite, h.DeleteSharedExpense)
+	handle(mux, "GET /api/shared-ledgers/{id}/settlements", auth.ScopeTransactionsRead, h.ListSettlements)
+	handle(mux, "POST /api/shared-ledgers/{id}/settlements", auth.ScopeTransactionsWrite, h.RecordSettlement)
+}
+
+func registerTenantRoutes(mux *http.ServeMux, h *Handlers) {
+	handle(mux, "PUT /api/session/tenant", auth.ScopeAccount, h.SelectSessionTenant)
+	handle(mux, "GET /api/tenants", auth.ScopeAccount, h.ListTenants)
+	handle(mux, "POST /api/tenants", auth.ScopeAccount, h.CreateTenant)
+	handle(mux, "GET /api/tenants/{id}/members", auth.ScopeAccount, h.ListTenantMembers)
+	handle(mux, "GET /api/tenants/invitations", auth.ScopeAccount, h.ListTenantInvitations)
+	handle(mux, "POST /api/tenants/{id}/members", auth.ScopeAccount, h.InviteTenantMember)
+	handle(mux, "PATCH /api/tenants/{id}/members/{user_id}", auth.ScopeAccount, h.UpdateTenantMemberRole)
+	handle(mux, "DELETE /api/tenants/{id}/members/{user_id}", auth.ScopeAccount, h.RemoveTenantMember)
+	handle(mux, "POST /api/tenants/{id}/invitation", auth.ScopeAccount, h.AcceptTenantInvitation)
+	handle(mux, "DELETE /api/tenants/{id}/invitation", auth.ScopeAccount, h.DeclineTenantInvitation)
+	handle(mux, "GET /api/account/export", auth.ScopeAccount, h.ExportAccount)
+	handle(mux, "POST /api/account/import", auth.ScopeAccount, h.ImportAccount)
 }
 
 func registerDiagnosticRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/extraction-diagnostics", h.ListExtractionDiagnostics)
-	mux.HandleFunc("GET /api/extraction-diagnostics/{id}", h.GetExtractionDiagnostic)
-	mux.HandleFunc("PATCH /api/extraction-diagnostics/{id}", h.UpdateExtractionDiagnosticStatus)
+	handle(mux, "GET /api/extraction-diagnostics", auth.ScopeReadersRead, h.ListExtractionDiagnostics)
+	handle(mux, "GET /api/extraction-diagnostics/{id}", auth.ScopeReadersRead, h.GetExtractionDiagnostic)
+	handle(mux, "PATCH /api/extraction-diagnostics/{id}", auth.ScopeReadersWrite, h.UpdateExtractionDiagnosticStatus)
 }
 
 func registerMerchantRoutes(mux *http.ServeMux, h *Handlers) {
-	mux.HandleFunc("GET /api/muted-merchants", h.ListMutedMerchants)
-	mux.HandleFunc("POST /api/muted-merchants", h.MuteByMerchant)
-	mux.HandleFunc("PATCH /api/muted-merchants/{id}", h.UpdateMerchantReason)
-	mux.HandleFunc("DELETE /api/muted-merchants/{id}", h.DeleteMutedMerchant)
-	mux.HandleFunc("POST /api/merchants/categorize", h.CategorizeMerchant)
+	handle(mux, "GET /api/muted-merchants", auth.ScopeTransactionsRead, h.ListMutedMerchants)
+	handle(mux, "POST /api/muted-merchants", auth.ScopeTransactionsWrite, h.MuteByMerchant)
+	handle(mux, "PATCH /api/muted-merchants/{id}", auth.ScopeTransactionsWrite, h.UpdateMerchantReason)
+	handle(mux, "DELETE /api/muted-merchants/{id}", auth.ScopeTransactionsWrite, h.DeleteMutedMerchant)
+	handle(mux, "POST /api/merchants/categorize", auth.ScopeTransactionsWrite, h.CategorizeMerchant)
 }
 
 // apiErrorFallback replaces the default ServeMux 404 and 405 bodies for API
@@ -17,6 +17,7 @@ import (
 // Server wraps the HTTP server and its dependencies.
 type Server struct {
 	httpServer *http.Server
+	handlers   *Handlers
 	logger     *slog.Logger
 }
 
@@ -42,13 +43,16 @@ func NewServer(port int, handlers *Handlers, staticDir string, logger *slog.Logg
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		},
-		logger: logger,
+		handlers: handlers,
+		logger:   logger,
 	}
 }
 
 // Start listens and serves until ctx is canceled.
 func (s *Server) Start(ctx context.Context) error {
 	errCh := make(chan error, 1)
+	go s.handlers.loginThrottle.run(ctx)
+	go s.handlers.mfaThrottle.run(ctx)
 	go func() {
 		s.logger.Info("HTTP server listening", "addr", s.httpServer.Addr)
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -6,17 +6,28 @@ import "github.com/ArionMiles/expensor/backend/internal/store/instrumented"
 // Using an interface allows handler unit tests to inject a mock without a real database.
 type Storer interface {
 	authStore
+	mfaStore
 	settingsStore
 	scanningStore
 	analyticsStore
 	transactionStore
+	sharedLedgerStore
+	attachmentStore
+	archiveStore
+	auditStore
+	backupStore
+	tenantStore
 	muteStore
 	taxonomyStore
 	readerRuntimeStore
 	llmRuntimeStore
+	llmUsageStore
+	llmPromptStore
 	ruleStore
 	syncStore
 	diagnosticStore
+	webhookStore
+	notificationStore
 }
 
 var _ Storer = (*instrumented.Store)(nil)
@@ -3,6 +3,7 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/ArionMiles/expensor/backend/internal/store"
 )
@@ -36,7 +37,13 @@ type transactionStore interface {
 	ListTransactions(ctx context.Context, tenant store.Tenant, f store.ListFilter) ([]store.Transaction, store.TransactionListResult, error)
 	SearchTransactions(ctx context.Context, tenant store.Tenant, query string, f store.ListFilter) ([]store.Transaction, store.TransactionListResult, error)
 	GetTransaction(ctx context.Context, tenant store.Tenant, id string) (*store.Transaction, error)
+	CreateTransaction(ctx context.Context, tenant store.Tenant, input store.CreateTransactionInput) (*store.Transaction, error)
+	DeleteTransaction(ctx context.Context, tenant store.Tenant, id string) error
+	SetTransactionSplits(ctx context.Context, tenant store.Tenant, id string, splits []store.TransactionSplitInput) error
 	UpdateTransaction(ctx context.Context, tenant store.Tenant, id string, u store.TransactionUpdate) error
+	BulkUpdateTransactions(ctx context.Context, tenant store.Tenant, input store.BulkTransactionInput) (store.BulkTransactionResult, error)
+	ListTransactionHistory(ctx context.Context, tenant store.Tenant, id string) ([]store.TransactionChange, error)
+	RevertChangeBatch(ctx context.Context, tenant store.Tenant, batchID string) (store.ChangeBatchRevert, error)
 	AddLabels(ctx context.Context, tenant store.Tenant, transactionID string, labels []string) error
 	RemoveLabel(ctx context.Context, tenant store.Tenant, transactionID, label string) error
 	GetFacets(ctx context.Context, tenant store.Tenant) (*store.Facets, error)
@@ -94,8 +101,82 @@ type syncStore interface {
 	PatchCommunitySyncSettings(ctx context.Context, patch store.CommunitySyncSettingsPatch) (store.CommunitySyncSettings, error)
 }
 
+type llmUsageStore interface {
+	SumLLMUsageTokens(ctx context.Context, tenant store.Tenant, since time.Time) (int64, error)
+	ListLLMUsage(ctx context.Context, filter store.LLMUsageFilter) ([]store.LLMUsageBucket, error)
+	GetLLMUsageQuota(ctx context.Context, tenant store.Tenant) (store.LLMUsageQuota, error)
+	SetLLMUsageQuota(ctx context.Context, tenant store.Tenant, quota store.LLMUsageQuota) (store.LLMUsageQuota, error)
+}
+
+type mfaStore interface {
+	store.MFAStore
+}
+
+type tenantStore interface {
+	store.TenantStore
+}
+
+type sharedLedgerStore interface {
+	store.SharedLedgerStore
+}
+
+type llmPromptStore interface {
+	store.LLMPromptStore
+}
+
+type attachmentStore interface {
+	store.AttachmentStore
+}
+
+type archiveStore interface {
+	store.TenantArchiveStore
+}
+
+type auditStore interface {
+	store.AuditStore
+}
+
+type backupStore interface {
+	GetBackupSettings(ctx context.Context) (store.BackupSettings, error)
+	PatchBackupSettings(ctx context.Context, patch store.BackupSettingsPatch) (store.BackupSettings, error)
+}
+
 type diagnosticStore interface {
 	ListExtractionDiagnostics(ctx context.Context, tenant store.Tenant, filter store.DiagnosticFilter) ([]store.ExtractionDiagnosticRow, error)
 	GetExtractionDiagnostic(ctx context.Context, tenant store.Tenant, id string) (*store.ExtractionDiagnosticRow, error)
 	UpdateExtractionDiagnosticStatus(ctx context.Context, tenant store.Tenant, id, status string) (*store.ExtractionDiagnosticRow, error)
 }
+
+type webhookStore interface {
+	ListWebhooks(ctx context.Context, tenant store.Tenant) ([]store.Webhook, error)
+	GetWebhook(ctx context.Context, tenant store.Tenant, id string) (*store.Webhook, error)
+	CreateWebhook(ctx context.Context, tenant store.Tenant, input store.NewWebhook) (*store.Webhook, error)
+	UpdateWebhook(ctx context.Context, tenant store.Tenant, id string, patch store.WebhookPatch) (*store.Webhook, error)
+	DeleteWebhook(ctx context.Context, tenant store.Tenant, id string) error
+	ListWebhookDeliveries(ctx context.Context, tenant store.Tenant, webhookID string, limit int) ([]store.WebhookDelivery, error)
+}
+
+type notificationStore interface {
+	GetNotificationPreferences(ctx context.Context, tenant store.Tenant, userID string) (*store.NotificationPreferences, error)
+	UpdateNotificationPreferences(
+		ctx context.Context,
+		tenant store.Tenant,
+		userID string,
+		patch store.NotificationPreferencesPatch,
+	) (*store.NotificationPreferences, error)
+	ListNotificationChannels(ctx context.Context, tenant store.Tenant, userID string) ([]store.NotificationChannel, error)
+	OpenNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) (*store.NotificationChannel, string, error)
+	CreateNotificationChannel(
+		ctx context.Context,
+		tenant store.Tenant,
+		userID string,
+		input store.NewNotificationChannel,
+	) (*store.NotificationChannel, error)
+	UpdateNotificationChannel(
+		ctx context.Context,
+		tenant store.Tenant,
+		userID, id string,
+		patch store.NotificationChannelPatch,
+	) (*store.NotificationChannel, error)
+	DeleteNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) error
+}
@@ -11,6 +11,12 @@ var (
 	_ settingsStore      = (*postgres.Store)(nil)
 	_ analyticsStore     = (*postgres.Store)(nil)
 	_ transactionStore   = (*postgres.Store)(nil)
+	_ sharedLedgerStore  = (*postgres.Store)(nil)
+	_ attachmentStore    = (*postgres.Store)(nil)
+	_ archiveStore       = (*postgres.Store)(nil)
+	_ backupStore        = (*postgres.Store)(nil)
+	_ mfaStore           = (*postgres.Store)(nil)
+	_ tenantStore        = (*postgres.Store)(nil)
 	_ muteStore          = (*postgres.Store)(nil)
 	_ taxonomyStore      = (*postgres.Store)(nil)
 	_ readerRuntimeStore = (*postgres.Store)(nil)
@@ -21,6 +27,12 @@ var (
 	_ settingsStore      = (*instrumented.Store)(nil)
 	_ analyticsStore     = (*instrumented.Store)(nil)
 	_ transactionStore   = (*instrumented.Store)(nil)
+	_ sharedLedgerStore  = (*instrumented.Store)(nil)
+	_ attachmentStore    = (*instrumented.Store)(nil)
+	_ archiveStore       = (*instrumented.Store)(nil)
+	_ backupStore        = (*instrumented.Store)(nil)
+	_ mfaStore           = (*instrumented.Store)(nil)
+	_ tenantStore        = (*instrumented.Store)(nil)
 	_ muteStore          = (*instrumented.Store)(nil)
 	_ taxonomyStore      = (*instrumented.Store)(nil)
 	_ readerRuntimeStore = (*instrumented.Store)(nil)
@@ -13,6 +13,7 @@ import (
 
 	"github.com/go-playground/validator/v10"
 
+	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
@@ -32,6 +33,7 @@ func newRequestValidator() *validator.Validate {
 	mustRegisterValidation(validate, "currency_code", isCurrencyCode)
 	mustRegisterValidation(validate, "time_format", isTimeFormat)
 	mustRegisterValidation(validate, "regexp", isRegularExpression)
+	mustRegisterValidation(validate, "webhook_event", isWebhookEvent)
 	validate.RegisterStructValidation(validateTransactionPagination, transactionListQuery{})
 	validate.RegisterStructValidation(validateHeatmapQuery, heatmapQuery{})
 	return validate
@@ -101,6 +103,10 @@ func isRegularExpression(field validator.FieldLevel) bool {
 	return err == nil
 }
 
+func isWebhookEvent(field validator.FieldLevel) bool {
+	return store.ValidWebhookEventType(store.WebhookEventType(field.Field().String()))
+}
+
 func mustRegisterValidation(validate *validator.Validate, tag string, fn validator.Func) {
 	if err := validate.RegisterValidation(tag, fn); err != nil {
 		panic(err)
@@ -176,6 +182,10 @@ func validationMessage(fieldError validator.FieldError) string {
 		return fmt.Sprintf("must be at least %s", fieldError.Param())
 	case "max":
 		return fmt.Sprintf("must be at most %s", fieldError.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldError.Param())
+	case "lte":
+		return fmt.Sprintf("must be at most %s", fieldError.Param())
 	case "len":
 		return fmt.Sprintf("must be exactly %s characters", fieldError.Param())
 	case "hexcolor":
@@ -184,6 +194,8 @@ func validationMessage(fieldError validator.FieldError) string {
 		return "must be a valid URL"
 	case "email":
 		return "must be a valid email address"
+	case "uuid":
+		return "must be a valid UUID"
 	case "regexp":
 		return "must be a valid regular expression"
 	case "no_control_chars":
@@ -0,0 +1,79 @@
+package anthropic
+
+import (
+	"encoding/json"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+)
+
+type messagesRequest struct {
+	Model       string           `json:"model"`
+	System      string           `json:"system,omitempty"`
+	Messages    []messageParam   `json:"messages"`
+	MaxTokens   int              `json:"max_tokens"`
+	Temperature *float64         `json:"temperature,omitempty"`
+	Tools       []toolParam      `json:"tools,omitempty"`
+	ToolChoice  *toolChoiceParam `json:"tool_choice,omitempty"`
+}
+
+type messageParam struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+type contentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type toolParam struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type toolChoiceParam struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type messagesResponse struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      messagesUsage  `json:"usage"`
+	Error      *apiError      `json:"error"`
+}
+
+type messagesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type modelsResponse struct {
+	Data []struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+func (u messagesUsage) toLLMUsage() llm.Usage {
+	return llm.Usage{
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+		TotalTokens:  u.InputTokens + u.OutputTokens,
+	}
+}
@@ -0,0 +1,456 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	ProviderName     = "anthropic"
+	defaultBaseURL   = "https://api.anthropic.com"
+	defaultModel     = "claude-sonnet-4-5"
+	defaultMaxTokens = 1024
+	defaultTimeout   = 60 * time.Second
+	apiVersion       = "2023-06-01"
+
+	// responseToolName is the forced tool used to obtain structured outputs,
+	// since the Messages API expresses JSON schemas as tool input schemas.
+	responseToolName = "expensor_response"
+)
+
+type credentials struct {
+	APIKey string `json:"api_key"`
+}
+
+type providerConfig struct {
+	Model     string `json:"model"`
+	BaseURL   string `json:"base_url"`
+	MaxTokens int    `json:"max_tokens"`
+}
+
+type client struct {
+	apiKey     string
+	model      string
+	baseURL    string
+	maxTokens  int
+	httpClient *http.Client
+}
+
+// Provider returns the Anthropic Messages API-backed LLM provider registration.
+func Provider(modelOptions []llm.ModelOption) llm.Provider {
+	return llm.Provider{
+		Metadata: llm.ProviderMetadata{
+			Name:        ProviderName,
+			DisplayName: "Anthropic",
+			Description: "Connect an Anthropic API key to run LLM workflows on Claude models.",
+			Auth: llm.AuthSpec{
+				Type:     llm.AuthTypeAPIKey,
+				Required: true,
+			},
+			ConfigSchema: json.RawMessage(`{
+				"type":"object",
+				"properties":{
+					"model":{"type":"string","default":"claude-sonnet-4-5"},
+					"base_url":{"type":"string","default":"https://api.anthropic.com"},
+					"max_tokens":{"type":"integer","minimum":1,"default":1024}
+				}
+			}`),
+			Capabilities: []llm.Capability{
+				llm.CapabilityTextGeneration,
+				llm.CapabilityJSONSchema,
+				llm.CapabilityTools,
+			},
+			ModelOptions: append([]llm.ModelOption(nil), modelOptions...),
+		},
+		NewClient: NewClient,
+	}
+}
+
+// NewClient builds an Anthropic API client from encrypted credentials and provider config.
+func NewClient(input llm.ClientConfig) (llm.Client, error) {
+	const op = "llm.anthropic.NewClient"
+
+	var creds credentials
+	if len(input.Credentials) > 0 {
+		if err := json.Unmarshal(input.Credentials, &creds); err != nil {
+			return nil, errors.E(op, errors.InvalidInput, "decoding Anthropic credentials", err)
+		}
+	}
+	if strings.TrimSpace(creds.APIKey) == "" {
+		return nil, errors.E(op, errors.FailedPrecondition, "Anthropic API key is not configured")
+	}
+
+	cfg := providerConfig{Model: defaultModel, BaseURL: defaultBaseURL, MaxTokens: defaultMaxTokens}
+	if len(input.Config) > 0 {
+		if err := json.Unmarshal(input.Config, &cfg); err != nil {
+			return nil, errors.E(op, errors.InvalidInput, "decoding Anthropic config", err)
+		}
+	}
+	cfg.Model = strings.TrimSpace(cfg.Model)
+	if cfg.Model == "" {
+		cfg.Model = defaultModel
+	}
+	cfg.BaseURL = strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = defaultMaxTokens
+	}
+
+	return &client{
+		apiKey:     strings.TrimSpace(creds.APIKey),
+		model:      cfg.Model,
+		baseURL:    cfg.BaseURL,
+		maxTokens:  cfg.MaxTokens,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// Model returns the model requests are sent to.
+func (c *client) Model() string {
+	return c.model
+}
+
+func (c *client) HealthCheck(ctx context.Context) error {
+	const op = "llm.anthropic.HealthCheck"
+
+	schema := json.RawMessage(`{
+		"type":"object",
+		"additionalProperties":false,
+		"required":["ok"],
+		"properties":{"ok":{"type":"boolean"}}
+	}`)
+	resp, err := c.Complete(ctx, llm.Request{
+		Workflow:             "provider_setup",
+		Purpose:              "healthcheck",
+		RequiredCapabilities: []llm.Capability{llm.CapabilityTextGeneration, llm.CapabilityJSONSchema},
+		MaxOutputTokens:      64,
+		ResponseFormat: llm.ResponseFormat{
+			Type:   llm.ResponseFormatJSONSchema,
+			Name:   "anthropic_healthcheck",
+			Strict: true,
+			Schema: schema,
+		},
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "Return a JSON object confirming the connection health."},
+			{Role: llm.RoleUser, Content: `Return {"ok":true}.`},
+		},
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
+	var out struct {
+		OK bool `json:"ok"`
+	}
+	if err := json.Unmarshal([]byte(resp.Text), &out); err != nil || !out.OK {
+		return errors.E(op, errors.BadGateway, "Anthropic healthcheck returned an invalid structured response")
+	}
+	return nil
+}
+
+// ListModels returns the models available to the configured API key.
+func (c *client) ListModels(ctx context.Context) ([]llm.ModelOption, error) {
+	const op = "llm.anthropic.ListModels"
+
+	var models []llm.ModelOption
+	afterID := ""
+	for {
+		query := url.Values{"limit": {"100"}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+		body, err := c.do(ctx, http.MethodGet, "/v1/models?"+query.Encode(), nil)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		var page modelsResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, errors.E(op, errors.BadGateway, "decoding Anthropic models response", err)
+		}
+		for _, model := range page.Data {
+			displayName := strings.TrimSpace(model.DisplayName)
+			if displayName == "" {
+				displayName = model.ID
+			}
+			models = append(models, llm.ModelOption{ID: model.ID, DisplayName: displayName})
+		}
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+func (c *client) Complete(ctx context.Context, req llm.Request) (llm.Response, error) {
+	const op = "llm.anthropic.Complete"
+
+	payload, err := c.messagesPayload(req)
+	if err != nil {
+		return llm.Response{}, errors.E(op, err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return llm.Response{}, errors.E(op, errors.Internal, "building Anthropic request", err)
+	}
+	respBody, err := c.do(ctx, http.MethodPost, "/v1/messages", body)
+	if err != nil {
+		return llm.Response{}, errors.E(op, err)
+	}
+
+	var resp messagesResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "decoding Anthropic response", err)
+	}
+	if resp.Error != nil {
+		return llm.Response{}, errors.E(op, anthropicProviderFailure(http.StatusOK, resp.Error.Type))
+	}
+	return responseFromMessages(resp, structuredOutput(req.ResponseFormat))
+}
+
+func (c *client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, errors.E(errors.Internal, "building Anthropic request", err)
+	}
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", apiVersion)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, errors.E(errors.Unavailable, "calling Anthropic", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, 4<<20))
+	if err != nil {
+		return nil, errors.E(errors.BadGateway, "reading Anthropic response", err)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, anthropicProviderError(httpResp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
+
+func (c *client) messagesPayload(req llm.Request) (messagesRequest, error) {
+	const op = "llm.anthropic.messagesPayload"
+
+	payload := messagesRequest{
+		Model:       c.model,
+		MaxTokens:   c.maxTokens,
+		Temperature: req.Temperature,
+	}
+	if req.MaxOutputTokens > 0 {
+		payload.MaxTokens = req.MaxOutputTokens
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case llm.RoleSystem:
+			if content := strings.TrimSpace(msg.Content); content != "" {
+				system = append(system, content)
+			}
+		case llm.RoleUser:
+			if strings.TrimSpace(msg.Content) == "" {
+				continue
+			}
+			payload.Messages = appendMessage(payload.Messages, "user", contentBlock{Type: "text", Text: msg.Content})
+		case llm.RoleAssistant:
+			var blocks []contentBlock
+			if strings.TrimSpace(msg.Content) != "" {
+				blocks = append(blocks, contentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, contentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: toolInput(call.Arguments)})
+			}
+			if len(blocks) == 0 {
+				continue
+			}
+			payload.Messages = appendMessage(payload.Messages, "assistant", blocks...)
+		case llm.RoleTool:
+			if strings.TrimSpace(msg.ToolCallID) == "" {
+				return messagesRequest{}, errors.E(op, errors.InvalidInput, "Anthropic tool result requires a tool call id")
+			}
+			payload.Messages = appendMessage(payload.Messages, "user", contentBlock{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			})
+		default:
+			return messagesRequest{}, errors.E(op, errors.InvalidInput, fmt.Sprintf("unsupported Anthropic message role %q", msg.Role))
+		}
+	}
+	payload.System = strings.Join(system, "\n\n")
+	if len(payload.Messages) == 0 {
+		return messagesRequest{}, errors.E(op, errors.InvalidInput, "Anthropic request requires at least one non-empty message")
+	}
+
+	for _, tool := range req.Tools {
+		payload.Tools = append(payload.Tools, toolParam{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: toolSchema(tool.Parameters),
+		})
+	}
+	if structuredOutput(req.ResponseFormat) {
+		if len(req.Tools) > 0 {
+			return messagesRequest{}, errors.E(op, errors.InvalidInput, "Anthropic requests cannot combine tools with a structured response format")
+		}
+		tool, err := responseFormatTool(req.ResponseFormat)
+		if err != nil {
+			return messagesRequest{}, errors.E(op, err)
+		}
+		payload.Tools = []toolParam{tool}
+		payload.ToolChoice = &toolChoiceParam{Type: "tool", Name: responseToolName}
+	}
+	return payload, nil
+}
+
+// appendMessage merges consecutive same-role blocks because the Messages API
+// requires user and assistant turns to alternate.
+func appendMessage(messages []messageParam, role string, blocks ...contentBlock) []messageParam {
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		messages[n-1].Content = append(messages[n-1].Content, blocks...)
+		return messages
+	}
+	return append(messages, messageParam{Role: role, Content: blocks})
+}
+
+func structuredOutput(format llm.ResponseFormat) bool {
+	return format.Type != "" && format.Type != llm.ResponseFormatText
+}
+
+func responseFormatTool(format llm.ResponseFormat) (toolParam, error) {
+	const op = "llm.anthropic.responseFormatTool"
+
+	switch format.Type {
+	case llm.ResponseFormatJSONSchema:
+		if len(format.Schema) == 0 || !json.Valid(format.Schema) {
+			return toolParam{}, errors.E(op, errors.InvalidInput, "json_schema response format requires a valid schema")
+		}
+		return toolParam{
+			Name:        responseToolName,
+			Description: "Return the final response matching the " + safeName(format.Name) + " schema.",
+			InputSchema: format.Schema,
+		}, nil
+	case llm.ResponseFormatJSONObject:
+		return toolParam{
+			Name:        responseToolName,
+			Description: "Return the final response as a JSON object.",
+			InputSchema: json.RawMessage(`{"type":"object"}`),
+		}, nil
+	default:
+		return toolParam{}, errors.E(op, errors.InvalidInput, fmt.Sprintf("unsupported Anthropic response format %q", format.Type))
+	}
+}
+
+func responseFromMessages(resp messagesResponse, structured bool) (llm.Response, error) {
+	const op = "llm.anthropic.responseFromMessages"
+
+	var text []string
+	var calls []llm.ToolCall
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			if strings.TrimSpace(block.Text) != "" {
+				text = append(text, block.Text)
+			}
+		case "tool_use":
+			if structured && block.Name == responseToolName {
+				return llm.Response{
+					Text:         string(block.Input),
+					Messages:     []llm.Message{{Role: llm.RoleAssistant, Content: string(block.Input)}},
+					Usage:        resp.Usage.toLLMUsage(),
+					FinishReason: resp.StopReason,
+				}, nil
+			}
+			calls = append(calls, llm.ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+	if structured {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "Anthropic response did not include structured output")
+	}
+	joined := strings.TrimSpace(strings.Join(text, "\n"))
+	if joined == "" && len(calls) == 0 {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "Anthropic response did not include output text")
+	}
+	return llm.Response{
+		Text:         joined,
+		Messages:     []llm.Message{{Role: llm.RoleAssistant, Content: joined, ToolCalls: calls}},
+		ToolCalls:    calls,
+		Usage:        resp.Usage.toLLMUsage(),
+		FinishReason: resp.StopReason,
+	}, nil
+}
+
+func toolSchema(parameters json.RawMessage) json.RawMessage {
+	if len(parameters) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return parameters
+}
+
+func toolInput(arguments json.RawMessage) json.RawMessage {
+	if len(arguments) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	return arguments
+}
+
+func safeName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "expensor_response"
+	}
+	return name
+}
+
+func anthropicProviderError(status int, body []byte) error {
+	var parsed struct {
+		Error *apiError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error != nil {
+		return anthropicProviderFailure(status, parsed.Error.Type)
+	}
+	return anthropicProviderFailure(status, "")
+}
+
+func anthropicProviderFailure(status int, typ string) error {
+	switch strings.TrimSpace(typ) {
+	case "authentication_error", "permission_error":
+		return errors.E(errors.Unauthenticated, errors.User("Anthropic API key was rejected. Check the key and try again."))
+	case "rate_limit_error":
+		return errors.E(errors.ResourceExhausted, errors.User("Anthropic rate limit exceeded. Wait a moment and try again."))
+	case "overloaded_error":
+		return errors.E(errors.Unavailable, errors.User("Anthropic is temporarily overloaded. Try again shortly."))
+	}
+
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.E(errors.Unauthenticated, errors.User("Anthropic API key was rejected. Check the key and try again."))
+	case http.StatusTooManyRequests:
+		return errors.E(errors.ResourceExhausted, errors.User("LLM provider request was rate limited. Wait a moment and try again."))
+	default:
+		return errors.E(errors.BadGateway, errors.User("LLM provider request failed."))
+	}
+}
+
+var _ llm.ModelLister = (*client)(nil)
@@ -0,0 +1,194 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestNewClientRequiresAPIKeyAndAppliesDefaults(t *testing.T) {
+	_, err := NewClient(llm.ClientConfig{})
+	if err == nil {
+		t.Fatal("NewClient() error = nil, want missing API key error")
+	}
+
+	got, err := NewClient(llm.ClientConfig{Credentials: []byte(`{"api_key":" sk-ant-test "}`)})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client, ok := got.(*client)
+	if !ok {
+		t.Fatalf("client type = %T, want *client", got)
+	}
+	if client.apiKey != "sk-ant-test" || client.model != defaultModel || client.baseURL != defaultBaseURL || client.maxTokens != defaultMaxTokens {
+		t.Fatalf("client = %+v, want trimmed key and default model/base URL/max tokens", client)
+	}
+}
+
+func TestCompleteUsesForcedToolForStructuredOutputs(t *testing.T) {
+	var captured messagesRequest
+	var apiKey, version, requestPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		apiKey = r.Header.Get("x-api-key")
+		version = r.Header.Get("anthropic-version")
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("Decode request body error = %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"type":"message",
+			"stop_reason":"tool_use",
+			"content":[{"type":"tool_use","id":"toolu_1","name":"expensor_response","input":{"amount":"10"}}],
+			"usage":{"input_tokens":7,"output_tokens":3}
+		}`))
+	}))
+	defer server.Close()
+
+	config, err := json.Marshal(providerConfig{Model: "claude-test", BaseURL: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	got, err := NewClient(llm.ClientConfig{Config: config, Credentials: []byte(`{"api_key":"sk-ant-test"}`)})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	resp, err := got.Complete(context.Background(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "Return JSON."},
+			{Role: llm.RoleUser, Content: "extract amount"},
+		},
+		MaxOutputTokens: 128,
+		ResponseFormat: llm.ResponseFormat{
+			Type:   llm.ResponseFormatJSONSchema,
+			Name:   "amount_result",
+			Schema: json.RawMessage(`{"type":"object","required":["amount"],"properties":{"amount":{"type":"string"}}}`),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if requestPath != "/v1/messages" || apiKey != "sk-ant-test" || version != apiVersion {
+		t.Fatalf("request path/key/version = %q/%q/%q", requestPath, apiKey, version)
+	}
+	if captured.Model != "claude-test" || captured.MaxTokens != 128 || captured.System != "Return JSON." {
+		t.Fatalf("captured model/tokens/system = %q/%d/%q", captured.Model, captured.MaxTokens, captured.System)
+	}
+	if len(captured.Messages) != 1 || captured.Messages[0].Role != "user" {
+		t.Fatalf("captured messages = %#v, want one user message", captured.Messages)
+	}
+	if len(captured.Tools) != 1 || captured.Tools[0].Name != responseToolName || captured.ToolChoice == nil || captured.ToolChoice.Name != responseToolName {
+		t.Fatalf("captured tools/choice = %#v/%#v, want forced response tool", captured.Tools, captured.ToolChoice)
+	}
+	if resp.Text != `{"amount":"10"}` || resp.Usage.TotalTokens != 10 || resp.FinishReason != "tool_use" {
+		t.Fatalf("response = %+v, want structured tool input, usage and finish reason", resp)
+	}
+}
+
+func TestCompleteRoundTripsToolCalls(t *testing.T) {
+	var captured messagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("Decode request body error = %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"stop_reason":"tool_use",
+			"content":[
+				{"type":"text","text":"Looking that up."},
+				{"type":"tool_use","id":"toolu_2","name":"list_transactions","input":{"category":"Food"}}
+			],
+			"usage":{"input_tokens":20,"output_tokens":5}
+		}`))
+	}))
+	defer server.Close()
+
+	config, _ := json.Marshal(providerConfig{BaseURL: server.URL})
+	got, err := NewClient(llm.ClientConfig{Config: config, Credentials: []byte(`{"api_key":"sk-ant-test"}`)})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	resp, err := got.Complete(context.Background(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: "How much on food?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "toolu_1", Name: "get_facets", Arguments: json.RawMessage(`{}`)}}},
+			{Role: llm.RoleTool, ToolCallID: "toolu_1", Content: `{"categories":["Food"]}`},
+		},
+		Tools: []llm.Tool{{Name: "list_transactions", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if len(captured.Messages) != 3 || captured.Messages[1].Content[0].Type != "tool_use" || captured.Messages[2].Content[0].ToolUseID != "toolu_1" {
+		t.Fatalf("captured messages = %#v, want tool use and tool result turns", captured.Messages)
+	}
+	if captured.ToolChoice != nil || len(captured.Tools) != 1 {
+		t.Fatalf("captured tools/choice = %#v/%#v, want caller tools without forced choice", captured.Tools, captured.ToolChoice)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "list_transactions" || string(resp.ToolCalls[0].Arguments) != `{"category":"Food"}` {
+		t.Fatalf("tool calls = %#v", resp.ToolCalls)
+	}
+	if len(resp.Messages) != 1 || len(resp.Messages[0].ToolCalls) != 1 || resp.Text != "Looking that up." {
+		t.Fatalf("response = %+v, want assistant message carrying tool calls", resp)
+	}
+}
+
+func TestListModelsFollowsPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("after_id") == "" {
+			_, _ = w.Write([]byte(`{"data":[{"id":"claude-a","display_name":"Claude A"}],"has_more":true,"last_id":"claude-a"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"claude-b"}],"has_more":false}`))
+	}))
+	defer server.Close()
+
+	config, _ := json.Marshal(providerConfig{BaseURL: server.URL})
+	got, err := NewClient(llm.ClientConfig{Config: config, Credentials: []byte(`{"api_key":"sk-ant-test"}`)})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	models, err := got.(llm.ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].DisplayName != "Claude A" || models[1].DisplayName != "claude-b" {
+		t.Fatalf("models = %+v, want both pages with display name fallback", models)
+	}
+}
+
+func TestCompleteMapsAnthropicErrorResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer server.Close()
+
+	config, _ := json.Marshal(providerConfig{BaseURL: server.URL})
+	got, err := NewClient(llm.ClientConfig{Config: config, Credentials: []byte(`{"api_key":"sk-ant-test"}`)})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	err = got.HealthCheck(context.Background())
+	if errors.WhatKind(err) != errors.Unauthenticated {
+		t.Fatalf("HealthCheck() error = %v, want unauthenticated", err)
+	}
+	if errors.UserMsg(err) != "Anthropic API key was rejected. Check the key and try again." {
+		t.Fatalf("user message = %q", errors.UserMsg(err))
+	}
+}
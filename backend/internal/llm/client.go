@@ -28,10 +28,11 @@ const (
 
 // Message is a provider-neutral chat/message input or output.
 type Message struct {
-	Role       Role   `json:"role" yaml:"role"`
-	Content    string `json:"content" yaml:"content"`
-	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
-	ToolCallID string `json:"tool_call_id,omitempty" yaml:"tool_call_id,omitempty"`
+	Role       Role       `json:"role" yaml:"role"`
+	Content    string     `json:"content" yaml:"content"`
+	Name       string     `json:"name,omitempty" yaml:"name,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty" yaml:"tool_call_id,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty" yaml:"tool_calls,omitempty"`
 }
 
 // Tool declares a callable tool exposed to an LLM provider.
@@ -100,6 +101,23 @@ type Client interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// CapabilityDeclarer is implemented by clients whose capabilities depend on
+// tenant configuration rather than on the provider registration alone.
+type CapabilityDeclarer interface {
+	Capabilities() []Capability
+}
+
+// ModelNamer is implemented by clients that report the model their requests
+// are sent to, after any provider default is applied.
+type ModelNamer interface {
+	Model() string
+}
+
+// ModelLister is implemented by clients that can discover available models.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelOption, error)
+}
+
 // ClientConfig contains tenant runtime state needed to construct a provider client.
 type ClientConfig struct {
 	Config      json.RawMessage
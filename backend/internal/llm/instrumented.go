@@ -97,6 +97,39 @@ func (c *InstrumentedClient) HealthCheck(ctx context.Context) error {
 	return err
 }
 
+// ListModels delegates model discovery when the wrapped client supports it.
+func (c *InstrumentedClient) ListModels(ctx context.Context) ([]ModelOption, error) {
+	const op = "llm.InstrumentedClient.ListModels"
+
+	lister, ok := c.next.(ModelLister)
+	if !ok {
+		return nil, errors.E(op, errors.Unimplemented, errors.User("This LLM provider does not support model discovery."))
+	}
+	start := time.Now()
+	ctx, span := c.scope.Start(ctx, "llm.list_models")
+	defer span.End()
+
+	attrs := []attribute.KeyValue{attribute.String("llm.provider", c.provider)}
+	span.SetAttributes(attrs...)
+	models, err := lister.ListModels(ctx)
+	recordAttrs := append([]attribute.KeyValue(nil), attrs...)
+	if err != nil {
+		if kind := errors.WhatKind(err); kind.Code != "" {
+			recordAttrs = append(recordAttrs, attribute.String("error_kind", kind.Code))
+		}
+		c.logError(ctx, "llm provider model discovery failed", "list_models", err, attrs)
+	}
+	span.SetAttributes(recordAttrs...)
+	c.scope.RecordDuration(ctx, observability.DurationOperation{
+		Namespace:  "llm",
+		Name:       "list_models",
+		Duration:   time.Since(start),
+		Err:        err,
+		Attributes: recordAttrs,
+	})
+	return models, err
+}
+
 func (c *InstrumentedClient) requestAttrs(req Request) []attribute.KeyValue {
 	attrs := []attribute.KeyValue{
 		attribute.String("llm.provider", c.provider),
@@ -156,4 +189,7 @@ func safeAttr(value, fallback string) string {
 	return value
 }
 
-var _ Client = (*InstrumentedClient)(nil)
+var (
+	_ Client      = (*InstrumentedClient)(nil)
+	_ ModelLister = (*InstrumentedClient)(nil)
+)
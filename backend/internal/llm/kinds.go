@@ -9,4 +9,5 @@ import (
 var (
 	KindNoProviderConfigured  = errors.Kind{Code: "llm_no_provider_configured", Status: http.StatusConflict}
 	KindCapabilityUnsupported = errors.Kind{Code: "llm_capability_unsupported", Status: http.StatusConflict}
+	KindQuotaExceeded         = errors.Kind{Code: "llm_quota_exceeded", Status: http.StatusTooManyRequests}
 )
@@ -0,0 +1,79 @@
+package ollama
+
+import (
+	"encoding/json"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+)
+
+type chatRequest struct {
+	Model    string          `json:"model"`
+	Messages []chatMessage   `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Tools    []chatTool      `json:"tools,omitempty"`
+	Options  *chatOptions    `json:"options,omitempty"`
+}
+
+type chatMessage struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []chatToolCall `json:"tool_calls,omitempty"`
+	ToolName  string         `json:"tool_name,omitempty"`
+}
+
+type chatToolCall struct {
+	ID       string           `json:"id,omitempty"`
+	Function chatToolFunction `json:"function"`
+}
+
+type chatToolFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type chatTool struct {
+	Type     string             `json:"type"`
+	Function chatToolDefinition `json:"function"`
+}
+
+type chatToolDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type chatOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type chatResponse struct {
+	Model           string      `json:"model"`
+	Message         chatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	DoneReason      string      `json:"done_reason"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+	EvalCount       int         `json:"eval_count"`
+	Error           string      `json:"error"`
+}
+
+type tagsResponse struct {
+	Models []struct {
+		Name    string `json:"name"`
+		Model   string `json:"model"`
+		Details struct {
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+	} `json:"models"`
+}
+
+func (r chatResponse) usage() llm.Usage {
+	return llm.Usage{
+		InputTokens:  r.PromptEvalCount,
+		OutputTokens: r.EvalCount,
+		TotalTokens:  r.PromptEvalCount + r.EvalCount,
+	}
+}
@@ -0,0 +1,320 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	ProviderName   = "ollama"
+	defaultBaseURL = "http://localhost:11434"
+	defaultModel   = "llama3.2"
+	defaultTimeout = 120 * time.Second
+)
+
+type providerConfig struct {
+	Model   string `json:"model"`
+	BaseURL string `json:"base_url"`
+}
+
+type client struct {
+	model      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Provider returns the Ollama native API-backed LLM provider registration.
+func Provider() llm.Provider {
+	return llm.Provider{
+		Metadata: llm.ProviderMetadata{
+			Name:        ProviderName,
+			DisplayName: "Ollama",
+			Description: "Run LLM workflows on a self-hosted Ollama server so prompts never leave your network.",
+			Auth: llm.AuthSpec{
+				Type:     llm.AuthTypeNone,
+				Required: false,
+			},
+			ConfigSchema: json.RawMessage(`{
+				"type":"object",
+				"properties":{
+					"model":{"type":"string","default":"llama3.2"},
+					"base_url":{"type":"string","default":"http://localhost:11434"}
+				}
+			}`),
+			Capabilities: []llm.Capability{
+				llm.CapabilityTextGeneration,
+				llm.CapabilityJSONSchema,
+				llm.CapabilityTools,
+			},
+		},
+		NewClient: NewClient,
+	}
+}
+
+// NewClient builds an Ollama client from provider config. Ollama has no credentials.
+func NewClient(input llm.ClientConfig) (llm.Client, error) {
+	const op = "llm.ollama.NewClient"
+
+	cfg := providerConfig{Model: defaultModel, BaseURL: defaultBaseURL}
+	if len(input.Config) > 0 {
+		if err := json.Unmarshal(input.Config, &cfg); err != nil {
+			return nil, errors.E(op, errors.InvalidInput, "decoding Ollama config", err)
+		}
+	}
+	cfg.Model = strings.TrimSpace(cfg.Model)
+	if cfg.Model == "" {
+		cfg.Model = defaultModel
+	}
+	cfg.BaseURL = strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+
+	return &client{
+		model:      cfg.Model,
+		baseURL:    cfg.BaseURL,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// Model returns the model requests are sent to.
+func (c *client) Model() string {
+	return c.model
+}
+
+// HealthCheck verifies the server is reachable and the configured model is pulled.
+// It avoids a generation call because cold-loading a local model can take minutes.
+func (c *client) HealthCheck(ctx context.Context) error {
+	const op = "llm.ollama.HealthCheck"
+
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	for _, model := range models {
+		if sameModel(model.ID, c.model) {
+			return nil
+		}
+	}
+	return errors.E(
+		op,
+		errors.FailedPrecondition,
+		errors.User(fmt.Sprintf("Ollama model %q is not available. Pull it on the Ollama server and try again.", c.model)),
+	)
+}
+
+// ListModels returns the models pulled on the Ollama server.
+func (c *client) ListModels(ctx context.Context) ([]llm.ModelOption, error) {
+	const op = "llm.ollama.ListModels"
+
+	body, err := c.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var tags tagsResponse
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, errors.E(op, errors.BadGateway, "decoding Ollama tags response", err)
+	}
+	models := make([]llm.ModelOption, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		id := model.Name
+		if id == "" {
+			id = model.Model
+		}
+		details := []string{}
+		for _, detail := range []string{model.Details.Family, model.Details.ParameterSize, model.Details.QuantizationLevel} {
+			if strings.TrimSpace(detail) != "" {
+				details = append(details, detail)
+			}
+		}
+		models = append(models, llm.ModelOption{
+			ID:          id,
+			DisplayName: id,
+			Description: strings.Join(details, " · "),
+		})
+	}
+	return models, nil
+}
+
+func (c *client) Complete(ctx context.Context, req llm.Request) (llm.Response, error) {
+	const op = "llm.ollama.Complete"
+
+	payload, err := c.chatPayload(req)
+	if err != nil {
+		return llm.Response{}, errors.E(op, err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return llm.Response{}, errors.E(op, errors.Internal, "building Ollama request", err)
+	}
+	respBody, err := c.do(ctx, http.MethodPost, "/api/chat", body)
+	if err != nil {
+		return llm.Response{}, errors.E(op, err)
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "decoding Ollama response", err)
+	}
+	if resp.Error != "" {
+		return llm.Response{}, errors.E(op, errors.BadGateway, errors.User("LLM provider request failed."), resp.Error)
+	}
+
+	calls := make([]llm.ToolCall, 0, len(resp.Message.ToolCalls))
+	for i, call := range resp.Message.ToolCalls {
+		id := strings.TrimSpace(call.ID)
+		if id == "" {
+			id = "call_" + strconv.Itoa(i)
+		}
+		calls = append(calls, llm.ToolCall{ID: id, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	text := strings.TrimSpace(resp.Message.Content)
+	if text == "" && len(calls) == 0 {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "Ollama response did not include output text")
+	}
+	if len(calls) == 0 {
+		calls = nil
+	}
+	return llm.Response{
+		Text:         text,
+		Messages:     []llm.Message{{Role: llm.RoleAssistant, Content: text, ToolCalls: calls}},
+		ToolCalls:    calls,
+		Usage:        resp.usage(),
+		FinishReason: resp.DoneReason,
+	}, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, errors.E(errors.Internal, "building Ollama request", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, errors.E(
+			errors.Unavailable,
+			errors.User("Could not reach the Ollama server. Check the base URL and that Ollama is running."),
+			err,
+		)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, 4<<20))
+	if err != nil {
+		return nil, errors.E(errors.BadGateway, "reading Ollama response", err)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, ollamaProviderError(httpResp.StatusCode, respBody, c.model)
+	}
+	return respBody, nil
+}
+
+func (c *client) chatPayload(req llm.Request) (chatRequest, error) {
+	const op = "llm.ollama.chatPayload"
+
+	payload := chatRequest{
+		Model:    c.model,
+		Messages: make([]chatMessage, 0, len(req.Messages)),
+	}
+	if req.Temperature != nil || req.MaxOutputTokens > 0 {
+		payload.Options = &chatOptions{Temperature: req.Temperature, NumPredict: req.MaxOutputTokens}
+	}
+	for _, msg := range req.Messages {
+		if strings.TrimSpace(msg.Content) == "" && len(msg.ToolCalls) == 0 {
+			continue
+		}
+		out := chatMessage{Role: string(msg.Role), Content: msg.Content}
+		for _, call := range msg.ToolCalls {
+			arguments := call.Arguments
+			if len(arguments) == 0 {
+				arguments = json.RawMessage(`{}`)
+			}
+			out.ToolCalls = append(out.ToolCalls, chatToolCall{
+				ID:       call.ID,
+				Function: chatToolFunction{Name: call.Name, Arguments: arguments},
+			})
+		}
+		if msg.Role == llm.RoleTool {
+			out.ToolName = msg.Name
+		}
+		payload.Messages = append(payload.Messages, out)
+	}
+	if len(payload.Messages) == 0 {
+		return chatRequest{}, errors.E(op, errors.InvalidInput, "Ollama request requires at least one non-empty message")
+	}
+	for _, tool := range req.Tools {
+		payload.Tools = append(payload.Tools, chatTool{
+			Type: "function",
+			Function: chatToolDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	switch req.ResponseFormat.Type {
+	case "", llm.ResponseFormatText:
+	case llm.ResponseFormatJSONSchema:
+		if len(req.ResponseFormat.Schema) == 0 || !json.Valid(req.ResponseFormat.Schema) {
+			return chatRequest{}, errors.E(op, errors.InvalidInput, "json_schema response format requires a valid schema")
+		}
+		payload.Format = req.ResponseFormat.Schema
+	case llm.ResponseFormatJSONObject:
+		payload.Format = json.RawMessage(`"json"`)
+	default:
+		return chatRequest{}, errors.E(op, errors.InvalidInput, fmt.Sprintf("unsupported Ollama response format %q", req.ResponseFormat.Type))
+	}
+	return payload, nil
+}
+
+// sameModel treats an untagged model name as the implicit ":latest" tag.
+func sameModel(available, configured string) bool {
+	normalize := func(name string) string {
+		name = strings.TrimSpace(name)
+		if !strings.Contains(name, ":") {
+			name += ":latest"
+		}
+		return name
+	}
+	return normalize(available) == normalize(configured)
+}
+
+func ollamaProviderError(status int, body []byte, model string) error {
+	var parsed struct {
+		Error string `json:"error"`
+	}
+	_ = json.Unmarshal(body, &parsed)
+	switch status {
+	case http.StatusNotFound:
+		return errors.E(
+			errors.FailedPrecondition,
+			errors.User(fmt.Sprintf("Ollama model %q is not available. Pull it on the Ollama server and try again.", model)),
+			parsed.Error,
+		)
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return errors.E(errors.ResourceExhausted, errors.User("Ollama server is busy. Wait a moment and try again."), parsed.Error)
+	default:
+		return errors.E(errors.BadGateway, errors.User("LLM provider request failed."), parsed.Error)
+	}
+}
+
+var _ llm.ModelLister = (*client)(nil)
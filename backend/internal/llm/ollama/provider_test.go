@@ -0,0 +1,143 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestNewClientAppliesDefaultsWithoutCredentials(t *testing.T) {
+	got, err := NewClient(llm.ClientConfig{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client, ok := got.(*client)
+	if !ok {
+		t.Fatalf("client type = %T, want *client", got)
+	}
+	if client.model != defaultModel || client.baseURL != defaultBaseURL {
+		t.Fatalf("client = %+v, want default model/base URL", client)
+	}
+}
+
+func TestCompleteUsesNativeChatAPIWithSchemaAndTools(t *testing.T) {
+	var captured chatRequest
+	var requestPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("Decode request body error = %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_facets","arguments":{"field":"category"}}}]},
+			"done":true,
+			"done_reason":"stop",
+			"prompt_eval_count":12,
+			"eval_count":4
+		}`))
+	}))
+	defer server.Close()
+
+	config, err := json.Marshal(providerConfig{Model: "qwen3:8b", BaseURL: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	got, err := NewClient(llm.ClientConfig{Config: config})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	temperature := 0.1
+	resp, err := got.Complete(context.Background(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "Answer with data."},
+			{Role: llm.RoleUser, Content: "Which categories exist?"},
+			{Role: llm.RoleTool, Name: "list_transactions", ToolCallID: "call_0", Content: `[]`},
+		},
+		Tools:           []llm.Tool{{Name: "get_facets", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		Temperature:     &temperature,
+		MaxOutputTokens: 256,
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if requestPath != "/api/chat" || captured.Model != "qwen3:8b" || captured.Stream {
+		t.Fatalf("request path/model/stream = %q/%q/%v", requestPath, captured.Model, captured.Stream)
+	}
+	if captured.Options == nil || captured.Options.NumPredict != 256 || *captured.Options.Temperature != temperature {
+		t.Fatalf("captured options = %#v", captured.Options)
+	}
+	if len(captured.Tools) != 1 || captured.Tools[0].Type != "function" || captured.Tools[0].Function.Name != "get_facets" {
+		t.Fatalf("captured tools = %#v", captured.Tools)
+	}
+	if len(captured.Messages) != 3 || captured.Messages[2].ToolName != "list_transactions" {
+		t.Fatalf("captured messages = %#v, want tool result named for its tool", captured.Messages)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_0" || string(resp.ToolCalls[0].Arguments) != `{"field":"category"}` {
+		t.Fatalf("tool calls = %#v, want synthesized id and raw arguments", resp.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 16 || resp.FinishReason != "stop" {
+		t.Fatalf("response = %+v, want usage and finish reason", resp)
+	}
+
+	if _, err := got.Complete(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "json please"}},
+		ResponseFormat: llm.ResponseFormat{
+			Type:   llm.ResponseFormatJSONSchema,
+			Schema: json.RawMessage(`{"type":"object"}`),
+		},
+	}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if string(captured.Format) != `{"type":"object"}` {
+		t.Fatalf("captured format = %s, want schema passed as format", captured.Format)
+	}
+}
+
+func TestHealthCheckRequiresPulledModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"models":[{"name":"llama3.2:latest","details":{"family":"llama","parameter_size":"3.2B"}}]}`))
+	}))
+	defer server.Close()
+
+	config, _ := json.Marshal(providerConfig{Model: "llama3.2", BaseURL: server.URL})
+	got, err := NewClient(llm.ClientConfig{Config: config})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := got.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck() error = %v, want implicit latest tag to match", err)
+	}
+	models, err := got.(llm.ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].ID != "llama3.2:latest" || models[0].Description != "llama · 3.2B" {
+		t.Fatalf("models = %+v", models)
+	}
+
+	config, _ = json.Marshal(providerConfig{Model: "mistral", BaseURL: server.URL})
+	got, err = NewClient(llm.ClientConfig{Config: config})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	err = got.HealthCheck(context.Background())
+	if errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Fatalf("HealthCheck() error = %v, want failed precondition for missing model", err)
+	}
+}
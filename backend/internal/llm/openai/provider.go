@@ -102,6 +102,11 @@ func NewClient(input llm.ClientConfig) (llm.Client, error) {
 	}, nil
 }
 
+// Model returns the model requests are sent to.
+func (c *client) Model() string {
+	return c.model
+}
+
 func (c *client) HealthCheck(ctx context.Context) error {
 	const op = "llm.openai.HealthCheck"
 
@@ -28,6 +28,9 @@ func TestNewClientRequiresAPIKeyAndAppliesDefaults(t *testing.T) {
 	if client.apiKey != "sk-test" || client.model != defaultModel || client.baseURL != defaultBaseURL {
 		t.Fatalf("client = %+v, want trimmed key and default model/base URL", client)
 	}
+	if client.Model() != defaultModel {
+		t.Fatalf("Model() = %q, want %q", client.Model(), defaultModel)
+	}
 }
 
 func TestCompleteUsesResponsesAPIWithStructuredOutputs(t *testing.T) {
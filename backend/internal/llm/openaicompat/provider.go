@@ -0,0 +1,361 @@
+// Package openaicompat implements an LLM provider for self-hosted servers that
+// expose the OpenAI Chat Completions API, such as LM Studio, vLLM and the
+// llama.cpp server.
+package openaicompat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	ProviderName   = "openai_compatible"
+	defaultTimeout = 120 * time.Second
+)
+
+type credentials struct {
+	APIKey string `json:"api_key"`
+}
+
+type providerConfig struct {
+	Model        string           `json:"model"`
+	BaseURL      string           `json:"base_url"`
+	Capabilities []llm.Capability `json:"capabilities"`
+}
+
+type client struct {
+	apiKey       string
+	model        string
+	baseURL      string
+	capabilities []llm.Capability
+	httpClient   *http.Client
+}
+
+// Provider returns the OpenAI-compatible Chat Completions provider registration.
+// Metadata lists every capability the adapter can drive; each tenant declares
+// which of those its server actually supports in the provider config.
+func Provider() llm.Provider {
+	return llm.Provider{
+		Metadata: llm.ProviderMetadata{
+			Name:        ProviderName,
+			DisplayName: "OpenAI-compatible server",
+			Description: "Connect LM Studio, vLLM, llama.cpp or any server exposing the OpenAI Chat Completions API.",
+			Auth: llm.AuthSpec{
+				Type:     llm.AuthTypeAPIKey,
+				Required: false,
+			},
+			ConfigSchema: json.RawMessage(`{
+				"type":"object",
+				"required":["base_url","model"],
+				"properties":{
+					"base_url":{"type":"string","examples":["http://localhost:1234/v1"]},
+					"model":{"type":"string"},
+					"capabilities":{
+						"type":"array",
+						"items":{"type":"string","enum":["json_schema","tools"]},
+						"default":[]
+					}
+				}
+			}`),
+			Capabilities: []llm.Capability{
+				llm.CapabilityTextGeneration,
+				llm.CapabilityJSONSchema,
+				llm.CapabilityTools,
+			},
+		},
+		NewClient: NewClient,
+	}
+}
+
+// NewClient builds a Chat Completions client from provider config and optional credentials.
+func NewClient(input llm.ClientConfig) (llm.Client, error) {
+	const op = "llm.openaicompat.NewClient"
+
+	var creds credentials
+	if len(input.Credentials) > 0 {
+		if err := json.Unmarshal(input.Credentials, &creds); err != nil {
+			return nil, errors.E(op, errors.InvalidInput, "decoding OpenAI-compatible credentials", err)
+		}
+	}
+
+	var cfg providerConfig
+	if len(input.Config) > 0 {
+		if err := json.Unmarshal(input.Config, &cfg); err != nil {
+			return nil, errors.E(op, errors.InvalidInput, "decoding OpenAI-compatible config", err)
+		}
+	}
+	cfg.BaseURL = strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if cfg.BaseURL == "" {
+		return nil, errors.E(op, errors.FailedPrecondition, "OpenAI-compatible base URL is not configured")
+	}
+	cfg.Model = strings.TrimSpace(cfg.Model)
+	if cfg.Model == "" {
+		return nil, errors.E(op, errors.FailedPrecondition, "OpenAI-compatible model is not configured")
+	}
+	capabilities := []llm.Capability{llm.CapabilityTextGeneration}
+	for _, capability := range cfg.Capabilities {
+		switch capability {
+		case llm.CapabilityJSONSchema, llm.CapabilityTools:
+			capabilities = append(capabilities, capability)
+		case llm.CapabilityTextGeneration:
+		default:
+			return nil, errors.E(op, errors.InvalidInput, fmt.Sprintf("unsupported OpenAI-compatible capability %q", capability))
+		}
+	}
+
+	return &client{
+		apiKey:       strings.TrimSpace(creds.APIKey),
+		model:        cfg.Model,
+		baseURL:      cfg.BaseURL,
+		capabilities: capabilities,
+		httpClient:   &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// Capabilities returns the capabilities declared in the tenant provider config.
+func (c *client) Capabilities() []llm.Capability {
+	return append([]llm.Capability(nil), c.capabilities...)
+}
+
+// Model returns the model requests are sent to.
+func (c *client) Model() string {
+	return c.model
+}
+
+func (c *client) HealthCheck(ctx context.Context) error {
+	const op = "llm.openaicompat.HealthCheck"
+
+	if _, err := c.Complete(ctx, llm.Request{
+		Workflow:             "provider_setup",
+		Purpose:              "healthcheck",
+		RequiredCapabilities: []llm.Capability{llm.CapabilityTextGeneration},
+		MaxOutputTokens:      16,
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: "Reply with OK."},
+		},
+	}); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// ListModels returns the models advertised by the server's /models endpoint.
+func (c *client) ListModels(ctx context.Context) ([]llm.ModelOption, error) {
+	const op = "llm.openaicompat.ListModels"
+
+	body, err := c.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var resp modelsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, errors.E(op, errors.BadGateway, "decoding OpenAI-compatible models response", err)
+	}
+	models := make([]llm.ModelOption, 0, len(resp.Data))
+	for _, model := range resp.Data {
+		if strings.TrimSpace(model.ID) == "" {
+			continue
+		}
+		models = append(models, llm.ModelOption{ID: model.ID, DisplayName: model.ID, Description: model.OwnedBy})
+	}
+	return models, nil
+}
+
+func (c *client) Complete(ctx context.Context, req llm.Request) (llm.Response, error) {
+	const op = "llm.openaicompat.Complete"
+
+	payload, err := c.chatPayload(req)
+	if err != nil {
+		return llm.Response{}, errors.E(op, err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return llm.Response{}, errors.E(op, errors.Internal, "building OpenAI-compatible request", err)
+	}
+	respBody, err := c.do(ctx, http.MethodPost, "/chat/completions", body)
+	if err != nil {
+		return llm.Response{}, errors.E(op, err)
+	}
+
+	var resp chatCompletionResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "decoding OpenAI-compatible response", err)
+	}
+	if resp.Error != nil {
+		return llm.Response{}, errors.E(op, errors.BadGateway, errors.User("LLM provider request failed."), resp.Error.Message)
+	}
+	if len(resp.Choices) == 0 {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "OpenAI-compatible response did not include choices")
+	}
+
+	choice := resp.Choices[0]
+	text := ""
+	if choice.Message.Content != nil {
+		text = strings.TrimSpace(*choice.Message.Content)
+	}
+	var calls []llm.ToolCall
+	for _, call := range choice.Message.ToolCalls {
+		arguments := json.RawMessage(call.Function.Arguments)
+		if !json.Valid(arguments) {
+			return llm.Response{}, errors.E(op, errors.BadGateway, fmt.Sprintf("OpenAI-compatible tool call %q has invalid JSON arguments", call.Function.Name))
+		}
+		calls = append(calls, llm.ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: arguments})
+	}
+	if text == "" && len(calls) == 0 {
+		return llm.Response{}, errors.E(op, errors.BadGateway, "OpenAI-compatible response did not include output text")
+	}
+	return llm.Response{
+		Text:         text,
+		Messages:     []llm.Message{{Role: llm.RoleAssistant, Content: text, ToolCalls: calls}},
+		ToolCalls:    calls,
+		Usage:        resp.Usage.toLLMUsage(),
+		FinishReason: choice.FinishReason,
+	}, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, errors.E(errors.Internal, "building OpenAI-compatible request", err)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, errors.E(
+			errors.Unavailable,
+			errors.User("Could not reach the OpenAI-compatible server. Check the base URL and that the server is running."),
+			err,
+		)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, 4<<20))
+	if err != nil {
+		return nil, errors.E(errors.BadGateway, "reading OpenAI-compatible response", err)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, compatProviderFailure(httpResp.StatusCode)
+	}
+	return respBody, nil
+}
+
+func (c *client) chatPayload(req llm.Request) (chatCompletionRequest, error) {
+	const op = "llm.openaicompat.chatPayload"
+
+	payload := chatCompletionRequest{
+		Model:       c.model,
+		Messages:    make([]chatMessage, 0, len(req.Messages)),
+		MaxTokens:   req.MaxOutputTokens,
+		Temperature: req.Temperature,
+	}
+	for _, msg := range req.Messages {
+		if strings.TrimSpace(msg.Content) == "" && len(msg.ToolCalls) == 0 {
+			continue
+		}
+		out := chatMessage{Role: string(msg.Role), Content: msg.Content, ToolCallID: msg.ToolCallID}
+		if msg.Role != llm.RoleTool {
+			out.Name = msg.Name
+		}
+		for _, call := range msg.ToolCalls {
+			arguments := string(call.Arguments)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			out.ToolCalls = append(out.ToolCalls, chatToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: chatFunctionCall{Name: call.Name, Arguments: arguments},
+			})
+		}
+		payload.Messages = append(payload.Messages, out)
+	}
+	if len(payload.Messages) == 0 {
+		return chatCompletionRequest{}, errors.E(op, errors.InvalidInput, "OpenAI-compatible request requires at least one non-empty message")
+	}
+	for _, tool := range req.Tools {
+		parameters, err := decodeSchema(tool.Parameters)
+		if err != nil {
+			return chatCompletionRequest{}, errors.E(op, errors.InvalidInput, fmt.Sprintf("decoding tool %q parameters", tool.Name), err)
+		}
+		payload.Tools = append(payload.Tools, chatTool{
+			Type:     "function",
+			Function: chatToolDefinition{Name: tool.Name, Description: tool.Description, Parameters: parameters},
+		})
+	}
+
+	switch req.ResponseFormat.Type {
+	case "", llm.ResponseFormatText:
+	case llm.ResponseFormatJSONSchema:
+		if len(req.ResponseFormat.Schema) == 0 || !json.Valid(req.ResponseFormat.Schema) {
+			return chatCompletionRequest{}, errors.E(op, errors.InvalidInput, "json_schema response format requires a valid schema")
+		}
+		schema, err := decodeSchema(req.ResponseFormat.Schema)
+		if err != nil {
+			return chatCompletionRequest{}, errors.E(op, errors.InvalidInput, "decoding json_schema response format", err)
+		}
+		name := strings.TrimSpace(req.ResponseFormat.Name)
+		if name == "" {
+			name = "expensor_response"
+		}
+		payload.ResponseFormat = &chatResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &chatJSONSchema{Name: name, Strict: req.ResponseFormat.Strict, Schema: schema},
+		}
+	case llm.ResponseFormatJSONObject:
+		payload.ResponseFormat = &chatResponseFormat{Type: "json_object"}
+	default:
+		return chatCompletionRequest{}, errors.E(op, errors.InvalidInput, fmt.Sprintf("unsupported OpenAI-compatible response format %q", req.ResponseFormat.Type))
+	}
+	return payload, nil
+}
+
+func decodeSchema(raw json.RawMessage) (map[string]any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func compatProviderFailure(status int) error {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.E(errors.Unauthenticated, errors.User("OpenAI-compatible server rejected the API key. Check the key and try again."))
+	case http.StatusNotFound:
+		return errors.E(
+			errors.FailedPrecondition,
+			errors.User("OpenAI-compatible server did not recognize the request. Check the base URL includes the API prefix, such as /v1."),
+		)
+	case http.StatusTooManyRequests:
+		return errors.E(errors.ResourceExhausted, errors.User("LLM provider request was rate limited. Wait a moment and try again."))
+	default:
+		return errors.E(errors.BadGateway, errors.User("LLM provider request failed."))
+	}
+}
+
+var (
+	_ llm.CapabilityDeclarer = (*client)(nil)
+	_ llm.ModelLister        = (*client)(nil)
+)
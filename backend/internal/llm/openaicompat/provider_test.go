@@ -0,0 +1,156 @@
+package openaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/llm"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestNewClientRequiresBaseURLAndModel(t *testing.T) {
+	if _, err := NewClient(llm.ClientConfig{Config: []byte(`{"model":"local"}`)}); err == nil {
+		t.Fatal("NewClient() error = nil, want missing base URL error")
+	}
+	if _, err := NewClient(llm.ClientConfig{Config: []byte(`{"base_url":"http://localhost:1234/v1"}`)}); err == nil {
+		t.Fatal("NewClient() error = nil, want missing model error")
+	}
+	if _, err := NewClient(llm.ClientConfig{Config: []byte(`{"base_url":"http://x/v1","model":"m","capabilities":["streaming"]}`)}); err == nil {
+		t.Fatal("NewClient() error = nil, want unsupported capability error")
+	}
+
+	got, err := NewClient(llm.ClientConfig{
+		Config: []byte(`{"base_url":" http://localhost:1234/v1/ ","model":" qwen ","capabilities":["tools"]}`),
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client, ok := got.(*client)
+	if !ok {
+		t.Fatalf("client type = %T, want *client", got)
+	}
+	if client.apiKey != "" || client.model != "qwen" || client.baseURL != "http://localhost:1234/v1" {
+		t.Fatalf("client = %+v, want trimmed config without API key", client)
+	}
+	if err := llm.RequireClientCapabilities(got, llm.CapabilityTools); err != nil {
+		t.Fatalf("RequireClientCapabilities(tools) error = %v", err)
+	}
+	if err := llm.RequireClientCapabilities(got, llm.CapabilityJSONSchema); errors.WhatKind(err) != llm.KindCapabilityUnsupported {
+		t.Fatalf("RequireClientCapabilities(json_schema) error = %v, want KindCapabilityUnsupported", err)
+	}
+}
+
+func TestCompleteUsesChatCompletionsWithToolsAndSchema(t *testing.T) {
+	var captured chatCompletionRequest
+	var authHeader, requestPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		authHeader = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("Decode request body error = %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"choices":[{
+				"message":{"role":"assistant","content":null,"tool_calls":[
+					{"id":"call_1","type":"function","function":{"name":"get_facets","arguments":"{\"field\":\"category\"}"}}
+				]},
+				"finish_reason":"tool_calls"
+			}],
+			"usage":{"prompt_tokens":9,"completion_tokens":2}
+		}`))
+	}))
+	defer server.Close()
+
+	config, err := json.Marshal(providerConfig{
+		BaseURL:      server.URL + "/v1",
+		Model:        "local-model",
+		Capabilities: []llm.Capability{llm.CapabilityTools, llm.CapabilityJSONSchema},
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	got, err := NewClient(llm.ClientConfig{Config: config, Credentials: []byte(`{"api_key":"lm-studio"}`)})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	resp, err := got.Complete(context.Background(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: "Which categories exist?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_0", Name: "list_transactions"}}},
+			{Role: llm.RoleTool, Name: "list_transactions", ToolCallID: "call_0", Content: `[]`},
+		},
+		Tools: []llm.Tool{{Name: "get_facets", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		ResponseFormat: llm.ResponseFormat{
+			Type:   llm.ResponseFormatJSONSchema,
+			Name:   "facets",
+			Schema: json.RawMessage(`{"type":"object"}`),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if requestPath != "/v1/chat/completions" || authHeader != "Bearer lm-studio" {
+		t.Fatalf("request path/auth = %q/%q", requestPath, authHeader)
+	}
+	if len(captured.Messages) != 3 || captured.Messages[1].ToolCalls[0].Function.Arguments != "{}" || captured.Messages[2].ToolCallID != "call_0" {
+		t.Fatalf("captured messages = %#v, want assistant tool call and tool result", captured.Messages)
+	}
+	if len(captured.Tools) != 1 || captured.Tools[0].Function.Parameters["type"] != "object" {
+		t.Fatalf("captured tools = %#v", captured.Tools)
+	}
+	if captured.ResponseFormat == nil || captured.ResponseFormat.JSONSchema == nil || captured.ResponseFormat.JSONSchema.Name != "facets" {
+		t.Fatalf("captured response format = %#v", captured.ResponseFormat)
+	}
+	if len(resp.ToolCalls) != 1 || string(resp.ToolCalls[0].Arguments) != `{"field":"category"}` || resp.Usage.TotalTokens != 11 {
+		t.Fatalf("response = %+v, want decoded tool call and derived total usage", resp)
+	}
+}
+
+func TestListModelsAndHealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"data":[{"id":"llama-3.1-8b","owned_by":"vllm"},{"id":""}]}`))
+		case "/v1/chat/completions":
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"OK"},"finish_reason":"stop"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config, _ := json.Marshal(providerConfig{BaseURL: server.URL + "/v1", Model: "llama-3.1-8b"})
+	got, err := NewClient(llm.ClientConfig{Config: config})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := got.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck() error = %v", err)
+	}
+	models, err := got.(llm.ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].ID != "llama-3.1-8b" || models[0].Description != "vllm" {
+		t.Fatalf("models = %+v", models)
+	}
+
+	config, _ = json.Marshal(providerConfig{BaseURL: server.URL, Model: "llama-3.1-8b"})
+	got, err = NewClient(llm.ClientConfig{Config: config})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := got.HealthCheck(context.Background()); errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Fatalf("HealthCheck() error = %v, want failed precondition for missing API prefix", err)
+	}
+}
@@ -64,25 +64,58 @@ func LoadPromptCatalog(fsys fs.FS, dir string) (*PromptCatalog, error) {
 		if err != nil {
 			return nil, errors.E("llm.prompts.load_prompt_catalog", fmt.Sprintf("reading prompt %q", name), err)
 		}
-		var prompt PromptDefinition
-		if err := yaml.Unmarshal(body, &prompt); err != nil {
-			return nil, errors.E("llm.prompts.load_prompt_catalog", fmt.Sprintf("parsing prompt %q", name), err)
+		prompt, err := ParsePromptDefinition(body, name)
+		if err != nil {
+			return nil, err
 		}
-		if err := prompt.validate(name); err != nil {
+		if err := catalog.add(prompt); err != nil {
 			return nil, err
 		}
-		key := promptKey{workflow: prompt.Workflow, purpose: prompt.Purpose}
-		if existing, ok := catalog.prompts[key]; ok {
-			return nil, errors.E(
-				errors.InvalidInput,
-				fmt.Sprintf("duplicate prompt for workflow %q purpose %q: %s and %s", key.workflow, key.purpose, existing.ID, prompt.ID),
-			)
+	}
+	return catalog, nil
+}
+
+// NewPromptCatalog builds a catalog from already parsed prompt definitions.
+func NewPromptCatalog(prompts ...PromptDefinition) (*PromptCatalog, error) {
+	catalog := &PromptCatalog{prompts: make(map[promptKey]PromptDefinition, len(prompts))}
+	for _, prompt := range prompts {
+		if err := prompt.validate(prompt.ID); err != nil {
+			return nil, err
+		}
+		if err := catalog.add(prompt); err != nil {
+			return nil, err
 		}
-		catalog.prompts[key] = prompt
 	}
 	return catalog, nil
 }
 
+// ParsePromptDefinition decodes and validates one YAML or JSON prompt
+// definition. name identifies the source in error messages.
+func ParsePromptDefinition(body []byte, name string) (PromptDefinition, error) {
+	var prompt PromptDefinition
+	if err := yaml.Unmarshal(body, &prompt); err != nil {
+		return PromptDefinition{}, errors.E(
+			"llm.prompts.parse_prompt_definition", errors.InvalidInput, fmt.Sprintf("parsing prompt %q", name), err,
+		)
+	}
+	if err := prompt.validate(name); err != nil {
+		return PromptDefinition{}, err
+	}
+	return prompt, nil
+}
+
+func (c *PromptCatalog) add(prompt PromptDefinition) error {
+	key := promptKey{workflow: prompt.Workflow, purpose: prompt.Purpose}
+	if existing, ok := c.prompts[key]; ok {
+		return errors.E(
+			errors.InvalidInput,
+			fmt.Sprintf("duplicate prompt for workflow %q purpose %q: %s and %s", key.workflow, key.purpose, existing.ID, prompt.ID),
+		)
+	}
+	c.prompts[key] = prompt
+	return nil
+}
+
 // Len returns the number of prompt definitions.
 func (c *PromptCatalog) Len() int {
 	if c == nil {
@@ -76,6 +76,23 @@ func (p Provider) RequireCapabilities(required ...Capability) error {
 	return nil
 }
 
+// RequireClientCapabilities returns an error if a client declares a narrower
+// capability set than the request requires. Clients that do not implement
+// CapabilityDeclarer inherit their provider registration capabilities.
+func RequireClientCapabilities(client Client, required ...Capability) error {
+	const op = "llm.RequireClientCapabilities"
+
+	declarer, ok := client.(CapabilityDeclarer)
+	if !ok || len(required) == 0 {
+		return nil
+	}
+	provider := Provider{Metadata: ProviderMetadata{Capabilities: declarer.Capabilities()}}
+	if err := provider.RequireCapabilities(required...); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
 // Registry manages available LLM providers.
 type Registry struct {
 	providers map[string]Provider
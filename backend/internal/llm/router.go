@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"time"
 
 	"github.com/ArionMiles/expensor/backend/internal/observability"
 	"github.com/ArionMiles/expensor/backend/internal/store"
@@ -16,22 +18,43 @@ type RuntimeStore interface {
 	GetActiveLLMProviderRuntime(ctx context.Context, tenant store.Tenant) (store.LLMProviderRuntime, bool, error)
 }
 
+// UsageStore persists router call accounting and tenant token quotas.
+type UsageStore interface {
+	RecordLLMUsage(ctx context.Context, tenant store.Tenant, record store.LLMUsageRecord) error
+	SumLLMUsageTokens(ctx context.Context, tenant store.Tenant, since time.Time) (int64, error)
+	GetLLMUsageQuota(ctx context.Context, tenant store.Tenant) (store.LLMUsageQuota, error)
+}
+
+// PromptOverrideStore resolves admin-managed prompt versions that replace
+// embedded catalog prompts.
+type PromptOverrideStore interface {
+	GetActiveLLMPromptVersion(ctx context.Context, workflow, purpose string) (store.LLMPromptVersion, bool, error)
+}
+
 // RouterConfig holds router dependencies.
 type RouterConfig struct {
-	Registry *Registry
-	Runtime  RuntimeStore
-	Prompts  *PromptCatalog
-	Scope    *observability.Scope
-	Logger   *slog.Logger
+	Registry        *Registry
+	Runtime         RuntimeStore
+	Usage           UsageStore
+	Prompts         *PromptCatalog
+	PromptOverrides PromptOverrideStore
+	Scope           *observability.Scope
+	Logger          *slog.Logger
+	Now             func() time.Time
 }
 
-// Router resolves the active tenant provider and enforces capability requirements.
+// Router resolves the active tenant provider and enforces capability requirements
+// and token quotas. Every call that reaches a configured provider is recorded
+// when a usage store is configured.
 type Router struct {
-	registry *Registry
-	runtime  RuntimeStore
-	prompts  *PromptCatalog
-	scope    *observability.Scope
-	logger   *slog.Logger
+	registry  *Registry
+	runtime   RuntimeStore
+	usage     UsageStore
+	prompts   *PromptCatalog
+	overrides PromptOverrideStore
+	scope     *observability.Scope
+	logger    *slog.Logger
+	now       func() time.Time
 }
 
 // NewRouter creates an LLM router.
@@ -40,12 +63,23 @@ func NewRouter(cfg RouterConfig) *Router {
 	if registry == nil {
 		registry = NewRegistry()
 	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
 	return &Router{
-		registry: registry,
-		runtime:  cfg.Runtime,
-		prompts:  cfg.Prompts,
-		scope:    cfg.Scope,
-		logger:   cfg.Logger,
+		registry:  registry,
+		runtime:   cfg.Runtime,
+		usage:     cfg.Usage,
+		prompts:   cfg.Prompts,
+		overrides: cfg.PromptOverrides,
+		scope:     cfg.Scope,
+		logger:    logger,
+		now:       now,
 	}
 }
 
@@ -67,25 +101,173 @@ func (r *Router) Complete(ctx context.Context, tenant store.Tenant, req Request)
 	if err != nil {
 		return Response{}, errors.E(op, err)
 	}
-	if err := provider.RequireCapabilities(req.RequiredCapabilities...); err != nil {
+
+	start := r.now()
+	response, model, err := r.complete(ctx, tenant, provider, runtime, req)
+	r.recordUsage(ctx, tenant, store.LLMUsageRecord{
+		Workflow:     req.Workflow,
+		Purpose:      req.Purpose,
+		Provider:     runtime.Provider,
+		Model:        model,
+		InputTokens:  response.Usage.InputTokens,
+		OutputTokens: response.Usage.OutputTokens,
+		TotalTokens:  usageTotal(response.Usage),
+		Latency:      r.now().Sub(start),
+		Outcome:      usageOutcome(err),
+		CreatedAt:    start,
+	})
+	if err != nil {
 		return Response{}, errors.E(op, err)
 	}
+	return response, nil
+}
+
+// complete runs req against a client for runtime. It also returns the model
+// the call was sent to, or the configured model when no client was made.
+func (r *Router) complete(
+	ctx context.Context,
+	tenant store.Tenant,
+	provider Provider,
+	runtime store.LLMProviderRuntime,
+	req Request,
+) (Response, string, error) {
+	const op = "llm.Router.complete"
+
+	model := runtimeModel(runtime.Config)
+	if err := r.enforceQuota(ctx, tenant); err != nil {
+		return Response{}, model, errors.E(op, err)
+	}
+	if err := provider.RequireCapabilities(req.RequiredCapabilities...); err != nil {
+		return Response{}, model, errors.E(op, err)
+	}
 	client, err := provider.NewClient(ClientConfig{
 		Config:      cloneRawMessage(runtime.Config),
 		Credentials: cloneRawMessage(runtime.Credentials),
 	})
 	if err != nil {
-		return Response{}, errors.E(op, fmt.Sprintf("creating llm provider %q client", runtime.Provider), err)
+		return Response{}, model, errors.E(op, fmt.Sprintf("creating llm provider %q client", runtime.Provider), err)
+	}
+	if namer, ok := client.(ModelNamer); ok && namer.Model() != "" {
+		model = namer.Model()
+	}
+	if err := RequireClientCapabilities(client, req.RequiredCapabilities...); err != nil {
+		return Response{}, model, errors.E(op, err)
 	}
 	client = NewInstrumentedClient(client, runtime.Provider, r.scope, r.logger)
-	return client.Complete(ctx, req)
+	response, err := client.Complete(ctx, req)
+	return response, model, err
+}
+
+// enforceQuota rejects calls once the tenant has consumed its daily or monthly
+// token allowance. Windows start at midnight UTC and the first of the month UTC.
+func (r *Router) enforceQuota(ctx context.Context, tenant store.Tenant) error {
+	const op = "llm.Router.enforceQuota"
+
+	if r.usage == nil {
+		return nil
+	}
+	quota, err := r.usage.GetLLMUsageQuota(ctx, tenant)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	now := r.now().UTC()
+	windows := []struct {
+		name  string
+		limit int64
+		since time.Time
+	}{
+		{name: "daily", limit: quota.DailyTokenLimit, since: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)},
+		{name: "monthly", limit: quota.MonthlyTokenLimit, since: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, window := range windows {
+		if window.limit <= 0 {
+			continue
+		}
+		used, err := r.usage.SumLLMUsageTokens(ctx, tenant, window.since)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		if used >= window.limit {
+			return errors.E(
+				op,
+				KindQuotaExceeded,
+				errors.User("The "+window.name+" LLM token quota for this account has been used up."),
+				window.name+" llm token quota exceeded: used "+strconv.FormatInt(used, 10)+" of "+strconv.FormatInt(window.limit, 10),
+			)
+		}
+	}
+	return nil
+}
+
+// recordUsage persists the call outcome. Accounting failures are logged rather
+// than returned so they never fail an otherwise successful completion.
+func (r *Router) recordUsage(ctx context.Context, tenant store.Tenant, record store.LLMUsageRecord) {
+	if r.usage == nil {
+		return
+	}
+	if err := r.usage.RecordLLMUsage(context.WithoutCancel(ctx), tenant, record); err != nil {
+		r.logger.WarnContext(ctx, "failed to record llm usage",
+			"provider", record.Provider,
+			"workflow", record.Workflow,
+			"error", err,
+		)
+	}
+}
+
+func runtimeModel(config []byte) string {
+	var parsed struct {
+		Model string `json:"model"`
+	}
+	if len(config) == 0 || json.Unmarshal(config, &parsed) != nil {
+		return ""
+	}
+	return parsed.Model
 }
 
-// PromptCatalog returns the router prompt catalog.
+func usageTotal(usage Usage) int {
+	if usage.TotalTokens > 0 {
+		return usage.TotalTokens
+	}
+	return usage.InputTokens + usage.OutputTokens
+}
+
+func usageOutcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if kind := errors.WhatKind(err); kind.Code != "" {
+		return kind.Code
+	}
+	return "error"
+}
+
+// PromptCatalog returns the embedded router prompt catalog.
 func (r *Router) PromptCatalog() *PromptCatalog {
 	return r.prompts
 }
 
+// Prompt returns the prompt used for workflow and purpose. An activated
+// override version takes precedence over the embedded catalog.
+func (r *Router) Prompt(ctx context.Context, workflow, purpose string) (PromptDefinition, bool, error) {
+	const op = "llm.Router.Prompt"
+
+	if r.overrides != nil {
+		version, found, err := r.overrides.GetActiveLLMPromptVersion(ctx, workflow, purpose)
+		if err != nil {
+			return PromptDefinition{}, false, errors.E(op, err)
+		}
+		if found {
+			prompt, err := ParsePromptDefinition(version.Definition, workflow+"/"+purpose+" v"+strconv.Itoa(version.Version))
+			if err != nil {
+				return PromptDefinition{}, false, errors.E(op, errors.Internal, err)
+			}
+			return prompt, true, nil
+		}
+	}
+	prompt, ok := r.prompts.Get(workflow, purpose)
+	return prompt, ok, nil
+}
+
 func cloneRawMessage(in []byte) json.RawMessage {
 	if len(in) == 0 {
 		return nil
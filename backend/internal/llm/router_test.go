@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
@@ -60,3 +61,220 @@ func TestRouterRequiresProviderCapabilities(t *testing.T) {
 		t.Fatalf("UserMsg() = %q", message)
 	}
 }
+
+type declaringStubClient struct {
+	stubClient
+	capabilities []Capability
+}
+
+func (c declaringStubClient) Capabilities() []Capability {
+	return c.capabilities
+}
+
+func TestRouterRequiresClientDeclaredCapabilities(t *testing.T) {
+	registry := NewRegistry()
+	provider := testProvider("compat-provider", CapabilityTextGeneration, CapabilityTools, CapabilityJSONSchema)
+	provider.NewClient = func(ClientConfig) (Client, error) {
+		return declaringStubClient{capabilities: []Capability{CapabilityTextGeneration}}, nil
+	}
+	if err := registry.RegisterProvider(provider); err != nil {
+		t.Fatalf("RegisterProvider() error = %v", err)
+	}
+	router := NewRouter(RouterConfig{
+		Registry: registry,
+		Runtime: stubRuntimeStore{
+			found:   true,
+			runtime: store.LLMProviderRuntime{Provider: "compat-provider"},
+		},
+	})
+
+	_, err := router.Complete(context.Background(), store.Tenant{ID: "tenant-a"}, Request{
+		RequiredCapabilities: []Capability{CapabilityTools},
+	})
+	if errors.WhatKind(err) != KindCapabilityUnsupported {
+		t.Fatalf("Complete() error = %v, want KindCapabilityUnsupported from client declaration", err)
+	}
+}
+
+type stubUsageStore struct {
+	quota   store.LLMUsageQuota
+	used    map[time.Time]int64
+	records []store.LLMUsageRecord
+}
+
+func (s *stubUsageStore) RecordLLMUsage(_ context.Context, _ store.Tenant, record store.LLMUsageRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *stubUsageStore) SumLLMUsageTokens(_ context.Context, _ store.Tenant, since time.Time) (int64, error) {
+	return s.used[since], nil
+}
+
+func (s *stubUsageStore) GetLLMUsageQuota(context.Context, store.Tenant) (store.LLMUsageQuota, error) {
+	return s.quota, nil
+}
+
+type usageStubClient struct {
+	calls int
+}
+
+func (c *usageStubClient) Complete(context.Context, Request) (Response, error) {
+	c.calls++
+	return Response{Text: "ok", Usage: Usage{InputTokens: 120, OutputTokens: 30}}, nil
+}
+
+func (c *usageStubClient) HealthCheck(context.Context) error {
+	return nil
+}
+
+func newUsageTestRouter(t *testing.T, client *usageStubClient, usage *stubUsageStore, now time.Time) *Router {
+	t.Helper()
+	registry := NewRegistry()
+	provider := testProvider("usage-provider", CapabilityTextGeneration)
+	provider.NewClient = func(ClientConfig) (Client, error) {
+		return client, nil
+	}
+	if err := registry.RegisterProvider(provider); err != nil {
+		t.Fatalf("RegisterProvider() error = %v", err)
+	}
+	return NewRouter(RouterConfig{
+		Registry: registry,
+		Runtime: stubRuntimeStore{
+			found: true,
+			runtime: store.LLMProviderRuntime{
+				Provider: "usage-provider",
+				Config:   json.RawMessage(`{"model":"small-model"}`),
+			},
+		},
+		Usage: usage,
+		Now:   func() time.Time { return now },
+	})
+}
+
+func TestRouterRecordsUsageForCompletedCalls(t *testing.T) {
+	now := time.Date(2026, time.May, 14, 9, 30, 0, 0, time.UTC)
+	client := &usageStubClient{}
+	usage := &stubUsageStore{}
+	router := newUsageTestRouter(t, client, usage, now)
+
+	_, err := router.Complete(context.Background(), store.Tenant{ID: "tenant-a"}, Request{
+		Workflow: "rule_drafting",
+		Purpose:  "draft_rule",
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if len(usage.records) != 1 {
+		t.Fatalf("records = %d, want 1", len(usage.records))
+	}
+	got := usage.records[0]
+	if got.Workflow != "rule_drafting" || got.Purpose != "draft_rule" || got.Provider != "usage-provider" || got.Model != "small-model" {
+		t.Fatalf("record = %+v, want request and runtime identity", got)
+	}
+	if got.InputTokens != 120 || got.OutputTokens != 30 || got.TotalTokens != 150 || got.Outcome != "ok" {
+		t.Fatalf("record = %+v, want token counts and ok outcome", got)
+	}
+	if !got.CreatedAt.Equal(now) {
+		t.Fatalf("created at = %s, want %s", got.CreatedAt, now)
+	}
+}
+
+type namedStubClient struct {
+	*usageStubClient
+	model string
+}
+
+func (c namedStubClient) Model() string {
+	return c.model
+}
+
+func TestRouterRecordsTheModelTheClientResolved(t *testing.T) {
+	registry := NewRegistry()
+	provider := testProvider("default-provider", CapabilityTextGeneration)
+	provider.NewClient = func(ClientConfig) (Client, error) {
+		return namedStubClient{usageStubClient: &usageStubClient{}, model: "provider-default"}, nil
+	}
+	if err := registry.RegisterProvider(provider); err != nil {
+		t.Fatalf("RegisterProvider() error = %v", err)
+	}
+	usage := &stubUsageStore{}
+	router := NewRouter(RouterConfig{
+		Registry: registry,
+		Runtime: stubRuntimeStore{
+			found:   true,
+			runtime: store.LLMProviderRuntime{Provider: "default-provider", Config: json.RawMessage(`{}`)},
+		},
+		Usage: usage,
+	})
+
+	if _, err := router.Complete(context.Background(), store.Tenant{ID: "tenant-a"}, Request{}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if len(usage.records) != 1 || usage.records[0].Model != "provider-default" {
+		t.Fatalf("records = %+v, want the client's default model", usage.records)
+	}
+}
+
+func TestRouterEnforcesTokenQuotas(t *testing.T) {
+	now := time.Date(2026, time.May, 14, 9, 30, 0, 0, time.UTC)
+	dayStart := time.Date(2026, time.May, 14, 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		quota   store.LLMUsageQuota
+		used    map[time.Time]int64
+		message string
+	}{
+		{
+			name:    "daily",
+			quota:   store.LLMUsageQuota{DailyTokenLimit: 1000},
+			used:    map[time.Time]int64{dayStart: 1000, monthStart: 1000},
+			message: "The daily LLM token quota for this account has been used up.",
+		},
+		{
+			name:    "monthly",
+			quota:   store.LLMUsageQuota{DailyTokenLimit: 1000, MonthlyTokenLimit: 5000},
+			used:    map[time.Time]int64{dayStart: 10, monthStart: 5200},
+			message: "The monthly LLM token quota for this account has been used up.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &usageStubClient{}
+			usage := &stubUsageStore{quota: tt.quota, used: tt.used}
+			router := newUsageTestRouter(t, client, usage, now)
+
+			_, err := router.Complete(context.Background(), store.Tenant{ID: "tenant-a"}, Request{})
+			if errors.WhatKind(err) != KindQuotaExceeded {
+				t.Fatalf("Complete() error = %v, want KindQuotaExceeded", err)
+			}
+			if message := errors.UserMsg(err); message != tt.message {
+				t.Fatalf("UserMsg() = %q, want %q", message, tt.message)
+			}
+			if client.calls != 0 {
+				t.Fatalf("client calls = %d, want provider not called", client.calls)
+			}
+			if len(usage.records) != 1 || usage.records[0].Outcome != KindQuotaExceeded.Code {
+				t.Fatalf("records = %+v, want rejected call recorded", usage.records)
+			}
+		})
+	}
+}
+
+func TestRouterAllowsCallsUnderQuota(t *testing.T) {
+	now := time.Date(2026, time.May, 14, 9, 30, 0, 0, time.UTC)
+	client := &usageStubClient{}
+	usage := &stubUsageStore{
+		quota: store.LLMUsageQuota{DailyTokenLimit: 1000, MonthlyTokenLimit: 5000},
+		used:  map[time.Time]int64{time.Date(2026, time.May, 14, 0, 0, 0, 0, time.UTC): 999},
+	}
+	router := newUsageTestRouter(t, client, usage, now)
+
+	if _, err := router.Complete(context.Background(), store.Tenant{ID: "tenant-a"}, Request{}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if client.calls != 1 {
+		t.Fatalf("client calls = %d, want 1", client.calls)
+	}
+}
@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/internal/webhook"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// send delivers msg through channel.
+func (s *Service) send(ctx context.Context, channel store.NotificationChannel, token string, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	switch channel.Type {
+	case store.NotificationChannelEmail:
+		return s.sendEmail(ctx, channel.Target, msg)
+	case store.NotificationChannelNtfy:
+		return s.sendNtfy(ctx, channel.Target, token, msg)
+	case store.NotificationChannelGotify:
+		return s.sendGotify(ctx, channel.Target, token, msg)
+	case store.NotificationChannelTelegram:
+		return s.sendTelegram(ctx, channel.Target, token, msg)
+	case store.NotificationChannelWebhook:
+		return s.sendWebhook(ctx, channel.Target, token, msg)
+	default:
+		return fmt.Errorf("unknown channel type %q", channel.Type)
+	}
+}
+
+// sendNtfy publishes msg to an ntfy topic URL.
+func (s *Service) sendNtfy(ctx context.Context, topicURL, token string, msg Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, topicURL, strings.NewReader(msg.Body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", msg.Title))
+	req.Header.Set("Tags", string(msg.Kind))
+	if msg.Urgent {
+		req.Header.Set("Priority", "high")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return s.do(req, "ntfy")
+}
+
+// sendGotify posts msg to the message endpoint of a Gotify server.
+func (s *Service) sendGotify(ctx context.Context, serverURL, token string, msg Message) error {
+	priority := 5
+	if msg.Urgent {
+		priority = 8
+	}
+	body, err := json.Marshal(map[string]any{"title": msg.Title, "message": msg.Body, "priority": priority})
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+	endpoint := strings.TrimRight(serverURL, "/") + "/message"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", token)
+	return s.do(req, "gotify")
+}
+
+// sendTelegram sends msg to a chat through a Telegram bot.
+func (s *Service) sendTelegram(ctx context.Context, chatID, token string, msg Message) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  chatID,
+		"text":                     msg.Title + "\n\n" + msg.Body,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+	endpoint := strings.TrimRight(s.telegramAPI, "/") + "/bot" + token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		// The endpoint holds the bot token, so it is left out of errors.
+		return fmt.Errorf("building request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return s.do(req, "telegram")
+}
+
+// sendWebhook posts msg as JSON, signed like webhook deliveries when the
+// channel has a token.
+func (s *Service) sendWebhook(ctx context.Context, endpoint, token string, msg Message) error {
+	body, err := json.Marshal(struct {
+		ID    string                 `json:"id,omitempty"`
+		Kind  store.NotificationKind `json:"kind"`
+		Title string                 `json:"title"`
+		Body  string                 `json:"body"`
+		Data  json.RawMessage        `json:"data,omitempty"`
+	}{msg.ID, msg.Kind, msg.Title, msg.Body, msg.Data})
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, "notification."+string(msg.Kind))
+	if msg.ID != "" {
+		req.Header.Set(webhook.HeaderDelivery, msg.ID)
+	}
+	if token != "" {
+		timestamp := strconv.FormatInt(s.now().Unix(), 10)
+		req.Header.Set(webhook.HeaderTimestamp, timestamp)
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(token, timestamp, body))
+	}
+	return s.do(req, "endpoint")
+}
+
+// do sends req and fails unless the service answers with a 2xx status.
+func (s *Service) do(req *http.Request, service string) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Request URLs can hold tokens, so only the cause is reported.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("sending to %s: %w", service, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if reason := responseReason(detail); reason != "" {
+			return fmt.Errorf("%s responded %s: %s", service, resp.Status, reason)
+		}
+		return fmt.Errorf("%s responded %s", service, resp.Status)
+	}
+	return nil
+}
+
+// responseReason extracts the error description from the JSON error
+// bodies of ntfy, Gotify and Telegram.
+func responseReason(body []byte) string {
+	var reply struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"errorDescription"`
+		Description      string `json:"description"`
+	}
+	if json.Unmarshal(body, &reply) != nil {
+		return ""
+	}
+	for _, reason := range []string{reply.Description, reply.ErrorDescription, reply.Error} {
+		if reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// sendEmail mails msg to address through the configured SMTP server.
+func (s *Service) sendEmail(ctx context.Context, address string, msg Message) error {
+	cfg := s.smtp
+	if cfg.Host == "" {
+		return fmt.Errorf("no SMTP server is configured")
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if cfg.Security == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("greeting SMTP server: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if cfg.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("authenticating with SMTP server: %w", err)
+		}
+	}
+	sender := cfg.From
+	if parsed, err := mail.ParseAddress(cfg.From); err == nil {
+		sender = parsed.Address
+	}
+	if err := client.Mail(sender); err != nil {
+		return fmt.Errorf("SMTP MAIL: %w", err)
+	}
+	if err := client.Rcpt(address); err != nil {
+		return fmt.Errorf("SMTP RCPT: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(s.emailMessage(address, msg)); err != nil {
+		return fmt.Errorf("writing email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	return client.Quit()
+}
+
+// emailMessage formats msg as a plain text email to address.
+func (s *Service) emailMessage(address string, msg Message) []byte {
+	var buf bytes.Buffer
+	header := func(name, value string) { fmt.Fprintf(&buf, "%s: %s\r\n", name, value) }
+	header("From", s.smtp.From)
+	header("To", address)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Title))
+	header("Date", s.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	header("Auto-Submitted", "auto-generated")
+	buf.WriteString("\r\n")
+	body := quotedprintable.NewWriter(&buf)
+	_, _ = body.Write([]byte(msg.Body))
+	_ = body.Close()
+	return buf.Bytes()
+}
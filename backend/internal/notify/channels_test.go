@@ -0,0 +1,315 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/egress"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/internal/webhook"
+	"github.com/ArionMiles/expensor/backend/pkg/config"
+)
+
+type stubRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// stubServer records the requests it receives and answers them with status
+// and body.
+func stubServer(t *testing.T, status int, body string) (*httptest.Server, <-chan stubRequest) {
+	t.Helper()
+	requests := make(chan stubRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		requests <- stubRequest{method: r.Method, path: r.URL.Path, header: r.Header.Clone(), body: payload}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+var testMessage = Message{
+	ID:     "delivery-a",
+	Kind:   store.NotificationScanNeedsAuth,
+	Title:  "Scanning stopped: gmail needs to be authorized again",
+	Body:   "Reconnect it from the setup page to resume scanning.",
+	Urgent: true,
+	Data:   json.RawMessage(`{"reader":"gmail"}`),
+}
+
+func TestSendNtfy(t *testing.T) {
+	server, requests := stubServer(t, http.StatusOK, `{}`)
+	channel := store.NotificationChannel{Type: store.NotificationChannelNtfy, Target: server.URL + "/expensor-alerts"}
+
+	if err := newTestService(t, &fakeStore{}).send(context.Background(), channel, "tk_secret", testMessage); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	got := <-requests
+	if got.method != http.MethodPost || got.path != "/expensor-alerts" || string(got.body) != testMessage.Body {
+		t.Fatalf("request = %s %s %q", got.method, got.path, got.body)
+	}
+	if got.header.Get("Title") != testMessage.Title || got.header.Get("Priority") != "high" ||
+		got.header.Get("Tags") != "scan_needs_auth" || got.header.Get("Authorization") != "Bearer tk_secret" {
+		t.Fatalf("headers = %v", got.header)
+	}
+}
+
+func TestSendRefusesInternalAddresses(t *testing.T) {
+	server, requests := stubServer(t, http.StatusOK, `{}`)
+	channel := store.NotificationChannel{Type: store.NotificationChannelGotify, Target: server.URL}
+	s, err := New(Dependencies{Store: &fakeStore{}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = s.send(context.Background(), channel, "app-token", testMessage)
+
+	if !errors.Is(err, egress.ErrBlocked) {
+		t.Fatalf("send() error = %v, want the loopback server refused", err)
+	}
+	select {
+	case <-requests:
+		t.Fatal("request reached the loopback server")
+	default:
+	}
+}
+
+func TestSendGotify(t *testing.T) {
+	server, requests := stubServer(t, http.StatusOK, `{"id":1}`)
+	channel := store.NotificationChannel{Type: store.NotificationChannelGotify, Target: server.URL + "/"}
+
+	if err := newTestService(t, &fakeStore{}).send(context.Background(), channel, "app-token", testMessage); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	got := <-requests
+	if got.path != "/message" || got.header.Get("X-Gotify-Key") != "app-token" {
+		t.Fatalf("request = %s %v", got.path, got.header)
+	}
+	var body struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+	if err := json.Unmarshal(got.body, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Title != testMessage.Title || body.Message != testMessage.Body || body.Priority != 8 {
+		t.Fatalf("body = %s", got.body)
+	}
+}
+
+func TestSendTelegram(t *testing.T) {
+	server, requests := stubServer(t, http.StatusOK, `{"ok":true}`)
+	s, err := New(Dependencies{Store: &fakeStore{}, TelegramAPIURL: server.URL, AllowedNetworks: loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := store.NotificationChannel{Type: store.NotificationChannelTelegram, Target: "-1001234"}
+
+	if err := s.send(context.Background(), channel, "123:bot-token", testMessage); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	got := <-requests
+	if got.path != "/bot123:bot-token/sendMessage" {
+		t.Fatalf("path = %s", got.path)
+	}
+	var body struct {
+		ChatID string `json:"chat_id"`
+		Text   string `json:"text"`
+	}
+	if err := json.Unmarshal(got.body, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.ChatID != "-1001234" || body.Text != testMessage.Title+"\n\n"+testMessage.Body {
+		t.Fatalf("body = %s", got.body)
+	}
+}
+
+func TestSendTelegramErrorsLeaveOutBotToken(t *testing.T) {
+	server, _ := stubServer(t, http.StatusUnauthorized, `{"ok":false,"error_code":401,"description":"Unauthorized"}`)
+	s, err := New(Dependencies{Store: &fakeStore{}, TelegramAPIURL: server.URL, AllowedNetworks: loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := store.NotificationChannel{Type: store.NotificationChannelTelegram, Target: "@expensor"}
+
+	err = s.send(context.Background(), channel, "123:bot-token", testMessage)
+	if err == nil || err.Error() != "telegram responded 401 Unauthorized: Unauthorized" {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	server.Close()
+	err = s.send(context.Background(), channel, "123:bot-token", testMessage)
+	if err == nil || strings.Contains(err.Error(), "bot-token") {
+		t.Fatalf("send() to a closed server error = %v, want one without the token", err)
+	}
+}
+
+func TestSendWebhookSignsWithToken(t *testing.T) {
+	server, requests := stubServer(t, http.StatusNoContent, "")
+	channel := store.NotificationChannel{Type: store.NotificationChannelWebhook, Target: server.URL}
+
+	if err := newTestService(t, &fakeStore{}).send(context.Background(), channel, "shh", testMessage); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	got := <-requests
+	timestamp := got.header.Get(webhook.HeaderTimestamp)
+	if timestamp != strconv.FormatInt(now.Unix(), 10) || got.header.Get(webhook.HeaderSignature) != webhook.Sign("shh", timestamp, got.body) {
+		t.Fatalf("headers = %v", got.header)
+	}
+	if got.header.Get(webhook.HeaderEvent) != "notification.scan_needs_auth" || got.header.Get(webhook.HeaderDelivery) != "delivery-a" {
+		t.Fatalf("headers = %v", got.header)
+	}
+	var body struct {
+		ID   string          `json:"id"`
+		Kind string          `json:"kind"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(got.body, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.ID != "delivery-a" || body.Kind != "scan_needs_auth" || string(body.Data) != `{"reader":"gmail"}` {
+		t.Fatalf("body = %s", got.body)
+	}
+}
+
+// smtpStub is a minimal SMTP server that accepts one message.
+type smtpStub struct {
+	addr     string
+	auth     chan string
+	envelope chan []string
+	data     chan string
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	stub := &smtpStub{addr: listener.Addr().String(), auth: make(chan string, 1), envelope: make(chan []string, 1), data: make(chan string, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		stub.serve(bufio.NewReader(conn), conn)
+	}()
+	return stub
+}
+
+func (s *smtpStub) serve(r *bufio.Reader, w io.Writer) {
+	reply := func(line string) { _, _ = io.WriteString(w, line+"\r\n") }
+	reply("220 localhost ESMTP stub")
+	var envelope []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			s.auth <- string(decoded)
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL", "RCPT":
+			envelope = append(envelope, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil || dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.envelope <- envelope
+			s.data <- data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	stub := newSMTPStub(t)
+	host, port, _ := net.SplitHostPort(stub.addr)
+	portNumber, _ := strconv.Atoi(port)
+	s := newTestService(t, &fakeStore{})
+	s.smtp = config.SMTP{
+		Host: host, Port: portNumber, Username: "mailer", Password: "hunter2",
+		From: "Expensor <expensor@example.com>", Security: "none",
+	}
+	channel := store.NotificationChannel{Type: store.NotificationChannelEmail, Target: "alex@example.com"}
+	msg := testMessage
+	msg.Body = "Spent ₹1,200 at Café Coffee Day."
+
+	if err := s.send(context.Background(), channel, "", msg); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	if auth := <-stub.auth; auth != "\x00mailer\x00hunter2" {
+		t.Fatalf("auth = %q", auth)
+	}
+	if envelope := <-stub.envelope; strings.Join(envelope, "|") != "MAIL FROM:<expensor@example.com>|RCPT TO:<alex@example.com>" {
+		t.Fatalf("envelope = %q", envelope)
+	}
+	data := <-stub.data
+	for _, want := range []string{
+		"From: Expensor <expensor@example.com>\r\n",
+		"To: alex@example.com\r\n",
+		"Subject: " + testMessage.Title + "\r\n",
+		"Content-Transfer-Encoding: quoted-printable\r\n",
+		"\r\n\r\nSpent =E2=82=B91,200 at Caf=C3=A9 Coffee Day.",
+	} {
+		if !strings.Contains(data, want) {
+			t.Fatalf("message = %q, want it to contain %q", data, want)
+		}
+	}
+}
+
+func TestSendEmailRequiresSTARTTLSWhenConfigured(t *testing.T) {
+	stub := newSMTPStub(t)
+	host, port, _ := net.SplitHostPort(stub.addr)
+	portNumber, _ := strconv.Atoi(port)
+	s := newTestService(t, &fakeStore{})
+	s.smtp = config.SMTP{Host: host, Port: portNumber, From: "expensor@example.com", Security: "starttls"}
+	channel := store.NotificationChannel{Type: store.NotificationChannelEmail, Target: "alex@example.com"}
+
+	err := s.send(context.Background(), channel, "", testMessage)
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("send() error = %v, want missing STARTTLS", err)
+	}
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const digestDateLayout = "2006-01-02"
+
+// QueueDigests queues the weekly digest of every subscription whose
+// digest came due since the last one, and reports how many it queued.
+//
+// A digest is due at its hour on its weekday in the tenant's timezone and
+// covers the seven days before the day it is due. After downtime only the
+// most recent digest that came due is sent.
+func (s *Service) QueueDigests(ctx context.Context) (int, error) {
+	const op = "notify.queue_digests"
+
+	subscriptions, err := s.store.ListDigestSubscriptions(ctx)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+	now := s.now()
+	queued := 0
+	for _, sub := range subscriptions {
+		if ctx.Err() != nil {
+			return queued, ctx.Err()
+		}
+		due := lastDue(now.In(tenantLocation(sub.Timezone)), sub.Weekday, sub.Hour)
+		if !sub.LastDigestAt.Before(due) {
+			continue
+		}
+		tenant := store.Tenant{ID: sub.TenantID}
+		to := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, due.Location())
+		from := to.AddDate(0, 0, -7)
+		digest, err := s.store.GetSpendDigest(ctx, tenant, from, to)
+		if err != nil {
+			s.logger.Warn("failed to summarize spending for digest", "tenant_id", sub.TenantID, "user_id", sub.UserID, "error", err)
+			continue
+		}
+		digest.From = from.Format(digestDateLayout)
+		digest.To = to.AddDate(0, 0, -1).Format(digestDateLayout)
+		ok, err := s.store.QueueDigest(ctx, tenant, sub.UserID, due, *digest)
+		if err != nil {
+			s.logger.Warn("failed to queue digest", "tenant_id", sub.TenantID, "user_id", sub.UserID, "error", err)
+			continue
+		}
+		if ok {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// lastDue returns the latest instant at or before now that falls on
+// weekday at hour, in now's location.
+func lastDue(now time.Time, weekday time.Weekday, hour int) time.Time {
+	daysBack := (int(now.Weekday()) - int(weekday) + 7) % 7
+	due := time.Date(now.Year(), now.Month(), now.Day()-daysBack, hour, 0, 0, 0, now.Location())
+	if due.After(now) {
+		due = time.Date(due.Year(), due.Month(), due.Day()-7, hour, 0, 0, 0, now.Location())
+	}
+	return due
+}
+
+// tenantLocation loads a tenant's timezone preference, falling back to UTC
+// when it is unset or unknown.
+func tenantLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
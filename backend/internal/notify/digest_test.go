@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+func TestQueueDigestsFollowsTenantTimezone(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	// now is Tuesday 17:30 in Kolkata and Tuesday 05:00 in Los Angeles.
+	mondayDue := time.Date(2026, 3, 30, 9, 0, 0, 0, kolkata)
+	st := &fakeStore{subscriptions: []store.DigestSubscription{
+		{TenantID: "tenant-a", UserID: "due", Timezone: "Asia/Kolkata", Weekday: time.Monday, Hour: 9, LastDigestAt: mondayDue.AddDate(0, 0, -7)},
+		{TenantID: "tenant-a", UserID: "sent", Timezone: "Asia/Kolkata", Weekday: time.Monday, Hour: 9, LastDigestAt: mondayDue},
+		{TenantID: "tenant-b", UserID: "later-today", Timezone: "America/Los_Angeles", Weekday: time.Tuesday, Hour: 9,
+			LastDigestAt: now.AddDate(0, 0, -6)},
+	}}
+
+	queued, err := newTestService(t, st).QueueDigests(context.Background())
+
+	if err != nil || queued != 1 {
+		t.Fatalf("QueueDigests() = %d, %v; want 1, nil", queued, err)
+	}
+	got := st.queued[0]
+	if got.userID != "due" || !got.dueAt.Equal(mondayDue) {
+		t.Fatalf("queued %q due %v, want due at %v", got.userID, got.dueAt, mondayDue)
+	}
+	if got.digest.From != "2026-03-23" || got.digest.To != "2026-03-29" || got.digest.Total != 1250 {
+		t.Fatalf("digest = %#v", got.digest)
+	}
+	from, to := st.digestRanges[0][0], st.digestRanges[0][1]
+	if !from.Equal(time.Date(2026, 3, 23, 0, 0, 0, 0, kolkata)) || !to.Equal(time.Date(2026, 3, 30, 0, 0, 0, 0, kolkata)) {
+		t.Fatalf("digest range = %v to %v", from, to)
+	}
+}
+
+func TestLastDue(t *testing.T) {
+	tests := []struct {
+		name    string
+		now     time.Time
+		weekday time.Weekday
+		hour    int
+		want    time.Time
+	}{
+		{
+			name:    "earlier today",
+			now:     time.Date(2026, 3, 30, 9, 0, 0, 0, time.UTC),
+			weekday: time.Monday, hour: 9,
+			want: time.Date(2026, 3, 30, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "later today",
+			now:     time.Date(2026, 3, 30, 8, 59, 0, 0, time.UTC),
+			weekday: time.Monday, hour: 9,
+			want: time.Date(2026, 3, 23, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "earlier this week",
+			now:     time.Date(2026, 4, 2, 0, 30, 0, 0, time.UTC),
+			weekday: time.Sunday, hour: 20,
+			want: time.Date(2026, 3, 29, 20, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastDue(tt.now, tt.weekday, tt.hour); !got.Equal(tt.want) {
+				t.Fatalf("lastDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantLocationFallsBackToUTC(t *testing.T) {
+	for _, name := range []string{"", "Not/AZone"} {
+		if loc := tenantLocation(name); loc != time.UTC {
+			t.Fatalf("tenantLocation(%q) = %v, want UTC", name, loc)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// kindTest is the kind of test notifications, which are sent directly and
+// never queued.
+const kindTest store.NotificationKind = "test"
+
+// errRender marks notifications whose template cannot be rendered.
+var errRender = errors.E("notify.render", "rendering notification")
+
+// Message is a rendered notification.
+type Message struct {
+	// ID identifies the delivery; it is empty for test notifications.
+	ID    string
+	Kind  store.NotificationKind
+	Title string
+	Body  string
+	// Urgent is set for alerts, which channels that support it deliver with
+	// a raised priority.
+	Urgent bool
+	// Data is the payload the message was rendered from.
+	Data json.RawMessage
+}
+
+// largeTransaction is the payload of large transaction alerts.
+type largeTransaction struct {
+	ID        string    `json:"id"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Merchant  string    `json:"merchant"`
+	Category  string    `json:"category"`
+	Source    string    `json:"source"`
+	Timestamp time.Time `json:"timestamp"`
+	Threshold float64   `json:"threshold"`
+}
+
+// scanAlert is the payload of scanning failure alerts.
+type scanAlert struct {
+	Reader  string `json:"reader"`
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+type messageTemplate struct {
+	title  *template.Template
+	body   *template.Template
+	urgent bool
+	// data decodes a payload into what the templates are executed with.
+	data func(json.RawMessage) (any, error)
+}
+
+var templateFuncs = template.FuncMap{
+	"money": func(amount float64, currency string) string {
+		return strings.TrimSpace(fmt.Sprintf("%s %.2f", currency, amount))
+	},
+	"date": func(t time.Time) string { return t.Format("2 Jan 2006") },
+	"change": func(total, previous float64) string {
+		if previous == 0 {
+			return ""
+		}
+		return fmt.Sprintf(" (%+.0f%% on the week before)", (total-previous)/previous*100)
+	},
+}
+
+func decodeAs[T any](payload json.RawMessage) (any, error) {
+	var data T
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func newTemplate(kind store.NotificationKind, title, body string, urgent bool, data func(json.RawMessage) (any, error)) messageTemplate {
+	return messageTemplate{
+		title:  template.Must(template.New(string(kind) + ".title").Funcs(templateFuncs).Parse(title)),
+		body:   template.Must(template.New(string(kind) + ".body").Funcs(templateFuncs).Parse(body)),
+		urgent: urgent,
+		data:   data,
+	}
+}
+
+var templates = map[store.NotificationKind]messageTemplate{
+	store.NotificationWeeklyDigest: newTemplate(store.NotificationWeeklyDigest,
+		`Your spending from {{.From}} to {{.To}}`,
+		`You spent {{money .Total .Currency}} across {{.Count}} transaction{{if ne .Count 1}}s{{end}}{{change .Total .Previous}}.
+{{- if .Top}}
+
+Top categories:
+{{- range .Top}}
+  {{.Category}}: {{money .Amount $.Currency}}
+{{- end}}
+{{- end}}
+{{- if .Largest}}
+
+Largest transactions:
+{{- range .Largest}}
+  {{.Merchant}}: {{money .Amount .Currency}} on {{date .Timestamp}}
+{{- end}}
+{{- end}}`,
+		false, decodeAs[store.SpendDigest]),
+	store.NotificationLargeTransaction: newTemplate(store.NotificationLargeTransaction,
+		`Large transaction: {{money .Amount .Currency}}{{with .Merchant}} at {{.}}{{end}}`,
+		`{{money .Amount .Currency}}{{with .Merchant}} at {{.}}{{end}} on {{date .Timestamp}}{{with .Source}}, from {{.}}{{end}}.
+{{- with .Category}}
+Category: {{.}}{{end}}
+
+You are alerted about transactions of {{money .Threshold ""}} or more.`,
+		true, decodeAs[largeTransaction]),
+	store.NotificationScanNeedsAuth: newTemplate(store.NotificationScanNeedsAuth,
+		`Scanning stopped: {{.Reader}} needs to be authorized again`,
+		`Expensor stopped scanning {{.Reader}} because its authorization is no longer valid.
+{{- with .Message}}
+
+{{.}}{{end}}
+
+Reconnect it from the setup page to resume scanning.`,
+		true, decodeAs[scanAlert]),
+	kindTest: newTemplate(kindTest,
+		`Expensor test notification`,
+		`This channel is set up to receive Expensor notifications.`,
+		false, func(json.RawMessage) (any, error) { return nil, nil }),
+}
+
+// render renders the notification of the given kind from its payload.
+func render(kind store.NotificationKind, payload json.RawMessage) (Message, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return Message{}, errors.E(errRender, fmt.Sprintf("unknown notification kind %q", kind))
+	}
+	data, err := tmpl.data(payload)
+	if err != nil {
+		return Message{}, errors.E(errRender, "decoding payload", err)
+	}
+	var title, body bytes.Buffer
+	if err := tmpl.title.Execute(&title, data); err != nil {
+		return Message{}, errors.E(errRender, err)
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return Message{}, errors.E(errRender, err)
+	}
+	return Message{Kind: kind, Title: title.String(), Body: body.String(), Urgent: tmpl.urgent, Data: payload}, nil
+}
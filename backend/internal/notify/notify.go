@@ -0,0 +1,227 @@
+// Package notify sends users' notifications through their channels. The
+// store queues alerts as the transactions and scanning failures they are
+// about are committed; the Service queues weekly digests on each user's
+// schedule, renders every notification from its template and sends it,
+// retrying failed sends with exponential backoff.
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/egress"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/config"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	pollInterval   = 15 * time.Second
+	digestInterval = 5 * time.Minute
+	claimBatch     = 20
+	// claimLease outlasts a send, so a delivery is only claimed again when
+	// the process sending it went away.
+	claimLease  = 2 * time.Minute
+	sendTimeout = 30 * time.Second
+
+	// MaxAttempts is how many times a notification is sent before it is
+	// marked failed. Retries wait retryBaseDelay, doubling each time, so the
+	// last attempt is made about half an hour after the first.
+	MaxAttempts    = 5
+	retryBaseDelay = 2 * time.Minute
+
+	deliveryRetention = 30 * 24 * time.Hour
+	pruneInterval     = time.Hour
+)
+
+// Dependencies configures a Service.
+type Dependencies struct {
+	Store  store.NotificationStore
+	Logger *slog.Logger
+	Now    func() time.Time
+	// Client sends to HTTP channels. It defaults to a client that times out
+	// after sendTimeout and refuses internal addresses outside
+	// AllowedNetworks.
+	Client *http.Client
+	// AllowedNetworks are internal networks HTTP channels may send to.
+	AllowedNetworks []netip.Prefix
+	// SMTP is the mail server of email channels; they are unavailable while
+	// its Host is empty.
+	SMTP config.SMTP
+	// TelegramAPIURL is the Bot API server of Telegram channels. It
+	// defaults to https://api.telegram.org.
+	TelegramAPIURL string
+}
+
+// Service queues weekly digests and sends due notifications.
+type Service struct {
+	store       store.NotificationStore
+	logger      *slog.Logger
+	now         func() time.Time
+	client      *http.Client
+	smtp        config.SMTP
+	telegramAPI string
+}
+
+// New constructs a Service without starting it.
+func New(deps Dependencies) (*Service, error) {
+	if deps.Store == nil {
+		return nil, errors.E("notify.new", errors.FailedPrecondition, "notification store is required")
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	client := deps.Client
+	if client == nil {
+		client = egress.NewClient(sendTimeout, deps.AllowedNetworks)
+	}
+	telegramAPI := deps.TelegramAPIURL
+	if telegramAPI == "" {
+		telegramAPI = "https://api.telegram.org"
+	}
+	return &Service{
+		store:       deps.Store,
+		logger:      logger.With("component", "notify"),
+		now:         now,
+		client:      client,
+		smtp:        deps.SMTP,
+		telegramAPI: telegramAPI,
+	}, nil
+}
+
+// Run blocks while sending due notifications every pollInterval, queueing
+// due digests every digestInterval and pruning sent notifications every
+// pruneInterval.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var digested, pruned time.Time
+	for {
+		if s.now().Sub(digested) >= digestInterval {
+			digested = s.now()
+			if _, err := s.QueueDigests(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Warn("failed to queue weekly digests", "error", err)
+			}
+		}
+		if _, err := s.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn("failed to send notifications", "error", err)
+		}
+		if s.now().Sub(pruned) >= pruneInterval {
+			pruned = s.now()
+			if removed, err := s.store.PruneNotificationDeliveries(ctx, pruned.Add(-deliveryRetention)); err != nil {
+				s.logger.Warn("failed to prune notifications", "error", err)
+			} else if removed > 0 {
+				s.logger.Info("pruned notifications", "removed", removed)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends the notifications that are due, claimed in batches
+// until none are left, and reports how many it sent.
+func (s *Service) DispatchDue(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		dispatches, err := s.store.ClaimNotificationDeliveries(ctx, claimBatch, claimLease)
+		if err != nil {
+			return sent, errors.E("notify.dispatch", err)
+		}
+		var wg sync.WaitGroup
+		for _, dispatch := range dispatches {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.dispatch(ctx, dispatch)
+			}()
+		}
+		wg.Wait()
+		sent += len(dispatches)
+		if len(dispatches) < claimBatch {
+			break
+		}
+	}
+	return sent, nil
+}
+
+// dispatch makes one attempt at a claimed delivery and records its result.
+func (s *Service) dispatch(ctx context.Context, dispatch store.NotificationDispatch) {
+	delivery := dispatch.Delivery
+	attempt := store.NotificationAttempt{AttemptedAt: s.now()}
+	msg, err := render(delivery.Kind, delivery.Payload)
+	if err == nil {
+		msg.ID = delivery.ID
+		err = s.send(ctx, dispatch.Channel, dispatch.Token, msg)
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+		// A notification that cannot be rendered never will be, so it is
+		// not retried.
+		if !errors.Is(err, errRender) && delivery.Attempts < MaxAttempts {
+			retryAt := attempt.AttemptedAt.Add(RetryDelay(delivery.Attempts))
+			attempt.RetryAt = &retryAt
+		}
+	} else {
+		attempt.Delivered = true
+	}
+	if err := s.store.CompleteNotificationDelivery(context.WithoutCancel(ctx), delivery.ID, attempt); err != nil {
+		s.logger.Warn("failed to record notification", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+	if !attempt.Delivered {
+		s.logger.Info("notification failed",
+			"delivery_id", delivery.ID,
+			"channel_id", delivery.ChannelID,
+			"kind", delivery.Kind,
+			"attempt", delivery.Attempts,
+			"retrying", attempt.RetryAt != nil,
+			"error", attempt.Error,
+		)
+	}
+}
+
+// Available reports why channels of the given type cannot send, or nil
+// when they can.
+func (s *Service) Available(channelType store.NotificationChannelType) error {
+	if channelType == store.NotificationChannelEmail && s.smtp.Host == "" {
+		return errors.E("notify.available", errors.FailedPrecondition,
+			errors.User("email notifications need an SMTP server; set EXPENSOR_SMTP_HOST to enable them"))
+	}
+	return nil
+}
+
+// SendTest sends a test notification through channel right away.
+func (s *Service) SendTest(ctx context.Context, channel store.NotificationChannel, token string) error {
+	const op = "notify.send_test"
+
+	if err := s.Available(channel.Type); err != nil {
+		return errors.E(op, err)
+	}
+	msg, err := render(kindTest, nil)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if err := s.send(ctx, channel, token, msg); err != nil {
+		return errors.E(op, errors.BadGateway, errors.User("sending the test notification failed: "+err.Error()), err)
+	}
+	return nil
+}
+
+// RetryDelay is how long to wait after the given failed attempt, counted
+// from one, before the next.
+func RetryDelay(attempt int) time.Duration {
+	return retryBaseDelay << max(min(attempt-1, MaxAttempts), 0)
+}
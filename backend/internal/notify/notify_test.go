@@ -0,0 +1,226 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/config"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type fakeStore struct {
+	store.NotificationStore
+	mu            sync.Mutex
+	due           []store.NotificationDispatch
+	completed     map[string]store.NotificationAttempt
+	subscriptions []store.DigestSubscription
+	digestRanges  [][2]time.Time
+	queued        []queuedDigest
+}
+
+type queuedDigest struct {
+	userID string
+	dueAt  time.Time
+	digest store.SpendDigest
+}
+
+func (s *fakeStore) ClaimNotificationDeliveries(_ context.Context, limit int, _ time.Duration) ([]store.NotificationDispatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(limit, len(s.due))
+	claimed := s.due[:n]
+	s.due = s.due[n:]
+	return claimed, nil
+}
+
+func (s *fakeStore) CompleteNotificationDelivery(_ context.Context, id string, attempt store.NotificationAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.completed == nil {
+		s.completed = map[string]store.NotificationAttempt{}
+	}
+	s.completed[id] = attempt
+	return nil
+}
+
+func (s *fakeStore) ListDigestSubscriptions(context.Context) ([]store.DigestSubscription, error) {
+	return s.subscriptions, nil
+}
+
+func (s *fakeStore) GetSpendDigest(_ context.Context, _ store.Tenant, from, to time.Time) (*store.SpendDigest, error) {
+	s.digestRanges = append(s.digestRanges, [2]time.Time{from, to})
+	return &store.SpendDigest{Currency: "INR", Total: 1250, Count: 3}, nil
+}
+
+func (s *fakeStore) QueueDigest(_ context.Context, _ store.Tenant, userID string, dueAt time.Time, digest store.SpendDigest) (bool, error) {
+	s.queued = append(s.queued, queuedDigest{userID: userID, dueAt: dueAt, digest: digest})
+	return true, nil
+}
+
+var now = time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+// loopback allows the test servers, which listen on it.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func newTestService(t *testing.T, st *fakeStore) *Service {
+	t.Helper()
+	s, err := New(Dependencies{Store: st, Now: func() time.Time { return now }, AllowedNetworks: loopback})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return s
+}
+
+func ntfyDispatch(url, id string, kind store.NotificationKind, payload string, attempts int) store.NotificationDispatch {
+	return store.NotificationDispatch{
+		Channel: store.NotificationChannel{ID: "channel-a", Type: store.NotificationChannelNtfy, Target: url, Enabled: true},
+		Delivery: store.NotificationDelivery{
+			ID:        id,
+			ChannelID: "channel-a",
+			TenantID:  "tenant-a",
+			UserID:    "user-a",
+			Kind:      kind,
+			Payload:   json.RawMessage(payload),
+			Attempts:  attempts,
+		},
+	}
+}
+
+const largePayload = `{"id":"txn-a","amount":25000,"currency":"INR","merchant":"Croma","category":"Electronics",` +
+	`"source":"HDFC Credit Card","timestamp":"2026-03-30T18:15:00+00:00","threshold":10000}`
+
+func TestDispatchRendersAndDelivers(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   string
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: string(body)}
+	}))
+	defer server.Close()
+	st := &fakeStore{due: []store.NotificationDispatch{
+		ntfyDispatch(server.URL+"/expensor", "delivery-a", store.NotificationLargeTransaction, largePayload, 1),
+	}}
+
+	sent, err := newTestService(t, st).DispatchDue(context.Background())
+
+	if err != nil || sent != 1 {
+		t.Fatalf("DispatchDue() = %d, %v; want 1, nil", sent, err)
+	}
+	got := <-requests
+	if got.header.Get("Title") != "Large transaction: INR 25000.00 at Croma" || got.header.Get("Priority") != "high" {
+		t.Fatalf("headers = %v", got.header)
+	}
+	for _, want := range []string{"INR 25000.00 at Croma on 30 Mar 2026, from HDFC Credit Card.", "Category: Electronics", "10000.00 or more"} {
+		if !strings.Contains(got.body, want) {
+			t.Fatalf("body = %q, want it to contain %q", got.body, want)
+		}
+	}
+	attempt := st.completed["delivery-a"]
+	if !attempt.Delivered || attempt.RetryAt != nil || attempt.Error != "" {
+		t.Fatalf("attempt = %#v", attempt)
+	}
+}
+
+func TestDispatchRetriesWithBackoffThenGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"code":40301,"http":403,"error":"forbidden"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+	st := &fakeStore{due: []store.NotificationDispatch{
+		ntfyDispatch(server.URL+"/a", "first", store.NotificationScanNeedsAuth, `{"reader":"gmail"}`, 1),
+		ntfyDispatch(server.URL+"/a", "last", store.NotificationScanNeedsAuth, `{"reader":"gmail"}`, MaxAttempts),
+	}}
+
+	if _, err := newTestService(t, st).DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue() error = %v", err)
+	}
+
+	first := st.completed["first"]
+	if first.Delivered || first.RetryAt == nil || !first.RetryAt.Equal(now.Add(retryBaseDelay)) {
+		t.Fatalf("first attempt = %#v", first)
+	}
+	if !strings.Contains(first.Error, "403 Forbidden: forbidden") {
+		t.Fatalf("first error = %q", first.Error)
+	}
+	if last := st.completed["last"]; last.Delivered || last.RetryAt != nil {
+		t.Fatalf("last attempt = %#v, want failed without retry", last)
+	}
+}
+
+func TestDispatchDoesNotRetryUnrenderablePayloads(t *testing.T) {
+	st := &fakeStore{due: []store.NotificationDispatch{
+		ntfyDispatch("http://127.0.0.1:1/a", "delivery-a", store.NotificationWeeklyDigest, `"not a digest"`, 1),
+	}}
+
+	if _, err := newTestService(t, st).DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue() error = %v", err)
+	}
+
+	if attempt := st.completed["delivery-a"]; attempt.Delivered || attempt.RetryAt != nil || attempt.Error == "" {
+		t.Fatalf("attempt = %#v, want failed without retry", attempt)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	want := []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute}
+	for i, delay := range want {
+		if got := RetryDelay(i + 1); got != delay {
+			t.Fatalf("RetryDelay(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+}
+
+func TestRenderWeeklyDigest(t *testing.T) {
+	payload, err := json.Marshal(store.SpendDigest{
+		From: "2026-03-23", To: "2026-03-29", Currency: "INR", Total: 4500, Count: 6, Previous: 3000,
+		Top: []store.CategorySpend{{Category: "Food", Amount: 3000, Count: 4}, {Category: "Travel", Amount: 1500, Count: 2}},
+		Largest: []store.DigestTransaction{
+			{Merchant: "Swiggy", Amount: 1200, Currency: "INR", Timestamp: time.Date(2026, 3, 25, 20, 0, 0, 0, time.UTC)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := render(store.NotificationWeeklyDigest, payload)
+
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if msg.Title != "Your spending from 2026-03-23 to 2026-03-29" || msg.Urgent {
+		t.Fatalf("message = %#v", msg)
+	}
+	want := "You spent INR 4500.00 across 6 transactions (+50% on the week before).\n\n" +
+		"Top categories:\n  Food: INR 3000.00\n  Travel: INR 1500.00\n\n" +
+		"Largest transactions:\n  Swiggy: INR 1200.00 on 25 Mar 2026"
+	if msg.Body != want {
+		t.Fatalf("body = %q, want %q", msg.Body, want)
+	}
+}
+
+func TestEmailUnavailableWithoutSMTPServer(t *testing.T) {
+	s := newTestService(t, &fakeStore{})
+	if err := s.Available(store.NotificationChannelEmail); errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Fatalf("Available(email) = %v, want failed precondition", err)
+	}
+	if err := s.Available(store.NotificationChannelNtfy); err != nil {
+		t.Fatalf("Available(ntfy) = %v", err)
+	}
+
+	s.smtp = config.SMTP{Host: "smtp.example.com"}
+	if err := s.Available(store.NotificationChannelEmail); err != nil {
+		t.Fatalf("Available(email) with SMTP server = %v", err)
+	}
+}
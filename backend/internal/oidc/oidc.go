@@ -0,0 +1,335 @@
+// Package oidc signs users in through an OpenID Connect provider using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	discoveryTTL   = time.Hour
+	maxResponse    = 1 << 20
+	requestTimeout = 10 * time.Second
+)
+
+// ClaimMapping names the ID token claims Identity is read from.
+type ClaimMapping struct {
+	Email string
+	Name  string
+	// Role names a string or string-array claim. When empty, Identity.Admin
+	// is left nil and roles are managed in Expensor.
+	Role        string
+	AdminValues []string
+}
+
+// Config configures a Provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Claims       ClaimMapping
+	// Name labels the provider for users.
+	Name       string
+	HTTPClient *http.Client
+	Now        func() time.Time
+}
+
+// Identity is the user a provider vouched for.
+type Identity struct {
+	// Issuer and Subject identify the provider account.
+	Issuer      string
+	Subject     string
+	Email       string
+	DisplayName string
+	// EmailVerified reports whether the provider asserted, through the
+	// email_verified claim, that the user controls Email.
+	EmailVerified bool
+	// Admin reports the role claim mapping, or nil when no role claim is
+	// configured.
+	Admin *bool
+}
+
+// AuthRequest holds the per-attempt secrets of one sign-in. State and Nonce
+// travel through the browser; Verifier stays on the server.
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewAuthRequest returns fresh random values for one sign-in attempt.
+func NewAuthRequest() (AuthRequest, error) {
+	state, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+	return AuthRequest{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.E("oidc.random", errors.Internal, "generating random value", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID Connect issuer. Discovery and signing keys are
+// fetched lazily and cached, so a provider that is down at startup does not
+// stop Expensor from starting.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu           sync.Mutex
+	metadata     *discovery
+	discoveredAt time.Time
+	keys         *keySet
+}
+
+// New returns a Provider for cfg without contacting the issuer.
+func New(cfg Config) (*Provider, error) {
+	const op = "oidc.new"
+	cfg.Issuer = strings.TrimSpace(cfg.Issuer)
+	if cfg.Issuer == "" || strings.TrimSpace(cfg.ClientID) == "" {
+		return nil, errors.E(op, errors.InvalidArgument, "issuer and client ID are required")
+	}
+	if !slices.Contains(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+	if cfg.Claims.Email == "" {
+		cfg.Claims.Email = "email"
+	}
+	if cfg.Claims.Name == "" {
+		cfg.Claims.Name = "name"
+	}
+	if cfg.Name == "" {
+		cfg.Name = "SSO"
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &Provider{cfg: cfg, client: client, now: now}, nil
+}
+
+// Name labels the provider for users.
+func (p *Provider) Name() string { return p.cfg.Name }
+
+// AuthCodeURL returns the provider URL that starts sign-in for req.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURL string, req AuthRequest) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauthConfig(metadata, redirectURL).AuthCodeURL(req.State,
+		oauth2.S256ChallengeOption(req.Verifier), oauth2.SetAuthURLParam("nonce", req.Nonce)), nil
+}
+
+// Exchange redeems an authorization code, verifies the ID token against req
+// and returns the mapped identity. Claims missing from the ID token are
+// looked up at the userinfo endpoint.
+func (p *Provider) Exchange(ctx context.Context, redirectURL, code string, req AuthRequest) (Identity, error) {
+	const op = "oidc.exchange"
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	token, err := p.oauthConfig(metadata, redirectURL).Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client), code,
+		oauth2.VerifierOption(req.Verifier))
+	if err != nil {
+		return Identity{}, errors.E(op, errors.Unauthenticated, errors.User("The sign-in provider rejected the login."), err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return Identity{}, errors.E(op, errors.Unauthenticated, "token response has no id_token")
+	}
+	claims, err := p.verifyIDToken(ctx, metadata, rawIDToken, req.Nonce)
+	if err != nil {
+		return Identity{}, err
+	}
+	if p.needsUserinfo(claims) && metadata.UserinfoEndpoint != "" {
+		extra, err := p.userinfo(ctx, metadata.UserinfoEndpoint, token.AccessToken)
+		if err != nil {
+			return Identity{}, err
+		}
+		if extra.String("sub") != claims.String("sub") {
+			return Identity{}, errors.E(op, errors.Unauthenticated, "userinfo subject does not match the ID token")
+		}
+		for name, value := range extra {
+			if _, ok := claims[name]; !ok {
+				claims[name] = value
+			}
+		}
+	}
+	return p.identity(claims)
+}
+
+func (p *Provider) oauthConfig(metadata *discovery, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     oauth2.Endpoint{AuthURL: metadata.AuthorizationEndpoint, TokenURL: metadata.TokenEndpoint},
+		RedirectURL:  redirectURL,
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+func (p *Provider) needsUserinfo(claims Claims) bool {
+	if claims.String(p.cfg.Claims.Email) == "" || claims.String(p.cfg.Claims.Name) == "" {
+		return true
+	}
+	_, hasRole := claims[p.cfg.Claims.Role]
+	return p.cfg.Claims.Role != "" && !hasRole
+}
+
+func (p *Provider) identity(claims Claims) (Identity, error) {
+	const op = "oidc.identity"
+	verified, hasVerified := claims["email_verified"].(bool)
+	identity := Identity{
+		Issuer:        p.cfg.Issuer,
+		Subject:       claims.String("sub"),
+		Email:         strings.ToLower(strings.TrimSpace(claims.String(p.cfg.Claims.Email))),
+		DisplayName:   strings.TrimSpace(claims.String(p.cfg.Claims.Name)),
+		EmailVerified: verified,
+	}
+	if identity.Email == "" {
+		return Identity{}, errors.E(op, errors.Unauthenticated,
+			errors.User("The sign-in provider did not share an email address."), fmt.Sprintf("claim %q is empty", p.cfg.Claims.Email))
+	}
+	if hasVerified && !verified {
+		return Identity{}, errors.E(op, errors.Unauthenticated, errors.User("Your email address is not verified with the sign-in provider."))
+	}
+	if p.cfg.Claims.Role != "" {
+		admin := false
+		for _, value := range claims.Strings(p.cfg.Claims.Role) {
+			if slices.Contains(p.cfg.Claims.AdminValues, value) {
+				admin = true
+				break
+			}
+		}
+		identity.Admin = &admin
+	}
+	return identity, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	const op = "oidc.discover"
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil && p.now().Sub(p.discoveredAt) < discoveryTTL {
+		return p.metadata, nil
+	}
+
+	var metadata discovery
+	wellKnown := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, "", &metadata); err != nil {
+		if p.metadata != nil {
+			return p.metadata, nil
+		}
+		return nil, errors.E(op, errors.Unavailable, errors.User("The sign-in provider is unavailable."), err)
+	}
+	if metadata.Issuer != p.cfg.Issuer {
+		return nil, errors.E(op, errors.FailedPrecondition,
+			fmt.Sprintf("provider reports issuer %q, configured issuer is %q", metadata.Issuer, p.cfg.Issuer))
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.E(op, errors.FailedPrecondition, "provider metadata is missing required endpoints")
+	}
+	if p.metadata == nil || p.metadata.JWKSURI != metadata.JWKSURI {
+		p.keys = nil
+	}
+	p.metadata = &metadata
+	p.discoveredAt = p.now()
+	return p.metadata, nil
+}
+
+func (p *Provider) userinfo(ctx context.Context, endpoint, accessToken string) (Claims, error) {
+	var claims Claims
+	if err := p.getJSON(ctx, endpoint, accessToken, &claims); err != nil {
+		return nil, errors.E("oidc.userinfo", errors.Unauthenticated, err)
+	}
+	return claims, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, rawURL, bearer string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", rawURL, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// Claims are the decoded claims of an ID token or userinfo response.
+type Claims map[string]any
+
+// String returns a string claim, or "" when it is missing or not a string.
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Strings returns a string or string-array claim as a slice.
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		out := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
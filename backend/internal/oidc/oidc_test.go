@@ -0,0 +1,209 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/oidc/oidctest"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const redirectURL = "https://expensor.example/api/auth/oidc/callback"
+
+func newTestProvider(t *testing.T, srv *oidctest.Server, mutate func(*Config)) *Provider {
+	t.Helper()
+	cfg := Config{
+		Issuer:       srv.URL,
+		ClientID:     srv.ClientID,
+		ClientSecret: srv.ClientSecret,
+		Scopes:       []string{"email", "profile"},
+		Claims:       ClaimMapping{Role: "groups", AdminValues: []string{"expensor-admins"}},
+	}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	provider, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return provider
+}
+
+// login walks the browser side of the flow and exchanges the returned code.
+// exchangeReq lets tests tamper with the request presented at the callback.
+func login(t *testing.T, srv *oidctest.Server, provider *Provider, exchangeReq func(AuthRequest) AuthRequest) (Identity, error) {
+	t.Helper()
+	ctx := context.Background()
+	req, err := NewAuthRequest()
+	if err != nil {
+		t.Fatalf("NewAuthRequest: %v", err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, redirectURL, req)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	callback, err := srv.Login(authURL)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if got := callback.Query().Get("state"); got != req.State {
+		t.Fatalf("callback state = %q, want %q", got, req.State)
+	}
+	if exchangeReq != nil {
+		req = exchangeReq(req)
+	}
+	return provider.Exchange(ctx, redirectURL, callback.Query().Get("code"), req)
+}
+
+func TestExchangeMapsClaims(t *testing.T) {
+	srv := oidctest.NewServer(t, "expensor", "secret")
+	srv.SetClaims(map[string]any{
+		"sub":            "abc",
+		"email":          " Asha@Example.com ",
+		"email_verified": true,
+		"name":           "Asha",
+		"groups":         []string{"family", "expensor-admins"},
+	})
+
+	identity, err := login(t, srv, newTestProvider(t, srv, nil), nil)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Issuer != srv.URL || identity.Subject != "abc" || identity.Email != "asha@example.com" ||
+		identity.DisplayName != "Asha" || !identity.EmailVerified {
+		t.Fatalf("identity = %+v", identity)
+	}
+	if identity.Admin == nil || !*identity.Admin {
+		t.Fatalf("Admin = %v, want true", identity.Admin)
+	}
+
+	srv.SetClaims(map[string]any{"sub": "def", "email": "ravi@example.com", "name": "Ravi", "groups": "family"})
+	identity, err = login(t, srv, newTestProvider(t, srv, nil), nil)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Admin == nil || *identity.Admin {
+		t.Fatalf("Admin = %v, want false", identity.Admin)
+	}
+	if identity.EmailVerified {
+		t.Fatal("EmailVerified = true without the email_verified claim")
+	}
+
+	identity, err = login(t, srv, newTestProvider(t, srv, func(cfg *Config) { cfg.Claims = ClaimMapping{} }), nil)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Admin != nil {
+		t.Fatalf("Admin = %v without a role claim, want nil", *identity.Admin)
+	}
+}
+
+func TestExchangeCustomClaimsFromUserinfo(t *testing.T) {
+	srv := oidctest.NewServer(t, "expensor", "secret")
+	srv.SetClaims(map[string]any{"sub": "abc"})
+	srv.SetUserinfoClaims(map[string]any{"mail": "asha@example.com", "preferred_username": "asha", "roles": []string{"admin"}})
+	provider := newTestProvider(t, srv, func(cfg *Config) {
+		cfg.Claims = ClaimMapping{Email: "mail", Name: "preferred_username", Role: "roles", AdminValues: []string{"admin"}}
+	})
+
+	identity, err := login(t, srv, provider, nil)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Email != "asha@example.com" || identity.DisplayName != "asha" || identity.Admin == nil || !*identity.Admin {
+		t.Fatalf("identity = %+v", identity)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		mutate   func(*Config)
+		tamper   func(AuthRequest) AuthRequest
+		wantKind errors.Kind
+	}{
+		{
+			name:     "nonce mismatch",
+			claims:   map[string]any{"sub": "abc", "email": "a@example.com"},
+			tamper:   func(req AuthRequest) AuthRequest { req.Nonce = "other"; return req },
+			wantKind: errors.Unauthenticated,
+		},
+		{
+			name:   "wrong verifier",
+			claims: map[string]any{"sub": "abc", "email": "a@example.com"},
+			tamper: func(req AuthRequest) AuthRequest {
+				req.Verifier = "wrong-verifier-wrong-verifier-wrong-verifier"
+				return req
+			},
+			wantKind: errors.Unauthenticated,
+		},
+		{
+			name:     "other audience",
+			claims:   map[string]any{"sub": "abc", "email": "a@example.com", "aud": "someone-else"},
+			wantKind: errors.Unauthenticated,
+		},
+		{
+			name:     "other issuer",
+			claims:   map[string]any{"sub": "abc", "email": "a@example.com", "iss": "https://evil.example"},
+			wantKind: errors.Unauthenticated,
+		},
+		{
+			name:     "expired",
+			claims:   map[string]any{"sub": "abc", "email": "a@example.com"},
+			mutate:   func(cfg *Config) { cfg.Now = func() time.Time { return time.Now().Add(time.Hour) } },
+			wantKind: errors.Unauthenticated,
+		},
+		{
+			name:     "unverified email",
+			claims:   map[string]any{"sub": "abc", "email": "a@example.com", "email_verified": false},
+			wantKind: errors.Unauthenticated,
+		},
+		{
+			name:     "missing email",
+			claims:   map[string]any{"sub": "abc"},
+			wantKind: errors.Unauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := oidctest.NewServer(t, "expensor", "secret")
+			srv.SetClaims(tt.claims)
+
+			_, err := login(t, srv, newTestProvider(t, srv, tt.mutate), tt.tamper)
+			if errors.WhatKind(err) != tt.wantKind {
+				t.Fatalf("Exchange error = %v, want kind %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestDiscoveryFailures(t *testing.T) {
+	srv := oidctest.NewServer(t, "expensor", "secret")
+	ctx := context.Background()
+
+	provider := newTestProvider(t, srv, func(cfg *Config) { cfg.Issuer = srv.URL + "/" })
+	if _, err := provider.AuthCodeURL(ctx, redirectURL, AuthRequest{}); errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Fatalf("issuer mismatch error = %v, want FailedPrecondition", err)
+	}
+
+	provider = newTestProvider(t, srv, func(cfg *Config) { cfg.Issuer = "http://127.0.0.1:1" })
+	if _, err := provider.AuthCodeURL(ctx, redirectURL, AuthRequest{}); errors.WhatKind(err) != errors.Unavailable {
+		t.Fatalf("unreachable issuer error = %v, want Unavailable", err)
+	}
+}
+
+func TestVerifySignatureRejectsUnsafeAlgorithms(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	for _, alg := range []string{"none", "HS256", "RS1", "", "ES256"} {
+		if err := verifySignature(alg, &key.PublicKey, "a.b", []byte("sig")); err == nil {
+			t.Fatalf("verifySignature(%q) accepted", alg)
+		}
+	}
+}
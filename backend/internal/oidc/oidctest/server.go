@@ -0,0 +1,261 @@
+// Package oidctest runs an in-process OpenID Connect provider for tests. It
+// implements discovery, the authorization code flow with PKCE, JWKS and
+// userinfo, and signs ID tokens with a throwaway RSA key.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const keyID = "oidctest-key"
+
+// Server is a mock OpenID Connect provider.
+type Server struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu             sync.Mutex
+	claims         map[string]any
+	userinfoClaims map[string]any
+	codes          map[string]authorization
+	tokens         map[string]map[string]any
+}
+
+type authorization struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+	claims      map[string]any
+}
+
+// NewServer starts a provider that accepts clientID and clientSecret. It is
+// shut down when the test ends.
+func NewServer(t testing.TB, clientID, clientSecret string) *Server {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		claims:       map[string]any{"sub": "user-1"},
+		codes:        map[string]authorization{},
+		tokens:       map[string]map[string]any{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("GET /userinfo", s.userinfo)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	s.URL = srv.URL
+	return s
+}
+
+// SetClaims replaces the claims put into ID tokens for subsequent logins.
+// Standard claims (iss, aud, exp, iat, nonce) are filled in unless set here.
+func (s *Server) SetClaims(claims map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claims = maps.Clone(claims)
+}
+
+// SetUserinfoClaims sets extra claims returned only by the userinfo endpoint.
+func (s *Server) SetUserinfoClaims(claims map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userinfoClaims = maps.Clone(claims)
+}
+
+// Login plays the user approving the sign-in at authURL and returns the
+// callback URL the provider redirects the browser to.
+func (s *Server) Login(authURL string) (*url.URL, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("authorize: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return url.Parse(resp.Header.Get("Location"))
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"userinfo_endpoint":                     s.URL + "/userinfo",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	if q.Get("client_id") != s.ClientID || q.Get("response_type") != "code" || redirectURI == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE S256 is required", http.StatusBadRequest)
+		return
+	}
+	code := rand.Text()
+	s.mu.Lock()
+	s.codes[code] = authorization{
+		redirectURI: redirectURI,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		claims:      maps.Clone(s.claims),
+	}
+	s.mu.Unlock()
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+	code := r.PostForm.Get("code")
+	auth, ok := s.codes[code]
+	delete(s.codes, code)
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != auth.redirectURI {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   s.URL,
+		"aud":   s.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": auth.nonce,
+	}
+	maps.Copy(claims, auth.claims)
+	idToken, err := s.sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	accessToken := rand.Text()
+	userinfo := map[string]any{"sub": claims["sub"]}
+	maps.Copy(userinfo, s.userinfoClaims)
+	s.tokens[accessToken] = userinfo
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": keyID,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+func (s *Server) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	claims, known := s.tokens[accessToken]
+	s.mu.Unlock()
+	if !ok || !known {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, claims)
+}
+
+func (s *Server) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,282 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers the SHA-256 hash used by *256 algorithms
+	_ "crypto/sha512" // registers the SHA-384/512 hashes
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	clockSkew = 2 * time.Minute
+	// keyRefreshInterval limits how often an unknown key ID triggers a JWKS
+	// fetch, so forged tokens cannot turn the provider into a request loop.
+	keyRefreshInterval = time.Minute
+)
+
+type keySet struct {
+	keys      map[string]jsonWebKey
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, metadata *discovery, raw, nonce string) (Claims, error) {
+	const op = "oidc.verify"
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.E(op, errors.Unauthenticated, "ID token is not a signed JWT")
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.E(op, errors.Unauthenticated, "decoding ID token header", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.E(op, errors.Unauthenticated, "decoding ID token signature", err)
+	}
+	key, err := p.signingKey(ctx, metadata, header)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, errors.E(op, errors.Unauthenticated, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.E(op, errors.Unauthenticated, "decoding ID token claims", err)
+	}
+	if err := p.checkClaims(claims, nonce); err != nil {
+		return nil, errors.E(op, errors.Unauthenticated, err)
+	}
+	return claims, nil
+}
+
+func (p *Provider) checkClaims(claims Claims, nonce string) error {
+	if claims.String("iss") != p.cfg.Issuer {
+		return fmt.Errorf("ID token issuer %q is not %q", claims.String("iss"), p.cfg.Issuer)
+	}
+	audience := claims.Strings("aud")
+	if !slices.Contains(audience, p.cfg.ClientID) {
+		return fmt.Errorf("ID token audience %v does not include the client ID", audience)
+	}
+	if azp := claims.String("azp"); len(audience) > 1 && azp != p.cfg.ClientID {
+		return fmt.Errorf("ID token authorized party %q is not the client ID", azp)
+	}
+	if claims.String("sub") == "" {
+		return fmt.Errorf("ID token has no subject")
+	}
+	now := p.now()
+	exp, ok := numericDate(claims["exp"])
+	if !ok || !now.Before(exp.Add(clockSkew)) {
+		return fmt.Errorf("ID token expired")
+	}
+	if iat, ok := numericDate(claims["iat"]); ok && iat.After(now.Add(clockSkew)) {
+		return fmt.Errorf("ID token issued in the future")
+	}
+	if claims.String("nonce") != nonce {
+		return fmt.Errorf("ID token nonce does not match the sign-in request")
+	}
+	return nil
+}
+
+func numericDate(value any) (time.Time, bool) {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// signingKey returns the JWKS key for header, refetching the key set once
+// when the key ID is unknown so provider key rotation is picked up.
+func (p *Provider) signingKey(ctx context.Context, metadata *discovery, header tokenHeader) (crypto.PublicKey, error) {
+	const op = "oidc.signingKey"
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	find := func() (jsonWebKey, bool) {
+		if p.keys == nil {
+			return jsonWebKey{}, false
+		}
+		if header.Kid != "" {
+			key, ok := p.keys.keys[header.Kid]
+			return key, ok
+		}
+		// Without a key ID the token is only usable when the set is unambiguous.
+		if len(p.keys.keys) == 1 {
+			for _, key := range p.keys.keys {
+				return key, true
+			}
+		}
+		return jsonWebKey{}, false
+	}
+
+	jwk, ok := find()
+	if !ok && (p.keys == nil || p.now().Sub(p.keys.fetchedAt) >= keyRefreshInterval) {
+		var set struct {
+			Keys []jsonWebKey `json:"keys"`
+		}
+		if err := p.getJSON(ctx, metadata.JWKSURI, "", &set); err != nil {
+			return nil, errors.E(op, errors.Unavailable, errors.User("The sign-in provider is unavailable."), err)
+		}
+		keys := make(map[string]jsonWebKey, len(set.Keys))
+		for _, key := range set.Keys {
+			if key.Use == "" || key.Use == "sig" {
+				keys[key.Kid] = key
+			}
+		}
+		p.keys = &keySet{keys: keys, fetchedAt: p.now()}
+		jwk, ok = find()
+	}
+	if !ok {
+		return nil, errors.E(op, errors.Unauthenticated, fmt.Sprintf("no signing key %q", header.Kid))
+	}
+	if jwk.Alg != "" && jwk.Alg != header.Alg {
+		return nil, errors.E(op, errors.Unauthenticated, fmt.Sprintf("key %q is for %s, token uses %s", jwk.Kid, jwk.Alg, header.Alg))
+	}
+	key, err := jwk.publicKey()
+	if err != nil {
+		return nil, errors.E(op, errors.Unauthenticated, err)
+	}
+	return key, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decoding RSA exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("RSA exponent is too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding EC x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decoding EC y: %w", err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, fmt.Errorf("EC coordinates are too long")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// verifySignature checks a JWS signature. Only asymmetric algorithms are
+// accepted: "none" and HMAC would let anyone who knows the client secret, or
+// nobody at all, mint tokens.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	if hash == 0 {
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	digest := hash.New()
+	digest.Write([]byte(signed))
+	sum := digest.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s needs an RSA key", alg)
+		}
+		if alg[0] == 'R' {
+			return rsa.VerifyPKCS1v15(rsaKey, hash, sum, signature)
+		}
+		return rsa.VerifyPSS(rsaKey, hash, sum, signature, nil)
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s needs an EC key", alg)
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid ECDSA signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, sum, r, s) {
+			return fmt.Errorf("invalid ECDSA signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+func decodeSegment(segment string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,270 @@
+// Package rekey re-encrypts sealed credentials and attachments with the
+// active secret key, so previous keys can be retired after a rotation.
+package rekey
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/observability"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// batchSize is how many values each store call reseals.
+	batchSize = 100
+	// maxFailures bounds the failure descriptions a run keeps.
+	maxFailures = 50
+)
+
+// State is where a re-encryption run is.
+type State string
+
+const (
+	// StateIdle means no run has started since the process did.
+	StateIdle State = "idle"
+	// StateRunning means a run is in progress.
+	StateRunning State = "running"
+	// StateCompleted means the last run walked every value. Values that
+	// failed to open are counted in its progress.
+	StateCompleted State = "completed"
+	// StateFailed means the last run stopped on an error.
+	StateFailed State = "failed"
+)
+
+// KindProgress counts the values of one kind a run has walked.
+type KindProgress struct {
+	Kind store.SealedSecretKind
+	// Total is the number of values when the run started.
+	Total    int64
+	Scanned  int64
+	Resealed int64
+	Failed   int64
+}
+
+// Status describes the keyring and the latest re-encryption run. Progress is
+// kept in memory only; a run interrupted by a restart can simply be started
+// again, since values already sealed with the active key are skipped.
+type Status struct {
+	ActiveKeyID    string
+	PreviousKeyIDs []string
+	State          State
+	StartedAt      *time.Time
+	FinishedAt     *time.Time
+	Kinds          []KindProgress
+	// Failures describe values no configured key could open, up to
+	// maxFailures of them.
+	Failures []string
+	Error    string
+}
+
+// Dependencies configures a Service.
+type Dependencies struct {
+	Store store.SecretStore
+	// SecretKey and PreviousSecretKeys are the keyring the store was opened
+	// with; only their IDs are kept.
+	SecretKey          []byte
+	PreviousSecretKeys [][]byte
+	Logger             *slog.Logger
+	Scope              *observability.Scope
+	Now                func() time.Time
+}
+
+// Service runs admin-triggered re-encryption in the background.
+type Service struct {
+	rootCtx context.Context
+	cancel  context.CancelFunc
+	store   store.SecretStore
+	logger  *slog.Logger
+	scope   *observability.Scope
+	now     func() time.Time
+	running sync.WaitGroup
+
+	mu     sync.Mutex
+	status Status
+	closed bool
+}
+
+// New constructs a Service without starting a run.
+func New(ctx context.Context, deps Dependencies) (*Service, error) {
+	if deps.Store == nil {
+		return nil, errors.E("rekey.new", errors.FailedPrecondition, "secret store is required")
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	scope := deps.Scope
+	if scope == nil {
+		scope = observability.NewScope(logger, "github.com/ArionMiles/expensor/backend/internal/rekey")
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	status := Status{State: StateIdle, PreviousKeyIDs: []string{}}
+	if len(deps.SecretKey) > 0 {
+		status.ActiveKeyID = auth.SecretKeyID(deps.SecretKey)
+	}
+	for _, key := range deps.PreviousSecretKeys {
+		status.PreviousKeyIDs = append(status.PreviousKeyIDs, auth.SecretKeyID(key))
+	}
+	serviceCtx, cancel := context.WithCancel(ctx)
+	return &Service{
+		rootCtx: serviceCtx,
+		cancel:  cancel,
+		store:   deps.Store,
+		logger:  logger.With("component", "rekey"),
+		scope:   scope,
+		now:     now,
+		status:  status,
+	}, nil
+}
+
+// Status returns the keyring and the progress of the latest run.
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+// Start begins re-encrypting every sealed value with the active key and
+// returns at once. Only one run happens at a time.
+func (s *Service) Start() (Status, error) {
+	const op = "rekey.start"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Status{}, errors.E(op, errors.FailedPrecondition, "re-encryption service is shutting down")
+	}
+	if s.status.State == StateRunning {
+		return Status{}, errors.E(op, errors.Conflict, errors.User("Re-encryption is already running."))
+	}
+	started := s.now()
+	s.status.State = StateRunning
+	s.status.StartedAt = &started
+	s.status.FinishedAt = nil
+	s.status.Error = ""
+	s.status.Failures = []string{}
+	s.status.Kinds = make([]KindProgress, 0, len(store.SealedSecretKinds))
+	for _, kind := range store.SealedSecretKinds {
+		s.status.Kinds = append(s.status.Kinds, KindProgress{Kind: kind})
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.run(s.rootCtx)
+	}()
+	return s.snapshot(), nil
+}
+
+// Close cancels a running re-encryption and waits for it to stop.
+func (s *Service) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) run(ctx context.Context) {
+	ctx, span := s.scope.Start(ctx, "rekey.run")
+	defer span.End()
+	started := time.Now()
+	s.logger.Info("re-encryption started", "active_key_id", s.status.ActiveKeyID)
+
+	err := s.walk(ctx)
+	s.scope.RecordDuration(ctx, observability.DurationOperation{
+		Namespace: "rekey",
+		Name:      "run",
+		Duration:  time.Since(started),
+		Err:       err,
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := s.now()
+	s.status.FinishedAt = &finished
+	if err != nil {
+		s.status.State = StateFailed
+		s.status.Error = err.Error()
+		s.logger.Error("re-encryption failed", "error", err)
+		return
+	}
+	s.status.State = StateCompleted
+	var resealed, failed int64
+	for _, progress := range s.status.Kinds {
+		resealed += progress.Resealed
+		failed += progress.Failed
+	}
+	s.logger.Info("re-encryption completed", "resealed", resealed, "failed", failed)
+}
+
+func (s *Service) walk(ctx context.Context) error {
+	for i, kind := range store.SealedSecretKinds {
+		total, err := s.store.CountSealedSecrets(ctx, kind)
+		if err != nil {
+			return errors.E("rekey.run", err)
+		}
+		s.update(func(status *Status) { status.Kinds[i].Total = total })
+
+		cursor := ""
+		for {
+			batch, err := s.store.ResealSecrets(ctx, kind, cursor, batchSize)
+			if err != nil {
+				return errors.E("rekey.run", err)
+			}
+			for _, failure := range batch.Failures {
+				s.logger.Warn("value could not be re-encrypted", "kind", kind, "detail", failure)
+			}
+			s.update(func(status *Status) {
+				progress := &status.Kinds[i]
+				progress.Scanned += int64(batch.Scanned)
+				progress.Resealed += int64(batch.Resealed)
+				progress.Failed += int64(len(batch.Failures))
+				room := max(maxFailures-len(status.Failures), 0)
+				status.Failures = append(status.Failures, batch.Failures[:min(room, len(batch.Failures))]...)
+			})
+			if batch.Next == "" {
+				break
+			}
+			cursor = batch.Next
+		}
+	}
+	return nil
+}
+
+func (s *Service) update(apply func(*Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	apply(&s.status)
+}
+
+// snapshot copies the status so callers cannot race with the run. The caller
+// holds mu.
+func (s *Service) snapshot() Status {
+	out := s.status
+	out.PreviousKeyIDs = slices.Clone(s.status.PreviousKeyIDs)
+	out.Kinds = slices.Clone(s.status.Kinds)
+	out.Failures = slices.Clone(s.status.Failures)
+	return out
+}
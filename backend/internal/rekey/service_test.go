@@ -0,0 +1,236 @@
+package rekey
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// fakeStore serves values of each kind in batches. Values at index
+// failing[kind] and beyond cannot be opened; the rest with an even index are
+// already sealed with the active key.
+type fakeStore struct {
+	values  map[store.SealedSecretKind]int
+	failing map[store.SealedSecretKind]int
+	err     error
+	// block, when set, holds every reseal call until it is closed.
+	block   chan struct{}
+	cursors []string
+}
+
+func (s *fakeStore) CountSealedSecrets(_ context.Context, kind store.SealedSecretKind) (int64, error) {
+	return int64(s.values[kind]), nil
+}
+
+func (s *fakeStore) ResealSecrets(ctx context.Context, kind store.SealedSecretKind, after string, limit int) (store.ResealBatch, error) {
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return store.ResealBatch{}, ctx.Err()
+		}
+	}
+	if s.err != nil {
+		return store.ResealBatch{}, s.err
+	}
+	s.cursors = append(s.cursors, string(kind)+":"+after)
+	start := 0
+	if after != "" {
+		start, _ = strconv.Atoi(after)
+		start++
+	}
+	var batch store.ResealBatch
+	end := min(start+limit, s.values[kind])
+	for i := start; i < end; i++ {
+		batch.Scanned++
+		batch.Next = strconv.Itoa(i)
+		failing, ok := s.failing[kind]
+		switch {
+		case ok && i >= failing:
+			batch.Failures = append(batch.Failures, fmt.Sprintf("%s %d", kind, i))
+		case i%2 == 1:
+			batch.Resealed++
+		}
+	}
+	if end-start < limit {
+		batch.Next = ""
+	}
+	return batch, nil
+}
+
+func newTestService(t *testing.T, st *fakeStore) *Service {
+	t.Helper()
+	svc, err := New(context.Background(), Dependencies{
+		Store:              st,
+		SecretKey:          bytes.Repeat([]byte{9}, auth.SecretKeySize),
+		PreviousSecretKeys: [][]byte{bytes.Repeat([]byte{7}, auth.SecretKeySize)},
+		Now:                func() time.Time { return time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC) },
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close(context.Background()) })
+	return svc
+}
+
+func waitForRun(t *testing.T, svc *Service) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status := svc.Status(); status.State != StateRunning {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("re-encryption did not finish")
+	return Status{}
+}
+
+func TestServiceReportsKeyring(t *testing.T) {
+	svc := newTestService(t, &fakeStore{})
+
+	status := svc.Status()
+
+	if status.State != StateIdle {
+		t.Fatalf("State = %q, want idle", status.State)
+	}
+	if status.ActiveKeyID != auth.SecretKeyID(bytes.Repeat([]byte{9}, auth.SecretKeySize)) {
+		t.Fatalf("ActiveKeyID = %q", status.ActiveKeyID)
+	}
+	if len(status.PreviousKeyIDs) != 1 || status.PreviousKeyIDs[0] != auth.SecretKeyID(bytes.Repeat([]byte{7}, auth.SecretKeySize)) {
+		t.Fatalf("PreviousKeyIDs = %v", status.PreviousKeyIDs)
+	}
+}
+
+func TestServiceWalksEveryKindInBatches(t *testing.T) {
+	st := &fakeStore{
+		values: map[store.SealedSecretKind]int{
+			store.SealedReaderOAuthToken: batchSize + 3,
+			store.SealedTOTPSecret:       batchSize,
+			store.SealedAttachment:       4,
+		},
+		failing: map[store.SealedSecretKind]int{store.SealedAttachment: 3},
+	}
+	svc := newTestService(t, st)
+
+	started, err := svc.Start()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if started.State != StateRunning || started.StartedAt == nil || len(started.Kinds) != len(store.SealedSecretKinds) {
+		t.Fatalf("Start() status = %+v", started)
+	}
+	status := waitForRun(t, svc)
+
+	if status.State != StateCompleted || status.FinishedAt == nil || status.Error != "" {
+		t.Fatalf("status = %+v, want completed", status)
+	}
+	want := map[store.SealedSecretKind]KindProgress{
+		store.SealedReaderClientSecret: {Kind: store.SealedReaderClientSecret},
+		store.SealedReaderOAuthToken: {
+			Kind: store.SealedReaderOAuthToken, Total: batchSize + 3, Scanned: batchSize + 3, Resealed: (batchSize + 3) / 2,
+		},
+		store.SealedLLMCredentials:    {Kind: store.SealedLLMCredentials},
+		store.SealedTOTPSecret:        {Kind: store.SealedTOTPSecret, Total: batchSize, Scanned: batchSize, Resealed: batchSize / 2},
+		store.SealedWebhookSecret:     {Kind: store.SealedWebhookSecret},
+		store.SealedNotificationToken: {Kind: store.SealedNotificationToken},
+		store.SealedAttachment:        {Kind: store.SealedAttachment, Total: 4, Scanned: 4, Resealed: 1, Failed: 1},
+	}
+	for _, progress := range status.Kinds {
+		if progress != want[progress.Kind] {
+			t.Errorf("progress = %+v, want %+v", progress, want[progress.Kind])
+		}
+	}
+	if len(status.Failures) != 1 || status.Failures[0] != "attachment 3" {
+		t.Fatalf("Failures = %v, want attachment 3", status.Failures)
+	}
+	wantCursor := fmt.Sprintf("reader_oauth_token:%d", batchSize-1)
+	found := false
+	for _, cursor := range st.cursors {
+		found = found || cursor == wantCursor
+	}
+	if !found {
+		t.Fatalf("cursors = %v, want a second batch after %s", st.cursors, wantCursor)
+	}
+}
+
+func TestServiceKeepsBoundedFailures(t *testing.T) {
+	st := &fakeStore{
+		values:  map[store.SealedSecretKind]int{store.SealedLLMCredentials: maxFailures + 10},
+		failing: map[store.SealedSecretKind]int{store.SealedLLMCredentials: 0},
+	}
+	svc := newTestService(t, st)
+
+	if _, err := svc.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	status := waitForRun(t, svc)
+
+	if len(status.Failures) != maxFailures {
+		t.Fatalf("len(Failures) = %d, want %d", len(status.Failures), maxFailures)
+	}
+	for _, progress := range status.Kinds {
+		if progress.Kind == store.SealedLLMCredentials && progress.Failed != maxFailures+10 {
+			t.Fatalf("Failed = %d, want every failure counted", progress.Failed)
+		}
+	}
+}
+
+func TestServiceRunsOneAtATime(t *testing.T) {
+	st := &fakeStore{block: make(chan struct{})}
+	svc := newTestService(t, st)
+
+	if _, err := svc.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := svc.Start(); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("second Start() error = %v, want conflict", err)
+	}
+	close(st.block)
+	if status := waitForRun(t, svc); status.State != StateCompleted {
+		t.Fatalf("State = %q, want completed", status.State)
+	}
+	if _, err := svc.Start(); err != nil {
+		t.Fatalf("Start() after completion error = %v", err)
+	}
+	waitForRun(t, svc)
+}
+
+func TestServiceRecordsStoreFailure(t *testing.T) {
+	svc := newTestService(t, &fakeStore{err: errors.E(errors.FailedPrecondition, "attachment blob store is not configured")})
+
+	if _, err := svc.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	status := waitForRun(t, svc)
+
+	if status.State != StateFailed || status.Error == "" || status.FinishedAt == nil {
+		t.Fatalf("status = %+v, want failed with an error", status)
+	}
+}
+
+func TestServiceCloseStopsRun(t *testing.T) {
+	st := &fakeStore{block: make(chan struct{})}
+	svc := newTestService(t, st)
+	if _, err := svc.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if status := svc.Status(); status.State != StateFailed {
+		t.Fatalf("State after Close = %q, want failed", status.State)
+	}
+	if _, err := svc.Start(); errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Fatalf("Start() after Close error = %v, want failed precondition", err)
+	}
+}
@@ -0,0 +1,357 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	// TenantArchiveFormat identifies a tenant archive document.
+	TenantArchiveFormat = "expensor.tenant-archive"
+	// TenantArchiveVersion is the archive version this build writes. Imports
+	// accept every version up to it.
+	TenantArchiveVersion = 1
+)
+
+// Archive sections, in the order an import applies them. Taxonomy and rules
+// come before transactions so that everything a transaction refers to exists.
+const (
+	ArchiveSectionPreferences      = "preferences"
+	ArchiveSectionLabels           = "labels"
+	ArchiveSectionCategories       = "categories"
+	ArchiveSectionBuckets          = "buckets"
+	ArchiveSectionLabelMappings    = "label_mappings"
+	ArchiveSectionMerchantMappings = "merchant_mappings"
+	ArchiveSectionMutedMerchants   = "muted_merchants"
+	ArchiveSectionRules            = "rules"
+	ArchiveSectionReaders          = "readers"
+	ArchiveSectionDiagnostics      = "diagnostics"
+	ArchiveSectionTransactions     = "transactions"
+)
+
+// TenantArchiveExcluded names the tenant data an archive never holds. Reader
+// and LLM credentials are encrypted with the instance key and must be
+// reconnected after an import; attachment content lives in the blob store.
+var TenantArchiveExcluded = []string{
+	"reader_credentials", "reader_tokens", "llm_provider_credentials", "attachments",
+	"transaction_history", "shared_ledgers", "processed_messages",
+}
+
+// ArchiveConflictPolicy decides what an import does with an archived item
+// that already exists in the tenant. Items are matched by natural key:
+// transactions by message ID, rules by name, taxonomy by name or pattern.
+type ArchiveConflictPolicy string
+
+const (
+	// ArchiveConflictSkip leaves existing items unchanged.
+	ArchiveConflictSkip ArchiveConflictPolicy = "skip"
+	// ArchiveConflictOverwrite replaces existing items with the archived ones.
+	ArchiveConflictOverwrite ArchiveConflictPolicy = "overwrite"
+	// ArchiveConflictMerge keeps existing values, fills in the ones that are
+	// empty, and adds archived labels and mappings to the existing ones.
+	ArchiveConflictMerge ArchiveConflictPolicy = "merge"
+)
+
+// TenantArchive is a self-describing copy of one tenant's data, used to back
+// up a tenant or move it to another instance.
+type TenantArchive struct {
+	Format     string        `json:"format"`
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Tenant     ArchiveTenant `json:"tenant"`
+	// Counts holds the number of items in each section.
+	Counts map[string]int `json:"counts"`
+	// Excluded lists the kinds of tenant data left out of the archive.
+	Excluded []string `json:"excluded"`
+	// Preferences holds tenant settings by key. Reader scan checkpoints and
+	// the active reader are left out because readers are not reconnected.
+	Preferences      map[string]string        `json:"preferences"`
+	Labels           []Label                  `json:"labels"`
+	Categories       []Category               `json:"categories"`
+	Buckets          []Bucket                 `json:"buckets"`
+	LabelMappings    []ArchiveLabelMapping    `json:"label_mappings"`
+	MerchantMappings []ArchiveMerchantMapping `json:"merchant_mappings"`
+	MutedMerchants   []MutedMerchant          `json:"muted_merchants"`
+	// Rules holds the tenant's own extraction rules; predefined rules ship
+	// with every instance.
+	Rules        []RuleRow                 `json:"rules"`
+	Readers      []ArchiveReader           `json:"readers"`
+	Diagnostics  []ExtractionDiagnosticRow `json:"diagnostics"`
+	Transactions []ArchiveTransaction      `json:"transactions"`
+}
+
+// ArchiveTenant describes the tenant an archive was taken from.
+type ArchiveTenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ArchiveLabelMapping applies a label to merchants matching a pattern.
+type ArchiveLabelMapping struct {
+	Label   string `json:"label"`
+	Pattern string `json:"pattern"`
+}
+
+// ArchiveMerchantMapping assigns a category, a bucket, or both to merchants
+// matching a pattern.
+type ArchiveMerchantMapping struct {
+	Pattern  string `json:"pattern"`
+	Category string `json:"category,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+}
+
+// ArchiveReader holds a reader's non-secret settings.
+type ArchiveReader struct {
+	Reader string          `json:"reader"`
+	Config json.RawMessage `json:"config"`
+}
+
+// ArchiveTransaction is a transaction with the data needed to restore it
+// exactly: why each label is applied and the text of its source email.
+type ArchiveTransaction struct {
+	Transaction
+	LabelSources []ArchiveLabelSource `json:"label_sources,omitempty"`
+	Email        *ArchiveEmail        `json:"email,omitempty"`
+}
+
+// ArchiveLabelSource records that a label was applied by hand ("manual") or
+// by a merchant mapping ("merchant").
+type ArchiveLabelSource struct {
+	Label           string `json:"label"`
+	Type            string `json:"type"`
+	MerchantPattern string `json:"merchant_pattern,omitempty"`
+}
+
+// ArchiveEmail is the searchable text of a transaction's source email.
+type ArchiveEmail struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// TenantImportResult reports what an import did, per archive section.
+type TenantImportResult struct {
+	Policy   ArchiveConflictPolicy           `json:"policy"`
+	Sections map[string]ArchiveSectionResult `json:"sections"`
+	// RemappedIDs counts archived items stored under a new ID because their
+	// original ID was already taken on this instance.
+	RemappedIDs int `json:"remapped_ids"`
+}
+
+// ArchiveSectionResult counts the items of one section an import created,
+// changed, or left alone.
+type ArchiveSectionResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// ArchivedPreference reports whether the tenant setting key belongs in an
+// archive. Reader state is tied to reader credentials, which are not archived.
+func ArchivedPreference(key string) bool {
+	return key != "" && key != "active_reader" && !strings.HasPrefix(key, "reader.")
+}
+
+// CountSections fills in a.Counts from the archive's contents.
+func (a *TenantArchive) CountSections() {
+	a.Counts = map[string]int{
+		ArchiveSectionPreferences:      len(a.Preferences),
+		ArchiveSectionLabels:           len(a.Labels),
+		ArchiveSectionCategories:       len(a.Categories),
+		ArchiveSectionBuckets:          len(a.Buckets),
+		ArchiveSectionLabelMappings:    len(a.LabelMappings),
+		ArchiveSectionMerchantMappings: len(a.MerchantMappings),
+		ArchiveSectionMutedMerchants:   len(a.MutedMerchants),
+		ArchiveSectionRules:            len(a.Rules),
+		ArchiveSectionReaders:          len(a.Readers),
+		ArchiveSectionDiagnostics:      len(a.Diagnostics),
+		ArchiveSectionTransactions:     len(a.Transactions),
+	}
+}
+
+// ValidArchiveConflictPolicy reports whether policy is a known conflict policy.
+func ValidArchiveConflictPolicy(policy ArchiveConflictPolicy) bool {
+	switch policy {
+	case ArchiveConflictSkip, ArchiveConflictOverwrite, ArchiveConflictMerge:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateTenantArchive checks that a can be imported as a whole. It reports
+// the first problem found, naming the section and item.
+func ValidateTenantArchive(a TenantArchive) error {
+	const op = "store.archive.validate"
+
+	invalid := func(format string, args ...any) error {
+		return errors.E(op, errors.InvalidInput, errors.User(fmt.Sprintf(format, args...)))
+	}
+	if a.Format != TenantArchiveFormat {
+		return invalid("Not an Expensor tenant archive: format must be %q.", TenantArchiveFormat)
+	}
+	if a.Version < 1 || a.Version > TenantArchiveVersion {
+		return invalid("Archive version %d is not supported; this instance reads versions 1 to %d.", a.Version, TenantArchiveVersion)
+	}
+	for key := range a.Preferences {
+		if strings.TrimSpace(key) == "" {
+			return invalid("preferences: keys must not be empty.")
+		}
+	}
+	if err := validateArchiveNames(ArchiveSectionLabels, len(a.Labels), func(i int) string { return a.Labels[i].Name }, maxLabelLength); err != nil {
+		return err
+	}
+	categoryName := func(i int) string { return a.Categories[i].Name }
+	if err := validateArchiveNames(ArchiveSectionCategories, len(a.Categories), categoryName, maxCategoryLength); err != nil {
+		return err
+	}
+	if err := validateArchiveNames(ArchiveSectionBuckets, len(a.Buckets), func(i int) string { return a.Buckets[i].Name }, maxBucketLength); err != nil {
+		return err
+	}
+	for i, mapping := range a.LabelMappings {
+		if strings.TrimSpace(mapping.Label) == "" || strings.TrimSpace(mapping.Pattern) == "" {
+			return invalid("label_mappings[%d]: label and pattern are required.", i)
+		}
+	}
+	for i, mapping := range a.MerchantMappings {
+		if strings.TrimSpace(mapping.Pattern) == "" || (mapping.Category == "" && mapping.Bucket == "") {
+			return invalid("merchant_mappings[%d]: a pattern and a category or bucket are required.", i)
+		}
+	}
+	if err := validateArchiveNames(ArchiveSectionMutedMerchants, len(a.MutedMerchants), func(i int) string { return a.MutedMerchants[i].Pattern }, 0); err != nil {
+		return err
+	}
+	if err := validateArchiveNames(ArchiveSectionRules, len(a.Rules), func(i int) string { return a.Rules[i].Name }, 0); err != nil {
+		return err
+	}
+	for i, rule := range a.Rules {
+		if err := validateArchiveRule(rule); err != nil {
+			return invalid("rules[%d] %q: %v.", i, rule.Name, err)
+		}
+	}
+	if err := validateArchiveNames(ArchiveSectionReaders, len(a.Readers), func(i int) string { return a.Readers[i].Reader }, 0); err != nil {
+		return err
+	}
+	for i, reader := range a.Readers {
+		if len(reader.Config) > 0 && !json.Valid(reader.Config) {
+			return invalid("readers[%d]: config must be valid JSON.", i)
+		}
+	}
+	for i, diagnostic := range a.Diagnostics {
+		switch diagnostic.Status {
+		case DiagnosticStatusOpen, DiagnosticStatusResolved, DiagnosticStatusIgnored:
+		default:
+			return invalid("diagnostics[%d]: unknown status %q.", i, diagnostic.Status)
+		}
+		if strings.TrimSpace(diagnostic.Reader) == "" {
+			return invalid("diagnostics[%d]: reader is required.", i)
+		}
+	}
+	messageID := func(i int) string { return a.Transactions[i].MessageID }
+	if err := validateArchiveNames(ArchiveSectionTransactions, len(a.Transactions), messageID, maxMessageIDLength); err != nil {
+		return err
+	}
+	for i, txn := range a.Transactions {
+		if err := validateArchiveTransaction(txn); err != nil {
+			return invalid("transactions[%d] %q: %v.", i, txn.MessageID, err)
+		}
+	}
+	return nil
+}
+
+// Column limits of the tables an archive is imported into.
+const (
+	maxMessageIDLength = 255
+	maxLabelLength     = 100
+	maxCategoryLength  = 100
+	maxBucketLength    = 50
+	maxCurrencyLength  = 3
+	maxArchiveAmount   = 1e15
+	maxExchangeRate    = 1e4
+)
+
+// validateArchiveNames checks that the n keys returned by name are present,
+// unique and, when limit is positive, at most limit characters long.
+func validateArchiveNames(section string, n int, name func(int) string, limit int) error {
+	seen := make(map[string]int, n)
+	for i := range n {
+		key := name(i)
+		if strings.TrimSpace(key) == "" {
+			return errors.E("store.archive.validate", errors.InvalidInput, errors.User(fmt.Sprintf("%s[%d]: name is required.", section, i)))
+		}
+		if limit > 0 && len([]rune(key)) > limit {
+			return errors.E("store.archive.validate", errors.InvalidInput,
+				errors.User(fmt.Sprintf("%s[%d]: %q is longer than %d characters.", section, i, key, limit)))
+		}
+		if first, ok := seen[key]; ok {
+			return errors.E("store.archive.validate", errors.InvalidInput,
+				errors.User(fmt.Sprintf("%s[%d]: %q duplicates %s[%d].", section, i, key, section, first)))
+		}
+		seen[key] = i
+	}
+	return nil
+}
+
+func validateArchiveRule(rule RuleRow) error {
+	if rule.AmountRegex == "" || rule.MerchantRegex == "" {
+		return fmt.Errorf("amount_regex and merchant_regex are required")
+	}
+	for field, pattern := range map[string]string{
+		"amount_regex": rule.AmountRegex, "merchant_regex": rule.MerchantRegex, "currency_regex": rule.CurrencyRegex,
+	} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid %s", field)
+		}
+	}
+	return nil
+}
+
+func validateArchiveTransaction(txn ArchiveTransaction) error {
+	if txn.Timestamp.IsZero() {
+		return fmt.Errorf("timestamp is required")
+	}
+	if !validArchiveAmount(txn.Amount) {
+		return fmt.Errorf("amount is out of range")
+	}
+	if txn.OriginalAmount != nil && !validArchiveAmount(*txn.OriginalAmount) {
+		return fmt.Errorf("original_amount is out of range")
+	}
+	if txn.ExchangeRate != nil && (math.IsNaN(*txn.ExchangeRate) || math.Abs(*txn.ExchangeRate) >= maxExchangeRate) {
+		return fmt.Errorf("exchange_rate is out of range")
+	}
+	if len(txn.Currency) > maxCurrencyLength || (txn.OriginalCurrency != nil && len(*txn.OriginalCurrency) > maxCurrencyLength) {
+		return fmt.Errorf("currencies must be ISO 4217 codes")
+	}
+	if len([]rune(txn.Category)) > maxCategoryLength || len([]rune(txn.Bucket)) > maxBucketLength {
+		return fmt.Errorf("category or bucket is too long")
+	}
+	for _, label := range txn.Labels {
+		if strings.TrimSpace(label) == "" || len([]rune(label)) > maxLabelLength {
+			return fmt.Errorf("label %q is empty or too long", label)
+		}
+	}
+	for _, source := range txn.LabelSources {
+		if source.Type != "manual" && source.Type != "merchant" {
+			return fmt.Errorf("label source type %q must be manual or merchant", source.Type)
+		}
+	}
+	splits := make([]TransactionSplitInput, 0, len(txn.Splits))
+	for _, split := range txn.Splits {
+		splits = append(splits, TransactionSplitInput{
+			Amount: split.Amount, Category: split.Category, Bucket: split.Bucket, Labels: split.Labels, Counterparty: split.Counterparty,
+		})
+	}
+	if err := ValidateSplits(txn.Amount, splits); err != nil {
+		return fmt.Errorf("splits: %s", strings.TrimSuffix(errors.UserMsg(err), "."))
+	}
+	return nil
+}
+
+func validArchiveAmount(amount float64) bool {
+	return !math.IsNaN(amount) && math.Abs(amount) < maxArchiveAmount
+}
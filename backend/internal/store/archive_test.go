@@ -0,0 +1,81 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func validArchive() TenantArchive {
+	return TenantArchive{
+		Format:  TenantArchiveFormat,
+		Version: TenantArchiveVersion,
+		Labels:  []Label{{Name: "food"}},
+		Rules: []RuleRow{{
+			Name: "HDFC", AmountRegex: `INR\s+([0-9.]+)`, MerchantRegex: `at\s+(.+)`,
+		}},
+		Transactions: []ArchiveTransaction{{
+			Transaction: Transaction{
+				MessageID: "m-1", Amount: 100, Currency: "INR", Timestamp: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+				Labels: []string{"food"},
+				Splits: []TransactionSplit{{Amount: 60}, {Amount: 40}},
+			},
+			LabelSources: []ArchiveLabelSource{{Label: "food", Type: "merchant", MerchantPattern: "Swiggy"}},
+		}},
+	}
+}
+
+func TestValidateTenantArchive(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*TenantArchive)
+		want   string
+	}{
+		{name: "valid", mutate: func(*TenantArchive) {}},
+		{name: "format", mutate: func(a *TenantArchive) { a.Format = "expensor.rules" }, want: "format"},
+		{name: "newer version", mutate: func(a *TenantArchive) { a.Version = TenantArchiveVersion + 1 }, want: "not supported"},
+		{name: "duplicate label", mutate: func(a *TenantArchive) { a.Labels = append(a.Labels, Label{Name: "food"}) }, want: "labels[1]"},
+		{name: "long bucket", mutate: func(a *TenantArchive) { a.Buckets = []Bucket{{Name: strings.Repeat("b", 51)}} }, want: "longer than 50"},
+		{name: "empty mapping", mutate: func(a *TenantArchive) { a.MerchantMappings = []ArchiveMerchantMapping{{Pattern: "Swiggy"}} }, want: "merchant_mappings[0]"},
+		{name: "bad regex", mutate: func(a *TenantArchive) { a.Rules[0].CurrencyRegex = "(" }, want: "invalid currency_regex"},
+		{name: "reader config", mutate: func(a *TenantArchive) { a.Readers = []ArchiveReader{{Reader: "gmail", Config: []byte("{")}} }, want: "readers[0]"},
+		{name: "diagnostic status", mutate: func(a *TenantArchive) {
+			a.Diagnostics = []ExtractionDiagnosticRow{{Reader: "gmail", Status: "done"}}
+		}, want: "unknown status"},
+		{name: "duplicate message", mutate: func(a *TenantArchive) { a.Transactions = append(a.Transactions, a.Transactions[0]) }, want: "duplicates"},
+		{name: "split sum", mutate: func(a *TenantArchive) { a.Transactions[0].Splits[1].Amount = 50 }, want: "splits:"},
+		{name: "label source type", mutate: func(a *TenantArchive) { a.Transactions[0].LabelSources[0].Type = "rule" }, want: "label source type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := validArchive()
+			tt.mutate(&archive)
+			err := ValidateTenantArchive(archive)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ValidateTenantArchive() error = %v, want nil", err)
+				}
+				return
+			}
+			if errors.WhatKind(err) != errors.InvalidInput || !strings.Contains(errors.UserMsg(err), tt.want) {
+				t.Fatalf("ValidateTenantArchive() error = %v (%q), want InvalidInput containing %q", err, errors.UserMsg(err), tt.want)
+			}
+		})
+	}
+}
+
+func TestArchivedPreferenceSkipsReaderState(t *testing.T) {
+	for key, want := range map[string]bool{
+		"base_currency":          true,
+		"export.accounts":        true,
+		"active_reader":          false,
+		"reader.gmail.last_scan": false,
+		"":                       false,
+	} {
+		if got := ArchivedPreference(key); got != want {
+			t.Errorf("ArchivedPreference(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package store
+
+import "time"
+
+// AttachmentSource says how a file was attached to a transaction.
+type AttachmentSource string
+
+const (
+	// AttachmentSourceUpload is a file uploaded through the API.
+	AttachmentSourceUpload AttachmentSource = "upload"
+	// AttachmentSourceEmail is a PDF taken from the source email at ingestion.
+	AttachmentSourceEmail AttachmentSource = "email"
+)
+
+// Attachment describes a file kept with a transaction, such as an invoice.
+// The content is stored encrypted in the blob store, not in the database.
+type Attachment struct {
+	ID            string           `json:"id"`
+	TransactionID string           `json:"transaction_id"`
+	Filename      string           `json:"filename"`
+	ContentType   string           `json:"content_type"`
+	Size          int64            `json:"size"`
+	SHA256        string           `json:"sha256"`
+	Source        AttachmentSource `json:"source"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// NewAttachment is a file to attach to a transaction.
+type NewAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+	Source      AttachmentSource
+}
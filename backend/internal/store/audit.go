@@ -0,0 +1,129 @@
+package store
+
+import "time"
+
+// AuditAction names a security-relevant action recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditBootstrap                 AuditAction = "auth.bootstrap"
+	AuditLogin                     AuditAction = "auth.login"
+	AuditLogout                    AuditAction = "auth.logout"
+	AuditPasswordChange            AuditAction = "auth.password_change"
+	AuditAccountSetup              AuditAction = "auth.account_setup"
+	AuditMFAEnroll                 AuditAction = "mfa.enroll"
+	AuditMFARemove                 AuditAction = "mfa.remove"
+	AuditRecoveryCodesRegenerate   AuditAction = "mfa.recovery_codes_regenerate"
+	AuditSessionRevoke             AuditAction = "session.revoke"
+	AuditAccessTokenCreate         AuditAction = "access_token.create"
+	AuditAccessTokenRevoke         AuditAction = "access_token.revoke"
+	AuditUserCreate                AuditAction = "user.create"
+	AuditUserUpdate                AuditAction = "user.update"
+	AuditUserDelete                AuditAction = "user.delete"
+	AuditSetupTokenCreate          AuditAction = "user.setup_token_create"
+	AuditTenantMemberInvite        AuditAction = "tenant.member_invite"
+	AuditTenantMemberJoin          AuditAction = "tenant.member_join"
+	AuditTenantMemberRoleUpdate    AuditAction = "tenant.member_role_update"
+	AuditTenantMemberRemove        AuditAction = "tenant.member_remove"
+	AuditAccountImport             AuditAction = "account.import"
+	AuditBackupRestore             AuditAction = "backup.restore"
+	AuditReencryptionStart         AuditAction = "encryption.reencrypt"
+	AuditReaderCredentials         AuditAction = "reader.credentials_upload"
+	AuditReaderConnect             AuditAction = "reader.connect"
+	AuditReaderDisconnect          AuditAction = "reader.disconnect"
+	AuditReaderTokenRevoke         AuditAction = "reader.token_revoke"
+	AuditScanningSettings          AuditAction = "scanning.settings_update"
+	AuditScanningAdminSettings     AuditAction = "scanning.admin_settings_update"
+	AuditScanningRescan            AuditAction = "scanning.rescan"
+	AuditLLMConfig                 AuditAction = "llm.config_update"
+	AuditLLMCredentials            AuditAction = "llm.credentials_update"
+	AuditLLMActivate               AuditAction = "llm.activate"
+	AuditLLMDisconnect             AuditAction = "llm.disconnect"
+	AuditSettingsUpdate            AuditAction = "audit.settings_update"
+	AuditWebhookCreate             AuditAction = "webhook.create"
+	AuditWebhookUpdate             AuditAction = "webhook.update"
+	AuditWebhookDelete             AuditAction = "webhook.delete"
+	AuditNotificationChannelCreate AuditAction = "notification_channel.create"
+	AuditNotificationChannelUpdate AuditAction = "notification_channel.update"
+	AuditNotificationChannelDelete AuditAction = "notification_channel.delete"
+)
+
+// AuditOutcome says whether an audited action took effect.
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+	// AuditDenied is an action refused because the caller was not
+	// authenticated, not allowed, or throttled.
+	AuditDenied AuditOutcome = "denied"
+)
+
+// NewAuditEvent is an action to record in the audit log.
+type NewAuditEvent struct {
+	// ActorUserID is the user who acted; empty when the request was not
+	// authenticated, as with a failed sign-in for an unknown email.
+	ActorUserID string
+	// TenantID is the tenant the action applied to, if any.
+	TenantID string
+	// AuthMethod is how the actor authenticated: session, bearer, proxy or,
+	// for single sign-on sign-ins, oidc.
+	AuthMethod string
+	Action     AuditAction
+	// TargetType and TargetID identify what the action applied to, such as
+	// a user ID, an access token ID or a reader name.
+	TargetType string
+	TargetID   string
+	RequestID  string
+	IPAddress  string
+	Outcome    AuditOutcome
+	// Detail says why a failed or denied action did not take effect.
+	Detail string
+}
+
+// AuditEvent is a recorded audit log entry.
+type AuditEvent struct {
+	ID          string
+	OccurredAt  time.Time
+	ActorUserID string
+	// ActorEmail is the actor's current email; empty once the user is deleted.
+	ActorEmail string
+	TenantID   string
+	AuthMethod string
+	Action     AuditAction
+	TargetType string
+	TargetID   string
+	RequestID  string
+	IPAddress  string
+	Outcome    AuditOutcome
+	Detail     string
+}
+
+// AuditEventFilter selects audit events. Empty fields match everything.
+type AuditEventFilter struct {
+	ActorUserID string
+	TenantID    string
+	Action      AuditAction
+	TargetType  string
+	TargetID    string
+	Outcome     AuditOutcome
+	RequestID   string
+	// From is inclusive and To exclusive.
+	From     *time.Time
+	To       *time.Time
+	Page     int
+	PageSize int
+}
+
+// AuditSettings controls how long audit events are kept.
+type AuditSettings struct {
+	// RetentionDays removes events older than this many days. Zero keeps
+	// every event.
+	RetentionDays int
+	UpdatedAt     time.Time
+}
+
+// AuditSettingsPatch partially updates AuditSettings.
+type AuditSettingsPatch struct {
+	RetentionDays *int
+}
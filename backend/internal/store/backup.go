@@ -0,0 +1,70 @@
+package store
+
+import (
+	"io"
+	"time"
+)
+
+// BackupSettings controls scheduled database backups. The Last* fields
+// report the most recent backup run, scheduled or manual.
+type BackupSettings struct {
+	Enabled bool
+	// IntervalHours is the time between scheduled backups.
+	IntervalHours int
+	// KeepLast is how many of the newest backups retention always keeps.
+	KeepLast int
+	// KeepDays removes backups beyond KeepLast once they are older than this
+	// many days. Zero removes every backup beyond KeepLast.
+	KeepDays       int
+	LastStartedAt  *time.Time
+	LastFinishedAt *time.Time
+	LastBackup     string
+	LastError      string
+	UpdatedAt      time.Time
+}
+
+// BackupSettingsPatch partially updates BackupSettings.
+type BackupSettingsPatch struct {
+	Enabled       *bool
+	IntervalHours *int
+	KeepLast      *int
+	KeepDays      *int
+}
+
+// BackupRun records the outcome of one backup run.
+type BackupRun struct {
+	FinishedAt time.Time
+	// Backup names the backup written; empty when the run failed.
+	Backup string
+	Error  string
+}
+
+// BackupTable describes one table of a database backup. Rows is only known
+// once the table has been copied.
+type BackupTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int64    `json:"rows"`
+}
+
+// DatabaseSchema identifies the schema a database backup was taken from:
+// the applied migration version and the copied columns of every table.
+type DatabaseSchema struct {
+	Version int64         `json:"version"`
+	Tables  []BackupTable `json:"tables"`
+}
+
+// BackupSchemaCheck compares a backup's schema with the current database.
+// A backup can only be restored when Problems is empty.
+type BackupSchemaCheck struct {
+	CurrentVersion int64
+	Problems       []string
+}
+
+// BackupTableWriter returns where the rows of table are written, in
+// PostgreSQL COPY text format.
+type BackupTableWriter func(table BackupTable) (io.Writer, error)
+
+// BackupTableReader returns the rows of table, in the format a
+// BackupTableWriter received them.
+type BackupTableReader func(table BackupTable) (io.Reader, error)
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"strconv"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// MaxBulkTransactions caps how many transactions one bulk operation can touch,
+// whether they are named by ID or matched by a filter.
+const MaxBulkTransactions = 1000
+
+// Bulk row statuses reported per transaction.
+const (
+	BulkRowUpdated  = "updated"
+	BulkRowMatched  = "matched"
+	BulkRowNotFound = "not_found"
+)
+
+// BulkTransactionInput selects transactions either by ID or by filter and
+// applies the same changes to all of them. Filter pagination is ignored.
+type BulkTransactionInput struct {
+	IDs          []string
+	Filter       *ListFilter
+	Update       TransactionUpdate
+	AddLabels    []string
+	RemoveLabels []string
+	// Muted mutes or unmutes every selected transaction; nil leaves mute state
+	// alone. MuteReason is only stored when muting.
+	Muted      *bool
+	MuteReason string
+	// DryRun reports which transactions would change without writing.
+	DryRun bool
+}
+
+// BulkTransactionResult reports the outcome of a bulk operation. BatchID names
+// the logged change batch and is empty when nothing was written.
+type BulkTransactionResult struct {
+	DryRun  bool                       `json:"dry_run"`
+	BatchID string                     `json:"batch_id,omitempty"`
+	Matched int                        `json:"matched"`
+	Updated int                        `json:"updated"`
+	Results []BulkTransactionRowResult `json:"results"`
+}
+
+// BulkTransactionRowResult is the outcome for one requested or matched
+// transaction.
+type BulkTransactionRowResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// ValidateBulkTransactionInput checks that input names exactly one way of
+// selecting transactions and at least one change.
+func ValidateBulkTransactionInput(input BulkTransactionInput) error {
+	const op = "store.transactions.bulk_validate"
+
+	switch {
+	case len(input.IDs) == 0 && input.Filter == nil:
+		return errors.E(op, errors.InvalidInput, errors.User("Select transactions by ids or by filter."))
+	case len(input.IDs) > 0 && input.Filter != nil:
+		return errors.E(op, errors.InvalidInput, errors.User("Select transactions by ids or by filter, not both."))
+	case len(input.IDs) > MaxBulkTransactions:
+		return errors.E(op, errors.InvalidInput, errors.User(
+			"A bulk operation can touch at most "+strconv.Itoa(MaxBulkTransactions)+" transactions.",
+		))
+	}
+	u := input.Update
+	if u.Description == nil && u.Category == nil && u.Bucket == nil &&
+		len(input.AddLabels) == 0 && len(input.RemoveLabels) == 0 && input.Muted == nil {
+		return errors.E(op, errors.InvalidInput, errors.User("A bulk operation needs at least one change."))
+	}
+	removing := make(map[string]bool, len(input.RemoveLabels))
+	for _, label := range input.RemoveLabels {
+		removing[label] = true
+	}
+	for _, label := range input.AddLabels {
+		if removing[label] {
+			return errors.E(op, errors.InvalidInput, errors.User("Label "+strconv.Quote(label)+" cannot be both added and removed."))
+		}
+	}
+	return nil
+}
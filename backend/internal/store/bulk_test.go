@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestValidateBulkTransactionInput(t *testing.T) {
+	category := "Food"
+	muted := true
+	ids := make([]string, MaxBulkTransactions+1)
+	tests := []struct {
+		name  string
+		input BulkTransactionInput
+		valid bool
+	}{
+		{name: "ids", input: BulkTransactionInput{IDs: []string{"a"}, Update: TransactionUpdate{Category: &category}}, valid: true},
+		{name: "filter", input: BulkTransactionInput{Filter: &ListFilter{Merchant: "uber"}, Muted: &muted}, valid: true},
+		{name: "no selector", input: BulkTransactionInput{AddLabels: []string{"trip"}}},
+		{name: "both selectors", input: BulkTransactionInput{IDs: []string{"a"}, Filter: &ListFilter{}, AddLabels: []string{"trip"}}},
+		{name: "too many ids", input: BulkTransactionInput{IDs: ids, AddLabels: []string{"trip"}}},
+		{name: "no change", input: BulkTransactionInput{IDs: []string{"a"}}},
+		{name: "add and remove", input: BulkTransactionInput{IDs: []string{"a"}, AddLabels: []string{"trip"}, RemoveLabels: []string{"trip"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBulkTransactionInput(tt.input)
+			if tt.valid && err != nil {
+				t.Fatalf("ValidateBulkTransactionInput() error = %v, want nil", err)
+			}
+			if !tt.valid && errors.WhatKind(err) != errors.InvalidInput {
+				t.Fatalf("ValidateBulkTransactionInput() error = %v, want InvalidInput", err)
+			}
+		})
+	}
+}
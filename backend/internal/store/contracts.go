@@ -19,9 +19,25 @@ type AuthStore interface {
 	DeleteUser(ctx context.Context, id string) error
 	FindUserByEmail(ctx context.Context, email string) (*User, error)
 	FindUserByID(ctx context.Context, id string) (*User, error)
+	// FindUserByOIDCSubject returns the account linked to subject at issuer.
+	// It is NotFound when none is.
+	FindUserByOIDCSubject(ctx context.Context, issuer, subject string) (*User, error)
+	// LinkOIDCSubject links the account to subject at issuer. It is a
+	// Conflict when either is already linked elsewhere.
+	LinkOIDCSubject(ctx context.Context, userID, issuer, subject string) error
 	CreateSession(ctx context.Context, input CreateSessionInput) (*Session, error)
 	FindSessionByHash(ctx context.Context, tokenHash string) (*Session, error)
 	RevokeSession(ctx context.Context, id string) error
+	// ListSessions returns the user's unrevoked, unexpired sessions, most
+	// recently used first.
+	ListSessions(ctx context.Context, userID string) ([]Session, error)
+	// RevokeUserSession revokes one of the user's active sessions.
+	RevokeUserSession(ctx context.Context, userID, id string) error
+	// RevokeOtherSessions revokes every active session of the user except
+	// keepID, which may be empty, and returns how many it revoked.
+	RevokeOtherSessions(ctx context.Context, userID, keepID string) (int64, error)
+	// TouchSession records a use of the session from ipAddress.
+	TouchSession(ctx context.Context, id, ipAddress string) error
 	CreateAccessToken(ctx context.Context, input CreateAccessTokenInput) (*AccessToken, error)
 	ListAccessTokens(ctx context.Context, userID string) ([]AccessToken, error)
 	FindAccessTokenByHash(ctx context.Context, tokenHash string) (*AccessToken, error)
@@ -32,6 +48,57 @@ type AuthStore interface {
 	CompleteAccountSetup(ctx context.Context, input CompleteAccountSetupInput) (*User, error)
 }
 
+// MFAStore keeps users' second factors and the instance MFA policy.
+type MFAStore interface {
+	GetMFAFactors(ctx context.Context, userID string) (MFAFactors, error)
+	GetTOTPFactor(ctx context.Context, userID string) (*TOTPFactor, error)
+	// SetPendingTOTP stores a secret awaiting its first code, replacing any
+	// earlier unconfirmed one. It is a Conflict when TOTP is already enabled.
+	SetPendingTOTP(ctx context.Context, userID string, secret []byte) error
+	// ConfirmTOTP enables the pending secret after a code from step matched.
+	ConfirmTOTP(ctx context.Context, userID string, step int64) error
+	// UseTOTPStep records the step of an accepted code. It is a Conflict when
+	// step is not after the last accepted one, so each code works once.
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	DeleteTOTP(ctx context.Context, userID string) error
+	// ReplaceRecoveryCodes swaps the user's recovery codes for codeHashes.
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	// UseRecoveryCode spends an unused code. It is NotFound otherwise.
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	CreatePasskey(ctx context.Context, input CreatePasskeyInput) (*Passkey, error)
+	ListPasskeys(ctx context.Context, userID string) ([]Passkey, error)
+	// UsePasskey records a sign-in and the authenticator's new counter.
+	UsePasskey(ctx context.Context, id string, signCount uint32) error
+	DeletePasskey(ctx context.Context, userID, id string) error
+	GetMFASettings(ctx context.Context) (MFASettings, error)
+	PatchMFASettings(ctx context.Context, patch MFASettingsPatch) (MFASettings, error)
+}
+
+// TenantStore manages tenants and the users who may select them. Data
+// repositories never consult memberships; callers resolve the tenant first.
+type TenantStore interface {
+	CreateTenant(ctx context.Context, ownerUserID string, input CreateTenantInput) (TenantMembership, error)
+	ListTenantMemberships(ctx context.Context, userID string) ([]TenantMembership, error)
+	GetTenantMembership(ctx context.Context, tenant Tenant, userID string) (TenantMembership, error)
+	ListTenantMembers(ctx context.Context, tenant Tenant) ([]TenantMember, error)
+	// InviteTenantMember invites the active user with email to the tenant
+	// with role. Emails that match no such user, or an existing member, are
+	// ignored so the result never reveals which accounts exist.
+	InviteTenantMember(ctx context.Context, tenant Tenant, invitedBy, email string, role TenantRole) error
+	ListTenantInvitations(ctx context.Context, userID string) ([]TenantInvitation, error)
+	AcceptTenantInvitation(ctx context.Context, tenant Tenant, userID string) (TenantMembership, error)
+	DeclineTenantInvitation(ctx context.Context, tenant Tenant, userID string) error
+	// UpdateTenantMemberRole and RemoveTenantMember refuse to leave a tenant
+	// without an owner or to change a user's membership of their personal
+	// tenant.
+	UpdateTenantMemberRole(ctx context.Context, tenant Tenant, userID string, role TenantRole) (TenantMember, error)
+	RemoveTenantMember(ctx context.Context, tenant Tenant, userID string) error
+	SetSessionTenant(ctx context.Context, sessionID string, tenant Tenant) error
+	// ListActiveTenants returns every tenant with at least one member whose
+	// account is not disabled.
+	ListActiveTenants(ctx context.Context) ([]Tenant, error)
+}
+
 // AnalyticsStore reads dashboard and reporting projections.
 type AnalyticsStore interface {
 	GetStats(ctx context.Context, tenant Tenant, baseCurrency string) (*Stats, error)
@@ -59,6 +126,167 @@ type DiagnosticStore interface {
 	RecordExtractionDiagnostic(ctx context.Context, tenant Tenant, diagnostic api.ExtractionDiagnostic) error
 }
 
+// LLMUsageStore persists LLM call accounting and per-tenant token quotas.
+type LLMUsageStore interface {
+	RecordLLMUsage(ctx context.Context, tenant Tenant, record LLMUsageRecord) error
+	SumLLMUsageTokens(ctx context.Context, tenant Tenant, since time.Time) (int64, error)
+	ListLLMUsage(ctx context.Context, filter LLMUsageFilter) ([]LLMUsageBucket, error)
+	GetLLMUsageQuota(ctx context.Context, tenant Tenant) (LLMUsageQuota, error)
+	SetLLMUsageQuota(ctx context.Context, tenant Tenant, quota LLMUsageQuota) (LLMUsageQuota, error)
+}
+
+// LLMPromptStore persists admin-managed prompt versions that override the
+// embedded prompt catalog. Prompt versions are instance-wide.
+type LLMPromptStore interface {
+	CreateLLMPromptVersion(ctx context.Context, input CreateLLMPromptVersionInput) (LLMPromptVersion, error)
+	ListLLMPromptVersions(ctx context.Context, workflow, purpose string) ([]LLMPromptVersion, error)
+	GetActiveLLMPromptVersion(ctx context.Context, workflow, purpose string) (LLMPromptVersion, bool, error)
+	ActivateLLMPromptVersion(ctx context.Context, workflow, purpose string, version int) (LLMPromptVersion, error)
+	DeactivateLLMPromptVersions(ctx context.Context, workflow, purpose string) error
+}
+
+// SharedLedgerStore persists cost sharing between instance users. Calls are
+// scoped to userID's ledger memberships; ledgers the user does not belong to
+// report NotFound. Users join a ledger only by accepting an invitation from
+// its owner.
+type SharedLedgerStore interface {
+	CreateSharedLedger(ctx context.Context, userID string, input CreateSharedLedgerInput) (SharedLedger, error)
+	ListSharedLedgers(ctx context.Context, userID string) ([]SharedLedger, error)
+	GetSharedLedger(ctx context.Context, userID, ledgerID string) (SharedLedger, error)
+	// InviteSharedLedgerMember invites the active user with email to the
+	// ledger. Emails that match no such user, or an existing member, are
+	// ignored so the result never reveals which accounts exist.
+	InviteSharedLedgerMember(ctx context.Context, userID, ledgerID, email string) error
+	ListSharedLedgerInvitations(ctx context.Context, userID string) ([]SharedLedgerInvitation, error)
+	AcceptSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) (SharedLedger, error)
+	DeclineSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) error
+	ShareExpense(ctx context.Context, tenant Tenant, userID, ledgerID string, input ShareExpenseInput) (SharedExpense, error)
+	ListSharedExpenses(ctx context.Context, userID, ledgerID string) ([]SharedExpense, error)
+	DeleteSharedExpense(ctx context.Context, userID, ledgerID, expenseID string) error
+	RecordSettlement(ctx context.Context, userID, ledgerID string, input RecordSettlementInput) (Settlement, error)
+	ListSettlements(ctx context.Context, userID, ledgerID string) ([]Settlement, error)
+	ListLedgerBalances(ctx context.Context, userID, ledgerID string) ([]LedgerBalance, error)
+}
+
+// AttachmentStore keeps files attached to transactions. Attachments of a
+// transaction outside tenant report NotFound.
+type AttachmentStore interface {
+	CreateAttachment(ctx context.Context, tenant Tenant, transactionID string, input NewAttachment) (*Attachment, error)
+	ListAttachments(ctx context.Context, tenant Tenant, transactionID string) ([]Attachment, error)
+	// GetAttachment returns the attachment metadata with its decrypted content.
+	GetAttachment(ctx context.Context, tenant Tenant, transactionID, attachmentID string) (*Attachment, []byte, error)
+	DeleteAttachment(ctx context.Context, tenant Tenant, transactionID, attachmentID string) error
+}
+
+// TenantArchiveStore copies a tenant's data to and from a TenantArchive.
+type TenantArchiveStore interface {
+	ExportTenantArchive(ctx context.Context, tenant Tenant) (*TenantArchive, error)
+	// ImportTenantArchive applies archive to tenant in one transaction,
+	// resolving items that already exist with policy.
+	ImportTenantArchive(ctx context.Context, tenant Tenant, archive TenantArchive, policy ArchiveConflictPolicy) (TenantImportResult, error)
+}
+
+// BackupStore copies the whole database to and from logical backups and
+// keeps the backup schedule.
+type BackupStore interface {
+	GetBackupSettings(ctx context.Context) (BackupSettings, error)
+	PatchBackupSettings(ctx context.Context, patch BackupSettingsPatch) (BackupSettings, error)
+	// ClaimScheduledBackup marks a scheduled backup as started and reports
+	// whether one was due. Only one caller wins a claim.
+	ClaimScheduledBackup(ctx context.Context, now time.Time) (bool, error)
+	RecordBackupRun(ctx context.Context, run BackupRun) error
+	// DumpDatabase copies every table from a single snapshot to the writers
+	// open returns and describes what it copied.
+	DumpDatabase(ctx context.Context, open BackupTableWriter) (DatabaseSchema, error)
+	CheckBackupSchema(ctx context.Context, schema DatabaseSchema) (BackupSchemaCheck, error)
+	// RestoreDatabase replaces the contents of every table with the rows
+	// open returns, in one transaction.
+	RestoreDatabase(ctx context.Context, schema DatabaseSchema, open BackupTableReader) error
+}
+
+// AuditStore records security-relevant actions and keeps the audit log's
+// retention setting.
+type AuditStore interface {
+	RecordAuditEvent(ctx context.Context, event NewAuditEvent) error
+	// ListAuditEvents returns one page of matching events, newest first, and
+	// the number of events that match.
+	ListAuditEvents(ctx context.Context, filter AuditEventFilter) ([]AuditEvent, int, error)
+	GetAuditSettings(ctx context.Context) (AuditSettings, error)
+	PatchAuditSettings(ctx context.Context, patch AuditSettingsPatch) (AuditSettings, error)
+	// PruneAuditEvents deletes events that occurred before cutoff and reports
+	// how many it deleted.
+	PruneAuditEvents(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// WebhookStore keeps tenants' webhook subscriptions and the queue of
+// deliveries to them. Events are queued by the stores that record them.
+type WebhookStore interface {
+	ListWebhooks(ctx context.Context, tenant Tenant) ([]Webhook, error)
+	GetWebhook(ctx context.Context, tenant Tenant, id string) (*Webhook, error)
+	CreateWebhook(ctx context.Context, tenant Tenant, input NewWebhook) (*Webhook, error)
+	UpdateWebhook(ctx context.Context, tenant Tenant, id string, patch WebhookPatch) (*Webhook, error)
+	DeleteWebhook(ctx context.Context, tenant Tenant, id string) error
+	// ListWebhookDeliveries returns a webhook's most recent deliveries,
+	// newest first.
+	ListWebhookDeliveries(ctx context.Context, tenant Tenant, webhookID string, limit int) ([]WebhookDelivery, error)
+	// ClaimWebhookDeliveries leases up to limit due deliveries of enabled
+	// webhooks across all tenants. A claimed delivery is not claimed again
+	// until lease has passed, so one that is never completed is retried.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDispatch, error)
+	CompleteWebhookDelivery(ctx context.Context, id string, attempt WebhookAttempt) error
+	// PruneWebhookDeliveries deletes delivered and failed deliveries
+	// completed before cutoff and reports how many it deleted.
+	PruneWebhookDeliveries(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// NotificationStore keeps users' notification channels and preferences and
+// the queue of notifications to send. Alerts are queued by the stores that
+// record what they are about; digests are queued by the notifier.
+type NotificationStore interface {
+	GetNotificationPreferences(ctx context.Context, tenant Tenant, userID string) (*NotificationPreferences, error)
+	UpdateNotificationPreferences(
+		ctx context.Context,
+		tenant Tenant,
+		userID string,
+		patch NotificationPreferencesPatch,
+	) (*NotificationPreferences, error)
+	ListNotificationChannels(ctx context.Context, tenant Tenant, userID string) ([]NotificationChannel, error)
+	// OpenNotificationChannel returns a channel with its token decrypted.
+	OpenNotificationChannel(ctx context.Context, tenant Tenant, userID, id string) (*NotificationChannel, string, error)
+	CreateNotificationChannel(ctx context.Context, tenant Tenant, userID string, input NewNotificationChannel) (*NotificationChannel, error)
+	UpdateNotificationChannel(
+		ctx context.Context,
+		tenant Tenant,
+		userID, id string,
+		patch NotificationChannelPatch,
+	) (*NotificationChannel, error)
+	DeleteNotificationChannel(ctx context.Context, tenant Tenant, userID, id string) error
+	// ListDigestSubscriptions returns every weekly digest schedule of users
+	// who are still members of the tenant.
+	ListDigestSubscriptions(ctx context.Context) ([]DigestSubscription, error)
+	// GetSpendDigest summarizes spending from from up to but excluding to.
+	GetSpendDigest(ctx context.Context, tenant Tenant, from, to time.Time) (*SpendDigest, error)
+	// QueueDigest queues a digest to the user's enabled channels unless one
+	// due at or after dueAt was already queued, and reports whether it did.
+	QueueDigest(ctx context.Context, tenant Tenant, userID string, dueAt time.Time, digest SpendDigest) (bool, error)
+	// ClaimNotificationDeliveries leases up to limit due deliveries through
+	// enabled channels, like ClaimWebhookDeliveries.
+	ClaimNotificationDeliveries(ctx context.Context, limit int, lease time.Duration) ([]NotificationDispatch, error)
+	CompleteNotificationDelivery(ctx context.Context, id string, attempt NotificationAttempt) error
+	// PruneNotificationDeliveries deletes delivered and failed deliveries
+	// completed before cutoff and reports how many it deleted.
+	PruneNotificationDeliveries(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// SecretStore re-encrypts sealed values with the active secret key after a
+// key rotation.
+type SecretStore interface {
+	CountSealedSecrets(ctx context.Context, kind SealedSecretKind) (int64, error)
+	// ResealSecrets re-encrypts up to limit values of kind that come after the
+	// cursor. Values sealed with the active key are counted but not written.
+	ResealSecrets(ctx context.Context, kind SealedSecretKind, after string, limit int) (ResealBatch, error)
+}
+
 // RuleStore persists system and user extraction rules.
 type RuleStore interface {
 	ListRules(ctx context.Context, tenant Tenant) ([]RuleRow, error)
@@ -141,6 +369,9 @@ type TaxonomyStore interface {
 type TransactionStore interface {
 	ListTransactions(ctx context.Context, tenant Tenant, f ListFilter) ([]Transaction, TransactionListResult, error)
 	GetTransaction(ctx context.Context, tenant Tenant, id string) (*Transaction, error)
+	CreateTransaction(ctx context.Context, tenant Tenant, input CreateTransactionInput) (*Transaction, error)
+	DeleteTransaction(ctx context.Context, tenant Tenant, id string) error
+	SetTransactionSplits(ctx context.Context, tenant Tenant, id string, splits []TransactionSplitInput) error
 	UpdateDescription(ctx context.Context, tenant Tenant, id, description string) error
 	AddLabel(ctx context.Context, tenant Tenant, transactionID, label string) error
 	AddLabels(ctx context.Context, tenant Tenant, transactionID string, labels []string) error
@@ -148,6 +379,9 @@ type TransactionStore interface {
 	SearchTransactions(ctx context.Context, tenant Tenant, query string, f ListFilter) ([]Transaction, TransactionListResult, error)
 	GetFacets(ctx context.Context, tenant Tenant) (*Facets, error)
 	UpdateTransaction(ctx context.Context, tenant Tenant, id string, u TransactionUpdate) error
+	BulkUpdateTransactions(ctx context.Context, tenant Tenant, input BulkTransactionInput) (BulkTransactionResult, error)
+	ListTransactionHistory(ctx context.Context, tenant Tenant, id string) ([]TransactionChange, error)
+	RevertChangeBatch(ctx context.Context, tenant Tenant, batchID string) (ChangeBatchRevert, error)
 	MuteTransaction(ctx context.Context, tenant Tenant, id string, muted bool, reason string) error
 	UpdateMuteReason(ctx context.Context, tenant Tenant, id, reason string) error
 	UpdateMerchantReason(ctx context.Context, tenant Tenant, id, reason string) error
@@ -174,13 +408,25 @@ type HealthChecker interface {
 type Backend interface {
 	AuthStore
 	AnalyticsStore
+	AttachmentStore
+	AuditStore
+	BackupStore
 	CommunityStore
 	DiagnosticStore
+	LLMUsageStore
+	LLMPromptStore
+	MFAStore
 	RuleStore
 	RuntimeStore
 	ScanningStore
+	SecretStore
+	SharedLedgerStore
 	TaxonomyStore
+	TenantStore
+	TenantArchiveStore
 	TransactionStore
+	WebhookStore
+	NotificationStore
 	Seeder
 	TransactionBatchWriter
 	HealthChecker
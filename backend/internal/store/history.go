@@ -0,0 +1,70 @@
+package store
+
+import "time"
+
+// ChangeCause says which kind of write produced a transaction change.
+type ChangeCause string
+
+const (
+	// ChangeCauseUserEdit is a direct edit of one transaction or taxonomy item.
+	ChangeCauseUserEdit ChangeCause = "user_edit"
+	// ChangeCauseBulkEdit is a bulk transaction operation.
+	ChangeCauseBulkEdit ChangeCause = "bulk_edit"
+	// ChangeCauseMerchantMapping is a label, category, bucket, or mute pattern
+	// applied to every matching transaction.
+	ChangeCauseMerchantMapping ChangeCause = "merchant_mapping"
+	// ChangeCauseRuleEngine is a mapping applied automatically at ingestion.
+	ChangeCauseRuleEngine ChangeCause = "rule_engine"
+	// ChangeCauseReExtraction is an already stored email being extracted again.
+	ChangeCauseReExtraction ChangeCause = "re_extraction"
+	// ChangeCauseRevert is an undo of an earlier change batch.
+	ChangeCauseRevert ChangeCause = "revert"
+	// ChangeCauseImport is a tenant archive being imported.
+	ChangeCauseImport ChangeCause = "import"
+	// ChangeCauseSystem is any write that did not say why it happened.
+	ChangeCauseSystem ChangeCause = "system"
+)
+
+// ChangeFieldLabel is the TransactionChange field used for label changes. An
+// added label has no old value and a removed label has no new value.
+const ChangeFieldLabel = "label"
+
+// TransactionChange is one entry in a transaction's change history. Every
+// change made by one write shares a BatchID, which can be reverted as a unit.
+type TransactionChange struct {
+	ID            int64       `json:"id"`
+	BatchID       string      `json:"batch_id"`
+	TransactionID string      `json:"transaction_id"`
+	Field         string      `json:"field"`
+	OldValue      *string     `json:"old_value"`
+	NewValue      *string     `json:"new_value"`
+	Cause         ChangeCause `json:"cause"`
+	ActorID       string      `json:"actor_id,omitempty"`
+	ActorEmail    string      `json:"actor_email,omitempty"`
+	ChangedAt     time.Time   `json:"changed_at"`
+}
+
+// Change revert statuses reported per logged change.
+const (
+	ChangeReverted = "reverted"
+	// ChangeSkipped means the field has changed again since and was left alone.
+	ChangeSkipped = "skipped"
+)
+
+// ChangeBatchRevert reports the outcome of reverting a change batch. The
+// revert is logged as its own batch, BatchID, so it can be undone too.
+type ChangeBatchRevert struct {
+	BatchID         string               `json:"batch_id"`
+	RevertedBatchID string               `json:"reverted_batch_id"`
+	Reverted        int                  `json:"reverted"`
+	Skipped         int                  `json:"skipped"`
+	Results         []ChangeRevertResult `json:"results"`
+}
+
+// ChangeRevertResult is the outcome for one logged change.
+type ChangeRevertResult struct {
+	ChangeID      int64  `json:"change_id"`
+	TransactionID string `json:"transaction_id"`
+	Field         string `json:"field"`
+	Status        string `json:"status"`
+}
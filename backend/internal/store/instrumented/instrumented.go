@@ -13,30 +13,54 @@ import (
 
 // Store records telemetry around the full store surface.
 type Store struct {
-	auth         store.AuthStore
-	analytics    store.AnalyticsStore
-	community    store.CommunityStore
-	diagnostics  store.DiagnosticStore
-	rules        store.RuleStore
-	runtime      store.RuntimeStore
-	scanning     store.ScanningStore
-	taxonomy     store.TaxonomyStore
-	transactions store.TransactionStore
-	scope        *observability.Scope
+	auth          store.AuthStore
+	analytics     store.AnalyticsStore
+	archives      store.TenantArchiveStore
+	attachments   store.AttachmentStore
+	audit         store.AuditStore
+	backups       store.BackupStore
+	community     store.CommunityStore
+	diagnostics   store.DiagnosticStore
+	llmUsage      store.LLMUsageStore
+	llmPrompts    store.LLMPromptStore
+	mfa           store.MFAStore
+	notifications store.NotificationStore
+	rules         store.RuleStore
+	runtime       store.RuntimeStore
+	scanning      store.ScanningStore
+	secrets       store.SecretStore
+	sharedLedgers store.SharedLedgerStore
+	taxonomy      store.TaxonomyStore
+	tenants       store.TenantStore
+	transactions  store.TransactionStore
+	webhooks      store.WebhookStore
+	scope         *observability.Scope
 }
 
 // StoreDeps groups backend capabilities by the behavior boundaries
 // that the instrumentation wrapper decorates.
 type StoreDeps struct {
-	Auth         store.AuthStore
-	Analytics    store.AnalyticsStore
-	Community    store.CommunityStore
-	Diagnostics  store.DiagnosticStore
-	Rules        store.RuleStore
-	Runtime      store.RuntimeStore
-	Scanning     store.ScanningStore
-	Taxonomy     store.TaxonomyStore
-	Transactions store.TransactionStore
+	Auth          store.AuthStore
+	Analytics     store.AnalyticsStore
+	Archives      store.TenantArchiveStore
+	Attachments   store.AttachmentStore
+	Audit         store.AuditStore
+	Backups       store.BackupStore
+	Community     store.CommunityStore
+	Diagnostics   store.DiagnosticStore
+	LLMUsage      store.LLMUsageStore
+	LLMPrompts    store.LLMPromptStore
+	MFA           store.MFAStore
+	Notifications store.NotificationStore
+	Rules         store.RuleStore
+	Runtime       store.RuntimeStore
+	Scanning      store.ScanningStore
+	Secrets       store.SecretStore
+	SharedLedgers store.SharedLedgerStore
+	Taxonomy      store.TaxonomyStore
+	Tenants       store.TenantStore
+	Transactions  store.TransactionStore
+	Webhooks      store.WebhookStore
 }
 
 func NewStore(deps StoreDeps, scope *observability.Scope, logger *slog.Logger) *Store {
@@ -47,16 +71,28 @@ func NewStore(deps StoreDeps, scope *observability.Scope, logger *slog.Logger) *
 		scope = observability.NewScope(logger, "store")
 	}
 	return &Store{
-		auth:         deps.Auth,
-		analytics:    deps.Analytics,
-		community:    deps.Community,
-		diagnostics:  deps.Diagnostics,
-		rules:        deps.Rules,
-		runtime:      deps.Runtime,
-		scanning:     deps.Scanning,
-		taxonomy:     deps.Taxonomy,
-		transactions: deps.Transactions,
-		scope:        scope,
+		auth:          deps.Auth,
+		analytics:     deps.Analytics,
+		archives:      deps.Archives,
+		attachments:   deps.Attachments,
+		audit:         deps.Audit,
+		backups:       deps.Backups,
+		community:     deps.Community,
+		diagnostics:   deps.Diagnostics,
+		llmUsage:      deps.LLMUsage,
+		llmPrompts:    deps.LLMPrompts,
+		mfa:           deps.MFA,
+		notifications: deps.Notifications,
+		rules:         deps.Rules,
+		runtime:       deps.Runtime,
+		scanning:      deps.Scanning,
+		secrets:       deps.Secrets,
+		sharedLedgers: deps.SharedLedgers,
+		taxonomy:      deps.Taxonomy,
+		tenants:       deps.Tenants,
+		transactions:  deps.Transactions,
+		webhooks:      deps.Webhooks,
+		scope:         scope,
 	}
 }
 
@@ -149,6 +185,24 @@ func (s *Store) FindUserByID(ctx context.Context, id string) (*store.User, error
 	return user, err
 }
 
+func (s *Store) FindUserByOIDCSubject(ctx context.Context, issuer, subject string) (*store.User, error) {
+	ctx, span := s.scope.Start(ctx, "store.auth.find_user_by_oidc_subject")
+	defer span.End()
+
+	user, err := s.auth.FindUserByOIDCSubject(ctx, issuer, subject)
+	s.recordOperation(ctx, "auth.find_user_by_oidc_subject", err)
+	return user, err
+}
+
+func (s *Store) LinkOIDCSubject(ctx context.Context, userID, issuer, subject string) error {
+	ctx, span := s.scope.Start(ctx, "store.auth.link_oidc_subject")
+	defer span.End()
+
+	err := s.auth.LinkOIDCSubject(ctx, userID, issuer, subject)
+	s.recordOperation(ctx, "auth.link_oidc_subject", err)
+	return err
+}
+
 func (s *Store) CreateSession(ctx context.Context, input store.CreateSessionInput) (*store.Session, error) {
 	ctx, span := s.scope.Start(ctx, "store.auth.create_session")
 	defer span.End()
@@ -176,6 +230,42 @@ func (s *Store) RevokeSession(ctx context.Context, id string) error {
 	return err
 }
 
+func (s *Store) ListSessions(ctx context.Context, userID string) ([]store.Session, error) {
+	ctx, span := s.scope.Start(ctx, "store.auth.list_sessions")
+	defer span.End()
+
+	sessions, err := s.auth.ListSessions(ctx, userID)
+	s.recordOperation(ctx, "auth.list_sessions", err)
+	return sessions, err
+}
+
+func (s *Store) RevokeUserSession(ctx context.Context, userID, id string) error {
+	ctx, span := s.scope.Start(ctx, "store.auth.revoke_user_session")
+	defer span.End()
+
+	err := s.auth.RevokeUserSession(ctx, userID, id)
+	s.recordOperation(ctx, "auth.revoke_user_session", err)
+	return err
+}
+
+func (s *Store) RevokeOtherSessions(ctx context.Context, userID, keepID string) (int64, error) {
+	ctx, span := s.scope.Start(ctx, "store.auth.revoke_other_sessions")
+	defer span.End()
+
+	revoked, err := s.auth.RevokeOtherSessions(ctx, userID, keepID)
+	s.recordOperation(ctx, "auth.revoke_other_sessions", err)
+	return revoked, err
+}
+
+func (s *Store) TouchSession(ctx context.Context, id, ipAddress string) error {
+	ctx, span := s.scope.Start(ctx, "store.auth.touch_session")
+	defer span.End()
+
+	err := s.auth.TouchSession(ctx, id, ipAddress)
+	s.recordOperation(ctx, "auth.touch_session", err)
+	return err
+}
+
 func (s *Store) CreateAccessToken(ctx context.Context, input store.CreateAccessTokenInput) (*store.AccessToken, error) {
 	ctx, span := s.scope.Start(ctx, "store.auth.create_access_token")
 	defer span.End()
@@ -851,6 +941,68 @@ func (s *Store) UpdateTransaction(ctx context.Context, tenant store.Tenant, id s
 	return err
 }
 
+func (s *Store) CreateTransaction(
+	ctx context.Context,
+	tenant store.Tenant,
+	input store.CreateTransactionInput,
+) (*store.Transaction, error) {
+	ctx, span := s.scope.Start(ctx, "store.transactions.create")
+	defer span.End()
+
+	txn, err := s.transactions.CreateTransaction(ctx, tenant, input)
+	s.recordOperation(ctx, "transactions.create", err)
+	return txn, err
+}
+
+func (s *Store) DeleteTransaction(ctx context.Context, tenant store.Tenant, id string) error {
+	ctx, span := s.scope.Start(ctx, "store.transactions.delete")
+	defer span.End()
+
+	err := s.transactions.DeleteTransaction(ctx, tenant, id)
+	s.recordOperation(ctx, "transactions.delete", err)
+	return err
+}
+
+func (s *Store) BulkUpdateTransactions(
+	ctx context.Context,
+	tenant store.Tenant,
+	input store.BulkTransactionInput,
+) (store.BulkTransactionResult, error) {
+	ctx, span := s.scope.Start(ctx, "store.transactions.bulk_update")
+	defer span.End()
+
+	result, err := s.transactions.BulkUpdateTransactions(ctx, tenant, input)
+	s.recordOperation(ctx, "transactions.bulk_update", err)
+	return result, err
+}
+
+func (s *Store) ListTransactionHistory(ctx context.Context, tenant store.Tenant, id string) ([]store.TransactionChange, error) {
+	ctx, span := s.scope.Start(ctx, "store.transactions.list_history")
+	defer span.End()
+
+	changes, err := s.transactions.ListTransactionHistory(ctx, tenant, id)
+	s.recordOperation(ctx, "transactions.list_history", err)
+	return changes, err
+}
+
+func (s *Store) RevertChangeBatch(ctx context.Context, tenant store.Tenant, batchID string) (store.ChangeBatchRevert, error) {
+	ctx, span := s.scope.Start(ctx, "store.transactions.revert_change_batch")
+	defer span.End()
+
+	result, err := s.transactions.RevertChangeBatch(ctx, tenant, batchID)
+	s.recordOperation(ctx, "transactions.revert_change_batch", err)
+	return result, err
+}
+
+func (s *Store) SetTransactionSplits(ctx context.Context, tenant store.Tenant, id string, splits []store.TransactionSplitInput) error {
+	ctx, span := s.scope.Start(ctx, "store.transactions.set_splits")
+	defer span.End()
+
+	err := s.transactions.SetTransactionSplits(ctx, tenant, id, splits)
+	s.recordOperation(ctx, "transactions.set_splits", err)
+	return err
+}
+
 func (s *Store) MuteTransaction(ctx context.Context, tenant store.Tenant, id string, muted bool, reason string) error {
 	ctx, span := s.scope.Start(ctx, "store.transactions.mute")
 	defer span.End()
@@ -1123,3 +1275,852 @@ func (s *Store) RecordExtractionDiagnostic(ctx context.Context, tenant store.Ten
 	s.recordOperation(ctx, "diagnostics.record_tenant_extraction", err)
 	return err
 }
+
+func (s *Store) RecordLLMUsage(ctx context.Context, tenant store.Tenant, record store.LLMUsageRecord) error {
+	ctx, span := s.scope.Start(ctx, "store.llm_usage.record")
+	defer span.End()
+
+	err := s.llmUsage.RecordLLMUsage(ctx, tenant, record)
+	s.recordOperation(ctx, "llm_usage.record", err)
+	return err
+}
+
+func (s *Store) SumLLMUsageTokens(ctx context.Context, tenant store.Tenant, since time.Time) (int64, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_usage.sum_tokens")
+	defer span.End()
+
+	total, err := s.llmUsage.SumLLMUsageTokens(ctx, tenant, since)
+	s.recordOperation(ctx, "llm_usage.sum_tokens", err)
+	return total, err
+}
+
+func (s *Store) ListLLMUsage(ctx context.Context, filter store.LLMUsageFilter) ([]store.LLMUsageBucket, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_usage.list")
+	defer span.End()
+
+	buckets, err := s.llmUsage.ListLLMUsage(ctx, filter)
+	s.recordOperation(ctx, "llm_usage.list", err)
+	return buckets, err
+}
+
+func (s *Store) GetLLMUsageQuota(ctx context.Context, tenant store.Tenant) (store.LLMUsageQuota, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_usage.get_quota")
+	defer span.End()
+
+	quota, err := s.llmUsage.GetLLMUsageQuota(ctx, tenant)
+	s.recordOperation(ctx, "llm_usage.get_quota", err)
+	return quota, err
+}
+
+func (s *Store) SetLLMUsageQuota(ctx context.Context, tenant store.Tenant, quota store.LLMUsageQuota) (store.LLMUsageQuota, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_usage.set_quota")
+	defer span.End()
+
+	saved, err := s.llmUsage.SetLLMUsageQuota(ctx, tenant, quota)
+	s.recordOperation(ctx, "llm_usage.set_quota", err)
+	return saved, err
+}
+
+func (s *Store) CreateLLMPromptVersion(ctx context.Context, input store.CreateLLMPromptVersionInput) (store.LLMPromptVersion, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_prompts.create_version")
+	defer span.End()
+
+	version, err := s.llmPrompts.CreateLLMPromptVersion(ctx, input)
+	s.recordOperation(ctx, "llm_prompts.create_version", err)
+	return version, err
+}
+
+func (s *Store) ListLLMPromptVersions(ctx context.Context, workflow, purpose string) ([]store.LLMPromptVersion, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_prompts.list_versions")
+	defer span.End()
+
+	versions, err := s.llmPrompts.ListLLMPromptVersions(ctx, workflow, purpose)
+	s.recordOperation(ctx, "llm_prompts.list_versions", err)
+	return versions, err
+}
+
+func (s *Store) GetActiveLLMPromptVersion(ctx context.Context, workflow, purpose string) (store.LLMPromptVersion, bool, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_prompts.get_active_version")
+	defer span.End()
+
+	version, found, err := s.llmPrompts.GetActiveLLMPromptVersion(ctx, workflow, purpose)
+	s.recordOperation(ctx, "llm_prompts.get_active_version", err)
+	return version, found, err
+}
+
+func (s *Store) ActivateLLMPromptVersion(ctx context.Context, workflow, purpose string, version int) (store.LLMPromptVersion, error) {
+	ctx, span := s.scope.Start(ctx, "store.llm_prompts.activate_version")
+	defer span.End()
+
+	activated, err := s.llmPrompts.ActivateLLMPromptVersion(ctx, workflow, purpose, version)
+	s.recordOperation(ctx, "llm_prompts.activate_version", err)
+	return activated, err
+}
+
+func (s *Store) DeactivateLLMPromptVersions(ctx context.Context, workflow, purpose string) error {
+	ctx, span := s.scope.Start(ctx, "store.llm_prompts.deactivate_versions")
+	defer span.End()
+
+	err := s.llmPrompts.DeactivateLLMPromptVersions(ctx, workflow, purpose)
+	s.recordOperation(ctx, "llm_prompts.deactivate_versions", err)
+	return err
+}
+
+func (s *Store) CreateSharedLedger(ctx context.Context, userID string, input store.CreateSharedLedgerInput) (store.SharedLedger, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.create")
+	defer span.End()
+
+	ledger, err := s.sharedLedgers.CreateSharedLedger(ctx, userID, input)
+	s.recordOperation(ctx, "shared_ledgers.create", err)
+	return ledger, err
+}
+
+func (s *Store) ListSharedLedgers(ctx context.Context, userID string) ([]store.SharedLedger, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.list")
+	defer span.End()
+
+	ledgers, err := s.sharedLedgers.ListSharedLedgers(ctx, userID)
+	s.recordOperation(ctx, "shared_ledgers.list", err)
+	return ledgers, err
+}
+
+func (s *Store) GetSharedLedger(ctx context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.get")
+	defer span.End()
+
+	ledger, err := s.sharedLedgers.GetSharedLedger(ctx, userID, ledgerID)
+	s.recordOperation(ctx, "shared_ledgers.get", err)
+	return ledger, err
+}
+
+func (s *Store) InviteSharedLedgerMember(ctx context.Context, userID, ledgerID, email string) error {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.invite_member")
+	defer span.End()
+
+	err := s.sharedLedgers.InviteSharedLedgerMember(ctx, userID, ledgerID, email)
+	s.recordOperation(ctx, "shared_ledgers.invite_member", err)
+	return err
+}
+
+func (s *Store) ListSharedLedgerInvitations(ctx context.Context, userID string) ([]store.SharedLedgerInvitation, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.list_invitations")
+	defer span.End()
+
+	invitations, err := s.sharedLedgers.ListSharedLedgerInvitations(ctx, userID)
+	s.recordOperation(ctx, "shared_ledgers.list_invitations", err)
+	return invitations, err
+}
+
+func (s *Store) AcceptSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.accept_invitation")
+	defer span.End()
+
+	ledger, err := s.sharedLedgers.AcceptSharedLedgerInvitation(ctx, userID, ledgerID)
+	s.recordOperation(ctx, "shared_ledgers.accept_invitation", err)
+	return ledger, err
+}
+
+func (s *Store) DeclineSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) error {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.decline_invitation")
+	defer span.End()
+
+	err := s.sharedLedgers.DeclineSharedLedgerInvitation(ctx, userID, ledgerID)
+	s.recordOperation(ctx, "shared_ledgers.decline_invitation", err)
+	return err
+}
+
+func (s *Store) ShareExpense(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, ledgerID string,
+	input store.ShareExpenseInput,
+) (store.SharedExpense, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.share_expense")
+	defer span.End()
+
+	expense, err := s.sharedLedgers.ShareExpense(ctx, tenant, userID, ledgerID, input)
+	s.recordOperation(ctx, "shared_ledgers.share_expense", err)
+	return expense, err
+}
+
+func (s *Store) ListSharedExpenses(ctx context.Context, userID, ledgerID string) ([]store.SharedExpense, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.list_expenses")
+	defer span.End()
+
+	expenses, err := s.sharedLedgers.ListSharedExpenses(ctx, userID, ledgerID)
+	s.recordOperation(ctx, "shared_ledgers.list_expenses", err)
+	return expenses, err
+}
+
+func (s *Store) DeleteSharedExpense(ctx context.Context, userID, ledgerID, expenseID string) error {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.delete_expense")
+	defer span.End()
+
+	err := s.sharedLedgers.DeleteSharedExpense(ctx, userID, ledgerID, expenseID)
+	s.recordOperation(ctx, "shared_ledgers.delete_expense", err)
+	return err
+}
+
+func (s *Store) RecordSettlement(ctx context.Context, userID, ledgerID string, input store.RecordSettlementInput) (store.Settlement, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.record_settlement")
+	defer span.End()
+
+	settlement, err := s.sharedLedgers.RecordSettlement(ctx, userID, ledgerID, input)
+	s.recordOperation(ctx, "shared_ledgers.record_settlement", err)
+	return settlement, err
+}
+
+func (s *Store) ListSettlements(ctx context.Context, userID, ledgerID string) ([]store.Settlement, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.list_settlements")
+	defer span.End()
+
+	settlements, err := s.sharedLedgers.ListSettlements(ctx, userID, ledgerID)
+	s.recordOperation(ctx, "shared_ledgers.list_settlements", err)
+	return settlements, err
+}
+
+func (s *Store) ListLedgerBalances(ctx context.Context, userID, ledgerID string) ([]store.LedgerBalance, error) {
+	ctx, span := s.scope.Start(ctx, "store.shared_ledgers.list_balances")
+	defer span.End()
+
+	balances, err := s.sharedLedgers.ListLedgerBalances(ctx, userID, ledgerID)
+	s.recordOperation(ctx, "shared_ledgers.list_balances", err)
+	return balances, err
+}
+
+func (s *Store) CreateAttachment(
+	ctx context.Context,
+	tenant store.Tenant,
+	transactionID string,
+	input store.NewAttachment,
+) (*store.Attachment, error) {
+	ctx, span := s.scope.Start(ctx, "store.attachments.create")
+	defer span.End()
+
+	attachment, err := s.attachments.CreateAttachment(ctx, tenant, transactionID, input)
+	s.recordOperation(ctx, "attachments.create", err)
+	return attachment, err
+}
+
+func (s *Store) ListAttachments(ctx context.Context, tenant store.Tenant, transactionID string) ([]store.Attachment, error) {
+	ctx, span := s.scope.Start(ctx, "store.attachments.list")
+	defer span.End()
+
+	attachments, err := s.attachments.ListAttachments(ctx, tenant, transactionID)
+	s.recordOperation(ctx, "attachments.list", err)
+	return attachments, err
+}
+
+func (s *Store) GetAttachment(ctx context.Context, tenant store.Tenant, transactionID, attachmentID string) (*store.Attachment, []byte, error) {
+	ctx, span := s.scope.Start(ctx, "store.attachments.get")
+	defer span.End()
+
+	attachment, data, err := s.attachments.GetAttachment(ctx, tenant, transactionID, attachmentID)
+	s.recordOperation(ctx, "attachments.get", err)
+	return attachment, data, err
+}
+
+func (s *Store) DeleteAttachment(ctx context.Context, tenant store.Tenant, transactionID, attachmentID string) error {
+	ctx, span := s.scope.Start(ctx, "store.attachments.delete")
+	defer span.End()
+
+	err := s.attachments.DeleteAttachment(ctx, tenant, transactionID, attachmentID)
+	s.recordOperation(ctx, "attachments.delete", err)
+	return err
+}
+
+func (s *Store) ExportTenantArchive(ctx context.Context, tenant store.Tenant) (*store.TenantArchive, error) {
+	ctx, span := s.scope.Start(ctx, "store.archives.export")
+	defer span.End()
+
+	archive, err := s.archives.ExportTenantArchive(ctx, tenant)
+	s.recordOperation(ctx, "archives.export", err)
+	return archive, err
+}
+
+func (s *Store) ImportTenantArchive(
+	ctx context.Context,
+	tenant store.Tenant,
+	archive store.TenantArchive,
+	policy store.ArchiveConflictPolicy,
+) (store.TenantImportResult, error) {
+	ctx, span := s.scope.Start(ctx, "store.archives.import")
+	defer span.End()
+
+	result, err := s.archives.ImportTenantArchive(ctx, tenant, archive, policy)
+	s.recordOperation(ctx, "archives.import", err)
+	return result, err
+}
+
+func (s *Store) GetMFAFactors(ctx context.Context, userID string) (store.MFAFactors, error) {
+	ctx, span := s.scope.Start(ctx, "store.mfa.get_factors")
+	defer span.End()
+
+	factors, err := s.mfa.GetMFAFactors(ctx, userID)
+	s.recordOperation(ctx, "mfa.get_factors", err)
+	return factors, err
+}
+
+func (s *Store) GetTOTPFactor(ctx context.Context, userID string) (*store.TOTPFactor, error) {
+	ctx, span := s.scope.Start(ctx, "store.mfa.get_totp_factor")
+	defer span.End()
+
+	factor, err := s.mfa.GetTOTPFactor(ctx, userID)
+	s.recordOperation(ctx, "mfa.get_totp_factor", err)
+	return factor, err
+}
+
+func (s *Store) SetPendingTOTP(ctx context.Context, userID string, secret []byte) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.set_pending_totp")
+	defer span.End()
+
+	err := s.mfa.SetPendingTOTP(ctx, userID, secret)
+	s.recordOperation(ctx, "mfa.set_pending_totp", err)
+	return err
+}
+
+func (s *Store) ConfirmTOTP(ctx context.Context, userID string, step int64) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.confirm_totp")
+	defer span.End()
+
+	err := s.mfa.ConfirmTOTP(ctx, userID, step)
+	s.recordOperation(ctx, "mfa.confirm_totp", err)
+	return err
+}
+
+func (s *Store) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.use_totp_step")
+	defer span.End()
+
+	err := s.mfa.UseTOTPStep(ctx, userID, step)
+	s.recordOperation(ctx, "mfa.use_totp_step", err)
+	return err
+}
+
+func (s *Store) DeleteTOTP(ctx context.Context, userID string) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.delete_totp")
+	defer span.End()
+
+	err := s.mfa.DeleteTOTP(ctx, userID)
+	s.recordOperation(ctx, "mfa.delete_totp", err)
+	return err
+}
+
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.replace_recovery_codes")
+	defer span.End()
+
+	err := s.mfa.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+	s.recordOperation(ctx, "mfa.replace_recovery_codes", err)
+	return err
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.use_recovery_code")
+	defer span.End()
+
+	err := s.mfa.UseRecoveryCode(ctx, userID, codeHash)
+	s.recordOperation(ctx, "mfa.use_recovery_code", err)
+	return err
+}
+
+func (s *Store) CreatePasskey(ctx context.Context, input store.CreatePasskeyInput) (*store.Passkey, error) {
+	ctx, span := s.scope.Start(ctx, "store.mfa.create_passkey")
+	defer span.End()
+
+	passkey, err := s.mfa.CreatePasskey(ctx, input)
+	s.recordOperation(ctx, "mfa.create_passkey", err)
+	return passkey, err
+}
+
+func (s *Store) ListPasskeys(ctx context.Context, userID string) ([]store.Passkey, error) {
+	ctx, span := s.scope.Start(ctx, "store.mfa.list_passkeys")
+	defer span.End()
+
+	passkeys, err := s.mfa.ListPasskeys(ctx, userID)
+	s.recordOperation(ctx, "mfa.list_passkeys", err)
+	return passkeys, err
+}
+
+func (s *Store) UsePasskey(ctx context.Context, id string, signCount uint32) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.use_passkey")
+	defer span.End()
+
+	err := s.mfa.UsePasskey(ctx, id, signCount)
+	s.recordOperation(ctx, "mfa.use_passkey", err)
+	return err
+}
+
+func (s *Store) DeletePasskey(ctx context.Context, userID, id string) error {
+	ctx, span := s.scope.Start(ctx, "store.mfa.delete_passkey")
+	defer span.End()
+
+	err := s.mfa.DeletePasskey(ctx, userID, id)
+	s.recordOperation(ctx, "mfa.delete_passkey", err)
+	return err
+}
+
+func (s *Store) GetMFASettings(ctx context.Context) (store.MFASettings, error) {
+	ctx, span := s.scope.Start(ctx, "store.mfa.get_settings")
+	defer span.End()
+
+	settings, err := s.mfa.GetMFASettings(ctx)
+	s.recordOperation(ctx, "mfa.get_settings", err)
+	return settings, err
+}
+
+func (s *Store) PatchMFASettings(ctx context.Context, patch store.MFASettingsPatch) (store.MFASettings, error) {
+	ctx, span := s.scope.Start(ctx, "store.mfa.patch_settings")
+	defer span.End()
+
+	settings, err := s.mfa.PatchMFASettings(ctx, patch)
+	s.recordOperation(ctx, "mfa.patch_settings", err)
+	return settings, err
+}
+
+func (s *Store) GetBackupSettings(ctx context.Context) (store.BackupSettings, error) {
+	ctx, span := s.scope.Start(ctx, "store.backups.get_settings")
+	defer span.End()
+
+	settings, err := s.backups.GetBackupSettings(ctx)
+	s.recordOperation(ctx, "backups.get_settings", err)
+	return settings, err
+}
+
+func (s *Store) PatchBackupSettings(ctx context.Context, patch store.BackupSettingsPatch) (store.BackupSettings, error) {
+	ctx, span := s.scope.Start(ctx, "store.backups.patch_settings")
+	defer span.End()
+
+	settings, err := s.backups.PatchBackupSettings(ctx, patch)
+	s.recordOperation(ctx, "backups.patch_settings", err)
+	return settings, err
+}
+
+func (s *Store) ClaimScheduledBackup(ctx context.Context, now time.Time) (bool, error) {
+	ctx, span := s.scope.Start(ctx, "store.backups.claim_scheduled")
+	defer span.End()
+
+	claimed, err := s.backups.ClaimScheduledBackup(ctx, now)
+	s.recordOperation(ctx, "backups.claim_scheduled", err)
+	return claimed, err
+}
+
+func (s *Store) RecordBackupRun(ctx context.Context, run store.BackupRun) error {
+	ctx, span := s.scope.Start(ctx, "store.backups.record_run")
+	defer span.End()
+
+	err := s.backups.RecordBackupRun(ctx, run)
+	s.recordOperation(ctx, "backups.record_run", err)
+	return err
+}
+
+func (s *Store) DumpDatabase(ctx context.Context, open store.BackupTableWriter) (store.DatabaseSchema, error) {
+	ctx, span := s.scope.Start(ctx, "store.backups.dump")
+	defer span.End()
+
+	schema, err := s.backups.DumpDatabase(ctx, open)
+	s.recordOperation(ctx, "backups.dump", err)
+	return schema, err
+}
+
+func (s *Store) CheckBackupSchema(ctx context.Context, schema store.DatabaseSchema) (store.BackupSchemaCheck, error) {
+	ctx, span := s.scope.Start(ctx, "store.backups.check_schema")
+	defer span.End()
+
+	check, err := s.backups.CheckBackupSchema(ctx, schema)
+	s.recordOperation(ctx, "backups.check_schema", err)
+	return check, err
+}
+
+func (s *Store) RestoreDatabase(ctx context.Context, schema store.DatabaseSchema, open store.BackupTableReader) error {
+	ctx, span := s.scope.Start(ctx, "store.backups.restore")
+	defer span.End()
+
+	err := s.backups.RestoreDatabase(ctx, schema, open)
+	s.recordOperation(ctx, "backups.restore", err)
+	return err
+}
+
+func (s *Store) RecordAuditEvent(ctx context.Context, event store.NewAuditEvent) error {
+	ctx, span := s.scope.Start(ctx, "store.audit.record")
+	defer span.End()
+
+	err := s.audit.RecordAuditEvent(ctx, event)
+	s.recordOperation(ctx, "audit.record", err)
+	return err
+}
+
+func (s *Store) ListAuditEvents(ctx context.Context, filter store.AuditEventFilter) ([]store.AuditEvent, int, error) {
+	ctx, span := s.scope.Start(ctx, "store.audit.list")
+	defer span.End()
+
+	events, total, err := s.audit.ListAuditEvents(ctx, filter)
+	s.recordOperation(ctx, "audit.list", err)
+	return events, total, err
+}
+
+func (s *Store) GetAuditSettings(ctx context.Context) (store.AuditSettings, error) {
+	ctx, span := s.scope.Start(ctx, "store.audit.get_settings")
+	defer span.End()
+
+	settings, err := s.audit.GetAuditSettings(ctx)
+	s.recordOperation(ctx, "audit.get_settings", err)
+	return settings, err
+}
+
+func (s *Store) PatchAuditSettings(ctx context.Context, patch store.AuditSettingsPatch) (store.AuditSettings, error) {
+	ctx, span := s.scope.Start(ctx, "store.audit.patch_settings")
+	defer span.End()
+
+	settings, err := s.audit.PatchAuditSettings(ctx, patch)
+	s.recordOperation(ctx, "audit.patch_settings", err)
+	return settings, err
+}
+
+func (s *Store) PruneAuditEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, span := s.scope.Start(ctx, "store.audit.prune")
+	defer span.End()
+
+	deleted, err := s.audit.PruneAuditEvents(ctx, cutoff)
+	s.recordOperation(ctx, "audit.prune", err)
+	return deleted, err
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, tenant store.Tenant) ([]store.Webhook, error) {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.list")
+	defer span.End()
+
+	webhooks, err := s.webhooks.ListWebhooks(ctx, tenant)
+	s.recordOperation(ctx, "webhooks.list", err)
+	return webhooks, err
+}
+
+func (s *Store) GetWebhook(ctx context.Context, tenant store.Tenant, id string) (*store.Webhook, error) {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.get")
+	defer span.End()
+
+	webhook, err := s.webhooks.GetWebhook(ctx, tenant, id)
+	s.recordOperation(ctx, "webhooks.get", err)
+	return webhook, err
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, tenant store.Tenant, input store.NewWebhook) (*store.Webhook, error) {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.create")
+	defer span.End()
+
+	webhook, err := s.webhooks.CreateWebhook(ctx, tenant, input)
+	s.recordOperation(ctx, "webhooks.create", err)
+	return webhook, err
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, tenant store.Tenant, id string, patch store.WebhookPatch) (*store.Webhook, error) {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.update")
+	defer span.End()
+
+	webhook, err := s.webhooks.UpdateWebhook(ctx, tenant, id, patch)
+	s.recordOperation(ctx, "webhooks.update", err)
+	return webhook, err
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, tenant store.Tenant, id string) error {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.delete")
+	defer span.End()
+
+	err := s.webhooks.DeleteWebhook(ctx, tenant, id)
+	s.recordOperation(ctx, "webhooks.delete", err)
+	return err
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, tenant store.Tenant, webhookID string, limit int) ([]store.WebhookDelivery, error) {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.list_deliveries")
+	defer span.End()
+
+	deliveries, err := s.webhooks.ListWebhookDeliveries(ctx, tenant, webhookID, limit)
+	s.recordOperation(ctx, "webhooks.list_deliveries", err)
+	return deliveries, err
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.WebhookDispatch, error) {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.claim_deliveries")
+	defer span.End()
+
+	dispatches, err := s.webhooks.ClaimWebhookDeliveries(ctx, limit, lease)
+	s.recordOperation(ctx, "webhooks.claim_deliveries", err)
+	return dispatches, err
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, id string, attempt store.WebhookAttempt) error {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.complete_delivery")
+	defer span.End()
+
+	err := s.webhooks.CompleteWebhookDelivery(ctx, id, attempt)
+	s.recordOperation(ctx, "webhooks.complete_delivery", err)
+	return err
+}
+
+func (s *Store) PruneWebhookDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, span := s.scope.Start(ctx, "store.webhooks.prune_deliveries")
+	defer span.End()
+
+	deleted, err := s.webhooks.PruneWebhookDeliveries(ctx, cutoff)
+	s.recordOperation(ctx, "webhooks.prune_deliveries", err)
+	return deleted, err
+}
+
+func (s *Store) GetNotificationPreferences(ctx context.Context, tenant store.Tenant, userID string) (*store.NotificationPreferences, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.get_preferences")
+	defer span.End()
+
+	prefs, err := s.notifications.GetNotificationPreferences(ctx, tenant, userID)
+	s.recordOperation(ctx, "notifications.get_preferences", err)
+	return prefs, err
+}
+
+func (s *Store) UpdateNotificationPreferences(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	patch store.NotificationPreferencesPatch,
+) (*store.NotificationPreferences, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.update_preferences")
+	defer span.End()
+
+	prefs, err := s.notifications.UpdateNotificationPreferences(ctx, tenant, userID, patch)
+	s.recordOperation(ctx, "notifications.update_preferences", err)
+	return prefs, err
+}
+
+func (s *Store) ListNotificationChannels(ctx context.Context, tenant store.Tenant, userID string) ([]store.NotificationChannel, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.list_channels")
+	defer span.End()
+
+	channels, err := s.notifications.ListNotificationChannels(ctx, tenant, userID)
+	s.recordOperation(ctx, "notifications.list_channels", err)
+	return channels, err
+}
+
+func (s *Store) OpenNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) (*store.NotificationChannel, string, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.open_channel")
+	defer span.End()
+
+	channel, token, err := s.notifications.OpenNotificationChannel(ctx, tenant, userID, id)
+	s.recordOperation(ctx, "notifications.open_channel", err)
+	return channel, token, err
+}
+
+func (s *Store) CreateNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	input store.NewNotificationChannel,
+) (*store.NotificationChannel, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.create_channel")
+	defer span.End()
+
+	channel, err := s.notifications.CreateNotificationChannel(ctx, tenant, userID, input)
+	s.recordOperation(ctx, "notifications.create_channel", err)
+	return channel, err
+}
+
+func (s *Store) UpdateNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, id string,
+	patch store.NotificationChannelPatch,
+) (*store.NotificationChannel, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.update_channel")
+	defer span.End()
+
+	channel, err := s.notifications.UpdateNotificationChannel(ctx, tenant, userID, id, patch)
+	s.recordOperation(ctx, "notifications.update_channel", err)
+	return channel, err
+}
+
+func (s *Store) DeleteNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) error {
+	ctx, span := s.scope.Start(ctx, "store.notifications.delete_channel")
+	defer span.End()
+
+	err := s.notifications.DeleteNotificationChannel(ctx, tenant, userID, id)
+	s.recordOperation(ctx, "notifications.delete_channel", err)
+	return err
+}
+
+func (s *Store) ListDigestSubscriptions(ctx context.Context) ([]store.DigestSubscription, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.list_digest_subscriptions")
+	defer span.End()
+
+	subscriptions, err := s.notifications.ListDigestSubscriptions(ctx)
+	s.recordOperation(ctx, "notifications.list_digest_subscriptions", err)
+	return subscriptions, err
+}
+
+func (s *Store) GetSpendDigest(ctx context.Context, tenant store.Tenant, from, to time.Time) (*store.SpendDigest, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.get_spend_digest")
+	defer span.End()
+
+	digest, err := s.notifications.GetSpendDigest(ctx, tenant, from, to)
+	s.recordOperation(ctx, "notifications.get_spend_digest", err)
+	return digest, err
+}
+
+func (s *Store) QueueDigest(ctx context.Context, tenant store.Tenant, userID string, dueAt time.Time, digest store.SpendDigest) (bool, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.queue_digest")
+	defer span.End()
+
+	queued, err := s.notifications.QueueDigest(ctx, tenant, userID, dueAt, digest)
+	s.recordOperation(ctx, "notifications.queue_digest", err)
+	return queued, err
+}
+
+func (s *Store) ClaimNotificationDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.NotificationDispatch, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.claim_deliveries")
+	defer span.End()
+
+	dispatches, err := s.notifications.ClaimNotificationDeliveries(ctx, limit, lease)
+	s.recordOperation(ctx, "notifications.claim_deliveries", err)
+	return dispatches, err
+}
+
+func (s *Store) CompleteNotificationDelivery(ctx context.Context, id string, attempt store.NotificationAttempt) error {
+	ctx, span := s.scope.Start(ctx, "store.notifications.complete_delivery")
+	defer span.End()
+
+	err := s.notifications.CompleteNotificationDelivery(ctx, id, attempt)
+	s.recordOperation(ctx, "notifications.complete_delivery", err)
+	return err
+}
+
+func (s *Store) PruneNotificationDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, span := s.scope.Start(ctx, "store.notifications.prune_deliveries")
+	defer span.End()
+
+	deleted, err := s.notifications.PruneNotificationDeliveries(ctx, cutoff)
+	s.recordOperation(ctx, "notifications.prune_deliveries", err)
+	return deleted, err
+}
+
+func (s *Store) CountSealedSecrets(ctx context.Context, kind store.SealedSecretKind) (int64, error) {
+	ctx, span := s.scope.Start(ctx, "store.secrets.count")
+	defer span.End()
+
+	count, err := s.secrets.CountSealedSecrets(ctx, kind)
+	s.recordOperation(ctx, "secrets.count", err)
+	return count, err
+}
+
+func (s *Store) ResealSecrets(ctx context.Context, kind store.SealedSecretKind, after string, limit int) (store.ResealBatch, error) {
+	ctx, span := s.scope.Start(ctx, "store.secrets.reseal")
+	defer span.End()
+
+	batch, err := s.secrets.ResealSecrets(ctx, kind, after, limit)
+	s.recordOperation(ctx, "secrets.reseal", err)
+	return batch, err
+}
+
+func (s *Store) CreateTenant(ctx context.Context, ownerUserID string, input store.CreateTenantInput) (store.TenantMembership, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.create")
+	defer span.End()
+
+	membership, err := s.tenants.CreateTenant(ctx, ownerUserID, input)
+	s.recordOperation(ctx, "tenants.create", err)
+	return membership, err
+}
+
+func (s *Store) ListActiveTenants(ctx context.Context) ([]store.Tenant, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.list_active")
+	defer span.End()
+
+	tenants, err := s.tenants.ListActiveTenants(ctx)
+	s.recordOperation(ctx, "tenants.list_active", err)
+	return tenants, err
+}
+
+func (s *Store) ListTenantMemberships(ctx context.Context, userID string) ([]store.TenantMembership, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.list_memberships")
+	defer span.End()
+
+	memberships, err := s.tenants.ListTenantMemberships(ctx, userID)
+	s.recordOperation(ctx, "tenants.list_memberships", err)
+	return memberships, err
+}
+
+func (s *Store) GetTenantMembership(ctx context.Context, tenant store.Tenant, userID string) (store.TenantMembership, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.get_membership")
+	defer span.End()
+
+	membership, err := s.tenants.GetTenantMembership(ctx, tenant, userID)
+	s.recordOperation(ctx, "tenants.get_membership", err)
+	return membership, err
+}
+
+func (s *Store) ListTenantMembers(ctx context.Context, tenant store.Tenant) ([]store.TenantMember, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.list_members")
+	defer span.End()
+
+	members, err := s.tenants.ListTenantMembers(ctx, tenant)
+	s.recordOperation(ctx, "tenants.list_members", err)
+	return members, err
+}
+
+func (s *Store) InviteTenantMember(ctx context.Context, tenant store.Tenant, invitedBy, email string, role store.TenantRole) error {
+	ctx, span := s.scope.Start(ctx, "store.tenants.invite_member")
+	defer span.End()
+
+	err := s.tenants.InviteTenantMember(ctx, tenant, invitedBy, email, role)
+	s.recordOperation(ctx, "tenants.invite_member", err)
+	return err
+}
+
+func (s *Store) ListTenantInvitations(ctx context.Context, userID string) ([]store.TenantInvitation, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.list_invitations")
+	defer span.End()
+
+	invitations, err := s.tenants.ListTenantInvitations(ctx, userID)
+	s.recordOperation(ctx, "tenants.list_invitations", err)
+	return invitations, err
+}
+
+func (s *Store) AcceptTenantInvitation(ctx context.Context, tenant store.Tenant, userID string) (store.TenantMembership, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.accept_invitation")
+	defer span.End()
+
+	membership, err := s.tenants.AcceptTenantInvitation(ctx, tenant, userID)
+	s.recordOperation(ctx, "tenants.accept_invitation", err)
+	return membership, err
+}
+
+func (s *Store) DeclineTenantInvitation(ctx context.Context, tenant store.Tenant, userID string) error {
+	ctx, span := s.scope.Start(ctx, "store.tenants.decline_invitation")
+	defer span.End()
+
+	err := s.tenants.DeclineTenantInvitation(ctx, tenant, userID)
+	s.recordOperation(ctx, "tenants.decline_invitation", err)
+	return err
+}
+
+func (s *Store) UpdateTenantMemberRole(ctx context.Context, tenant store.Tenant, userID string, role store.TenantRole) (store.TenantMember, error) {
+	ctx, span := s.scope.Start(ctx, "store.tenants.update_member_role")
+	defer span.End()
+
+	member, err := s.tenants.UpdateTenantMemberRole(ctx, tenant, userID, role)
+	s.recordOperation(ctx, "tenants.update_member_role", err)
+	return member, err
+}
+
+func (s *Store) RemoveTenantMember(ctx context.Context, tenant store.Tenant, userID string) error {
+	ctx, span := s.scope.Start(ctx, "store.tenants.remove_member")
+	defer span.End()
+
+	err := s.tenants.RemoveTenantMember(ctx, tenant, userID)
+	s.recordOperation(ctx, "tenants.remove_member", err)
+	return err
+}
+
+func (s *Store) SetSessionTenant(ctx context.Context, sessionID string, tenant store.Tenant) error {
+	ctx, span := s.scope.Start(ctx, "store.tenants.set_session_tenant")
+	defer span.End()
+
+	err := s.tenants.SetSessionTenant(ctx, sessionID, tenant)
+	s.recordOperation(ctx, "tenants.set_session_tenant", err)
+	return err
+}
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// SharedLedger groups instance users who split costs with each other. Members
+// see the ledger's shared expenses and settlements, never each other's
+// transactions.
+type SharedLedger struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	CreatedBy string               `json:"created_by"`
+	Members   []SharedLedgerMember `json:"members"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// SharedLedgerMember is a user who belongs to a shared ledger.
+type SharedLedgerMember struct {
+	UserID      string    `json:"user_id"`
+	DisplayName string    `json:"display_name"`
+	Email       string    `json:"email"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// CreateSharedLedgerInput creates a ledger owned by its creator, who is its
+// only member until invitees accept.
+type CreateSharedLedgerInput struct {
+	Name         string
+	InviteEmails []string
+}
+
+// SharedLedgerInvitation is an invitation to join a ledger that the invited
+// user has not yet accepted or declined.
+type SharedLedgerInvitation struct {
+	LedgerID      string    `json:"ledger_id"`
+	LedgerName    string    `json:"ledger_name"`
+	InvitedBy     string    `json:"invited_by"`
+	InvitedByName string    `json:"invited_by_name"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SharedExpense is a transaction, or one split of it, whose cost the payer
+// shares with other ledger members. Only the fields needed to recognise the
+// expense are exposed to other members.
+type SharedExpense struct {
+	ID            string         `json:"id"`
+	LedgerID      string         `json:"ledger_id"`
+	TransactionID string         `json:"transaction_id"`
+	SplitID       string         `json:"split_id,omitempty"`
+	PaidBy        string         `json:"paid_by"`
+	MerchantInfo  string         `json:"merchant_info"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Amount        float64        `json:"amount"`
+	Currency      string         `json:"currency"`
+	Shares        []ExpenseShare `json:"shares"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// ExpenseShare is the part of a shared expense one member owes the payer.
+type ExpenseShare struct {
+	UserID string  `json:"user_id"`
+	Ratio  float64 `json:"ratio"`
+	Amount float64 `json:"amount"`
+}
+
+// ShareExpenseInput shares a transaction, or one of its splits, with other
+// ledger members. The payer keeps whatever ratio the shares leave over.
+type ShareExpenseInput struct {
+	TransactionID string
+	SplitID       string
+	Shares        []ExpenseShareInput
+}
+
+// ExpenseShareInput assigns a fraction of an expense to a member.
+type ExpenseShareInput struct {
+	UserID string
+	Ratio  float64
+}
+
+// Settlement records money paid from one member to another outside Expensor.
+type Settlement struct {
+	ID         string    `json:"id"`
+	LedgerID   string    `json:"ledger_id"`
+	FromUserID string    `json:"from_user_id"`
+	ToUserID   string    `json:"to_user_id"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	Note       string    `json:"note,omitempty"`
+	SettledAt  time.Time `json:"settled_at"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RecordSettlementInput records a settlement. The recording user must be the
+// payer or the recipient.
+type RecordSettlementInput struct {
+	FromUserID string
+	ToUserID   string
+	Amount     float64
+	Currency   string
+	Note       string
+	SettledAt  time.Time
+}
+
+// LedgerBalance is a member's net position in one currency. Positive amounts
+// are owed to the member; negative amounts are owed by them.
+type LedgerBalance struct {
+	UserID   string  `json:"user_id"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// SettlementSuggestion is one payment that moves the ledger towards zero.
+type SettlementSuggestion struct {
+	FromUserID string  `json:"from_user_id"`
+	ToUserID   string  `json:"to_user_id"`
+	Currency   string  `json:"currency"`
+	Amount     float64 `json:"amount"`
+}
+
+// ValidateExpenseShares checks that shares name distinct members other than
+// the payer and together assign at most the whole expense.
+func ValidateExpenseShares(payerID string, shares []ExpenseShareInput) error {
+	const op = "store.ledgers.validate_shares"
+
+	if len(shares) == 0 {
+		return errors.E(op, errors.InvalidInput, errors.User("Share the expense with at least one member."))
+	}
+	seen := make(map[string]bool, len(shares))
+	var total float64
+	for _, share := range shares {
+		if share.UserID == payerID {
+			return errors.E(op, errors.InvalidInput, errors.User("The payer's own part is whatever the shares leave over."))
+		}
+		if seen[share.UserID] {
+			return errors.E(op, errors.InvalidInput, errors.User("Each member can only appear once in an expense."))
+		}
+		seen[share.UserID] = true
+		if share.Ratio <= 0 || share.Ratio > 1 {
+			return errors.E(op, errors.InvalidInput, errors.User("Share ratios must be greater than 0 and at most 1."))
+		}
+		total += share.Ratio
+	}
+	if total > 1+1e-9 {
+		return errors.E(op, errors.InvalidInput, errors.User("Share ratios cannot add up to more than 1."))
+	}
+	return nil
+}
+
+// ShareAmounts converts ratios into amounts owed, rounded to cents.
+func ShareAmounts(amount float64, shares []ExpenseShareInput) []ExpenseShare {
+	out := make([]ExpenseShare, 0, len(shares))
+	for _, share := range shares {
+		out = append(out, ExpenseShare{
+			UserID: share.UserID,
+			Ratio:  share.Ratio,
+			Amount: float64(toCents(amount*share.Ratio)) / 100,
+		})
+	}
+	return out
+}
+
+// SuggestSettlements returns payments that clear every balance. Per currency
+// it repeatedly pays the largest creditor from the largest debtor, which
+// needs at most one payment fewer than the members with a balance.
+func SuggestSettlements(balances []LedgerBalance) []SettlementSuggestion {
+	type position struct {
+		userID string
+		cents  int64
+	}
+	byCurrency := make(map[string][]position)
+	for _, balance := range balances {
+		if cents := toCents(balance.Amount); cents != 0 {
+			byCurrency[balance.Currency] = append(byCurrency[balance.Currency], position{balance.UserID, cents})
+		}
+	}
+	currencies := make([]string, 0, len(byCurrency))
+	for currency := range byCurrency {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	suggestions := []SettlementSuggestion{}
+	for _, currency := range currencies {
+		var creditors, debtors []position
+		for _, p := range byCurrency[currency] {
+			if p.cents > 0 {
+				creditors = append(creditors, p)
+			} else {
+				debtors = append(debtors, position{p.userID, -p.cents})
+			}
+		}
+		largestFirst := func(ps []position) {
+			sort.Slice(ps, func(i, j int) bool {
+				if ps[i].cents != ps[j].cents {
+					return ps[i].cents > ps[j].cents
+				}
+				return ps[i].userID < ps[j].userID
+			})
+		}
+		for len(creditors) > 0 && len(debtors) > 0 {
+			largestFirst(creditors)
+			largestFirst(debtors)
+			pay := min(creditors[0].cents, debtors[0].cents)
+			suggestions = append(suggestions, SettlementSuggestion{
+				FromUserID: debtors[0].userID,
+				ToUserID:   creditors[0].userID,
+				Currency:   currency,
+				Amount:     float64(pay) / 100,
+			})
+			creditors[0].cents -= pay
+			debtors[0].cents -= pay
+			if creditors[0].cents == 0 {
+				creditors = creditors[1:]
+			}
+			if debtors[0].cents == 0 {
+				debtors = debtors[1:]
+			}
+		}
+	}
+	return suggestions
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestSuggestSettlementsClearsBalancesWithFewestPayments(t *testing.T) {
+	got := SuggestSettlements([]LedgerBalance{
+		{UserID: "alice", Currency: "INR", Amount: 900},
+		{UserID: "bob", Currency: "INR", Amount: -300},
+		{UserID: "carol", Currency: "INR", Amount: -600},
+		{UserID: "alice", Currency: "USD", Amount: -12.5},
+		{UserID: "bob", Currency: "USD", Amount: 12.5},
+		{UserID: "dave", Currency: "INR", Amount: 0},
+	})
+	want := []SettlementSuggestion{
+		{FromUserID: "carol", ToUserID: "alice", Currency: "INR", Amount: 600},
+		{FromUserID: "bob", ToUserID: "alice", Currency: "INR", Amount: 300},
+		{FromUserID: "alice", ToUserID: "bob", Currency: "USD", Amount: 12.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SuggestSettlements() = %#v, want %#v", got, want)
+	}
+}
+
+func TestSuggestSettlementsChainsThroughIntermediateMembers(t *testing.T) {
+	// alice paid for bob, bob paid the same for carol: one payment settles it.
+	got := SuggestSettlements([]LedgerBalance{
+		{UserID: "alice", Currency: "INR", Amount: 500},
+		{UserID: "bob", Currency: "INR", Amount: 0},
+		{UserID: "carol", Currency: "INR", Amount: -500},
+	})
+	want := []SettlementSuggestion{{FromUserID: "carol", ToUserID: "alice", Currency: "INR", Amount: 500}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SuggestSettlements() = %#v, want %#v", got, want)
+	}
+}
+
+func TestShareAmountsRoundsToCents(t *testing.T) {
+	got := ShareAmounts(100, []ExpenseShareInput{{UserID: "bob", Ratio: 1.0 / 3}, {UserID: "carol", Ratio: 0.5}})
+	want := []ExpenseShare{{UserID: "bob", Ratio: 1.0 / 3, Amount: 33.33}, {UserID: "carol", Ratio: 0.5, Amount: 50}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ShareAmounts() = %#v, want %#v", got, want)
+	}
+}
+
+func TestValidateExpenseShares(t *testing.T) {
+	tests := []struct {
+		name   string
+		shares []ExpenseShareInput
+		valid  bool
+	}{
+		{name: "half", shares: []ExpenseShareInput{{UserID: "bob", Ratio: 0.5}}, valid: true},
+		{name: "thirds", shares: []ExpenseShareInput{{UserID: "bob", Ratio: 1.0 / 3}, {UserID: "carol", Ratio: 2.0 / 3}}, valid: true},
+		{name: "empty"},
+		{name: "payer", shares: []ExpenseShareInput{{UserID: "alice", Ratio: 0.5}}},
+		{name: "duplicate", shares: []ExpenseShareInput{{UserID: "bob", Ratio: 0.2}, {UserID: "bob", Ratio: 0.2}}},
+		{name: "zero", shares: []ExpenseShareInput{{UserID: "bob", Ratio: 0}}},
+		{name: "over whole", shares: []ExpenseShareInput{{UserID: "bob", Ratio: 0.6}, {UserID: "carol", Ratio: 0.6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExpenseShares("alice", tt.shares)
+			if tt.valid && err != nil {
+				t.Fatalf("ValidateExpenseShares() error = %v, want nil", err)
+			}
+			if !tt.valid && errors.WhatKind(err) != errors.InvalidInput {
+				t.Fatalf("ValidateExpenseShares() error = %v, want InvalidInput", err)
+			}
+		})
+	}
+}
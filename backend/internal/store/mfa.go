@@ -0,0 +1,82 @@
+package store
+
+import "time"
+
+// SessionMFA is how far a browser session is through multi-factor
+// authentication.
+type SessionMFA string
+
+const (
+	// SessionMFAComplete sessions are fully signed in.
+	SessionMFAComplete SessionMFA = "complete"
+	// SessionMFAPending sessions passed the password check and wait for a
+	// second factor.
+	SessionMFAPending SessionMFA = "pending"
+	// SessionMFAEnrollment sessions belong to users without a second factor
+	// while MFA is required, and may only enroll one.
+	SessionMFAEnrollment SessionMFA = "enrollment"
+)
+
+// MFAFactors summarizes the second factors a user has enrolled.
+type MFAFactors struct {
+	TOTP     bool
+	Passkeys int
+	// RecoveryCodes counts unused recovery codes.
+	RecoveryCodes int
+}
+
+// Enrolled reports whether the user has a second factor. Recovery codes
+// only stand in for one.
+func (f MFAFactors) Enrolled() bool {
+	return f.TOTP || f.Passkeys > 0
+}
+
+// TOTPFactor is a user's authenticator app secret. It is only used for
+// sign-in once ConfirmedAt is set.
+type TOTPFactor struct {
+	Secret      []byte
+	ConfirmedAt *time.Time
+	// LastUsedStep is the time step of the last accepted code; codes from
+	// it or earlier steps are refused.
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// Passkey is a registered WebAuthn credential.
+type Passkey struct {
+	ID           string
+	UserID       string
+	Name         string
+	CredentialID []byte
+	// PublicKey is the credential key in PKIX DER form.
+	PublicKey  []byte
+	Algorithm  int64
+	SignCount  uint32
+	Transports []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// CreatePasskeyInput registers a WebAuthn credential.
+type CreatePasskeyInput struct {
+	UserID       string
+	Name         string
+	CredentialID []byte
+	PublicKey    []byte
+	Algorithm    int64
+	SignCount    uint32
+	Transports   []string
+}
+
+// MFASettings is the instance-wide multi-factor policy.
+type MFASettings struct {
+	// Required makes password sign-ins without a second factor enroll one
+	// before they can do anything else.
+	Required  bool
+	UpdatedAt time.Time
+}
+
+// MFASettingsPatch partially updates MFASettings.
+type MFASettingsPatch struct {
+	Required *bool
+}
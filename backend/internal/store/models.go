@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/ArionMiles/expensor/backend/pkg/api"
@@ -17,7 +18,9 @@ const (
 	UserRoleUser UserRole = "user"
 )
 
-// Tenant identifies a tenant boundary for user-owned data. In Phase 1, the tenant ID is the user's ID.
+// Tenant identifies a tenant boundary for user-owned data. Every user has a
+// personal tenant whose ID is the user's ID; other users reach a tenant through
+// a TenantMembership.
 type Tenant struct {
 	ID string
 }
@@ -25,7 +28,7 @@ type Tenant struct {
 // User is an Expensor account.
 type User struct {
 	ID string
-	// TenantID is equal to ID in Phase 1.
+	// TenantID is the user's personal tenant, which shares the user's ID.
 	TenantID     string
 	Email        string
 	PasswordHash string
@@ -84,6 +87,13 @@ type Session struct {
 	ExpiresAt  time.Time
 	LastUsedAt *time.Time
 	RevokedAt  *time.Time
+	// ActiveTenantID is the tenant selected for this session. Empty selects
+	// the user's personal tenant.
+	ActiveTenantID string
+	MFA            SessionMFA
+	// IPAddress is the client address of the latest use.
+	IPAddress string
+	UserAgent string
 }
 
 // CreateSessionInput creates a session hash record.
@@ -91,6 +101,10 @@ type CreateSessionInput struct {
 	UserID    string
 	TokenHash string
 	ExpiresAt time.Time
+	// MFA defaults to SessionMFAComplete.
+	MFA       SessionMFA
+	IPAddress string
+	UserAgent string
 }
 
 // AccessToken is metadata for a programmatic access token.
@@ -103,6 +117,8 @@ type AccessToken struct {
 	ExpiresAt  *time.Time
 	LastUsedAt *time.Time
 	RevokedAt  *time.Time
+	// Scopes are the auth.Scope values the token may use.
+	Scopes []string
 }
 
 // CreateAccessTokenInput creates a programmatic access token hash record.
@@ -110,6 +126,7 @@ type CreateAccessTokenInput struct {
 	UserID    string
 	Name      string
 	TokenHash string
+	Scopes    []string
 	ExpiresAt *time.Time
 }
 
@@ -151,6 +168,10 @@ type Transaction struct {
 	MuteReason       string     `json:"mute_reason,omitempty"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
+	// Splits allocates Amount across categories or people; empty when unsplit.
+	Splits []TransactionSplit `json:"splits,omitempty"`
+	// Highlights is only populated by SearchTransactions.
+	Highlights []SearchHighlight `json:"highlights,omitempty"`
 }
 
 // MutedMerchant holds a merchant pattern that auto-mutes matching transactions at write time.
@@ -337,6 +358,83 @@ type LLMProviderRuntime struct {
 	UpdatedAt      time.Time
 }
 
+// LLMPromptVersion is one stored revision of a catalog prompt. Definition holds
+// the JSON-encoded prompt; at most one version per workflow and purpose is
+// active at a time.
+type LLMPromptVersion struct {
+	ID          string          `json:"id"`
+	Workflow    string          `json:"workflow"`
+	Purpose     string          `json:"purpose"`
+	Version     int             `json:"version"`
+	Definition  json.RawMessage `json:"definition"`
+	Active      bool            `json:"active"`
+	CreatedBy   string          `json:"created_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ActivatedAt *time.Time      `json:"activated_at,omitempty"`
+}
+
+// CreateLLMPromptVersionInput describes a new prompt version.
+type CreateLLMPromptVersionInput struct {
+	Workflow   string
+	Purpose    string
+	Version    int
+	Definition json.RawMessage
+	CreatedBy  string
+	Activate   bool
+}
+
+// LLMUsageRecord is one persisted LLM router call.
+type LLMUsageRecord struct {
+	Workflow     string
+	Purpose      string
+	Provider     string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	TotalTokens  int
+	Latency      time.Duration
+	Outcome      string
+	CreatedAt    time.Time
+}
+
+// LLMUsageInterval is the bucket width for aggregated LLM usage.
+type LLMUsageInterval string
+
+const (
+	LLMUsageIntervalDay   LLMUsageInterval = "day"
+	LLMUsageIntervalMonth LLMUsageInterval = "month"
+)
+
+// LLMUsageFilter selects LLM usage for aggregation. An empty TenantID spans all tenants.
+type LLMUsageFilter struct {
+	TenantID string
+	From     time.Time
+	To       time.Time
+	Interval LLMUsageInterval
+}
+
+// LLMUsageBucket aggregates LLM calls for one tenant, provider, and model over a period.
+type LLMUsageBucket struct {
+	PeriodStart    time.Time `json:"period_start"`
+	TenantID       string    `json:"tenant_id"`
+	Provider       string    `json:"provider"`
+	Model          string    `json:"model"`
+	Calls          int64     `json:"calls"`
+	FailedCalls    int64     `json:"failed_calls"`
+	InputTokens    int64     `json:"input_tokens"`
+	OutputTokens   int64     `json:"output_tokens"`
+	TotalTokens    int64     `json:"total_tokens"`
+	AverageLatency float64   `json:"average_latency_ms"`
+}
+
+// LLMUsageQuota caps tenant token usage. Zero limits are unlimited.
+type LLMUsageQuota struct {
+	TenantID          string     `json:"tenant_id"`
+	DailyTokenLimit   int64      `json:"daily_token_limit"`
+	MonthlyTokenLimit int64      `json:"monthly_token_limit"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+}
+
 // RuleRow is a rule as stored in the database.
 // Source is either "system" (seeded from embedded rules.json) or "user" (created via UI).
 // TransactionSource is the human-readable identifier written to transaction.source (e.g. "Credit Card - HDFC").
@@ -395,6 +493,23 @@ type DiagnosticFilter struct {
 	Limit  int
 }
 
+// SourceTypeManual marks transactions entered by hand rather than extracted
+// from an email. Only these rows can be deleted.
+const SourceTypeManual = "manual"
+
+// CreateTransactionInput describes a manually entered transaction.
+type CreateTransactionInput struct {
+	Amount       float64
+	Currency     string
+	Timestamp    time.Time
+	MerchantInfo string
+	Category     string
+	Bucket       string
+	Description  string
+	SourceLabel  string
+	Labels       []string
+}
+
 // TransactionUpdate carries optional fields for updating a transaction.
 // Only non-nil fields are written.
 type TransactionUpdate struct {
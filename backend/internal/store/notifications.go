@@ -0,0 +1,203 @@
+package store
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// NotificationChannelType names how a notification channel reaches a user.
+type NotificationChannelType string
+
+const (
+	// NotificationChannelEmail mails the target address through the
+	// instance's SMTP server.
+	NotificationChannelEmail NotificationChannelType = "email"
+	// NotificationChannelNtfy publishes to the ntfy topic URL in the target,
+	// with the token as an optional access token.
+	NotificationChannelNtfy NotificationChannelType = "ntfy"
+	// NotificationChannelGotify posts to the Gotify server in the target
+	// with the token as its application token.
+	NotificationChannelGotify NotificationChannelType = "gotify"
+	// NotificationChannelTelegram sends to the chat ID in the target through
+	// the bot whose token is the token.
+	NotificationChannelTelegram NotificationChannelType = "telegram"
+	// NotificationChannelWebhook posts JSON to the target URL, signed with
+	// the token when one is set.
+	NotificationChannelWebhook NotificationChannelType = "webhook"
+)
+
+// NotificationChannelTypes lists every notification channel type.
+var NotificationChannelTypes = []NotificationChannelType{
+	NotificationChannelEmail,
+	NotificationChannelNtfy,
+	NotificationChannelGotify,
+	NotificationChannelTelegram,
+	NotificationChannelWebhook,
+}
+
+// ValidNotificationChannelType reports whether t is a known channel type.
+func ValidNotificationChannelType(t NotificationChannelType) bool {
+	return slices.Contains(NotificationChannelTypes, t)
+}
+
+// NotificationTokenRequired reports whether channels of type t cannot send
+// without a token.
+func NotificationTokenRequired(t NotificationChannelType) bool {
+	return t == NotificationChannelGotify || t == NotificationChannelTelegram
+}
+
+// NotificationKind names a kind of notification.
+type NotificationKind string
+
+const (
+	NotificationWeeklyDigest     NotificationKind = "weekly_digest"
+	NotificationLargeTransaction NotificationKind = "large_transaction"
+	// NotificationScanNeedsAuth is sent when scanning stops until the reader
+	// is authorized again.
+	NotificationScanNeedsAuth NotificationKind = "scan_needs_auth"
+)
+
+// NotificationChannel is one of a user's ways of receiving notifications
+// about a tenant.
+type NotificationChannel struct {
+	ID       string
+	TenantID string
+	UserID   string
+	Type     NotificationChannelType
+	Name     string
+	// Target is the address the channel type sends to: an email address, a
+	// topic or server URL, or a chat ID.
+	Target string
+	// HasToken is set when the channel has a sealed token.
+	HasToken  bool
+	Enabled   bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NewNotificationChannel describes a notification channel to create.
+type NewNotificationChannel struct {
+	Type    NotificationChannelType
+	Name    string
+	Target  string
+	Token   string
+	Enabled bool
+}
+
+// NotificationChannelPatch partially updates a notification channel. Nil
+// fields are left unchanged; an empty Token removes the token.
+type NotificationChannelPatch struct {
+	Name    *string
+	Target  *string
+	Token   *string
+	Enabled *bool
+}
+
+// NotificationPreferences are what a user wants to be notified about in a
+// tenant. Every notification is off until the user opts in.
+type NotificationPreferences struct {
+	WeeklyDigest bool
+	// DigestWeekday and DigestHour are when the weekly digest is sent, in
+	// the tenant's timezone.
+	DigestWeekday time.Weekday
+	DigestHour    int
+	// LargeTransactionAlerts sends an alert for each scanned transaction of
+	// at least LargeTransactionThreshold.
+	LargeTransactionAlerts    bool
+	LargeTransactionThreshold float64
+	ScanAlerts                bool
+	// UpdatedAt is zero until the user first saves their preferences.
+	UpdatedAt time.Time
+}
+
+// DefaultNotificationPreferences are the preferences of a user who has not
+// saved any.
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{DigestWeekday: time.Monday, DigestHour: 9, LargeTransactionThreshold: 10000}
+}
+
+// NotificationPreferencesPatch partially updates notification preferences.
+// Nil fields are left unchanged.
+type NotificationPreferencesPatch struct {
+	WeeklyDigest              *bool
+	DigestWeekday             *time.Weekday
+	DigestHour                *int
+	LargeTransactionAlerts    *bool
+	LargeTransactionThreshold *float64
+	ScanAlerts                *bool
+}
+
+// DigestSubscription is a user's weekly digest schedule in a tenant.
+type DigestSubscription struct {
+	TenantID string
+	UserID   string
+	// Timezone is the tenant's timezone preference; empty when unset.
+	Timezone string
+	Weekday  time.Weekday
+	Hour     int
+	// LastDigestAt is when the last digest was due, or when the digest was
+	// turned on if none has been sent since.
+	LastDigestAt time.Time
+}
+
+// SpendDigest summarizes a tenant's spending over a period. Amounts are in
+// the tenant's base currency and muted transactions are left out.
+type SpendDigest struct {
+	// From and To are the first and last local dates of the period.
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Currency string              `json:"currency"`
+	Total    float64             `json:"total"`
+	Count    int                 `json:"count"`
+	Previous float64             `json:"previous_total"`
+	Top      []CategorySpend     `json:"top_categories"`
+	Largest  []DigestTransaction `json:"largest"`
+}
+
+// CategorySpend is the spending in one category of a SpendDigest.
+type CategorySpend struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+}
+
+// DigestTransaction is one of the largest transactions of a SpendDigest.
+type DigestTransaction struct {
+	ID        string    `json:"id"`
+	Merchant  string    `json:"merchant"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NotificationDelivery is one notification queued for one channel.
+type NotificationDelivery struct {
+	ID        string
+	ChannelID string
+	TenantID  string
+	UserID    string
+	Kind      NotificationKind
+	// Payload is the data the notification's template is rendered with.
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// NotificationDispatch is a delivery claimed for sending, with the channel
+// it is sent through.
+type NotificationDispatch struct {
+	Delivery NotificationDelivery
+	Channel  NotificationChannel
+	Token    string
+}
+
+// NotificationAttempt is the result of sending a claimed delivery.
+type NotificationAttempt struct {
+	AttemptedAt time.Time
+	Error       string
+	Delivered   bool
+	// RetryAt schedules another attempt of an undelivered delivery; nil
+	// gives up and marks it failed.
+	RetryAt *time.Time
+}
@@ -14,6 +14,22 @@ import (
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
+// transactionAllocations yields one row per split for split transactions and
+// one row for everything else, so category, bucket and label aggregates follow
+// allocations. Split rows carry their labels in split_labels; unsplit rows
+// join transaction_labels on id. A transaction split twice into one category
+// yields two rows there, so transaction counts take distinct ids.
+const transactionAllocations = `(
+	SELECT t.id, t.tenant_id, t.muted, t.timestamp,
+	       COALESCE(s.amount, t.amount) AS amount,
+	       CASE WHEN s.id IS NULL THEN t.category ELSE s.category END AS category,
+	       CASE WHEN s.id IS NULL THEN t.bucket ELSE s.bucket END AS bucket,
+	       s.id AS split_id,
+	       s.labels AS split_labels
+	FROM transactions t
+	LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+)`
+
 type analyticsRepository struct {
 	pool    *pgxpool.Pool
 	runtime *runtimeRepository
@@ -89,8 +105,8 @@ func (r *analyticsRepository) statsReadModel(ctx context.Context, tenant store.T
 	}
 
 	const catQ = `
-		SELECT COALESCE(NULLIF(category, ''), 'Uncategorized'), COALESCE(SUM(amount), 0), COUNT(*)
-		FROM transactions
+		SELECT COALESCE(NULLIF(category, ''), 'Uncategorized'), COALESCE(SUM(amount), 0), COUNT(DISTINCT id)
+		FROM ` + transactionAllocations + ` a
 		WHERE muted = false AND tenant_id = $1
 		GROUP BY COALESCE(NULLIF(category, ''), 'Uncategorized')
 		ORDER BY SUM(amount) DESC
@@ -157,7 +173,7 @@ func (r *analyticsRepository) getChartDataAt(ctx context.Context, tenant store.T
 			Label: "category chart data",
 			Query: `
 			SELECT COALESCE(NULLIF(category, ''), 'Uncategorized'), COALESCE(SUM(amount), 0)
-			FROM transactions
+			FROM ` + transactionAllocations + ` a
 			WHERE muted = false AND tenant_id = $1
 			GROUP BY COALESCE(NULLIF(category, ''), 'Uncategorized')
 			ORDER BY SUM(amount) DESC
@@ -168,7 +184,7 @@ func (r *analyticsRepository) getChartDataAt(ctx context.Context, tenant store.T
 			Label: "bucket chart data",
 			Query: `
 			SELECT COALESCE(NULLIF(bucket, ''), 'Uncategorized'), COALESCE(SUM(amount), 0)
-			FROM transactions
+			FROM ` + transactionAllocations + ` a
 			WHERE muted = false AND tenant_id = $1
 			GROUP BY COALESCE(NULLIF(bucket, ''), 'Uncategorized')
 			ORDER BY SUM(amount) DESC
@@ -178,11 +194,12 @@ func (r *analyticsRepository) getChartDataAt(ctx context.Context, tenant store.T
 		Label: chartQueryRequest{
 			Label: "label chart data",
 			Query: `
-			SELECT COALESCE(tl.label, 'Uncategorized'), COALESCE(SUM(t.amount), 0)
-			FROM transactions t
-			LEFT JOIN transaction_labels tl ON tl.transaction_id = t.id
+			SELECT COALESCE(sl.label, tl.label, 'Uncategorized'), COALESCE(SUM(t.amount), 0)
+			FROM ` + transactionAllocations + ` t
+			LEFT JOIN transaction_labels tl ON t.split_id IS NULL AND tl.transaction_id = t.id
+			LEFT JOIN LATERAL unnest(t.split_labels) AS sl(label) ON true
 			WHERE t.muted = false AND t.tenant_id = $1
-			GROUP BY COALESCE(tl.label, 'Uncategorized')
+			GROUP BY COALESCE(sl.label, tl.label, 'Uncategorized')
 			ORDER BY SUM(t.amount) DESC
 			LIMIT 20
 		`,
@@ -282,8 +299,8 @@ func (r *analyticsRepository) getStatsBetween(ctx context.Context, tenant store.
 	}
 
 	const catQ = `
-		SELECT COALESCE(NULLIF(category, ''), 'Uncategorized'), COALESCE(SUM(amount), 0), COUNT(*)
-		FROM transactions
+		SELECT COALESCE(NULLIF(category, ''), 'Uncategorized'), COALESCE(SUM(amount), 0), COUNT(DISTINCT id)
+		FROM ` + transactionAllocations + ` a
 		WHERE muted = false AND tenant_id = $3 AND timestamp >= $1 AND timestamp < $2
 		GROUP BY COALESCE(NULLIF(category, ''), 'Uncategorized')
 		ORDER BY SUM(amount) DESC
@@ -350,7 +367,7 @@ func (r *analyticsRepository) getChartDataBetween(
 			Label: "range category chart data",
 			Query: `
 		SELECT COALESCE(NULLIF(category, ''), 'Uncategorized'), COALESCE(SUM(amount), 0)
-		FROM transactions
+		FROM ` + transactionAllocations + ` a
 		WHERE muted = false AND tenant_id = $3 AND timestamp >= $1 AND timestamp < $2
 		GROUP BY COALESCE(NULLIF(category, ''), 'Uncategorized')
 		ORDER BY SUM(amount) DESC
@@ -361,7 +378,7 @@ func (r *analyticsRepository) getChartDataBetween(
 			Label: "range bucket chart data",
 			Query: `
 		SELECT COALESCE(NULLIF(bucket, ''), 'Uncategorized'), COALESCE(SUM(amount), 0)
-		FROM transactions
+		FROM ` + transactionAllocations + ` a
 		WHERE muted = false AND tenant_id = $3 AND timestamp >= $1 AND timestamp < $2
 		GROUP BY COALESCE(NULLIF(bucket, ''), 'Uncategorized')
 		ORDER BY SUM(amount) DESC
@@ -371,11 +388,12 @@ func (r *analyticsRepository) getChartDataBetween(
 		Label: chartQueryRequest{
 			Label: "range label chart data",
 			Query: `
-		SELECT COALESCE(tl.label, 'Uncategorized'), COALESCE(SUM(t.amount), 0)
-		FROM transactions t
-		LEFT JOIN transaction_labels tl ON tl.transaction_id = t.id
+		SELECT COALESCE(sl.label, tl.label, 'Uncategorized'), COALESCE(SUM(t.amount), 0)
+		FROM ` + transactionAllocations + ` t
+		LEFT JOIN transaction_labels tl ON t.split_id IS NULL AND tl.transaction_id = t.id
+		LEFT JOIN LATERAL unnest(t.split_labels) AS sl(label) ON true
 		WHERE t.muted = false AND t.tenant_id = $3 AND t.timestamp >= $1 AND t.timestamp < $2
-		GROUP BY COALESCE(tl.label, 'Uncategorized')
+		GROUP BY COALESCE(sl.label, tl.label, 'Uncategorized')
 		ORDER BY SUM(t.amount) DESC
 		LIMIT 20
 	`,
@@ -450,7 +468,7 @@ func (r *analyticsRepository) queryCategoryMonthlyBetween(
 		        WHERE timestamp >= $3
 		          AND timestamp  < $1
 		    ), 0) AS prior_month
-		FROM transactions
+		FROM ` + transactionAllocations + ` a
 		WHERE muted = false
 		    AND tenant_id = $4
 		    AND timestamp >= $3
@@ -748,15 +766,16 @@ func (r *analyticsRepository) monthlyBreakdownSpendReadModel(
 	case "labels":
 		query = `
 			SELECT
-				COALESCE(tl.label, 'Uncategorized') AS label,
+				COALESCE(sl.label, tl.label, 'Uncategorized') AS label,
 				TO_CHAR(t.timestamp AT TIME ZONE $1, 'YYYY-MM') AS month,
 				COALESCE(SUM(t.amount), 0) AS amount
-			FROM transactions t
-			LEFT JOIN transaction_labels tl ON tl.transaction_id = t.id
+			FROM ` + transactionAllocations + ` t
+			LEFT JOIN transaction_labels tl ON t.split_id IS NULL AND tl.transaction_id = t.id
+			LEFT JOIN LATERAL unnest(t.split_labels) AS sl(label) ON true
 			WHERE t.muted = false
 			  AND t.timestamp >= $2
 			  AND t.tenant_id = $3
-			GROUP BY COALESCE(tl.label, 'Uncategorized'), month
+			GROUP BY COALESCE(sl.label, tl.label, 'Uncategorized'), month
 			ORDER BY month, label
 		`
 		args = []any{tz, startUTC, tenant.ID}
@@ -766,7 +785,7 @@ func (r *analyticsRepository) monthlyBreakdownSpendReadModel(
 				COALESCE(NULLIF(t.category, ''), 'Uncategorized') AS label,
 				TO_CHAR(t.timestamp AT TIME ZONE $1, 'YYYY-MM') AS month,
 				COALESCE(SUM(t.amount), 0) AS amount
-			FROM transactions t
+			FROM ` + transactionAllocations + ` t
 			WHERE t.muted = false
 			  AND t.timestamp >= $2
 			  AND t.tenant_id = $3
@@ -780,7 +799,7 @@ func (r *analyticsRepository) monthlyBreakdownSpendReadModel(
 				COALESCE(NULLIF(t.bucket, ''), 'Uncategorized') AS label,
 				TO_CHAR(t.timestamp AT TIME ZONE $1, 'YYYY-MM') AS month,
 				COALESCE(SUM(t.amount), 0) AS amount
-			FROM transactions t
+			FROM ` + transactionAllocations + ` t
 			WHERE t.muted = false
 			  AND t.timestamp >= $2
 			  AND t.tenant_id = $3
@@ -0,0 +1,365 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/blob"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// defaultMaxAttachmentSize applies when Options.MaxAttachmentSize is unset.
+const defaultMaxAttachmentSize = 25 << 20
+
+type attachmentRepository struct {
+	pool      *pgxpool.Pool
+	logger    *slog.Logger
+	secretBox *auth.SecretBox
+	blobs     blob.Store
+	maxSize   int64
+}
+
+func newAttachmentRepository(deps repositoryDependencies) *attachmentRepository {
+	maxSize := deps.maxAttachmentSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxAttachmentSize
+	}
+	return &attachmentRepository{
+		pool:      deps.pool,
+		logger:    deps.logger,
+		secretBox: deps.secretBox,
+		blobs:     deps.blobs,
+		maxSize:   maxSize,
+	}
+}
+
+// attachmentAssociatedData binds sealed attachment content to its tenant and
+// attachment ID, so a blob copied under another key fails to open.
+func attachmentAssociatedData(tenant store.Tenant, attachmentID string) auth.SecretAssociatedData {
+	return auth.SecretAssociatedData{
+		TenantID: tenant.ID,
+		Scope:    "attachment",
+		Name:     attachmentID,
+		Kind:     "content",
+	}
+}
+
+func attachmentBlobKey(tenant store.Tenant, attachmentID string) string {
+	return "attachments/" + tenant.ID + "/" + attachmentID
+}
+
+func (r *attachmentRepository) ready() error {
+	if r.secretBox == nil {
+		return errors.E(errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	if r.blobs == nil {
+		return errors.E(errors.FailedPrecondition, "attachment blob store is not configured")
+	}
+	return nil
+}
+
+func (r *attachmentRepository) CreateAttachment(
+	ctx context.Context,
+	tenant store.Tenant,
+	transactionID string,
+	input store.NewAttachment,
+) (*store.Attachment, error) {
+	const op = "postgres.attachments.create"
+
+	if err := r.ready(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	filename := sanitizeAttachmentFilename(input.Filename)
+	if filename == "" {
+		return nil, errors.E(op, errors.InvalidInput, errors.User("filename is required"))
+	}
+	if len(input.Data) == 0 {
+		return nil, errors.E(op, errors.InvalidInput, errors.User("attachment is empty"))
+	}
+	if int64(len(input.Data)) > r.maxSize {
+		return nil, errors.E(op, errors.PayloadTooLarge, errors.User(fmt.Sprintf("attachment exceeds the %d byte limit", r.maxSize)))
+	}
+	contentType := strings.TrimSpace(input.ContentType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	source := input.Source
+	if source == "" {
+		source = store.AttachmentSourceUpload
+	}
+	if err := r.requireTransaction(ctx, tenant, transactionID); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(input.Data)
+	attachment := store.Attachment{
+		ID:            uuid.NewString(),
+		TransactionID: transactionID,
+		Filename:      filename,
+		ContentType:   contentType,
+		Size:          int64(len(input.Data)),
+		SHA256:        hex.EncodeToString(sum[:]),
+		Source:        source,
+	}
+	sealed, err := r.secretBox.Seal(input.Data, attachmentAssociatedData(tenant, attachment.ID))
+	if err != nil {
+		return nil, errors.E(op, "encrypting attachment", err)
+	}
+	key := attachmentBlobKey(tenant, attachment.ID)
+	// The blob is written first so a committed row always has content. A
+	// failed insert removes it again.
+	if err := r.blobs.Put(ctx, key, sealed); err != nil {
+		return nil, errors.E(op, "storing attachment content", err)
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO transaction_attachments (
+			id, tenant_id, transaction_id, filename, content_type, size_bytes, sha256, source, blob_key
+		)
+		SELECT $1, tenant_id, id, $4, $5, $6, $7, $8, $9
+		FROM transactions
+		WHERE id = $2 AND tenant_id = $3
+		ON CONFLICT (transaction_id, sha256) WHERE source = 'email' DO NOTHING
+		RETURNING created_at
+	`, attachment.ID, transactionID, tenant.ID, attachment.Filename, attachment.ContentType,
+		attachment.Size, attachment.SHA256, string(attachment.Source), key,
+	).Scan(&attachment.CreatedAt)
+	if err != nil {
+		r.deleteBlob(ctx, key)
+		if !errorsIsNoRows(err) {
+			return nil, errors.E(op, "inserting attachment", err)
+		}
+		// Either the email attachment is already stored or the transaction
+		// was deleted since the check above.
+		existing, findErr := r.findEmailAttachment(ctx, tenant, transactionID, attachment.SHA256)
+		if findErr != nil {
+			return nil, findErr
+		}
+		return existing, nil
+	}
+	return &attachment, nil
+}
+
+func (r *attachmentRepository) ListAttachments(ctx context.Context, tenant store.Tenant, transactionID string) ([]store.Attachment, error) {
+	const op = "postgres.attachments.list"
+
+	if err := r.requireTransaction(ctx, tenant, transactionID); err != nil {
+		return nil, err
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, transaction_id, filename, content_type, size_bytes, sha256, source, created_at
+		FROM transaction_attachments
+		WHERE tenant_id = $1 AND transaction_id = $2
+		ORDER BY created_at, id
+	`, tenant.ID, transactionID)
+	if err != nil {
+		return nil, errors.E(op, "listing attachments", err)
+	}
+	defer rows.Close()
+
+	attachments := []store.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, errors.E(op, "scanning attachment", err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating attachments", err)
+	}
+	return attachments, nil
+}
+
+func (r *attachmentRepository) GetAttachment(
+	ctx context.Context,
+	tenant store.Tenant,
+	transactionID, attachmentID string,
+) (*store.Attachment, []byte, error) {
+	const op = "postgres.attachments.get"
+
+	if err := r.ready(); err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	var key string
+	attachment, err := scanAttachment(r.pool.QueryRow(ctx, `
+		SELECT id, transaction_id, filename, content_type, size_bytes, sha256, source, created_at, blob_key
+		FROM transaction_attachments
+		WHERE id = $1 AND transaction_id = $2 AND tenant_id = $3
+	`, attachmentID, transactionID, tenant.ID), &key)
+	if errorsIsNoRows(err) {
+		return nil, nil, errors.E("store.attachments.get", errors.NotFound, errors.User("attachment not found"))
+	}
+	if err != nil {
+		return nil, nil, errors.E(op, "getting attachment", err)
+	}
+	sealed, err := r.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, nil, errors.E(op, "reading attachment content", err)
+	}
+	data, err := r.secretBox.Open(sealed, attachmentAssociatedData(tenant, attachment.ID))
+	if err != nil {
+		return nil, nil, errors.E(op, "decrypting attachment", err)
+	}
+	return &attachment, data, nil
+}
+
+func (r *attachmentRepository) DeleteAttachment(ctx context.Context, tenant store.Tenant, transactionID, attachmentID string) error {
+	const op = "postgres.attachments.delete"
+
+	var key string
+	err := r.pool.QueryRow(ctx, `
+		DELETE FROM transaction_attachments
+		WHERE id = $1 AND transaction_id = $2 AND tenant_id = $3
+		RETURNING blob_key
+	`, attachmentID, transactionID, tenant.ID).Scan(&key)
+	if errorsIsNoRows(err) {
+		return errors.E("store.attachments.delete", errors.NotFound, errors.User("attachment not found"))
+	}
+	if err != nil {
+		return errors.E(op, "deleting attachment", err)
+	}
+	r.deleteBlob(ctx, key)
+	return nil
+}
+
+// attachEmailFiles stores the PDF attachments of an ingested email. Failures
+// are logged rather than returned: the transaction itself is already saved.
+func (r *attachmentRepository) attachEmailFiles(ctx context.Context, tenant store.Tenant, transactionID string, files []store.NewAttachment) {
+	if len(files) == 0 {
+		return
+	}
+	if err := r.ready(); err != nil {
+		r.logger.Debug("skipping email attachments", "transaction_id", transactionID, "reason", err)
+		return
+	}
+	for _, file := range files {
+		file.Source = store.AttachmentSourceEmail
+		if _, err := r.CreateAttachment(ctx, tenant, transactionID, file); err != nil {
+			r.logger.Warn("failed to store email attachment",
+				"transaction_id", transactionID,
+				"filename", file.Filename,
+				"error", err,
+			)
+		}
+	}
+}
+
+// blobKeysForTransaction returns the blob keys of a transaction's attachments,
+// so their content can be removed after the rows cascade away.
+func (r *attachmentRepository) blobKeysForTransaction(ctx context.Context, tenant store.Tenant, transactionID string) ([]string, error) {
+	return r.blobKeys(ctx, `
+		SELECT blob_key FROM transaction_attachments WHERE tenant_id = $1 AND transaction_id = $2
+	`, tenant.ID, transactionID)
+}
+
+// blobKeysForTenant returns the blob keys of every attachment in a tenant.
+func (r *attachmentRepository) blobKeysForTenant(ctx context.Context, tenantID string) ([]string, error) {
+	return r.blobKeys(ctx, `SELECT blob_key FROM transaction_attachments WHERE tenant_id = $1`, tenantID)
+}
+
+func (r *attachmentRepository) blobKeys(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.E("postgres.attachments.blob_keys", "listing attachment blob keys", err)
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, errors.E("postgres.attachments.blob_keys", "scanning attachment blob key", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E("postgres.attachments.blob_keys", "iterating attachment blob keys", err)
+	}
+	return keys, nil
+}
+
+func (r *attachmentRepository) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		r.deleteBlob(ctx, key)
+	}
+}
+
+func (r *attachmentRepository) deleteBlob(ctx context.Context, key string) {
+	if r.blobs == nil {
+		return
+	}
+	if err := r.blobs.Delete(ctx, key); err != nil {
+		r.logger.Warn("failed to delete attachment content", "blob_key", key, "error", err)
+	}
+}
+
+func (r *attachmentRepository) requireTransaction(ctx context.Context, tenant store.Tenant, transactionID string) error {
+	var exists bool
+	if err := r.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM transactions WHERE id = $1 AND tenant_id = $2)`,
+		transactionID, tenant.ID,
+	).Scan(&exists); err != nil {
+		return errors.E("postgres.attachments.require_transaction", "checking transaction", err)
+	}
+	if !exists {
+		return errors.E("store.attachments", errors.NotFound, errors.User("transaction not found"))
+	}
+	return nil
+}
+
+func (r *attachmentRepository) findEmailAttachment(ctx context.Context, tenant store.Tenant, transactionID, sum string) (*store.Attachment, error) {
+	attachment, err := scanAttachment(r.pool.QueryRow(ctx, `
+		SELECT id, transaction_id, filename, content_type, size_bytes, sha256, source, created_at
+		FROM transaction_attachments
+		WHERE tenant_id = $1 AND transaction_id = $2 AND sha256 = $3 AND source = 'email'
+	`, tenant.ID, transactionID, sum))
+	if errorsIsNoRows(err) {
+		return nil, errors.E("store.attachments.create", errors.NotFound, errors.User("transaction not found"))
+	}
+	if err != nil {
+		return nil, errors.E("postgres.attachments.find_email_attachment", "getting attachment", err)
+	}
+	return &attachment, nil
+}
+
+func scanAttachment(row pgx.Row, extra ...any) (store.Attachment, error) {
+	var a store.Attachment
+	var source string
+	dest := append([]any{&a.ID, &a.TransactionID, &a.Filename, &a.ContentType, &a.Size, &a.SHA256, &source, &a.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return store.Attachment{}, err
+	}
+	a.Source = store.AttachmentSource(source)
+	return a, nil
+}
+
+// sanitizeAttachmentFilename keeps only the base name of a client-supplied
+// filename and drops characters that would break a Content-Disposition header.
+func sanitizeAttachmentFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(strings.TrimSpace(name))
+	if name == "." || name == "/" {
+		return ""
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	return strings.TrimSpace(name)
+}
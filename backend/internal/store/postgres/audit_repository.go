@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type auditRepository struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+func newAuditRepository(deps repositoryDependencies) *auditRepository {
+	return &auditRepository{pool: deps.pool, now: deps.now}
+}
+
+func (r *auditRepository) RecordAuditEvent(ctx context.Context, event store.NewAuditEvent) error {
+	if event.Action == "" || event.Outcome == "" {
+		return errors.E("postgres.audit.record", errors.InvalidArgument, "audit event needs an action and an outcome")
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO audit_events (
+			occurred_at, actor_user_id, tenant_id, auth_method, action,
+			target_type, target_id, request_id, ip_address, outcome, detail
+		)
+		VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9, $10, $11)
+	`,
+		r.now(),
+		event.ActorUserID,
+		event.TenantID,
+		event.AuthMethod,
+		string(event.Action),
+		event.TargetType,
+		event.TargetID,
+		event.RequestID,
+		event.IPAddress,
+		string(event.Outcome),
+		event.Detail,
+	)
+	if err != nil {
+		return errors.E("postgres.audit.record", fmt.Sprintf("recording %s audit event", event.Action), err)
+	}
+	return nil
+}
+
+func (r *auditRepository) ListAuditEvents(ctx context.Context, filter store.AuditEventFilter) ([]store.AuditEvent, int, error) {
+	const op = "postgres.audit.list"
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultAuditPageSize
+	}
+	filter.PageSize = min(filter.PageSize, maxAuditPageSize)
+	if filter.Page-1 > math.MaxInt/filter.PageSize {
+		return nil, 0, errors.E(op, errors.InvalidInput,
+			fmt.Sprintf("pagination offset overflow: page=%d page_size=%d", filter.Page, filter.PageSize))
+	}
+	where, args := buildAuditWhere(filter)
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT count(*) FROM audit_events e`+where, args...).Scan(&total); err != nil {
+		return nil, 0, errors.E(op, "counting audit events", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT e.id::text, e.occurred_at, COALESCE(e.actor_user_id::text, ''), COALESCE(u.email, ''),
+		       COALESCE(e.tenant_id::text, ''), e.auth_method, e.action, e.target_type, e.target_id,
+		       e.request_id, e.ip_address, e.outcome, e.detail
+		FROM audit_events e
+		LEFT JOIN users u ON u.id = e.actor_user_id%s
+		ORDER BY e.occurred_at DESC, e.id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, errors.E(op, "listing audit events", err)
+	}
+	defer rows.Close()
+
+	events := []store.AuditEvent{}
+	for rows.Next() {
+		var event store.AuditEvent
+		var action, outcome string
+		if err := rows.Scan(
+			&event.ID, &event.OccurredAt, &event.ActorUserID, &event.ActorEmail,
+			&event.TenantID, &event.AuthMethod, &action, &event.TargetType, &event.TargetID,
+			&event.RequestID, &event.IPAddress, &outcome, &event.Detail,
+		); err != nil {
+			return nil, 0, errors.E(op, "scanning audit event", err)
+		}
+		event.Action = store.AuditAction(action)
+		event.Outcome = store.AuditOutcome(outcome)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.E(op, "listing audit events", err)
+	}
+	return events, total, nil
+}
+
+// buildAuditWhere builds the WHERE clause for filter over audit_events
+// aliased as e.
+func buildAuditWhere(filter store.AuditEventFilter) (string, []any) {
+	var conds []string
+	var args []any
+
+	next := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.ActorUserID != "" {
+		conds = append(conds, fmt.Sprintf("e.actor_user_id = %s::uuid", next(filter.ActorUserID)))
+	}
+	if filter.TenantID != "" {
+		conds = append(conds, fmt.Sprintf("e.tenant_id = %s::uuid", next(filter.TenantID)))
+	}
+	if filter.Action != "" {
+		conds = append(conds, fmt.Sprintf("e.action = %s", next(string(filter.Action))))
+	}
+	if filter.TargetType != "" {
+		conds = append(conds, fmt.Sprintf("e.target_type = %s", next(filter.TargetType)))
+	}
+	if filter.TargetID != "" {
+		conds = append(conds, fmt.Sprintf("e.target_id = %s", next(filter.TargetID)))
+	}
+	if filter.Outcome != "" {
+		conds = append(conds, fmt.Sprintf("e.outcome = %s", next(string(filter.Outcome))))
+	}
+	if filter.RequestID != "" {
+		conds = append(conds, fmt.Sprintf("e.request_id = %s", next(filter.RequestID)))
+	}
+	if filter.From != nil {
+		conds = append(conds, fmt.Sprintf("e.occurred_at >= %s", next(*filter.From)))
+	}
+	if filter.To != nil {
+		conds = append(conds, fmt.Sprintf("e.occurred_at < %s", next(*filter.To)))
+	}
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (r *auditRepository) GetAuditSettings(ctx context.Context) (store.AuditSettings, error) {
+	var settings store.AuditSettings
+	err := r.pool.QueryRow(ctx, `SELECT retention_days, updated_at FROM audit_settings WHERE id = true`).
+		Scan(&settings.RetentionDays, &settings.UpdatedAt)
+	if err != nil {
+		return store.AuditSettings{}, errors.E("postgres.audit.get_settings", "getting audit settings", err)
+	}
+	return settings, nil
+}
+
+func (r *auditRepository) PatchAuditSettings(ctx context.Context, patch store.AuditSettingsPatch) (store.AuditSettings, error) {
+	var settings store.AuditSettings
+	err := r.pool.QueryRow(ctx, `
+		UPDATE audit_settings
+		SET retention_days = COALESCE($1, retention_days),
+		    updated_at = now()
+		WHERE id = true
+		RETURNING retention_days, updated_at
+	`, patch.RetentionDays).Scan(&settings.RetentionDays, &settings.UpdatedAt)
+	if err != nil {
+		return store.AuditSettings{}, errors.E("postgres.audit.patch_settings", "patching audit settings", err)
+	}
+	return settings, nil
+}
+
+func (r *auditRepository) PruneAuditEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM audit_events WHERE occurred_at < $1`, cutoff)
+	if err != nil {
+		return 0, errors.E("postgres.audit.prune", "pruning audit events", err)
+	}
+	return tag.RowsAffected(), nil
+}
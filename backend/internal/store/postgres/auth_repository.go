@@ -153,13 +153,29 @@ func (r *authRepository) UpdateUserPassword(ctx context.Context, id string, inpu
 }
 
 func (r *authRepository) DeleteUser(ctx context.Context, id string) error {
-	tag, err := r.pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return errors.E("postgres.auth.delete_user", "beginning delete user transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
 	if err != nil {
 		return errors.E("postgres.auth.delete_user", "deleting user", err)
 	}
 	if tag.RowsAffected() == 0 {
 		return errors.E("store.auth.delete_user", errors.NotFound, errors.User("user not found"))
 	}
+	// The personal tenant goes with its user, as tenant data did before
+	// tenants were decoupled from users.
+	if _, err := tx.Exec(ctx, `DELETE FROM tenants WHERE id = $1`, id); err != nil {
+		return errors.E("postgres.auth.delete_user", "deleting personal tenant", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return errors.E("postgres.auth.delete_user", "committing delete user transaction", err)
+	}
 	return nil
 }
 
@@ -195,12 +211,44 @@ func (r *authRepository) FindUserByID(ctx context.Context, id string) (*store.Us
 	return user, nil
 }
 
+func (r *authRepository) FindUserByOIDCSubject(ctx context.Context, issuer, subject string) (*store.User, error) {
+	user, err := scanUser(r.pool.QueryRow(ctx, `
+		SELECT u.id, u.id AS tenant_id, u.email, COALESCE(u.password_hash, ''), u.display_name, u.role, u.avatar_key,
+		       u.disabled_at, u.created_at, u.updated_at
+		FROM oidc_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.issuer = $1 AND i.subject = $2
+	`, issuer, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.E("store.auth.find_user_by_oidc_subject", errors.NotFound, errors.User("user not found"))
+		}
+		return nil, errors.E("postgres.auth.find_user_by_oidc_subject", "finding user by OIDC subject", err)
+	}
+	return user, nil
+}
+
+func (r *authRepository) LinkOIDCSubject(ctx context.Context, userID, issuer, subject string) error {
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO oidc_identities (issuer, subject, user_id)
+		VALUES ($1, $2, $3)
+	`, issuer, subject, userID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return errors.E("store.auth.link_oidc_subject", errors.Conflict, "account or OIDC subject is already linked", err)
+		}
+		return errors.E("postgres.auth.link_oidc_subject", "linking OIDC subject", err)
+	}
+	return nil
+}
+
 func (r *authRepository) CreateSession(ctx context.Context, input store.CreateSessionInput) (*store.Session, error) {
 	session, err := scanSession(r.pool.QueryRow(ctx, `
-		INSERT INTO sessions (user_id, token_hash, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, user_id, token_hash, created_at, expires_at, last_used_at, revoked_at
-	`, input.UserID, input.TokenHash, input.ExpiresAt))
+		INSERT INTO sessions (user_id, token_hash, expires_at, mfa_state, ip_address, user_agent)
+		VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'complete'), $5, $6)
+		RETURNING id, user_id, token_hash, created_at, expires_at, last_used_at, revoked_at,
+		          COALESCE(active_tenant_id::text, ''), mfa_state, ip_address, user_agent
+	`, input.UserID, input.TokenHash, input.ExpiresAt, string(input.MFA), input.IPAddress, input.UserAgent))
 	if err != nil {
 		return nil, errors.E("postgres.auth.create_session", "creating session", err)
 	}
@@ -209,7 +257,8 @@ func (r *authRepository) CreateSession(ctx context.Context, input store.CreateSe
 
 func (r *authRepository) FindSessionByHash(ctx context.Context, tokenHash string) (*store.Session, error) {
 	session, err := scanSession(r.pool.QueryRow(ctx, `
-		SELECT id, user_id, token_hash, created_at, expires_at, last_used_at, revoked_at
+		SELECT id, user_id, token_hash, created_at, expires_at, last_used_at, revoked_at,
+		       COALESCE(active_tenant_id::text, ''), mfa_state, ip_address, user_agent
 		FROM sessions
 		WHERE token_hash = $1
 	`, tokenHash))
@@ -233,12 +282,77 @@ func (r *authRepository) RevokeSession(ctx context.Context, id string) error {
 	return nil
 }
 
+func (r *authRepository) ListSessions(ctx context.Context, userID string) ([]store.Session, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, token_hash, created_at, expires_at, last_used_at, revoked_at,
+		       COALESCE(active_tenant_id::text, ''), mfa_state, ip_address, user_agent
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY COALESCE(last_used_at, created_at) DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, errors.E("postgres.auth.list_sessions", "listing sessions", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]store.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, errors.E("postgres.auth.list_sessions", "scanning session", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E("postgres.auth.list_sessions", "iterating sessions", err)
+	}
+	return sessions, nil
+}
+
+func (r *authRepository) RevokeUserSession(ctx context.Context, userID, id string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`, id, userID)
+	if err != nil {
+		return errors.E("postgres.auth.revoke_user_session", "revoking session", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.auth.revoke_user_session", errors.NotFound, errors.User("session not found"))
+	}
+	return nil
+}
+
+func (r *authRepository) RevokeOtherSessions(ctx context.Context, userID, keepID string) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		  AND ($2 = '' OR id::text <> $2)
+	`, userID, keepID)
+	if err != nil {
+		return 0, errors.E("postgres.auth.revoke_other_sessions", "revoking sessions", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *authRepository) TouchSession(ctx context.Context, id, ipAddress string) error {
+	if _, err := r.pool.Exec(ctx, `
+		UPDATE sessions SET last_used_at = NOW(), ip_address = $2 WHERE id = $1
+	`, id, ipAddress); err != nil {
+		return errors.E("postgres.auth.touch_session", "touching session", err)
+	}
+	return nil
+}
+
 func (r *authRepository) CreateAccessToken(ctx context.Context, input store.CreateAccessTokenInput) (*store.AccessToken, error) {
+	if len(input.Scopes) == 0 {
+		return nil, errors.E("store.auth.create_access_token", errors.InvalidInput, "access token has no scopes")
+	}
 	token, err := scanAccessToken(r.pool.QueryRow(ctx, `
-		INSERT INTO access_tokens (user_id, name, token_hash, expires_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, name, token_hash, created_at, expires_at, last_used_at, revoked_at
-	`, input.UserID, input.Name, input.TokenHash, input.ExpiresAt))
+		INSERT INTO access_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, user_id, name, token_hash, created_at, expires_at, last_used_at, revoked_at, scopes
+	`, input.UserID, input.Name, input.TokenHash, input.Scopes, input.ExpiresAt))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -257,7 +371,7 @@ func (r *authRepository) CreateAccessToken(ctx context.Context, input store.Crea
 
 func (r *authRepository) ListAccessTokens(ctx context.Context, userID string) ([]store.AccessToken, error) {
 	rows, err := r.pool.Query(ctx, `
-		SELECT id, user_id, name, token_hash, created_at, expires_at, last_used_at, revoked_at
+		SELECT id, user_id, name, token_hash, created_at, expires_at, last_used_at, revoked_at, scopes
 		FROM access_tokens
 		WHERE user_id = $1 AND revoked_at IS NULL
 		ORDER BY created_at DESC, id DESC
@@ -283,7 +397,7 @@ func (r *authRepository) ListAccessTokens(ctx context.Context, userID string) ([
 
 func (r *authRepository) FindAccessTokenByHash(ctx context.Context, tokenHash string) (*store.AccessToken, error) {
 	token, err := scanAccessToken(r.pool.QueryRow(ctx, `
-		SELECT id, user_id, name, token_hash, created_at, expires_at, last_used_at, revoked_at
+		SELECT id, user_id, name, token_hash, created_at, expires_at, last_used_at, revoked_at, scopes
 		FROM access_tokens
 		WHERE token_hash = $1
 	`, tokenHash))
@@ -417,6 +531,15 @@ func insertUser(ctx context.Context, tx pgx.Tx, input store.CreateUserInput) (*s
 		}
 		return nil, errors.E("postgres.auth.insert_user", "creating user", err)
 	}
+	if _, err := tx.Exec(ctx, `INSERT INTO tenants (id, name) VALUES ($1, $2)`, user.ID, user.DisplayName); err != nil {
+		return nil, errors.E("postgres.auth.insert_user", "creating personal tenant", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tenant_memberships (tenant_id, user_id, role)
+		VALUES ($1, $1, $2)
+	`, user.ID, store.TenantRoleOwner); err != nil {
+		return nil, errors.E("postgres.auth.insert_user", "creating personal tenant membership", err)
+	}
 	return user, nil
 }
 
@@ -453,6 +576,10 @@ func scanSession(row scanner) (*store.Session, error) {
 		&session.ExpiresAt,
 		&session.LastUsedAt,
 		&session.RevokedAt,
+		&session.ActiveTenantID,
+		&session.MFA,
+		&session.IPAddress,
+		&session.UserAgent,
 	); err != nil {
 		return nil, err
 	}
@@ -470,6 +597,7 @@ func scanAccessToken(row scanner) (*store.AccessToken, error) {
 		&token.ExpiresAt,
 		&token.LastUsedAt,
 		&token.RevokedAt,
+		&token.Scopes,
 	); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,455 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type backupRepository struct {
+	pool *pgxpool.Pool
+}
+
+func newBackupRepository(deps repositoryDependencies) *backupRepository {
+	return &backupRepository{pool: deps.pool}
+}
+
+const backupSettingsColumns = `enabled, interval_hours, keep_last, keep_days, last_started_at, last_finished_at,
+	last_backup, last_error, updated_at`
+
+func scanBackupSettings(row pgx.Row) (store.BackupSettings, error) {
+	var s store.BackupSettings
+	err := row.Scan(&s.Enabled, &s.IntervalHours, &s.KeepLast, &s.KeepDays, &s.LastStartedAt, &s.LastFinishedAt,
+		&s.LastBackup, &s.LastError, &s.UpdatedAt)
+	return s, err
+}
+
+func (r *backupRepository) GetBackupSettings(ctx context.Context) (store.BackupSettings, error) {
+	settings, err := scanBackupSettings(r.pool.QueryRow(ctx, `SELECT `+backupSettingsColumns+` FROM backup_settings WHERE id = true`))
+	if err != nil {
+		return store.BackupSettings{}, errors.E("postgres.backups.get_settings", "getting backup settings", err)
+	}
+	return settings, nil
+}
+
+func (r *backupRepository) PatchBackupSettings(ctx context.Context, patch store.BackupSettingsPatch) (store.BackupSettings, error) {
+	if patch.IntervalHours != nil && (*patch.IntervalHours < 1 || *patch.IntervalHours > 8760) {
+		return store.BackupSettings{}, errors.E(errors.InvalidInput, errors.User("interval_hours must be between 1 and 8760"))
+	}
+	if patch.KeepLast != nil && (*patch.KeepLast < 1 || *patch.KeepLast > 1000) {
+		return store.BackupSettings{}, errors.E(errors.InvalidInput, errors.User("keep_last must be between 1 and 1000"))
+	}
+	if patch.KeepDays != nil && (*patch.KeepDays < 0 || *patch.KeepDays > 3650) {
+		return store.BackupSettings{}, errors.E(errors.InvalidInput, errors.User("keep_days must be between 0 and 3650"))
+	}
+	settings, err := scanBackupSettings(r.pool.QueryRow(ctx, `
+		UPDATE backup_settings
+		SET enabled = COALESCE($1, enabled),
+		    interval_hours = COALESCE($2, interval_hours),
+		    keep_last = COALESCE($3, keep_last),
+		    keep_days = COALESCE($4, keep_days),
+		    updated_at = now()
+		WHERE id = true
+		RETURNING `+backupSettingsColumns,
+		patch.Enabled, patch.IntervalHours, patch.KeepLast, patch.KeepDays,
+	))
+	if err != nil {
+		return store.BackupSettings{}, errors.E("postgres.backups.patch_settings", "patching backup settings", err)
+	}
+	return settings, nil
+}
+
+func (r *backupRepository) ClaimScheduledBackup(ctx context.Context, now time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE backup_settings
+		SET last_started_at = $1
+		WHERE id = true
+		  AND enabled
+		  AND (last_started_at IS NULL OR last_started_at <= $1 - make_interval(hours => interval_hours))
+	`, now)
+	if err != nil {
+		return false, errors.E("postgres.backups.claim_scheduled", "claiming scheduled backup", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *backupRepository) RecordBackupRun(ctx context.Context, run store.BackupRun) error {
+	if _, err := r.pool.Exec(ctx, `
+		UPDATE backup_settings
+		SET last_finished_at = $1,
+		    last_backup = CASE WHEN $2 = '' THEN last_backup ELSE $2 END,
+		    last_error = $3
+		WHERE id = true
+	`, run.FinishedAt, run.Backup, run.Error); err != nil {
+		return errors.E("postgres.backups.record_run", "recording backup run", err)
+	}
+	return nil
+}
+
+// DumpDatabase reads every table inside one repeatable-read transaction, so
+// the tables are consistent with each other even while the app keeps writing.
+func (r *backupRepository) DumpDatabase(ctx context.Context, open store.BackupTableWriter) (store.DatabaseSchema, error) {
+	const op = "postgres.backups.dump"
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return store.DatabaseSchema{}, errors.E(op, "beginning backup transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	version, dirty, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return store.DatabaseSchema{}, errors.E(op, err)
+	}
+	if dirty {
+		return store.DatabaseSchema{}, errors.E(op, errors.FailedPrecondition,
+			errors.User(fmt.Sprintf("Migration %d did not finish; repair the schema before taking a backup.", version)))
+	}
+	tables, err := backupTables(ctx, tx)
+	if err != nil {
+		return store.DatabaseSchema{}, errors.E(op, err)
+	}
+	for i := range tables {
+		w, err := open(tables[i])
+		if err != nil {
+			return store.DatabaseSchema{}, errors.E(op, fmt.Sprintf("opening backup of %s", tables[i].Name), err)
+		}
+		tag, err := tx.Conn().PgConn().CopyTo(ctx, w, fmt.Sprintf("COPY %s (%s) TO STDOUT",
+			pgx.Identifier{tables[i].Name}.Sanitize(), columnList(tables[i].Columns)))
+		if err != nil {
+			return store.DatabaseSchema{}, errors.E(op, fmt.Sprintf("copying %s", tables[i].Name), err)
+		}
+		tables[i].Rows = tag.RowsAffected()
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return store.DatabaseSchema{}, errors.E(op, "committing backup transaction", err)
+	}
+	return store.DatabaseSchema{Version: version, Tables: tables}, nil
+}
+
+func (r *backupRepository) CheckBackupSchema(ctx context.Context, schema store.DatabaseSchema) (store.BackupSchemaCheck, error) {
+	const op = "postgres.backups.check_schema"
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return store.BackupSchemaCheck{}, errors.E(op, "beginning schema check", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	check, _, err := checkBackupSchema(ctx, tx, schema)
+	if err != nil {
+		return store.BackupSchemaCheck{}, errors.E(op, err)
+	}
+	return check, nil
+}
+
+// RestoreDatabase truncates every table and copies the backup in, parents
+// before the tables referencing them. User triggers are disabled while
+// copying: the backup already holds the transaction history, and the history
+// triggers would log every restored label again as a new change.
+func (r *backupRepository) RestoreDatabase(ctx context.Context, schema store.DatabaseSchema, open store.BackupTableReader) error {
+	const op = "postgres.backups.restore"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return errors.E(op, "beginning restore transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	check, current, err := checkBackupSchema(ctx, tx, schema)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if len(check.Problems) > 0 {
+		return errors.E(op, errors.FailedPrecondition,
+			errors.User("The backup does not match the current schema: "+strings.Join(check.Problems, "; ")))
+	}
+	order, err := restoreOrder(ctx, tx, current)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	names := make([]string, 0, len(current))
+	for _, table := range current {
+		names = append(names, pgx.Identifier{table.Name}.Sanitize())
+	}
+	if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(names, ", ")); err != nil {
+		return errors.E(op, "truncating tables", err)
+	}
+	if err := setUserTriggers(ctx, tx, names, "DISABLE"); err != nil {
+		return errors.E(op, err)
+	}
+
+	byName := make(map[string]store.BackupTable, len(schema.Tables))
+	for _, table := range schema.Tables {
+		byName[table.Name] = table
+	}
+	for _, name := range order {
+		table := byName[name]
+		rows, err := open(table)
+		if err != nil {
+			return errors.E(op, fmt.Sprintf("opening backup of %s", name), err)
+		}
+		tag, err := tx.Conn().PgConn().CopyFrom(ctx, rows, fmt.Sprintf("COPY %s (%s) FROM STDIN",
+			pgx.Identifier{name}.Sanitize(), columnList(table.Columns)))
+		if err != nil {
+			return errors.E(op, errors.InvalidInput, errors.User(fmt.Sprintf("Restoring table %s failed.", name)), err)
+		}
+		if tag.RowsAffected() != table.Rows {
+			return errors.E(op, errors.InvalidInput,
+				errors.User(fmt.Sprintf("Table %s restored %d rows but the backup lists %d.", name, tag.RowsAffected(), table.Rows)))
+		}
+	}
+	if err := setUserTriggers(ctx, tx, names, "ENABLE"); err != nil {
+		return errors.E(op, err)
+	}
+	if err := resetSequences(ctx, tx); err != nil {
+		return errors.E(op, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return errors.E(op, "committing restore transaction", err)
+	}
+	return nil
+}
+
+// setUserTriggers enables or disables the triggers created by migrations on
+// tables, which are sanitized names. Foreign key checks are internal triggers
+// and keep running.
+func setUserTriggers(ctx context.Context, tx pgx.Tx, tables []string, action string) error {
+	for _, table := range tables {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s %s TRIGGER USER", table, action)); err != nil {
+			return fmt.Errorf("%s triggers on %s: %w", strings.ToLower(action), table, err)
+		}
+	}
+	return nil
+}
+
+func schemaVersion(ctx context.Context, tx pgx.Tx) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	if err := tx.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty); err != nil {
+		return 0, false, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// backupTables lists the application tables on the search path with their
+// writable columns. Generated columns are recomputed on restore, and the
+// migration bookkeeping table is described by DatabaseSchema.Version instead.
+func backupTables(ctx context.Context, tx pgx.Tx) ([]store.BackupTable, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT c.relname::text, array_agg(a.attname::text ORDER BY a.attnum)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped AND a.attgenerated = ''
+		WHERE c.relkind = 'r'
+		  AND n.nspname = ANY (current_schemas(false))
+		  AND pg_table_is_visible(c.oid)
+		  AND c.relname <> 'schema_migrations'
+		GROUP BY c.relname
+		ORDER BY c.relname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []store.BackupTable
+	for rows.Next() {
+		var table store.BackupTable
+		if err := rows.Scan(&table.Name, &table.Columns); err != nil {
+			return nil, fmt.Errorf("scanning table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tables: %w", err)
+	}
+	return tables, nil
+}
+
+// checkBackupSchema compares schema with the database and also returns the
+// current tables.
+func checkBackupSchema(ctx context.Context, tx pgx.Tx, schema store.DatabaseSchema) (store.BackupSchemaCheck, []store.BackupTable, error) {
+	version, dirty, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return store.BackupSchemaCheck{}, nil, err
+	}
+	current, err := backupTables(ctx, tx)
+	if err != nil {
+		return store.BackupSchemaCheck{}, nil, err
+	}
+
+	check := store.BackupSchemaCheck{CurrentVersion: version, Problems: []string{}}
+	if dirty {
+		check.Problems = append(check.Problems, fmt.Sprintf("migration %d of the current database did not finish", version))
+	}
+	if schema.Version != version {
+		check.Problems = append(check.Problems, fmt.Sprintf("backup schema version %d does not match current version %d", schema.Version, version))
+	}
+	archived := make(map[string]store.BackupTable, len(schema.Tables))
+	for _, table := range schema.Tables {
+		archived[table.Name] = table
+	}
+	for _, table := range current {
+		backup, ok := archived[table.Name]
+		if !ok {
+			check.Problems = append(check.Problems, fmt.Sprintf("table %s is missing from the backup", table.Name))
+			continue
+		}
+		delete(archived, table.Name)
+		if missing, extra := diffColumns(table.Columns, backup.Columns); len(missing) > 0 || len(extra) > 0 {
+			check.Problems = append(check.Problems, fmt.Sprintf("table %s columns differ (missing: %s; unknown: %s)",
+				table.Name, strings.Join(missing, ", "), strings.Join(extra, ", ")))
+		}
+	}
+	unknown := make([]string, 0, len(archived))
+	for name := range archived {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		check.Problems = append(check.Problems, fmt.Sprintf("table %s does not exist in the current database", name))
+	}
+	return check, current, nil
+}
+
+// diffColumns returns the columns of want missing from got, and the columns
+// of got that want does not have.
+func diffColumns(want, got []string) (missing, extra []string) {
+	have := make(map[string]bool, len(got))
+	for _, column := range got {
+		have[column] = true
+	}
+	for _, column := range want {
+		if !have[column] {
+			missing = append(missing, column)
+		}
+		delete(have, column)
+	}
+	for column := range have {
+		extra = append(extra, column)
+	}
+	sort.Strings(extra)
+	return missing, extra
+}
+
+// restoreOrder sorts tables so every table comes after the tables its
+// foreign keys reference. Self-references are ignored.
+func restoreOrder(ctx context.Context, tx pgx.Tx, tables []store.BackupTable) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT child.relname::text, parent.relname::text
+		FROM pg_constraint c
+		JOIN pg_class child ON child.oid = c.conrelid
+		JOIN pg_class parent ON parent.oid = c.confrelid
+		WHERE c.contype = 'f'
+		  AND c.conrelid <> c.confrelid
+		  AND pg_table_is_visible(child.oid)
+		  AND pg_table_is_visible(parent.oid)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("listing foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make(map[string]int, len(tables))
+	for _, table := range tables {
+		pending[table.Name] = 0
+	}
+	dependents := map[string][]string{}
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, fmt.Errorf("scanning foreign key: %w", err)
+		}
+		if _, ok := pending[child]; !ok {
+			continue
+		}
+		if _, ok := pending[parent]; !ok {
+			continue
+		}
+		pending[child]++
+		dependents[parent] = append(dependents[parent], child)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating foreign keys: %w", err)
+	}
+
+	order := make([]string, 0, len(tables))
+	for len(pending) > 0 {
+		var ready []string
+		for name, parents := range pending {
+			if parents == 0 {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			return nil, errors.E(errors.Internal, "foreign keys between tables form a cycle")
+		}
+		sort.Strings(ready)
+		for _, name := range ready {
+			delete(pending, name)
+			for _, child := range dependents[name] {
+				pending[child]--
+			}
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
+
+// resetSequences moves every serial or identity sequence past the restored
+// rows, so the next insert does not reuse an ID.
+func resetSequences(ctx context.Context, tx pgx.Tx) error {
+	rows, err := tx.Query(ctx, `
+		SELECT c.relname::text, a.attname::text, seq
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		CROSS JOIN LATERAL pg_get_serial_sequence(quote_ident(n.nspname) || '.' || quote_ident(c.relname), a.attname) AS seq
+		WHERE c.relkind = 'r'
+		  AND n.nspname = ANY (current_schemas(false))
+		  AND pg_table_is_visible(c.oid)
+		  AND seq IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("listing sequences: %w", err)
+	}
+	type sequence struct{ table, column, name string }
+	var sequences []sequence
+	for rows.Next() {
+		var s sequence
+		if err := rows.Scan(&s.table, &s.column, &s.name); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning sequence: %w", err)
+		}
+		sequences = append(sequences, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating sequences: %w", err)
+	}
+	for _, s := range sequences {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`SELECT setval($1::regclass, COALESCE((SELECT max(%s) FROM %s), 0) + 1, false)`,
+			pgx.Identifier{s.column}.Sanitize(), pgx.Identifier{s.table}.Sanitize()), s.name); err != nil {
+			return fmt.Errorf("resetting sequence %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func columnList(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -55,7 +55,7 @@ func (r *communityRepository) GetBucketMappings(ctx context.Context, tenant stor
 }
 
 func (r *communityRepository) CategorizeMerchant(ctx context.Context, tenant store.Tenant, merchant, category, bucket string) (int64, error) {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseMerchantMapping)
 	if err != nil {
 		return 0, errors.E("postgres.community.categorize_merchant", "beginning categorize-merchant transaction", err)
 	}
@@ -99,7 +99,7 @@ func (r *communityRepository) applyTaxonomyByMerchant(
 	column string,
 	value string,
 ) (int64, error) {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseMerchantMapping)
 	if err != nil {
 		return 0, errors.E("postgres.community.apply_taxonomy_by_merchant", "beginning taxonomy merchant transaction", err)
 	}
@@ -12,3 +12,12 @@ func TestStoreConformance(t *testing.T) {
 
 	storetest.Run(t, ts.Store)
 }
+
+func TestStoreRestoreIntoEmptyDatabase(t *testing.T) {
+	source := newTestStore(t)
+	defer source.cleanup()
+	target := newTestStore(t)
+	defer target.cleanup()
+
+	storetest.RunRestore(t, source.Store, target.Store)
+}
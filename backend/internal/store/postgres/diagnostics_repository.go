@@ -3,11 +3,13 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/api"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
@@ -34,6 +36,7 @@ const recordExtractionDiagnosticSQL = `
 	    currency_regex = EXCLUDED.currency_regex,
 	    failure_reasons = EXCLUDED.failure_reasons,
 	    updated_at = NOW()
+	RETURNING id::text, (xmax = 0)
 `
 
 const diagnosticColumns = `
@@ -43,12 +46,16 @@ const diagnosticColumns = `
 `
 
 type diagnosticsRepository struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	webhooks *webhookRepository
+	events   events.Publisher
 }
 
-func newDiagnosticsRepository(deps repositoryDependencies) *diagnosticsRepository {
+func newDiagnosticsRepository(deps repositoryDependencies, webhooks *webhookRepository) *diagnosticsRepository {
 	return &diagnosticsRepository{
-		pool: deps.pool,
+		pool:     deps.pool,
+		webhooks: webhooks,
+		events:   deps.events,
 	}
 }
 
@@ -57,7 +64,9 @@ func (r *diagnosticsRepository) RecordExtractionDiagnostic(ctx context.Context,
 		return errors.E("postgres.diagnostics.record_extraction", errors.InvalidInput, "tenant is required")
 	}
 
-	_, err := r.pool.Exec(ctx, recordExtractionDiagnosticSQL,
+	var id string
+	var inserted bool
+	err := r.pool.QueryRow(ctx, recordExtractionDiagnosticSQL,
 		tenant.ID,
 		diagnostic.Reader,
 		nullableString(diagnostic.MessageID),
@@ -74,13 +83,47 @@ func (r *diagnosticsRepository) RecordExtractionDiagnostic(ctx context.Context,
 		diagnostic.MerchantRegex,
 		diagnostic.CurrencyRegex,
 		diagnosticFailureReasons(diagnostic.FailureReasons),
-	)
+	).Scan(&id, &inserted)
 	if err != nil {
 		return errors.E("postgres.diagnostics.record_extraction_diagnostic", "recording extraction diagnostic", err)
 	}
+	// A diagnostic recorded again for the same email while still open is
+	// not announced twice.
+	if inserted {
+		r.webhooks.enqueue(ctx, tenant.ID, store.WebhookDiagnosticOpened, diagnosticOpenedEvent{
+			ID:             id,
+			Reader:         diagnostic.Reader,
+			MessageID:      diagnostic.MessageID,
+			Source:         diagnostic.Source,
+			SenderEmail:    diagnostic.SenderEmail,
+			Subject:        diagnostic.Subject,
+			ReceivedAt:     diagnostic.ReceivedAt,
+			RuleName:       diagnostic.RuleName,
+			FailureReasons: diagnosticFailureReasons(diagnostic.FailureReasons),
+		})
+		r.events.Publish(tenant.ID, events.TypeDiagnosticCreated, events.Diagnostic{
+			ID:      id,
+			Reader:  diagnostic.Reader,
+			Source:  diagnostic.Source,
+			Subject: diagnostic.Subject,
+		})
+	}
 	return nil
 }
 
+// diagnosticOpenedEvent is the data of diagnostic.opened webhook events.
+type diagnosticOpenedEvent struct {
+	ID             string     `json:"id"`
+	Reader         string     `json:"reader"`
+	MessageID      string     `json:"message_id"`
+	Source         string     `json:"source"`
+	SenderEmail    string     `json:"sender_email"`
+	Subject        string     `json:"subject"`
+	ReceivedAt     *time.Time `json:"received_at"`
+	RuleName       string     `json:"rule_name"`
+	FailureReasons []string   `json:"failure_reasons"`
+}
+
 func (r *diagnosticsRepository) ListExtractionDiagnostics(
 	ctx context.Context,
 	tenant store.Tenant,
@@ -141,24 +141,6 @@ func appendTaxonomyListWhere(conds []string, f store.ListFilter, next func(any)
 	return conds
 }
 
-// buildSearchCondition appends raw search text and returns a safe hybrid search condition.
-func buildSearchCondition(query string, args *[]any) string {
-	*args = append(*args, query)
-	tsArg := len(*args)
-	*args = append(*args, escapeLikePattern(query))
-	likeArg := len(*args)
-	return fmt.Sprintf(
-		`(
-			(to_tsvector('english', t.merchant_info) || to_tsvector('english', COALESCE(t.description,''))) @@ websearch_to_tsquery('english', $%d)
-			OR t.merchant_info ILIKE $%d ESCAPE '\'
-			OR COALESCE(t.description, '') ILIKE $%d ESCAPE '\'
-		)`,
-		tsArg,
-		likeArg,
-		likeArg,
-	)
-}
-
 func escapeLikePattern(value string) string {
 	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
 	return "%" + replacer.Replace(value) + "%"
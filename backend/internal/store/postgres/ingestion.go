@@ -4,26 +4,47 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/api"
 	apperrors "github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
 type ingestionRepository struct {
-	pool   poolBeginner
-	logger *slog.Logger
+	pool          poolBeginner
+	logger        *slog.Logger
+	attachments   *attachmentRepository
+	webhooks      *webhookRepository
+	notifications *notificationRepository
+	diagnostics   *diagnosticsRepository
+	events        events.Publisher
 }
 
 const defaultTransactionCurrency = "INR"
 
-func newIngestionRepository(deps repositoryDependencies) *ingestionRepository {
+// maxEmailContentBytes caps the searchable email body stored per transaction.
+const maxEmailContentBytes = 64 << 10
+
+func newIngestionRepository(
+	deps repositoryDependencies,
+	attachments *attachmentRepository,
+	webhooks *webhookRepository,
+	notifications *notificationRepository,
+	diagnostics *diagnosticsRepository,
+) *ingestionRepository {
 	return &ingestionRepository{
-		pool:   deps.pool,
-		logger: deps.logger,
+		pool:          deps.pool,
+		logger:        deps.logger,
+		attachments:   attachments,
+		webhooks:      webhooks,
+		notifications: notifications,
+		diagnostics:   diagnostics,
+		events:        deps.events,
 	}
 }
 
@@ -39,8 +60,9 @@ func (w *ingestionRepository) Write(ctx context.Context, batch store.IngestionBa
 		return nil
 	}
 
-	// Start a transaction
-	tx, err := w.pool.Begin(ctx) // TODO: Can we have a WithTx(...) context manager pattern here?
+	// Upserts of already stored messages are logged as re-extraction; the
+	// mapping passes below get a batch of their own.
+	tx, err := beginChange(ctx, w.pool, store.ChangeCauseReExtraction)
 	if err != nil {
 		return apperrors.E("postgres.ingestion.write", apperrors.Internal, "beginning transaction", err)
 	}
@@ -51,32 +73,44 @@ func (w *ingestionRepository) Write(ctx context.Context, batch store.IngestionBa
 	const conflictClause = "ON CONFLICT (tenant_id, message_id) WHERE tenant_id IS NOT NULL"
 	for _, txn := range transactions {
 		currency, timestamp := w.normalizeWriteInput(txn)
+		// A re-extracted amount must keep any splits summing to it, so they
+		// are rescaled in the same statement.
 		pgBatch.Queue(fmt.Sprintf(`
-			INSERT INTO transactions (
-				tenant_id, message_id, amount, currency, original_amount, original_currency,
-				exchange_rate, timestamp, merchant_info, category, bucket, source,
-				source_type, source_label, bank, description
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
-			%s DO UPDATE SET
-				amount            = EXCLUDED.amount,
-				currency          = EXCLUDED.currency,
-				original_amount   = EXCLUDED.original_amount,
-				original_currency = EXCLUDED.original_currency,
-				exchange_rate     = EXCLUDED.exchange_rate,
-				timestamp         = EXCLUDED.timestamp,
-				merchant_info     = EXCLUDED.merchant_info,
-				source            = EXCLUDED.source,
-				source_type       = EXCLUDED.source_type,
-				source_label      = EXCLUDED.source_label,
-				bank              = EXCLUDED.bank,
-				-- Preserve user edits: only fall back to extracted value when the
-				-- stored value is NULL or empty (user has not yet set it).
-				category = COALESCE(NULLIF(transactions.category, ''), EXCLUDED.category),
-				bucket   = COALESCE(NULLIF(transactions.bucket, ''), EXCLUDED.bucket),
-				-- description is never produced by extraction; never overwrite it.
-				updated_at = NOW()
-			RETURNING id
-		`, conflictClause),
+			WITH upserted AS (
+				INSERT INTO transactions (
+					tenant_id, message_id, amount, currency, original_amount, original_currency,
+					exchange_rate, timestamp, merchant_info, category, bucket, source,
+					source_type, source_label, bank, description
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+				%s DO UPDATE SET
+					amount            = EXCLUDED.amount,
+					currency          = EXCLUDED.currency,
+					original_amount   = EXCLUDED.original_amount,
+					original_currency = EXCLUDED.original_currency,
+					exchange_rate     = EXCLUDED.exchange_rate,
+					timestamp         = EXCLUDED.timestamp,
+					merchant_info     = EXCLUDED.merchant_info,
+					source            = EXCLUDED.source,
+					source_type       = EXCLUDED.source_type,
+					source_label      = EXCLUDED.source_label,
+					bank              = EXCLUDED.bank,
+					-- Preserve user edits: only fall back to extracted value when the
+					-- stored value is NULL or empty (user has not yet set it).
+					category = COALESCE(NULLIF(transactions.category, ''), EXCLUDED.category),
+					bucket   = COALESCE(NULLIF(transactions.bucket, ''), EXCLUDED.bucket),
+					-- description is never produced by extraction; never overwrite it.
+					updated_at = NOW()
+				-- xmax is only set on rows the upsert updated.
+				RETURNING id, amount, (xmax = 0) AS inserted
+			),
+			split_parents AS (
+				SELECT id, amount FROM upserted WHERE NOT inserted
+			),
+			%s
+			SELECT u.id, u.inserted, u.amount, f.previous, f.rescaled
+			FROM upserted u
+			LEFT JOIN split_fit f ON f.transaction_id = u.id
+		`, conflictClause, splitRescaleCTEs),
 			batch.Tenant.ID,
 			txn.MessageID,
 			txn.Amount,
@@ -101,11 +135,19 @@ func (w *ingestionRepository) Write(ctx context.Context, batch store.IngestionBa
 
 	// First: Collect all transaction IDs (fully consume batch results)
 	txnIDs := make([]string, len(transactions))
+	inserted := make([]bool, len(transactions))
+	rescales := make(map[int]splitRescale)
 	for i := 0; i < len(transactions); i++ {
-		if err := batchResults.QueryRow().Scan(&txnIDs[i]); err != nil {
+		var amount float64
+		var previous *float64
+		var rescaled *bool
+		if err := batchResults.QueryRow().Scan(&txnIDs[i], &inserted[i], &amount, &previous, &rescaled); err != nil {
 			_ = batchResults.Close()
 			return apperrors.E("postgres.ingestion.write", apperrors.Internal, fmt.Sprintf("inserting transaction %d", i), err)
 		}
+		if rescaled != nil && previous != nil {
+			rescales[i] = splitRescale{transactionID: txnIDs[i], previous: *previous, amount: amount, rescaled: *rescaled}
+		}
 	}
 
 	// Close batch results before executing more queries on the transaction
@@ -113,15 +155,21 @@ func (w *ingestionRepository) Write(ctx context.Context, batch store.IngestionBa
 		return apperrors.E("postgres.ingestion.write", apperrors.Internal, "closing batch results", err)
 	}
 
-	// Second: Insert labels (now safe to use tx.Exec)
+	// Second: Insert labels and searchable email text (now safe to use tx.Exec)
 	for i, txn := range transactions {
 		if len(txn.Labels) > 0 {
 			if err := w.insertLabels(ctx, tx, txnIDs[i], txn.Labels); err != nil {
 				return apperrors.E("postgres.ingestion.write", apperrors.Internal, fmt.Sprintf("inserting labels for transaction %s", txnIDs[i]), err)
 			}
 		}
+		if err := w.upsertEmailContent(ctx, tx, txnIDs[i], txn); err != nil {
+			return apperrors.E("postgres.ingestion.write", apperrors.Internal, fmt.Sprintf("storing email content for transaction %s", txnIDs[i]), err)
+		}
 	}
 
+	if _, err := startChangeBatch(ctx, tx, store.ChangeCauseRuleEngine); err != nil {
+		return apperrors.E("postgres.ingestion.write", apperrors.Internal, err)
+	}
 	if err := w.applyMerchantLabels(ctx, tx, txnIDs); err != nil {
 		return apperrors.E("postgres.ingestion.write", apperrors.Internal, "auto-applying merchant labels", err)
 	}
@@ -137,9 +185,63 @@ func (w *ingestionRepository) Write(ctx context.Context, batch store.IngestionBa
 		return apperrors.E("postgres.ingestion.write", apperrors.Internal, "committing transaction", err)
 	}
 
+	if w.diagnostics != nil {
+		for i, rescale := range rescales {
+			txn := transactions[i]
+			diagnostic := rescale.diagnostic(api.ExtractionDiagnostic{
+				Reader:    string(store.ChangeCauseReExtraction),
+				MessageID: txn.MessageID,
+				Source:    txn.Source.Display(),
+				Subject:   txn.EmailSubject,
+				EmailBody: txn.EmailBody,
+			})
+			if err := w.diagnostics.RecordExtractionDiagnostic(ctx, batch.Tenant, diagnostic); err != nil {
+				w.logger.Warn("failed to record split rescale diagnostic", "transaction_id", rescale.transactionID, "error", err)
+			}
+		}
+	}
+	// Email attachments go to the blob store, so they are saved only once the
+	// transactions they belong to are committed.
+	if w.attachments != nil {
+		for i, txn := range transactions {
+			w.attachments.attachEmailFiles(ctx, batch.Tenant, txnIDs[i], emailAttachments(txn))
+		}
+	}
+	var created, updated []string
+	for i, id := range txnIDs {
+		if inserted[i] {
+			created = append(created, id)
+		} else {
+			updated = append(updated, id)
+		}
+	}
+	if w.webhooks != nil {
+		w.webhooks.enqueueTransactions(ctx, batch.Tenant.ID, store.WebhookTransactionCreated, created)
+		w.webhooks.enqueueTransactions(ctx, batch.Tenant.ID, store.WebhookTransactionUpdated, updated)
+	}
+	// Only newly scanned transactions raise alerts; rescanning one that is
+	// already stored must not alert again.
+	if w.notifications != nil {
+		w.notifications.enqueueLargeTransactions(ctx, batch.Tenant.ID, created)
+	}
+	if len(txnIDs) > 0 {
+		w.events.Publish(batch.Tenant.ID, events.TypeTransactionBatch, events.TransactionBatch{Created: created, Updated: updated})
+	}
 	return nil
 }
 
+func emailAttachments(txn *api.TransactionDetails) []store.NewAttachment {
+	files := make([]store.NewAttachment, 0, len(txn.Attachments))
+	for _, attachment := range txn.Attachments {
+		files = append(files, store.NewAttachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Data:        attachment.Data,
+		})
+	}
+	return files
+}
+
 func (w *ingestionRepository) normalizeWriteInput(txn *api.TransactionDetails) (string, time.Time) {
 	currency := txn.Currency
 	if currency == "" {
@@ -230,6 +332,31 @@ func (w *ingestionRepository) applyMutedMerchants(ctx context.Context, tx pgx.Tx
 	return err
 }
 
+// upsertEmailContent stores the plain-text source email used by transaction search.
+func (w *ingestionRepository) upsertEmailContent(ctx context.Context, tx pgx.Tx, txnID string, txn *api.TransactionDetails) error {
+	subject := strings.TrimSpace(txn.EmailSubject)
+	body := truncateEmailContent(strings.TrimSpace(txn.EmailBody))
+	if subject == "" && body == "" {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO transaction_email_content (transaction_id, subject, body)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (transaction_id) DO UPDATE SET
+			subject    = EXCLUDED.subject,
+			body       = EXCLUDED.body,
+			updated_at = NOW()
+	`, txnID, subject, body)
+	return err
+}
+
+func truncateEmailContent(body string) string {
+	if len(body) <= maxEmailContentBytes {
+		return body
+	}
+	return strings.ToValidUTF8(body[:maxEmailContentBytes], "")
+}
+
 // insertLabels inserts labels for a transaction.
 func (w *ingestionRepository) insertLabels(ctx context.Context, tx pgx.Tx, txnID string, labels []string) error {
 	if len(labels) == 0 {
@@ -262,6 +262,77 @@ func TestWrite_Upsert_PreservesUserEdits(t *testing.T) {
 	}
 }
 
+// TestWrite_Upsert_RescalesSplits verifies that a re-extracted amount keeps the
+// transaction's splits summing to it, that a revert does the same, and that
+// both raise a diagnostic.
+func TestWrite_Upsert_RescalesSplits(t *testing.T) {
+	w := newTestIngestor(t, store.IngestionConfig{BatchSize: 1, FlushInterval: time.Second})
+	ctx := context.Background()
+
+	txn := &api.TransactionDetails{
+		MessageID:    fmt.Sprintf("upsert-splits-%d", time.Now().UnixNano()),
+		Amount:       300,
+		Currency:     "INR",
+		Timestamp:    time.Now().Format(time.RFC3339),
+		MerchantInfo: "Swiggy",
+		Source:       api.Source{Label: "UPI"},
+	}
+	assertWrite(t, w, []*api.TransactionDetails{txn}, 5*time.Second)
+
+	var id string
+	if err := poolForTest(w.st).QueryRow(ctx, `SELECT id FROM transactions WHERE message_id = $1`, txn.MessageID).Scan(&id); err != nil {
+		t.Fatalf("query transaction: %v", err)
+	}
+	if err := w.st.SetTransactionSplits(ctx, w.tenant, id, []store.TransactionSplitInput{
+		{Amount: 100}, {Amount: 200, Counterparty: "Priya"},
+	}); err != nil {
+		t.Fatalf("SetTransactionSplits: %v", err)
+	}
+
+	splits := func(want ...float64) {
+		t.Helper()
+		got, err := w.st.GetTransaction(ctx, w.tenant, id)
+		if err != nil {
+			t.Fatalf("GetTransaction: %v", err)
+		}
+		if len(got.Splits) != len(want) {
+			t.Fatalf("splits = %#v, want amounts %v", got.Splits, want)
+		}
+		for i, split := range got.Splits {
+			if split.Amount != want[i] {
+				t.Fatalf("split %d amount = %v, want %v", i, split.Amount, want[i])
+			}
+		}
+	}
+
+	txn.Amount = 400
+	assertWrite(t, w, []*api.TransactionDetails{txn}, 5*time.Second)
+	splits(133.3333, 266.6667)
+
+	history, err := w.st.ListTransactionHistory(ctx, w.tenant, id)
+	if err != nil || len(history) == 0 || history[0].Field != "amount" {
+		t.Fatalf("ListTransactionHistory = %#v, err = %v", history, err)
+	}
+	if _, err := w.st.RevertChangeBatch(ctx, w.tenant, history[0].BatchID); err != nil {
+		t.Fatalf("RevertChangeBatch: %v", err)
+	}
+	splits(100, 200)
+
+	rows, err := w.st.ListExtractionDiagnostics(ctx, w.tenant, store.DiagnosticFilter{Status: store.DiagnosticStatusOpen, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListExtractionDiagnostics: %v", err)
+	}
+	readers := map[string]bool{}
+	for _, row := range rows {
+		if row.MessageID == txn.MessageID && len(row.FailureReasons) == 1 && row.FailureReasons[0] == api.FailureSplitsRescaled {
+			readers[row.Reader] = true
+		}
+	}
+	if !readers[string(store.ChangeCauseReExtraction)] || !readers[string(store.ChangeCauseRevert)] {
+		t.Fatalf("diagnostics = %#v, want split rescales for re-extraction and revert", rows)
+	}
+}
+
 // TestWrite_Upsert_PopulatesEmptyCategoryBucket verifies that when a transaction has
 // no user-set category or bucket, re-processing updates them from the extracted values.
 func TestWrite_Upsert_PopulatesEmptyCategoryBucket(t *testing.T) {
@@ -84,7 +84,7 @@ func (r *taxonomyRepository) UpdateLabel(ctx context.Context, tenant store.Tenan
 }
 
 func (r *taxonomyRepository) DeleteLabel(ctx context.Context, tenant store.Tenant, name string, removeFromTransactions bool) error {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseUserEdit)
 	if err != nil {
 		return errors.E("postgres.label.delete_label", "deleting label: beginning delete-label transaction", err)
 	}
@@ -120,7 +120,7 @@ func (r *taxonomyRepository) DeleteLabel(ctx context.Context, tenant store.Tenan
 }
 
 func (r *taxonomyRepository) ApplyLabelByMerchant(ctx context.Context, tenant store.Tenant, label, pattern string) (int64, error) {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseMerchantMapping)
 	if err != nil {
 		return 0, errors.E("postgres.label.apply_label_by_merchant", "beginning apply-label-by-merchant transaction", err)
 	}
@@ -172,7 +172,7 @@ const labelMerchantUpsertSQL = `
 `
 
 func (r *taxonomyRepository) RemoveLabelByMerchant(ctx context.Context, tenant store.Tenant, label, pattern string) (int64, error) {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseMerchantMapping)
 	if err != nil {
 		return 0, errors.E("postgres.label.remove_label_by_merchant", "beginning remove-label-by-merchant transaction", err)
 	}
@@ -444,7 +444,7 @@ func (r *taxonomyRepository) deleteNamedTaxonomy(
 	ctx context.Context,
 	input taxonomyDeleteInput,
 ) error {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseUserEdit)
 	if err != nil {
 		return errors.E("postgres.label.delete_named_taxonomy", fmt.Sprintf("beginning delete %s transaction", input.spec.kind), err)
 	}
@@ -0,0 +1,653 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	sharedLedgerColumns = `l.id, l.name, COALESCE(l.created_by::text, ''), l.created_at`
+	settlementColumns   = `id, ledger_id, from_user_id, to_user_id, amount, currency, note, settled_at, COALESCE(created_by::text, ''), created_at`
+)
+
+type ledgerRepository struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func newLedgerRepository(deps repositoryDependencies) *ledgerRepository {
+	return &ledgerRepository{pool: deps.pool, now: deps.now}
+}
+
+func (r *ledgerRepository) CreateSharedLedger(
+	ctx context.Context,
+	userID string,
+	input store.CreateSharedLedgerInput,
+) (store.SharedLedger, error) {
+	const op = "postgres.ledgers.create_shared_ledger"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.SharedLedger{}, errors.E(op, "beginning create-ledger transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var ledgerID string
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO shared_ledgers (name, created_by) VALUES ($1, $2) RETURNING id`,
+		input.Name, userID,
+	).Scan(&ledgerID); err != nil {
+		return store.SharedLedger{}, errors.E(op, "inserting ledger", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO shared_ledger_members (ledger_id, user_id) VALUES ($1, $2)`,
+		ledgerID, userID,
+	); err != nil {
+		return store.SharedLedger{}, errors.E(op, "adding ledger owner", err)
+	}
+	if err := inviteLedgerMembers(ctx, tx, userID, ledgerID, input.InviteEmails); err != nil {
+		return store.SharedLedger{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return store.SharedLedger{}, errors.E(op, "committing create-ledger transaction", err)
+	}
+	return r.GetSharedLedger(ctx, userID, ledgerID)
+}
+
+func (r *ledgerRepository) ListSharedLedgers(ctx context.Context, userID string) ([]store.SharedLedger, error) {
+	const op = "postgres.ledgers.list_shared_ledgers"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+sharedLedgerColumns+`
+		FROM shared_ledgers l
+		JOIN shared_ledger_members m ON m.ledger_id = l.id
+		WHERE m.user_id = $1
+		ORDER BY l.created_at DESC, l.id
+	`, userID)
+	if err != nil {
+		return nil, errors.E(op, "listing ledgers", err)
+	}
+	defer rows.Close()
+
+	ledgers := make([]store.SharedLedger, 0)
+	for rows.Next() {
+		ledger, err := scanSharedLedger(rows)
+		if err != nil {
+			return nil, errors.E(op, "scanning ledger", err)
+		}
+		ledgers = append(ledgers, ledger)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating ledgers", err)
+	}
+	rows.Close()
+
+	if err := r.loadLedgerMembers(ctx, ledgers); err != nil {
+		return nil, err
+	}
+	return ledgers, nil
+}
+
+func (r *ledgerRepository) GetSharedLedger(ctx context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	ledger, err := scanSharedLedger(r.pool.QueryRow(ctx, `
+		SELECT `+sharedLedgerColumns+`
+		FROM shared_ledgers l
+		JOIN shared_ledger_members m ON m.ledger_id = l.id
+		WHERE l.id = $1 AND m.user_id = $2
+	`, ledgerID, userID))
+	if errorsIsNoRows(err) {
+		return store.SharedLedger{}, errLedgerNotFound()
+	}
+	if err != nil {
+		return store.SharedLedger{}, errors.E("postgres.ledgers.get_shared_ledger", "fetching ledger", err)
+	}
+	ledgers := []store.SharedLedger{ledger}
+	if err := r.loadLedgerMembers(ctx, ledgers); err != nil {
+		return store.SharedLedger{}, err
+	}
+	return ledgers[0], nil
+}
+
+func (r *ledgerRepository) InviteSharedLedgerMember(ctx context.Context, userID, ledgerID, email string) error {
+	const op = "postgres.ledgers.invite_shared_ledger_member"
+
+	if err := requireLedgerMember(ctx, r.pool, userID, ledgerID); err != nil {
+		return err
+	}
+	var owner bool
+	if err := r.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM shared_ledgers WHERE id = $1 AND created_by = $2)`,
+		ledgerID, userID,
+	).Scan(&owner); err != nil {
+		return errors.E(op, "checking ledger owner", err)
+	}
+	if !owner {
+		return errors.E(op, errors.PermissionDenied, errors.User("Only the ledger owner can invite members."))
+	}
+	return inviteLedgerMembers(ctx, r.pool, userID, ledgerID, []string{email})
+}
+
+func (r *ledgerRepository) ListSharedLedgerInvitations(ctx context.Context, userID string) ([]store.SharedLedgerInvitation, error) {
+	const op = "postgres.ledgers.list_shared_ledger_invitations"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT l.id, l.name, COALESCE(i.invited_by::text, ''), COALESCE(u.display_name, ''), i.created_at
+		FROM shared_ledger_invitations i
+		JOIN shared_ledgers l ON l.id = i.ledger_id
+		LEFT JOIN users u ON u.id = i.invited_by
+		WHERE i.user_id = $1
+		ORDER BY i.created_at DESC, l.id
+	`, userID)
+	if err != nil {
+		return nil, errors.E(op, "listing invitations", err)
+	}
+	defer rows.Close()
+
+	invitations := make([]store.SharedLedgerInvitation, 0)
+	for rows.Next() {
+		var inv store.SharedLedgerInvitation
+		if err := rows.Scan(&inv.LedgerID, &inv.LedgerName, &inv.InvitedBy, &inv.InvitedByName, &inv.CreatedAt); err != nil {
+			return nil, errors.E(op, "scanning invitation", err)
+		}
+		invitations = append(invitations, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating invitations", err)
+	}
+	return invitations, nil
+}
+
+func (r *ledgerRepository) AcceptSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	const op = "postgres.ledgers.accept_shared_ledger_invitation"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.SharedLedger{}, errors.E(op, "beginning accept-invitation transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx,
+		`DELETE FROM shared_ledger_invitations WHERE ledger_id = $1 AND user_id = $2`,
+		ledgerID, userID,
+	)
+	if err != nil {
+		return store.SharedLedger{}, errors.E(op, "removing invitation", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return store.SharedLedger{}, errInvitationNotFound()
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO shared_ledger_members (ledger_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (ledger_id, user_id) DO NOTHING
+	`, ledgerID, userID); err != nil {
+		return store.SharedLedger{}, errors.E(op, "adding ledger member", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return store.SharedLedger{}, errors.E(op, "committing accept-invitation transaction", err)
+	}
+	return r.GetSharedLedger(ctx, userID, ledgerID)
+}
+
+func (r *ledgerRepository) DeclineSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) error {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM shared_ledger_invitations WHERE ledger_id = $1 AND user_id = $2`,
+		ledgerID, userID,
+	)
+	if err != nil {
+		return errors.E("postgres.ledgers.decline_shared_ledger_invitation", "removing invitation", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errInvitationNotFound()
+	}
+	return nil
+}
+
+func (r *ledgerRepository) ShareExpense(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, ledgerID string,
+	input store.ShareExpenseInput,
+) (store.SharedExpense, error) {
+	const op = "postgres.ledgers.share_expense"
+
+	if err := store.ValidateExpenseShares(userID, input.Shares); err != nil {
+		return store.SharedExpense{}, err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.SharedExpense{}, errors.E(op, "beginning share-expense transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := requireLedgerMember(ctx, tx, userID, ledgerID); err != nil {
+		return store.SharedExpense{}, err
+	}
+	shareUserIDs := make([]string, 0, len(input.Shares))
+	for _, share := range input.Shares {
+		shareUserIDs = append(shareUserIDs, share.UserID)
+	}
+	var members int
+	if err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM shared_ledger_members WHERE ledger_id = $1 AND user_id = ANY($2::uuid[])`,
+		ledgerID, shareUserIDs,
+	).Scan(&members); err != nil {
+		return store.SharedExpense{}, errors.E(op, "checking share members", err)
+	}
+	if members != len(shareUserIDs) {
+		return store.SharedExpense{}, errors.E(op, errors.InvalidInput, errors.User("Expenses can only be shared with members of this ledger."))
+	}
+
+	// Only the payer's own transaction, or a split of it, can be shared.
+	var amount float64
+	var currency string
+	if input.SplitID == "" {
+		err = tx.QueryRow(ctx,
+			`SELECT amount, currency FROM transactions WHERE id = $1 AND tenant_id = $2`,
+			input.TransactionID, tenant.ID,
+		).Scan(&amount, &currency)
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT s.amount, t.currency
+			FROM transaction_splits s
+			JOIN transactions t ON t.id = s.transaction_id
+			WHERE s.id = $3 AND t.id = $1 AND t.tenant_id = $2
+		`, input.TransactionID, tenant.ID, input.SplitID).Scan(&amount, &currency)
+	}
+	if errorsIsNoRows(err) {
+		return store.SharedExpense{}, errors.E(op, errors.NotFound, errors.User("transaction not found"))
+	}
+	if err != nil {
+		return store.SharedExpense{}, errors.E(op, "fetching shared allocation", err)
+	}
+
+	var expenseID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO shared_expenses (ledger_id, transaction_id, split_id, paid_by, amount, currency)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6)
+		RETURNING id
+	`, ledgerID, input.TransactionID, input.SplitID, userID, amount, currency).Scan(&expenseID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return store.SharedExpense{}, errors.E(op, errors.Conflict, errors.User("This expense is already shared."), err)
+		}
+		return store.SharedExpense{}, errors.E(op, "inserting shared expense", err)
+	}
+	for _, share := range store.ShareAmounts(amount, input.Shares) {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO shared_expense_shares (expense_id, user_id, ratio, amount)
+			VALUES ($1, $2, $3, $4)
+		`, expenseID, share.UserID, share.Ratio, share.Amount); err != nil {
+			return store.SharedExpense{}, errors.E(op, "inserting expense share", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return store.SharedExpense{}, errors.E(op, "committing share-expense transaction", err)
+	}
+	expenses, err := r.querySharedExpenses(ctx, `e.id = $1`, expenseID)
+	if err != nil {
+		return store.SharedExpense{}, err
+	}
+	if len(expenses) == 0 {
+		return store.SharedExpense{}, errors.E(op, errors.NotFound, errors.User("shared expense not found"))
+	}
+	return expenses[0], nil
+}
+
+func (r *ledgerRepository) ListSharedExpenses(ctx context.Context, userID, ledgerID string) ([]store.SharedExpense, error) {
+	if err := requireLedgerMember(ctx, r.pool, userID, ledgerID); err != nil {
+		return nil, err
+	}
+	return r.querySharedExpenses(ctx, `e.ledger_id = $1`, ledgerID)
+}
+
+func (r *ledgerRepository) DeleteSharedExpense(ctx context.Context, userID, ledgerID, expenseID string) error {
+	const op = "postgres.ledgers.delete_shared_expense"
+
+	if err := requireLedgerMember(ctx, r.pool, userID, ledgerID); err != nil {
+		return err
+	}
+	var deleted bool
+	err := r.pool.QueryRow(ctx, `
+		WITH target AS (
+			SELECT id, paid_by
+			FROM shared_expenses
+			WHERE id = $1 AND ledger_id = $2
+		), deleted AS (
+			DELETE FROM shared_expenses e
+			USING target
+			WHERE e.id = target.id AND target.paid_by = $3
+			RETURNING e.id
+		)
+		SELECT EXISTS (SELECT 1 FROM deleted)
+		FROM target
+	`, expenseID, ledgerID, userID).Scan(&deleted)
+	if errorsIsNoRows(err) {
+		return errors.E(op, errors.NotFound, errors.User("shared expense not found"))
+	}
+	if err != nil {
+		return errors.E(op, "deleting shared expense", err)
+	}
+	if !deleted {
+		return errors.E(op, errors.PermissionDenied, errors.User("Only the member who paid can unshare an expense."))
+	}
+	return nil
+}
+
+func (r *ledgerRepository) RecordSettlement(
+	ctx context.Context,
+	userID, ledgerID string,
+	input store.RecordSettlementInput,
+) (store.Settlement, error) {
+	const op = "postgres.ledgers.record_settlement"
+
+	if input.FromUserID == input.ToUserID {
+		return store.Settlement{}, errors.E(op, errors.InvalidInput, errors.User("A settlement needs two different members."))
+	}
+	if userID != input.FromUserID && userID != input.ToUserID {
+		return store.Settlement{}, errors.E(op, errors.PermissionDenied, errors.User("You can only record settlements you paid or received."))
+	}
+	if err := requireLedgerMember(ctx, r.pool, userID, ledgerID); err != nil {
+		return store.Settlement{}, err
+	}
+	settledAt := input.SettledAt
+	if settledAt.IsZero() {
+		settledAt = r.now().UTC()
+	}
+
+	settlement, err := scanSettlement(r.pool.QueryRow(ctx, `
+		INSERT INTO ledger_settlements (ledger_id, from_user_id, to_user_id, amount, currency, note, settled_at, created_by)
+		SELECT $1::uuid, $2::uuid, $3::uuid, $4::numeric, $5::text, $6::text, $7::timestamptz, $8::uuid
+		WHERE (
+			SELECT COUNT(*) FROM shared_ledger_members
+			WHERE ledger_id = $1::uuid AND user_id IN ($2::uuid, $3::uuid)
+		) = 2
+		RETURNING `+settlementColumns,
+		ledgerID, input.FromUserID, input.ToUserID, input.Amount, input.Currency, input.Note, settledAt, userID,
+	))
+	if errorsIsNoRows(err) {
+		return store.Settlement{}, errors.E(op, errors.InvalidInput, errors.User("Settlements can only be recorded between members of this ledger."))
+	}
+	if err != nil {
+		return store.Settlement{}, errors.E(op, "inserting settlement", err)
+	}
+	return settlement, nil
+}
+
+func (r *ledgerRepository) ListSettlements(ctx context.Context, userID, ledgerID string) ([]store.Settlement, error) {
+	const op = "postgres.ledgers.list_settlements"
+
+	if err := requireLedgerMember(ctx, r.pool, userID, ledgerID); err != nil {
+		return nil, err
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+settlementColumns+`
+		FROM ledger_settlements
+		WHERE ledger_id = $1
+		ORDER BY settled_at DESC, id
+	`, ledgerID)
+	if err != nil {
+		return nil, errors.E(op, "listing settlements", err)
+	}
+	defer rows.Close()
+
+	settlements := make([]store.Settlement, 0)
+	for rows.Next() {
+		settlement, err := scanSettlement(rows)
+		if err != nil {
+			return nil, errors.E(op, "scanning settlement", err)
+		}
+		settlements = append(settlements, settlement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating settlements", err)
+	}
+	return settlements, nil
+}
+
+func (r *ledgerRepository) ListLedgerBalances(ctx context.Context, userID, ledgerID string) ([]store.LedgerBalance, error) {
+	const op = "postgres.ledgers.list_ledger_balances"
+
+	if err := requireLedgerMember(ctx, r.pool, userID, ledgerID); err != nil {
+		return nil, err
+	}
+	// Payers are owed each share; sharers owe it. A settlement from A to B
+	// reduces what A owes and what B is owed.
+	rows, err := r.pool.Query(ctx, `
+		SELECT user_id, currency, SUM(delta)
+		FROM (
+			SELECT e.paid_by AS user_id, e.currency, s.amount AS delta
+			FROM shared_expenses e
+			JOIN shared_expense_shares s ON s.expense_id = e.id
+			WHERE e.ledger_id = $1
+			UNION ALL
+			SELECT s.user_id, e.currency, -s.amount
+			FROM shared_expenses e
+			JOIN shared_expense_shares s ON s.expense_id = e.id
+			WHERE e.ledger_id = $1
+			UNION ALL
+			SELECT from_user_id, currency, amount
+			FROM ledger_settlements
+			WHERE ledger_id = $1
+			UNION ALL
+			SELECT to_user_id, currency, -amount
+			FROM ledger_settlements
+			WHERE ledger_id = $1
+		) deltas
+		GROUP BY user_id, currency
+		HAVING SUM(delta) <> 0
+		ORDER BY currency, user_id
+	`, ledgerID)
+	if err != nil {
+		return nil, errors.E(op, "computing balances", err)
+	}
+	defer rows.Close()
+
+	balances := make([]store.LedgerBalance, 0)
+	for rows.Next() {
+		var balance store.LedgerBalance
+		if err := rows.Scan(&balance.UserID, &balance.Currency, &balance.Amount); err != nil {
+			return nil, errors.E(op, "scanning balance", err)
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating balances", err)
+	}
+	return balances, nil
+}
+
+// querySharedExpenses reads expenses with the few transaction fields members
+// may see. where is a fixed condition on e with its arguments.
+func (r *ledgerRepository) querySharedExpenses(ctx context.Context, where string, args ...any) ([]store.SharedExpense, error) {
+	const op = "postgres.ledgers.query_shared_expenses"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT e.id, e.ledger_id, e.transaction_id, COALESCE(e.split_id::text, ''), e.paid_by,
+		       t.merchant_info, t.timestamp, e.amount, e.currency, e.created_at
+		FROM shared_expenses e
+		JOIN transactions t ON t.id = e.transaction_id
+		WHERE `+where+`
+		ORDER BY t.timestamp DESC, e.id
+	`, args...)
+	if err != nil {
+		return nil, errors.E(op, "listing shared expenses", err)
+	}
+	defer rows.Close()
+
+	expenses := make([]store.SharedExpense, 0)
+	idx := make(map[string]int)
+	for rows.Next() {
+		var e store.SharedExpense
+		if err := rows.Scan(
+			&e.ID, &e.LedgerID, &e.TransactionID, &e.SplitID, &e.PaidBy,
+			&e.MerchantInfo, &e.Timestamp, &e.Amount, &e.Currency, &e.CreatedAt,
+		); err != nil {
+			return nil, errors.E(op, "scanning shared expense", err)
+		}
+		e.Shares = []store.ExpenseShare{}
+		idx[e.ID] = len(expenses)
+		expenses = append(expenses, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating shared expenses", err)
+	}
+	rows.Close()
+	if len(expenses) == 0 {
+		return expenses, nil
+	}
+
+	ids := make([]string, 0, len(expenses))
+	for _, e := range expenses {
+		ids = append(ids, e.ID)
+	}
+	shareRows, err := r.pool.Query(ctx, `
+		SELECT expense_id, user_id, ratio, amount
+		FROM shared_expense_shares
+		WHERE expense_id = ANY($1::uuid[])
+		ORDER BY expense_id, user_id
+	`, ids)
+	if err != nil {
+		return nil, errors.E(op, "listing expense shares", err)
+	}
+	defer shareRows.Close()
+	for shareRows.Next() {
+		var expenseID string
+		var share store.ExpenseShare
+		if err := shareRows.Scan(&expenseID, &share.UserID, &share.Ratio, &share.Amount); err != nil {
+			return nil, errors.E(op, "scanning expense share", err)
+		}
+		if i, ok := idx[expenseID]; ok {
+			expenses[i].Shares = append(expenses[i].Shares, share)
+		}
+	}
+	if err := shareRows.Err(); err != nil {
+		return nil, errors.E(op, "iterating expense shares", err)
+	}
+	return expenses, nil
+}
+
+func (r *ledgerRepository) loadLedgerMembers(ctx context.Context, ledgers []store.SharedLedger) error {
+	const op = "postgres.ledgers.load_ledger_members"
+
+	if len(ledgers) == 0 {
+		return nil
+	}
+	ids := make([]string, len(ledgers))
+	idx := make(map[string]int, len(ledgers))
+	for i, l := range ledgers {
+		ids[i] = l.ID
+		idx[l.ID] = i
+		ledgers[i].Members = []store.SharedLedgerMember{}
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT m.ledger_id, u.id, u.display_name, u.email, m.joined_at
+		FROM shared_ledger_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.ledger_id = ANY($1::uuid[])
+		ORDER BY m.ledger_id, m.joined_at, u.display_name
+	`, ids)
+	if err != nil {
+		return errors.E(op, "fetching ledger members", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ledgerID string
+		var member store.SharedLedgerMember
+		if err := rows.Scan(&ledgerID, &member.UserID, &member.DisplayName, &member.Email, &member.JoinedAt); err != nil {
+			return errors.E(op, "scanning ledger member", err)
+		}
+		if i, ok := idx[ledgerID]; ok {
+			ledgers[i].Members = append(ledgers[i].Members, member)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.E(op, "iterating ledger members", err)
+	}
+	return nil
+}
+
+// requireLedgerMember reports NotFound for ledgers the user does not belong
+// to so membership never leaks which ledger IDs exist.
+func requireLedgerMember(ctx context.Context, db queryRower, userID, ledgerID string) error {
+	var member bool
+	if err := db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM shared_ledger_members WHERE ledger_id = $1 AND user_id = $2)`,
+		ledgerID, userID,
+	).Scan(&member); err != nil {
+		return errors.E("postgres.ledgers.require_ledger_member", "checking ledger membership", err)
+	}
+	if !member {
+		return errLedgerNotFound()
+	}
+	return nil
+}
+
+// inviteLedgerMembers invites the active users matching emails who are not
+// already members. Other emails are skipped without an error so callers
+// cannot tell which accounts exist.
+func inviteLedgerMembers(ctx context.Context, db execer, invitedBy, ledgerID string, emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	if _, err := db.Exec(ctx, `
+		INSERT INTO shared_ledger_invitations (ledger_id, user_id, invited_by)
+		SELECT $1::uuid, u.id, $2::uuid
+		FROM users u
+		WHERE lower(u.email) IN (SELECT lower(e) FROM unnest($3::text[]) AS e)
+		  AND u.disabled_at IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM shared_ledger_members m WHERE m.ledger_id = $1::uuid AND m.user_id = u.id
+		  )
+		ON CONFLICT (ledger_id, user_id) DO NOTHING
+	`, ledgerID, invitedBy, emails); err != nil {
+		return errors.E("postgres.ledgers.invite_ledger_members", "inserting invitations", err)
+	}
+	return nil
+}
+
+func errInvitationNotFound() error {
+	return errors.E("store.ledgers.invitation", errors.NotFound, errors.User("shared ledger invitation not found"))
+}
+
+func errLedgerNotFound() error {
+	return errors.E("store.ledgers.get", errors.NotFound, errors.User("shared ledger not found"))
+}
+
+func scanSharedLedger(row scanner) (store.SharedLedger, error) {
+	var ledger store.SharedLedger
+	if err := row.Scan(&ledger.ID, &ledger.Name, &ledger.CreatedBy, &ledger.CreatedAt); err != nil {
+		return store.SharedLedger{}, err
+	}
+	return ledger, nil
+}
+
+func scanSettlement(row scanner) (store.Settlement, error) {
+	var s store.Settlement
+	if err := row.Scan(
+		&s.ID, &s.LedgerID, &s.FromUserID, &s.ToUserID, &s.Amount, &s.Currency,
+		&s.Note, &s.SettledAt, &s.CreatedBy, &s.CreatedAt,
+	); err != nil {
+		return store.Settlement{}, err
+	}
+	return s, nil
+}
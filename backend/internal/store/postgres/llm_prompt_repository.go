@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const llmPromptVersionColumns = `id, workflow, purpose, version, definition, active, COALESCE(created_by::text, ''), created_at, activated_at`
+
+type llmPromptRepository struct {
+	pool *pgxpool.Pool
+}
+
+func newLLMPromptRepository(deps repositoryDependencies) *llmPromptRepository {
+	return &llmPromptRepository{pool: deps.pool}
+}
+
+func (r *llmPromptRepository) CreateLLMPromptVersion(
+	ctx context.Context,
+	input store.CreateLLMPromptVersionInput,
+) (store.LLMPromptVersion, error) {
+	const op = "postgres.llm_prompts.create_llm_prompt_version"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.LLMPromptVersion{}, errors.E(op, "beginning prompt version transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if input.Activate {
+		if err := deactivateLLMPromptVersions(ctx, tx, input.Workflow, input.Purpose); err != nil {
+			return store.LLMPromptVersion{}, errors.E(op, err)
+		}
+	}
+	version, err := scanLLMPromptVersion(tx.QueryRow(ctx, `
+		INSERT INTO llm_prompt_versions (workflow, purpose, version, definition, active, created_by, activated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::uuid, CASE WHEN $5 THEN now() END)
+		RETURNING `+llmPromptVersionColumns,
+		input.Workflow,
+		input.Purpose,
+		input.Version,
+		input.Definition,
+		input.Activate,
+		input.CreatedBy,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return store.LLMPromptVersion{}, errors.E(
+				op,
+				errors.Conflict,
+				errors.User("Prompt version "+strconv.Itoa(input.Version)+" already exists."),
+				"prompt version conflict",
+				err,
+			)
+		}
+		return store.LLMPromptVersion{}, errors.E(op, "creating prompt version", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return store.LLMPromptVersion{}, errors.E(op, "committing prompt version transaction", err)
+	}
+	return version, nil
+}
+
+func (r *llmPromptRepository) ListLLMPromptVersions(ctx context.Context, workflow, purpose string) ([]store.LLMPromptVersion, error) {
+	const op = "postgres.llm_prompts.list_llm_prompt_versions"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+llmPromptVersionColumns+`
+		FROM llm_prompt_versions
+		WHERE workflow = $1 AND purpose = $2
+		ORDER BY version DESC
+	`, workflow, purpose)
+	if err != nil {
+		return nil, errors.E(op, "listing prompt versions", err)
+	}
+	defer rows.Close()
+
+	versions := make([]store.LLMPromptVersion, 0)
+	for rows.Next() {
+		version, err := scanLLMPromptVersion(rows)
+		if err != nil {
+			return nil, errors.E(op, "scanning prompt version", err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating prompt versions", err)
+	}
+	return versions, nil
+}
+
+func (r *llmPromptRepository) GetActiveLLMPromptVersion(
+	ctx context.Context,
+	workflow, purpose string,
+) (store.LLMPromptVersion, bool, error) {
+	version, err := scanLLMPromptVersion(r.pool.QueryRow(ctx, `
+		SELECT `+llmPromptVersionColumns+`
+		FROM llm_prompt_versions
+		WHERE workflow = $1 AND purpose = $2 AND active
+	`, workflow, purpose))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.LLMPromptVersion{}, false, nil
+	}
+	if err != nil {
+		return store.LLMPromptVersion{}, false, errors.E("postgres.llm_prompts.get_active_llm_prompt_version", "getting active prompt version", err)
+	}
+	return version, true, nil
+}
+
+func (r *llmPromptRepository) ActivateLLMPromptVersion(
+	ctx context.Context,
+	workflow, purpose string,
+	version int,
+) (store.LLMPromptVersion, error) {
+	const op = "postgres.llm_prompts.activate_llm_prompt_version"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.LLMPromptVersion{}, errors.E(op, "beginning prompt activation transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := deactivateLLMPromptVersions(ctx, tx, workflow, purpose); err != nil {
+		return store.LLMPromptVersion{}, errors.E(op, err)
+	}
+	activated, err := scanLLMPromptVersion(tx.QueryRow(ctx, `
+		UPDATE llm_prompt_versions
+		SET active = true, activated_at = now()
+		WHERE workflow = $1 AND purpose = $2 AND version = $3
+		RETURNING `+llmPromptVersionColumns,
+		workflow, purpose, version,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.LLMPromptVersion{}, errors.E(
+			op,
+			errors.NotFound,
+			errors.User("Prompt version "+strconv.Itoa(version)+" was not found."),
+		)
+	}
+	if err != nil {
+		return store.LLMPromptVersion{}, errors.E(op, "activating prompt version", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return store.LLMPromptVersion{}, errors.E(op, "committing prompt activation transaction", err)
+	}
+	return activated, nil
+}
+
+func (r *llmPromptRepository) DeactivateLLMPromptVersions(ctx context.Context, workflow, purpose string) error {
+	return deactivateLLMPromptVersions(ctx, r.pool, workflow, purpose)
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func deactivateLLMPromptVersions(ctx context.Context, db execer, workflow, purpose string) error {
+	if _, err := db.Exec(ctx, `
+		UPDATE llm_prompt_versions
+		SET active = false
+		WHERE workflow = $1 AND purpose = $2 AND active
+	`, workflow, purpose); err != nil {
+		return errors.E("postgres.llm_prompts.deactivate_llm_prompt_versions", "deactivating prompt versions", err)
+	}
+	return nil
+}
+
+func scanLLMPromptVersion(row scanner) (store.LLMPromptVersion, error) {
+	var version store.LLMPromptVersion
+	if err := row.Scan(
+		&version.ID,
+		&version.Workflow,
+		&version.Purpose,
+		&version.Version,
+		&version.Definition,
+		&version.Active,
+		&version.CreatedBy,
+		&version.CreatedAt,
+		&version.ActivatedAt,
+	); err != nil {
+		return store.LLMPromptVersion{}, err
+	}
+	return version, nil
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const listLLMUsageSQL = `
+	SELECT date_trunc($1, created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS period_start,
+	       tenant_id::text, provider, model,
+	       count(*),
+	       count(*) FILTER (WHERE outcome <> 'ok'),
+	       COALESCE(sum(input_tokens), 0),
+	       COALESCE(sum(output_tokens), 0),
+	       COALESCE(sum(total_tokens), 0),
+	       COALESCE(avg(latency_ms), 0)::float8
+	FROM llm_usage
+	WHERE created_at >= $2
+	  AND created_at < $3
+	  AND ($4 = '' OR tenant_id = NULLIF($4, '')::uuid)
+	GROUP BY period_start, tenant_id, provider, model
+	ORDER BY period_start, tenant_id, provider, model
+`
+
+type llmUsageRepository struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+func newLLMUsageRepository(deps repositoryDependencies) *llmUsageRepository {
+	return &llmUsageRepository{pool: deps.pool, now: deps.now}
+}
+
+func (r *llmUsageRepository) RecordLLMUsage(ctx context.Context, tenant store.Tenant, record store.LLMUsageRecord) error {
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return err
+	}
+	createdAt := record.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = r.now()
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO llm_usage (
+			tenant_id, workflow, purpose, provider, model,
+			input_tokens, output_tokens, total_tokens, latency_ms, outcome, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`,
+		tenantID,
+		record.Workflow,
+		record.Purpose,
+		record.Provider,
+		record.Model,
+		max(record.InputTokens, 0),
+		max(record.OutputTokens, 0),
+		max(record.TotalTokens, 0),
+		max(record.Latency.Milliseconds(), 0),
+		record.Outcome,
+		createdAt,
+	)
+	if err != nil {
+		return errors.E("postgres.llm_usage.record_llm_usage", "recording llm usage", err)
+	}
+	return nil
+}
+
+func (r *llmUsageRepository) SumLLMUsageTokens(ctx context.Context, tenant store.Tenant, since time.Time) (int64, error) {
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = r.pool.QueryRow(ctx, `
+		SELECT COALESCE(sum(total_tokens), 0)
+		FROM llm_usage
+		WHERE tenant_id = $1
+		  AND created_at >= $2
+	`, tenantID, since).Scan(&total)
+	if err != nil {
+		return 0, errors.E("postgres.llm_usage.sum_llm_usage_tokens", "summing llm usage tokens", err)
+	}
+	return total, nil
+}
+
+func (r *llmUsageRepository) ListLLMUsage(ctx context.Context, filter store.LLMUsageFilter) ([]store.LLMUsageBucket, error) {
+	const op = "postgres.llm_usage.list_llm_usage"
+
+	interval := filter.Interval
+	switch interval {
+	case "":
+		interval = store.LLMUsageIntervalDay
+	case store.LLMUsageIntervalDay, store.LLMUsageIntervalMonth:
+	default:
+		return nil, errors.E(op, errors.InvalidInput, "interval must be day or month")
+	}
+	if filter.From.IsZero() || filter.To.IsZero() || !filter.From.Before(filter.To) {
+		return nil, errors.E(op, errors.InvalidInput, "usage range requires from before to")
+	}
+
+	rows, err := r.pool.Query(ctx, listLLMUsageSQL, string(interval), filter.From, filter.To, filter.TenantID)
+	if err != nil {
+		return nil, errors.E(op, "listing llm usage", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]store.LLMUsageBucket, 0)
+	for rows.Next() {
+		var bucket store.LLMUsageBucket
+		if err := rows.Scan(
+			&bucket.PeriodStart,
+			&bucket.TenantID,
+			&bucket.Provider,
+			&bucket.Model,
+			&bucket.Calls,
+			&bucket.FailedCalls,
+			&bucket.InputTokens,
+			&bucket.OutputTokens,
+			&bucket.TotalTokens,
+			&bucket.AverageLatency,
+		); err != nil {
+			return nil, errors.E(op, "scanning llm usage", err)
+		}
+		bucket.PeriodStart = bucket.PeriodStart.UTC()
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating llm usage", err)
+	}
+	return buckets, nil
+}
+
+func (r *llmUsageRepository) GetLLMUsageQuota(ctx context.Context, tenant store.Tenant) (store.LLMUsageQuota, error) {
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return store.LLMUsageQuota{}, err
+	}
+	quota := store.LLMUsageQuota{TenantID: tenantID}
+	var updatedAt time.Time
+	err = r.pool.QueryRow(ctx, `
+		SELECT daily_token_limit, monthly_token_limit, updated_at
+		FROM llm_usage_quotas
+		WHERE tenant_id = $1
+	`, tenantID).Scan(&quota.DailyTokenLimit, &quota.MonthlyTokenLimit, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return quota, nil
+	}
+	if err != nil {
+		return store.LLMUsageQuota{}, errors.E("postgres.llm_usage.get_llm_usage_quota", "getting llm usage quota", err)
+	}
+	quota.UpdatedAt = &updatedAt
+	return quota, nil
+}
+
+func (r *llmUsageRepository) SetLLMUsageQuota(ctx context.Context, tenant store.Tenant, quota store.LLMUsageQuota) (store.LLMUsageQuota, error) {
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return store.LLMUsageQuota{}, err
+	}
+	if quota.DailyTokenLimit < 0 || quota.MonthlyTokenLimit < 0 {
+		return store.LLMUsageQuota{}, errors.E(errors.InvalidInput, "token limits must not be negative")
+	}
+	saved := store.LLMUsageQuota{TenantID: tenantID}
+	var updatedAt time.Time
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO llm_usage_quotas (tenant_id, daily_token_limit, monthly_token_limit)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET daily_token_limit = EXCLUDED.daily_token_limit,
+		    monthly_token_limit = EXCLUDED.monthly_token_limit,
+		    updated_at = now()
+		RETURNING daily_token_limit, monthly_token_limit, updated_at
+	`, tenantID, quota.DailyTokenLimit, quota.MonthlyTokenLimit).Scan(&saved.DailyTokenLimit, &saved.MonthlyTokenLimit, &updatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return store.LLMUsageQuota{}, errors.E("postgres.llm_usage.set_llm_usage_quota", errors.NotFound, "tenant not found", err)
+		}
+		return store.LLMUsageQuota{}, errors.E("postgres.llm_usage.set_llm_usage_quota", "setting llm usage quota", err)
+	}
+	saved.UpdatedAt = &updatedAt
+	return saved, nil
+}
@@ -0,0 +1,287 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type mfaRepository struct {
+	pool      *pgxpool.Pool
+	secretBox *auth.SecretBox
+}
+
+func newMFARepository(deps repositoryDependencies) *mfaRepository {
+	return &mfaRepository{pool: deps.pool, secretBox: deps.secretBox}
+}
+
+// totpAssociatedData binds a TOTP secret ciphertext to its user.
+func totpAssociatedData(userID string) auth.SecretAssociatedData {
+	return auth.SecretAssociatedData{TenantID: userID, Scope: "user", Name: userID, Kind: "totp_secret"}
+}
+
+func (r *mfaRepository) GetMFAFactors(ctx context.Context, userID string) (store.MFAFactors, error) {
+	var factors store.MFAFactors
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM totp_factors WHERE user_id = $1 AND confirmed_at IS NOT NULL),
+		       (SELECT COUNT(*) FROM passkeys WHERE user_id = $1),
+		       (SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL)
+	`, userID).Scan(&factors.TOTP, &factors.Passkeys, &factors.RecoveryCodes)
+	if err != nil {
+		return store.MFAFactors{}, errors.E("postgres.mfa.get_factors", "getting MFA factors", err)
+	}
+	return factors, nil
+}
+
+func (r *mfaRepository) GetTOTPFactor(ctx context.Context, userID string) (*store.TOTPFactor, error) {
+	const op = "postgres.mfa.get_totp_factor"
+	if r.secretBox == nil {
+		return nil, errors.E(errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	var factor store.TOTPFactor
+	var ciphertext []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT secret_ciphertext, confirmed_at, last_used_step, created_at
+		FROM totp_factors
+		WHERE user_id = $1
+	`, userID).Scan(&ciphertext, &factor.ConfirmedAt, &factor.LastUsedStep, &factor.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.E("store.mfa.get_totp_factor", errors.NotFound, errors.User("TOTP is not set up"))
+		}
+		return nil, errors.E(op, "getting TOTP factor", err)
+	}
+	factor.Secret, err = r.secretBox.Open(ciphertext, totpAssociatedData(userID))
+	if err != nil {
+		return nil, errors.E(op, "decrypting TOTP secret", err)
+	}
+	return &factor, nil
+}
+
+func (r *mfaRepository) SetPendingTOTP(ctx context.Context, userID string, secret []byte) error {
+	const op = "postgres.mfa.set_pending_totp"
+	if r.secretBox == nil {
+		return errors.E(errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	ciphertext, err := r.secretBox.Seal(secret, totpAssociatedData(userID))
+	if err != nil {
+		return errors.E(op, "encrypting TOTP secret", err)
+	}
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO totp_factors (user_id, secret_ciphertext)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_ciphertext = EXCLUDED.secret_ciphertext, last_used_step = 0, created_at = NOW()
+		WHERE totp_factors.confirmed_at IS NULL
+	`, userID, ciphertext)
+	if err != nil {
+		return errors.E(op, "storing pending TOTP secret", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.mfa.set_pending_totp", errors.Conflict, errors.User("TOTP is already enabled"))
+	}
+	return nil
+}
+
+func (r *mfaRepository) ConfirmTOTP(ctx context.Context, userID string, step int64) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE totp_factors
+		SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return errors.E("postgres.mfa.confirm_totp", "confirming TOTP", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.mfa.confirm_totp", errors.NotFound, errors.User("no TOTP setup in progress"))
+	}
+	return nil
+}
+
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	// The comparison in the WHERE clause makes concurrent submissions of the
+	// same code race for a single row update.
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE totp_factors
+		SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return errors.E("postgres.mfa.use_totp_step", "recording TOTP use", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.mfa.use_totp_step", errors.Conflict, errors.User("code already used"))
+	}
+	return nil
+}
+
+func (r *mfaRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM totp_factors WHERE user_id = $1`, userID)
+	if err != nil {
+		return errors.E("postgres.mfa.delete_totp", "deleting TOTP factor", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.mfa.delete_totp", errors.NotFound, errors.User("TOTP is not set up"))
+	}
+	return nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	const op = "postgres.mfa.replace_recovery_codes"
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return errors.E(op, "beginning recovery code transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return errors.E(op, "deleting recovery codes", err)
+	}
+	if len(codeHashes) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO recovery_codes (user_id, code_hash)
+			SELECT $1, hash FROM unnest($2::text[]) AS hash
+		`, userID, codeHashes); err != nil {
+			return errors.E(op, "inserting recovery codes", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return errors.E(op, "committing recovery codes", err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return errors.E("postgres.mfa.use_recovery_code", "using recovery code", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.mfa.use_recovery_code", errors.NotFound, errors.User("invalid recovery code"))
+	}
+	return nil
+}
+
+const passkeyColumns = `id, user_id, name, credential_id, public_key, algorithm, sign_count, transports, created_at, last_used_at`
+
+func scanPasskey(row scanner) (*store.Passkey, error) {
+	var passkey store.Passkey
+	var signCount int64
+	if err := row.Scan(
+		&passkey.ID,
+		&passkey.UserID,
+		&passkey.Name,
+		&passkey.CredentialID,
+		&passkey.PublicKey,
+		&passkey.Algorithm,
+		&signCount,
+		&passkey.Transports,
+		&passkey.CreatedAt,
+		&passkey.LastUsedAt,
+	); err != nil {
+		return nil, err
+	}
+	passkey.SignCount = uint32(signCount) //nolint:gosec // Stored from a uint32.
+	return &passkey, nil
+}
+
+func (r *mfaRepository) CreatePasskey(ctx context.Context, input store.CreatePasskeyInput) (*store.Passkey, error) {
+	transports := input.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+	passkey, err := scanPasskey(r.pool.QueryRow(ctx, `
+		INSERT INTO passkeys (user_id, name, credential_id, public_key, algorithm, sign_count, transports)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+passkeyColumns,
+		input.UserID, input.Name, input.CredentialID, input.PublicKey, input.Algorithm, int64(input.SignCount), transports,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return nil, errors.E("store.mfa.create_passkey", errors.Conflict, errors.User("passkey already registered"), err)
+		}
+		return nil, errors.E("postgres.mfa.create_passkey", "creating passkey", err)
+	}
+	return passkey, nil
+}
+
+func (r *mfaRepository) ListPasskeys(ctx context.Context, userID string) ([]store.Passkey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+passkeyColumns+` FROM passkeys WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, errors.E("postgres.mfa.list_passkeys", "listing passkeys", err)
+	}
+	defer rows.Close()
+	passkeys := []store.Passkey{}
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, errors.E("postgres.mfa.list_passkeys", "scanning passkey", err)
+		}
+		passkeys = append(passkeys, *passkey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E("postgres.mfa.list_passkeys", "iterating passkeys", err)
+	}
+	return passkeys, nil
+}
+
+func (r *mfaRepository) UsePasskey(ctx context.Context, id string, signCount uint32) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE passkeys SET sign_count = $2, last_used_at = NOW() WHERE id = $1
+	`, id, int64(signCount))
+	if err != nil {
+		return errors.E("postgres.mfa.use_passkey", "recording passkey use", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.mfa.use_passkey", errors.NotFound, errors.User("passkey not found"))
+	}
+	return nil
+}
+
+func (r *mfaRepository) DeletePasskey(ctx context.Context, userID, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM passkeys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return errors.E("postgres.mfa.delete_passkey", "deleting passkey", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.mfa.delete_passkey", errors.NotFound, errors.User("passkey not found"))
+	}
+	return nil
+}
+
+func (r *mfaRepository) GetMFASettings(ctx context.Context) (store.MFASettings, error) {
+	var settings store.MFASettings
+	err := r.pool.QueryRow(ctx, `SELECT required, updated_at FROM mfa_settings WHERE id = TRUE`).Scan(&settings.Required, &settings.UpdatedAt)
+	if err != nil {
+		return store.MFASettings{}, errors.E("postgres.mfa.get_settings", "getting MFA settings", err)
+	}
+	return settings, nil
+}
+
+func (r *mfaRepository) PatchMFASettings(ctx context.Context, patch store.MFASettingsPatch) (store.MFASettings, error) {
+	var settings store.MFASettings
+	err := r.pool.QueryRow(ctx, `
+		UPDATE mfa_settings
+		SET required = COALESCE($1, required),
+		    updated_at = NOW()
+		WHERE id = TRUE
+		RETURNING required, updated_at
+	`, patch.Required).Scan(&settings.Required, &settings.UpdatedAt)
+	if err != nil {
+		return store.MFASettings{}, errors.E("postgres.mfa.patch_settings", "patching MFA settings", err)
+	}
+	return settings, nil
+}
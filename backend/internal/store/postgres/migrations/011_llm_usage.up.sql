@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workflow text NOT NULL DEFAULT '',
+    purpose text NOT NULL DEFAULT '',
+    provider text NOT NULL,
+    model text NOT NULL DEFAULT '',
+    input_tokens integer NOT NULL DEFAULT 0 CHECK (input_tokens >= 0),
+    output_tokens integer NOT NULL DEFAULT 0 CHECK (output_tokens >= 0),
+    total_tokens integer NOT NULL DEFAULT 0 CHECK (total_tokens >= 0),
+    latency_ms bigint NOT NULL DEFAULT 0 CHECK (latency_ms >= 0),
+    outcome text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS llm_usage_tenant_created_at_idx
+    ON llm_usage (tenant_id, created_at);
+
+CREATE INDEX IF NOT EXISTS llm_usage_created_at_idx
+    ON llm_usage (created_at);
+
+CREATE TABLE IF NOT EXISTS llm_usage_quotas (
+    tenant_id uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    daily_token_limit bigint NOT NULL DEFAULT 0 CHECK (daily_token_limit >= 0),
+    monthly_token_limit bigint NOT NULL DEFAULT 0 CHECK (monthly_token_limit >= 0),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
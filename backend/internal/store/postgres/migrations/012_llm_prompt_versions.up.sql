@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS llm_prompt_versions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow text NOT NULL,
+    purpose text NOT NULL,
+    version integer NOT NULL CHECK (version > 0),
+    definition jsonb NOT NULL,
+    active boolean NOT NULL DEFAULT false,
+    created_by uuid REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    activated_at timestamptz,
+    UNIQUE (workflow, purpose, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS llm_prompt_versions_active_idx
+    ON llm_prompt_versions (workflow, purpose)
+    WHERE active;
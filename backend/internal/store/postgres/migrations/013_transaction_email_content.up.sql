@@ -0,0 +1,22 @@
+-- Plain-text copy of the source email for each transaction so search can match
+-- details that never reach the extracted fields (order IDs, cities, card hints).
+CREATE TABLE IF NOT EXISTS transaction_email_content (
+    transaction_id uuid PRIMARY KEY REFERENCES transactions(id) ON DELETE CASCADE,
+    subject text NOT NULL DEFAULT '',
+    body text NOT NULL DEFAULT '',
+    search tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', subject), 'A') ||
+        setweight(to_tsvector('english', body), 'B')
+    ) STORED,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS transaction_email_content_search_idx
+    ON transaction_email_content USING GIN (search);
+
+CREATE INDEX IF NOT EXISTS transaction_email_content_subject_trgm_idx
+    ON transaction_email_content USING GIN (subject gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS transaction_email_content_body_trgm_idx
+    ON transaction_email_content USING GIN (body gin_trgm_ops);
@@ -0,0 +1,15 @@
+-- Allocations of one transaction across categories, buckets, labels and
+-- people. When a transaction has splits, analytics read these rows instead of
+-- the parent's category, bucket and labels; amounts always sum to the parent.
+CREATE TABLE IF NOT EXISTS transaction_splits (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id uuid NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    amount NUMERIC(19,4) NOT NULL CHECK (amount > 0),
+    category text,
+    bucket text,
+    labels text[] NOT NULL DEFAULT '{}',
+    counterparty text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (transaction_id, position)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ledger_settlements;
+DROP TABLE IF EXISTS shared_expense_shares;
+DROP TABLE IF EXISTS shared_expenses;
+DROP TABLE IF EXISTS shared_ledger_members;
+DROP TABLE IF EXISTS shared_ledgers;
@@ -0,0 +1,63 @@
+-- Ledgers let instance users share costs. Members see the merchant, time and
+-- amount of each shared expense, never the rest of another member's data.
+CREATE TABLE IF NOT EXISTS shared_ledgers (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    created_by uuid REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS shared_ledger_members (
+    ledger_id uuid NOT NULL REFERENCES shared_ledgers(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (ledger_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS shared_ledger_members_user_idx
+    ON shared_ledger_members (user_id);
+
+-- A transaction, or one of its splits, paid by paid_by and shared with other
+-- members. amount and currency are copied so balances stay fixed if the
+-- transaction is edited later.
+CREATE TABLE IF NOT EXISTS shared_expenses (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    ledger_id uuid NOT NULL REFERENCES shared_ledgers(id) ON DELETE CASCADE,
+    transaction_id uuid NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    split_id uuid REFERENCES transaction_splits(id) ON DELETE CASCADE,
+    paid_by uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(19,4) NOT NULL,
+    currency text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shared_expenses_allocation_idx
+    ON shared_expenses (transaction_id, COALESCE(split_id, '00000000-0000-0000-0000-000000000000'::uuid));
+
+CREATE INDEX IF NOT EXISTS shared_expenses_ledger_idx
+    ON shared_expenses (ledger_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS shared_expense_shares (
+    expense_id uuid NOT NULL REFERENCES shared_expenses(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ratio NUMERIC(9,8) NOT NULL CHECK (ratio > 0 AND ratio <= 1),
+    amount NUMERIC(19,4) NOT NULL,
+    PRIMARY KEY (expense_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS ledger_settlements (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    ledger_id uuid NOT NULL REFERENCES shared_ledgers(id) ON DELETE CASCADE,
+    from_user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(19,4) NOT NULL CHECK (amount > 0),
+    currency text NOT NULL,
+    note text NOT NULL DEFAULT '',
+    settled_at timestamptz NOT NULL,
+    created_by uuid REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_settlements_ledger_idx
+    ON ledger_settlements (ledger_id, settled_at DESC);
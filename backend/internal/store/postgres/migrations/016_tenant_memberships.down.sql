@@ -0,0 +1,27 @@
+-- Shared tenants have no owning user to fall back to, so their data goes.
+DELETE FROM tenants WHERE id NOT IN (SELECT id FROM users);
+
+DO $$
+DECLARE
+    fk record;
+BEGIN
+    FOR fk IN
+        SELECT c.conname, c.conrelid::regclass AS table_name
+        FROM pg_constraint c
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = ANY (c.conkey)
+        WHERE c.contype = 'f'
+          AND c.confrelid = 'tenants'::regclass
+          AND c.conrelid <> 'tenant_memberships'::regclass
+          AND a.attname = 'tenant_id'
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', fk.table_name, fk.conname);
+        EXECUTE format(
+            'ALTER TABLE %s ADD CONSTRAINT %I FOREIGN KEY (tenant_id) REFERENCES users(id) ON DELETE CASCADE',
+            fk.table_name, fk.conname
+        );
+    END LOOP;
+END $$;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS active_tenant_id;
+DROP TABLE IF EXISTS tenant_memberships;
+DROP TABLE IF EXISTS tenants;
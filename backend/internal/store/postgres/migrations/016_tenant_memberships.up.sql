@@ -0,0 +1,50 @@
+-- Tenants are decoupled from users. Every existing account keeps its data in
+-- a personal tenant with the same id, owned by that account.
+CREATE TABLE IF NOT EXISTS tenants (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO tenants (id, name, created_at)
+SELECT id, display_name, created_at FROM users
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS tenant_memberships (
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS tenant_memberships_user_idx
+    ON tenant_memberships (user_id);
+
+INSERT INTO tenant_memberships (tenant_id, user_id, role)
+SELECT id, id, 'owner' FROM users
+ON CONFLICT (tenant_id, user_id) DO NOTHING;
+
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS active_tenant_id uuid REFERENCES tenants(id) ON DELETE SET NULL;
+
+-- Tenant-owned rows now reference tenants instead of users.
+DO $$
+DECLARE
+    fk record;
+BEGIN
+    FOR fk IN
+        SELECT c.conname, c.conrelid::regclass AS table_name
+        FROM pg_constraint c
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = ANY (c.conkey)
+        WHERE c.contype = 'f'
+          AND c.confrelid = 'users'::regclass
+          AND a.attname = 'tenant_id'
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', fk.table_name, fk.conname);
+        EXECUTE format(
+            'ALTER TABLE %s ADD CONSTRAINT %I FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE',
+            fk.table_name, fk.conname
+        );
+    END LOOP;
+END $$;
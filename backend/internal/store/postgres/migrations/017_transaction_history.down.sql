@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS log_transaction_label_changes ON transaction_labels;
+DROP TRIGGER IF EXISTS log_transaction_updates ON transactions;
+DROP FUNCTION IF EXISTS log_transaction_label_changes();
+DROP FUNCTION IF EXISTS log_transaction_updates();
+DROP FUNCTION IF EXISTS log_transaction_change(uuid, uuid, text, text, text);
+DROP TABLE IF EXISTS transaction_changes;
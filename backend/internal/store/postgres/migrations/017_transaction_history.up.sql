@@ -0,0 +1,100 @@
+-- Append-only log of transaction field and label changes. Writers tag each
+-- database transaction with a cause, an actor and a batch id through
+-- set_config('expensor.change_*', ..., true); the triggers below read them so
+-- every write path is covered, including mapping and ingestion updates.
+CREATE TABLE IF NOT EXISTS transaction_changes (
+    id bigserial PRIMARY KEY,
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    transaction_id uuid NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    batch_id uuid NOT NULL,
+    field text NOT NULL,
+    old_value text,
+    new_value text,
+    cause text NOT NULL,
+    -- Not a foreign key: history outlives the accounts that made it.
+    actor_id uuid,
+    changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS transaction_changes_transaction_idx
+    ON transaction_changes (tenant_id, transaction_id, id DESC);
+
+CREATE INDEX IF NOT EXISTS transaction_changes_batch_idx
+    ON transaction_changes (tenant_id, batch_id);
+
+CREATE OR REPLACE FUNCTION log_transaction_change(
+    p_tenant_id uuid, p_transaction_id uuid, p_field text, p_old text, p_new text
+) RETURNS void AS $$
+DECLARE
+    batch text := current_setting('expensor.change_batch', true);
+BEGIN
+    -- Writes that did not open a batch still get one per database transaction.
+    IF batch IS NULL OR batch = '' THEN
+        batch := gen_random_uuid()::text;
+        PERFORM set_config('expensor.change_batch', batch, true);
+    END IF;
+    INSERT INTO transaction_changes (
+        tenant_id, transaction_id, batch_id, field, old_value, new_value, cause, actor_id
+    ) VALUES (
+        p_tenant_id, p_transaction_id, batch::uuid, p_field, p_old, p_new,
+        COALESCE(NULLIF(current_setting('expensor.change_cause', true), ''), 'system'),
+        NULLIF(current_setting('expensor.change_actor', true), '')::uuid
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION log_transaction_updates()
+RETURNS TRIGGER AS $$
+DECLARE
+    old_row jsonb := to_jsonb(OLD);
+    new_row jsonb := to_jsonb(NEW);
+    field text;
+BEGIN
+    IF NEW.tenant_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+    FOREACH field IN ARRAY ARRAY[
+        'description', 'category', 'bucket', 'muted', 'muted_by_merchant', 'mute_reason',
+        'amount', 'currency', 'timestamp', 'merchant_info'
+    ] LOOP
+        IF old_row -> field IS DISTINCT FROM new_row -> field THEN
+            PERFORM log_transaction_change(NEW.tenant_id, NEW.id, field, old_row ->> field, new_row ->> field);
+        END IF;
+    END LOOP;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS log_transaction_updates ON transactions;
+CREATE TRIGGER log_transaction_updates
+    AFTER UPDATE ON transactions
+    FOR EACH ROW
+    EXECUTE FUNCTION log_transaction_updates();
+
+CREATE OR REPLACE FUNCTION log_transaction_label_changes()
+RETURNS TRIGGER AS $$
+DECLARE
+    txn_tenant uuid;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT tenant_id INTO txn_tenant FROM transactions WHERE id = NEW.transaction_id;
+        IF txn_tenant IS NOT NULL THEN
+            PERFORM log_transaction_change(txn_tenant, NEW.transaction_id, 'label', NULL, NEW.label);
+        END IF;
+    ELSE
+        -- Labels removed by a transaction delete cascade find no parent row
+        -- and are not logged.
+        SELECT tenant_id INTO txn_tenant FROM transactions WHERE id = OLD.transaction_id;
+        IF txn_tenant IS NOT NULL THEN
+            PERFORM log_transaction_change(txn_tenant, OLD.transaction_id, 'label', OLD.label, NULL);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS log_transaction_label_changes ON transaction_labels;
+CREATE TRIGGER log_transaction_label_changes
+    AFTER INSERT OR DELETE ON transaction_labels
+    FOR EACH ROW
+    EXECUTE FUNCTION log_transaction_label_changes();
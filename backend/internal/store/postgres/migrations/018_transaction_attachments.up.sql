@@ -0,0 +1,23 @@
+-- Files kept with a transaction, such as invoices for reimbursement. Only the
+-- metadata lives here; the encrypted content is in the blob store under
+-- blob_key.
+CREATE TABLE IF NOT EXISTS transaction_attachments (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    transaction_id uuid NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size_bytes bigint NOT NULL,
+    sha256 text NOT NULL,
+    source text NOT NULL CHECK (source IN ('upload', 'email')),
+    blob_key text NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS transaction_attachments_transaction_idx
+    ON transaction_attachments (tenant_id, transaction_id, created_at);
+
+-- Re-extracting an email must not attach its PDFs a second time.
+CREATE UNIQUE INDEX IF NOT EXISTS transaction_attachments_email_dedup_idx
+    ON transaction_attachments (transaction_id, sha256)
+    WHERE source = 'email';
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS backup_settings (
+    id boolean PRIMARY KEY DEFAULT true,
+    enabled boolean NOT NULL DEFAULT false,
+    interval_hours integer NOT NULL DEFAULT 24 CHECK (interval_hours >= 1 AND interval_hours <= 8760),
+    keep_last integer NOT NULL DEFAULT 7 CHECK (keep_last >= 1 AND keep_last <= 1000),
+    keep_days integer NOT NULL DEFAULT 30 CHECK (keep_days >= 0 AND keep_days <= 3650),
+    last_started_at timestamptz,
+    last_finished_at timestamptz,
+    last_backup text NOT NULL DEFAULT '',
+    last_error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT backup_settings_singleton CHECK (id)
+);
+
+INSERT INTO backup_settings (id)
+VALUES (true)
+ON CONFLICT (id) DO NOTHING;
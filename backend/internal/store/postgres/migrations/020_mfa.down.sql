@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS mfa_settings;
+DROP TABLE IF EXISTS passkeys;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_factors;
+ALTER TABLE sessions DROP COLUMN IF EXISTS mfa_state;
@@ -0,0 +1,45 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS mfa_state TEXT NOT NULL DEFAULT 'complete'
+    CHECK (mfa_state IN ('complete', 'pending', 'enrollment'));
+
+CREATE TABLE IF NOT EXISTS totp_factors (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_ciphertext BYTEA NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    used_at TIMESTAMPTZ,
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS passkeys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    credential_id BYTEA NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL,
+    algorithm INTEGER NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT mfa_settings_singleton CHECK (id)
+);
+
+INSERT INTO mfa_settings (id)
+VALUES (TRUE)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,5 @@
+-- Tokens issued before scopes existed keep the full access they were created with.
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT ARRAY[
+    'transactions:read', 'transactions:write', 'stats:read', 'rules:read', 'rules:write',
+    'readers:read', 'readers:write', 'settings:read', 'settings:write', 'account', 'admin'
+]::TEXT[];
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audit_settings;
+DROP TABLE IF EXISTS audit_events;
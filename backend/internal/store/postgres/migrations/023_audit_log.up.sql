@@ -0,0 +1,36 @@
+-- Events keep no foreign keys so they outlive the users and tenants they
+-- mention.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    occurred_at timestamptz NOT NULL DEFAULT now(),
+    actor_user_id uuid,
+    tenant_id uuid,
+    auth_method text NOT NULL DEFAULT '',
+    action text NOT NULL,
+    target_type text NOT NULL DEFAULT '',
+    target_id text NOT NULL DEFAULT '',
+    request_id text NOT NULL DEFAULT '',
+    ip_address text NOT NULL DEFAULT '',
+    outcome text NOT NULL CHECK (outcome IN ('success', 'failure', 'denied')),
+    detail text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_occurred_at_idx
+    ON audit_events (occurred_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS audit_events_actor_occurred_at_idx
+    ON audit_events (actor_user_id, occurred_at DESC);
+
+CREATE INDEX IF NOT EXISTS audit_events_tenant_occurred_at_idx
+    ON audit_events (tenant_id, occurred_at DESC);
+
+CREATE TABLE IF NOT EXISTS audit_settings (
+    id boolean PRIMARY KEY DEFAULT true,
+    retention_days integer NOT NULL DEFAULT 365 CHECK (retention_days >= 0 AND retention_days <= 3650),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT audit_settings_singleton CHECK (id)
+);
+
+INSERT INTO audit_settings (id)
+VALUES (true)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    url text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    events text[] NOT NULL,
+    enabled boolean NOT NULL DEFAULT true,
+    -- secret_ciphertext signs deliveries; it is sealed with the instance
+    -- secret key.
+    secret_ciphertext bytea NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_tenant_idx ON webhooks (tenant_id);
+
+-- Each delivery is one event queued for one webhook. Pending rows are the
+-- retry queue; delivered and failed rows are the delivery log.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id uuid NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_attempt_at timestamptz,
+    response_status integer,
+    last_error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_created_at_idx
+    ON webhook_deliveries (webhook_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_completed_at_idx
+    ON webhook_deliveries (completed_at)
+    WHERE status <> 'pending';
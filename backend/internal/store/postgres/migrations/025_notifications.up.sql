@@ -0,0 +1,58 @@
+-- Preferences and channels belong to a user within a tenant, since a user
+-- can be notified about every tenant they are a member of.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    weekly_digest boolean NOT NULL DEFAULT false,
+    -- digest_weekday counts from 0 for Sunday; the digest is sent at
+    -- digest_hour on that day in the tenant's timezone.
+    digest_weekday smallint NOT NULL DEFAULT 1 CHECK (digest_weekday BETWEEN 0 AND 6),
+    digest_hour smallint NOT NULL DEFAULT 9 CHECK (digest_hour BETWEEN 0 AND 23),
+    last_digest_at timestamptz,
+    large_transaction_alerts boolean NOT NULL DEFAULT false,
+    large_transaction_threshold numeric(19, 4) NOT NULL DEFAULT 10000 CHECK (large_transaction_threshold > 0),
+    scan_alerts boolean NOT NULL DEFAULT false,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type text NOT NULL CHECK (type IN ('email', 'ntfy', 'gotify', 'telegram', 'webhook')),
+    name text NOT NULL DEFAULT '',
+    target text NOT NULL,
+    -- token_ciphertext is sealed with the instance secret key.
+    token_ciphertext bytea,
+    enabled boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notification_channels_tenant_user_idx
+    ON notification_channels (tenant_id, user_id);
+
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id uuid NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_attempt_at timestamptz,
+    last_error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS notification_deliveries_due_idx
+    ON notification_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS notification_deliveries_completed_at_idx
+    ON notification_deliveries (completed_at)
+    WHERE status <> 'pending';
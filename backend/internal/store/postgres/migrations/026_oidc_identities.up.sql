@@ -0,0 +1,11 @@
+-- An account is linked to the single sign-on identity that first signed in
+-- with its email, and is only matched on that identity afterwards, so a
+-- provider account that later claims the same email cannot take it over.
+CREATE TABLE IF NOT EXISTS oidc_identities (
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (issuer, subject),
+    UNIQUE (issuer, user_id)
+);
@@ -0,0 +1,12 @@
+-- Only a ledger's owner can invite, and a user joins a ledger only by
+-- accepting an invitation, so nobody is added to a ledger without consent.
+CREATE TABLE IF NOT EXISTS shared_ledger_invitations (
+    ledger_id uuid NOT NULL REFERENCES shared_ledgers(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invited_by uuid REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (ledger_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS shared_ledger_invitations_user_idx
+    ON shared_ledger_invitations (user_id);
@@ -0,0 +1,13 @@
+-- Owners invite users to a tenant, and a user joins only by accepting, so
+-- nobody gains access to (or is exposed to) a tenant without consent.
+CREATE TABLE IF NOT EXISTS tenant_invitations (
+    tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    invited_by uuid REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS tenant_invitations_user_idx
+    ON tenant_invitations (user_id);
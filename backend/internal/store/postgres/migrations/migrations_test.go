@@ -110,8 +110,8 @@ func TestRunUsesSchemaMigrationsInExpensor(t *testing.T) {
 	if dirty {
 		t.Fatal("schema_migrations marked dirty after migration run")
 	}
-	if version != 10 {
-		t.Fatalf("schema_migrations version = %d, want 10", version)
+	if version != 28 {
+		t.Fatalf("schema_migrations version = %d, want 28", version)
 	}
 }
 
@@ -0,0 +1,730 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const notificationChannelColumns = `
+	c.id::text, c.tenant_id::text, c.user_id::text, c.type, c.name, c.target,
+	c.token_ciphertext IS NOT NULL, c.enabled, c.created_at, c.updated_at
+`
+
+const notificationPreferenceColumns = `
+	weekly_digest, digest_weekday, digest_hour, large_transaction_alerts,
+	large_transaction_threshold::float8, scan_alerts, updated_at
+`
+
+// notificationRecipients joins the preferences of active members of a
+// tenant, as p, to their enabled channels, as c.
+const notificationRecipients = `
+	FROM notification_preferences p
+	JOIN tenant_memberships m ON m.tenant_id = p.tenant_id AND m.user_id = p.user_id
+	JOIN users u ON u.id = p.user_id AND u.disabled_at IS NULL
+	JOIN notification_channels c ON c.tenant_id = p.tenant_id AND c.user_id = p.user_id AND c.enabled
+`
+
+// telegramChatID matches a numeric chat ID or a public @channel name.
+var telegramChatID = regexp.MustCompile(`^(-?\d+|@[A-Za-z][A-Za-z0-9_]{3,})$`)
+
+type notificationRepository struct {
+	pool      *pgxpool.Pool
+	logger    *slog.Logger
+	now       func() time.Time
+	secretBox *auth.SecretBox
+}
+
+func newNotificationRepository(deps repositoryDependencies) *notificationRepository {
+	return &notificationRepository{pool: deps.pool, logger: deps.logger, now: deps.now, secretBox: deps.secretBox}
+}
+
+func notificationChannelAssociatedData(tenantID, channelID string) auth.SecretAssociatedData {
+	return auth.SecretAssociatedData{TenantID: tenantID, Scope: "notification_channel", Name: channelID, Kind: "token"}
+}
+
+func (r *notificationRepository) GetNotificationPreferences(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+) (*store.NotificationPreferences, error) {
+	prefs, err := scanNotificationPreferences(r.pool.QueryRow(ctx,
+		`SELECT `+notificationPreferenceColumns+` FROM notification_preferences WHERE tenant_id = $1 AND user_id = $2`,
+		tenant.ID, userID))
+	if errorsIsNoRows(err) {
+		defaults := store.DefaultNotificationPreferences()
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, errors.E("postgres.notifications.get_preferences", "getting notification preferences", err)
+	}
+	return &prefs, nil
+}
+
+func (r *notificationRepository) UpdateNotificationPreferences(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	patch store.NotificationPreferencesPatch,
+) (*store.NotificationPreferences, error) {
+	const op = "postgres.notifications.update_preferences"
+
+	prefs, err := r.GetNotificationPreferences(ctx, tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	if patch.WeeklyDigest != nil {
+		prefs.WeeklyDigest = *patch.WeeklyDigest
+	}
+	if patch.DigestWeekday != nil {
+		prefs.DigestWeekday = *patch.DigestWeekday
+	}
+	if patch.DigestHour != nil {
+		prefs.DigestHour = *patch.DigestHour
+	}
+	if patch.LargeTransactionAlerts != nil {
+		prefs.LargeTransactionAlerts = *patch.LargeTransactionAlerts
+	}
+	if patch.LargeTransactionThreshold != nil {
+		prefs.LargeTransactionThreshold = *patch.LargeTransactionThreshold
+	}
+	if patch.ScanAlerts != nil {
+		prefs.ScanAlerts = *patch.ScanAlerts
+	}
+	switch {
+	case prefs.DigestWeekday < time.Sunday || prefs.DigestWeekday > time.Saturday:
+		return nil, errors.E("store.notifications.update_preferences", errors.InvalidInput, errors.User("digest weekday must be 0-6"))
+	case prefs.DigestHour < 0 || prefs.DigestHour > 23:
+		return nil, errors.E("store.notifications.update_preferences", errors.InvalidInput, errors.User("digest hour must be 0-23"))
+	case prefs.LargeTransactionThreshold <= 0:
+		return nil, errors.E("store.notifications.update_preferences", errors.InvalidInput,
+			errors.User("large transaction threshold must be positive"))
+	}
+
+	// Turning the digest on starts its schedule now, so the first digest is
+	// the next one due rather than one already past.
+	updated, err := scanNotificationPreferences(r.pool.QueryRow(ctx, `
+		INSERT INTO notification_preferences AS p (
+			tenant_id, user_id, weekly_digest, digest_weekday, digest_hour, last_digest_at,
+			large_transaction_alerts, large_transaction_threshold, scan_alerts, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $9, $6, $7, $8, $9)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET weekly_digest = EXCLUDED.weekly_digest,
+		    digest_weekday = EXCLUDED.digest_weekday,
+		    digest_hour = EXCLUDED.digest_hour,
+		    last_digest_at = CASE
+		        WHEN EXCLUDED.weekly_digest AND NOT p.weekly_digest THEN EXCLUDED.last_digest_at
+		        ELSE p.last_digest_at
+		    END,
+		    large_transaction_alerts = EXCLUDED.large_transaction_alerts,
+		    large_transaction_threshold = EXCLUDED.large_transaction_threshold,
+		    scan_alerts = EXCLUDED.scan_alerts,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING `+notificationPreferenceColumns,
+		tenant.ID, userID, prefs.WeeklyDigest, int(prefs.DigestWeekday), prefs.DigestHour,
+		prefs.LargeTransactionAlerts, prefs.LargeTransactionThreshold, prefs.ScanAlerts, r.now(),
+	))
+	if err != nil {
+		return nil, errors.E(op, "updating notification preferences", err)
+	}
+	return &updated, nil
+}
+
+func (r *notificationRepository) ListNotificationChannels(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+) ([]store.NotificationChannel, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+notificationChannelColumns+`
+		FROM notification_channels c
+		WHERE c.tenant_id = $1 AND c.user_id = $2
+		ORDER BY c.created_at, c.id
+	`, tenant.ID, userID)
+	if err != nil {
+		return nil, errors.E("postgres.notifications.list_channels", "listing notification channels", err)
+	}
+	defer rows.Close()
+
+	channels := []store.NotificationChannel{}
+	for rows.Next() {
+		channel, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, errors.E("postgres.notifications.list_channels", "scanning notification channel", err)
+		}
+		channels = append(channels, channel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E("postgres.notifications.list_channels", "listing notification channels", err)
+	}
+	return channels, nil
+}
+
+func (r *notificationRepository) OpenNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, id string,
+) (*store.NotificationChannel, string, error) {
+	const op = "postgres.notifications.open_channel"
+
+	var channel store.NotificationChannel
+	var sealed []byte
+	err := scanNotificationChannelInto(r.pool.QueryRow(ctx, `
+		SELECT `+notificationChannelColumns+`, c.token_ciphertext
+		FROM notification_channels c
+		WHERE c.id = $1 AND c.tenant_id = $2 AND c.user_id = $3
+	`, id, tenant.ID, userID), &channel, &sealed)
+	if err != nil {
+		if errorsIsNoRows(err) {
+			return nil, "", errNotificationChannelNotFound("store.notifications.open_channel")
+		}
+		return nil, "", errors.E(op, "getting notification channel", err)
+	}
+	if sealed == nil {
+		return &channel, "", nil
+	}
+	if r.secretBox == nil {
+		return nil, "", errors.E(op, errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	token, err := r.secretBox.Open(sealed, notificationChannelAssociatedData(tenant.ID, id))
+	if err != nil {
+		return nil, "", errors.E(op, "decrypting notification channel token", err)
+	}
+	return &channel, string(token), nil
+}
+
+func (r *notificationRepository) CreateNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	input store.NewNotificationChannel,
+) (*store.NotificationChannel, error) {
+	const op = "postgres.notifications.create_channel"
+
+	if err := validateNotificationChannel(input.Type, input.Target, input.Token != ""); err != nil {
+		return nil, err
+	}
+	id := uuid.NewString()
+	token, err := r.sealToken(tenant.ID, id, input.Token)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	channel, err := scanNotificationChannel(r.pool.QueryRow(ctx, `
+		INSERT INTO notification_channels AS c (id, tenant_id, user_id, type, name, target, token_ciphertext, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+notificationChannelColumns,
+		id, tenant.ID, userID, string(input.Type), input.Name, input.Target, token, input.Enabled,
+	))
+	if err != nil {
+		return nil, errors.E(op, "creating notification channel", err)
+	}
+	return &channel, nil
+}
+
+func (r *notificationRepository) UpdateNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, id string,
+	patch store.NotificationChannelPatch,
+) (*store.NotificationChannel, error) {
+	const op = "postgres.notifications.update_channel"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, errors.E(op, "beginning notification channel update", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	current, err := scanNotificationChannel(tx.QueryRow(ctx, `
+		SELECT `+notificationChannelColumns+`
+		FROM notification_channels c
+		WHERE c.id = $1 AND c.tenant_id = $2 AND c.user_id = $3
+		FOR UPDATE
+	`, id, tenant.ID, userID))
+	if err != nil {
+		if errorsIsNoRows(err) {
+			return nil, errNotificationChannelNotFound("store.notifications.update_channel")
+		}
+		return nil, errors.E(op, "getting notification channel", err)
+	}
+	target, hasToken := current.Target, current.HasToken
+	if patch.Target != nil {
+		target = *patch.Target
+	}
+	if patch.Token != nil {
+		hasToken = *patch.Token != ""
+	}
+	if err := validateNotificationChannel(current.Type, target, hasToken); err != nil {
+		return nil, err
+	}
+
+	var args []any
+	next := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	sets := []string{"updated_at = now()"}
+	if patch.Name != nil {
+		sets = append(sets, "name = "+next(*patch.Name))
+	}
+	if patch.Target != nil {
+		sets = append(sets, "target = "+next(*patch.Target))
+	}
+	if patch.Enabled != nil {
+		sets = append(sets, "enabled = "+next(*patch.Enabled))
+	}
+	if patch.Token != nil {
+		token, err := r.sealToken(tenant.ID, id, *patch.Token)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		sets = append(sets, "token_ciphertext = "+next(token))
+	}
+	channel, err := scanNotificationChannel(tx.QueryRow(ctx, fmt.Sprintf(`
+		UPDATE notification_channels AS c
+		SET %s
+		WHERE c.id = %s
+		RETURNING `+notificationChannelColumns,
+		strings.Join(sets, ", "), next(id)), args...))
+	if err != nil {
+		return nil, errors.E(op, "updating notification channel", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, errors.E(op, "committing notification channel update", err)
+	}
+	return &channel, nil
+}
+
+func (r *notificationRepository) DeleteNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) error {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM notification_channels WHERE id = $1 AND tenant_id = $2 AND user_id = $3`, id, tenant.ID, userID)
+	if err != nil {
+		return errors.E("postgres.notifications.delete_channel", "deleting notification channel", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errNotificationChannelNotFound("store.notifications.delete_channel")
+	}
+	return nil
+}
+
+func (r *notificationRepository) ListDigestSubscriptions(ctx context.Context) ([]store.DigestSubscription, error) {
+	const op = "postgres.notifications.list_digest_subscriptions"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT p.tenant_id::text, p.user_id::text, COALESCE(tz.value, ''), p.digest_weekday, p.digest_hour,
+		       COALESCE(p.last_digest_at, p.updated_at)
+		FROM notification_preferences p
+		JOIN tenant_memberships m ON m.tenant_id = p.tenant_id AND m.user_id = p.user_id
+		JOIN users u ON u.id = p.user_id AND u.disabled_at IS NULL
+		LEFT JOIN app_config tz ON tz.tenant_id = p.tenant_id AND tz.key = 'app.timezone'
+		WHERE p.weekly_digest
+		ORDER BY p.tenant_id, p.user_id
+	`)
+	if err != nil {
+		return nil, errors.E(op, "listing digest subscriptions", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []store.DigestSubscription
+	for rows.Next() {
+		var sub store.DigestSubscription
+		var weekday int
+		if err := rows.Scan(&sub.TenantID, &sub.UserID, &sub.Timezone, &weekday, &sub.Hour, &sub.LastDigestAt); err != nil {
+			return nil, errors.E(op, "scanning digest subscription", err)
+		}
+		sub.Weekday = time.Weekday(weekday)
+		subscriptions = append(subscriptions, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "listing digest subscriptions", err)
+	}
+	return subscriptions, nil
+}
+
+func (r *notificationRepository) GetSpendDigest(ctx context.Context, tenant store.Tenant, from, to time.Time) (*store.SpendDigest, error) {
+	const op = "postgres.notifications.get_spend_digest"
+
+	digest := store.SpendDigest{Top: []store.CategorySpend{}, Largest: []store.DigestTransaction{}}
+	previousFrom := from.Add(-to.Sub(from))
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE((SELECT NULLIF(value, '') FROM app_config WHERE tenant_id = $1 AND key = 'base_currency'), $5),
+		       COALESCE(SUM(amount) FILTER (WHERE timestamp >= $2), 0)::float8,
+		       COUNT(*) FILTER (WHERE timestamp >= $2),
+		       COALESCE(SUM(amount) FILTER (WHERE timestamp < $2), 0)::float8
+		FROM transactions
+		WHERE tenant_id = $1 AND muted = false AND timestamp >= $4 AND timestamp < $3
+	`, tenant.ID, from, to, previousFrom, defaultTransactionCurrency).Scan(&digest.Currency, &digest.Total, &digest.Count, &digest.Previous)
+	if err != nil {
+		return nil, errors.E(op, "summing spend", err)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT COALESCE(NULLIF(category, ''), 'Uncategorized'), SUM(amount)::float8, COUNT(DISTINCT id)
+		FROM `+transactionAllocations+` a
+		WHERE muted = false AND tenant_id = $1 AND timestamp >= $2 AND timestamp < $3
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT 5
+	`, tenant.ID, from, to)
+	if err != nil {
+		return nil, errors.E(op, "summing category spend", err)
+	}
+	for rows.Next() {
+		var category store.CategorySpend
+		if err := rows.Scan(&category.Category, &category.Amount, &category.Count); err != nil {
+			rows.Close()
+			return nil, errors.E(op, "scanning category spend", err)
+		}
+		digest.Top = append(digest.Top, category)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "summing category spend", err)
+	}
+
+	rows, err = r.pool.Query(ctx, `
+		SELECT id::text, COALESCE(merchant_info, ''), amount::float8, currency, timestamp
+		FROM transactions
+		WHERE muted = false AND tenant_id = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY amount DESC, timestamp DESC
+		LIMIT 3
+	`, tenant.ID, from, to)
+	if err != nil {
+		return nil, errors.E(op, "listing largest transactions", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var txn store.DigestTransaction
+		if err := rows.Scan(&txn.ID, &txn.Merchant, &txn.Amount, &txn.Currency, &txn.Timestamp); err != nil {
+			return nil, errors.E(op, "scanning largest transaction", err)
+		}
+		digest.Largest = append(digest.Largest, txn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "listing largest transactions", err)
+	}
+	return &digest, nil
+}
+
+func (r *notificationRepository) QueueDigest(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	dueAt time.Time,
+	digest store.SpendDigest,
+) (bool, error) {
+	const op = "postgres.notifications.queue_digest"
+
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		return false, errors.E(op, "encoding digest", err)
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, errors.E(op, "beginning digest transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE notification_preferences
+		SET last_digest_at = $3
+		WHERE tenant_id = $1 AND user_id = $2 AND weekly_digest AND (last_digest_at IS NULL OR last_digest_at < $3)
+	`, tenant.ID, userID, dueAt)
+	if err != nil {
+		return false, errors.E(op, "marking digest sent", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notification_deliveries (channel_id, tenant_id, user_id, kind, payload, next_attempt_at)
+		SELECT id, tenant_id, user_id, $3::text, $4::jsonb, $5::timestamptz
+		FROM notification_channels
+		WHERE tenant_id = $1 AND user_id = $2 AND enabled
+	`, tenant.ID, userID, string(store.NotificationWeeklyDigest), payload, r.now())
+	if err != nil {
+		return false, errors.E(op, "queueing digest", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, errors.E(op, "committing digest", err)
+	}
+	return true, nil
+}
+
+func (r *notificationRepository) ClaimNotificationDeliveries(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]store.NotificationDispatch, error) {
+	const op = "postgres.notifications.claim_deliveries"
+
+	if r.secretBox == nil {
+		return nil, errors.E(op, errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	now := r.now()
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT d.id
+			FROM notification_deliveries d
+			JOIN notification_channels c ON c.id = d.channel_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND c.enabled
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE notification_deliveries d
+		SET attempts = d.attempts + 1,
+		    next_attempt_at = $3
+		FROM due, notification_channels c
+		WHERE d.id = due.id AND c.id = d.channel_id
+		RETURNING d.id::text, d.channel_id::text, d.tenant_id::text, d.user_id::text, d.kind, d.payload, d.attempts,
+		          d.created_at, `+notificationChannelColumns+`, c.token_ciphertext
+	`, now, limit, now.Add(lease))
+	if err != nil {
+		return nil, errors.E(op, "claiming notification deliveries", err)
+	}
+	type claimed struct {
+		dispatch store.NotificationDispatch
+		token    []byte
+	}
+	var claims []claimed
+	for rows.Next() {
+		var claim claimed
+		delivery := &claim.dispatch.Delivery
+		var kind string
+		var payload []byte
+		err := scanNotificationChannelInto(rows, &claim.dispatch.Channel, &claim.token, func(dest []any) []any {
+			return append([]any{
+				&delivery.ID, &delivery.ChannelID, &delivery.TenantID, &delivery.UserID, &kind, &payload,
+				&delivery.Attempts, &delivery.CreatedAt,
+			}, dest...)
+		})
+		if err != nil {
+			rows.Close()
+			return nil, errors.E(op, "scanning claimed notification delivery", err)
+		}
+		delivery.Kind = store.NotificationKind(kind)
+		delivery.Payload = payload
+		claims = append(claims, claim)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "claiming notification deliveries", err)
+	}
+
+	dispatches := make([]store.NotificationDispatch, 0, len(claims))
+	for _, claim := range claims {
+		channel := claim.dispatch.Channel
+		if claim.token != nil {
+			token, err := r.secretBox.Open(claim.token, notificationChannelAssociatedData(channel.TenantID, channel.ID))
+			if err != nil {
+				// No attempt can succeed without the token, so the delivery
+				// is marked failed straight away.
+				r.logger.Warn("failed to decrypt notification channel token", "channel_id", channel.ID, "error", err)
+				attempt := store.NotificationAttempt{AttemptedAt: now, Error: "channel token cannot be decrypted"}
+				if err := r.CompleteNotificationDelivery(ctx, claim.dispatch.Delivery.ID, attempt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			claim.dispatch.Token = string(token)
+		}
+		dispatches = append(dispatches, claim.dispatch)
+	}
+	return dispatches, nil
+}
+
+func (r *notificationRepository) CompleteNotificationDelivery(ctx context.Context, id string, attempt store.NotificationAttempt) error {
+	status := "failed"
+	switch {
+	case attempt.Delivered:
+		status = "delivered"
+	case attempt.RetryAt != nil:
+		status = "pending"
+	}
+	var retryAt *time.Time
+	if status == "pending" {
+		retryAt = attempt.RetryAt
+	}
+	_, err := r.pool.Exec(ctx, `
+		UPDATE notification_deliveries
+		SET status = $2::text,
+		    last_attempt_at = $3,
+		    last_error = $4,
+		    next_attempt_at = $5,
+		    completed_at = CASE WHEN $2::text = 'pending' THEN NULL ELSE $3 END
+		WHERE id = $1 AND status = 'pending'
+	`, id, status, attempt.AttemptedAt, attempt.Error, retryAt)
+	if err != nil {
+		return errors.E("postgres.notifications.complete_delivery", "completing notification delivery", err)
+	}
+	return nil
+}
+
+func (r *notificationRepository) PruneNotificationDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM notification_deliveries WHERE status <> 'pending' AND completed_at < $1`, cutoff)
+	if err != nil {
+		return 0, errors.E("postgres.notifications.prune_deliveries", "pruning notification deliveries", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// enqueueLargeTransactions queues an alert to each member who asked for
+// one about the transactions among ids at or above their threshold. Like
+// webhook events, alerts are queued after the write committed and failures
+// are only logged.
+func (r *notificationRepository) enqueueLargeTransactions(ctx context.Context, tenantID string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	_, err := r.pool.Exec(context.WithoutCancel(ctx), `
+		INSERT INTO notification_deliveries (channel_id, tenant_id, user_id, kind, payload, next_attempt_at)
+		SELECT c.id, c.tenant_id, c.user_id, $3::text, jsonb_build_object(
+			'id', t.id,
+			'amount', t.amount,
+			'currency', t.currency,
+			'merchant', COALESCE(t.merchant_info, ''),
+			'category', COALESCE(t.category, ''),
+			'source', t.source_label,
+			'timestamp', t.timestamp,
+			'threshold', p.large_transaction_threshold
+		), $4::timestamptz
+		`+notificationRecipients+`
+		JOIN transactions t ON t.tenant_id = p.tenant_id
+		WHERE p.tenant_id = $1 AND p.large_transaction_alerts
+		  AND t.id = ANY($2::uuid[]) AND NOT t.muted AND t.amount >= p.large_transaction_threshold
+	`, tenantID, ids, string(store.NotificationLargeTransaction), r.now())
+	if err != nil {
+		r.logger.Warn("failed to queue large transaction alerts", "tenant_id", tenantID, "error", err)
+	}
+}
+
+// enqueueScanAlert queues a scan alert with the given data to each member
+// who asked for scan alerts.
+func (r *notificationRepository) enqueueScanAlert(ctx context.Context, tenantID string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		r.logger.Warn("failed to encode scan alert", "error", err)
+		return
+	}
+	_, err = r.pool.Exec(context.WithoutCancel(ctx), `
+		INSERT INTO notification_deliveries (channel_id, tenant_id, user_id, kind, payload, next_attempt_at)
+		SELECT c.id, c.tenant_id, c.user_id, $2::text, $3::jsonb, $4::timestamptz
+		`+notificationRecipients+`
+		WHERE p.tenant_id = $1 AND p.scan_alerts
+	`, tenantID, string(store.NotificationScanNeedsAuth), payload, r.now())
+	if err != nil {
+		r.logger.Warn("failed to queue scan alerts", "tenant_id", tenantID, "error", err)
+	}
+}
+
+// sealToken encrypts a channel token; an empty token is stored as NULL.
+func (r *notificationRepository) sealToken(tenantID, channelID, token string) ([]byte, error) {
+	if token == "" {
+		return nil, nil
+	}
+	if r.secretBox == nil {
+		return nil, errors.E(errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	sealed, err := r.secretBox.Seal([]byte(token), notificationChannelAssociatedData(tenantID, channelID))
+	if err != nil {
+		return nil, errors.E("encrypting notification channel token", err)
+	}
+	return sealed, nil
+}
+
+// validateNotificationChannel checks that target is an address channels of
+// the given type can send to, and that a token is set where one is needed.
+func validateNotificationChannel(channelType store.NotificationChannelType, target string, hasToken bool) error {
+	const op = "store.notifications.validate_channel"
+
+	invalid := func(msg string) error { return errors.E(op, errors.InvalidInput, errors.User(msg)) }
+	switch channelType {
+	case store.NotificationChannelEmail:
+		if address, err := mail.ParseAddress(target); err != nil || address.Address != target {
+			return invalid("email channels need an email address")
+		}
+	case store.NotificationChannelNtfy, store.NotificationChannelGotify, store.NotificationChannelWebhook:
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return invalid(fmt.Sprintf("%s channels need an http or https URL", channelType))
+		}
+		if channelType == store.NotificationChannelNtfy && strings.Trim(parsed.Path, "/") == "" {
+			return invalid("ntfy channels need a topic URL such as https://ntfy.sh/my-topic")
+		}
+	case store.NotificationChannelTelegram:
+		if !telegramChatID.MatchString(target) {
+			return invalid("telegram channels need a numeric chat ID or @channel name")
+		}
+	default:
+		return invalid(fmt.Sprintf("unknown channel type %q", channelType))
+	}
+	if !hasToken && store.NotificationTokenRequired(channelType) {
+		return invalid(fmt.Sprintf("%s channels need a token", channelType))
+	}
+	return nil
+}
+
+func errNotificationChannelNotFound(op string) error {
+	return errors.E(op, errors.NotFound, errors.User("notification channel not found"))
+}
+
+func scanNotificationPreferences(row pgx.Row) (store.NotificationPreferences, error) {
+	var prefs store.NotificationPreferences
+	var weekday int
+	err := row.Scan(
+		&prefs.WeeklyDigest, &weekday, &prefs.DigestHour, &prefs.LargeTransactionAlerts,
+		&prefs.LargeTransactionThreshold, &prefs.ScanAlerts, &prefs.UpdatedAt,
+	)
+	prefs.DigestWeekday = time.Weekday(weekday)
+	return prefs, err
+}
+
+func scanNotificationChannel(row pgx.Row) (store.NotificationChannel, error) {
+	var channel store.NotificationChannel
+	err := scanNotificationChannelInto(row, &channel)
+	return channel, err
+}
+
+// scanNotificationChannelInto scans notificationChannelColumns into channel.
+// A []byte destination is scanned from the column after them, and a
+// function wraps the destinations with columns before and after.
+func scanNotificationChannelInto(row pgx.Row, channel *store.NotificationChannel, extra ...any) error {
+	var channelType string
+	dest := []any{
+		&channel.ID, &channel.TenantID, &channel.UserID, &channelType, &channel.Name, &channel.Target,
+		&channel.HasToken, &channel.Enabled, &channel.CreatedAt, &channel.UpdatedAt,
+	}
+	for _, e := range extra {
+		if wrap, ok := e.(func([]any) []any); ok {
+			dest = wrap(dest)
+		} else {
+			dest = append(dest, e)
+		}
+	}
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	channel.Type = store.NotificationChannelType(channelType)
+	return nil
+}
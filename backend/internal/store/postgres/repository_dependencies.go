@@ -7,11 +7,16 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/blob"
+	"github.com/ArionMiles/expensor/backend/internal/events"
 )
 
 type repositoryDependencies struct {
-	pool      *pgxpool.Pool
-	logger    *slog.Logger
-	now       func() time.Time
-	secretBox *auth.SecretBox
+	pool              *pgxpool.Pool
+	logger            *slog.Logger
+	now               func() time.Time
+	secretBox         *auth.SecretBox
+	blobs             blob.Store
+	maxAttachmentSize int64
+	events            events.Publisher
 }
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -12,11 +13,17 @@ import (
 )
 
 type scanningRepository struct {
-	pool *pgxpool.Pool
+	pool          *pgxpool.Pool
+	webhooks      *webhookRepository
+	notifications *notificationRepository
 }
 
-func newScanningRepository(deps repositoryDependencies) *scanningRepository {
-	return &scanningRepository{pool: deps.pool}
+func newScanningRepository(
+	deps repositoryDependencies,
+	webhooks *webhookRepository,
+	notifications *notificationRepository,
+) *scanningRepository {
+	return &scanningRepository{pool: deps.pool, webhooks: webhooks, notifications: notifications}
 }
 
 func (r *scanningRepository) GetSchedulerConfig(ctx context.Context) (store.SchedulerConfig, error) {
@@ -60,8 +67,8 @@ func (r *scanningRepository) EnsureScanningStateForTenant(ctx context.Context, t
 	_, err = r.pool.Exec(ctx, `
 		INSERT INTO tenant_scanning_state (tenant_id, active_reader, enabled, state)
 		SELECT $1::uuid, '', true, 'stopped'
-		FROM users u
-		WHERE u.id = $1
+		FROM tenants t
+		WHERE t.id = $1
 		ON CONFLICT (tenant_id) DO NOTHING
 	`, tenantID)
 	if err != nil {
@@ -221,7 +228,13 @@ func (r *scanningRepository) UpdateScanningState(ctx context.Context, tenant sto
 	if update.RetryCount != nil {
 		retryCount = *update.RetryCount
 	}
-	_, err = r.pool.Exec(ctx, `
+	var previous store.ScanningState
+	var reader string
+	var retries int
+	err = r.pool.QueryRow(ctx, `
+		WITH previous AS (
+			SELECT state FROM tenant_scanning_state WHERE tenant_id = $1 FOR UPDATE
+		)
 		UPDATE tenant_scanning_state
 		SET state = $2,
 		    reason_code = $3,
@@ -233,16 +246,51 @@ func (r *scanningRepository) UpdateScanningState(ctx context.Context, tenant sto
 		    retry_count = COALESCE($9, retry_count),
 		    updated_at = now()
 		WHERE tenant_id = $1
+		RETURNING (SELECT state FROM previous), active_reader, retry_count
 	`,
 		tenantID, update.State, update.ReasonCode, update.PublicMessage, update.LastStartedAt,
 		update.LastStoppedAt, update.LastFailedAt, update.NextRetryAt, retryCount,
-	)
+	).Scan(&previous, &reader, &retries)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
 	if err != nil {
 		return errors.E("postgres.scanning.update_scanning_state", "updating scanning state", err)
 	}
+	if scanFailed(update.State) && previous != update.State {
+		event := scanFailedEvent{
+			Reader:      reader,
+			State:       update.State,
+			ReasonCode:  update.ReasonCode,
+			Message:     update.PublicMessage,
+			RetryCount:  retries,
+			NextRetryAt: update.NextRetryAt,
+		}
+		r.webhooks.enqueue(ctx, tenantID, store.WebhookScanFailed, event)
+		// Backing off retries by itself, so only scanning that waits on the
+		// user raises an alert.
+		if update.State == store.ScanningStateNeedsAuth && r.notifications != nil {
+			r.notifications.enqueueScanAlert(ctx, tenantID, event)
+		}
+	}
 	return nil
 }
 
+// scanFailed reports whether state is scanning stopped on an error.
+func scanFailed(state store.ScanningState) bool {
+	return state == store.ScanningStateNeedsAuth || state == store.ScanningStateBackingOff
+}
+
+// scanFailedEvent is the data of scan.failed webhook events.
+type scanFailedEvent struct {
+	Reader      string                   `json:"reader"`
+	State       store.ScanningState      `json:"state"`
+	ReasonCode  store.ScanningReasonCode `json:"reason_code"`
+	Message     string                   `json:"message"`
+	RetryCount  int                      `json:"retry_count"`
+	NextRetryAt *time.Time               `json:"next_retry_at"`
+}
+
 func (r *scanningRepository) fetchScanningState(ctx context.Context, tenant store.Tenant) (store.TenantScanningState, error) {
 	var state store.TenantScanningState
 	err := r.pool.QueryRow(ctx, `
@@ -0,0 +1,282 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/blob"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+type secretRepository struct {
+	pool      *pgxpool.Pool
+	logger    *slog.Logger
+	secretBox *auth.SecretBox
+	blobs     blob.Store
+}
+
+func newSecretRepository(deps repositoryDependencies) *secretRepository {
+	return &secretRepository{pool: deps.pool, logger: deps.logger, secretBox: deps.secretBox, blobs: deps.blobs}
+}
+
+// sealedColumn describes a column of sealed values. Rows are identified by
+// tenantColumn, plus nameColumn when it is set, and walked in that order.
+type sealedColumn struct {
+	table        string
+	column       string
+	tenantColumn string
+	nameColumn   string
+	associated   func(tenantID, name string) auth.SecretAssociatedData
+}
+
+func readerSecretAssociatedData(column string) func(tenantID, name string) auth.SecretAssociatedData {
+	return func(tenantID, reader string) auth.SecretAssociatedData {
+		return auth.SecretAssociatedData{TenantID: tenantID, Scope: "reader", Name: reader, Kind: column}
+	}
+}
+
+var sealedColumns = map[store.SealedSecretKind]sealedColumn{
+	store.SealedReaderClientSecret: {
+		table: "reader_runtime", column: "client_secret_ciphertext", tenantColumn: "tenant_id", nameColumn: "reader",
+		associated: readerSecretAssociatedData(readerRuntimeClientSecret),
+	},
+	store.SealedReaderOAuthToken: {
+		table: "reader_runtime", column: "oauth_token_ciphertext", tenantColumn: "tenant_id", nameColumn: "reader",
+		associated: readerSecretAssociatedData(readerRuntimeOAuthToken),
+	},
+	store.SealedLLMCredentials: {
+		table: "llm_provider_runtime", column: "credentials_ciphertext", tenantColumn: "tenant_id", nameColumn: "provider",
+		associated: func(tenantID, provider string) auth.SecretAssociatedData {
+			return llmProviderAssociatedData(store.Tenant{ID: tenantID}, provider)
+		},
+	},
+	store.SealedTOTPSecret: {
+		table: "totp_factors", column: "secret_ciphertext", tenantColumn: "user_id",
+		associated: func(userID, _ string) auth.SecretAssociatedData { return totpAssociatedData(userID) },
+	},
+	store.SealedWebhookSecret: {
+		table: "webhooks", column: "secret_ciphertext", tenantColumn: "tenant_id", nameColumn: "id::text",
+		associated: webhookAssociatedData,
+	},
+	store.SealedNotificationToken: {
+		table: "notification_channels", column: "token_ciphertext", tenantColumn: "tenant_id", nameColumn: "id::text",
+		associated: notificationChannelAssociatedData,
+	},
+}
+
+// cursor is the SQL expression rows are ordered and resumed by.
+func (c sealedColumn) cursor() string {
+	if c.nameColumn == "" {
+		return c.tenantColumn + "::text"
+	}
+	return c.tenantColumn + "::text || '/' || " + c.nameColumn
+}
+
+func (c sealedColumn) name() string {
+	if c.nameColumn == "" {
+		return "''"
+	}
+	return c.nameColumn
+}
+
+func (r *secretRepository) CountSealedSecrets(ctx context.Context, kind store.SealedSecretKind) (int64, error) {
+	const op = "postgres.secrets.count"
+
+	var query string
+	if kind == store.SealedAttachment {
+		query = `SELECT COUNT(*) FROM transaction_attachments`
+	} else {
+		column, ok := sealedColumns[kind]
+		if !ok {
+			return 0, errors.E(op, errors.InvalidArgument, fmt.Sprintf("unknown sealed secret kind %q", kind))
+		}
+		query = fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL AND %s IS NOT NULL`,
+			column.table, column.tenantColumn, column.column)
+	}
+	var count int64
+	if err := r.pool.QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, errors.E(op, fmt.Sprintf("counting %s values", kind), err)
+	}
+	return count, nil
+}
+
+func (r *secretRepository) ResealSecrets(
+	ctx context.Context,
+	kind store.SealedSecretKind,
+	after string,
+	limit int,
+) (store.ResealBatch, error) {
+	const op = "postgres.secrets.reseal"
+
+	if r.secretBox == nil {
+		return store.ResealBatch{}, errors.E(op, errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	if limit <= 0 {
+		return store.ResealBatch{}, errors.E(op, errors.InvalidArgument, "reseal batch limit must be positive")
+	}
+	if kind == store.SealedAttachment {
+		return r.resealAttachments(ctx, after, limit)
+	}
+	column, ok := sealedColumns[kind]
+	if !ok {
+		return store.ResealBatch{}, errors.E(op, errors.InvalidArgument, fmt.Sprintf("unknown sealed secret kind %q", kind))
+	}
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT %[1]s, %[2]s::text, %[3]s, %[4]s
+		FROM %[5]s
+		WHERE %[2]s IS NOT NULL AND %[4]s IS NOT NULL AND %[1]s > $1
+		ORDER BY 1
+		LIMIT $2
+	`, column.cursor(), column.tenantColumn, column.name(), column.column, column.table), after, limit)
+	if err != nil {
+		return store.ResealBatch{}, errors.E(op, fmt.Sprintf("listing %s values", kind), err)
+	}
+	type sealedRow struct {
+		cursor, tenantID, name string
+		ciphertext             []byte
+	}
+	var sealed []sealedRow
+	for rows.Next() {
+		var row sealedRow
+		if err := rows.Scan(&row.cursor, &row.tenantID, &row.name, &row.ciphertext); err != nil {
+			rows.Close()
+			return store.ResealBatch{}, errors.E(op, fmt.Sprintf("scanning %s value", kind), err)
+		}
+		sealed = append(sealed, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return store.ResealBatch{}, errors.E(op, fmt.Sprintf("listing %s values", kind), err)
+	}
+
+	// The update only applies while the value is still the one read, so a
+	// concurrent write of a new value wins.
+	update := fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s = $2 AND %s = $3`,
+		column.table, column.column, column.tenantColumn, column.column)
+	if column.nameColumn != "" {
+		update += " AND " + column.nameColumn + " = $4"
+	}
+	var batch store.ResealBatch
+	for _, row := range sealed {
+		batch.Scanned++
+		batch.Next = row.cursor
+		resealed, changed, err := r.secretBox.Reseal(row.ciphertext, column.associated(row.tenantID, row.name))
+		if err != nil {
+			batch.Failures = append(batch.Failures, describeSealedValue(kind, row.tenantID, row.name)+": "+err.Error())
+			continue
+		}
+		if !changed {
+			continue
+		}
+		args := []any{resealed, row.tenantID, row.ciphertext}
+		if column.nameColumn != "" {
+			args = append(args, row.name)
+		}
+		tag, err := r.pool.Exec(ctx, update, args...)
+		if err != nil {
+			return store.ResealBatch{}, errors.E(op, "storing resealed "+describeSealedValue(kind, row.tenantID, row.name), err)
+		}
+		if tag.RowsAffected() > 0 {
+			batch.Resealed++
+		}
+	}
+	if len(sealed) < limit {
+		batch.Next = ""
+	}
+	return batch, nil
+}
+
+func (r *secretRepository) resealAttachments(ctx context.Context, after string, limit int) (store.ResealBatch, error) {
+	const op = "postgres.secrets.reseal_attachments"
+
+	if r.blobs == nil {
+		return store.ResealBatch{}, errors.E(op, errors.FailedPrecondition, "attachment blob store is not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, tenant_id::text, blob_key
+		FROM transaction_attachments
+		WHERE id::text > $1
+		ORDER BY id::text
+		LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return store.ResealBatch{}, errors.E(op, "listing attachments", err)
+	}
+	type attachmentRow struct{ id, tenantID, key string }
+	var attachments []attachmentRow
+	for rows.Next() {
+		var row attachmentRow
+		if err := rows.Scan(&row.id, &row.tenantID, &row.key); err != nil {
+			rows.Close()
+			return store.ResealBatch{}, errors.E(op, "scanning attachment", err)
+		}
+		attachments = append(attachments, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return store.ResealBatch{}, errors.E(op, "listing attachments", err)
+	}
+
+	var batch store.ResealBatch
+	for _, row := range attachments {
+		batch.Scanned++
+		batch.Next = row.id
+		describe := describeSealedValue(store.SealedAttachment, row.tenantID, row.id)
+		sealed, err := r.blobs.Get(ctx, row.key)
+		if err != nil {
+			if errors.WhatKind(err) == errors.NotFound {
+				batch.Failures = append(batch.Failures, describe+": content is missing")
+				continue
+			}
+			return store.ResealBatch{}, errors.E(op, "reading "+describe, err)
+		}
+		resealed, changed, err := r.secretBox.Reseal(sealed, attachmentAssociatedData(store.Tenant{ID: row.tenantID}, row.id))
+		if err != nil {
+			batch.Failures = append(batch.Failures, describe+": "+err.Error())
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if err := r.blobs.Put(ctx, row.key, resealed); err != nil {
+			return store.ResealBatch{}, errors.E(op, "storing resealed "+describe, err)
+		}
+		// An attachment deleted meanwhile must not keep its content.
+		var exists bool
+		if err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM transaction_attachments WHERE id = $1)`, row.id).Scan(&exists); err != nil {
+			return store.ResealBatch{}, errors.E(op, "checking "+describe, err)
+		}
+		if !exists {
+			if err := r.blobs.Delete(ctx, row.key); err != nil {
+				r.logger.Warn("removing content of deleted attachment failed", "attachment_id", row.id, "error", err)
+			}
+			continue
+		}
+		batch.Resealed++
+	}
+	if len(attachments) < limit {
+		batch.Next = ""
+	}
+	return batch, nil
+}
+
+func describeSealedValue(kind store.SealedSecretKind, tenantID, name string) string {
+	switch kind {
+	case store.SealedTOTPSecret:
+		return fmt.Sprintf("%s of user %s", kind, tenantID)
+	case store.SealedAttachment:
+		return fmt.Sprintf("attachment %s in tenant %s", name, tenantID)
+	case store.SealedWebhookSecret:
+		return fmt.Sprintf("secret of webhook %s in tenant %s", name, tenantID)
+	case store.SealedNotificationToken:
+		return fmt.Sprintf("token of notification channel %s in tenant %s", name, tenantID)
+	default:
+		return fmt.Sprintf("%s of %q in tenant %s", kind, name, tenantID)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/blob"
+	"github.com/ArionMiles/expensor/backend/internal/events"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/internal/store/postgres/migrations"
 	"github.com/ArionMiles/expensor/backend/pkg/api"
@@ -22,26 +24,48 @@ import (
 type Options struct {
 	Config   config.Postgres
 	Security config.Security
-	Logger   *slog.Logger
+	// Blobs holds encrypted attachment content. Attachment operations fail
+	// with FailedPrecondition when it is nil.
+	Blobs             blob.Store
+	MaxAttachmentSize int64
+	// Events receives live events about stored transactions and
+	// diagnostics. Nil drops them.
+	Events events.Publisher
+	Logger *slog.Logger
 }
 
 // Store wraps a pgxpool.Pool and provides query operations for the API layer.
 type Store struct {
-	pool      *pgxpool.Pool
-	logger    *slog.Logger
-	now       func() time.Time
-	secretBox *auth.SecretBox
-	auth      *authRepository
-	community *communityRepository
-	diag      *diagnosticsRepository
-	analytics *analyticsRepository
-	ingestion *ingestionRepository
-	rules     *rulesRepository
-	runtime   *runtimeRepository
-	scanning  *scanningRepository
-	seeder    *seederRepository
-	taxonomy  *taxonomyRepository
-	txns      *transactionsRepository
+	pool              *pgxpool.Pool
+	logger            *slog.Logger
+	now               func() time.Time
+	secretBox         *auth.SecretBox
+	blobs             blob.Store
+	maxAttachmentSize int64
+	events            events.Publisher
+	attachments       *attachmentRepository
+	audit             *auditRepository
+	auth              *authRepository
+	community         *communityRepository
+	diag              *diagnosticsRepository
+	analytics         *analyticsRepository
+	archives          *archiveRepository
+	backups           *backupRepository
+	ingestion         *ingestionRepository
+	ledgers           *ledgerRepository
+	llmUsage          *llmUsageRepository
+	llmPrompts        *llmPromptRepository
+	mfa               *mfaRepository
+	notifications     *notificationRepository
+	rules             *rulesRepository
+	runtime           *runtimeRepository
+	scanning          *scanningRepository
+	secrets           *secretRepository
+	seeder            *seederRepository
+	taxonomy          *taxonomyRepository
+	tenants           *tenantRepository
+	txns              *transactionsRepository
+	webhooks          *webhookRepository
 }
 
 var _ store.Backend = (*Store)(nil)
@@ -53,7 +77,7 @@ func New(ctx context.Context, opts Options) (*Store, error) {
 		logger = slog.Default()
 	}
 
-	st, err := newStore(ctx, opts.Config, opts.Security, logger)
+	st, err := newStore(ctx, opts, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +93,8 @@ func New(ctx context.Context, opts Options) (*Store, error) {
 	return st, nil
 }
 
-func newStore(ctx context.Context, cfg config.Postgres, security config.Security, logger *slog.Logger) (*Store, error) {
+func newStore(ctx context.Context, opts Options, logger *slog.Logger) (*Store, error) {
+	cfg, security := opts.Config, opts.Security
 	connStr := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg.SSLMode,
@@ -92,14 +117,21 @@ func newStore(ctx context.Context, cfg config.Postgres, security config.Security
 
 	var secretBox *auth.SecretBox
 	if len(security.SecretKey) > 0 {
-		secretBox, err = auth.NewSecretBox(security.SecretKey)
+		secretBox, err = auth.NewSecretBox(security.SecretKey, security.PreviousSecretKeys...)
 		if err != nil {
 			pool.Close()
 			return nil, errors.E("postgres.store.open", errors.InvalidArgument, "creating store secret box", err)
 		}
 	}
 
-	s := &Store{pool: pool, logger: logger, now: time.Now, secretBox: secretBox}
+	publisher := opts.Events
+	if publisher == nil {
+		publisher = events.Discard
+	}
+	s := &Store{
+		pool: pool, logger: logger, now: time.Now, secretBox: secretBox, blobs: opts.Blobs, maxAttachmentSize: opts.MaxAttachmentSize,
+		events: publisher,
+	}
 	s.initRepositories()
 	return s, nil
 }
@@ -114,21 +146,36 @@ func (s *Store) HealthCheck(ctx context.Context) error {
 
 func (s *Store) initRepositories() {
 	deps := repositoryDependencies{
-		pool:      s.pool,
-		logger:    s.logger,
-		now:       s.now,
-		secretBox: s.secretBox,
+		pool:              s.pool,
+		logger:            s.logger,
+		now:               s.now,
+		secretBox:         s.secretBox,
+		blobs:             s.blobs,
+		maxAttachmentSize: s.maxAttachmentSize,
+		events:            s.events,
 	}
+	s.archives = newArchiveRepository(deps)
+	s.backups = newBackupRepository(deps)
+	s.attachments = newAttachmentRepository(deps)
+	s.audit = newAuditRepository(deps)
 	s.auth = newAuthRepository(deps)
 	s.community = newCommunityRepository(deps)
-	s.diag = newDiagnosticsRepository(deps)
-	s.ingestion = newIngestionRepository(deps)
+	s.webhooks = newWebhookRepository(deps)
+	s.notifications = newNotificationRepository(deps)
+	s.diag = newDiagnosticsRepository(deps, s.webhooks)
+	s.ingestion = newIngestionRepository(deps, s.attachments, s.webhooks, s.notifications, s.diag)
+	s.ledgers = newLedgerRepository(deps)
+	s.llmUsage = newLLMUsageRepository(deps)
+	s.llmPrompts = newLLMPromptRepository(deps)
+	s.mfa = newMFARepository(deps)
 	s.rules = newRulesRepository(deps)
 	s.runtime = newRuntimeRepository(deps)
-	s.scanning = newScanningRepository(deps)
+	s.scanning = newScanningRepository(deps, s.webhooks, s.notifications)
+	s.secrets = newSecretRepository(deps)
 	s.analytics = newAnalyticsRepository(deps, s.runtime)
 	s.taxonomy = newTaxonomyRepository(deps)
-	s.txns = newTransactionsRepository(deps)
+	s.tenants = newTenantRepository(deps)
+	s.txns = newTransactionsRepository(deps, s.webhooks, s.diag)
 	s.seeder = newSeederRepository(s.rules, s.community, s.logger)
 }
 
@@ -169,8 +216,18 @@ func (s *Store) UpdateUserPassword(ctx context.Context, id string, input store.U
 	return s.auth.UpdateUserPassword(ctx, id, input)
 }
 
+// DeleteUser also removes the attachment content of the user's personal
+// tenant, whose rows go with it.
 func (s *Store) DeleteUser(ctx context.Context, id string) error {
-	return s.auth.DeleteUser(ctx, id)
+	keys, err := s.attachments.blobKeysForTenant(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.auth.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	s.attachments.deleteBlobs(ctx, keys)
+	return nil
 }
 
 func (s *Store) FindUserByEmail(ctx context.Context, email string) (*store.User, error) {
@@ -181,6 +238,14 @@ func (s *Store) FindUserByID(ctx context.Context, id string) (*store.User, error
 	return s.auth.FindUserByID(ctx, id)
 }
 
+func (s *Store) FindUserByOIDCSubject(ctx context.Context, issuer, subject string) (*store.User, error) {
+	return s.auth.FindUserByOIDCSubject(ctx, issuer, subject)
+}
+
+func (s *Store) LinkOIDCSubject(ctx context.Context, userID, issuer, subject string) error {
+	return s.auth.LinkOIDCSubject(ctx, userID, issuer, subject)
+}
+
 func (s *Store) CreateSession(ctx context.Context, input store.CreateSessionInput) (*store.Session, error) {
 	return s.auth.CreateSession(ctx, input)
 }
@@ -193,6 +258,22 @@ func (s *Store) RevokeSession(ctx context.Context, id string) error {
 	return s.auth.RevokeSession(ctx, id)
 }
 
+func (s *Store) ListSessions(ctx context.Context, userID string) ([]store.Session, error) {
+	return s.auth.ListSessions(ctx, userID)
+}
+
+func (s *Store) RevokeUserSession(ctx context.Context, userID, id string) error {
+	return s.auth.RevokeUserSession(ctx, userID, id)
+}
+
+func (s *Store) RevokeOtherSessions(ctx context.Context, userID, keepID string) (int64, error) {
+	return s.auth.RevokeOtherSessions(ctx, userID, keepID)
+}
+
+func (s *Store) TouchSession(ctx context.Context, id, ipAddress string) error {
+	return s.auth.TouchSession(ctx, id, ipAddress)
+}
+
 func (s *Store) CreateAccessToken(ctx context.Context, input store.CreateAccessTokenInput) (*store.AccessToken, error) {
 	return s.auth.CreateAccessToken(ctx, input)
 }
@@ -225,6 +306,62 @@ func (s *Store) CompleteAccountSetup(ctx context.Context, input store.CompleteAc
 	return s.auth.CompleteAccountSetup(ctx, input)
 }
 
+func (s *Store) GetMFAFactors(ctx context.Context, userID string) (store.MFAFactors, error) {
+	return s.mfa.GetMFAFactors(ctx, userID)
+}
+
+func (s *Store) GetTOTPFactor(ctx context.Context, userID string) (*store.TOTPFactor, error) {
+	return s.mfa.GetTOTPFactor(ctx, userID)
+}
+
+func (s *Store) SetPendingTOTP(ctx context.Context, userID string, secret []byte) error {
+	return s.mfa.SetPendingTOTP(ctx, userID, secret)
+}
+
+func (s *Store) ConfirmTOTP(ctx context.Context, userID string, step int64) error {
+	return s.mfa.ConfirmTOTP(ctx, userID, step)
+}
+
+func (s *Store) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	return s.mfa.UseTOTPStep(ctx, userID, step)
+}
+
+func (s *Store) DeleteTOTP(ctx context.Context, userID string) error {
+	return s.mfa.DeleteTOTP(ctx, userID)
+}
+
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	return s.mfa.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	return s.mfa.UseRecoveryCode(ctx, userID, codeHash)
+}
+
+func (s *Store) CreatePasskey(ctx context.Context, input store.CreatePasskeyInput) (*store.Passkey, error) {
+	return s.mfa.CreatePasskey(ctx, input)
+}
+
+func (s *Store) ListPasskeys(ctx context.Context, userID string) ([]store.Passkey, error) {
+	return s.mfa.ListPasskeys(ctx, userID)
+}
+
+func (s *Store) UsePasskey(ctx context.Context, id string, signCount uint32) error {
+	return s.mfa.UsePasskey(ctx, id, signCount)
+}
+
+func (s *Store) DeletePasskey(ctx context.Context, userID, id string) error {
+	return s.mfa.DeletePasskey(ctx, userID, id)
+}
+
+func (s *Store) GetMFASettings(ctx context.Context) (store.MFASettings, error) {
+	return s.mfa.GetMFASettings(ctx)
+}
+
+func (s *Store) PatchMFASettings(ctx context.Context, patch store.MFASettingsPatch) (store.MFASettings, error) {
+	return s.mfa.PatchMFASettings(ctx, patch)
+}
+
 // ListTransactions returns a paginated, filtered list of transactions and the total
 // count plus total amount matching the filter (ignoring pagination).
 func (s *Store) ListTransactions(
@@ -240,6 +377,243 @@ func (s *Store) GetTransaction(ctx context.Context, tenant store.Tenant, id stri
 	return s.txns.GetTransaction(ctx, tenant, id)
 }
 
+// CreateTransaction records a manually entered transaction with the manual
+// source type and returns it with its labels.
+func (s *Store) CreateTransaction(ctx context.Context, tenant store.Tenant, input store.CreateTransactionInput) (*store.Transaction, error) {
+	return s.txns.CreateTransaction(ctx, tenant, input)
+}
+
+// DeleteTransaction removes a manually entered transaction. Email-derived
+// transactions return a Conflict error kind and should be muted instead.
+// DeleteTransaction also removes the content of the transaction's attachments.
+func (s *Store) DeleteTransaction(ctx context.Context, tenant store.Tenant, id string) error {
+	keys, err := s.attachments.blobKeysForTransaction(ctx, tenant, id)
+	if err != nil {
+		return err
+	}
+	if err := s.txns.DeleteTransaction(ctx, tenant, id); err != nil {
+		return err
+	}
+	s.attachments.deleteBlobs(ctx, keys)
+	return nil
+}
+
+func (s *Store) CreateAttachment(ctx context.Context, tenant store.Tenant, transactionID string, input store.NewAttachment) (*store.Attachment, error) {
+	return s.attachments.CreateAttachment(ctx, tenant, transactionID, input)
+}
+
+func (s *Store) ListAttachments(ctx context.Context, tenant store.Tenant, transactionID string) ([]store.Attachment, error) {
+	return s.attachments.ListAttachments(ctx, tenant, transactionID)
+}
+
+func (s *Store) GetAttachment(ctx context.Context, tenant store.Tenant, transactionID, attachmentID string) (*store.Attachment, []byte, error) {
+	return s.attachments.GetAttachment(ctx, tenant, transactionID, attachmentID)
+}
+
+func (s *Store) DeleteAttachment(ctx context.Context, tenant store.Tenant, transactionID, attachmentID string) error {
+	return s.attachments.DeleteAttachment(ctx, tenant, transactionID, attachmentID)
+}
+
+func (s *Store) ExportTenantArchive(ctx context.Context, tenant store.Tenant) (*store.TenantArchive, error) {
+	return s.archives.ExportTenantArchive(ctx, tenant)
+}
+
+func (s *Store) ImportTenantArchive(
+	ctx context.Context,
+	tenant store.Tenant,
+	archive store.TenantArchive,
+	policy store.ArchiveConflictPolicy,
+) (store.TenantImportResult, error) {
+	return s.archives.ImportTenantArchive(ctx, tenant, archive, policy)
+}
+
+func (s *Store) GetBackupSettings(ctx context.Context) (store.BackupSettings, error) {
+	return s.backups.GetBackupSettings(ctx)
+}
+
+func (s *Store) PatchBackupSettings(ctx context.Context, patch store.BackupSettingsPatch) (store.BackupSettings, error) {
+	return s.backups.PatchBackupSettings(ctx, patch)
+}
+
+func (s *Store) ClaimScheduledBackup(ctx context.Context, now time.Time) (bool, error) {
+	return s.backups.ClaimScheduledBackup(ctx, now)
+}
+
+func (s *Store) RecordBackupRun(ctx context.Context, run store.BackupRun) error {
+	return s.backups.RecordBackupRun(ctx, run)
+}
+
+func (s *Store) DumpDatabase(ctx context.Context, open store.BackupTableWriter) (store.DatabaseSchema, error) {
+	return s.backups.DumpDatabase(ctx, open)
+}
+
+func (s *Store) CheckBackupSchema(ctx context.Context, schema store.DatabaseSchema) (store.BackupSchemaCheck, error) {
+	return s.backups.CheckBackupSchema(ctx, schema)
+}
+
+func (s *Store) RestoreDatabase(ctx context.Context, schema store.DatabaseSchema, open store.BackupTableReader) error {
+	return s.backups.RestoreDatabase(ctx, schema, open)
+}
+
+func (s *Store) RecordAuditEvent(ctx context.Context, event store.NewAuditEvent) error {
+	return s.audit.RecordAuditEvent(ctx, event)
+}
+
+func (s *Store) ListAuditEvents(ctx context.Context, filter store.AuditEventFilter) ([]store.AuditEvent, int, error) {
+	return s.audit.ListAuditEvents(ctx, filter)
+}
+
+func (s *Store) GetAuditSettings(ctx context.Context) (store.AuditSettings, error) {
+	return s.audit.GetAuditSettings(ctx)
+}
+
+func (s *Store) PatchAuditSettings(ctx context.Context, patch store.AuditSettingsPatch) (store.AuditSettings, error) {
+	return s.audit.PatchAuditSettings(ctx, patch)
+}
+
+func (s *Store) PruneAuditEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.audit.PruneAuditEvents(ctx, cutoff)
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, tenant store.Tenant) ([]store.Webhook, error) {
+	return s.webhooks.ListWebhooks(ctx, tenant)
+}
+
+func (s *Store) GetWebhook(ctx context.Context, tenant store.Tenant, id string) (*store.Webhook, error) {
+	return s.webhooks.GetWebhook(ctx, tenant, id)
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, tenant store.Tenant, input store.NewWebhook) (*store.Webhook, error) {
+	return s.webhooks.CreateWebhook(ctx, tenant, input)
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, tenant store.Tenant, id string, patch store.WebhookPatch) (*store.Webhook, error) {
+	return s.webhooks.UpdateWebhook(ctx, tenant, id, patch)
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, tenant store.Tenant, id string) error {
+	return s.webhooks.DeleteWebhook(ctx, tenant, id)
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, tenant store.Tenant, webhookID string, limit int) ([]store.WebhookDelivery, error) {
+	return s.webhooks.ListWebhookDeliveries(ctx, tenant, webhookID, limit)
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.WebhookDispatch, error) {
+	return s.webhooks.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, id string, attempt store.WebhookAttempt) error {
+	return s.webhooks.CompleteWebhookDelivery(ctx, id, attempt)
+}
+
+func (s *Store) PruneWebhookDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.webhooks.PruneWebhookDeliveries(ctx, cutoff)
+}
+
+func (s *Store) GetNotificationPreferences(ctx context.Context, tenant store.Tenant, userID string) (*store.NotificationPreferences, error) {
+	return s.notifications.GetNotificationPreferences(ctx, tenant, userID)
+}
+
+func (s *Store) UpdateNotificationPreferences(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	patch store.NotificationPreferencesPatch,
+) (*store.NotificationPreferences, error) {
+	return s.notifications.UpdateNotificationPreferences(ctx, tenant, userID, patch)
+}
+
+func (s *Store) ListNotificationChannels(ctx context.Context, tenant store.Tenant, userID string) ([]store.NotificationChannel, error) {
+	return s.notifications.ListNotificationChannels(ctx, tenant, userID)
+}
+
+func (s *Store) OpenNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) (*store.NotificationChannel, string, error) {
+	return s.notifications.OpenNotificationChannel(ctx, tenant, userID, id)
+}
+
+func (s *Store) CreateNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	input store.NewNotificationChannel,
+) (*store.NotificationChannel, error) {
+	return s.notifications.CreateNotificationChannel(ctx, tenant, userID, input)
+}
+
+func (s *Store) UpdateNotificationChannel(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, id string,
+	patch store.NotificationChannelPatch,
+) (*store.NotificationChannel, error) {
+	return s.notifications.UpdateNotificationChannel(ctx, tenant, userID, id, patch)
+}
+
+func (s *Store) DeleteNotificationChannel(ctx context.Context, tenant store.Tenant, userID, id string) error {
+	return s.notifications.DeleteNotificationChannel(ctx, tenant, userID, id)
+}
+
+func (s *Store) ListDigestSubscriptions(ctx context.Context) ([]store.DigestSubscription, error) {
+	return s.notifications.ListDigestSubscriptions(ctx)
+}
+
+func (s *Store) GetSpendDigest(ctx context.Context, tenant store.Tenant, from, to time.Time) (*store.SpendDigest, error) {
+	return s.notifications.GetSpendDigest(ctx, tenant, from, to)
+}
+
+func (s *Store) QueueDigest(ctx context.Context, tenant store.Tenant, userID string, dueAt time.Time, digest store.SpendDigest) (bool, error) {
+	return s.notifications.QueueDigest(ctx, tenant, userID, dueAt, digest)
+}
+
+func (s *Store) ClaimNotificationDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.NotificationDispatch, error) {
+	return s.notifications.ClaimNotificationDeliveries(ctx, limit, lease)
+}
+
+func (s *Store) CompleteNotificationDelivery(ctx context.Context, id string, attempt store.NotificationAttempt) error {
+	return s.notifications.CompleteNotificationDelivery(ctx, id, attempt)
+}
+
+func (s *Store) PruneNotificationDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.notifications.PruneNotificationDeliveries(ctx, cutoff)
+}
+
+func (s *Store) CountSealedSecrets(ctx context.Context, kind store.SealedSecretKind) (int64, error) {
+	return s.secrets.CountSealedSecrets(ctx, kind)
+}
+
+func (s *Store) ResealSecrets(ctx context.Context, kind store.SealedSecretKind, after string, limit int) (store.ResealBatch, error) {
+	return s.secrets.ResealSecrets(ctx, kind, after, limit)
+}
+
+// BulkUpdateTransactions applies one set of changes to transactions selected
+// by ID or by filter, inside a single database transaction.
+func (s *Store) BulkUpdateTransactions(
+	ctx context.Context,
+	tenant store.Tenant,
+	input store.BulkTransactionInput,
+) (store.BulkTransactionResult, error) {
+	return s.txns.BulkUpdateTransactions(ctx, tenant, input)
+}
+
+// ListTransactionHistory returns the logged changes of a transaction, newest
+// first.
+func (s *Store) ListTransactionHistory(ctx context.Context, tenant store.Tenant, id string) ([]store.TransactionChange, error) {
+	return s.txns.ListTransactionHistory(ctx, tenant, id)
+}
+
+// RevertChangeBatch undoes every change logged under batchID that has not been
+// overwritten since.
+func (s *Store) RevertChangeBatch(ctx context.Context, tenant store.Tenant, batchID string) (store.ChangeBatchRevert, error) {
+	return s.txns.RevertChangeBatch(ctx, tenant, batchID)
+}
+
+// SetTransactionSplits replaces the allocations of a transaction. Splits must
+// sum to the transaction amount; an empty list removes the split.
+func (s *Store) SetTransactionSplits(ctx context.Context, tenant store.Tenant, id string, splits []store.TransactionSplitInput) error {
+	return s.txns.SetTransactionSplits(ctx, tenant, id, splits)
+}
+
 // UpdateDescription sets the user-provided description on a transaction.
 func (s *Store) UpdateDescription(ctx context.Context, tenant store.Tenant, id, description string) error {
 	return s.txns.UpdateDescription(ctx, tenant, id, description)
@@ -601,6 +975,56 @@ func (s *Store) SeedPredefinedRules(ctx context.Context, rules []store.RuleRow)
 	return s.rules.SeedPredefinedRules(ctx, rules)
 }
 
+// RecordLLMUsage persists one LLM router call for a tenant.
+func (s *Store) RecordLLMUsage(ctx context.Context, tenant store.Tenant, record store.LLMUsageRecord) error {
+	return s.llmUsage.RecordLLMUsage(ctx, tenant, record)
+}
+
+// SumLLMUsageTokens returns the tenant's total tokens consumed since the given time.
+func (s *Store) SumLLMUsageTokens(ctx context.Context, tenant store.Tenant, since time.Time) (int64, error) {
+	return s.llmUsage.SumLLMUsageTokens(ctx, tenant, since)
+}
+
+// ListLLMUsage returns LLM usage aggregated by period, tenant, provider, and model.
+func (s *Store) ListLLMUsage(ctx context.Context, filter store.LLMUsageFilter) ([]store.LLMUsageBucket, error) {
+	return s.llmUsage.ListLLMUsage(ctx, filter)
+}
+
+// GetLLMUsageQuota returns the tenant's token quota; missing quotas are unlimited.
+func (s *Store) GetLLMUsageQuota(ctx context.Context, tenant store.Tenant) (store.LLMUsageQuota, error) {
+	return s.llmUsage.GetLLMUsageQuota(ctx, tenant)
+}
+
+// SetLLMUsageQuota replaces the tenant's token quota.
+func (s *Store) SetLLMUsageQuota(ctx context.Context, tenant store.Tenant, quota store.LLMUsageQuota) (store.LLMUsageQuota, error) {
+	return s.llmUsage.SetLLMUsageQuota(ctx, tenant, quota)
+}
+
+// CreateLLMPromptVersion stores a new prompt version, optionally activating it.
+func (s *Store) CreateLLMPromptVersion(ctx context.Context, input store.CreateLLMPromptVersionInput) (store.LLMPromptVersion, error) {
+	return s.llmPrompts.CreateLLMPromptVersion(ctx, input)
+}
+
+// ListLLMPromptVersions returns the stored versions of a prompt, newest first.
+func (s *Store) ListLLMPromptVersions(ctx context.Context, workflow, purpose string) ([]store.LLMPromptVersion, error) {
+	return s.llmPrompts.ListLLMPromptVersions(ctx, workflow, purpose)
+}
+
+// GetActiveLLMPromptVersion returns the active override for a prompt, if any.
+func (s *Store) GetActiveLLMPromptVersion(ctx context.Context, workflow, purpose string) (store.LLMPromptVersion, bool, error) {
+	return s.llmPrompts.GetActiveLLMPromptVersion(ctx, workflow, purpose)
+}
+
+// ActivateLLMPromptVersion makes one stored version the active override.
+func (s *Store) ActivateLLMPromptVersion(ctx context.Context, workflow, purpose string, version int) (store.LLMPromptVersion, error) {
+	return s.llmPrompts.ActivateLLMPromptVersion(ctx, workflow, purpose, version)
+}
+
+// DeactivateLLMPromptVersions reverts a prompt to its embedded definition.
+func (s *Store) DeactivateLLMPromptVersions(ctx context.Context, workflow, purpose string) error {
+	return s.llmPrompts.DeactivateLLMPromptVersions(ctx, workflow, purpose)
+}
+
 // RecordExtractionDiagnostic persists a failed extraction attempt for a tenant.
 func (s *Store) RecordExtractionDiagnostic(ctx context.Context, tenant store.Tenant, diagnostic api.ExtractionDiagnostic) error {
 	return s.diag.RecordExtractionDiagnostic(ctx, tenant, diagnostic)
@@ -751,3 +1175,133 @@ func (s *Store) GetCommunityURL(ctx context.Context) (string, error) {
 func (s *Store) SetCommunityURL(ctx context.Context, url string) error {
 	return s.runtime.SetCommunityURL(ctx, url)
 }
+
+// CreateSharedLedger creates a ledger owned by the creator and invites the given emails.
+func (s *Store) CreateSharedLedger(ctx context.Context, userID string, input store.CreateSharedLedgerInput) (store.SharedLedger, error) {
+	return s.ledgers.CreateSharedLedger(ctx, userID, input)
+}
+
+// ListSharedLedgers returns the ledgers the user belongs to.
+func (s *Store) ListSharedLedgers(ctx context.Context, userID string) ([]store.SharedLedger, error) {
+	return s.ledgers.ListSharedLedgers(ctx, userID)
+}
+
+// GetSharedLedger returns one ledger the user belongs to.
+func (s *Store) GetSharedLedger(ctx context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	return s.ledgers.GetSharedLedger(ctx, userID, ledgerID)
+}
+
+// InviteSharedLedgerMember invites an instance user to a ledger the user owns.
+func (s *Store) InviteSharedLedgerMember(ctx context.Context, userID, ledgerID, email string) error {
+	return s.ledgers.InviteSharedLedgerMember(ctx, userID, ledgerID, email)
+}
+
+// ListSharedLedgerInvitations returns the user's pending ledger invitations.
+func (s *Store) ListSharedLedgerInvitations(ctx context.Context, userID string) ([]store.SharedLedgerInvitation, error) {
+	return s.ledgers.ListSharedLedgerInvitations(ctx, userID)
+}
+
+// AcceptSharedLedgerInvitation makes the user a member of a ledger they were invited to.
+func (s *Store) AcceptSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) (store.SharedLedger, error) {
+	return s.ledgers.AcceptSharedLedgerInvitation(ctx, userID, ledgerID)
+}
+
+// DeclineSharedLedgerInvitation discards the user's invitation to a ledger.
+func (s *Store) DeclineSharedLedgerInvitation(ctx context.Context, userID, ledgerID string) error {
+	return s.ledgers.DeclineSharedLedgerInvitation(ctx, userID, ledgerID)
+}
+
+// ShareExpense shares one of the user's transactions or splits with ledger members.
+func (s *Store) ShareExpense(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID, ledgerID string,
+	input store.ShareExpenseInput,
+) (store.SharedExpense, error) {
+	return s.ledgers.ShareExpense(ctx, tenant, userID, ledgerID, input)
+}
+
+// ListSharedExpenses returns the expenses shared in a ledger.
+func (s *Store) ListSharedExpenses(ctx context.Context, userID, ledgerID string) ([]store.SharedExpense, error) {
+	return s.ledgers.ListSharedExpenses(ctx, userID, ledgerID)
+}
+
+// DeleteSharedExpense unshares an expense the user paid for.
+func (s *Store) DeleteSharedExpense(ctx context.Context, userID, ledgerID, expenseID string) error {
+	return s.ledgers.DeleteSharedExpense(ctx, userID, ledgerID, expenseID)
+}
+
+// RecordSettlement records a payment between two ledger members.
+func (s *Store) RecordSettlement(ctx context.Context, userID, ledgerID string, input store.RecordSettlementInput) (store.Settlement, error) {
+	return s.ledgers.RecordSettlement(ctx, userID, ledgerID, input)
+}
+
+// ListSettlements returns the settlements recorded in a ledger.
+func (s *Store) ListSettlements(ctx context.Context, userID, ledgerID string) ([]store.Settlement, error) {
+	return s.ledgers.ListSettlements(ctx, userID, ledgerID)
+}
+
+// ListLedgerBalances returns each member's net balance per currency.
+func (s *Store) ListLedgerBalances(ctx context.Context, userID, ledgerID string) ([]store.LedgerBalance, error) {
+	return s.ledgers.ListLedgerBalances(ctx, userID, ledgerID)
+}
+
+// CreateTenant creates a tenant owned by the given user.
+func (s *Store) CreateTenant(ctx context.Context, ownerUserID string, input store.CreateTenantInput) (store.TenantMembership, error) {
+	return s.tenants.CreateTenant(ctx, ownerUserID, input)
+}
+
+// ListActiveTenants returns the tenants that have an enabled member.
+func (s *Store) ListActiveTenants(ctx context.Context) ([]store.Tenant, error) {
+	return s.tenants.ListActiveTenants(ctx)
+}
+
+// ListTenantMemberships returns the tenants a user can select.
+func (s *Store) ListTenantMemberships(ctx context.Context, userID string) ([]store.TenantMembership, error) {
+	return s.tenants.ListTenantMemberships(ctx, userID)
+}
+
+// GetTenantMembership returns a user's membership in one tenant.
+func (s *Store) GetTenantMembership(ctx context.Context, tenant store.Tenant, userID string) (store.TenantMembership, error) {
+	return s.tenants.GetTenantMembership(ctx, tenant, userID)
+}
+
+// ListTenantMembers returns the users with access to a tenant.
+func (s *Store) ListTenantMembers(ctx context.Context, tenant store.Tenant) ([]store.TenantMember, error) {
+	return s.tenants.ListTenantMembers(ctx, tenant)
+}
+
+// InviteTenantMember invites an instance user to a tenant with a role.
+func (s *Store) InviteTenantMember(ctx context.Context, tenant store.Tenant, invitedBy, email string, role store.TenantRole) error {
+	return s.tenants.InviteTenantMember(ctx, tenant, invitedBy, email, role)
+}
+
+// ListTenantInvitations returns the tenants a user is invited to.
+func (s *Store) ListTenantInvitations(ctx context.Context, userID string) ([]store.TenantInvitation, error) {
+	return s.tenants.ListTenantInvitations(ctx, userID)
+}
+
+// AcceptTenantInvitation makes the user a member of a tenant they were invited to.
+func (s *Store) AcceptTenantInvitation(ctx context.Context, tenant store.Tenant, userID string) (store.TenantMembership, error) {
+	return s.tenants.AcceptTenantInvitation(ctx, tenant, userID)
+}
+
+// DeclineTenantInvitation discards the user's invitation to a tenant.
+func (s *Store) DeclineTenantInvitation(ctx context.Context, tenant store.Tenant, userID string) error {
+	return s.tenants.DeclineTenantInvitation(ctx, tenant, userID)
+}
+
+// UpdateTenantMemberRole changes a member's role in a tenant.
+func (s *Store) UpdateTenantMemberRole(ctx context.Context, tenant store.Tenant, userID string, role store.TenantRole) (store.TenantMember, error) {
+	return s.tenants.UpdateTenantMemberRole(ctx, tenant, userID, role)
+}
+
+// RemoveTenantMember revokes a user's access to a tenant.
+func (s *Store) RemoveTenantMember(ctx context.Context, tenant store.Tenant, userID string) error {
+	return s.tenants.RemoveTenantMember(ctx, tenant, userID)
+}
+
+// SetSessionTenant selects the tenant a browser session works in.
+func (s *Store) SetSessionTenant(ctx context.Context, sessionID string, tenant store.Tenant) error {
+	return s.tenants.SetSessionTenant(ctx, sessionID, tenant)
+}
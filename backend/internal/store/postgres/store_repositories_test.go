@@ -37,15 +37,26 @@ func newInstrumentedTestStore(t *testing.T) *instrumentedTestStore {
 	scope := observability.NewScope(logger, "test.store")
 	return &instrumentedTestStore{
 		Store: instrumented.NewStore(instrumented.StoreDeps{
-			Auth:         ts.Store,
-			Analytics:    ts.Store,
-			Community:    ts.Store,
-			Diagnostics:  ts.Store,
-			Rules:        ts.Store,
-			Runtime:      ts.Store,
-			Scanning:     ts.Store,
-			Taxonomy:     ts.Store,
-			Transactions: ts.Store,
+			Auth:          ts.Store,
+			Analytics:     ts.Store,
+			Archives:      ts.Store,
+			Backups:       ts.Store,
+			Attachments:   ts.Store,
+			Audit:         ts.Store,
+			Community:     ts.Store,
+			Diagnostics:   ts.Store,
+			LLMUsage:      ts.Store,
+			LLMPrompts:    ts.Store,
+			MFA:           ts.Store,
+			Notifications: ts.Store,
+			Rules:         ts.Store,
+			Runtime:       ts.Store,
+			Scanning:      ts.Store,
+			SharedLedgers: ts.Store,
+			Taxonomy:      ts.Store,
+			Tenants:       ts.Store,
+			Transactions:  ts.Store,
+			Webhooks:      ts.Store,
 		}, scope, logger),
 		base: ts,
 		logs: logs,
@@ -18,6 +18,8 @@ import (
 	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
 
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/blob"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/internal/store/postgres/migrations"
 	"github.com/ArionMiles/expensor/backend/pkg/api"
@@ -85,9 +87,15 @@ func newTestStoreWithLogger(t *testing.T, logs *bytes.Buffer) *testStore {
 		logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	}
 
+	blobs, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		_ = ctr.Terminate(ctx)
+		t.Fatalf("failed to create blob store: %v", err)
+	}
 	st, err := New(ctx, Options{
 		Config:   cfg,
 		Security: config.Security{SecretKey: bytes.Repeat([]byte{4}, 32)},
+		Blobs:    blobs,
 		Logger:   logger,
 	})
 	if err != nil {
@@ -386,6 +394,144 @@ func TestLLMProviderRuntimeStoresTenantConfigCredentialsAndActiveProvider(t *tes
 	}
 }
 
+func TestResealSecretsMovesValuesToActiveKey(t *testing.T) {
+	ts := newTestStore(t)
+	defer ts.cleanup()
+	ctx := context.Background()
+
+	user := createRuntimeTestUser(t, ts, "reseal@example.com")
+	tenant := store.Tenant{ID: user.TenantID}
+	if err := ts.SetReaderToken(ctx, tenant, "gmail", []byte(`{"access_token":"old-key"}`)); err != nil {
+		t.Fatalf("SetReaderToken() error = %v", err)
+	}
+	if err := ts.SetLLMProviderCredentials(ctx, tenant, "test-provider", []byte(`{"api_key":"old-key"}`)); err != nil {
+		t.Fatalf("SetLLMProviderCredentials() error = %v", err)
+	}
+	if err := ts.SetPendingTOTP(ctx, user.ID, []byte("totp-secret")); err != nil {
+		t.Fatalf("SetPendingTOTP() error = %v", err)
+	}
+	txID := seedTransaction(ctx, t, ts.Store, insertParams{Tenant: tenant, MessageID: "reseal-1", Amount: 10})
+	attachment, err := ts.CreateAttachment(ctx, tenant, txID, store.NewAttachment{
+		Filename: "receipt.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4"), Source: store.AttachmentSourceUpload,
+	})
+	if err != nil {
+		t.Fatalf("CreateAttachment() error = %v", err)
+	}
+
+	// Rotate: the key the store was opened with becomes a previous key.
+	box, err := auth.NewSecretBox(bytes.Repeat([]byte{5}, auth.SecretKeySize), bytes.Repeat([]byte{4}, auth.SecretKeySize))
+	if err != nil {
+		t.Fatalf("NewSecretBox() error = %v", err)
+	}
+	ts.secretBox = box
+	ts.initRepositories()
+
+	for _, kind := range store.SealedSecretKinds {
+		batch, err := ts.ResealSecrets(ctx, kind, "", 10)
+		if err != nil {
+			t.Fatalf("ResealSecrets(%s) error = %v", kind, err)
+		}
+		want := 1
+		if kind == store.SealedReaderClientSecret {
+			want = 0
+		}
+		if batch.Scanned != want || batch.Resealed != want || len(batch.Failures) != 0 || batch.Next != "" {
+			t.Fatalf("ResealSecrets(%s) = %+v, want %d resealed", kind, batch, want)
+		}
+		again, err := ts.ResealSecrets(ctx, kind, "", 10)
+		if err != nil || again.Resealed != 0 {
+			t.Fatalf("second ResealSecrets(%s) = %+v, %v; want nothing resealed", kind, again, err)
+		}
+	}
+
+	var ciphertext []byte
+	if err := poolForTest(ts.Store).QueryRow(ctx, `
+		SELECT oauth_token_ciphertext FROM reader_runtime WHERE tenant_id = $1 AND reader = 'gmail'
+	`, tenant.ID).Scan(&ciphertext); err != nil {
+		t.Fatalf("read ciphertext: %v", err)
+	}
+	if box.SealedKeyID(ciphertext) != box.ActiveKeyID() {
+		t.Fatalf("token sealed with %q, want active key %q", box.SealedKeyID(ciphertext), box.ActiveKeyID())
+	}
+
+	// Values must open with the new key alone.
+	box, err = auth.NewSecretBox(bytes.Repeat([]byte{5}, auth.SecretKeySize))
+	if err != nil {
+		t.Fatalf("NewSecretBox() error = %v", err)
+	}
+	ts.secretBox = box
+	ts.initRepositories()
+	if token, found, err := ts.GetReaderToken(ctx, tenant, "gmail"); err != nil || !found || string(token) != `{"access_token":"old-key"}` {
+		t.Fatalf("GetReaderToken() = %s, %v, %v", token, found, err)
+	}
+	if credentials, found, err := ts.GetLLMProviderCredentials(ctx, tenant, "test-provider"); err != nil || !found {
+		t.Fatalf("GetLLMProviderCredentials() = %s, %v, %v", credentials, found, err)
+	}
+	if factor, err := ts.GetTOTPFactor(ctx, user.ID); err != nil || string(factor.Secret) != "totp-secret" {
+		t.Fatalf("GetTOTPFactor() = %+v, %v", factor, err)
+	}
+	if _, data, err := ts.GetAttachment(ctx, tenant, txID, attachment.ID); err != nil || string(data) != "%PDF-1.4" {
+		t.Fatalf("GetAttachment() = %q, %v", data, err)
+	}
+}
+
+func TestAuditEventsRecordListAndPrune(t *testing.T) {
+	ts := newTestStore(t)
+	defer ts.cleanup()
+	ctx := context.Background()
+
+	admin := createRuntimeTestUser(t, ts, "audit-admin@example.com")
+	events := []store.NewAuditEvent{
+		{Action: store.AuditLogin, TargetType: "email", TargetID: "nobody@example.com", IPAddress: "192.0.2.1", Outcome: store.AuditDenied},
+		{
+			ActorUserID: admin.ID, TenantID: admin.TenantID, AuthMethod: "session", Action: store.AuditUserCreate,
+			TargetType: "user", TargetID: "user-b", RequestID: "req-1", IPAddress: "192.0.2.1", Outcome: store.AuditSuccess,
+		},
+		{ActorUserID: admin.ID, Action: store.AuditAccessTokenCreate, TargetType: "access_token", Outcome: store.AuditSuccess},
+	}
+	for _, event := range events {
+		if err := ts.RecordAuditEvent(ctx, event); err != nil {
+			t.Fatalf("RecordAuditEvent(%s) error = %v", event.Action, err)
+		}
+	}
+	if err := ts.RecordAuditEvent(ctx, store.NewAuditEvent{Action: store.AuditLogin}); errors.WhatKind(err) != errors.InvalidArgument {
+		t.Fatalf("RecordAuditEvent() without outcome error = %v, want invalid argument", err)
+	}
+
+	all, total, err := ts.ListAuditEvents(ctx, store.AuditEventFilter{})
+	if err != nil || total != 3 || len(all) != 3 {
+		t.Fatalf("ListAuditEvents() = %d events, total %d, err %v; want 3", len(all), total, err)
+	}
+	byActor, total, err := ts.ListAuditEvents(ctx, store.AuditEventFilter{ActorUserID: admin.ID, Outcome: store.AuditSuccess, PageSize: 1})
+	if err != nil || total != 2 || len(byActor) != 1 {
+		t.Fatalf("ListAuditEvents(actor) = %d events, total %d, err %v; want one page of 2", len(byActor), total, err)
+	}
+	created, _, err := ts.ListAuditEvents(ctx, store.AuditEventFilter{RequestID: "req-1"})
+	if err != nil || len(created) != 1 {
+		t.Fatalf("ListAuditEvents(request) = %v, %v", created, err)
+	}
+	if got := created[0]; got.Action != store.AuditUserCreate || got.ActorEmail != admin.Email || got.TenantID != admin.TenantID ||
+		got.TargetID != "user-b" || got.AuthMethod != "session" || got.IPAddress != "192.0.2.1" {
+		t.Fatalf("event = %#v", got)
+	}
+
+	settings, err := ts.GetAuditSettings(ctx)
+	if err != nil || settings.RetentionDays != 365 {
+		t.Fatalf("GetAuditSettings() = %#v, %v; want 365 days", settings, err)
+	}
+	days := 0
+	if settings, err = ts.PatchAuditSettings(ctx, store.AuditSettingsPatch{RetentionDays: &days}); err != nil || settings.RetentionDays != 0 {
+		t.Fatalf("PatchAuditSettings() = %#v, %v; want 0 days", settings, err)
+	}
+
+	if removed, err := ts.PruneAuditEvents(ctx, time.Now().Add(-time.Hour)); err != nil || removed != 0 {
+		t.Fatalf("PruneAuditEvents(past) = %d, %v; want nothing removed", removed, err)
+	}
+	if removed, err := ts.PruneAuditEvents(ctx, time.Now().Add(time.Hour)); err != nil || removed != 3 {
+		t.Fatalf("PruneAuditEvents(future) = %d, %v; want 3 removed", removed, err)
+	}
+}
+
 func TestProcessedMessagesAreTenantScoped(t *testing.T) {
 	ts := newTestStore(t)
 	defer ts.cleanup()
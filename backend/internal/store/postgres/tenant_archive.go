@@ -0,0 +1,1073 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// archiveRepository copies a tenant's data out to a TenantArchive and back
+// in, possibly on another instance.
+type archiveRepository struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+func newArchiveRepository(deps repositoryDependencies) *archiveRepository {
+	return &archiveRepository{
+		pool: deps.pool,
+		now:  deps.now,
+	}
+}
+
+func (r *archiveRepository) ExportTenantArchive(ctx context.Context, tenant store.Tenant) (*store.TenantArchive, error) {
+	const op = "postgres.archive.export"
+
+	// Read every section from one snapshot so they agree with each other.
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, errors.E(op, "beginning export transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	archive := &store.TenantArchive{
+		Format:     store.TenantArchiveFormat,
+		Version:    store.TenantArchiveVersion,
+		ExportedAt: r.now().UTC(),
+		Tenant:     store.ArchiveTenant{ID: tenant.ID},
+		Excluded:   store.TenantArchiveExcluded,
+	}
+	err = tx.QueryRow(ctx, `SELECT name FROM tenants WHERE id = $1`, tenant.ID).Scan(&archive.Tenant.Name)
+	if err != nil && !errorsIsNoRows(err) {
+		return nil, errors.E(op, "fetching tenant", err)
+	}
+
+	for _, export := range []func(context.Context, pgx.Tx, store.Tenant, *store.TenantArchive) error{
+		exportArchivePreferences,
+		exportArchiveTaxonomy,
+		exportArchiveRules,
+		exportArchiveReaders,
+		exportArchiveDiagnostics,
+		exportArchiveTransactions,
+	} {
+		if err := export(ctx, tx, tenant, archive); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+	archive.CountSections()
+	return archive, nil
+}
+
+// queryArchive runs sql in tx and scans each row with scan. It never returns
+// a nil slice, so empty sections encode as [] rather than null.
+func queryArchive[T any](ctx context.Context, tx pgx.Tx, what, sql string, scan func(pgx.Rows, *T) error, args ...any) ([]T, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s: %w", what, err)
+	}
+	defer rows.Close()
+
+	result := []T{}
+	for rows.Next() {
+		var item T
+		if err := scan(rows, &item); err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", what, err)
+		}
+		result = append(result, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating %s: %w", what, err)
+	}
+	return result, nil
+}
+
+func exportArchivePreferences(ctx context.Context, tx pgx.Tx, tenant store.Tenant, archive *store.TenantArchive) error {
+	type setting struct{ key, value string }
+	settings, err := queryArchive(ctx, tx, "preferences",
+		`SELECT key, value FROM app_config WHERE tenant_id = $1 ORDER BY key`,
+		func(rows pgx.Rows, s *setting) error { return rows.Scan(&s.key, &s.value) },
+		tenant.ID)
+	if err != nil {
+		return err
+	}
+	archive.Preferences = make(map[string]string, len(settings))
+	for _, s := range settings {
+		if store.ArchivedPreference(s.key) {
+			archive.Preferences[s.key] = s.value
+		}
+	}
+	return nil
+}
+
+func exportArchiveTaxonomy(ctx context.Context, tx pgx.Tx, tenant store.Tenant, archive *store.TenantArchive) error {
+	var err error
+	archive.Labels, err = queryArchive(ctx, tx, "labels",
+		`SELECT name, color, created_at FROM labels WHERE tenant_id = $1 ORDER BY name`,
+		func(rows pgx.Rows, l *store.Label) error { return rows.Scan(&l.Name, &l.Color, &l.CreatedAt) },
+		tenant.ID)
+	if err != nil {
+		return err
+	}
+	archive.Categories, err = queryArchive(ctx, tx, "categories",
+		`SELECT name, COALESCE(description, ''), is_default FROM categories WHERE tenant_id = $1 ORDER BY name`,
+		func(rows pgx.Rows, c *store.Category) error { return rows.Scan(&c.Name, &c.Description, &c.IsDefault) },
+		tenant.ID)
+	if err != nil {
+		return err
+	}
+	archive.Buckets, err = queryArchive(ctx, tx, "buckets",
+		`SELECT name, COALESCE(description, ''), is_default FROM buckets WHERE tenant_id = $1 ORDER BY name`,
+		func(rows pgx.Rows, b *store.Bucket) error { return rows.Scan(&b.Name, &b.Description, &b.IsDefault) },
+		tenant.ID)
+	if err != nil {
+		return err
+	}
+	archive.LabelMappings, err = queryArchive(ctx, tx, "label mappings",
+		`SELECT label, merchant_pattern FROM label_merchants WHERE tenant_id = $1 ORDER BY label, merchant_pattern`,
+		func(rows pgx.Rows, m *store.ArchiveLabelMapping) error { return rows.Scan(&m.Label, &m.Pattern) },
+		tenant.ID)
+	if err != nil {
+		return err
+	}
+	archive.MerchantMappings, err = queryArchive(ctx, tx, "merchant mappings",
+		`SELECT fragment, COALESCE(category, ''), COALESCE(bucket, '')
+		 FROM merchant_categories
+		 WHERE tenant_id = $1 AND (category IS NOT NULL OR bucket IS NOT NULL)
+		 ORDER BY fragment`,
+		func(rows pgx.Rows, m *store.ArchiveMerchantMapping) error {
+			return rows.Scan(&m.Pattern, &m.Category, &m.Bucket)
+		},
+		tenant.ID)
+	if err != nil {
+		return err
+	}
+	archive.MutedMerchants, err = queryArchive(ctx, tx, "muted merchants",
+		`SELECT id::text, pattern, COALESCE(reason, ''), created_at FROM muted_merchants WHERE tenant_id = $1 ORDER BY pattern`,
+		func(rows pgx.Rows, m *store.MutedMerchant) error {
+			return rows.Scan(&m.ID, &m.Pattern, &m.Reason, &m.CreatedAt)
+		},
+		tenant.ID)
+	return err
+}
+
+func exportArchiveRules(ctx context.Context, tx pgx.Tx, tenant store.Tenant, archive *store.TenantArchive) error {
+	rows, err := tx.Query(ctx,
+		`SELECT `+ruleColumns+` FROM rules WHERE tenant_id = $1 AND predefined = false ORDER BY name`,
+		tenant.ID)
+	if err != nil {
+		return fmt.Errorf("querying rules: %w", err)
+	}
+	defer rows.Close()
+	archive.Rules, err = scanRuleRows(rows)
+	return err
+}
+
+func exportArchiveReaders(ctx context.Context, tx pgx.Tx, tenant store.Tenant, archive *store.TenantArchive) error {
+	var err error
+	// Only the plain config column: client secrets and OAuth tokens are
+	// encrypted with this instance's key and stay behind.
+	archive.Readers, err = queryArchive(ctx, tx, "readers",
+		`SELECT reader, config FROM reader_runtime WHERE tenant_id = $1 AND config IS NOT NULL ORDER BY reader`,
+		func(rows pgx.Rows, reader *store.ArchiveReader) error {
+			var config []byte
+			if err := rows.Scan(&reader.Reader, &config); err != nil {
+				return err
+			}
+			reader.Config = json.RawMessage(config)
+			return nil
+		},
+		tenant.ID)
+	return err
+}
+
+func exportArchiveDiagnostics(ctx context.Context, tx pgx.Tx, tenant store.Tenant, archive *store.TenantArchive) error {
+	rows, err := tx.Query(ctx,
+		`SELECT `+diagnosticColumns+` FROM extraction_diagnostics WHERE tenant_id = $1 ORDER BY created_at, id`,
+		tenant.ID)
+	if err != nil {
+		return fmt.Errorf("querying diagnostics: %w", err)
+	}
+	defer rows.Close()
+	archive.Diagnostics, err = scanDiagnosticRows(rows)
+	return err
+}
+
+func exportArchiveTransactions(ctx context.Context, tx pgx.Tx, tenant store.Tenant, archive *store.TenantArchive) error {
+	rows, err := tx.Query(ctx, `
+		SELECT t.id, t.message_id, t.amount, t.currency,
+		       t.original_amount, t.original_currency, t.exchange_rate,
+		       t.timestamp, t.merchant_info,
+		       COALESCE(t.category, ''), COALESCE(t.bucket, ''),
+		       t.source, COALESCE(t.source_type, ''), COALESCE(t.source_label, ''), COALESCE(t.bank, ''),
+		       COALESCE(t.description, ''), t.muted, t.muted_by_merchant, COALESCE(t.mute_reason,''), t.created_at, t.updated_at
+		FROM transactions t
+		WHERE t.tenant_id = $1
+		ORDER BY t.timestamp, t.id
+	`, tenant.ID)
+	if err != nil {
+		return fmt.Errorf("querying transactions: %w", err)
+	}
+	txns, err := scanTransactions(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	archive.Transactions = make([]store.ArchiveTransaction, len(txns))
+	index := make(map[string]*store.ArchiveTransaction, len(txns))
+	for i := range txns {
+		archive.Transactions[i].Transaction = txns[i]
+		index[txns[i].ID] = &archive.Transactions[i]
+	}
+
+	type label struct{ transactionID, name string }
+	labels, err := queryArchive(ctx, tx, "transaction labels", `
+		SELECT tl.transaction_id::text, tl.label
+		FROM transaction_labels tl
+		JOIN transactions t ON t.id = tl.transaction_id
+		WHERE t.tenant_id = $1
+		ORDER BY tl.transaction_id, tl.label
+	`, func(rows pgx.Rows, l *label) error { return rows.Scan(&l.transactionID, &l.name) }, tenant.ID)
+	if err != nil {
+		return err
+	}
+	for _, l := range labels {
+		if txn, ok := index[l.transactionID]; ok {
+			txn.Labels = append(txn.Labels, l.name)
+		}
+	}
+
+	type labelSource struct {
+		transactionID string
+		store.ArchiveLabelSource
+	}
+	sources, err := queryArchive(ctx, tx, "transaction label sources", `
+		SELECT tls.transaction_id::text, tls.label, tls.source_type, tls.merchant_pattern
+		FROM transaction_label_sources tls
+		JOIN transactions t ON t.id = tls.transaction_id
+		WHERE t.tenant_id = $1
+		ORDER BY tls.transaction_id, tls.label, tls.source_type, tls.merchant_pattern
+	`, func(rows pgx.Rows, s *labelSource) error {
+		return rows.Scan(&s.transactionID, &s.Label, &s.Type, &s.MerchantPattern)
+	}, tenant.ID)
+	if err != nil {
+		return err
+	}
+	for _, s := range sources {
+		if txn, ok := index[s.transactionID]; ok {
+			txn.LabelSources = append(txn.LabelSources, s.ArchiveLabelSource)
+		}
+	}
+
+	type split struct {
+		transactionID string
+		store.TransactionSplit
+	}
+	splits, err := queryArchive(ctx, tx, "transaction splits", `
+		SELECT s.transaction_id::text, s.id::text, s.amount, COALESCE(s.category, ''), COALESCE(s.bucket, ''),
+		       s.labels, COALESCE(s.counterparty, '')
+		FROM transaction_splits s
+		JOIN transactions t ON t.id = s.transaction_id
+		WHERE t.tenant_id = $1
+		ORDER BY s.transaction_id, s.position
+	`, func(rows pgx.Rows, s *split) error {
+		return rows.Scan(&s.transactionID, &s.ID, &s.Amount, &s.Category, &s.Bucket, &s.Labels, &s.Counterparty)
+	}, tenant.ID)
+	if err != nil {
+		return err
+	}
+	for _, s := range splits {
+		if s.Labels == nil {
+			s.Labels = []string{}
+		}
+		if txn, ok := index[s.transactionID]; ok {
+			txn.Splits = append(txn.Splits, s.TransactionSplit)
+		}
+	}
+
+	type email struct {
+		transactionID string
+		store.ArchiveEmail
+	}
+	emails, err := queryArchive(ctx, tx, "transaction email content", `
+		SELECT e.transaction_id::text, e.subject, e.body
+		FROM transaction_email_content e
+		JOIN transactions t ON t.id = e.transaction_id
+		WHERE t.tenant_id = $1
+	`, func(rows pgx.Rows, e *email) error { return rows.Scan(&e.transactionID, &e.Subject, &e.Body) }, tenant.ID)
+	if err != nil {
+		return err
+	}
+	for _, e := range emails {
+		if txn, ok := index[e.transactionID]; ok {
+			txn.Email = &e.ArchiveEmail
+		}
+	}
+	return nil
+}
+
+// archiveImport holds the state of one ImportTenantArchive call.
+type archiveImport struct {
+	tx     pgx.Tx
+	tenant store.Tenant
+	policy store.ArchiveConflictPolicy
+	result store.TenantImportResult
+	// ruleIDs maps archived rule IDs to the IDs of the tenant's rules with the
+	// same name, so diagnostics keep pointing at their rule.
+	ruleIDs map[string]string
+}
+
+func (r *archiveRepository) ImportTenantArchive(
+	ctx context.Context,
+	tenant store.Tenant,
+	archive store.TenantArchive,
+	policy store.ArchiveConflictPolicy,
+) (store.TenantImportResult, error) {
+	const op = "postgres.archive.import"
+
+	if !store.ValidArchiveConflictPolicy(policy) {
+		return store.TenantImportResult{}, errors.E(op, errors.InvalidArgument,
+			errors.User("Conflict policy must be skip, overwrite, or merge."))
+	}
+	if err := store.ValidateTenantArchive(archive); err != nil {
+		return store.TenantImportResult{}, err
+	}
+
+	// The whole archive is one change batch, so an import can be reverted
+	// from the transaction history like any other bulk edit.
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseImport)
+	if err != nil {
+		return store.TenantImportResult{}, errors.E(op, "beginning import transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	imp := &archiveImport{
+		tx:     tx,
+		tenant: tenant,
+		policy: policy,
+		result: store.TenantImportResult{
+			Policy:   policy,
+			Sections: make(map[string]store.ArchiveSectionResult),
+		},
+		ruleIDs: make(map[string]string),
+	}
+	for _, section := range []struct {
+		name string
+		run  func(context.Context, store.TenantArchive) error
+	}{
+		{store.ArchiveSectionPreferences, imp.importPreferences},
+		{store.ArchiveSectionLabels, imp.importLabels},
+		{store.ArchiveSectionCategories, imp.importCategories},
+		{store.ArchiveSectionBuckets, imp.importBuckets},
+		{store.ArchiveSectionLabelMappings, imp.importLabelMappings},
+		{store.ArchiveSectionMerchantMappings, imp.importMerchantMappings},
+		{store.ArchiveSectionMutedMerchants, imp.importMutedMerchants},
+		{store.ArchiveSectionRules, imp.importRules},
+		{store.ArchiveSectionReaders, imp.importReaders},
+		{store.ArchiveSectionDiagnostics, imp.importDiagnostics},
+		{store.ArchiveSectionTransactions, imp.importTransactions},
+	} {
+		imp.result.Sections[section.name] = store.ArchiveSectionResult{}
+		if err := section.run(ctx, archive); err != nil {
+			return store.TenantImportResult{}, errors.E(op, fmt.Sprintf("importing %s", section.name), err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return store.TenantImportResult{}, errors.E(op, "committing import transaction", err)
+	}
+	return imp.result, nil
+}
+
+type archiveOutcome int
+
+const (
+	archiveCreated archiveOutcome = iota
+	archiveUpdated
+	archiveSkipped
+)
+
+func (imp *archiveImport) count(section string, outcome archiveOutcome) {
+	counts := imp.result.Sections[section]
+	switch outcome {
+	case archiveCreated:
+		counts.Created++
+	case archiveUpdated:
+		counts.Updated++
+	default:
+		counts.Skipped++
+	}
+	imp.result.Sections[section] = counts
+}
+
+// onConflict returns the ON CONFLICT action for the import's policy, given
+// the SET clauses overwrite and merge use. An empty clause leaves existing
+// rows alone under that policy.
+func (imp *archiveImport) onConflict(overwrite, merge string) string {
+	set := ""
+	switch imp.policy {
+	case store.ArchiveConflictOverwrite:
+		set = overwrite
+	case store.ArchiveConflictMerge:
+		set = merge
+	}
+	if set == "" {
+		return "DO NOTHING"
+	}
+	return "DO UPDATE SET " + set
+}
+
+// upsert runs an INSERT ... ON CONFLICT statement ending in
+// RETURNING (xmax = 0) and counts its outcome. A statement that returns no
+// row hit a conflict its policy left alone.
+func (imp *archiveImport) upsert(ctx context.Context, section, sql string, args ...any) (archiveOutcome, error) {
+	var inserted bool
+	err := imp.tx.QueryRow(ctx, sql, args...).Scan(&inserted)
+	outcome := archiveUpdated
+	switch {
+	case errorsIsNoRows(err):
+		outcome = archiveSkipped
+	case err != nil:
+		return archiveSkipped, err
+	case inserted:
+		outcome = archiveCreated
+	}
+	imp.count(section, outcome)
+	return outcome, nil
+}
+
+// assignIDs returns the ID to store each archived item under: the archived
+// ID when it is a UUID no row of table uses yet, a fresh UUID otherwise.
+// IDs must be globally unique, so archives restored next to their source
+// tenant always get new ones.
+func (imp *archiveImport) assignIDs(ctx context.Context, table string, archived []string) ([]string, error) {
+	candidates := make([]string, 0, len(archived))
+	for _, id := range archived {
+		if _, err := uuid.Parse(id); err == nil {
+			candidates = append(candidates, id)
+		}
+	}
+	taken := make(map[string]bool)
+	if len(candidates) > 0 {
+		rows, err := imp.tx.Query(ctx, fmt.Sprintf(`SELECT id::text FROM %s WHERE id = ANY($1::uuid[])`, table), candidates)
+		if err != nil {
+			return nil, fmt.Errorf("checking %s IDs: %w", table, err)
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, fmt.Errorf("checking %s IDs: %w", table, err)
+		}
+		for _, id := range ids {
+			taken[id] = true
+		}
+	}
+
+	assigned := make([]string, len(archived))
+	for i, id := range archived {
+		if parsed, err := uuid.Parse(id); err == nil && !taken[parsed.String()] {
+			assigned[i] = parsed.String()
+			taken[parsed.String()] = true
+			continue
+		}
+		assigned[i] = uuid.NewString()
+	}
+	return assigned, nil
+}
+
+// remapped records that a created item was stored under a different ID.
+func (imp *archiveImport) remapped(outcome archiveOutcome, archivedID, id string) {
+	if outcome == archiveCreated && archivedID != "" && archivedID != id {
+		imp.result.RemappedIDs++
+	}
+}
+
+// archiveTime passes zero times as NULL so the column default applies.
+func archiveTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func (imp *archiveImport) importPreferences(ctx context.Context, archive store.TenantArchive) error {
+	keys := make([]string, 0, len(archive.Preferences))
+	for key := range archive.Preferences {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	sql := `INSERT INTO app_config (tenant_id, key, value) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, key) WHERE tenant_id IS NOT NULL ` + imp.onConflict(
+		`value = EXCLUDED.value WHERE app_config.value IS DISTINCT FROM EXCLUDED.value`,
+		`value = EXCLUDED.value WHERE app_config.value = ''`,
+	) + ` RETURNING (xmax = 0)`
+	for _, key := range keys {
+		if !store.ArchivedPreference(key) {
+			imp.count(store.ArchiveSectionPreferences, archiveSkipped)
+			continue
+		}
+		if _, err := imp.upsert(ctx, store.ArchiveSectionPreferences, sql, imp.tenant.ID, key, archive.Preferences[key]); err != nil {
+			return fmt.Errorf("preference %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) importLabels(ctx context.Context, archive store.TenantArchive) error {
+	sql := `INSERT INTO labels (tenant_id, name, color, created_at)
+		VALUES ($1, $2, COALESCE(NULLIF($3, ''), '#6366f1'), COALESCE($4::timestamptz, NOW()))
+		ON CONFLICT (tenant_id, name) WHERE tenant_id IS NOT NULL ` + imp.onConflict(
+		`color = EXCLUDED.color WHERE labels.color IS DISTINCT FROM EXCLUDED.color`,
+		``,
+	) + ` RETURNING (xmax = 0)`
+	for _, label := range archive.Labels {
+		if _, err := imp.upsert(ctx, store.ArchiveSectionLabels, sql, imp.tenant.ID, label.Name, label.Color, archiveTime(label.CreatedAt)); err != nil {
+			return fmt.Errorf("label %q: %w", label.Name, err)
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) importCategories(ctx context.Context, archive store.TenantArchive) error {
+	for _, category := range archive.Categories {
+		if err := imp.importTaxonomyItem(ctx, store.ArchiveSectionCategories, "categories", category.Name, category.Description, category.IsDefault); err != nil {
+			return fmt.Errorf("category %q: %w", category.Name, err)
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) importBuckets(ctx context.Context, archive store.TenantArchive) error {
+	for _, bucket := range archive.Buckets {
+		if err := imp.importTaxonomyItem(ctx, store.ArchiveSectionBuckets, "buckets", bucket.Name, bucket.Description, bucket.IsDefault); err != nil {
+			return fmt.Errorf("bucket %q: %w", bucket.Name, err)
+		}
+	}
+	return nil
+}
+
+// importTaxonomyItem writes one category or bucket; both tables share a shape.
+func (imp *archiveImport) importTaxonomyItem(ctx context.Context, section, table, name, description string, isDefault bool) error {
+	sql := fmt.Sprintf(`INSERT INTO %[1]s (tenant_id, name, description, is_default)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (tenant_id, name) WHERE tenant_id IS NOT NULL `, table) + imp.onConflict(
+		fmt.Sprintf(`description = EXCLUDED.description WHERE %s.description IS DISTINCT FROM EXCLUDED.description`, table),
+		fmt.Sprintf(`description = EXCLUDED.description
+			WHERE COALESCE(%s.description, '') = '' AND EXCLUDED.description IS NOT NULL`, table),
+	) + ` RETURNING (xmax = 0)`
+	_, err := imp.upsert(ctx, section, sql, imp.tenant.ID, name, description, isDefault)
+	return err
+}
+
+func (imp *archiveImport) importLabelMappings(ctx context.Context, archive store.TenantArchive) error {
+	// A mapping is only its key, so every policy keeps existing ones and
+	// adds the rest.
+	const sql = `INSERT INTO label_merchants (tenant_id, label, merchant_pattern) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, label, merchant_pattern) WHERE tenant_id IS NOT NULL DO NOTHING
+		RETURNING (xmax = 0)`
+	for _, mapping := range archive.LabelMappings {
+		if _, err := imp.upsert(ctx, store.ArchiveSectionLabelMappings, sql, imp.tenant.ID, mapping.Label, mapping.Pattern); err != nil {
+			return fmt.Errorf("label mapping %q: %w", mapping.Pattern, err)
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) importMerchantMappings(ctx context.Context, archive store.TenantArchive) error {
+	sql := `INSERT INTO merchant_categories (tenant_id, fragment, category, bucket, user_locked)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), true)
+		` + merchantCategoryConflictClause + ` ` + imp.onConflict(
+		`category = EXCLUDED.category, bucket = EXCLUDED.bucket, user_locked = true, updated_at = NOW()
+			WHERE (merchant_categories.category, merchant_categories.bucket) IS DISTINCT FROM (EXCLUDED.category, EXCLUDED.bucket)`,
+		`category = COALESCE(merchant_categories.category, EXCLUDED.category),
+			bucket = COALESCE(merchant_categories.bucket, EXCLUDED.bucket),
+			user_locked = true, updated_at = NOW()
+			WHERE (merchant_categories.category IS NULL AND EXCLUDED.category IS NOT NULL)
+			   OR (merchant_categories.bucket IS NULL AND EXCLUDED.bucket IS NOT NULL)`,
+	) + ` RETURNING (xmax = 0)`
+	for _, mapping := range archive.MerchantMappings {
+		if _, err := imp.upsert(ctx, store.ArchiveSectionMerchantMappings, sql,
+			imp.tenant.ID, mapping.Pattern, mapping.Category, mapping.Bucket); err != nil {
+			return fmt.Errorf("merchant mapping %q: %w", mapping.Pattern, err)
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) importMutedMerchants(ctx context.Context, archive store.TenantArchive) error {
+	archivedIDs := make([]string, len(archive.MutedMerchants))
+	for i, muted := range archive.MutedMerchants {
+		archivedIDs[i] = muted.ID
+	}
+	ids, err := imp.assignIDs(ctx, "muted_merchants", archivedIDs)
+	if err != nil {
+		return err
+	}
+
+	sql := `INSERT INTO muted_merchants (id, tenant_id, pattern, reason, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), COALESCE($5::timestamptz, NOW()))
+		ON CONFLICT (tenant_id, pattern) WHERE tenant_id IS NOT NULL ` + imp.onConflict(
+		`reason = EXCLUDED.reason WHERE muted_merchants.reason IS DISTINCT FROM EXCLUDED.reason`,
+		`reason = EXCLUDED.reason WHERE COALESCE(muted_merchants.reason, '') = '' AND EXCLUDED.reason IS NOT NULL`,
+	) + ` RETURNING (xmax = 0)`
+	for i, muted := range archive.MutedMerchants {
+		outcome, err := imp.upsert(ctx, store.ArchiveSectionMutedMerchants, sql,
+			ids[i], imp.tenant.ID, muted.Pattern, muted.Reason, archiveTime(muted.CreatedAt))
+		if err != nil {
+			return fmt.Errorf("muted merchant %q: %w", muted.Pattern, err)
+		}
+		imp.remapped(outcome, muted.ID, ids[i])
+	}
+	return nil
+}
+
+func (imp *archiveImport) importRules(ctx context.Context, archive store.TenantArchive) error {
+	archivedIDs := make([]string, len(archive.Rules))
+	for i, rule := range archive.Rules {
+		archivedIDs[i] = rule.ID
+	}
+	ids, err := imp.assignIDs(ctx, "rules", archivedIDs)
+	if err != nil {
+		return err
+	}
+
+	// Rules are matched by name and kept whole: merging two sets of regexes
+	// would produce a rule neither side wrote, so merge leaves them alone.
+	sql := `INSERT INTO rules (
+			id, tenant_id, name, sender_email, sender_emails, subject_contains, amount_regex, merchant_regex,
+			currency_regex, transaction_source, source_type, source_label, bank, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE($14::timestamptz, NOW()))
+		` + importUserRulesConflictClause + ` ` + imp.onConflict(
+		`sender_email = EXCLUDED.sender_email, sender_emails = EXCLUDED.sender_emails,
+			subject_contains = EXCLUDED.subject_contains, amount_regex = EXCLUDED.amount_regex,
+			merchant_regex = EXCLUDED.merchant_regex, currency_regex = EXCLUDED.currency_regex,
+			transaction_source = EXCLUDED.transaction_source, source_type = EXCLUDED.source_type,
+			source_label = EXCLUDED.source_label, bank = EXCLUDED.bank, updated_at = NOW()
+			WHERE (rules.sender_emails, rules.subject_contains, rules.amount_regex, rules.merchant_regex,
+			       rules.currency_regex, rules.source_type, rules.source_label, rules.bank)
+			   IS DISTINCT FROM
+			      (EXCLUDED.sender_emails, EXCLUDED.subject_contains, EXCLUDED.amount_regex, EXCLUDED.merchant_regex,
+			       EXCLUDED.currency_regex, EXCLUDED.source_type, EXCLUDED.source_label, EXCLUDED.bank)`,
+		``,
+	) + ` RETURNING (xmax = 0)`
+	for i, rule := range archive.Rules {
+		outcome, err := imp.upsert(ctx, store.ArchiveSectionRules, sql,
+			ids[i], imp.tenant.ID, rule.Name, primarySender(rule), normalizedRuleSenders(rule), rule.SubjectContains,
+			rule.AmountRegex, rule.MerchantRegex, rule.CurrencyRegex,
+			ruleSourceLabel(rule), rule.SourceType, ruleSourceLabel(rule), rule.Bank, archiveTime(rule.CreatedAt),
+		)
+		if err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		imp.remapped(outcome, rule.ID, ids[i])
+
+		var id string
+		if err := imp.tx.QueryRow(ctx,
+			`SELECT id::text FROM rules WHERE tenant_id = $1 AND name = $2 AND predefined = false`,
+			imp.tenant.ID, rule.Name,
+		).Scan(&id); err != nil {
+			return fmt.Errorf("resolving rule %q: %w", rule.Name, err)
+		}
+		if rule.ID != "" {
+			imp.ruleIDs[rule.ID] = id
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) importReaders(ctx context.Context, archive store.TenantArchive) error {
+	sql := `INSERT INTO reader_runtime (tenant_id, reader, config) VALUES ($1, $2, $3::jsonb)
+		ON CONFLICT (tenant_id, reader) WHERE tenant_id IS NOT NULL ` + imp.onConflict(
+		`config = EXCLUDED.config, updated_at = NOW() WHERE reader_runtime.config IS DISTINCT FROM EXCLUDED.config`,
+		`config = EXCLUDED.config, updated_at = NOW() WHERE reader_runtime.config IS NULL`,
+	) + ` RETURNING (xmax = 0)`
+	for _, reader := range archive.Readers {
+		if len(reader.Config) == 0 || string(reader.Config) == "null" {
+			imp.count(store.ArchiveSectionReaders, archiveSkipped)
+			continue
+		}
+		if _, err := imp.upsert(ctx, store.ArchiveSectionReaders, sql, imp.tenant.ID, reader.Reader, string(reader.Config)); err != nil {
+			return fmt.Errorf("reader %q: %w", reader.Reader, err)
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) importDiagnostics(ctx context.Context, archive store.TenantArchive) error {
+	archivedIDs := make([]string, len(archive.Diagnostics))
+	for i, diagnostic := range archive.Diagnostics {
+		archivedIDs[i] = diagnostic.ID
+	}
+	ids, err := imp.assignIDs(ctx, "extraction_diagnostics", archivedIDs)
+	if err != nil {
+		return err
+	}
+
+	// A diagnostic records one failed extraction, identified by what was read,
+	// with which rule, and when. Only its triage state can change.
+	const findSQL = `
+		SELECT id::text FROM extraction_diagnostics
+		WHERE tenant_id = $1 AND reader = $2 AND COALESCE(message_id, '') = $3 AND rule_name = $4 AND created_at = $5`
+	const overwriteSQL = `
+		UPDATE extraction_diagnostics d
+		SET status = $2, resolved_at = $3, failure_reasons = $4, updated_at = NOW()
+		WHERE d.id = $1
+		  AND (d.status, d.resolved_at, d.failure_reasons) IS DISTINCT FROM ($2, $3::timestamptz, $4::text[])
+		  AND NOT ($2 = 'open' AND d.message_id IS NOT NULL AND EXISTS (
+		      SELECT 1 FROM extraction_diagnostics o
+		      WHERE o.tenant_id = d.tenant_id AND o.reader = d.reader AND o.message_id = d.message_id
+		        AND o.rule_name = d.rule_name AND o.status = 'open' AND o.id <> d.id))`
+	const insertSQL = `
+		INSERT INTO extraction_diagnostics (
+			id, tenant_id, status, reader, message_id, source, sender, sender_email, subject, email_body,
+			received_at, snippet, rule_id, rule_name, amount_regex, merchant_regex, currency_regex, failure_reasons,
+			created_at, resolved_at
+		)
+		VALUES (
+			$1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12,
+			COALESCE($13::uuid, (
+				SELECT id FROM rules WHERE name = $14 AND (predefined OR tenant_id = $2) ORDER BY predefined LIMIT 1
+			)),
+			$14, $15, $16, $17, $18, COALESCE($19::timestamptz, NOW()), $20
+		)
+		ON CONFLICT (tenant_id, reader, message_id, rule_name) WHERE tenant_id IS NOT NULL AND status = 'open' AND message_id IS NOT NULL
+		DO NOTHING
+		RETURNING (xmax = 0)`
+
+	for i, diagnostic := range archive.Diagnostics {
+		var existing string
+		err := imp.tx.QueryRow(ctx, findSQL,
+			imp.tenant.ID, diagnostic.Reader, diagnostic.MessageID, diagnostic.RuleName, diagnostic.CreatedAt,
+		).Scan(&existing)
+		switch {
+		case err == nil:
+			outcome := archiveSkipped
+			if imp.policy == store.ArchiveConflictOverwrite {
+				tag, err := imp.tx.Exec(ctx, overwriteSQL,
+					existing, diagnostic.Status, diagnostic.ResolvedAt, diagnosticFailureReasons(diagnostic.FailureReasons))
+				if err != nil {
+					return fmt.Errorf("updating diagnostic %q: %w", diagnostic.MessageID, err)
+				}
+				if tag.RowsAffected() > 0 {
+					outcome = archiveUpdated
+				}
+			}
+			imp.count(store.ArchiveSectionDiagnostics, outcome)
+			continue
+		case !errorsIsNoRows(err):
+			return fmt.Errorf("matching diagnostic %q: %w", diagnostic.MessageID, err)
+		}
+
+		var ruleID any
+		if diagnostic.RuleID != nil {
+			if id, ok := imp.ruleIDs[*diagnostic.RuleID]; ok {
+				ruleID = id
+			}
+		}
+		outcome, err := imp.upsert(ctx, store.ArchiveSectionDiagnostics, insertSQL,
+			ids[i], imp.tenant.ID, diagnostic.Status, diagnostic.Reader, diagnostic.MessageID,
+			diagnostic.Source, diagnostic.Sender, diagnostic.SenderEmail, diagnostic.Subject, diagnostic.EmailBody,
+			diagnostic.ReceivedAt, diagnostic.Snippet, ruleID, diagnostic.RuleName,
+			diagnostic.AmountRegex, diagnostic.MerchantRegex, diagnostic.CurrencyRegex,
+			diagnosticFailureReasons(diagnostic.FailureReasons), archiveTime(diagnostic.CreatedAt), diagnostic.ResolvedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("diagnostic %q: %w", diagnostic.MessageID, err)
+		}
+		imp.remapped(outcome, diagnostic.ID, ids[i])
+	}
+	return nil
+}
+
+func (imp *archiveImport) importTransactions(ctx context.Context, archive store.TenantArchive) error {
+	messageIDs := make([]string, len(archive.Transactions))
+	for i, txn := range archive.Transactions {
+		messageIDs[i] = txn.MessageID
+	}
+	rows, err := imp.tx.Query(ctx,
+		`SELECT message_id, id::text FROM transactions WHERE tenant_id = $1 AND message_id = ANY($2)`,
+		imp.tenant.ID, messageIDs)
+	if err != nil {
+		return fmt.Errorf("matching transactions: %w", err)
+	}
+	existing := make(map[string]string)
+	for rows.Next() {
+		var messageID, id string
+		if err := rows.Scan(&messageID, &id); err != nil {
+			rows.Close()
+			return fmt.Errorf("matching transactions: %w", err)
+		}
+		existing[messageID] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("matching transactions: %w", err)
+	}
+
+	var newTxns []store.ArchiveTransaction
+	var archivedIDs []string
+	for _, txn := range archive.Transactions {
+		if _, ok := existing[txn.MessageID]; !ok {
+			newTxns = append(newTxns, txn)
+			archivedIDs = append(archivedIDs, txn.ID)
+		}
+	}
+	ids, err := imp.assignIDs(ctx, "transactions", archivedIDs)
+	if err != nil {
+		return err
+	}
+	for i, txn := range newTxns {
+		if err := imp.insertTransaction(ctx, ids[i], txn); err != nil {
+			return fmt.Errorf("transaction %q: %w", txn.MessageID, err)
+		}
+		imp.count(store.ArchiveSectionTransactions, archiveCreated)
+		imp.remapped(archiveCreated, txn.ID, ids[i])
+	}
+
+	for _, txn := range archive.Transactions {
+		id, ok := existing[txn.MessageID]
+		if !ok {
+			continue
+		}
+		var err error
+		switch imp.policy {
+		case store.ArchiveConflictOverwrite:
+			err = imp.overwriteTransaction(ctx, id, txn)
+		case store.ArchiveConflictMerge:
+			err = imp.mergeTransaction(ctx, id, txn)
+		default:
+			imp.count(store.ArchiveSectionTransactions, archiveSkipped)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("transaction %q: %w", txn.MessageID, err)
+		}
+		imp.count(store.ArchiveSectionTransactions, archiveUpdated)
+	}
+	return nil
+}
+
+func (imp *archiveImport) insertTransaction(ctx context.Context, id string, txn store.ArchiveTransaction) error {
+	if _, err := imp.tx.Exec(ctx, `
+		INSERT INTO transactions (
+			id, tenant_id, message_id, amount, currency, original_amount, original_currency, exchange_rate,
+			timestamp, merchant_info, category, bucket, source, source_type, source_label, bank, description,
+			muted, muted_by_merchant, mute_reason, created_at
+		)
+		VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''), $13, $14, $15, $16,
+			NULLIF($17, ''), $18, $19, NULLIF($20, ''), COALESCE($21::timestamptz, NOW())
+		)`,
+		id, imp.tenant.ID, txn.MessageID, txn.Amount, txn.Currency, txn.OriginalAmount, txn.OriginalCurrency, txn.ExchangeRate,
+		txn.Timestamp, txn.MerchantInfo, txn.Category, txn.Bucket,
+		txn.Source.Display(), txn.Source.Type, txn.Source.Label, txn.Source.Bank, txn.Description,
+		txn.Muted, txn.MutedByMerchant, txn.MuteReason, archiveTime(txn.CreatedAt),
+	); err != nil {
+		return fmt.Errorf("inserting: %w", err)
+	}
+	if err := imp.addLabels(ctx, id, txn); err != nil {
+		return err
+	}
+	if err := imp.insertSplits(ctx, id, txn.Splits); err != nil {
+		return err
+	}
+	return imp.writeEmail(ctx, id, txn.Email, false)
+}
+
+func (imp *archiveImport) overwriteTransaction(ctx context.Context, id string, txn store.ArchiveTransaction) error {
+	state, err := imp.lockTransaction(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Splits allocated to a shared ledger keep the ledger balanced; leave the
+	// amount and splits of such a transaction as they are.
+	amount := txn.Amount
+	if state.sharedSplits {
+		amount = state.amount
+	}
+	if _, err := imp.tx.Exec(ctx, `
+		UPDATE transactions
+		SET amount = $3, currency = $4, original_amount = $5, original_currency = $6, exchange_rate = $7,
+		    timestamp = $8, merchant_info = $9, category = NULLIF($10, ''), bucket = NULLIF($11, ''),
+		    source = $12, source_type = $13, source_label = $14, bank = $15, description = NULLIF($16, ''),
+		    muted = $17, muted_by_merchant = $18, mute_reason = NULLIF($19, ''), updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2`,
+		id, imp.tenant.ID, amount, txn.Currency, txn.OriginalAmount, txn.OriginalCurrency, txn.ExchangeRate,
+		txn.Timestamp, txn.MerchantInfo, txn.Category, txn.Bucket,
+		txn.Source.Display(), txn.Source.Type, txn.Source.Label, txn.Source.Bank, txn.Description,
+		txn.Muted, txn.MutedByMerchant, txn.MuteReason,
+	); err != nil {
+		return fmt.Errorf("updating: %w", err)
+	}
+
+	if _, err := imp.tx.Exec(ctx, `DELETE FROM transaction_label_sources WHERE transaction_id = $1`, id); err != nil {
+		return fmt.Errorf("clearing label sources: %w", err)
+	}
+	labels := txn.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	if _, err := imp.tx.Exec(ctx,
+		`DELETE FROM transaction_labels WHERE transaction_id = $1 AND NOT (label = ANY($2::text[]))`, id, labels,
+	); err != nil {
+		return fmt.Errorf("clearing labels: %w", err)
+	}
+	if err := imp.addLabels(ctx, id, txn); err != nil {
+		return err
+	}
+
+	if !state.sharedSplits {
+		if _, err := imp.tx.Exec(ctx, `DELETE FROM transaction_splits WHERE transaction_id = $1`, id); err != nil {
+			return fmt.Errorf("clearing splits: %w", err)
+		}
+		if err := imp.insertSplits(ctx, id, txn.Splits); err != nil {
+			return err
+		}
+	}
+	return imp.writeEmail(ctx, id, txn.Email, true)
+}
+
+func (imp *archiveImport) mergeTransaction(ctx context.Context, id string, txn store.ArchiveTransaction) error {
+	state, err := imp.lockTransaction(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := imp.tx.Exec(ctx, `
+		UPDATE transactions
+		SET category = COALESCE(NULLIF(category, ''), NULLIF($3, '')),
+		    bucket = COALESCE(NULLIF(bucket, ''), NULLIF($4, '')),
+		    description = COALESCE(NULLIF(description, ''), NULLIF($5, ''))
+		WHERE id = $1 AND tenant_id = $2
+		  AND ((COALESCE(category, '') = '' AND $3 <> '')
+		    OR (COALESCE(bucket, '') = '' AND $4 <> '')
+		    OR (COALESCE(description, '') = '' AND $5 <> ''))`,
+		id, imp.tenant.ID, txn.Category, txn.Bucket, txn.Description,
+	); err != nil {
+		return fmt.Errorf("filling in fields: %w", err)
+	}
+	if err := imp.addLabels(ctx, id, txn); err != nil {
+		return err
+	}
+	// Archived splits only fit when they divide the same amount.
+	if !state.hasSplits && math.Round(state.amount*100) == math.Round(txn.Amount*100) {
+		if err := imp.insertSplits(ctx, id, txn.Splits); err != nil {
+			return err
+		}
+	}
+	return imp.writeEmail(ctx, id, txn.Email, false)
+}
+
+type archiveTransactionState struct {
+	amount       float64
+	hasSplits    bool
+	sharedSplits bool
+}
+
+func (imp *archiveImport) lockTransaction(ctx context.Context, id string) (archiveTransactionState, error) {
+	var state archiveTransactionState
+	err := imp.tx.QueryRow(ctx, `
+		SELECT t.amount,
+		       EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id),
+		       EXISTS (SELECT 1 FROM shared_expenses e WHERE e.transaction_id = t.id AND e.split_id IS NOT NULL)
+		FROM transactions t
+		WHERE t.id = $1 AND t.tenant_id = $2
+		FOR UPDATE OF t`,
+		id, imp.tenant.ID,
+	).Scan(&state.amount, &state.hasSplits, &state.sharedSplits)
+	if err != nil {
+		return state, fmt.Errorf("locking: %w", err)
+	}
+	return state, nil
+}
+
+// addLabels adds txn's labels and the reasons they apply. Labels the archive
+// gives no reason for are treated as applied by hand.
+func (imp *archiveImport) addLabels(ctx context.Context, id string, txn store.ArchiveTransaction) error {
+	if len(txn.Labels) == 0 {
+		return nil
+	}
+	var names, types, patterns []string
+	explained := make(map[string]bool)
+	for _, source := range txn.LabelSources {
+		if !slices.Contains(txn.Labels, source.Label) {
+			continue
+		}
+		names = append(names, source.Label)
+		types = append(types, source.Type)
+		patterns = append(patterns, source.MerchantPattern)
+		explained[source.Label] = true
+	}
+	for _, label := range txn.Labels {
+		if !explained[label] {
+			names = append(names, label)
+			types = append(types, "manual")
+			patterns = append(patterns, "")
+		}
+	}
+
+	if _, err := imp.tx.Exec(ctx,
+		`INSERT INTO transaction_label_sources (transaction_id, label, source_type, merchant_pattern)
+		 SELECT $1, s.label, s.source_type, s.merchant_pattern
+		 FROM unnest($2::text[], $3::text[], $4::text[]) AS s(label, source_type, merchant_pattern)
+		 ON CONFLICT (transaction_id, label, source_type, merchant_pattern) DO NOTHING`,
+		id, names, types, patterns,
+	); err != nil {
+		return fmt.Errorf("adding label sources: %w", err)
+	}
+	if _, err := imp.tx.Exec(ctx,
+		`INSERT INTO transaction_labels (transaction_id, label)
+		 SELECT $1, unnest($2::text[])
+		 ON CONFLICT (transaction_id, label) DO NOTHING`,
+		id, txn.Labels,
+	); err != nil {
+		return fmt.Errorf("adding labels: %w", err)
+	}
+	return nil
+}
+
+func (imp *archiveImport) insertSplits(ctx context.Context, id string, splits []store.TransactionSplit) error {
+	for i, split := range splits {
+		labels := split.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		if _, err := imp.tx.Exec(ctx, `
+			INSERT INTO transaction_splits (transaction_id, position, amount, category, bucket, labels, counterparty)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, NULLIF($7, ''))
+		`, id, i, split.Amount, split.Category, split.Bucket, labels, split.Counterparty); err != nil {
+			return fmt.Errorf("inserting split: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeEmail stores the text of the transaction's source email. replace
+// overwrites text already stored; otherwise existing text is kept.
+func (imp *archiveImport) writeEmail(ctx context.Context, id string, email *store.ArchiveEmail, replace bool) error {
+	if email == nil {
+		return nil
+	}
+	action := `DO NOTHING`
+	if replace {
+		action = `DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body, updated_at = NOW()`
+	}
+	if _, err := imp.tx.Exec(ctx,
+		`INSERT INTO transaction_email_content (transaction_id, subject, body) VALUES ($1, $2, $3)
+		 ON CONFLICT (transaction_id) `+action,
+		id, email.Subject, email.Body,
+	); err != nil {
+		return fmt.Errorf("storing email content: %w", err)
+	}
+	return nil
+}
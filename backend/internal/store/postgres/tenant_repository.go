@@ -0,0 +1,482 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const tenantMembershipColumns = `t.id, t.name, m.role, t.id = m.user_id, m.created_at`
+
+type tenantRepository struct {
+	pool *pgxpool.Pool
+}
+
+func newTenantRepository(deps repositoryDependencies) *tenantRepository {
+	return &tenantRepository{pool: deps.pool}
+}
+
+func (r *tenantRepository) CreateTenant(ctx context.Context, ownerUserID string, input store.CreateTenantInput) (store.TenantMembership, error) {
+	const op = "postgres.tenants.create_tenant"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.TenantMembership{}, errors.E(op, "beginning create-tenant transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var tenantID string
+	if err := tx.QueryRow(ctx, `INSERT INTO tenants (name) VALUES ($1) RETURNING id`, input.Name).Scan(&tenantID); err != nil {
+		return store.TenantMembership{}, errors.E(op, "inserting tenant", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tenant_scanning_state (tenant_id, active_reader, enabled, state)
+		VALUES ($1, '', true, 'stopped')
+	`, tenantID); err != nil {
+		return store.TenantMembership{}, errors.E(op, "seeding tenant scanning state", err)
+	}
+	membership, err := scanTenantMembership(tx.QueryRow(ctx, `
+		WITH m AS (
+			INSERT INTO tenant_memberships (tenant_id, user_id, role)
+			VALUES ($1, $2, $3)
+			RETURNING tenant_id, user_id, role, created_at
+		)
+		SELECT `+tenantMembershipColumns+`
+		FROM m
+		JOIN tenants t ON t.id = m.tenant_id
+	`, tenantID, ownerUserID, store.TenantRoleOwner))
+	if err != nil {
+		return store.TenantMembership{}, errors.E(op, "inserting owner membership", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return store.TenantMembership{}, errors.E(op, "committing create-tenant transaction", err)
+	}
+	return membership, nil
+}
+
+func (r *tenantRepository) ListTenantMemberships(ctx context.Context, userID string) ([]store.TenantMembership, error) {
+	const op = "postgres.tenants.list_tenant_memberships"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+tenantMembershipColumns+`
+		FROM tenant_memberships m
+		JOIN tenants t ON t.id = m.tenant_id
+		WHERE m.user_id = $1
+		ORDER BY t.id = m.user_id DESC, t.name, t.id
+	`, userID)
+	if err != nil {
+		return nil, errors.E(op, "listing tenant memberships", err)
+	}
+	defer rows.Close()
+
+	memberships := make([]store.TenantMembership, 0)
+	for rows.Next() {
+		membership, err := scanTenantMembership(rows)
+		if err != nil {
+			return nil, errors.E(op, "scanning tenant membership", err)
+		}
+		memberships = append(memberships, membership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating tenant memberships", err)
+	}
+	return memberships, nil
+}
+
+func (r *tenantRepository) GetTenantMembership(ctx context.Context, tenant store.Tenant, userID string) (store.TenantMembership, error) {
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return store.TenantMembership{}, err
+	}
+	membership, err := scanTenantMembership(r.pool.QueryRow(ctx, `
+		SELECT `+tenantMembershipColumns+`
+		FROM tenant_memberships m
+		JOIN tenants t ON t.id = m.tenant_id
+		WHERE m.tenant_id = $1 AND m.user_id = $2
+	`, tenantID, userID))
+	if errorsIsNoRows(err) {
+		return store.TenantMembership{}, errors.E("store.tenants.get_membership", errors.NotFound, errors.User("tenant not found"))
+	}
+	if err != nil {
+		return store.TenantMembership{}, errors.E("postgres.tenants.get_tenant_membership", "fetching tenant membership", err)
+	}
+	return membership, nil
+}
+
+func (r *tenantRepository) ListTenantMembers(ctx context.Context, tenant store.Tenant) ([]store.TenantMember, error) {
+	const op = "postgres.tenants.list_tenant_members"
+
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT u.id, u.email, u.display_name, m.role, m.created_at
+		FROM tenant_memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.tenant_id = $1
+		ORDER BY m.created_at, u.email
+	`, tenantID)
+	if err != nil {
+		return nil, errors.E(op, "listing tenant members", err)
+	}
+	defer rows.Close()
+
+	members := make([]store.TenantMember, 0)
+	for rows.Next() {
+		var member store.TenantMember
+		if err := rows.Scan(&member.UserID, &member.Email, &member.DisplayName, &member.Role, &member.JoinedAt); err != nil {
+			return nil, errors.E(op, "scanning tenant member", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating tenant members", err)
+	}
+	return members, nil
+}
+
+func (r *tenantRepository) InviteTenantMember(
+	ctx context.Context,
+	tenant store.Tenant,
+	invitedBy, email string,
+	role store.TenantRole,
+) error {
+	const op = "postgres.tenants.invite_tenant_member"
+
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return err
+	}
+	if err := validateTenantRole(op, role); err != nil {
+		return err
+	}
+	// Inviting again replaces the pending role rather than failing, so the
+	// response is the same for new and repeated invitations.
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO tenant_invitations (tenant_id, user_id, role, invited_by)
+		SELECT $1::uuid, u.id, $3, $4::uuid
+		FROM users u
+		WHERE lower(u.email) = lower($2)
+		  AND u.disabled_at IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM tenant_memberships m WHERE m.tenant_id = $1::uuid AND m.user_id = u.id
+		  )
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, created_at = now()
+	`, tenantID, email, role, invitedBy); err != nil {
+		return errors.E(op, "inserting invitation", err)
+	}
+	return nil
+}
+
+func (r *tenantRepository) ListTenantInvitations(ctx context.Context, userID string) ([]store.TenantInvitation, error) {
+	const op = "postgres.tenants.list_tenant_invitations"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT t.id, t.name, i.role, COALESCE(i.invited_by::text, ''), COALESCE(u.display_name, ''), i.created_at
+		FROM tenant_invitations i
+		JOIN tenants t ON t.id = i.tenant_id
+		LEFT JOIN users u ON u.id = i.invited_by
+		WHERE i.user_id = $1
+		ORDER BY i.created_at DESC, t.id
+	`, userID)
+	if err != nil {
+		return nil, errors.E(op, "listing invitations", err)
+	}
+	defer rows.Close()
+
+	invitations := make([]store.TenantInvitation, 0)
+	for rows.Next() {
+		var inv store.TenantInvitation
+		if err := rows.Scan(&inv.TenantID, &inv.TenantName, &inv.Role, &inv.InvitedBy, &inv.InvitedByName, &inv.CreatedAt); err != nil {
+			return nil, errors.E(op, "scanning invitation", err)
+		}
+		invitations = append(invitations, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating invitations", err)
+	}
+	return invitations, nil
+}
+
+func (r *tenantRepository) AcceptTenantInvitation(ctx context.Context, tenant store.Tenant, userID string) (store.TenantMembership, error) {
+	const op = "postgres.tenants.accept_tenant_invitation"
+
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return store.TenantMembership{}, err
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.TenantMembership{}, errors.E(op, "beginning accept-invitation transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var role store.TenantRole
+	err = tx.QueryRow(ctx,
+		`DELETE FROM tenant_invitations WHERE tenant_id = $1 AND user_id = $2 RETURNING role`,
+		tenantID, userID,
+	).Scan(&role)
+	if errorsIsNoRows(err) {
+		return store.TenantMembership{}, errTenantInvitationNotFound()
+	}
+	if err != nil {
+		return store.TenantMembership{}, errors.E(op, "removing invitation", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tenant_memberships (tenant_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, user_id) DO NOTHING
+	`, tenantID, userID, role); err != nil {
+		return store.TenantMembership{}, errors.E(op, "adding tenant member", err)
+	}
+	membership, err := scanTenantMembership(tx.QueryRow(ctx, `
+		SELECT `+tenantMembershipColumns+`
+		FROM tenant_memberships m
+		JOIN tenants t ON t.id = m.tenant_id
+		WHERE m.tenant_id = $1 AND m.user_id = $2
+	`, tenantID, userID))
+	if err != nil {
+		return store.TenantMembership{}, errors.E(op, "fetching tenant membership", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return store.TenantMembership{}, errors.E(op, "committing accept-invitation transaction", err)
+	}
+	return membership, nil
+}
+
+func (r *tenantRepository) DeclineTenantInvitation(ctx context.Context, tenant store.Tenant, userID string) error {
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return err
+	}
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM tenant_invitations WHERE tenant_id = $1 AND user_id = $2`,
+		tenantID, userID,
+	)
+	if err != nil {
+		return errors.E("postgres.tenants.decline_tenant_invitation", "removing invitation", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errTenantInvitationNotFound()
+	}
+	return nil
+}
+
+func (r *tenantRepository) UpdateTenantMemberRole(
+	ctx context.Context,
+	tenant store.Tenant,
+	userID string,
+	role store.TenantRole,
+) (store.TenantMember, error) {
+	const op = "postgres.tenants.update_tenant_member_role"
+
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return store.TenantMember{}, err
+	}
+	if err := validateTenantRole(op, role); err != nil {
+		return store.TenantMember{}, err
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.TenantMember{}, errors.E(op, "beginning update-member transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	current, owners, err := lockTenantMember(ctx, tx, tenantID, userID)
+	if err != nil {
+		return store.TenantMember{}, err
+	}
+	if role != current && tenantID == userID {
+		return store.TenantMember{}, errPersonalTenantOwner(op)
+	}
+	if current == store.TenantRoleOwner && role != store.TenantRoleOwner && owners == 1 {
+		return store.TenantMember{}, errLastTenantOwner(op)
+	}
+	var member store.TenantMember
+	err = tx.QueryRow(ctx, `
+		WITH m AS (
+			UPDATE tenant_memberships
+			SET role = $3
+			WHERE tenant_id = $1 AND user_id = $2
+			RETURNING user_id, role, created_at
+		)
+		SELECT u.id, u.email, u.display_name, m.role, m.created_at
+		FROM m
+		JOIN users u ON u.id = m.user_id
+	`, tenantID, userID, role).Scan(&member.UserID, &member.Email, &member.DisplayName, &member.Role, &member.JoinedAt)
+	if err != nil {
+		return store.TenantMember{}, errors.E(op, "updating tenant member", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return store.TenantMember{}, errors.E(op, "committing update-member transaction", err)
+	}
+	return member, nil
+}
+
+func (r *tenantRepository) RemoveTenantMember(ctx context.Context, tenant store.Tenant, userID string) error {
+	const op = "postgres.tenants.remove_tenant_member"
+
+	tenantID, err := requireTenantID(tenant)
+	if err != nil {
+		return err
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return errors.E(op, "beginning remove-member transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	current, owners, err := lockTenantMember(ctx, tx, tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if tenantID == userID {
+		return errPersonalTenantOwner(op)
+	}
+	if current == store.TenantRoleOwner && owners == 1 {
+		return errLastTenantOwner(op)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM tenant_memberships WHERE tenant_id = $1 AND user_id = $2`,
+		tenantID, userID,
+	); err != nil {
+		return errors.E(op, "removing tenant member", err)
+	}
+	// Sessions that had the tenant selected go back to the personal tenant.
+	if _, err := tx.Exec(ctx,
+		`UPDATE sessions SET active_tenant_id = NULL WHERE user_id = $2 AND active_tenant_id = $1`,
+		tenantID, userID,
+	); err != nil {
+		return errors.E(op, "clearing session tenant", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.E(op, "committing remove-member transaction", err)
+	}
+	return nil
+}
+
+func (r *tenantRepository) ListActiveTenants(ctx context.Context) ([]store.Tenant, error) {
+	const op = "postgres.tenants.list_active_tenants"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT t.id
+		FROM tenants t
+		WHERE EXISTS (
+			SELECT 1
+			FROM tenant_memberships m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.tenant_id = t.id AND u.disabled_at IS NULL
+		)
+		ORDER BY t.created_at, t.id
+	`)
+	if err != nil {
+		return nil, errors.E(op, "listing active tenants", err)
+	}
+	defer rows.Close()
+
+	tenants := make([]store.Tenant, 0)
+	for rows.Next() {
+		var tenant store.Tenant
+		if err := rows.Scan(&tenant.ID); err != nil {
+			return nil, errors.E(op, "scanning tenant", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating tenants", err)
+	}
+	return tenants, nil
+}
+
+func (r *tenantRepository) SetSessionTenant(ctx context.Context, sessionID string, tenant store.Tenant) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE sessions
+		SET active_tenant_id = NULLIF($2, '')::uuid
+		WHERE id = $1 AND revoked_at IS NULL
+	`, sessionID, tenant.ID)
+	if err != nil {
+		return errors.E("postgres.tenants.set_session_tenant", "selecting session tenant", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.tenants.set_session_tenant", errors.NotFound, errors.User("session not found"))
+	}
+	return nil
+}
+
+// lockTenantMember locks the tenant's memberships for the rest of tx and
+// returns userID's role with the number of owners, so a concurrent change
+// cannot remove the last owner.
+func lockTenantMember(ctx context.Context, tx pgx.Tx, tenantID, userID string) (store.TenantRole, int, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT user_id, role FROM tenant_memberships WHERE tenant_id = $1 FOR UPDATE`,
+		tenantID,
+	)
+	if err != nil {
+		return "", 0, errors.E("postgres.tenants.lock_tenant_member", "locking tenant members", err)
+	}
+	defer rows.Close()
+
+	var (
+		role   store.TenantRole
+		owners int
+	)
+	for rows.Next() {
+		var memberID string
+		var memberRole store.TenantRole
+		if err := rows.Scan(&memberID, &memberRole); err != nil {
+			return "", 0, errors.E("postgres.tenants.lock_tenant_member", "scanning tenant member", err)
+		}
+		if memberRole == store.TenantRoleOwner {
+			owners++
+		}
+		if memberID == userID {
+			role = memberRole
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", 0, errors.E("postgres.tenants.lock_tenant_member", "iterating tenant members", err)
+	}
+	if role == "" {
+		return "", 0, errors.E("store.tenants.member", errors.NotFound, errors.User("tenant member not found"))
+	}
+	return role, owners, nil
+}
+
+func validateTenantRole(op string, role store.TenantRole) error {
+	switch role {
+	case store.TenantRoleOwner, store.TenantRoleEditor, store.TenantRoleViewer:
+		return nil
+	default:
+		return errors.E(op, errors.InvalidInput, errors.User("Tenant role must be owner, editor or viewer."))
+	}
+}
+
+func errTenantInvitationNotFound() error {
+	return errors.E("store.tenants.invitation", errors.NotFound, errors.User("tenant invitation not found"))
+}
+
+func errPersonalTenantOwner(op string) error {
+	return errors.E(op, errors.Conflict, errors.User("The owner of a personal tenant cannot be changed or removed."))
+}
+
+func errLastTenantOwner(op string) error {
+	return errors.E(op, errors.Conflict, errors.User("A tenant must keep at least one owner."))
+}
+
+func scanTenantMembership(row scanner) (store.TenantMembership, error) {
+	var m store.TenantMembership
+	if err := row.Scan(&m.TenantID, &m.TenantName, &m.Role, &m.Personal, &m.JoinedAt); err != nil {
+		return store.TenantMembership{}, err
+	}
+	return m, nil
+}
@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func (r *transactionsRepository) BulkUpdateTransactions(
+	ctx context.Context,
+	tenant store.Tenant,
+	input store.BulkTransactionInput,
+) (store.BulkTransactionResult, error) {
+	const op = "postgres.transactions.bulk_update_transactions"
+
+	if err := store.ValidateBulkTransactionInput(input); err != nil {
+		return store.BulkTransactionResult{}, err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.BulkTransactionResult{}, errors.E(op, "beginning bulk transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	batchID, err := startChangeBatch(ctx, tx, store.ChangeCauseBulkEdit)
+	if err != nil {
+		return store.BulkTransactionResult{}, errors.E(op, err)
+	}
+
+	var matched []string
+	if input.Filter != nil {
+		matched, err = lockFilteredTransactions(ctx, tx, tenant, *input.Filter)
+	} else {
+		matched, err = lockTransactionIDs(ctx, tx, tenant, input.IDs)
+	}
+	if err != nil {
+		return store.BulkTransactionResult{}, err
+	}
+
+	result := bulkRowResults(input, matched)
+	if input.DryRun || len(matched) == 0 {
+		return result, nil
+	}
+
+	if err := applyBulkChanges(ctx, tx, tenant, matched, input); err != nil {
+		return store.BulkTransactionResult{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return store.BulkTransactionResult{}, errors.E(op, "committing bulk transaction", err)
+	}
+	result.BatchID = batchID
+	return result, nil
+}
+
+// lockTransactionIDs locks the requested transactions that belong to tenant
+// and returns their IDs. Unknown and foreign IDs are left out.
+func lockTransactionIDs(ctx context.Context, tx pgx.Tx, tenant store.Tenant, ids []string) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id
+		FROM transactions
+		WHERE tenant_id = $1 AND id = ANY($2::uuid[])
+		ORDER BY id
+		FOR UPDATE
+	`, tenant.ID, ids)
+	if err != nil {
+		return nil, errors.E("postgres.transactions.lock_transaction_ids", "locking transactions", err)
+	}
+	return scanTransactionIDs(rows, "postgres.transactions.lock_transaction_ids")
+}
+
+// lockFilteredTransactions locks every tenant transaction matching f. Paging
+// is ignored; a filter matching more than store.MaxBulkTransactions rows is
+// rejected rather than silently truncated.
+func lockFilteredTransactions(ctx context.Context, tx pgx.Tx, tenant store.Tenant, f store.ListFilter) ([]string, error) {
+	where, args := buildListWhere(f)
+	args = append(args, tenant.ID)
+	where = combineWhere(fmt.Sprintf("t.tenant_id = $%d", len(args)), where)
+	args = append(args, store.MaxBulkTransactions+1)
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT x.id
+		FROM transactions x
+		WHERE x.id IN (SELECT t.id FROM transactions t%s%s)
+		ORDER BY x.id
+		LIMIT $%d
+		FOR UPDATE
+	`, joinLabel(f.Label), where, len(args)), args...)
+	if err != nil {
+		return nil, errors.E("postgres.transactions.lock_filtered_transactions", "locking filtered transactions", err)
+	}
+	ids, err := scanTransactionIDs(rows, "postgres.transactions.lock_filtered_transactions")
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > store.MaxBulkTransactions {
+		return nil, errors.E("store.transactions.bulk", errors.InvalidInput, errors.User(
+			"The filter matches more than "+strconv.Itoa(store.MaxBulkTransactions)+" transactions; narrow it down.",
+		))
+	}
+	return ids, nil
+}
+
+func scanTransactionIDs(rows pgx.Rows, op string) ([]string, error) {
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.E(op, "scanning transaction id", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating transaction ids", err)
+	}
+	return ids, nil
+}
+
+// bulkRowResults reports explicit IDs in request order, so callers can line
+// results up with what they sent, and filter matches in ID order.
+func bulkRowResults(input store.BulkTransactionInput, matched []string) store.BulkTransactionResult {
+	status := store.BulkRowUpdated
+	if input.DryRun {
+		status = store.BulkRowMatched
+	}
+	result := store.BulkTransactionResult{DryRun: input.DryRun, Matched: len(matched)}
+	if !input.DryRun {
+		result.Updated = len(matched)
+	}
+	if input.Filter != nil {
+		result.Results = make([]store.BulkTransactionRowResult, 0, len(matched))
+		for _, id := range matched {
+			result.Results = append(result.Results, store.BulkTransactionRowResult{ID: id, Status: status})
+		}
+		return result
+	}
+
+	found := make(map[string]bool, len(matched))
+	for _, id := range matched {
+		found[id] = true
+	}
+	seen := make(map[string]bool, len(input.IDs))
+	result.Results = make([]store.BulkTransactionRowResult, 0, len(input.IDs))
+	for _, id := range input.IDs {
+		key := strings.ToLower(id)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		row := store.BulkTransactionRowResult{ID: id, Status: status}
+		if !found[key] {
+			row.Status = store.BulkRowNotFound
+		}
+		result.Results = append(result.Results, row)
+	}
+	return result
+}
+
+func applyBulkChanges(ctx context.Context, tx pgx.Tx, tenant store.Tenant, ids []string, input store.BulkTransactionInput) error {
+	const op = "postgres.transactions.apply_bulk_changes"
+
+	args := []any{tenant.ID, ids}
+	setClauses := transactionUpdateClauses(input.Update, func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	})
+	if input.Muted != nil {
+		if *input.Muted {
+			args = append(args, input.MuteReason)
+			setClauses = append(setClauses,
+				"muted = true", "muted_by_merchant = false", fmt.Sprintf("mute_reason = NULLIF($%d, '')", len(args)))
+		} else {
+			setClauses = append(setClauses, "muted = false", "muted_by_merchant = false", "mute_reason = NULL")
+		}
+	}
+	if len(setClauses) > 0 {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(
+			"UPDATE transactions SET %s, updated_at = NOW() WHERE tenant_id = $1 AND id = ANY($2::uuid[])",
+			strings.Join(setClauses, ", "),
+		), args...); err != nil {
+			return errors.E(op, "updating transactions", err)
+		}
+	}
+
+	if len(input.AddLabels) > 0 {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO transaction_label_sources (transaction_id, label, source_type, merchant_pattern)
+				 SELECT t.id, label, 'manual', ''
+				 FROM transactions t, unnest($3::text[]) AS labels(label)
+				 WHERE t.tenant_id = $1 AND t.id = ANY($2::uuid[])
+				 ON CONFLICT (transaction_id, label, source_type, merchant_pattern) DO NOTHING`,
+			tenant.ID, ids, input.AddLabels,
+		); err != nil {
+			return errors.E(op, "adding label sources", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO transaction_labels (transaction_id, label)
+				 SELECT t.id, label
+				 FROM transactions t, unnest($3::text[]) AS labels(label)
+				 WHERE t.tenant_id = $1 AND t.id = ANY($2::uuid[])
+				 ON CONFLICT (transaction_id, label) DO NOTHING`,
+			tenant.ID, ids, input.AddLabels,
+		); err != nil {
+			return errors.E(op, "adding labels", err)
+		}
+	}
+
+	if len(input.RemoveLabels) > 0 {
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM transaction_label_sources tls
+			 USING transactions t
+			 WHERE tls.transaction_id = t.id
+			   AND t.tenant_id = $1
+			   AND t.id = ANY($2::uuid[])
+			   AND tls.label = ANY($3::text[])`,
+			tenant.ID, ids, input.RemoveLabels,
+		); err != nil {
+			return errors.E(op, "removing label sources", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM transaction_labels tl
+			 USING transactions t
+			 WHERE tl.transaction_id = t.id
+			   AND t.tenant_id = $1
+			   AND t.id = ANY($2::uuid[])
+			   AND tl.label = ANY($3::text[])`,
+			tenant.ID, ids, input.RemoveLabels,
+		); err != nil {
+			return errors.E(op, "removing labels", err)
+		}
+	}
+
+	if len(setClauses) == 0 {
+		if _, err := tx.Exec(ctx,
+			`UPDATE transactions SET updated_at = NOW() WHERE tenant_id = $1 AND id = ANY($2::uuid[])`,
+			tenant.ID, ids,
+		); err != nil {
+			return errors.E(op, "touching transactions", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// beginChange starts a transaction whose writes to transactions and their
+// labels are logged as one change batch, attributed to cause and to the
+// principal in ctx. See migration 017 for the triggers doing the logging.
+func beginChange(ctx context.Context, pool poolBeginner, cause store.ChangeCause) (pgx.Tx, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := startChangeBatch(ctx, tx, cause); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// startChangeBatch logs the following writes in tx under a fresh batch with
+// the given cause and returns the batch ID.
+func startChangeBatch(ctx context.Context, tx pgx.Tx, cause store.ChangeCause) (string, error) {
+	var actor string
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		actor = principal.UserID
+	}
+	var batchID, setCause, setActor string
+	if err := tx.QueryRow(ctx,
+		`SELECT set_config('expensor.change_batch', gen_random_uuid()::text, true),
+		        set_config('expensor.change_cause', $1, true),
+		        set_config('expensor.change_actor', $2, true)`,
+		string(cause), actor,
+	).Scan(&batchID, &setCause, &setActor); err != nil {
+		return "", fmt.Errorf("starting change batch: %w", err)
+	}
+	return batchID, nil
+}
+
+// execChange runs one statement as its own logged change batch.
+func execChange(ctx context.Context, pool poolBeginner, cause store.ChangeCause, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx, err := beginChange(ctx, pool, cause)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return tag, err
+	}
+	return tag, tx.Commit(ctx)
+}
+
+// revertColumnTypes lists the logged transaction columns a revert may write,
+// with the type each logged text value is cast back to.
+var revertColumnTypes = map[string]string{
+	"description":       "text",
+	"category":          "text",
+	"bucket":            "text",
+	"muted":             "boolean",
+	"muted_by_merchant": "boolean",
+	"mute_reason":       "text",
+	"amount":            "numeric",
+	"currency":          "text",
+	"timestamp":         "timestamptz",
+	"merchant_info":     "text",
+}
+
+func (r *transactionsRepository) ListTransactionHistory(ctx context.Context, tenant store.Tenant, id string) ([]store.TransactionChange, error) {
+	const op = "postgres.transactions.list_transaction_history"
+
+	var exists bool
+	if err := r.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM transactions WHERE id = $1 AND tenant_id = $2)`,
+		id, tenant.ID,
+	).Scan(&exists); err != nil {
+		return nil, errors.E(op, "checking transaction", err)
+	}
+	if !exists {
+		return nil, errors.E("store.transactions.history", errors.NotFound, errors.User("transaction not found"))
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT c.id, c.batch_id, c.transaction_id, c.field, c.old_value, c.new_value, c.cause,
+		       COALESCE(c.actor_id::text, ''), COALESCE(u.email, ''), c.changed_at
+		FROM transaction_changes c
+		LEFT JOIN users u ON u.id = c.actor_id
+		WHERE c.tenant_id = $1 AND c.transaction_id = $2
+		ORDER BY c.id DESC
+	`, tenant.ID, id)
+	if err != nil {
+		return nil, errors.E(op, "listing transaction history", err)
+	}
+	defer rows.Close()
+
+	changes := []store.TransactionChange{}
+	for rows.Next() {
+		var c store.TransactionChange
+		if err := rows.Scan(
+			&c.ID, &c.BatchID, &c.TransactionID, &c.Field, &c.OldValue, &c.NewValue, &c.Cause,
+			&c.ActorID, &c.ActorEmail, &c.ChangedAt,
+		); err != nil {
+			return nil, errors.E(op, "scanning transaction change", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating transaction history", err)
+	}
+	return changes, nil
+}
+
+// RevertChangeBatch restores the old value of every change in a batch, newest
+// first. A field that has changed again since the batch is skipped rather
+// than overwritten.
+func (r *transactionsRepository) RevertChangeBatch(ctx context.Context, tenant store.Tenant, batchID string) (store.ChangeBatchRevert, error) {
+	const op = "postgres.transactions.revert_change_batch"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return store.ChangeBatchRevert{}, errors.E(op, "beginning revert transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	changes, err := loadChangeBatch(ctx, tx, tenant, batchID)
+	if err != nil {
+		return store.ChangeBatchRevert{}, err
+	}
+	if len(changes) == 0 {
+		return store.ChangeBatchRevert{}, errors.E("store.transactions.revert_change_batch", errors.NotFound, errors.User("change batch not found"))
+	}
+
+	revertBatchID, err := startChangeBatch(ctx, tx, store.ChangeCauseRevert)
+	if err != nil {
+		return store.ChangeBatchRevert{}, errors.E(op, err)
+	}
+	result := store.ChangeBatchRevert{
+		BatchID:         revertBatchID,
+		RevertedBatchID: batchID,
+		Results:         make([]store.ChangeRevertResult, 0, len(changes)),
+	}
+	var amountChanged []string
+	for _, c := range changes {
+		reverted, err := revertChange(ctx, tx, tenant, c)
+		if err != nil {
+			return store.ChangeBatchRevert{}, err
+		}
+		status := store.ChangeSkipped
+		if reverted {
+			status = store.ChangeReverted
+			result.Reverted++
+			if c.Field == "amount" {
+				amountChanged = append(amountChanged, c.TransactionID)
+			}
+		} else {
+			result.Skipped++
+		}
+		result.Results = append(result.Results, store.ChangeRevertResult{
+			ChangeID:      c.ID,
+			TransactionID: c.TransactionID,
+			Field:         c.Field,
+			Status:        status,
+		})
+	}
+
+	rescales, err := rescaleSplits(ctx, tx, tenant, amountChanged)
+	if err != nil {
+		return store.ChangeBatchRevert{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return store.ChangeBatchRevert{}, errors.E(op, "committing revert transaction", err)
+	}
+	r.recordSplitRescales(ctx, tenant, rescales)
+	return result, nil
+}
+
+func loadChangeBatch(ctx context.Context, tx pgx.Tx, tenant store.Tenant, batchID string) ([]store.TransactionChange, error) {
+	const op = "postgres.transactions.load_change_batch"
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, transaction_id, field, old_value, new_value
+		FROM transaction_changes
+		WHERE tenant_id = $1 AND batch_id = $2
+		ORDER BY id DESC
+	`, tenant.ID, batchID)
+	if err != nil {
+		return nil, errors.E(op, "loading change batch", err)
+	}
+	defer rows.Close()
+
+	var changes []store.TransactionChange
+	for rows.Next() {
+		c := store.TransactionChange{BatchID: batchID}
+		if err := rows.Scan(&c.ID, &c.TransactionID, &c.Field, &c.OldValue, &c.NewValue); err != nil {
+			return nil, errors.E(op, "scanning transaction change", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating change batch", err)
+	}
+	return changes, nil
+}
+
+// revertChange writes the old value of c back if the field still holds the
+// value c wrote, and reports whether it did.
+func revertChange(ctx context.Context, tx pgx.Tx, tenant store.Tenant, c store.TransactionChange) (bool, error) {
+	const op = "postgres.transactions.revert_change"
+
+	if c.Field == store.ChangeFieldLabel {
+		return revertLabelChange(ctx, tx, tenant, c)
+	}
+	columnType, ok := revertColumnTypes[c.Field]
+	if !ok {
+		return false, nil
+	}
+	// The trigger logs values as to_jsonb(row) ->> column, so compare the
+	// current value the same way.
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE transactions t SET %[1]s = $1::%[2]s, updated_at = NOW()
+		WHERE t.id = $2 AND t.tenant_id = $3 AND to_jsonb(t) ->> '%[1]s' IS NOT DISTINCT FROM $4
+	`, c.Field, columnType), c.OldValue, c.TransactionID, tenant.ID, c.NewValue)
+	if err != nil {
+		return false, errors.E(op, fmt.Sprintf("reverting %s", c.Field), err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func revertLabelChange(ctx context.Context, tx pgx.Tx, tenant store.Tenant, c store.TransactionChange) (bool, error) {
+	const op = "postgres.transactions.revert_label_change"
+
+	switch {
+	case c.OldValue == nil && c.NewValue != nil:
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM transaction_label_sources tls
+			 USING transactions t
+			 WHERE tls.transaction_id = t.id AND t.id = $1 AND t.tenant_id = $2 AND tls.label = $3`,
+			c.TransactionID, tenant.ID, *c.NewValue,
+		); err != nil {
+			return false, errors.E(op, "removing label sources", err)
+		}
+		tag, err := tx.Exec(ctx,
+			`DELETE FROM transaction_labels tl
+			 USING transactions t
+			 WHERE tl.transaction_id = t.id AND t.id = $1 AND t.tenant_id = $2 AND tl.label = $3`,
+			c.TransactionID, tenant.ID, *c.NewValue,
+		)
+		if err != nil {
+			return false, errors.E(op, "removing label", err)
+		}
+		return tag.RowsAffected() > 0, nil
+	case c.OldValue != nil && c.NewValue == nil:
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO transaction_label_sources (transaction_id, label, source_type, merchant_pattern)
+			 SELECT id, $3, 'manual', ''
+			 FROM transactions
+			 WHERE id = $1 AND tenant_id = $2
+			 ON CONFLICT (transaction_id, label, source_type, merchant_pattern) DO NOTHING`,
+			c.TransactionID, tenant.ID, *c.OldValue,
+		); err != nil {
+			return false, errors.E(op, "restoring label source", err)
+		}
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO transaction_labels (transaction_id, label)
+			 SELECT id, $3
+			 FROM transactions
+			 WHERE id = $1 AND tenant_id = $2
+			 ON CONFLICT (transaction_id, label) DO NOTHING`,
+			c.TransactionID, tenant.ID, *c.OldValue,
+		)
+		if err != nil {
+			return false, errors.E(op, "restoring label", err)
+		}
+		return tag.RowsAffected() > 0, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=24, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "`
+
+// searchHighlightFields lists the text fields ts_headline can excerpt, in
+// response order. Values are HTML-escaped before highlighting so only the
+// inserted <mark> tags are markup.
+var searchHighlightFields = []struct {
+	name  string
+	value string
+}{
+	{store.SearchFieldMerchant, "t.merchant_info"},
+	{store.SearchFieldDescription, "COALESCE(t.description, '')"},
+	{store.SearchFieldSubject, "COALESCE(ec.subject, '')"},
+	{store.SearchFieldBody, "COALESCE(ec.body, '')"},
+}
+
+// buildSearchCondition appends search arguments and returns a condition that
+// requires every field-scoped term plus the free text, if any.
+func buildSearchCondition(search store.SearchQuery, args *[]any) string {
+	next := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+	var conds []string
+	if search.Text != "" {
+		ts := next(search.Text)
+		like := next(escapeLikePattern(search.Text))
+		conds = append(conds, fmt.Sprintf(
+			`(
+				(to_tsvector('english', t.merchant_info) || to_tsvector('english', COALESCE(t.description,''))) @@ websearch_to_tsquery('english', %[1]s)
+				OR t.merchant_info ILIKE %[2]s ESCAPE '\'
+				OR COALESCE(t.description, '') ILIKE %[2]s ESCAPE '\'
+				OR EXISTS (
+					SELECT 1
+					FROM transaction_email_content ec
+					WHERE ec.transaction_id = t.id
+					  AND (
+						ec.search @@ websearch_to_tsquery('english', %[1]s)
+						OR ec.subject ILIKE %[2]s ESCAPE '\'
+						OR ec.body ILIKE %[2]s ESCAPE '\'
+					  )
+				)
+			)`,
+			ts, like,
+		))
+	}
+	for _, term := range search.Merchant {
+		conds = append(conds, textMatchCondition("t.merchant_info", next(term), next(escapeLikePattern(term))))
+	}
+	for _, term := range search.Description {
+		conds = append(conds, textMatchCondition("COALESCE(t.description, '')", next(term), next(escapeLikePattern(term))))
+	}
+	for _, term := range search.Subject {
+		conds = append(conds, emailContentCondition(textMatchCondition("ec.subject", next(term), next(escapeLikePattern(term)))))
+	}
+	for _, term := range search.Body {
+		conds = append(conds, emailContentCondition(textMatchCondition("ec.body", next(term), next(escapeLikePattern(term)))))
+	}
+	for _, label := range search.Labels {
+		conds = append(conds, fmt.Sprintf(
+			`EXISTS (
+				SELECT 1
+				FROM transaction_labels tl_search
+				WHERE tl_search.transaction_id = t.id
+				  AND tl_search.label ILIKE %s ESCAPE '\'
+			)`,
+			next(escapeLikePattern(label)),
+		))
+	}
+	for _, amount := range search.Amounts {
+		conds = append(conds, fmt.Sprintf("t.amount %s %s", amountOperator(amount.Op), next(amount.Value)))
+	}
+	return "(" + strings.Join(conds, " AND ") + ")"
+}
+
+func textMatchCondition(column, tsArg, likeArg string) string {
+	return fmt.Sprintf(
+		`(to_tsvector('english', %[1]s) @@ websearch_to_tsquery('english', %[2]s) OR %[1]s ILIKE %[3]s ESCAPE '\')`,
+		column, tsArg, likeArg,
+	)
+}
+
+func emailContentCondition(cond string) string {
+	return `EXISTS (
+		SELECT 1
+		FROM transaction_email_content ec
+		WHERE ec.transaction_id = t.id
+		  AND ` + cond + `
+	)`
+}
+
+// amountOperator maps parsed operators onto SQL, falling back to equality so
+// the operator is never interpolated from user input.
+func amountOperator(op string) string {
+	switch op {
+	case ">", ">=", "<", "<=":
+		return op
+	default:
+		return "="
+	}
+}
+
+// loadSearchHighlights attaches ts_headline excerpts for every field whose
+// terms match. Substring-only matches have no lexemes to mark and get none.
+func (r *transactionsRepository) loadSearchHighlights(ctx context.Context, txns []store.Transaction, search store.SearchQuery) error {
+	if len(txns) == 0 {
+		return nil
+	}
+	ids := make([]string, len(txns))
+	idx := make(map[string]int, len(txns))
+	for i, t := range txns {
+		ids[i] = t.ID
+		idx[t.ID] = i
+	}
+
+	args := []any{ids}
+	next := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	scoped := map[string][]string{
+		store.SearchFieldMerchant:    search.Merchant,
+		store.SearchFieldDescription: search.Description,
+		store.SearchFieldSubject:     search.Subject,
+		store.SearchFieldBody:        search.Body,
+	}
+	var fields []string
+	for i, field := range searchHighlightFields {
+		terms := scoped[field.name]
+		if search.Text != "" {
+			terms = append([]string{search.Text}, terms...)
+		}
+		if len(terms) == 0 {
+			continue
+		}
+		queries := make([]string, 0, len(terms))
+		for _, term := range terms {
+			queries = append(queries, "websearch_to_tsquery('english', "+next(term)+")")
+		}
+		fields = append(fields, fmt.Sprintf("(%d, '%s', %s, %s)", i, field.name, field.value, strings.Join(queries, " || ")))
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT id, field, headline
+		FROM (
+			SELECT t.id, f.field, f.ord,
+			       ts_headline(
+			           'english',
+			           replace(replace(replace(f.value, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+			           f.query,
+			           '%s'
+			       ) AS headline
+			FROM transactions t
+			LEFT JOIN transaction_email_content ec ON ec.transaction_id = t.id
+			CROSS JOIN LATERAL (VALUES %s) AS f(ord, field, value, query)
+			WHERE t.id = ANY($1)
+			  AND f.value <> ''
+			  AND to_tsvector('english', f.value) @@ f.query
+		) highlights
+		WHERE position('<mark>' in headline) > 0
+		ORDER BY id, ord
+	`, searchHeadlineOptions, strings.Join(fields, ", ")), args...)
+	if err != nil {
+		return errors.E("postgres.transactions.load_search_highlights", "fetching search highlights", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tid string
+		var highlight store.SearchHighlight
+		if err := rows.Scan(&tid, &highlight.Field, &highlight.Snippet); err != nil {
+			return errors.E("postgres.transactions.load_search_highlights", "scanning search highlight", err)
+		}
+		if i, ok := idx[tid]; ok {
+			txns[i].Highlights = append(txns[i].Highlights, highlight)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.E("postgres.transactions.load_search_highlights", "iterating search highlights", err)
+	}
+	return nil
+}
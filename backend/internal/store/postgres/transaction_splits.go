@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/api"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// splitRescaleCTEs keeps splits summing to their parent after its amount
+// changes. It follows a split_parents (id, amount) CTE naming the changed
+// transactions and their new amounts. Splits are scaled to the new amount,
+// with the last absorbing rounding; when a split would scale to nothing, the
+// transaction's splits are removed instead. split_fit has one row per
+// transaction whose splits no longer matched.
+const splitRescaleCTEs = `
+	split_drift AS (
+		SELECT s.id, s.transaction_id, p.amount AS parent,
+		       SUM(s.amount) OVER w AS total,
+		       ROUND(s.amount * p.amount / SUM(s.amount) OVER w, 4) AS scaled,
+		       ROW_NUMBER() OVER (PARTITION BY s.transaction_id ORDER BY s.position DESC) AS from_last
+		FROM transaction_splits s
+		JOIN split_parents p ON p.id = s.transaction_id
+		WINDOW w AS (PARTITION BY s.transaction_id)
+	),
+	split_plan AS (
+		SELECT id, transaction_id, total,
+		       CASE WHEN from_last = 1
+		            THEN parent - (SUM(scaled) OVER (PARTITION BY transaction_id) - scaled)
+		            ELSE scaled
+		       END AS amount
+		FROM split_drift
+		WHERE total <> parent
+	),
+	split_fit AS (
+		SELECT transaction_id, MIN(total) AS previous, bool_and(amount > 0) AS rescaled
+		FROM split_plan
+		GROUP BY transaction_id
+	),
+	rescaled_splits AS (
+		UPDATE transaction_splits s
+		SET amount = p.amount
+		FROM split_plan p
+		JOIN split_fit f ON f.transaction_id = p.transaction_id
+		WHERE s.id = p.id AND f.rescaled
+	),
+	cleared_splits AS (
+		DELETE FROM transaction_splits s
+		USING split_fit f
+		WHERE s.transaction_id = f.transaction_id AND NOT f.rescaled
+	)`
+
+// splitRescale reports splits that splitRescaleCTEs changed.
+type splitRescale struct {
+	transactionID string
+	messageID     string
+	previous      float64
+	amount        float64
+	// rescaled is false when the splits were removed.
+	rescaled bool
+}
+
+// diagnostic fills in base to tell the user their split changed.
+func (r splitRescale) diagnostic(base api.ExtractionDiagnostic) api.ExtractionDiagnostic {
+	base.FailureReasons = []string{api.FailureSplitsCleared}
+	base.Snippet = fmt.Sprintf("Amount changed from %.2f to %.2f; the splits no longer fit and were removed.", r.previous, r.amount)
+	if r.rescaled {
+		base.FailureReasons = []string{api.FailureSplitsRescaled}
+		base.Snippet = fmt.Sprintf("Amount changed from %.2f to %.2f; the splits were scaled to match.", r.previous, r.amount)
+	}
+	return base
+}
+
+// rescaleSplits fits the splits of transactions ids to their current amounts
+// and returns the transactions whose splits changed.
+func rescaleSplits(ctx context.Context, tx pgx.Tx, tenant store.Tenant, ids []string) ([]splitRescale, error) {
+	const op = "postgres.transactions.rescale_splits"
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := tx.Query(ctx, `
+		WITH split_parents AS (
+			SELECT id, amount, message_id
+			FROM transactions
+			WHERE tenant_id = $1 AND id = ANY($2::uuid[])
+		),
+		`+splitRescaleCTEs+`
+		SELECT p.id, COALESCE(p.message_id, ''), f.previous, p.amount, f.rescaled
+		FROM split_fit f
+		JOIN split_parents p ON p.id = f.transaction_id
+	`, tenant.ID, ids)
+	if err != nil {
+		return nil, errors.E(op, "rescaling splits", err)
+	}
+	defer rows.Close()
+
+	var rescales []splitRescale
+	for rows.Next() {
+		var r splitRescale
+		if err := rows.Scan(&r.transactionID, &r.messageID, &r.previous, &r.amount, &r.rescaled); err != nil {
+			return nil, errors.E(op, "scanning rescaled splits", err)
+		}
+		rescales = append(rescales, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "iterating rescaled splits", err)
+	}
+	return rescales, nil
+}
+
+// recordSplitRescales raises a diagnostic for each transaction whose splits a
+// revert changed. It runs after commit and only logs failures.
+func (r *transactionsRepository) recordSplitRescales(ctx context.Context, tenant store.Tenant, rescales []splitRescale) {
+	if r.diagnostics == nil {
+		return
+	}
+	for _, rescale := range rescales {
+		diagnostic := rescale.diagnostic(api.ExtractionDiagnostic{
+			Reader:    string(store.ChangeCauseRevert),
+			MessageID: rescale.messageID,
+		})
+		if err := r.diagnostics.RecordExtractionDiagnostic(ctx, tenant, diagnostic); err != nil {
+			r.logger.Warn("failed to record split rescale diagnostic", "transaction_id", rescale.transactionID, "error", err)
+		}
+	}
+}
+
+func (r *transactionsRepository) SetTransactionSplits(
+	ctx context.Context,
+	tenant store.Tenant,
+	id string,
+	splits []store.TransactionSplitInput,
+) error {
+	const op = "postgres.transactions.set_transaction_splits"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return errors.E(op, "beginning set-splits transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Lock the parent so a concurrent split cannot interleave its rows.
+	var amount float64
+	err = tx.QueryRow(ctx,
+		`SELECT amount FROM transactions WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenant.ID,
+	).Scan(&amount)
+	if errorsIsNoRows(err) {
+		return errors.E("store.transactions.set_splits", errors.NotFound, errors.User("transaction not found"))
+	}
+	if err != nil {
+		return errors.E(op, "locking transaction", err)
+	}
+	if err := store.ValidateSplits(amount, splits); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM transaction_splits WHERE transaction_id = $1`, id); err != nil {
+		return errors.E(op, "clearing splits", err)
+	}
+	for i, split := range splits {
+		labels := split.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO transaction_splits (transaction_id, position, amount, category, bucket, labels, counterparty)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, NULLIF($7, ''))
+		`, id, i, split.Amount, split.Category, split.Bucket, labels, split.Counterparty); err != nil {
+			return errors.E(op, "inserting split", err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE transactions SET updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return errors.E(op, "touching transaction", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.E(op, "committing set-splits transaction", err)
+	}
+	return nil
+}
+
+func (r *transactionsRepository) loadSplits(ctx context.Context, txns []store.Transaction) error {
+	if len(txns) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(txns))
+	idx := make(map[string]int, len(txns))
+	for i, t := range txns {
+		ids[i] = t.ID
+		idx[t.ID] = i
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT transaction_id, id, amount, COALESCE(category, ''), COALESCE(bucket, ''), labels, COALESCE(counterparty, '')
+		FROM transaction_splits
+		WHERE transaction_id = ANY($1)
+		ORDER BY transaction_id, position
+	`, ids)
+	if err != nil {
+		return errors.E("postgres.transactions.load_splits", "fetching splits", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tid string
+		var split store.TransactionSplit
+		if err := rows.Scan(&tid, &split.ID, &split.Amount, &split.Category, &split.Bucket, &split.Labels, &split.Counterparty); err != nil {
+			return errors.E("postgres.transactions.load_splits", "scanning split row", err)
+		}
+		if split.Labels == nil {
+			split.Labels = []string{}
+		}
+		if i, ok := idx[tid]; ok {
+			txns[i].Splits = append(txns[i].Splits, split)
+		}
+	}
+	return rows.Err()
+}
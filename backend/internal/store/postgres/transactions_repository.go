@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math"
 	"strings"
 
@@ -11,24 +12,35 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/api"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
 type transactionsRepository struct {
-	pool *pgxpool.Pool
+	pool        *pgxpool.Pool
+	logger      *slog.Logger
+	webhooks    *webhookRepository
+	diagnostics *diagnosticsRepository
 }
 
 type transactionQueryRequest struct {
 	tenant     store.Tenant
 	filter     store.ListFilter
-	search     string
+	search     store.SearchQuery
 	countError string
 	dataError  string
 }
 
-func newTransactionsRepository(deps repositoryDependencies) *transactionsRepository {
+func newTransactionsRepository(
+	deps repositoryDependencies,
+	webhooks *webhookRepository,
+	diagnostics *diagnosticsRepository,
+) *transactionsRepository {
 	return &transactionsRepository{
-		pool: deps.pool,
+		pool:        deps.pool,
+		logger:      deps.logger,
+		webhooks:    webhooks,
+		diagnostics: diagnostics,
 	}
 }
 
@@ -62,13 +74,11 @@ func (r *transactionsRepository) queryTransactions(
 	if err != nil {
 		return nil, store.TransactionListResult{}, err
 	}
-	query := strings.TrimSpace(request.search)
-
 	where, args := buildListWhere(f)
 	args = append(args, request.tenant.ID)
 	where = combineWhere(fmt.Sprintf("t.tenant_id = $%d", len(args)), where)
-	if query != "" {
-		searchCond := buildSearchCondition(query, &args)
+	if !request.search.Empty() {
+		searchCond := buildSearchCondition(request.search, &args)
 		where = combineWhere(searchCond, where)
 	}
 
@@ -108,6 +118,14 @@ func (r *transactionsRepository) queryTransactions(
 	if err := r.loadLabels(ctx, txns); err != nil {
 		return nil, store.TransactionListResult{}, err
 	}
+	if err := r.loadSplits(ctx, txns); err != nil {
+		return nil, store.TransactionListResult{}, err
+	}
+	if !request.search.Empty() {
+		if err := r.loadSearchHighlights(ctx, txns, request.search); err != nil {
+			return nil, store.TransactionListResult{}, err
+		}
+	}
 
 	return txns, totalResult, nil
 }
@@ -133,11 +151,13 @@ func normalizeTransactionListFilter(f store.ListFilter) store.ListFilter {
 	return f
 }
 
+// transactionOrderClause breaks timestamp ties by ID so consecutive pages never
+// repeat or skip rows, which exports rely on when paging through everything.
 func transactionOrderClause(f store.ListFilter) string {
 	if strings.ToLower(f.SortDir) == "asc" {
-		return "t.timestamp ASC"
+		return "t.timestamp ASC, t.id ASC"
 	}
-	return "t.timestamp DESC"
+	return "t.timestamp DESC, t.id DESC"
 }
 
 func (r *transactionsRepository) GetTransaction(ctx context.Context, tenant store.Tenant, id string) (*store.Transaction, error) {
@@ -172,11 +192,116 @@ func (r *transactionsRepository) getTransactionQuery(ctx context.Context, tenant
 	if err := r.loadLabels(ctx, txns); err != nil {
 		return nil, err
 	}
+	if err := r.loadSplits(ctx, txns); err != nil {
+		return nil, err
+	}
 	return &txns[0], nil
 }
 
+func (r *transactionsRepository) CreateTransaction(
+	ctx context.Context,
+	tenant store.Tenant,
+	input store.CreateTransactionInput,
+) (*store.Transaction, error) {
+	const op = "postgres.transactions.create_transaction"
+
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseUserEdit)
+	if err != nil {
+		return nil, errors.E(op, "beginning create-transaction transaction", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	source := api.Source{Type: store.SourceTypeManual, Label: input.SourceLabel}
+	var id string
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO transactions (
+			id, tenant_id, message_id, amount, currency, timestamp, merchant_info,
+			category, bucket, source, source_type, source_label, description
+		)
+		SELECT new_id, $1, 'manual:' || new_id::text, $2, $3, $4, $5,
+		       NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, NULLIF($11, '')
+		FROM (SELECT gen_random_uuid() AS new_id) generated
+		RETURNING id
+	`,
+		tenant.ID,
+		input.Amount,
+		input.Currency,
+		input.Timestamp,
+		input.MerchantInfo,
+		input.Category,
+		input.Bucket,
+		source.Display(),
+		source.Type,
+		source.Label,
+		input.Description,
+	).Scan(&id); err != nil {
+		return nil, errors.E(op, "inserting manual transaction", err)
+	}
+
+	if len(input.Labels) > 0 {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO transaction_label_sources (transaction_id, label, source_type, merchant_pattern)
+			 SELECT $1, unnest($2::text[]), 'manual', ''
+			 ON CONFLICT (transaction_id, label, source_type, merchant_pattern) DO NOTHING`,
+			id, input.Labels,
+		); err != nil {
+			return nil, errors.E(op, "adding label sources", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO transaction_labels (transaction_id, label)
+			 SELECT $1, unnest($2::text[])
+			 ON CONFLICT (transaction_id, label) DO NOTHING`,
+			id, input.Labels,
+		); err != nil {
+			return nil, errors.E(op, "adding labels", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, errors.E(op, "committing create-transaction transaction", err)
+	}
+	r.webhooks.enqueueTransactions(ctx, tenant.ID, store.WebhookTransactionCreated, []string{id})
+	return r.getTransactionQuery(ctx, tenant, id)
+}
+
+func (r *transactionsRepository) DeleteTransaction(ctx context.Context, tenant store.Tenant, id string) error {
+	const op = "postgres.transactions.delete_transaction"
+
+	var sourceType string
+	var deleted bool
+	err := r.pool.QueryRow(ctx, `
+		WITH target AS (
+			SELECT id, source_type
+			FROM transactions
+			WHERE id = $1 AND tenant_id = $2
+		), deleted AS (
+			DELETE FROM transactions t
+			USING target
+			WHERE t.id = target.id AND target.source_type = $3
+			RETURNING t.id
+		)
+		SELECT target.source_type, EXISTS (SELECT 1 FROM deleted)
+		FROM target
+	`, id, tenant.ID, store.SourceTypeManual).Scan(&sourceType, &deleted)
+	if errorsIsNoRows(err) {
+		return errors.E("store.transactions.delete", errors.NotFound, errors.User("transaction not found"))
+	}
+	if err != nil {
+		return errors.E(op, "deleting transaction", err)
+	}
+	if !deleted {
+		return errors.E(
+			"store.transactions.delete",
+			errors.Conflict,
+			errors.User("Only manually entered transactions can be deleted. Mute email transactions instead."),
+			"refusing to delete "+sourceType+" transaction",
+		)
+	}
+	return nil
+}
+
 func (r *transactionsRepository) UpdateDescription(ctx context.Context, tenant store.Tenant, id, description string) error {
-	tag, err := r.pool.Exec(ctx,
+	tag, err := execChange(ctx, r.pool, store.ChangeCauseUserEdit,
 		`UPDATE transactions SET description = $1 WHERE id = $2 AND tenant_id = $3`,
 		description, id, tenant.ID,
 	)
@@ -190,7 +315,7 @@ func (r *transactionsRepository) UpdateDescription(ctx context.Context, tenant s
 }
 
 func (r *transactionsRepository) AddLabel(ctx context.Context, tenant store.Tenant, transactionID, label string) error {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseUserEdit)
 	if err != nil {
 		return errors.E("postgres.transactions.add_label", "beginning add-label transaction", err)
 	}
@@ -229,7 +354,7 @@ func (r *transactionsRepository) AddLabels(ctx context.Context, tenant store.Ten
 		return nil
 	}
 
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseUserEdit)
 	if err != nil {
 		return errors.E("postgres.transactions.add_labels", "beginning add-labels transaction", err)
 	}
@@ -264,7 +389,7 @@ func (r *transactionsRepository) AddLabels(ctx context.Context, tenant store.Ten
 }
 
 func (r *transactionsRepository) RemoveLabel(ctx context.Context, tenant store.Tenant, transactionID, label string) error {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseUserEdit)
 	if err != nil {
 		return errors.E("postgres.transactions.remove_label", "beginning remove-label transaction", err)
 	}
@@ -319,14 +444,17 @@ func (r *transactionsRepository) searchTransactionsQuery(
 	query string,
 	f store.ListFilter,
 ) (transactions []store.Transaction, result store.TransactionListResult, err error) {
-	query = strings.TrimSpace(query)
-	if query == "" {
+	search, err := store.ParseSearchQuery(strings.TrimSpace(query))
+	if err != nil {
+		return nil, store.TransactionListResult{}, err
+	}
+	if search.Empty() {
 		return r.listTransactionsQuery(ctx, tenant, f)
 	}
 	return r.queryTransactions(ctx, transactionQueryRequest{
 		tenant:     tenant,
 		filter:     f,
-		search:     query,
+		search:     search,
 		countError: "counting search results",
 		dataError:  "searching transactions",
 	})
@@ -527,47 +655,54 @@ func (r *transactionsRepository) UpdateTransaction(ctx context.Context, tenant s
 	if u.Description == nil && u.Category == nil && u.Bucket == nil {
 		return nil
 	}
-	var setClauses []string
 	var args []any
-	n := func(v any) string {
+	setClauses := transactionUpdateClauses(u, func(v any) string {
 		args = append(args, v)
 		return fmt.Sprintf("$%d", len(args))
-	}
-	if u.Description != nil {
-		setClauses = append(setClauses, "description = "+n(*u.Description))
-	}
-	if u.Category != nil {
-		setClauses = append(setClauses, "category = "+n(*u.Category))
-	}
-	if u.Bucket != nil {
-		setClauses = append(setClauses, "bucket = "+n(*u.Bucket))
-	}
+	})
 	args = append(args, id, tenant.ID)
 	q := fmt.Sprintf(
 		"UPDATE transactions SET %s, updated_at = NOW() WHERE id = $%d AND tenant_id = $%d",
 		strings.Join(setClauses, ", "), len(args)-1, len(args),
 	)
-	tag, err := r.pool.Exec(ctx, q, args...)
+	tag, err := execChange(ctx, r.pool, store.ChangeCauseUserEdit, q, args...)
 	if err != nil {
 		return errors.E("postgres.transactions.update_transaction", "updating transaction", err)
 	}
 	if tag.RowsAffected() == 0 {
 		return errors.E("store.transactions.update", errors.NotFound, errors.User("transaction not found"))
 	}
+	r.webhooks.enqueueTransactions(ctx, tenant.ID, store.WebhookTransactionUpdated, []string{id})
 	return nil
 }
 
+// transactionUpdateClauses returns the SET assignments for the non-nil fields
+// of u, binding each value through next.
+func transactionUpdateClauses(u store.TransactionUpdate, next func(any) string) []string {
+	var clauses []string
+	if u.Description != nil {
+		clauses = append(clauses, "description = "+next(*u.Description))
+	}
+	if u.Category != nil {
+		clauses = append(clauses, "category = "+next(*u.Category))
+	}
+	if u.Bucket != nil {
+		clauses = append(clauses, "bucket = "+next(*u.Bucket))
+	}
+	return clauses
+}
+
 func (r *transactionsRepository) MuteTransaction(ctx context.Context, tenant store.Tenant, id string, muted bool, reason string) error {
 	var tag pgconn.CommandTag
 	var err error
 	if muted {
-		tag, err = r.pool.Exec(ctx,
+		tag, err = execChange(ctx, r.pool, store.ChangeCauseUserEdit,
 			`UPDATE transactions SET muted=true, muted_by_merchant=false, mute_reason=NULLIF($2,''), updated_at=NOW()
 			 WHERE id=$1 AND tenant_id = $3`,
 			id, reason, tenant.ID,
 		)
 	} else {
-		tag, err = r.pool.Exec(ctx,
+		tag, err = execChange(ctx, r.pool, store.ChangeCauseUserEdit,
 			`UPDATE transactions SET muted=false, muted_by_merchant=false, mute_reason=NULL, updated_at=NOW()
 			 WHERE id=$1 AND tenant_id = $2`,
 			id, tenant.ID,
@@ -583,7 +718,7 @@ func (r *transactionsRepository) MuteTransaction(ctx context.Context, tenant sto
 }
 
 func (r *transactionsRepository) UpdateMuteReason(ctx context.Context, tenant store.Tenant, id, reason string) error {
-	tag, err := r.pool.Exec(ctx,
+	tag, err := execChange(ctx, r.pool, store.ChangeCauseUserEdit,
 		`UPDATE transactions SET mute_reason=NULLIF($2,''), updated_at=NOW()
 		 WHERE id=$1 AND muted=true AND tenant_id = $3`,
 		id, reason, tenant.ID,
@@ -612,7 +747,7 @@ func (r *transactionsRepository) UpdateMerchantReason(ctx context.Context, tenan
 }
 
 func (r *transactionsRepository) MuteByMerchant(ctx context.Context, tenant store.Tenant, pattern, reason string) error {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseMerchantMapping)
 	if err != nil {
 		return errors.E("postgres.transactions.mute_by_merchant", "beginning mute-by-merchant transaction", err)
 	}
@@ -722,7 +857,7 @@ func (r *transactionsRepository) DeleteMutedMerchant(ctx context.Context, tenant
 }
 
 func (r *transactionsRepository) UnmuteByPattern(ctx context.Context, tenant store.Tenant, pattern string) error {
-	_, err := r.pool.Exec(ctx,
+	_, err := execChange(ctx, r.pool, store.ChangeCauseMerchantMapping,
 		`UPDATE transactions SET muted=false, muted_by_merchant=false, mute_reason=NULL, updated_at=NOW()
 			 WHERE merchant_info ILIKE $1 AND tenant_id = $2`,
 		"%"+pattern+"%", tenant.ID,
@@ -734,7 +869,7 @@ func (r *transactionsRepository) UnmuteByPattern(ctx context.Context, tenant sto
 }
 
 func (r *transactionsRepository) DeleteMutedMerchantAndUnmute(ctx context.Context, tenant store.Tenant, id string) error {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := beginChange(ctx, r.pool, store.ChangeCauseMerchantMapping)
 	if err != nil {
 		return errors.E("postgres.transactions.delete_muted_merchant_and_unmute", "beginning transaction", err)
 	}
@@ -0,0 +1,422 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ArionMiles/expensor/backend/internal/auth"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const webhookColumns = `id::text, tenant_id::text, url, description, events, enabled, created_at, updated_at`
+
+const webhookDeliveryColumns = `
+	d.id::text, d.webhook_id::text, d.tenant_id::text, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_attempt_at, COALESCE(d.response_status, 0), d.last_error, d.created_at, d.completed_at
+`
+
+// transactionWebhookPayload is the data of transaction events, built from
+// a transactions row aliased as t.
+const transactionWebhookPayload = `jsonb_build_object(
+	'id', t.id,
+	'amount', t.amount,
+	'currency', t.currency,
+	'original_amount', t.original_amount,
+	'original_currency', t.original_currency,
+	'timestamp', t.timestamp,
+	'merchant_info', COALESCE(t.merchant_info, ''),
+	'category', COALESCE(t.category, ''),
+	'bucket', COALESCE(t.bucket, ''),
+	'source', jsonb_build_object('type', t.source_type, 'label', t.source_label, 'bank', t.bank),
+	'description', COALESCE(t.description, ''),
+	'labels', COALESCE((SELECT jsonb_agg(l.label ORDER BY l.label) FROM transaction_labels l WHERE l.transaction_id = t.id), '[]'::jsonb),
+	'muted', t.muted
+)`
+
+type webhookRepository struct {
+	pool      *pgxpool.Pool
+	logger    *slog.Logger
+	now       func() time.Time
+	secretBox *auth.SecretBox
+}
+
+func newWebhookRepository(deps repositoryDependencies) *webhookRepository {
+	return &webhookRepository{pool: deps.pool, logger: deps.logger, now: deps.now, secretBox: deps.secretBox}
+}
+
+func webhookAssociatedData(tenantID, webhookID string) auth.SecretAssociatedData {
+	return auth.SecretAssociatedData{TenantID: tenantID, Scope: "webhook", Name: webhookID, Kind: "secret"}
+}
+
+func (r *webhookRepository) ListWebhooks(ctx context.Context, tenant store.Tenant) ([]store.Webhook, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE tenant_id = $1 ORDER BY created_at, id`, tenant.ID)
+	if err != nil {
+		return nil, errors.E("postgres.webhooks.list", "listing webhooks", err)
+	}
+	defer rows.Close()
+
+	webhooks := []store.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, errors.E("postgres.webhooks.list", "scanning webhook", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E("postgres.webhooks.list", "listing webhooks", err)
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepository) GetWebhook(ctx context.Context, tenant store.Tenant, id string) (*store.Webhook, error) {
+	webhook, err := scanWebhook(r.pool.QueryRow(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND tenant_id = $2`, id, tenant.ID))
+	if err != nil {
+		if errorsIsNoRows(err) {
+			return nil, errors.E("store.webhooks.get", errors.NotFound, errors.User("webhook not found"))
+		}
+		return nil, errors.E("postgres.webhooks.get", "getting webhook", err)
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) CreateWebhook(ctx context.Context, tenant store.Tenant, input store.NewWebhook) (*store.Webhook, error) {
+	const op = "postgres.webhooks.create"
+
+	if err := validateWebhookEvents(input.Events); err != nil {
+		return nil, err
+	}
+	if r.secretBox == nil {
+		return nil, errors.E(op, errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	id := uuid.NewString()
+	secret, err := r.secretBox.Seal([]byte(input.Secret), webhookAssociatedData(tenant.ID, id))
+	if err != nil {
+		return nil, errors.E(op, "encrypting webhook secret", err)
+	}
+	webhook, err := scanWebhook(r.pool.QueryRow(ctx, `
+		INSERT INTO webhooks (id, tenant_id, url, description, events, enabled, secret_ciphertext)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+webhookColumns,
+		id, tenant.ID, input.URL, input.Description, webhookEventStrings(input.Events), input.Enabled, secret,
+	))
+	if err != nil {
+		return nil, errors.E(op, "creating webhook", err)
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) UpdateWebhook(
+	ctx context.Context,
+	tenant store.Tenant,
+	id string,
+	patch store.WebhookPatch,
+) (*store.Webhook, error) {
+	const op = "postgres.webhooks.update"
+
+	var args []any
+	next := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	var sets []string
+	if patch.URL != nil {
+		sets = append(sets, "url = "+next(*patch.URL))
+	}
+	if patch.Description != nil {
+		sets = append(sets, "description = "+next(*patch.Description))
+	}
+	if patch.Events != nil {
+		if err := validateWebhookEvents(patch.Events); err != nil {
+			return nil, err
+		}
+		sets = append(sets, "events = "+next(webhookEventStrings(patch.Events)))
+	}
+	if patch.Enabled != nil {
+		sets = append(sets, "enabled = "+next(*patch.Enabled))
+	}
+	if patch.Secret != nil {
+		if r.secretBox == nil {
+			return nil, errors.E(op, errors.FailedPrecondition, "store secret box is not initialized")
+		}
+		secret, err := r.secretBox.Seal([]byte(*patch.Secret), webhookAssociatedData(tenant.ID, id))
+		if err != nil {
+			return nil, errors.E(op, "encrypting webhook secret", err)
+		}
+		sets = append(sets, "secret_ciphertext = "+next(secret))
+	}
+	if len(sets) == 0 {
+		return r.GetWebhook(ctx, tenant, id)
+	}
+	args = append(args, id, tenant.ID)
+	webhook, err := scanWebhook(r.pool.QueryRow(ctx, fmt.Sprintf(`
+		UPDATE webhooks
+		SET %s, updated_at = now()
+		WHERE id = $%d AND tenant_id = $%d
+		RETURNING `+webhookColumns,
+		strings.Join(sets, ", "), len(args)-1, len(args)), args...))
+	if err != nil {
+		if errorsIsNoRows(err) {
+			return nil, errors.E("store.webhooks.update", errors.NotFound, errors.User("webhook not found"))
+		}
+		return nil, errors.E(op, "updating webhook", err)
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, tenant store.Tenant, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2`, id, tenant.ID)
+	if err != nil {
+		return errors.E("postgres.webhooks.delete", "deleting webhook", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.E("store.webhooks.delete", errors.NotFound, errors.User("webhook not found"))
+	}
+	return nil
+}
+
+func (r *webhookRepository) ListWebhookDeliveries(
+	ctx context.Context,
+	tenant store.Tenant,
+	webhookID string,
+	limit int,
+) ([]store.WebhookDelivery, error) {
+	const op = "postgres.webhooks.list_deliveries"
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = $1 AND d.tenant_id = $2
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $3
+	`, webhookID, tenant.ID, limit)
+	if err != nil {
+		return nil, errors.E(op, "listing webhook deliveries", err)
+	}
+	defer rows.Close()
+
+	deliveries := []store.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, errors.E(op, "scanning webhook delivery", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "listing webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.WebhookDispatch, error) {
+	const op = "postgres.webhooks.claim_deliveries"
+
+	if r.secretBox == nil {
+		return nil, errors.E(op, errors.FailedPrecondition, "store secret box is not initialized")
+	}
+	now := r.now()
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND w.enabled
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+		    next_attempt_at = $3
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING `+webhookDeliveryColumns+`, w.url, w.secret_ciphertext
+	`, now, limit, now.Add(lease))
+	if err != nil {
+		return nil, errors.E(op, "claiming webhook deliveries", err)
+	}
+	type claimed struct {
+		dispatch store.WebhookDispatch
+		secret   []byte
+	}
+	var claims []claimed
+	for rows.Next() {
+		var claim claimed
+		if err := scanWebhookDeliveryInto(rows, &claim.dispatch.Delivery, &claim.dispatch.URL, &claim.secret); err != nil {
+			rows.Close()
+			return nil, errors.E(op, "scanning claimed webhook delivery", err)
+		}
+		claims = append(claims, claim)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, "claiming webhook deliveries", err)
+	}
+
+	dispatches := make([]store.WebhookDispatch, 0, len(claims))
+	for _, claim := range claims {
+		delivery := claim.dispatch.Delivery
+		secret, err := r.secretBox.Open(claim.secret, webhookAssociatedData(delivery.TenantID, delivery.WebhookID))
+		if err != nil {
+			// No attempt can succeed without the secret, so the delivery is
+			// given up on rather than retried.
+			r.logger.Warn("failed to decrypt webhook secret", "webhook_id", delivery.WebhookID, "error", err)
+			attempt := store.WebhookAttempt{AttemptedAt: now, Error: "webhook secret cannot be decrypted"}
+			if err := r.CompleteWebhookDelivery(ctx, delivery.ID, attempt); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		claim.dispatch.Secret = string(secret)
+		dispatches = append(dispatches, claim.dispatch)
+	}
+	return dispatches, nil
+}
+
+func (r *webhookRepository) CompleteWebhookDelivery(ctx context.Context, id string, attempt store.WebhookAttempt) error {
+	status := store.WebhookDeliveryFailed
+	switch {
+	case attempt.Delivered:
+		status = store.WebhookDeliveryDelivered
+	case attempt.RetryAt != nil:
+		status = store.WebhookDeliveryPending
+	}
+	var retryAt *time.Time
+	if status == store.WebhookDeliveryPending {
+		retryAt = attempt.RetryAt
+	}
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2::text,
+		    last_attempt_at = $3,
+		    response_status = NULLIF($4, 0),
+		    last_error = $5,
+		    next_attempt_at = $6,
+		    completed_at = CASE WHEN $2::text = 'pending' THEN NULL ELSE $3 END
+		WHERE id = $1 AND status = 'pending'
+	`, id, string(status), attempt.AttemptedAt, attempt.ResponseStatus, attempt.Error, retryAt)
+	if err != nil {
+		return errors.E("postgres.webhooks.complete_delivery", "completing webhook delivery", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) PruneWebhookDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM webhook_deliveries WHERE status <> 'pending' AND completed_at < $1`, cutoff)
+	if err != nil {
+		return 0, errors.E("postgres.webhooks.prune_deliveries", "pruning webhook deliveries", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// enqueue queues an event with the given data for each enabled webhook of
+// the tenant subscribed to it. Events are queued after the change they
+// describe is committed, so a failure is logged rather than returned.
+func (r *webhookRepository) enqueue(ctx context.Context, tenantID string, eventType store.WebhookEventType, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		r.logger.Warn("failed to encode webhook event", "event", eventType, "error", err)
+		return
+	}
+	_, err = r.pool.Exec(context.WithoutCancel(ctx), `
+		INSERT INTO webhook_deliveries (webhook_id, tenant_id, event_type, payload, next_attempt_at)
+		SELECT id, tenant_id, $2::text, $3::jsonb, $4::timestamptz
+		FROM webhooks
+		WHERE tenant_id = $1 AND enabled AND $2::text = ANY(events)
+	`, tenantID, string(eventType), payload, r.now())
+	if err != nil {
+		r.logger.Warn("failed to queue webhook event", "tenant_id", tenantID, "event", eventType, "error", err)
+	}
+}
+
+// enqueueTransactions queues an event for each of the given transactions,
+// carrying the transaction as it is now stored.
+func (r *webhookRepository) enqueueTransactions(ctx context.Context, tenantID string, eventType store.WebhookEventType, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	_, err := r.pool.Exec(context.WithoutCancel(ctx), `
+		INSERT INTO webhook_deliveries (webhook_id, tenant_id, event_type, payload, next_attempt_at)
+		SELECT w.id, w.tenant_id, $2::text, `+transactionWebhookPayload+`, $4::timestamptz
+		FROM webhooks w
+		JOIN transactions t ON t.tenant_id = w.tenant_id
+		WHERE w.tenant_id = $1 AND w.enabled AND $2::text = ANY(w.events) AND t.id = ANY($3::uuid[])
+	`, tenantID, string(eventType), ids, r.now())
+	if err != nil {
+		r.logger.Warn("failed to queue webhook event", "tenant_id", tenantID, "event", eventType, "error", err)
+	}
+}
+
+func validateWebhookEvents(events []store.WebhookEventType) error {
+	if len(events) == 0 {
+		return errors.E("store.webhooks.validate", errors.InvalidInput, errors.User("at least one event is required"))
+	}
+	for _, event := range events {
+		if !store.ValidWebhookEventType(event) {
+			return errors.E("store.webhooks.validate", errors.InvalidInput, errors.User(fmt.Sprintf("unknown event %q", event)))
+		}
+	}
+	return nil
+}
+
+func webhookEventStrings(events []store.WebhookEventType) []string {
+	out := make([]string, len(events))
+	for i, event := range events {
+		out[i] = string(event)
+	}
+	return out
+}
+
+func scanWebhook(row pgx.Row) (store.Webhook, error) {
+	var webhook store.Webhook
+	var events []string
+	if err := row.Scan(
+		&webhook.ID, &webhook.TenantID, &webhook.URL, &webhook.Description, &events,
+		&webhook.Enabled, &webhook.CreatedAt, &webhook.UpdatedAt,
+	); err != nil {
+		return store.Webhook{}, err
+	}
+	webhook.Events = make([]store.WebhookEventType, len(events))
+	for i, event := range events {
+		webhook.Events[i] = store.WebhookEventType(event)
+	}
+	return webhook, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (store.WebhookDelivery, error) {
+	var delivery store.WebhookDelivery
+	err := scanWebhookDeliveryInto(row, &delivery)
+	return delivery, err
+}
+
+// scanWebhookDeliveryInto scans webhookDeliveryColumns into delivery and any
+// further columns into extra.
+func scanWebhookDeliveryInto(row pgx.Row, delivery *store.WebhookDelivery, extra ...any) error {
+	var eventType, status string
+	var payload []byte
+	dest := []any{
+		&delivery.ID, &delivery.WebhookID, &delivery.TenantID, &eventType, &payload, &status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.LastAttemptAt, &delivery.ResponseStatus, &delivery.LastError,
+		&delivery.CreatedAt, &delivery.CompletedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	delivery.EventType = store.WebhookEventType(eventType)
+	delivery.Status = store.WebhookDeliveryStatus(status)
+	delivery.Payload = payload
+	return nil
+}
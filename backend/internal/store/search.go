@@ -0,0 +1,134 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// Search fields that can scope a term (field:value) or carry a highlight.
+const (
+	SearchFieldMerchant    = "merchant"
+	SearchFieldDescription = "description"
+	SearchFieldSubject     = "subject"
+	SearchFieldBody        = "body"
+	SearchFieldLabel       = "label"
+	SearchFieldAmount      = "amount"
+)
+
+// SearchQuery is a transaction search split into free text and field-scoped
+// terms. Every scoped term must match; free text matches any searchable field.
+type SearchQuery struct {
+	Text        string
+	Merchant    []string
+	Description []string
+	Subject     []string
+	Body        []string
+	Labels      []string
+	Amounts     []AmountCondition
+}
+
+// AmountCondition compares the transaction amount against Value.
+type AmountCondition struct {
+	Op    string
+	Value float64
+}
+
+// SearchHighlight is a matched excerpt of one searchable field. Matches are
+// wrapped in <mark></mark>; the surrounding text is HTML-escaped.
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// Empty reports whether the query has no terms.
+func (q SearchQuery) Empty() bool {
+	return q.Text == "" && len(q.Merchant) == 0 && len(q.Description) == 0 && len(q.Subject) == 0 &&
+		len(q.Body) == 0 && len(q.Labels) == 0 && len(q.Amounts) == 0
+}
+
+// ParseSearchQuery parses search text such as
+// `merchant:amazon subject:"order 402" amount:>500 pune`. Double quotes group
+// words into one value; unknown prefixes are treated as free text.
+func ParseSearchQuery(raw string) (SearchQuery, error) {
+	var query SearchQuery
+	var text []string
+	for _, token := range splitSearchTokens(raw) {
+		field, value, ok := strings.Cut(token, ":")
+		value = strings.TrimSpace(strings.Trim(value, `"`))
+		if !ok || value == "" {
+			text = append(text, token)
+			continue
+		}
+		switch strings.ToLower(field) {
+		case SearchFieldMerchant:
+			query.Merchant = append(query.Merchant, value)
+		case SearchFieldDescription:
+			query.Description = append(query.Description, value)
+		case SearchFieldSubject:
+			query.Subject = append(query.Subject, value)
+		case SearchFieldBody:
+			query.Body = append(query.Body, value)
+		case SearchFieldLabel:
+			query.Labels = append(query.Labels, value)
+		case SearchFieldAmount:
+			condition, err := parseAmountCondition(value)
+			if err != nil {
+				return SearchQuery{}, err
+			}
+			query.Amounts = append(query.Amounts, condition)
+		default:
+			text = append(text, token)
+		}
+	}
+	query.Text = strings.Join(text, " ")
+	return query, nil
+}
+
+func parseAmountCondition(value string) (AmountCondition, error) {
+	op := "="
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, candidate); ok {
+			op, value = candidate, rest
+			break
+		}
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(value), ",", ""), 64)
+	if err != nil {
+		return AmountCondition{}, errors.E(
+			"store.search.parse_amount",
+			errors.InvalidInput,
+			errors.User("Amount filters must look like amount:>500, amount:<=1200 or amount:99.50."),
+			err,
+		)
+	}
+	return AmountCondition{Op: op, Value: amount}, nil
+}
+
+// splitSearchTokens splits on whitespace outside double quotes and keeps the
+// quotes so free-text phrases still reach the full-text parser intact.
+func splitSearchTokens(raw string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	got, err := ParseSearchQuery(`merchant:amazon Subject:"order 402-99" amount:>=1,500 label:trip "new delhi" http://x body:`)
+	if err != nil {
+		t.Fatalf("ParseSearchQuery() error = %v", err)
+	}
+	want := SearchQuery{
+		Text:     `"new delhi" http://x body:`,
+		Merchant: []string{"amazon"},
+		Subject:  []string{"order 402-99"},
+		Labels:   []string{"trip"},
+		Amounts:  []AmountCondition{{Op: ">=", Value: 1500}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseSearchQuery() = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseSearchQueryAmountDefaultsToEquality(t *testing.T) {
+	got, err := ParseSearchQuery("amount:99.50")
+	if err != nil {
+		t.Fatalf("ParseSearchQuery() error = %v", err)
+	}
+	if len(got.Amounts) != 1 || got.Amounts[0] != (AmountCondition{Op: "=", Value: 99.5}) || got.Text != "" {
+		t.Fatalf("ParseSearchQuery() = %#v", got)
+	}
+}
+
+func TestParseSearchQueryRejectsInvalidAmount(t *testing.T) {
+	if _, err := ParseSearchQuery("amount:>lots"); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("ParseSearchQuery() error = %v, want InvalidInput", err)
+	}
+}
@@ -0,0 +1,47 @@
+package store
+
+// SealedSecretKind names a set of values encrypted with the instance secret
+// key.
+type SealedSecretKind string
+
+const (
+	// SealedReaderClientSecret is a reader's OAuth client secret.
+	SealedReaderClientSecret SealedSecretKind = "reader_client_secret"
+	// SealedReaderOAuthToken is a reader's OAuth token.
+	SealedReaderOAuthToken SealedSecretKind = "reader_oauth_token"
+	// SealedLLMCredentials are an LLM provider's API credentials.
+	SealedLLMCredentials SealedSecretKind = "llm_credentials"
+	// SealedTOTPSecret is a user's authenticator app secret.
+	SealedTOTPSecret SealedSecretKind = "totp_secret"
+	// SealedWebhookSecret is the key a webhook's deliveries are signed with.
+	SealedWebhookSecret SealedSecretKind = "webhook_secret"
+	// SealedNotificationToken is a notification channel's access token.
+	SealedNotificationToken SealedSecretKind = "notification_token"
+	// SealedAttachment is attachment content, kept in the blob store rather
+	// than a column.
+	SealedAttachment SealedSecretKind = "attachment"
+)
+
+// SealedSecretKinds lists every kind in the order re-encryption walks them.
+var SealedSecretKinds = []SealedSecretKind{
+	SealedReaderClientSecret,
+	SealedReaderOAuthToken,
+	SealedLLMCredentials,
+	SealedTOTPSecret,
+	SealedWebhookSecret,
+	SealedNotificationToken,
+	SealedAttachment,
+}
+
+// ResealBatch reports one batch of re-encryption.
+type ResealBatch struct {
+	// Scanned counts the values read, whichever key sealed them.
+	Scanned int
+	// Resealed counts the values sealed again with the active key.
+	Resealed int
+	// Failures describe values no configured key could open; they are left
+	// unchanged.
+	Failures []string
+	// Next is the cursor for the following batch, empty after the last one.
+	Next string
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// MaxTransactionSplits caps how many allocations one transaction can carry.
+const MaxTransactionSplits = 50
+
+// splitAmountScale matches the NUMERIC(19,4) precision of stored amounts, so
+// split totals are compared in the same units the database keeps.
+const splitAmountScale = 10000
+
+// TransactionSplit is one allocation of a parent transaction's amount. When a
+// transaction has splits, analytics attribute each allocation separately
+// instead of the parent's category, bucket and labels.
+type TransactionSplit struct {
+	ID           string   `json:"id"`
+	Amount       float64  `json:"amount"`
+	Category     string   `json:"category"`
+	Bucket       string   `json:"bucket"`
+	Labels       []string `json:"labels"`
+	Counterparty string   `json:"counterparty,omitempty"`
+}
+
+// TransactionSplitInput describes one allocation to store for a transaction.
+type TransactionSplitInput struct {
+	Amount       float64
+	Category     string
+	Bucket       string
+	Labels       []string
+	Counterparty string
+}
+
+// ValidateSplits checks that splits can replace the allocation of a parent
+// transaction of the given amount. An empty list is valid and clears splits.
+func ValidateSplits(parentAmount float64, splits []TransactionSplitInput) error {
+	const op = "store.splits.validate"
+
+	if len(splits) == 0 {
+		return nil
+	}
+	if len(splits) == 1 {
+		return errors.E(op, errors.InvalidInput, errors.User("A split needs at least two parts."))
+	}
+	if len(splits) > MaxTransactionSplits {
+		return errors.E(op, errors.InvalidInput, errors.User(
+			"A transaction can have at most "+strconv.Itoa(MaxTransactionSplits)+" splits.",
+		))
+	}
+
+	var total int64
+	for _, split := range splits {
+		units := splitAmountUnits(split.Amount)
+		if units <= 0 {
+			return errors.E(op, errors.InvalidInput, errors.User("Every split amount must be greater than zero."))
+		}
+		total += units
+	}
+	if parent := splitAmountUnits(parentAmount); total != parent {
+		return errors.E(op, errors.InvalidInput, errors.User(
+			"Splits add up to "+formatSplitAmount(total)+" but the transaction amount is "+formatSplitAmount(parent)+".",
+		))
+	}
+	return nil
+}
+
+func splitAmountUnits(amount float64) int64 {
+	return int64(math.Round(amount * splitAmountScale))
+}
+
+func formatSplitAmount(units int64) string {
+	return strconv.FormatFloat(float64(units)/splitAmountScale, 'f', 2, 64)
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+func TestValidateSplits(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		splits []TransactionSplitInput
+		valid  bool
+	}{
+		{name: "empty clears", amount: 100, valid: true},
+		{name: "exact sum", amount: 1200.5, splits: []TransactionSplitInput{{Amount: 800.25}, {Amount: 400.25}}, valid: true},
+		{name: "float noise", amount: 0.3, splits: []TransactionSplitInput{{Amount: 0.1}, {Amount: 0.2}}, valid: true},
+		{name: "short", amount: 1200, splits: []TransactionSplitInput{{Amount: 800}, {Amount: 399.99}}},
+		{name: "over", amount: 1200, splits: []TransactionSplitInput{{Amount: 800}, {Amount: 500}}},
+		{name: "single part", amount: 1200, splits: []TransactionSplitInput{{Amount: 1200}}},
+		{name: "zero part", amount: 1200, splits: []TransactionSplitInput{{Amount: 1200}, {Amount: 0}}},
+		{name: "negative part", amount: 1200, splits: []TransactionSplitInput{{Amount: 1300}, {Amount: -100}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSplits(tt.amount, tt.splits)
+			if tt.valid && err != nil {
+				t.Fatalf("ValidateSplits() error = %v, want nil", err)
+			}
+			if !tt.valid && errors.WhatKind(err) != errors.InvalidInput {
+				t.Fatalf("ValidateSplits() error = %v, want InvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestValidateSplitsRejectsTooManyParts(t *testing.T) {
+	splits := make([]TransactionSplitInput, MaxTransactionSplits+1)
+	for i := range splits {
+		splits[i].Amount = 1
+	}
+	if err := ValidateSplits(float64(len(splits)), splits); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("ValidateSplits() error = %v, want InvalidInput", err)
+	}
+}
@@ -5,14 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ArionMiles/expensor/backend/internal/auth"
 	"github.com/ArionMiles/expensor/backend/internal/store"
 	"github.com/ArionMiles/expensor/backend/pkg/api"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
 // Run exercises the backend-neutral store contract. The supplied backend must
@@ -24,13 +28,94 @@ func Run(t *testing.T, backend store.Backend) {
 
 	t.Run("Health", func(t *testing.T) { testHealth(ctx, t, backend) })
 	t.Run("Auth", func(t *testing.T) { testAuth(ctx, t, backend) })
+	t.Run("MFA", func(t *testing.T) { testMFA(ctx, t, backend) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(ctx, t, backend) })
 	t.Run("Runtime", func(t *testing.T) { testRuntime(ctx, t, backend) })
 	t.Run("Scanning", func(t *testing.T) { testScanning(ctx, t, backend) })
 	t.Run("Taxonomy", func(t *testing.T) { testTaxonomy(ctx, t, backend) })
 	t.Run("Community", func(t *testing.T) { testCommunity(ctx, t, backend) })
 	t.Run("Rules", func(t *testing.T) { testRules(ctx, t, backend) })
 	t.Run("Ingestion", func(t *testing.T) { testIngestion(ctx, t, backend) })
+	t.Run("ManualTransactions", func(t *testing.T) { testManualTransactions(ctx, t, backend) })
+	t.Run("TransactionSplits", func(t *testing.T) { testTransactionSplits(ctx, t, backend) })
+	t.Run("BulkTransactions", func(t *testing.T) { testBulkTransactions(ctx, t, backend) })
+	t.Run("TransactionHistory", func(t *testing.T) { testTransactionHistory(ctx, t, backend) })
+	t.Run("Attachments", func(t *testing.T) { testAttachments(ctx, t, backend) })
+	t.Run("SharedLedgers", func(t *testing.T) { testSharedLedgers(ctx, t, backend) })
+	t.Run("Tenants", func(t *testing.T) { testTenants(ctx, t, backend) })
+	t.Run("TenantArchive", func(t *testing.T) { testTenantArchive(ctx, t, backend) })
 	t.Run("Diagnostics", func(t *testing.T) { testDiagnostics(ctx, t, backend) })
+	t.Run("LLMUsage", func(t *testing.T) { testLLMUsage(ctx, t, backend) })
+	t.Run("LLMPrompts", func(t *testing.T) { testLLMPrompts(ctx, t, backend) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(ctx, t, backend) })
+	t.Run("Notifications", func(t *testing.T) { testNotifications(ctx, t, backend) })
+	// Backups restores the whole database, so it runs last.
+	t.Run("Backups", func(t *testing.T) { testBackups(ctx, t, backend) })
+}
+
+// RunRestore restores a backup of source into target, the way a backup is
+// restored onto a new instance. Both must be connected to freshly migrated
+// empty databases of their own.
+func RunRestore(t *testing.T, source, target store.Backend) {
+	t.Helper()
+
+	ctx := context.Background()
+	tenant := createTenant(ctx, t, source, "restore")
+	userCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: tenant.ID, TenantID: tenant.ID})
+	txn, err := source.CreateTransaction(ctx, tenant, store.CreateTransactionInput{
+		Amount:       240,
+		Currency:     "INR",
+		Timestamp:    time.Date(2026, time.May, 2, 9, 0, 0, 0, time.UTC),
+		MerchantInfo: "Third Wave",
+		Category:     "Food",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	for _, label := range []string{"coffee", "work"} {
+		if err := source.AddLabel(userCtx, tenant, txn.ID, label); err != nil {
+			t.Fatalf("AddLabel(%s): %v", label, err)
+		}
+	}
+	want, err := source.ListTransactionHistory(ctx, tenant, txn.ID)
+	if err != nil || len(want) != 2 {
+		t.Fatalf("ListTransactionHistory before backup = %#v, %v; want two label changes", want, err)
+	}
+
+	tables := map[string]*strings.Builder{}
+	schema, err := source.DumpDatabase(ctx, func(table store.BackupTable) (io.Writer, error) {
+		tables[table.Name] = &strings.Builder{}
+		return tables[table.Name], nil
+	})
+	if err != nil {
+		t.Fatalf("DumpDatabase: %v", err)
+	}
+	err = target.RestoreDatabase(ctx, schema, func(table store.BackupTable) (io.Reader, error) {
+		return strings.NewReader(tables[table.Name].String()), nil
+	})
+	if err != nil {
+		t.Fatalf("RestoreDatabase into empty database: %v", err)
+	}
+
+	got, err := target.ListTransactionHistory(ctx, tenant, txn.ID)
+	if err != nil {
+		t.Fatalf("ListTransactionHistory after restore: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("history after restore = %#v, want the %d restored changes only", got, len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].BatchID != want[i].BatchID || got[i].Field != want[i].Field {
+			t.Fatalf("history[%d] after restore = %#v, want %#v", i, got[i], want[i])
+		}
+	}
+	// New changes get IDs past the restored ones.
+	if err := target.AddLabel(userCtx, tenant, txn.ID, "restored"); err != nil {
+		t.Fatalf("AddLabel after restore: %v", err)
+	}
+	if got, err := target.ListTransactionHistory(ctx, tenant, txn.ID); err != nil || len(got) != len(want)+1 {
+		t.Fatalf("history after a new label = %#v, %v; want one more change", got, err)
+	}
 }
 
 func testHealth(ctx context.Context, t *testing.T, backend store.Backend) {
@@ -102,6 +187,23 @@ func testAuth(ctx context.Context, t *testing.T, backend store.Backend) {
 	if foundByID.Email != user.Email {
 		t.Fatalf("FindUserByID Email = %q, want %q", foundByID.Email, user.Email)
 	}
+	issuer, subject := "https://sso.example", "subject-"+suffix(t)
+	if _, err := backend.FindUserByOIDCSubject(ctx, issuer, subject); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("FindUserByOIDCSubject before link error = %v, want NotFound", err)
+	}
+	if err := backend.LinkOIDCSubject(ctx, user.ID, issuer, subject); err != nil {
+		t.Fatalf("LinkOIDCSubject: %v", err)
+	}
+	linked, err := backend.FindUserByOIDCSubject(ctx, issuer, subject)
+	if err != nil || linked.ID != user.ID {
+		t.Fatalf("FindUserByOIDCSubject = %#v, %v; want %q", linked, err, user.ID)
+	}
+	if err := backend.LinkOIDCSubject(ctx, user.ID, issuer, subject+"-other"); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("LinkOIDCSubject second subject error = %v, want Conflict", err)
+	}
+	if err := backend.LinkOIDCSubject(ctx, admin.ID, issuer, subject); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("LinkOIDCSubject linked subject error = %v, want Conflict", err)
+	}
 	users, err := backend.ListUsers(ctx)
 	if err != nil {
 		t.Fatalf("ListUsers: %v", err)
@@ -142,6 +244,7 @@ func testAuth(ctx context.Context, t *testing.T, backend store.Backend) {
 		UserID:    user.ID,
 		Name:      "conformance-token",
 		TokenHash: "access-" + suffix(t),
+		Scopes:    []string{"stats:read", "transactions:read"},
 	})
 	if err != nil {
 		t.Fatalf("CreateAccessToken: %v", err)
@@ -153,9 +256,18 @@ func testAuth(ctx context.Context, t *testing.T, backend store.Backend) {
 	if len(tokens) == 0 {
 		t.Fatal("ListAccessTokens returned no tokens")
 	}
-	if _, err := backend.FindAccessTokenByHash(ctx, accessToken.TokenHash); err != nil {
+	foundToken, err := backend.FindAccessTokenByHash(ctx, accessToken.TokenHash)
+	if err != nil {
 		t.Fatalf("FindAccessTokenByHash: %v", err)
 	}
+	if !slices.Equal(foundToken.Scopes, []string{"stats:read", "transactions:read"}) {
+		t.Fatalf("FindAccessTokenByHash scopes = %v", foundToken.Scopes)
+	}
+	if _, err := backend.CreateAccessToken(ctx, store.CreateAccessTokenInput{
+		UserID: user.ID, Name: "unscoped-token", TokenHash: "unscoped-" + suffix(t),
+	}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("CreateAccessToken without scopes error = %v, want invalid input", err)
+	}
 	if err := backend.RevokeAccessToken(ctx, accessToken.ID, user.ID); err != nil {
 		t.Fatalf("RevokeAccessToken: %v", err)
 	}
@@ -197,6 +309,268 @@ func testAuth(ctx context.Context, t *testing.T, backend store.Backend) {
 	}
 }
 
+//nolint:gocognit // Conformance subtests intentionally exercise a broad backend contract in one scenario.
+func testMFA(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	user, err := backend.CreateUser(ctx, store.CreateUserInput{
+		Email:        email(t, "mfa"),
+		DisplayName:  "Conformance MFA User",
+		Role:         store.UserRoleUser,
+		AvatarKey:    "avatar-mfa",
+		PasswordHash: "hash-mfa",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	session, err := backend.CreateSession(ctx, store.CreateSessionInput{
+		UserID:    user.ID,
+		TokenHash: "session-" + suffix(t),
+		ExpiresAt: time.Now().Add(time.Hour),
+		MFA:       store.SessionMFAPending,
+	})
+	if err != nil {
+		t.Fatalf("CreateSession pending: %v", err)
+	}
+	found, err := backend.FindSessionByHash(ctx, session.TokenHash)
+	if err != nil || found.MFA != store.SessionMFAPending {
+		t.Fatalf("FindSessionByHash pending = %#v, %v", found, err)
+	}
+	complete, err := backend.CreateSession(ctx, store.CreateSessionInput{
+		UserID:    user.ID,
+		TokenHash: "session-complete-" + suffix(t),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil || complete.MFA != store.SessionMFAComplete {
+		t.Fatalf("CreateSession default MFA = %#v, %v", complete, err)
+	}
+
+	factors, err := backend.GetMFAFactors(ctx, user.ID)
+	if err != nil || factors.Enrolled() || factors.RecoveryCodes != 0 {
+		t.Fatalf("GetMFAFactors empty = %#v, %v", factors, err)
+	}
+	if _, err := backend.GetTOTPFactor(ctx, user.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetTOTPFactor missing err = %v, want not found", err)
+	}
+
+	secret := []byte("12345678901234567890")
+	if err := backend.SetPendingTOTP(ctx, user.ID, []byte("replaced-before-confirmation")); err != nil {
+		t.Fatalf("SetPendingTOTP first: %v", err)
+	}
+	if err := backend.SetPendingTOTP(ctx, user.ID, secret); err != nil {
+		t.Fatalf("SetPendingTOTP second: %v", err)
+	}
+	if err := backend.UseTOTPStep(ctx, user.ID, 5); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("UseTOTPStep unconfirmed err = %v, want conflict", err)
+	}
+	if err := backend.ConfirmTOTP(ctx, user.ID, 10); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	if err := backend.ConfirmTOTP(ctx, user.ID, 11); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("ConfirmTOTP twice err = %v, want not found", err)
+	}
+	if err := backend.SetPendingTOTP(ctx, user.ID, secret); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("SetPendingTOTP confirmed err = %v, want conflict", err)
+	}
+	factor, err := backend.GetTOTPFactor(ctx, user.ID)
+	if err != nil || string(factor.Secret) != string(secret) || factor.ConfirmedAt == nil || factor.LastUsedStep != 10 {
+		t.Fatalf("GetTOTPFactor = %#v, %v", factor, err)
+	}
+	if err := backend.UseTOTPStep(ctx, user.ID, 10); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("UseTOTPStep replay err = %v, want conflict", err)
+	}
+	if err := backend.UseTOTPStep(ctx, user.ID, 11); err != nil {
+		t.Fatalf("UseTOTPStep: %v", err)
+	}
+
+	if err := backend.ReplaceRecoveryCodes(ctx, user.ID, []string{"old-a", "old-b"}); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes old: %v", err)
+	}
+	if err := backend.ReplaceRecoveryCodes(ctx, user.ID, []string{"code-a", "code-b"}); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes: %v", err)
+	}
+	if err := backend.UseRecoveryCode(ctx, user.ID, "old-a"); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("UseRecoveryCode replaced err = %v, want not found", err)
+	}
+	if err := backend.UseRecoveryCode(ctx, user.ID, "code-a"); err != nil {
+		t.Fatalf("UseRecoveryCode: %v", err)
+	}
+	if err := backend.UseRecoveryCode(ctx, user.ID, "code-a"); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("UseRecoveryCode reuse err = %v, want not found", err)
+	}
+
+	passkey, err := backend.CreatePasskey(ctx, store.CreatePasskeyInput{
+		UserID:       user.ID,
+		Name:         "Security key",
+		CredentialID: []byte("credential-" + suffix(t)),
+		PublicKey:    []byte("public-key"),
+		Algorithm:    -7,
+		SignCount:    4,
+		Transports:   []string{"usb", "nfc"},
+	})
+	if err != nil {
+		t.Fatalf("CreatePasskey: %v", err)
+	}
+	if passkey.ID == "" || passkey.SignCount != 4 || len(passkey.Transports) != 2 || passkey.LastUsedAt != nil {
+		t.Fatalf("CreatePasskey = %#v", passkey)
+	}
+	if _, err := backend.CreatePasskey(ctx, store.CreatePasskeyInput{
+		UserID:       user.ID,
+		Name:         "Duplicate",
+		CredentialID: passkey.CredentialID,
+		PublicKey:    []byte("public-key"),
+		Algorithm:    -7,
+	}); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("CreatePasskey duplicate err = %v, want conflict", err)
+	}
+	if err := backend.UsePasskey(ctx, passkey.ID, 9); err != nil {
+		t.Fatalf("UsePasskey: %v", err)
+	}
+	passkeys, err := backend.ListPasskeys(ctx, user.ID)
+	if err != nil || len(passkeys) != 1 || passkeys[0].SignCount != 9 || passkeys[0].LastUsedAt == nil {
+		t.Fatalf("ListPasskeys = %#v, %v", passkeys, err)
+	}
+
+	factors, err = backend.GetMFAFactors(ctx, user.ID)
+	if err != nil || !factors.TOTP || factors.Passkeys != 1 || factors.RecoveryCodes != 1 {
+		t.Fatalf("GetMFAFactors enrolled = %#v, %v", factors, err)
+	}
+
+	other, err := backend.CreateUser(ctx, store.CreateUserInput{
+		Email:        email(t, "mfa-other"),
+		DisplayName:  "Conformance MFA Other",
+		Role:         store.UserRoleUser,
+		AvatarKey:    "avatar-mfa-other",
+		PasswordHash: "hash-mfa-other",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser other: %v", err)
+	}
+	if err := backend.DeletePasskey(ctx, other.ID, passkey.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("DeletePasskey other user err = %v, want not found", err)
+	}
+	if err := backend.DeletePasskey(ctx, user.ID, passkey.ID); err != nil {
+		t.Fatalf("DeletePasskey: %v", err)
+	}
+	if err := backend.DeleteTOTP(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteTOTP: %v", err)
+	}
+	if err := backend.DeleteTOTP(ctx, user.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("DeleteTOTP twice err = %v, want not found", err)
+	}
+
+	settings, err := backend.GetMFASettings(ctx)
+	if err != nil || settings.Required {
+		t.Fatalf("GetMFASettings = %#v, %v; want not required", settings, err)
+	}
+	required := true
+	settings, err = backend.PatchMFASettings(ctx, store.MFASettingsPatch{Required: &required})
+	if err != nil || !settings.Required {
+		t.Fatalf("PatchMFASettings required = %#v, %v", settings, err)
+	}
+	settings, err = backend.PatchMFASettings(ctx, store.MFASettingsPatch{})
+	if err != nil || !settings.Required {
+		t.Fatalf("PatchMFASettings empty = %#v, %v", settings, err)
+	}
+	required = false
+	if _, err := backend.PatchMFASettings(ctx, store.MFASettingsPatch{Required: &required}); err != nil {
+		t.Fatalf("PatchMFASettings reset: %v", err)
+	}
+}
+
+func testSessions(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	user, err := backend.CreateUser(ctx, store.CreateUserInput{
+		Email:        email(t, "sessions"),
+		DisplayName:  "Conformance Sessions User",
+		Role:         store.UserRoleUser,
+		AvatarKey:    "avatar-sessions",
+		PasswordHash: "hash-sessions",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	other, err := backend.CreateUser(ctx, store.CreateUserInput{
+		Email:        email(t, "sessions-other"),
+		DisplayName:  "Conformance Other Sessions User",
+		Role:         store.UserRoleUser,
+		AvatarKey:    "avatar-sessions",
+		PasswordHash: "hash-sessions",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser other: %v", err)
+	}
+
+	create := func(userID, name string, ttl time.Duration) *store.Session {
+		t.Helper()
+		session, err := backend.CreateSession(ctx, store.CreateSessionInput{
+			UserID:    userID,
+			TokenHash: "session-" + name + "-" + suffix(t),
+			ExpiresAt: time.Now().Add(ttl),
+			IPAddress: "192.0.2.10",
+			UserAgent: "Conformance/" + name,
+		})
+		if err != nil {
+			t.Fatalf("CreateSession %s: %v", name, err)
+		}
+		return session
+	}
+	current := create(user.ID, "current", time.Hour)
+	laptop := create(user.ID, "laptop", time.Hour)
+	phone := create(user.ID, "phone", time.Hour)
+	create(user.ID, "expired", -time.Minute)
+	foreign := create(other.ID, "foreign", time.Hour)
+
+	if current.IPAddress != "192.0.2.10" || current.UserAgent != "Conformance/current" {
+		t.Fatalf("CreateSession client = %q %q", current.IPAddress, current.UserAgent)
+	}
+	if err := backend.TouchSession(ctx, laptop.ID, "198.51.100.7"); err != nil {
+		t.Fatalf("TouchSession: %v", err)
+	}
+	sessions, err := backend.ListSessions(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 3 || sessions[0].ID != laptop.ID {
+		t.Fatalf("ListSessions = %#v, want 3 active sessions with the touched one first", sessions)
+	}
+	if sessions[0].LastUsedAt == nil || sessions[0].IPAddress != "198.51.100.7" {
+		t.Fatalf("touched session = %#v", sessions[0])
+	}
+
+	if err := backend.RevokeUserSession(ctx, user.ID, foreign.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("RevokeUserSession other user's session error = %v, want not found", err)
+	}
+	if err := backend.RevokeUserSession(ctx, user.ID, phone.ID); err != nil {
+		t.Fatalf("RevokeUserSession: %v", err)
+	}
+	if err := backend.RevokeUserSession(ctx, user.ID, phone.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("RevokeUserSession twice error = %v, want not found", err)
+	}
+	revoked, err := backend.RevokeOtherSessions(ctx, user.ID, current.ID)
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions: %v", err)
+	}
+	if revoked != 1 {
+		t.Fatalf("RevokeOtherSessions = %d, want 1", revoked)
+	}
+	sessions, err = backend.ListSessions(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListSessions after revoke: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != current.ID {
+		t.Fatalf("ListSessions after revoke = %#v, want only the kept session", sessions)
+	}
+	if revoked, err := backend.RevokeOtherSessions(ctx, user.ID, ""); err != nil || revoked != 1 {
+		t.Fatalf("RevokeOtherSessions without kept session = %d, %v; want 1", revoked, err)
+	}
+	if found, err := backend.FindSessionByHash(ctx, foreign.TokenHash); err != nil || found.RevokedAt != nil {
+		t.Fatalf("other user's session = %#v, %v; want untouched", found, err)
+	}
+}
+
 //nolint:gocognit // Conformance subtests intentionally exercise a broad backend contract in one scenario.
 func testRuntime(ctx context.Context, t *testing.T, backend store.Backend) {
 	t.Helper()
@@ -626,6 +1000,8 @@ func testIngestion(ctx context.Context, t *testing.T, backend store.Backend) {
 			Source:       api.Source{Type: "credit-card", Label: "Example Card", Bank: "Example"},
 			Description:  "conformance transaction",
 			Labels:       []string{"conf-label"},
+			EmailSubject: "Your order 402-7781 has shipped",
+			EmailBody:    "Delivery to Pune by <Friday> & weekend",
 		}},
 	}); err != nil {
 		t.Fatalf("Write: %v", err)
@@ -685,6 +1061,38 @@ func testIngestion(ctx context.Context, t *testing.T, backend store.Backend) {
 	if searchResult.Total != 1 || len(searchRows) != 1 {
 		t.Fatalf("SearchTransactions total=%d len=%d rows=%#v", searchResult.Total, len(searchRows), searchRows)
 	}
+	for _, query := range []string{"pune", "402-7781", "subject:shipped body:pune amount:>100 label:batch", `merchant:"conformance merchant"`} {
+		searchRows, _, err = backend.SearchTransactions(ctx, tenant, query, store.ListFilter{Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("SearchTransactions(%q): %v", query, err)
+		}
+		if len(searchRows) != 1 {
+			t.Fatalf("SearchTransactions(%q) len=%d, want 1", query, len(searchRows))
+		}
+	}
+	searchRows, _, err = backend.SearchTransactions(ctx, tenant, "body:pune", store.ListFilter{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("SearchTransactions highlights: %v", err)
+	}
+	if len(searchRows) != 1 || len(searchRows[0].Highlights) != 1 {
+		t.Fatalf("SearchTransactions highlights rows = %#v, want one body highlight", searchRows)
+	}
+	if h := searchRows[0].Highlights[0]; h.Field != store.SearchFieldBody ||
+		!strings.Contains(h.Snippet, "<mark>Pune</mark>") || !strings.Contains(h.Snippet, "&lt;Friday&gt;") {
+		t.Fatalf("SearchTransactions highlight = %#v, want escaped body snippet marking Pune", h)
+	}
+	for _, query := range []string{"subject:pune", "amount:<100", "label:missing"} {
+		searchRows, _, err = backend.SearchTransactions(ctx, tenant, query, store.ListFilter{Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("SearchTransactions(%q): %v", query, err)
+		}
+		if len(searchRows) != 0 {
+			t.Fatalf("SearchTransactions(%q) len=%d, want 0", query, len(searchRows))
+		}
+	}
+	if _, _, err := backend.SearchTransactions(ctx, tenant, "amount:>abc", store.ListFilter{Page: 1, PageSize: 10}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("SearchTransactions invalid amount err = %v, want invalid input", err)
+	}
 
 	facets, err := backend.GetFacets(ctx, tenant)
 	if err != nil {
@@ -758,55 +1166,1347 @@ func testIngestion(ctx context.Context, t *testing.T, backend store.Backend) {
 	}
 }
 
-func testDiagnostics(ctx context.Context, t *testing.T, backend store.Backend) {
+func testManualTransactions(ctx context.Context, t *testing.T, backend store.Backend) {
 	t.Helper()
 
-	tenant := createTenant(ctx, t, backend, "diagnostics")
-	receivedAt := time.Now().UTC().Truncate(time.Microsecond)
+	tenant := createTenant(ctx, t, backend, "manual")
+	timestamp := time.Date(2026, time.February, 3, 18, 0, 0, 0, time.UTC)
+	created, err := backend.CreateTransaction(ctx, tenant, store.CreateTransactionInput{
+		Amount:       80,
+		Currency:     "INR",
+		Timestamp:    timestamp,
+		MerchantInfo: "Corner Chai Stall",
+		Category:     "Food",
+		Description:  "team snacks",
+		SourceLabel:  "Cash",
+		Labels:       []string{"reimbursable"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	if created.ID == "" || created.Amount != 80 || !created.Timestamp.Equal(timestamp) || created.Category != "Food" ||
+		created.Source.Type != store.SourceTypeManual || created.Source.Label != "Cash" || !containsString(created.Labels, "reimbursable") {
+		t.Fatalf("CreateTransaction = %#v", created)
+	}
 
-	if err := backend.RecordExtractionDiagnostic(ctx, tenant, api.ExtractionDiagnostic{
-		Reader:         "gmail",
-		MessageID:      "diag-" + suffix(t),
-		Source:         "Example",
-		SenderEmail:    "sender@example.test",
-		Subject:        "transaction alert",
-		EmailBody:      "body",
-		ReceivedAt:     &receivedAt,
-		Snippet:        "snippet",
-		RuleName:       "diag rule",
-		AmountRegex:    `INR\s+([0-9.]+)`,
-		MerchantRegex:  `at\s+(.+)$`,
-		CurrencyRegex:  `(INR)`,
-		FailureReasons: []string{api.FailureMerchantEmpty},
+	rows, result, err := backend.ListTransactions(ctx, tenant, store.ListFilter{Page: 1, PageSize: 10, SourceType: store.SourceTypeManual})
+	if err != nil {
+		t.Fatalf("ListTransactions: %v", err)
+	}
+	if result.Total != 1 || len(rows) != 1 || rows[0].ID != created.ID {
+		t.Fatalf("ListTransactions manual total=%d rows=%#v", result.Total, rows)
+	}
+	stats, err := backend.GetStats(ctx, tenant, "INR")
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.TotalCount != 1 || stats.TotalBase != 80 {
+		t.Fatalf("GetStats = %#v, want manual transaction counted", stats)
+	}
+
+	if err := backend.Write(ctx, store.IngestionBatch{
+		Tenant: tenant,
+		Transactions: []*api.TransactionDetails{{
+			MessageID:    "message-" + suffix(t),
+			Amount:       10,
+			Timestamp:    timestamp.Format(time.RFC3339),
+			MerchantInfo: "Email Merchant",
+			Source:       api.Source{Type: "credit-card"},
+		}},
 	}); err != nil {
-		t.Fatalf("RecordExtractionDiagnostic: %v", err)
+		t.Fatalf("Write: %v", err)
+	}
+	emailRows, _, err := backend.ListTransactions(ctx, tenant, store.ListFilter{Page: 1, PageSize: 10, Merchant: "Email Merchant"})
+	if err != nil || len(emailRows) != 1 {
+		t.Fatalf("ListTransactions email rows=%#v err=%v", emailRows, err)
+	}
+	if err := backend.DeleteTransaction(ctx, tenant, emailRows[0].ID); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("DeleteTransaction email err = %v, want conflict", err)
 	}
 
-	rows, err := backend.ListExtractionDiagnostics(ctx, tenant, store.DiagnosticFilter{Status: store.DiagnosticStatusOpen, Limit: 10})
-	if err != nil {
-		t.Fatalf("ListExtractionDiagnostics: %v", err)
+	if err := backend.DeleteTransaction(ctx, tenant, created.ID); err != nil {
+		t.Fatalf("DeleteTransaction: %v", err)
 	}
-	if len(rows) != 1 {
-		t.Fatalf("ListExtractionDiagnostics len=%d rows=%#v", len(rows), rows)
+	if _, err := backend.GetTransaction(ctx, tenant, created.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetTransaction after delete err = %v, want not found", err)
 	}
-	if rows[0].SenderEmail != "sender@example.test" || rows[0].Status != store.DiagnosticStatusOpen {
-		t.Fatalf("ListExtractionDiagnostics returned invalid row: %#v", rows[0])
+	if err := backend.DeleteTransaction(ctx, tenant, created.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("DeleteTransaction again err = %v, want not found", err)
+	}
+}
+
+func testBulkTransactions(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	tenant := createTenant(ctx, t, backend, "bulk")
+	other := createTenant(ctx, t, backend, "bulk-other")
+	timestamp := time.Date(2026, time.April, 2, 9, 0, 0, 0, time.UTC)
+	create := func(tenant store.Tenant, merchant string, labels ...string) *store.Transaction {
+		t.Helper()
+		txn, err := backend.CreateTransaction(ctx, tenant, store.CreateTransactionInput{
+			Amount:       250,
+			Currency:     "INR",
+			Timestamp:    timestamp,
+			MerchantInfo: merchant,
+			Labels:       labels,
+		})
+		if err != nil {
+			t.Fatalf("CreateTransaction %s: %v", merchant, err)
+		}
+		return txn
+	}
+	first := create(tenant, "Uber Trip", "work")
+	second := create(tenant, "Uber Eats", "work")
+	foreign := create(other, "Uber Trip")
+	missing := "00000000-0000-0000-0000-000000000000"
+
+	category := "Travel"
+	dryRun, err := backend.BulkUpdateTransactions(ctx, tenant, store.BulkTransactionInput{
+		IDs:    []string{first.ID},
+		Update: store.TransactionUpdate{Category: &category},
+		DryRun: true,
+	})
+	if err != nil || !dryRun.DryRun || dryRun.Matched != 1 || dryRun.Updated != 0 || dryRun.Results[0].Status != store.BulkRowMatched {
+		t.Fatalf("BulkUpdateTransactions dry run = %#v, err = %v", dryRun, err)
+	}
+	if got, _ := backend.GetTransaction(ctx, tenant, first.ID); got.Category == category {
+		t.Fatal("dry run changed the transaction")
 	}
 
-	got, err := backend.GetExtractionDiagnostic(ctx, tenant, rows[0].ID)
+	result, err := backend.BulkUpdateTransactions(ctx, tenant, store.BulkTransactionInput{
+		IDs:          []string{first.ID, missing, foreign.ID},
+		Update:       store.TransactionUpdate{Category: &category},
+		AddLabels:    []string{"trip"},
+		RemoveLabels: []string{"work"},
+	})
+	if err != nil || result.Updated != 1 || len(result.Results) != 3 ||
+		result.Results[0].Status != store.BulkRowUpdated || result.Results[1].Status != store.BulkRowNotFound ||
+		result.Results[2].Status != store.BulkRowNotFound {
+		t.Fatalf("BulkUpdateTransactions ids = %#v, err = %v", result, err)
+	}
+	got, err := backend.GetTransaction(ctx, tenant, first.ID)
+	if err != nil || got.Category != category || !containsString(got.Labels, "trip") || containsString(got.Labels, "work") {
+		t.Fatalf("GetTransaction after bulk = %#v, err = %v", got, err)
+	}
+	if got, _ := backend.GetTransaction(ctx, other, foreign.ID); got.Category == category {
+		t.Fatal("bulk update changed another tenant's transaction")
+	}
+
+	muted := true
+	result, err = backend.BulkUpdateTransactions(ctx, tenant, store.BulkTransactionInput{
+		Filter:     &store.ListFilter{Merchant: "Uber"},
+		Muted:      &muted,
+		MuteReason: "reimbursed",
+	})
+	if err != nil || result.Updated != 2 || len(result.Results) != 2 {
+		t.Fatalf("BulkUpdateTransactions filter = %#v, err = %v", result, err)
+	}
+	got, err = backend.GetTransaction(ctx, tenant, second.ID)
+	if err != nil || !got.Muted || got.MuteReason != "reimbursed" || !containsString(got.Labels, "work") {
+		t.Fatalf("GetTransaction after filter mute = %#v, err = %v", got, err)
+	}
+
+	if _, err := backend.BulkUpdateTransactions(ctx, tenant, store.BulkTransactionInput{IDs: []string{first.ID}}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("BulkUpdateTransactions without changes err = %v, want invalid input", err)
+	}
+}
+
+func testTenantArchive(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	source := createTenant(ctx, t, backend, "archive-source")
+	if err := backend.CreateLabel(ctx, source, "coffee", "#8b5cf6"); err != nil {
+		t.Fatalf("CreateLabel: %v", err)
+	}
+	if err := backend.SetAppConfig(ctx, source, "base_currency", "EUR"); err != nil {
+		t.Fatalf("SetAppConfig: %v", err)
+	}
+	if err := backend.SetAppConfig(ctx, source, "reader.gmail.last_scan_at", "2026-04-01T00:00:00Z"); err != nil {
+		t.Fatalf("SetAppConfig(reader state): %v", err)
+	}
+	if err := backend.SetReaderConfig(ctx, source, "gmail", json.RawMessage(`{"labels":["INBOX"]}`)); err != nil {
+		t.Fatalf("SetReaderConfig: %v", err)
+	}
+	if _, err := backend.CreateRule(ctx, source, store.RuleRow{
+		Name: "Archive rule", SenderEmails: []string{"alerts@example.com"},
+		AmountRegex: `INR\s+([0-9.]+)`, MerchantRegex: `at\s+(.+)`,
+	}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	txn, err := backend.CreateTransaction(ctx, source, store.CreateTransactionInput{
+		Amount:       120,
+		Currency:     "INR",
+		Timestamp:    time.Date(2026, time.April, 6, 9, 0, 0, 0, time.UTC),
+		MerchantInfo: "Third Wave",
+		Category:     "Food",
+		Description:  "beans",
+		Labels:       []string{"coffee"},
+	})
 	if err != nil {
-		t.Fatalf("GetExtractionDiagnostic: %v", err)
+		t.Fatalf("CreateTransaction: %v", err)
 	}
-	if got.ID != rows[0].ID {
-		t.Fatalf("GetExtractionDiagnostic ID = %q, want %q", got.ID, rows[0].ID)
+	if err := backend.SetTransactionSplits(ctx, source, txn.ID, []store.TransactionSplitInput{{Amount: 70}, {Amount: 50, Counterparty: "Priya"}}); err != nil {
+		t.Fatalf("SetTransactionSplits: %v", err)
 	}
 
-	updated, err := backend.UpdateExtractionDiagnosticStatus(ctx, tenant, rows[0].ID, store.DiagnosticStatusResolved)
+	archive, err := backend.ExportTenantArchive(ctx, source)
 	if err != nil {
-		t.Fatalf("UpdateExtractionDiagnosticStatus: %v", err)
+		t.Fatalf("ExportTenantArchive: %v", err)
 	}
-	if updated.Status != store.DiagnosticStatusResolved || updated.ResolvedAt == nil {
-		t.Fatalf("UpdateExtractionDiagnosticStatus returned invalid row: %#v", updated)
+	if archive.Format != store.TenantArchiveFormat || archive.Counts[store.ArchiveSectionTransactions] != 1 ||
+		archive.Preferences["base_currency"] != "EUR" || len(archive.Rules) != 1 || len(archive.Readers) != 1 {
+		t.Fatalf("ExportTenantArchive = %#v", archive)
+	}
+	if _, ok := archive.Preferences["reader.gmail.last_scan_at"]; ok {
+		t.Fatal("archive holds reader scan state")
+	}
+	if got := archive.Transactions[0]; len(got.Splits) != 2 || len(got.Labels) != 1 || len(got.LabelSources) != 1 {
+		t.Fatalf("archived transaction = %#v", got)
+	}
+
+	// The source tenant still exists, so every archived ID is taken.
+	target := createTenant(ctx, t, backend, "archive-target")
+	result, err := backend.ImportTenantArchive(ctx, target, *archive, store.ArchiveConflictSkip)
+	if err != nil {
+		t.Fatalf("ImportTenantArchive: %v", err)
+	}
+	if result.Sections[store.ArchiveSectionTransactions].Created != 1 || result.Sections[store.ArchiveSectionRules].Created != 1 ||
+		result.RemappedIDs != 2 {
+		t.Fatalf("ImportTenantArchive = %#v", result)
+	}
+	imported, _, err := backend.ListTransactions(ctx, target, store.ListFilter{Page: 1, PageSize: 10})
+	if err != nil || len(imported) != 1 {
+		t.Fatalf("ListTransactions(target) = %#v, err = %v", imported, err)
+	}
+	restored, err := backend.GetTransaction(ctx, target, imported[0].ID)
+	if err != nil || restored.ID == txn.ID || restored.Description != "beans" || len(restored.Splits) != 2 ||
+		len(restored.Labels) != 1 || restored.Labels[0] != "coffee" {
+		t.Fatalf("GetTransaction(target) = %#v, err = %v", restored, err)
+	}
+
+	edited := "decaf"
+	if err := backend.UpdateTransaction(ctx, target, restored.ID, store.TransactionUpdate{Description: &edited}); err != nil {
+		t.Fatalf("UpdateTransaction: %v", err)
+	}
+	again, err := backend.ImportTenantArchive(ctx, target, *archive, store.ArchiveConflictSkip)
+	if err != nil || again.Sections[store.ArchiveSectionTransactions].Skipped != 1 || again.Sections[store.ArchiveSectionLabels].Skipped != 1 {
+		t.Fatalf("ImportTenantArchive(skip) = %#v, err = %v", again, err)
+	}
+	if got, _ := backend.GetTransaction(ctx, target, restored.ID); got == nil || got.Description != edited {
+		t.Fatalf("skip changed the transaction: %#v", got)
+	}
+
+	overwrite, err := backend.ImportTenantArchive(ctx, target, *archive, store.ArchiveConflictOverwrite)
+	if err != nil || overwrite.Sections[store.ArchiveSectionTransactions].Updated != 1 {
+		t.Fatalf("ImportTenantArchive(overwrite) = %#v, err = %v", overwrite, err)
+	}
+	if got, _ := backend.GetTransaction(ctx, target, restored.ID); got == nil || got.Description != "beans" || len(got.Splits) != 2 {
+		t.Fatalf("overwrite did not restore the transaction: %#v", got)
+	}
+	history, err := backend.ListTransactionHistory(ctx, target, restored.ID)
+	if err != nil || len(history) == 0 || history[0].Cause != store.ChangeCauseImport {
+		t.Fatalf("ListTransactionHistory(target) = %#v, err = %v", history, err)
+	}
+
+	bad := *archive
+	bad.Version = store.TenantArchiveVersion + 1
+	if _, err := backend.ImportTenantArchive(ctx, target, bad, store.ArchiveConflictSkip); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("ImportTenantArchive(newer version) error = %v, want InvalidInput", err)
+	}
+}
+
+func testAttachments(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	tenant := createTenant(ctx, t, backend, "attachments")
+	other := createTenant(ctx, t, backend, "attachments-other")
+	messageID := "message-" + suffix(t)
+	batch := store.IngestionBatch{
+		Tenant: tenant,
+		Transactions: []*api.TransactionDetails{{
+			MessageID:    messageID,
+			Amount:       4200,
+			Currency:     "INR",
+			Timestamp:    time.Date(2026, time.April, 9, 9, 0, 0, 0, time.UTC).Format(time.RFC3339),
+			MerchantInfo: "Croma",
+			Attachments:  []api.Attachment{{Filename: "invoice.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.7 invoice")}},
+		}},
+	}
+	for range 2 {
+		if err := backend.Write(ctx, batch); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	rows, _, err := backend.ListTransactions(ctx, tenant, store.ListFilter{Page: 1, PageSize: 10})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("ListTransactions = %#v, err = %v", rows, err)
+	}
+	txnID := rows[0].ID
+
+	attachments, err := backend.ListAttachments(ctx, tenant, txnID)
+	if err != nil {
+		t.Fatalf("ListAttachments: %v", err)
+	}
+	if len(attachments) != 1 || attachments[0].Source != store.AttachmentSourceEmail || attachments[0].Filename != "invoice.pdf" {
+		t.Fatalf("ListAttachments after repeated ingestion = %#v, want one email attachment", attachments)
+	}
+
+	uploaded, err := backend.CreateAttachment(ctx, tenant, txnID, store.NewAttachment{
+		Filename:    "../receipts/warranty card.pdf",
+		ContentType: "application/pdf",
+		Data:        []byte("%PDF-1.4 warranty"),
+		Source:      store.AttachmentSourceUpload,
+	})
+	if err != nil {
+		t.Fatalf("CreateAttachment: %v", err)
+	}
+	if uploaded.Filename != "warranty card.pdf" || uploaded.Size != 17 || uploaded.SHA256 == "" {
+		t.Fatalf("CreateAttachment = %#v", uploaded)
+	}
+	meta, data, err := backend.GetAttachment(ctx, tenant, txnID, uploaded.ID)
+	if err != nil || meta.ID != uploaded.ID || string(data) != "%PDF-1.4 warranty" {
+		t.Fatalf("GetAttachment = %#v, %q, err = %v", meta, data, err)
+	}
+	if _, _, err := backend.GetAttachment(ctx, other, txnID, uploaded.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetAttachment from another tenant kind = %v, want not found", errors.WhatKind(err))
+	}
+	if _, err := backend.CreateAttachment(ctx, other, txnID, store.NewAttachment{Filename: "x.pdf", Data: []byte("x")}); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("CreateAttachment on another tenant's transaction kind = %v, want not found", errors.WhatKind(err))
+	}
+	if _, err := backend.CreateAttachment(ctx, tenant, txnID, store.NewAttachment{Filename: "empty.pdf"}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("CreateAttachment empty kind = %v, want invalid input", errors.WhatKind(err))
+	}
+
+	if err := backend.DeleteAttachment(ctx, tenant, txnID, uploaded.ID); err != nil {
+		t.Fatalf("DeleteAttachment: %v", err)
+	}
+	if err := backend.DeleteAttachment(ctx, tenant, txnID, uploaded.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("DeleteAttachment again kind = %v, want not found", errors.WhatKind(err))
+	}
+
+	manual, err := backend.CreateTransaction(ctx, tenant, store.CreateTransactionInput{
+		Amount:       350,
+		Currency:     "INR",
+		Timestamp:    time.Date(2026, time.April, 10, 19, 0, 0, 0, time.UTC),
+		MerchantInfo: "Local Pharmacy",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	bill := store.NewAttachment{Filename: "bill.jpg", ContentType: "image/jpeg", Data: []byte("jpeg")}
+	if _, err := backend.CreateAttachment(ctx, tenant, manual.ID, bill); err != nil {
+		t.Fatalf("CreateAttachment on manual transaction: %v", err)
+	}
+	if err := backend.DeleteTransaction(ctx, tenant, manual.ID); err != nil {
+		t.Fatalf("DeleteTransaction: %v", err)
+	}
+	if _, err := backend.ListAttachments(ctx, tenant, manual.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("ListAttachments after DeleteTransaction kind = %v, want not found", errors.WhatKind(err))
+	}
+}
+
+func testTransactionHistory(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	tenant := createTenant(ctx, t, backend, "history")
+	userCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: tenant.ID, TenantID: tenant.ID})
+	txn, err := backend.CreateTransaction(ctx, tenant, store.CreateTransactionInput{
+		Amount:       90,
+		Currency:     "INR",
+		Timestamp:    time.Date(2026, time.April, 5, 8, 0, 0, 0, time.UTC),
+		MerchantInfo: "Blue Tokai",
+		Category:     "Shopping",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+
+	description := "morning coffee"
+	if err := backend.UpdateTransaction(userCtx, tenant, txn.ID, store.TransactionUpdate{Description: &description}); err != nil {
+		t.Fatalf("UpdateTransaction: %v", err)
+	}
+	if _, err := backend.ApplyCategoryByMerchant(ctx, tenant, "Food", "Blue Tokai"); err != nil {
+		t.Fatalf("ApplyCategoryByMerchant: %v", err)
+	}
+	if err := backend.AddLabel(userCtx, tenant, txn.ID, "coffee"); err != nil {
+		t.Fatalf("AddLabel: %v", err)
+	}
+
+	history, err := backend.ListTransactionHistory(ctx, tenant, txn.ID)
+	if err != nil {
+		t.Fatalf("ListTransactionHistory: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("ListTransactionHistory = %#v, want label, category and description changes", history)
+	}
+	label, mapping, edit := history[0], history[1], history[2]
+	if label.Field != store.ChangeFieldLabel || label.OldValue != nil || label.NewValue == nil || *label.NewValue != "coffee" {
+		t.Fatalf("label change = %#v", label)
+	}
+	if mapping.Field != "category" || mapping.Cause != store.ChangeCauseMerchantMapping ||
+		mapping.OldValue == nil || *mapping.OldValue != "Shopping" || *mapping.NewValue != "Food" || mapping.ActorID != "" {
+		t.Fatalf("mapping change = %#v", mapping)
+	}
+	if edit.Field != "description" || edit.Cause != store.ChangeCauseUserEdit || edit.ActorID != tenant.ID || edit.ActorEmail == "" {
+		t.Fatalf("edit change = %#v", edit)
+	}
+
+	revert, err := backend.RevertChangeBatch(userCtx, tenant, mapping.BatchID)
+	if err != nil || revert.Reverted != 1 || revert.Skipped != 0 || revert.BatchID == "" || revert.BatchID == mapping.BatchID {
+		t.Fatalf("RevertChangeBatch = %#v, err = %v", revert, err)
+	}
+	got, err := backend.GetTransaction(ctx, tenant, txn.ID)
+	if err != nil || got.Category != "Shopping" || got.Description != description {
+		t.Fatalf("GetTransaction after revert = %#v, err = %v", got, err)
+	}
+	history, err = backend.ListTransactionHistory(ctx, tenant, txn.ID)
+	if err != nil || len(history) != 4 || history[0].Cause != store.ChangeCauseRevert || history[0].BatchID != revert.BatchID {
+		t.Fatalf("ListTransactionHistory after revert = %#v, err = %v", history, err)
+	}
+	again, err := backend.RevertChangeBatch(userCtx, tenant, mapping.BatchID)
+	if err != nil || again.Reverted != 0 || again.Skipped != 1 {
+		t.Fatalf("RevertChangeBatch again = %#v, err = %v", again, err)
+	}
+
+	if _, err := backend.RevertChangeBatch(ctx, tenant, label.BatchID); err != nil {
+		t.Fatalf("RevertChangeBatch label: %v", err)
+	}
+	if got, _ := backend.GetTransaction(ctx, tenant, txn.ID); containsString(got.Labels, "coffee") {
+		t.Fatalf("labels after revert = %v, want coffee removed", got.Labels)
+	}
+
+	other := createTenant(ctx, t, backend, "history-other")
+	if _, err := backend.RevertChangeBatch(ctx, other, edit.BatchID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("RevertChangeBatch other tenant err = %v, want not found", err)
+	}
+	if _, err := backend.ListTransactionHistory(ctx, other, txn.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("ListTransactionHistory other tenant err = %v, want not found", err)
+	}
+}
+
+func testTransactionSplits(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	tenant := createTenant(ctx, t, backend, "splits")
+	txn, err := backend.CreateTransaction(ctx, tenant, store.CreateTransactionInput{
+		Amount:       1200,
+		Currency:     "INR",
+		Timestamp:    time.Date(2026, time.March, 7, 11, 0, 0, 0, time.UTC),
+		MerchantInfo: "Big Basket",
+		Category:     "Groceries",
+		Bucket:       "Needs",
+		Labels:       []string{"weekly"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+
+	if err := backend.SetTransactionSplits(ctx, tenant, txn.ID, []store.TransactionSplitInput{
+		{Amount: 800, Category: "Groceries"},
+		{Amount: 300, Category: "Household"},
+	}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("SetTransactionSplits short err = %v, want invalid input", err)
+	}
+	if err := backend.SetTransactionSplits(ctx, tenant, "00000000-0000-0000-0000-000000000000", nil); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("SetTransactionSplits missing err = %v, want not found", err)
+	}
+
+	if err := backend.SetTransactionSplits(ctx, tenant, txn.ID, []store.TransactionSplitInput{
+		{Amount: 800, Category: "Groceries", Bucket: "Needs"},
+		{Amount: 400, Category: "Household", Bucket: "Wants", Labels: []string{"shared"}, Counterparty: "Priya"},
+	}); err != nil {
+		t.Fatalf("SetTransactionSplits: %v", err)
+	}
+	got, err := backend.GetTransaction(ctx, tenant, txn.ID)
+	if err != nil {
+		t.Fatalf("GetTransaction: %v", err)
+	}
+	if len(got.Splits) != 2 || got.Splits[0].Amount != 800 || got.Splits[1].Counterparty != "Priya" ||
+		!containsString(got.Splits[1].Labels, "shared") || got.Splits[0].Labels == nil {
+		t.Fatalf("GetTransaction splits = %#v", got.Splits)
+	}
+	rows, _, err := backend.ListTransactions(ctx, tenant, store.ListFilter{Page: 1, PageSize: 10})
+	if err != nil || len(rows) != 1 || len(rows[0].Splits) != 2 {
+		t.Fatalf("ListTransactions rows=%#v err=%v", rows, err)
+	}
+
+	stats, err := backend.GetStats(ctx, tenant, "INR")
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.TotalCount != 1 || stats.TotalBase != 1200 ||
+		stats.TotalByCategory["Groceries"] != 800 || stats.TotalByCategory["Household"] != 400 {
+		t.Fatalf("GetStats = %#v, want totals by allocation", stats)
+	}
+	charts, err := backend.GetChartData(ctx, tenant)
+	if err != nil {
+		t.Fatalf("GetChartData: %v", err)
+	}
+	if charts.ByBucket["Needs"] != 800 || charts.ByBucket["Wants"] != 400 ||
+		charts.ByLabel["shared"] != 400 || charts.ByLabel["Uncategorized"] != 800 || charts.ByLabel["weekly"] != 0 {
+		t.Fatalf("GetChartData buckets=%#v labels=%#v, want allocation totals", charts.ByBucket, charts.ByLabel)
+	}
+
+	// Splits that share a category still count as one transaction.
+	if err := backend.SetTransactionSplits(ctx, tenant, txn.ID, []store.TransactionSplitInput{
+		{Amount: 700, Category: "Groceries"},
+		{Amount: 500, Category: "Groceries", Counterparty: "Priya"},
+	}); err != nil {
+		t.Fatalf("SetTransactionSplits same category: %v", err)
+	}
+	stats, err = backend.GetStats(ctx, tenant, "INR")
+	if err != nil {
+		t.Fatalf("GetStats same category: %v", err)
+	}
+	if stats.TotalByCategory["Groceries"] != 1200 || stats.TotalCategoryCount["Groceries"] != 1 {
+		t.Fatalf("GetStats same category = %#v / %#v, want one transaction", stats.TotalByCategory, stats.TotalCategoryCount)
+	}
+	digest, err := backend.GetSpendDigest(ctx, tenant, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetSpendDigest: %v", err)
+	}
+	if len(digest.Top) != 1 || digest.Top[0].Amount != 1200 || digest.Top[0].Count != 1 {
+		t.Fatalf("GetSpendDigest top = %#v, want one Groceries transaction", digest.Top)
+	}
+
+	if err := backend.SetTransactionSplits(ctx, tenant, txn.ID, nil); err != nil {
+		t.Fatalf("SetTransactionSplits clear: %v", err)
+	}
+	stats, err = backend.GetStats(ctx, tenant, "INR")
+	if err != nil {
+		t.Fatalf("GetStats after clear: %v", err)
+	}
+	if stats.TotalByCategory["Groceries"] != 1200 || stats.TotalByCategory["Household"] != 0 {
+		t.Fatalf("GetStats after clear = %#v, want parent category", stats.TotalByCategory)
+	}
+}
+
+func testSharedLedgers(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	alice := createTenant(ctx, t, backend, "ledger-alice")
+	bob := createTenant(ctx, t, backend, "ledger-bob")
+	carol := createTenant(ctx, t, backend, "ledger-carol")
+
+	ledger, err := backend.CreateSharedLedger(ctx, alice.ID, store.CreateSharedLedgerInput{
+		Name:         "Flat",
+		InviteEmails: []string{strings.ToUpper(email(t, "ledger-bob")), email(t, "ledger-nobody")},
+	})
+	if err != nil {
+		t.Fatalf("CreateSharedLedger: %v", err)
+	}
+	if len(ledger.Members) != 1 || ledger.CreatedBy != alice.ID {
+		t.Fatalf("CreateSharedLedger = %#v, want only alice until bob accepts", ledger)
+	}
+	if _, err := backend.GetSharedLedger(ctx, bob.ID, ledger.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetSharedLedger invitee err = %v, want not found", err)
+	}
+	invitations, err := backend.ListSharedLedgerInvitations(ctx, bob.ID)
+	if err != nil || len(invitations) != 1 || invitations[0].LedgerID != ledger.ID || invitations[0].InvitedBy != alice.ID {
+		t.Fatalf("ListSharedLedgerInvitations bob = %#v, err = %v", invitations, err)
+	}
+	ledger, err = backend.AcceptSharedLedgerInvitation(ctx, bob.ID, ledger.ID)
+	if err != nil || len(ledger.Members) != 2 {
+		t.Fatalf("AcceptSharedLedgerInvitation = %#v, err = %v, want alice and bob", ledger, err)
+	}
+	if _, err := backend.AcceptSharedLedgerInvitation(ctx, bob.ID, ledger.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("AcceptSharedLedgerInvitation twice err = %v, want not found", err)
+	}
+	if _, err := backend.GetSharedLedger(ctx, carol.ID, ledger.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetSharedLedger non-member err = %v, want not found", err)
+	}
+	ledgers, err := backend.ListSharedLedgers(ctx, bob.ID)
+	if err != nil || len(ledgers) != 1 || ledgers[0].ID != ledger.ID {
+		t.Fatalf("ListSharedLedgers bob = %#v, err = %v", ledgers, err)
+	}
+
+	txn, err := backend.CreateTransaction(ctx, alice, store.CreateTransactionInput{
+		Amount:       900,
+		Currency:     "INR",
+		Timestamp:    time.Date(2026, time.March, 8, 19, 0, 0, 0, time.UTC),
+		MerchantInfo: "Dinner",
+		Description:  "private note",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	half := []store.ExpenseShareInput{{UserID: bob.ID, Ratio: 0.5}}
+	if _, err := backend.ShareExpense(ctx, alice, alice.ID, ledger.ID, store.ShareExpenseInput{
+		TransactionID: txn.ID,
+		Shares:        []store.ExpenseShareInput{{UserID: carol.ID, Ratio: 0.5}},
+	}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("ShareExpense non-member err = %v, want invalid input", err)
+	}
+	if _, err := backend.ShareExpense(ctx, bob, bob.ID, ledger.ID, store.ShareExpenseInput{
+		TransactionID: txn.ID,
+		Shares:        []store.ExpenseShareInput{{UserID: alice.ID, Ratio: 0.5}},
+	}); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("ShareExpense foreign transaction err = %v, want not found", err)
+	}
+	expense, err := backend.ShareExpense(ctx, alice, alice.ID, ledger.ID, store.ShareExpenseInput{TransactionID: txn.ID, Shares: half})
+	if err != nil {
+		t.Fatalf("ShareExpense: %v", err)
+	}
+	if expense.Amount != 900 || len(expense.Shares) != 1 || expense.Shares[0].Amount != 450 {
+		t.Fatalf("ShareExpense = %#v, want half of 900", expense)
+	}
+	_, err = backend.ShareExpense(ctx, alice, alice.ID, ledger.ID, store.ShareExpenseInput{TransactionID: txn.ID, Shares: half})
+	if errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("ShareExpense duplicate err = %v, want conflict", err)
+	}
+
+	expenses, err := backend.ListSharedExpenses(ctx, bob.ID, ledger.ID)
+	if err != nil || len(expenses) != 1 || expenses[0].MerchantInfo != "Dinner" {
+		t.Fatalf("ListSharedExpenses bob = %#v, err = %v", expenses, err)
+	}
+	if _, err := backend.GetTransaction(ctx, bob, txn.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetTransaction across tenants err = %v, want not found", err)
+	}
+	if _, err := backend.ListSharedExpenses(ctx, carol.ID, ledger.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("ListSharedExpenses non-member err = %v, want not found", err)
+	}
+
+	if _, err := backend.RecordSettlement(ctx, bob.ID, ledger.ID, store.RecordSettlementInput{
+		FromUserID: bob.ID, ToUserID: carol.ID, Amount: 100, Currency: "INR",
+	}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("RecordSettlement non-member err = %v, want invalid input", err)
+	}
+	settlement, err := backend.RecordSettlement(ctx, bob.ID, ledger.ID, store.RecordSettlementInput{
+		FromUserID: bob.ID, ToUserID: alice.ID, Amount: 200, Currency: "INR", Note: "UPI",
+	})
+	if err != nil {
+		t.Fatalf("RecordSettlement: %v", err)
+	}
+	if settlement.CreatedBy != bob.ID || settlement.SettledAt.IsZero() {
+		t.Fatalf("RecordSettlement = %#v", settlement)
+	}
+	settlements, err := backend.ListSettlements(ctx, alice.ID, ledger.ID)
+	if err != nil || len(settlements) != 1 {
+		t.Fatalf("ListSettlements = %#v, err = %v", settlements, err)
+	}
+
+	balances, err := backend.ListLedgerBalances(ctx, alice.ID, ledger.ID)
+	if err != nil {
+		t.Fatalf("ListLedgerBalances: %v", err)
+	}
+	owed := make(map[string]float64)
+	for _, balance := range balances {
+		owed[balance.UserID] = balance.Amount
+	}
+	if len(balances) != 2 || owed[alice.ID] != 250 || owed[bob.ID] != -250 {
+		t.Fatalf("ListLedgerBalances = %#v, want alice +250 and bob -250", balances)
+	}
+
+	if err := backend.DeleteSharedExpense(ctx, bob.ID, ledger.ID, expense.ID); errors.WhatKind(err) != errors.PermissionDenied {
+		t.Fatalf("DeleteSharedExpense by non-payer err = %v, want permission denied", err)
+	}
+	carolEmail := email(t, "ledger-carol")
+	if err := backend.InviteSharedLedgerMember(ctx, bob.ID, ledger.ID, carolEmail); errors.WhatKind(err) != errors.PermissionDenied {
+		t.Fatalf("InviteSharedLedgerMember by non-owner err = %v, want permission denied", err)
+	}
+	if err := backend.InviteSharedLedgerMember(ctx, carol.ID, ledger.ID, carolEmail); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("InviteSharedLedgerMember by non-member err = %v, want not found", err)
+	}
+	if err := backend.InviteSharedLedgerMember(ctx, alice.ID, ledger.ID, email(t, "ledger-nobody")); err != nil {
+		t.Fatalf("InviteSharedLedgerMember unknown email: %v", err)
+	}
+	if err := backend.InviteSharedLedgerMember(ctx, alice.ID, ledger.ID, carolEmail); err != nil {
+		t.Fatalf("InviteSharedLedgerMember: %v", err)
+	}
+	if err := backend.DeclineSharedLedgerInvitation(ctx, carol.ID, ledger.ID); err != nil {
+		t.Fatalf("DeclineSharedLedgerInvitation: %v", err)
+	}
+	if _, err := backend.AcceptSharedLedgerInvitation(ctx, carol.ID, ledger.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("AcceptSharedLedgerInvitation after decline err = %v, want not found", err)
+	}
+	if err := backend.InviteSharedLedgerMember(ctx, alice.ID, ledger.ID, carolEmail); err != nil {
+		t.Fatalf("InviteSharedLedgerMember again: %v", err)
+	}
+	if _, err := backend.AcceptSharedLedgerInvitation(ctx, carol.ID, ledger.ID); err != nil {
+		t.Fatalf("AcceptSharedLedgerInvitation carol: %v", err)
+	}
+	if _, err := backend.ListSharedExpenses(ctx, carol.ID, ledger.ID); err != nil {
+		t.Fatalf("ListSharedExpenses new member: %v", err)
+	}
+	if err := backend.DeleteSharedExpense(ctx, alice.ID, ledger.ID, expense.ID); err != nil {
+		t.Fatalf("DeleteSharedExpense: %v", err)
+	}
+	expenses, err = backend.ListSharedExpenses(ctx, alice.ID, ledger.ID)
+	if err != nil || len(expenses) != 0 {
+		t.Fatalf("ListSharedExpenses after delete = %#v, err = %v", expenses, err)
+	}
+}
+
+func testDiagnostics(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	tenant := createTenant(ctx, t, backend, "diagnostics")
+	receivedAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	if err := backend.RecordExtractionDiagnostic(ctx, tenant, api.ExtractionDiagnostic{
+		Reader:         "gmail",
+		MessageID:      "diag-" + suffix(t),
+		Source:         "Example",
+		SenderEmail:    "sender@example.test",
+		Subject:        "transaction alert",
+		EmailBody:      "body",
+		ReceivedAt:     &receivedAt,
+		Snippet:        "snippet",
+		RuleName:       "diag rule",
+		AmountRegex:    `INR\s+([0-9.]+)`,
+		MerchantRegex:  `at\s+(.+)$`,
+		CurrencyRegex:  `(INR)`,
+		FailureReasons: []string{api.FailureMerchantEmpty},
+	}); err != nil {
+		t.Fatalf("RecordExtractionDiagnostic: %v", err)
+	}
+
+	rows, err := backend.ListExtractionDiagnostics(ctx, tenant, store.DiagnosticFilter{Status: store.DiagnosticStatusOpen, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListExtractionDiagnostics: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("ListExtractionDiagnostics len=%d rows=%#v", len(rows), rows)
+	}
+	if rows[0].SenderEmail != "sender@example.test" || rows[0].Status != store.DiagnosticStatusOpen {
+		t.Fatalf("ListExtractionDiagnostics returned invalid row: %#v", rows[0])
+	}
+
+	got, err := backend.GetExtractionDiagnostic(ctx, tenant, rows[0].ID)
+	if err != nil {
+		t.Fatalf("GetExtractionDiagnostic: %v", err)
+	}
+	if got.ID != rows[0].ID {
+		t.Fatalf("GetExtractionDiagnostic ID = %q, want %q", got.ID, rows[0].ID)
+	}
+
+	updated, err := backend.UpdateExtractionDiagnosticStatus(ctx, tenant, rows[0].ID, store.DiagnosticStatusResolved)
+	if err != nil {
+		t.Fatalf("UpdateExtractionDiagnosticStatus: %v", err)
+	}
+	if updated.Status != store.DiagnosticStatusResolved || updated.ResolvedAt == nil {
+		t.Fatalf("UpdateExtractionDiagnosticStatus returned invalid row: %#v", updated)
+	}
+}
+
+func testWebhooks(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	tenant := createTenant(ctx, t, backend, "webhooks")
+	hook, err := backend.CreateWebhook(ctx, tenant, store.NewWebhook{
+		URL:     "https://example.test/hook",
+		Events:  []store.WebhookEventType{store.WebhookTransactionCreated, store.WebhookScanFailed},
+		Enabled: true,
+		Secret:  "conformance-secret",
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := backend.CreateWebhook(ctx, tenant, store.NewWebhook{
+		URL:    "https://example.test/disabled",
+		Events: []store.WebhookEventType{store.WebhookTransactionCreated},
+		Secret: "conformance-secret",
+	}); err != nil {
+		t.Fatalf("CreateWebhook(disabled): %v", err)
+	}
+	if _, err := backend.CreateWebhook(ctx, tenant, store.NewWebhook{URL: "https://example.test/none", Secret: "s"}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("CreateWebhook without events error = %v, want invalid input", err)
+	}
+
+	write := func() {
+		t.Helper()
+		if err := backend.Write(ctx, store.IngestionBatch{
+			Tenant: tenant,
+			Transactions: []*api.TransactionDetails{{
+				MessageID:    "webhook-" + suffix(t),
+				Amount:       99.5,
+				Currency:     "INR",
+				Timestamp:    time.Date(2026, time.January, 2, 10, 30, 0, 0, time.UTC).Format(time.RFC3339),
+				MerchantInfo: "Webhook Merchant",
+				Source:       api.Source{Type: "credit-card", Label: "Example Card", Bank: "Example"},
+			}},
+		}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	write()
+	// Stored again, the transaction is updated, which the webhook is not
+	// subscribed to yet.
+	write()
+	enabled := true
+	hook, err = backend.UpdateWebhook(ctx, tenant, hook.ID, store.WebhookPatch{
+		Events:  []store.WebhookEventType{store.WebhookTransactionCreated, store.WebhookTransactionUpdated},
+		Enabled: &enabled,
+	})
+	if err != nil || len(hook.Events) != 2 || hook.Events[1] != store.WebhookTransactionUpdated {
+		t.Fatalf("UpdateWebhook = %#v, %v", hook, err)
+	}
+	write()
+
+	deliveries, err := backend.ListWebhookDeliveries(ctx, tenant, hook.ID, 10)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].EventType != store.WebhookTransactionUpdated ||
+		deliveries[1].EventType != store.WebhookTransactionCreated || deliveries[1].Status != store.WebhookDeliveryPending {
+		t.Fatalf("ListWebhookDeliveries = %#v", deliveries)
+	}
+	var payload struct {
+		MerchantInfo string  `json:"merchant_info"`
+		Amount       float64 `json:"amount"`
+	}
+	if err := json.Unmarshal(deliveries[1].Payload, &payload); err != nil || payload.MerchantInfo != "Webhook Merchant" || payload.Amount != 99.5 {
+		t.Fatalf("payload = %s, %v", deliveries[1].Payload, err)
+	}
+
+	dispatches, err := backend.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimWebhookDeliveries: %v", err)
+	}
+	if len(dispatches) != 2 || dispatches[0].URL != hook.URL || dispatches[0].Secret != "conformance-secret" || dispatches[0].Delivery.Attempts != 1 {
+		t.Fatalf("ClaimWebhookDeliveries = %#v", dispatches)
+	}
+	if again, err := backend.ClaimWebhookDeliveries(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Fatalf("ClaimWebhookDeliveries while leased = %#v, %v", again, err)
+	}
+
+	attemptedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := backend.CompleteWebhookDelivery(ctx, dispatches[0].Delivery.ID, store.WebhookAttempt{
+		AttemptedAt: attemptedAt, ResponseStatus: 204, Delivered: true,
+	}); err != nil {
+		t.Fatalf("CompleteWebhookDelivery(delivered): %v", err)
+	}
+	if err := backend.CompleteWebhookDelivery(ctx, dispatches[1].Delivery.ID, store.WebhookAttempt{
+		AttemptedAt: attemptedAt, Error: "connection refused",
+	}); err != nil {
+		t.Fatalf("CompleteWebhookDelivery(failed): %v", err)
+	}
+	deliveries, err = backend.ListWebhookDeliveries(ctx, tenant, hook.ID, 10)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries after completion: %v", err)
+	}
+	statuses := map[string]store.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		statuses[delivery.ID] = delivery
+	}
+	delivered := statuses[dispatches[0].Delivery.ID]
+	if delivered.Status != store.WebhookDeliveryDelivered || delivered.ResponseStatus != 204 || delivered.CompletedAt == nil {
+		t.Fatalf("delivered delivery = %#v", delivered)
+	}
+	failed := statuses[dispatches[1].Delivery.ID]
+	if failed.Status != store.WebhookDeliveryFailed || failed.LastError != "connection refused" || failed.NextAttemptAt != nil {
+		t.Fatalf("failed delivery = %#v", failed)
+	}
+
+	pruned, err := backend.PruneWebhookDeliveries(ctx, attemptedAt.Add(time.Second))
+	if err != nil || pruned != 2 {
+		t.Fatalf("PruneWebhookDeliveries = %d, %v; want 2", pruned, err)
+	}
+
+	other := createTenant(ctx, t, backend, "webhooks-other")
+	if _, err := backend.GetWebhook(ctx, other, hook.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetWebhook from another tenant error = %v, want not found", err)
+	}
+	if err := backend.DeleteWebhook(ctx, tenant, hook.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	webhooks, err := backend.ListWebhooks(ctx, tenant)
+	if err != nil || len(webhooks) != 1 || webhooks[0].Enabled {
+		t.Fatalf("ListWebhooks after delete = %#v, %v", webhooks, err)
+	}
+}
+
+func testNotifications(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	user, err := backend.CreateUser(ctx, store.CreateUserInput{
+		Email:        email(t, "notifications"),
+		DisplayName:  "Conformance Notifications User",
+		Role:         store.UserRoleUser,
+		AvatarKey:    "avatar-notifications",
+		PasswordHash: "hash-notifications",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	tenant := store.Tenant{ID: user.TenantID}
+
+	prefs, err := backend.GetNotificationPreferences(ctx, tenant, user.ID)
+	if err != nil || prefs.LargeTransactionAlerts || prefs.DigestWeekday != time.Monday || !prefs.UpdatedAt.IsZero() {
+		t.Fatalf("GetNotificationPreferences = %#v, %v; want defaults", prefs, err)
+	}
+	enabled, threshold := true, 50.0
+	prefs, err = backend.UpdateNotificationPreferences(ctx, tenant, user.ID, store.NotificationPreferencesPatch{
+		LargeTransactionAlerts:    &enabled,
+		LargeTransactionThreshold: &threshold,
+	})
+	if err != nil || !prefs.LargeTransactionAlerts || prefs.LargeTransactionThreshold != 50 || prefs.UpdatedAt.IsZero() {
+		t.Fatalf("UpdateNotificationPreferences = %#v, %v", prefs, err)
+	}
+
+	channel, err := backend.CreateNotificationChannel(ctx, tenant, user.ID, store.NewNotificationChannel{
+		Type:    store.NotificationChannelNtfy,
+		Name:    "Phone",
+		Target:  "https://ntfy.example.test/expensor",
+		Token:   "tk_conformance",
+		Enabled: true,
+	})
+	if err != nil || !channel.HasToken {
+		t.Fatalf("CreateNotificationChannel = %#v, %v", channel, err)
+	}
+	if _, err := backend.CreateNotificationChannel(ctx, tenant, user.ID, store.NewNotificationChannel{
+		Type: store.NotificationChannelGotify, Target: "https://gotify.example.test", Enabled: true,
+	}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("CreateNotificationChannel(gotify without token) error = %v, want invalid input", err)
+	}
+	if _, token, err := backend.OpenNotificationChannel(ctx, tenant, user.ID, channel.ID); err != nil || token != "tk_conformance" {
+		t.Fatalf("OpenNotificationChannel token = %q, %v", token, err)
+	}
+
+	if err := backend.Write(ctx, store.IngestionBatch{
+		Tenant: tenant,
+		Transactions: []*api.TransactionDetails{{
+			MessageID:    "notification-" + suffix(t),
+			Amount:       99.5,
+			Currency:     "INR",
+			Timestamp:    time.Date(2026, time.January, 2, 10, 30, 0, 0, time.UTC).Format(time.RFC3339),
+			MerchantInfo: "Notification Merchant",
+			Source:       api.Source{Type: "credit-card", Label: "Example Card", Bank: "Example"},
+		}},
+	}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	claimed, err := backend.ClaimNotificationDeliveries(ctx, 50, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimNotificationDeliveries: %v", err)
+	}
+	var dispatch *store.NotificationDispatch
+	for i := range claimed {
+		if claimed[i].Channel.ID == channel.ID {
+			dispatch = &claimed[i]
+		}
+	}
+	if dispatch == nil || dispatch.Delivery.Kind != store.NotificationLargeTransaction || dispatch.Token != "tk_conformance" ||
+		dispatch.Delivery.Attempts != 1 {
+		t.Fatalf("ClaimNotificationDeliveries = %#v", claimed)
+	}
+	var payload struct {
+		Merchant string  `json:"merchant"`
+		Amount   float64 `json:"amount"`
+	}
+	if err := json.Unmarshal(dispatch.Delivery.Payload, &payload); err != nil || payload.Merchant != "Notification Merchant" || payload.Amount != 99.5 {
+		t.Fatalf("payload = %s, %v", dispatch.Delivery.Payload, err)
+	}
+	attemptedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := backend.CompleteNotificationDelivery(ctx, dispatch.Delivery.ID, store.NotificationAttempt{
+		AttemptedAt: attemptedAt, Delivered: true,
+	}); err != nil {
+		t.Fatalf("CompleteNotificationDelivery: %v", err)
+	}
+	if pruned, err := backend.PruneNotificationDeliveries(ctx, attemptedAt.Add(time.Second)); err != nil || pruned < 1 {
+		t.Fatalf("PruneNotificationDeliveries = %d, %v; want at least 1", pruned, err)
+	}
+
+	empty, disabled := "", false
+	channel, err = backend.UpdateNotificationChannel(ctx, tenant, user.ID, channel.ID, store.NotificationChannelPatch{
+		Token: &empty, Enabled: &disabled,
+	})
+	if err != nil || channel.HasToken || channel.Enabled {
+		t.Fatalf("UpdateNotificationChannel = %#v, %v", channel, err)
+	}
+	other := createTenant(ctx, t, backend, "notifications-other")
+	if _, _, err := backend.OpenNotificationChannel(ctx, other, user.ID, channel.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("OpenNotificationChannel from another tenant error = %v, want not found", err)
+	}
+	if err := backend.DeleteNotificationChannel(ctx, tenant, user.ID, channel.ID); err != nil {
+		t.Fatalf("DeleteNotificationChannel: %v", err)
+	}
+	channels, err := backend.ListNotificationChannels(ctx, tenant, user.ID)
+	if err != nil || len(channels) != 0 {
+		t.Fatalf("ListNotificationChannels after delete = %#v, %v", channels, err)
+	}
+}
+
+func testLLMUsage(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	tenant := createTenant(ctx, t, backend, "llm-usage")
+	dayStart := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, record := range []store.LLMUsageRecord{
+		{InputTokens: 100, OutputTokens: 20, TotalTokens: 120, Latency: 200 * time.Millisecond, Outcome: "ok"},
+		{InputTokens: 50, TotalTokens: 50, Latency: 400 * time.Millisecond, Outcome: "llm_provider_error"},
+	} {
+		record.Workflow, record.Purpose, record.Provider, record.Model = "rule_drafting", "draft_rule", "openai", "small"
+		record.CreatedAt = dayStart.Add(time.Hour)
+		if err := backend.RecordLLMUsage(ctx, tenant, record); err != nil {
+			t.Fatalf("RecordLLMUsage: %v", err)
+		}
+	}
+
+	total, err := backend.SumLLMUsageTokens(ctx, tenant, dayStart)
+	if err != nil {
+		t.Fatalf("SumLLMUsageTokens: %v", err)
+	}
+	if total != 170 {
+		t.Fatalf("SumLLMUsageTokens = %d, want 170", total)
+	}
+
+	buckets, err := backend.ListLLMUsage(ctx, store.LLMUsageFilter{
+		TenantID: tenant.ID,
+		From:     dayStart,
+		To:       dayStart.Add(24 * time.Hour),
+		Interval: store.LLMUsageIntervalDay,
+	})
+	if err != nil {
+		t.Fatalf("ListLLMUsage: %v", err)
+	}
+	if len(buckets) != 1 {
+		t.Fatalf("ListLLMUsage len=%d buckets=%#v", len(buckets), buckets)
+	}
+	if b := buckets[0]; !b.PeriodStart.Equal(dayStart) || b.Calls != 2 || b.FailedCalls != 1 || b.TotalTokens != 170 || b.AverageLatency != 300 {
+		t.Fatalf("ListLLMUsage returned invalid bucket: %#v", b)
+	}
+
+	quota, err := backend.GetLLMUsageQuota(ctx, tenant)
+	if err != nil {
+		t.Fatalf("GetLLMUsageQuota: %v", err)
+	}
+	if quota.DailyTokenLimit != 0 || quota.MonthlyTokenLimit != 0 || quota.UpdatedAt != nil {
+		t.Fatalf("GetLLMUsageQuota default = %#v, want unlimited", quota)
+	}
+	if _, err := backend.SetLLMUsageQuota(ctx, tenant, store.LLMUsageQuota{DailyTokenLimit: 1000, MonthlyTokenLimit: 20000}); err != nil {
+		t.Fatalf("SetLLMUsageQuota: %v", err)
+	}
+	quota, err = backend.GetLLMUsageQuota(ctx, tenant)
+	if err != nil {
+		t.Fatalf("GetLLMUsageQuota after set: %v", err)
+	}
+	if quota.DailyTokenLimit != 1000 || quota.MonthlyTokenLimit != 20000 || quota.UpdatedAt == nil {
+		t.Fatalf("GetLLMUsageQuota after set = %#v", quota)
+	}
+}
+
+func testLLMPrompts(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	const workflow, purpose = "rule_drafting", "draft_rule"
+	if _, ok, err := backend.GetActiveLLMPromptVersion(ctx, workflow, purpose); err != nil || ok {
+		t.Fatalf("GetActiveLLMPromptVersion empty = %v, %v; want none", ok, err)
+	}
+	created, err := backend.CreateLLMPromptVersion(ctx, store.CreateLLMPromptVersionInput{
+		Workflow:   workflow,
+		Purpose:    purpose,
+		Version:    2,
+		Definition: json.RawMessage(`{"id":"rule_draft","version":2}`),
+		Activate:   true,
+	})
+	if err != nil {
+		t.Fatalf("CreateLLMPromptVersion v2: %v", err)
+	}
+	if created.ID == "" || !created.Active || created.ActivatedAt == nil || created.CreatedBy != "" {
+		t.Fatalf("CreateLLMPromptVersion v2 = %#v", created)
+	}
+	assertJSON(t, created.Definition, []byte(`{"id":"rule_draft","version":2}`))
+	if _, err := backend.CreateLLMPromptVersion(ctx, store.CreateLLMPromptVersionInput{
+		Workflow:   workflow,
+		Purpose:    purpose,
+		Version:    3,
+		Definition: json.RawMessage(`{"id":"rule_draft","version":3}`),
+	}); err != nil {
+		t.Fatalf("CreateLLMPromptVersion v3: %v", err)
+	}
+	if _, err := backend.CreateLLMPromptVersion(ctx, store.CreateLLMPromptVersionInput{
+		Workflow:   workflow,
+		Purpose:    purpose,
+		Version:    3,
+		Definition: json.RawMessage(`{}`),
+	}); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("CreateLLMPromptVersion duplicate err = %v, want conflict", err)
+	}
+
+	active, ok, err := backend.GetActiveLLMPromptVersion(ctx, workflow, purpose)
+	if err != nil || !ok || active.Version != 2 {
+		t.Fatalf("GetActiveLLMPromptVersion = %#v, %v, %v; want v2", active, ok, err)
+	}
+	if _, err := backend.ActivateLLMPromptVersion(ctx, workflow, purpose, 3); err != nil {
+		t.Fatalf("ActivateLLMPromptVersion v3: %v", err)
+	}
+	if _, err := backend.ActivateLLMPromptVersion(ctx, workflow, purpose, 9); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("ActivateLLMPromptVersion missing err = %v, want not found", err)
+	}
+	versions, err := backend.ListLLMPromptVersions(ctx, workflow, purpose)
+	if err != nil {
+		t.Fatalf("ListLLMPromptVersions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 3 || !versions[0].Active || versions[1].Active {
+		t.Fatalf("ListLLMPromptVersions = %#v, want active v3 then inactive v2", versions)
+	}
+
+	if err := backend.DeactivateLLMPromptVersions(ctx, workflow, purpose); err != nil {
+		t.Fatalf("DeactivateLLMPromptVersions: %v", err)
+	}
+	if _, ok, err := backend.GetActiveLLMPromptVersion(ctx, workflow, purpose); err != nil || ok {
+		t.Fatalf("GetActiveLLMPromptVersion after deactivate = %v, %v; want none", ok, err)
+	}
+}
+
+func testTenants(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	owner := createTenant(ctx, t, backend, "household-owner")
+	partner := createTenant(ctx, t, backend, "household-partner")
+	outsider := createTenant(ctx, t, backend, "household-outsider")
+
+	personal, err := backend.GetTenantMembership(ctx, owner, owner.ID)
+	if err != nil || !personal.Personal || personal.Role != store.TenantRoleOwner {
+		t.Fatalf("GetTenantMembership personal = %#v, err = %v", personal, err)
+	}
+
+	household, err := backend.CreateTenant(ctx, owner.ID, store.CreateTenantInput{Name: "Household"})
+	if err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	if household.Personal || household.Role != store.TenantRoleOwner || household.TenantID == owner.ID {
+		t.Fatalf("CreateTenant = %#v, want owned shared tenant", household)
+	}
+	tenant := store.Tenant{ID: household.TenantID}
+	if state, err := backend.GetScanningState(ctx, tenant); err != nil || state.State != store.ScanningStateStopped {
+		t.Fatalf("GetScanningState household = %#v, err = %v, want a stopped row", state, err)
+	}
+	dormant, err := backend.CreateTenant(ctx, outsider.ID, store.CreateTenantInput{Name: "Dormant"})
+	if err != nil {
+		t.Fatalf("CreateTenant dormant: %v", err)
+	}
+	disabled := true
+	if _, err := backend.UpdateUser(ctx, outsider.ID, store.UpdateUserInput{Disabled: &disabled}); err != nil {
+		t.Fatalf("UpdateUser disable outsider: %v", err)
+	}
+	active, err := backend.ListActiveTenants(ctx)
+	if err != nil || !slices.Contains(active, tenant) || slices.Contains(active, store.Tenant{ID: dormant.TenantID}) {
+		t.Fatalf("ListActiveTenants = %#v, err = %v, want the household and not the dormant tenant", active, err)
+	}
+	if err := backend.InviteTenantMember(ctx, tenant, owner.ID, strings.ToUpper(email(t, "household-partner")), store.TenantRoleEditor); err != nil {
+		t.Fatalf("InviteTenantMember: %v", err)
+	}
+	if err := backend.InviteTenantMember(ctx, tenant, owner.ID, email(t, "household-partner"), store.TenantRoleViewer); err != nil {
+		t.Fatalf("InviteTenantMember again: %v", err)
+	}
+	for _, invitee := range []string{email(t, "household-outsider"), email(t, "household-nobody")} {
+		if err := backend.InviteTenantMember(ctx, tenant, owner.ID, invitee, store.TenantRoleViewer); err != nil {
+			t.Fatalf("InviteTenantMember(%s) err = %v, want silently ignored", invitee, err)
+		}
+	}
+	if err := backend.InviteTenantMember(ctx, tenant, owner.ID, email(t, "household-partner"), "admin"); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("InviteTenantMember unknown role err = %v, want invalid input", err)
+	}
+	if _, err := backend.GetTenantMembership(ctx, tenant, partner.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetTenantMembership before accepting err = %v, want not found", err)
+	}
+	invitations, err := backend.ListTenantInvitations(ctx, partner.ID)
+	if err != nil || len(invitations) != 1 || invitations[0].TenantID != tenant.ID ||
+		invitations[0].Role != store.TenantRoleViewer || invitations[0].InvitedBy != owner.ID {
+		t.Fatalf("ListTenantInvitations partner = %#v, err = %v, want one viewer invitation from the owner", invitations, err)
+	}
+	if invitations, err := backend.ListTenantInvitations(ctx, outsider.ID); err != nil || len(invitations) != 0 {
+		t.Fatalf("ListTenantInvitations disabled outsider = %#v, err = %v, want none", invitations, err)
+	}
+	joined, err := backend.AcceptTenantInvitation(ctx, tenant, partner.ID)
+	if err != nil || joined.TenantID != tenant.ID || joined.Role != store.TenantRoleViewer || joined.Personal {
+		t.Fatalf("AcceptTenantInvitation = %#v, err = %v, want household viewer", joined, err)
+	}
+	if _, err := backend.AcceptTenantInvitation(ctx, tenant, partner.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("AcceptTenantInvitation twice err = %v, want not found", err)
+	}
+	if err := backend.DeclineTenantInvitation(ctx, tenant, partner.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("DeclineTenantInvitation without invitation err = %v, want not found", err)
+	}
+	if _, err := backend.GetTenantMembership(ctx, tenant, outsider.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetTenantMembership outsider err = %v, want not found", err)
+	}
+
+	memberships, err := backend.ListTenantMemberships(ctx, partner.ID)
+	if err != nil || len(memberships) != 2 || !memberships[0].Personal || memberships[1].Role != store.TenantRoleViewer {
+		t.Fatalf("ListTenantMemberships partner = %#v, err = %v", memberships, err)
+	}
+	members, err := backend.ListTenantMembers(ctx, tenant)
+	if err != nil || len(members) != 2 || members[0].UserID != owner.ID {
+		t.Fatalf("ListTenantMembers = %#v, err = %v", members, err)
+	}
+
+	txn, err := backend.CreateTransaction(ctx, tenant, store.CreateTransactionInput{
+		Amount:       1200,
+		Currency:     "INR",
+		Timestamp:    time.Date(2026, time.March, 9, 9, 0, 0, 0, time.UTC),
+		MerchantInfo: "Groceries",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction household: %v", err)
+	}
+	if _, err := backend.GetTransaction(ctx, partner, txn.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetTransaction from personal tenant err = %v, want not found", err)
+	}
+
+	session, err := backend.CreateSession(ctx, store.CreateSessionInput{
+		UserID:    partner.ID,
+		TokenHash: "tenant-session-" + suffix(t),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := backend.SetSessionTenant(ctx, session.ID, tenant); err != nil {
+		t.Fatalf("SetSessionTenant: %v", err)
+	}
+	found, err := backend.FindSessionByHash(ctx, session.TokenHash)
+	if err != nil || found.ActiveTenantID != tenant.ID {
+		t.Fatalf("FindSessionByHash active tenant = %#v, err = %v", found, err)
+	}
+	if err := backend.SetSessionTenant(ctx, session.ID, store.Tenant{}); err != nil {
+		t.Fatalf("SetSessionTenant personal: %v", err)
+	}
+	found, err = backend.FindSessionByHash(ctx, session.TokenHash)
+	if err != nil || found.ActiveTenantID != "" {
+		t.Fatalf("FindSessionByHash after reset = %#v, err = %v", found, err)
+	}
+
+	if _, err := backend.UpdateTenantMemberRole(ctx, tenant, owner.ID, store.TenantRoleEditor); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("UpdateTenantMemberRole last owner err = %v, want conflict", err)
+	}
+	if err := backend.RemoveTenantMember(ctx, tenant, owner.ID); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("RemoveTenantMember last owner err = %v, want conflict", err)
+	}
+	if err := backend.RemoveTenantMember(ctx, tenant, outsider.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("RemoveTenantMember non-member err = %v, want not found", err)
+	}
+	member, err := backend.UpdateTenantMemberRole(ctx, tenant, partner.ID, store.TenantRoleOwner)
+	if err != nil || member.UserID != partner.ID || member.Role != store.TenantRoleOwner {
+		t.Fatalf("UpdateTenantMemberRole partner = %#v, err = %v, want owner", member, err)
+	}
+	if _, err := backend.UpdateTenantMemberRole(ctx, tenant, owner.ID, store.TenantRoleEditor); err != nil {
+		t.Fatalf("UpdateTenantMemberRole owner with a second owner: %v", err)
+	}
+	if _, err := backend.UpdateTenantMemberRole(ctx, partner, partner.ID, store.TenantRoleViewer); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("UpdateTenantMemberRole personal tenant err = %v, want conflict", err)
+	}
+	if err := backend.SetSessionTenant(ctx, session.ID, tenant); err != nil {
+		t.Fatalf("SetSessionTenant before removal: %v", err)
+	}
+	if err := backend.RemoveTenantMember(ctx, tenant, partner.ID); errors.WhatKind(err) != errors.Conflict {
+		t.Fatalf("RemoveTenantMember only owner err = %v, want conflict", err)
+	}
+	if _, err := backend.UpdateTenantMemberRole(ctx, tenant, owner.ID, store.TenantRoleOwner); err != nil {
+		t.Fatalf("UpdateTenantMemberRole restore owner: %v", err)
+	}
+	if err := backend.RemoveTenantMember(ctx, tenant, partner.ID); err != nil {
+		t.Fatalf("RemoveTenantMember partner: %v", err)
+	}
+	if _, err := backend.GetTenantMembership(ctx, tenant, partner.ID); errors.WhatKind(err) != errors.NotFound {
+		t.Fatalf("GetTenantMembership after removal err = %v, want not found", err)
+	}
+	found, err = backend.FindSessionByHash(ctx, session.TokenHash)
+	if err != nil || found.ActiveTenantID != "" {
+		t.Fatalf("FindSessionByHash after removal = %#v, err = %v, want personal tenant", found, err)
+	}
+}
+
+func testBackups(ctx context.Context, t *testing.T, backend store.Backend) {
+	t.Helper()
+
+	settings, err := backend.GetBackupSettings(ctx)
+	if err != nil {
+		t.Fatalf("GetBackupSettings: %v", err)
+	}
+	if settings.Enabled || settings.IntervalHours != 24 || settings.KeepLast != 7 || settings.KeepDays != 30 {
+		t.Fatalf("default backup settings = %+v", settings)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if claimed, err := backend.ClaimScheduledBackup(ctx, now); err != nil || claimed {
+		t.Fatalf("ClaimScheduledBackup while disabled = %v, %v; want false", claimed, err)
+	}
+	zero := 0
+	if _, err := backend.PatchBackupSettings(ctx, store.BackupSettingsPatch{KeepLast: &zero}); errors.WhatKind(err) != errors.InvalidInput {
+		t.Fatalf("PatchBackupSettings(keep_last=0) error = %v, want InvalidInput", err)
+	}
+	enabled, interval := true, 6
+	settings, err = backend.PatchBackupSettings(ctx, store.BackupSettingsPatch{Enabled: &enabled, IntervalHours: &interval})
+	if err != nil {
+		t.Fatalf("PatchBackupSettings: %v", err)
+	}
+	if !settings.Enabled || settings.IntervalHours != 6 || settings.KeepLast != 7 {
+		t.Fatalf("patched backup settings = %+v", settings)
+	}
+	if claimed, err := backend.ClaimScheduledBackup(ctx, now); err != nil || !claimed {
+		t.Fatalf("first ClaimScheduledBackup = %v, %v; want true", claimed, err)
+	}
+	if claimed, err := backend.ClaimScheduledBackup(ctx, now.Add(time.Hour)); err != nil || claimed {
+		t.Fatalf("ClaimScheduledBackup within interval = %v, %v; want false", claimed, err)
+	}
+	if claimed, err := backend.ClaimScheduledBackup(ctx, now.Add(6*time.Hour)); err != nil || !claimed {
+		t.Fatalf("ClaimScheduledBackup after interval = %v, %v; want true", claimed, err)
+	}
+	if err := backend.RecordBackupRun(ctx, store.BackupRun{FinishedAt: now, Backup: "expensor-backup-test.tar.gz"}); err != nil {
+		t.Fatalf("RecordBackupRun: %v", err)
+	}
+	settings, err = backend.GetBackupSettings(ctx)
+	if err != nil {
+		t.Fatalf("GetBackupSettings after run: %v", err)
+	}
+	if settings.LastBackup != "expensor-backup-test.tar.gz" || settings.LastFinishedAt == nil || settings.LastError != "" {
+		t.Fatalf("backup settings after run = %+v", settings)
+	}
+
+	tenant := createTenant(ctx, t, backend, "backup")
+	if err := backend.CreateLabel(ctx, tenant, "kept", "#38bdf8"); err != nil {
+		t.Fatalf("CreateLabel: %v", err)
+	}
+	tables := map[string]*strings.Builder{}
+	schema, err := backend.DumpDatabase(ctx, func(table store.BackupTable) (io.Writer, error) {
+		tables[table.Name] = &strings.Builder{}
+		return tables[table.Name], nil
+	})
+	if err != nil {
+		t.Fatalf("DumpDatabase: %v", err)
+	}
+	if schema.Version == 0 || len(schema.Tables) == 0 || tables["labels"] == nil {
+		t.Fatalf("DumpDatabase schema = %+v", schema)
+	}
+	for _, table := range schema.Tables {
+		if table.Name == "schema_migrations" {
+			t.Fatal("DumpDatabase copied schema_migrations")
+		}
+	}
+	check, err := backend.CheckBackupSchema(ctx, schema)
+	if err != nil || len(check.Problems) != 0 || check.CurrentVersion != schema.Version {
+		t.Fatalf("CheckBackupSchema = %+v, %v; want no problems", check, err)
+	}
+	older := schema
+	older.Version--
+	if check, err := backend.CheckBackupSchema(ctx, older); err != nil || len(check.Problems) == 0 {
+		t.Fatalf("CheckBackupSchema(older version) = %+v, %v; want a problem", check, err)
+	}
+
+	if err := backend.CreateLabel(ctx, tenant, "dropped", "#0ea5e9"); err != nil {
+		t.Fatalf("CreateLabel after dump: %v", err)
+	}
+	if err := backend.RestoreDatabase(ctx, older, nil); errors.WhatKind(err) != errors.FailedPrecondition {
+		t.Fatalf("RestoreDatabase(older version) error = %v, want FailedPrecondition", err)
+	}
+	err = backend.RestoreDatabase(ctx, schema, func(table store.BackupTable) (io.Reader, error) {
+		return strings.NewReader(tables[table.Name].String()), nil
+	})
+	if err != nil {
+		t.Fatalf("RestoreDatabase: %v", err)
+	}
+	labels, err := backend.ListLabels(ctx, tenant)
+	if err != nil {
+		t.Fatalf("ListLabels after restore: %v", err)
+	}
+	if !hasLabel(labels, "kept", "#38bdf8") || hasLabel(labels, "dropped", "#0ea5e9") {
+		t.Fatalf("labels after restore = %#v", labels)
+	}
+	if err := backend.CreateLabel(ctx, tenant, "after-restore", "#0ea5e9"); err != nil {
+		t.Fatalf("CreateLabel after restore: %v", err)
 	}
 }
 
@@ -0,0 +1,49 @@
+package store
+
+import "time"
+
+// TenantRole is a user's role inside one tenant.
+type TenantRole string
+
+const (
+	// TenantRoleOwner can change tenant data and manage its members.
+	TenantRoleOwner TenantRole = "owner"
+	// TenantRoleEditor can change tenant data.
+	TenantRoleEditor TenantRole = "editor"
+	// TenantRoleViewer can only read tenant data.
+	TenantRoleViewer TenantRole = "viewer"
+)
+
+// TenantMembership is one tenant a user can select, seen from that user.
+type TenantMembership struct {
+	TenantID   string     `json:"tenant_id"`
+	TenantName string     `json:"tenant_name"`
+	Role       TenantRole `json:"role"`
+	Personal   bool       `json:"personal"`
+	JoinedAt   time.Time  `json:"joined_at"`
+}
+
+// TenantMember is one user with access to a tenant.
+type TenantMember struct {
+	UserID      string     `json:"user_id"`
+	Email       string     `json:"email"`
+	DisplayName string     `json:"display_name"`
+	Role        TenantRole `json:"role"`
+	JoinedAt    time.Time  `json:"joined_at"`
+}
+
+// CreateTenantInput creates a tenant owned by the creating user.
+type CreateTenantInput struct {
+	Name string
+}
+
+// TenantInvitation is an invitation to join a tenant that the invited user
+// has not yet accepted or declined.
+type TenantInvitation struct {
+	TenantID      string     `json:"tenant_id"`
+	TenantName    string     `json:"tenant_name"`
+	Role          TenantRole `json:"role"`
+	InvitedBy     string     `json:"invited_by"`
+	InvitedByName string     `json:"invited_by_name"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// WebhookEventType names an event a webhook can subscribe to.
+type WebhookEventType string
+
+const (
+	// WebhookTransactionCreated fires for each transaction stored by a scan
+	// or entered by hand.
+	WebhookTransactionCreated WebhookEventType = "transaction.created"
+	// WebhookTransactionUpdated fires when a scan re-extracts a stored
+	// transaction or a user edits one.
+	WebhookTransactionUpdated WebhookEventType = "transaction.updated"
+	// WebhookDiagnosticOpened fires when an email matched a rule but could
+	// not be extracted.
+	WebhookDiagnosticOpened WebhookEventType = "diagnostic.opened"
+	// WebhookScanFailed fires when scanning stops on an error: the reader
+	// needs to be authorized again, or a scan failed and is backing off.
+	WebhookScanFailed WebhookEventType = "scan.failed"
+)
+
+// WebhookEventTypes lists every event type a webhook can subscribe to.
+var WebhookEventTypes = []WebhookEventType{
+	WebhookTransactionCreated,
+	WebhookTransactionUpdated,
+	WebhookDiagnosticOpened,
+	WebhookScanFailed,
+}
+
+// ValidWebhookEventType reports whether t is a known event type.
+func ValidWebhookEventType(t WebhookEventType) bool {
+	return slices.Contains(WebhookEventTypes, t)
+}
+
+// Webhook is a tenant's subscription delivering events to a URL.
+type Webhook struct {
+	ID          string
+	TenantID    string
+	URL         string
+	Description string
+	Events      []WebhookEventType
+	Enabled     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// NewWebhook describes a webhook to create.
+type NewWebhook struct {
+	URL         string
+	Description string
+	Events      []WebhookEventType
+	Enabled     bool
+	// Secret is the key deliveries are signed with.
+	Secret string
+}
+
+// WebhookPatch partially updates a webhook. Nil fields are left unchanged.
+type WebhookPatch struct {
+	URL         *string
+	Description *string
+	Events      []WebhookEventType
+	Enabled     *bool
+	Secret      *string
+}
+
+// WebhookDeliveryStatus is where a delivery is in the retry queue.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed is a delivery given up on after its last attempt.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event queued for one webhook.
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	TenantID  string
+	EventType WebhookEventType
+	// Payload is the event data, sent as the data field of the request body.
+	Payload  json.RawMessage
+	Status   WebhookDeliveryStatus
+	Attempts int
+	// NextAttemptAt is when a pending delivery is sent next.
+	NextAttemptAt *time.Time
+	LastAttemptAt *time.Time
+	// ResponseStatus is the HTTP status of the last attempt; zero when it got
+	// no response.
+	ResponseStatus int
+	LastError      string
+	CreatedAt      time.Time
+	CompletedAt    *time.Time
+}
+
+// WebhookDispatch is a delivery claimed for sending, with where and how to
+// send it.
+type WebhookDispatch struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// WebhookAttempt is the result of sending a claimed delivery.
+type WebhookAttempt struct {
+	AttemptedAt    time.Time
+	ResponseStatus int
+	Error          string
+	// Delivered is set when the endpoint accepted the delivery.
+	Delivered bool
+	// RetryAt schedules another attempt of an undelivered delivery; nil
+	// gives up and marks it failed.
+	RetryAt *time.Time
+}
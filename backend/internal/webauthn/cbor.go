@@ -0,0 +1,144 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// maxCBORDepth bounds nesting so hostile input cannot exhaust the stack.
+// Attestation objects nest three levels deep.
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the data item at the front of data and returns the bytes
+// after it. It understands the subset of CBOR (RFC 8949) that authenticators
+// emit: definite-length integers, byte and text strings, arrays and maps, tags
+// and the simple values false, true, null and undefined. Integers decode to
+// int64, byte strings to []byte, text to string, arrays to []any and maps to
+// map[any]any keyed by int64 or string.
+func decodeCBOR(data []byte) (any, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, fmt.Errorf("cbor: nested too deeply")
+	}
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("cbor: unexpected end of data")
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data[1:], nil
+		case 21:
+			return true, data[1:], nil
+		case 22, 23:
+			return nil, data[1:], nil
+		default:
+			return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+		}
+	}
+	arg, rest, err := cborArgument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0, 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("cbor: integer overflows int64")
+		}
+		if major == 1 {
+			return -1 - int64(arg), rest, nil
+		}
+		return int64(arg), rest, nil
+	case 2, 3:
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("cbor: string longer than remaining data")
+		}
+		value := rest[:arg]
+		if major == 3 {
+			return string(value), rest[arg:], nil
+		}
+		return append([]byte(nil), value...), rest[arg:], nil
+	case 4:
+		// Every item takes at least a byte, which bounds the allocation.
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("cbor: array longer than remaining data")
+		}
+		items := make([]any, 0, arg)
+		for range arg {
+			var item any
+			if item, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case 5:
+		if arg > uint64(len(rest))/2 {
+			return nil, nil, fmt.Errorf("cbor: map longer than remaining data")
+		}
+		items := make(map[any]any, arg)
+		for range arg {
+			var key, value any
+			if key, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, dup := items[key]; dup {
+				return nil, nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			if value, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items[key] = value
+		}
+		return items, rest, nil
+	default: // 6: a tag only annotates the item that follows it.
+		return decodeCBORItem(rest, depth+1)
+	}
+}
+
+// cborArgument reads the argument encoded in the initial byte of data and
+// its following bytes. Indefinite lengths are rejected.
+func cborArgument(data []byte) (uint64, []byte, error) {
+	info := data[0] & 0x1f
+	rest := data[1:]
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), rest, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	if len(rest) < size {
+		return 0, nil, fmt.Errorf("cbor: unexpected end of data")
+	}
+	var arg uint64
+	switch size {
+	case 1:
+		arg = uint64(rest[0])
+	case 2:
+		arg = uint64(binary.BigEndian.Uint16(rest))
+	case 4:
+		arg = uint64(binary.BigEndian.Uint32(rest))
+	default:
+		arg = binary.BigEndian.Uint64(rest)
+	}
+	return arg, rest[size:], nil
+}
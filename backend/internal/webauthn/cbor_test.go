@@ -0,0 +1,46 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// {1: 2, 3: -7, "k": h'0102', "t": [true, null]}, then one trailing byte.
+	data := []byte{0xa4, 0x01, 0x02, 0x03, 0x26, 0x61, 'k', 0x42, 0x01, 0x02, 0x61, 't', 0x82, 0xf5, 0xf6, 0xff}
+	value, rest, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatalf("decodeCBOR: %v", err)
+	}
+	m, ok := value.(map[any]any)
+	if !ok || m[int64(1)] != int64(2) || m[int64(3)] != int64(-7) || !bytes.Equal(m["k"].([]byte), []byte{1, 2}) {
+		t.Fatalf("value = %#v", value)
+	}
+	if list, ok := m["t"].([]any); !ok || len(list) != 2 || list[0] != true || list[1] != nil {
+		t.Fatalf("array = %#v", m["t"])
+	}
+	if !bytes.Equal(rest, []byte{0xff}) {
+		t.Fatalf("rest = %x", rest)
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	tests := map[string][]byte{
+		"empty":                 {},
+		"truncated argument":    {0x19, 0x01},
+		"string past end":       {0x45, 0x01},
+		"huge array":            {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite length":     {0x5f, 0x41, 0x00, 0xff},
+		"array map key":         {0xa1, 0x80, 0x00},
+		"duplicate map key":     {0xa2, 0x01, 0x00, 0x01, 0x00},
+		"float":                 {0xfa, 0x00, 0x00, 0x00, 0x00},
+		"too deep":              append(nested, 0x00),
+		"integer overflows int": {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for name, data := range tests {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("%s: decodeCBOR accepted %x", name, data)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers offered when registering a passkey.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// COSE key parameter labels (RFC 9052 and RFC 9053).
+const (
+	coseKty         int64 = 1
+	coseAlg         int64 = 3
+	coseCrv         int64 = -1
+	coseX           int64 = -2
+	coseY           int64 = -3
+	coseRSAModulus  int64 = -1
+	coseRSAExponent int64 = -2
+
+	coseKtyOKP int64 = 1
+	coseKtyEC2 int64 = 2
+	coseKtyRSA int64 = 3
+
+	coseCrvP256    int64 = 1
+	coseCrvEd25519 int64 = 6
+)
+
+// minRSABits rejects authenticator keys too short to trust.
+const minRSABits = 2048
+
+// parseCOSEKey returns the public key and algorithm of a credential public
+// key in COSE_Key form. Only the algorithms offered at registration are
+// accepted.
+func parseCOSEKey(raw any) (crypto.PublicKey, int64, error) {
+	key, ok := raw.(map[any]any)
+	if !ok {
+		return nil, 0, fmt.Errorf("credential public key is not a COSE key")
+	}
+	kty, _ := key[coseKty].(int64)
+	alg, _ := key[coseAlg].(int64)
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := key[coseCrv].(int64)
+		x, _ := key[coseX].([]byte)
+		y, _ := key[coseY].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("ES256 key is not a P-256 point")
+		}
+		point := append(append([]byte{0x04}, x...), y...)
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ES256 key is not on the curve: %w", err)
+		}
+		return pub, alg, nil
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		crv, _ := key[coseCrv].(int64)
+		x, _ := key[coseX].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("EdDSA key is not an Ed25519 key")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := key[coseRSAModulus].([]byte)
+		e, _ := key[coseRSAExponent].([]byte)
+		exponent := new(big.Int).SetBytes(e)
+		if len(e) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, 0, fmt.Errorf("RS256 key has an invalid exponent")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		if pub.N.BitLen() < minRSABits {
+			return nil, 0, fmt.Errorf("RS256 key is shorter than %d bits", minRSABits)
+		}
+		return pub, alg, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported credential key type %d with algorithm %d", kty, alg)
+	}
+}
+
+// verifySignature checks sig over message with a credential key of alg.
+func verifySignature(alg int64, key crypto.PublicKey, message, sig []byte) error {
+	digest := sha256.Sum256(message)
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		if alg == AlgES256 && ecdsa.VerifyASN1(pub, digest[:], sig) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg == AlgEdDSA && ed25519.Verify(pub, message, sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if alg == AlgRS256 && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not verify")
+}
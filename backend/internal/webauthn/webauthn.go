@@ -0,0 +1,410 @@
+// Package webauthn registers passkeys and verifies sign-ins with them, as the
+// relying party of the Web Authentication API (WebAuthn Level 2).
+//
+// Registration asks for no attestation, so credentials are trusted on first
+// use and attestation statements are not checked. Browsers exchange options
+// and responses in the JSON form of PublicKeyCredential.parse*OptionsFromJSON
+// and toJSON, with binary fields encoded as unpadded base64url.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const (
+	challengeBytes = 32
+	// timeoutMillis is how long the browser waits for the authenticator.
+	timeoutMillis = 5 * 60 * 1000
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagAttestedData = 0x40
+)
+
+// RelyingParty describes this server to authenticators.
+type RelyingParty struct {
+	// ID is the domain passkeys are scoped to.
+	ID   string
+	Name string
+	// Origins are the web origins ceremonies may run on.
+	Origins []string
+}
+
+// New returns the relying party for a site served at origins. The first
+// origin's host becomes the passkey domain; empty origins are skipped.
+func New(name string, origins ...string) (*RelyingParty, error) {
+	rp := &RelyingParty{Name: name}
+	for _, raw := range origins {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		parsed, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Hostname() == "" {
+			return nil, errors.E("webauthn.new", errors.InvalidArgument, fmt.Sprintf("%q is not an http(s) origin", raw))
+		}
+		if rp.ID == "" {
+			rp.ID = parsed.Hostname()
+		}
+		origin := parsed.Scheme + "://" + parsed.Host
+		if !slices.Contains(rp.Origins, origin) {
+			rp.Origins = append(rp.Origins, origin)
+		}
+	}
+	if rp.ID == "" {
+		return nil, errors.E("webauthn.new", errors.InvalidArgument, "an origin is required")
+	}
+	return rp, nil
+}
+
+// Base64URL is binary data carried as unpadded base64url in JSON.
+type Base64URL []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, tolerating padding.
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("invalid base64url: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+// User identifies the account a passkey is registered for.
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential is a registered passkey.
+type Credential struct {
+	ID []byte
+	// PublicKey is the credential key in PKIX DER form.
+	PublicKey  []byte
+	Algorithm  int64
+	SignCount  uint32
+	Transports []string
+}
+
+// NewChallenge returns a random ceremony challenge.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeBytes)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, errors.E("webauthn.new_challenge", "generating challenge", err)
+	}
+	return challenge, nil
+}
+
+// CreationOptions are PublicKeyCredentialCreationOptions in JSON form.
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              Base64URL              `json:"challenge" swaggertype:"string"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are PublicKeyCredentialRequestOptions in JSON form.
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge" swaggertype:"string"`
+	Timeout          int                    `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RelyingPartyEntity names the relying party in CreationOptions.
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity names the account in CreationOptions.
+type UserEntity struct {
+	ID          Base64URL `json:"id" swaggertype:"string"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+// CredentialParameter offers one credential algorithm.
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor refers to an existing credential.
+type CredentialDescriptor struct {
+	Type       string    `json:"type"`
+	ID         Base64URL `json:"id" swaggertype:"string"`
+	Transports []string  `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection states what kind of authenticator is wanted.
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// RegistrationResponse is a PublicKeyCredential from navigator.credentials.create.
+type RegistrationResponse struct {
+	ID       string              `json:"id"`
+	RawID    Base64URL           `json:"rawId" swaggertype:"string"`
+	Type     string              `json:"type"`
+	Response AttestationResponse `json:"response"`
+}
+
+// AttestationResponse is an AuthenticatorAttestationResponse.
+type AttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" swaggertype:"string"`
+	AttestationObject Base64URL `json:"attestationObject" swaggertype:"string"`
+	Transports        []string  `json:"transports,omitempty"`
+}
+
+// AssertionResponse is a PublicKeyCredential from navigator.credentials.get.
+type AssertionResponse struct {
+	ID       string                 `json:"id"`
+	RawID    Base64URL              `json:"rawId" swaggertype:"string"`
+	Type     string                 `json:"type"`
+	Response AuthenticatorAssertion `json:"response"`
+}
+
+// AuthenticatorAssertion is an AuthenticatorAssertionResponse.
+type AuthenticatorAssertion struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" swaggertype:"string"`
+	AuthenticatorData Base64URL `json:"authenticatorData" swaggertype:"string"`
+	Signature         Base64URL `json:"signature" swaggertype:"string"`
+	UserHandle        Base64URL `json:"userHandle,omitempty" swaggertype:"string"`
+}
+
+// CreationOptions returns the options for registering a passkey for user.
+// exclude lists the user's existing passkeys so an authenticator is not
+// registered twice.
+func (rp *RelyingParty) CreationOptions(user User, challenge []byte, exclude []Credential) CreationOptions {
+	return CreationOptions{
+		RP:        RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:      UserEntity{ID: user.ID, Name: user.Name, DisplayName: user.DisplayName},
+		Challenge: challenge,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            timeoutMillis,
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options for signing in with one of allow.
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow []Credential) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          timeoutMillis,
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "preferred",
+	}
+}
+
+// VerifyRegistration checks a registration made for challenge and returns
+// the new credential. Failures are InvalidArgument.
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, resp RegistrationResponse) (Credential, error) {
+	const op = "webauthn.verify_registration"
+	fail := func(msg string, args ...any) (Credential, error) {
+		return Credential{}, errors.E(op, errors.InvalidArgument, errors.User("passkey registration could not be verified"),
+			fmt.Sprintf(msg, args...))
+	}
+	if resp.Type != "public-key" {
+		return fail("credential type %q", resp.Type)
+	}
+	if err := rp.checkClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return fail("%v", err)
+	}
+	decoded, rest, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil || len(rest) != 0 {
+		return fail("decoding attestation object: %v", err)
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return fail("attestation object is not a map")
+	}
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return fail("attestation object has no authData")
+	}
+	data, err := rp.parseAuthenticatorData(authData)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if data.flags&flagAttestedData == 0 {
+		return fail("authenticator data has no attested credential")
+	}
+	if !bytes.Equal(resp.RawID, data.credentialID) {
+		return fail("rawId does not match the attested credential")
+	}
+	key, alg, err := parseCOSEKey(data.publicKey)
+	if err != nil {
+		return fail("%v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return fail("encoding credential key: %v", err)
+	}
+	return Credential{
+		ID:         data.credentialID,
+		PublicKey:  der,
+		Algorithm:  alg,
+		SignCount:  data.signCount,
+		Transports: resp.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion checks a sign-in with credential made for challenge and
+// returns the authenticator's new signature counter. Failures are
+// Unauthenticated.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, resp AssertionResponse, credential Credential) (uint32, error) {
+	const op = "webauthn.verify_assertion"
+	fail := func(msg string, args ...any) (uint32, error) {
+		return 0, errors.E(op, errors.Unauthenticated, errors.User("passkey sign-in could not be verified"), fmt.Sprintf(msg, args...))
+	}
+	if resp.Type != "public-key" {
+		return fail("credential type %q", resp.Type)
+	}
+	if !bytes.Equal(resp.RawID, credential.ID) {
+		return fail("assertion is for another credential")
+	}
+	if err := rp.checkClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return fail("%v", err)
+	}
+	data, err := rp.parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return fail("%v", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(credential.PublicKey)
+	if err != nil {
+		return fail("parsing stored credential key: %v", err)
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := verifySignature(credential.Algorithm, key, signed, resp.Response.Signature); err != nil {
+		return fail("%v", err)
+	}
+	// Authenticators that count signatures must move forward; going back
+	// means the key was copied. Zero on both sides means no counter.
+	if (data.signCount != 0 || credential.SignCount != 0) && data.signCount <= credential.SignCount {
+		return fail("signature counter went from %d to %d", credential.SignCount, data.signCount)
+	}
+	return data.signCount, nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp *RelyingParty) checkClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("decoding client data: %w", err)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("client data type %q, want %q", data.Type, ceremony)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || len(challenge) == 0 || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return fmt.Errorf("client data challenge does not match")
+	}
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return fmt.Errorf("origin %q is not allowed", data.Origin)
+	}
+	if data.CrossOrigin {
+		return fmt.Errorf("cross-origin ceremonies are not allowed")
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    any
+}
+
+// parseAuthenticatorData checks the relying party and user presence and
+// extracts the attested credential, when present.
+func (rp *RelyingParty) parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	const headerSize = 32 + 1 + 4
+	if len(raw) < headerSize {
+		return authenticatorData{}, fmt.Errorf("authenticator data is %d bytes", len(raw))
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(raw[:32], rpIDHash[:]) != 1 {
+		return authenticatorData{}, fmt.Errorf("authenticator data is for another relying party")
+	}
+	data := authenticatorData{flags: raw[32], signCount: binary.BigEndian.Uint32(raw[33:37])}
+	if data.flags&flagUserPresent == 0 {
+		return authenticatorData{}, fmt.Errorf("user presence was not confirmed")
+	}
+	if data.flags&flagAttestedData == 0 {
+		return data, nil
+	}
+	rest := raw[headerSize:]
+	// The AAGUID identifies the authenticator model; without attestation it
+	// proves nothing, so it is skipped.
+	if len(rest) < 16+2 {
+		return authenticatorData{}, fmt.Errorf("attested credential data is truncated")
+	}
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+		return authenticatorData{}, fmt.Errorf("credential ID length %d is invalid", idLen)
+	}
+	data.credentialID = append([]byte(nil), rest[:idLen]...)
+	publicKey, _, err := decodeCBOR(rest[idLen:])
+	if err != nil {
+		return authenticatorData{}, fmt.Errorf("decoding credential public key: %w", err)
+	}
+	data.publicKey = publicKey
+	return data, nil
+}
+
+func descriptors(credentials []Credential) []CredentialDescriptor {
+	out := make([]CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		out = append(out, CredentialDescriptor{Type: "public-key", ID: credential.ID, Transports: credential.Transports})
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package webauthn_test
+
+import (
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/webauthn"
+	"github.com/ArionMiles/expensor/backend/internal/webauthn/webauthntest"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+const origin = "https://expensor.example"
+
+func newRelyingParty(t *testing.T) *webauthn.RelyingParty {
+	t.Helper()
+	rp, err := webauthn.New("Expensor", origin, "", "https://expensor.example:8443/api")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return rp
+}
+
+func challenge(t *testing.T) []byte {
+	t.Helper()
+	c, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	return c
+}
+
+// register enrolls authenticator and returns the stored credential.
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) webauthn.Credential {
+	t.Helper()
+	c := challenge(t)
+	options := rp.CreationOptions(webauthn.User{ID: []byte("user-a"), Name: "asha@example.com"}, c, nil)
+	credential, err := rp.VerifyRegistration(c, authenticator.Register(options, origin))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return credential
+}
+
+func TestNew(t *testing.T) {
+	rp := newRelyingParty(t)
+	if rp.ID != "expensor.example" || len(rp.Origins) != 2 || rp.Origins[1] != "https://expensor.example:8443" {
+		t.Fatalf("relying party = %+v", rp)
+	}
+	for _, origins := range [][]string{nil, {""}, {"expensor.example"}, {"ftp://expensor.example"}} {
+		if _, err := webauthn.New("Expensor", origins...); errors.WhatKind(err) != errors.InvalidArgument {
+			t.Fatalf("New(%q) error = %v, want InvalidArgument", origins, err)
+		}
+	}
+}
+
+func TestRegisterAndAssert(t *testing.T) {
+	rp := newRelyingParty(t)
+	authenticator := webauthntest.NewAuthenticator(t)
+	credential := register(t, rp, authenticator)
+	if string(credential.ID) != string(authenticator.CredentialID) || credential.Algorithm != webauthn.AlgES256 || credential.SignCount != 1 {
+		t.Fatalf("credential = %+v", credential)
+	}
+
+	c := challenge(t)
+	signCount, err := rp.VerifyAssertion(c, authenticator.Assert(rp.RequestOptions(c, []webauthn.Credential{credential}), origin), credential)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if signCount != 2 {
+		t.Fatalf("signCount = %d, want 2", signCount)
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		// before changes what the authenticator is asked to sign; after
+		// tampers with its answer.
+		before func(options *webauthn.CreationOptions, origin *string)
+		after  func(resp *webauthn.RegistrationResponse)
+	}{
+		{name: "other origin", before: func(_ *webauthn.CreationOptions, o *string) { *o = "https://evil.example" }},
+		{name: "other relying party", before: func(options *webauthn.CreationOptions, _ *string) { options.RP.ID = "evil.example" }},
+		{name: "other challenge", before: func(options *webauthn.CreationOptions, _ *string) { options.Challenge = []byte("other") }},
+		{name: "mismatched raw id", after: func(resp *webauthn.RegistrationResponse) { resp.RawID = []byte("other") }},
+		{name: "truncated attestation", after: func(resp *webauthn.RegistrationResponse) {
+			resp.Response.AttestationObject = resp.Response.AttestationObject[:40]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newRelyingParty(t)
+			c := challenge(t)
+			options := rp.CreationOptions(webauthn.User{ID: []byte("user-a")}, c, nil)
+			o := origin
+			if tt.before != nil {
+				tt.before(&options, &o)
+			}
+			resp := webauthntest.NewAuthenticator(t).Register(options, o)
+			if tt.after != nil {
+				tt.after(&resp)
+			}
+			if _, err := rp.VerifyRegistration(c, resp); errors.WhatKind(err) != errors.InvalidArgument {
+				t.Fatalf("VerifyRegistration error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	rp := newRelyingParty(t)
+	authenticator := webauthntest.NewAuthenticator(t)
+	credential := register(t, rp, authenticator)
+
+	assert := func(mutate func(*webauthn.AssertionResponse)) error {
+		c := challenge(t)
+		resp := authenticator.Assert(rp.RequestOptions(c, nil), origin)
+		if mutate != nil {
+			mutate(&resp)
+		}
+		_, err := rp.VerifyAssertion(c, resp, credential)
+		return err
+	}
+
+	flipSignature := func(resp *webauthn.AssertionResponse) { resp.Response.Signature[len(resp.Response.Signature)-1] ^= 1 }
+	if err := assert(flipSignature); errors.WhatKind(err) != errors.Unauthenticated {
+		t.Fatalf("bad signature error = %v", err)
+	}
+	if err := assert(func(resp *webauthn.AssertionResponse) { resp.RawID = []byte("other") }); errors.WhatKind(err) != errors.Unauthenticated {
+		t.Fatalf("other credential error = %v", err)
+	}
+
+	// A counter that does not move past the stored one suggests a clone.
+	credential.SignCount = 100
+	if err := assert(nil); errors.WhatKind(err) != errors.Unauthenticated {
+		t.Fatalf("stale counter error = %v", err)
+	}
+	// Authenticators without a counter always report zero.
+	credential.SignCount = 0
+	authenticator.SignCount = 0
+	if err := assert(nil); err != nil {
+		t.Fatalf("counterless assertion: %v", err)
+	}
+
+	c := challenge(t)
+	resp := authenticator.Assert(rp.RequestOptions(challenge(t), nil), origin)
+	if _, err := rp.VerifyAssertion(c, resp, credential); errors.WhatKind(err) != errors.Unauthenticated {
+		t.Fatalf("other challenge error = %v", err)
+	}
+}
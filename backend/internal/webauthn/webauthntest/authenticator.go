@@ -0,0 +1,163 @@
+// Package webauthntest provides a software authenticator that answers
+// WebAuthn ceremonies the way a browser and security key would.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/ArionMiles/expensor/backend/internal/webauthn"
+)
+
+// Authenticator holds one ES256 credential.
+type Authenticator struct {
+	t   testing.TB
+	key *ecdsa.PrivateKey
+	// CredentialID identifies the credential to the relying party.
+	CredentialID []byte
+	// SignCount is the counter sent with the next signature. Setting it to
+	// zero makes the authenticator behave as one without a counter.
+	SignCount uint32
+}
+
+// NewAuthenticator returns an authenticator with a fresh credential.
+func NewAuthenticator(t testing.TB) *Authenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+	return &Authenticator{t: t, key: key, CredentialID: id, SignCount: 1}
+}
+
+// Register answers creation options as if the user approved them on origin.
+func (a *Authenticator) Register(options webauthn.CreationOptions, origin string) webauthn.RegistrationResponse {
+	a.t.Helper()
+	clientData := a.clientData("webauthn.create", options.Challenge, origin)
+
+	point, err := a.key.PublicKey.Bytes()
+	if err != nil {
+		a.t.Fatalf("encode public key: %v", err)
+	}
+	x, y := point[1:33], point[33:]
+	coseKey := EncodeCBOR(map[any]any{int64(1): int64(2), int64(3): webauthn.AlgES256, int64(-1): int64(1), int64(-2): x, int64(-3): y})
+
+	authData := a.authData(options.RP.ID, 0x41)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, coseKey...)
+
+	return webauthn.RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AttestationResponse{
+			ClientDataJSON:    clientData,
+			AttestationObject: EncodeCBOR(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": authData}),
+			Transports:        []string{"usb"},
+		},
+	}
+}
+
+// Assert answers request options as if the user approved them on origin.
+func (a *Authenticator) Assert(options webauthn.RequestOptions, origin string) webauthn.AssertionResponse {
+	a.t.Helper()
+	clientData := a.clientData("webauthn.get", options.Challenge, origin)
+	authData := a.authData(options.RPID, 0x01)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatalf("SignASN1: %v", err)
+	}
+	return webauthn.AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAssertion{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: authData,
+			Signature:         sig,
+		},
+	}
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte, origin string) []byte {
+	data, err := json.Marshal(map[string]any{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	if err != nil {
+		a.t.Fatalf("marshal client data: %v", err)
+	}
+	return data
+}
+
+func (a *Authenticator) authData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	out := append(rpIDHash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, a.SignCount)
+	if a.SignCount != 0 {
+		a.SignCount++
+	}
+	return out
+}
+
+// EncodeCBOR encodes int64, string, []byte and map[any]any values, with map
+// keys in a fixed order.
+func EncodeCBOR(value any) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case map[any]any:
+		keys := make([]any, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(EncodeCBOR(keys[i])) < string(EncodeCBOR(keys[j])) })
+		out := cborHead(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(out, EncodeCBOR(k)...)
+			out = append(out, EncodeCBOR(v[k])...)
+		}
+		return out
+	default:
+		panic(fmt.Sprintf("webauthntest: cannot encode %T", value))
+	}
+}
+
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+	}
+}
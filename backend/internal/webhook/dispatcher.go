@@ -0,0 +1,247 @@
+// Package webhook delivers queued webhook events to tenants' endpoints.
+// Events are queued by the store as the changes they describe are
+// committed; the Dispatcher sends them, signs each request and retries
+// failed deliveries with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/egress"
+	"github.com/ArionMiles/expensor/backend/internal/store"
+	"github.com/ArionMiles/expensor/backend/pkg/errors"
+)
+
+// Request headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Expensor-Event"
+	HeaderDelivery  = "X-Expensor-Delivery"
+	HeaderTimestamp = "X-Expensor-Timestamp"
+	// HeaderSignature carries "sha256=" and the hex HMAC-SHA256, keyed with
+	// the webhook secret, of the timestamp header, a dot and the body.
+	HeaderSignature = "X-Expensor-Signature"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	claimBatch   = 20
+	// claimLease outlasts a request, so a delivery is only claimed again
+	// when the process sending it went away.
+	claimLease     = 2 * time.Minute
+	requestTimeout = 15 * time.Second
+
+	// MaxAttempts is how many times a delivery is sent before it is marked
+	// failed. Retries wait retryBaseDelay, doubling up to retryMaxDelay, so
+	// the last attempt is made about four hours after the first.
+	MaxAttempts    = 10
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 2 * time.Hour
+
+	// deliveryRetention is how long delivered and failed deliveries stay in
+	// the delivery log.
+	deliveryRetention = 30 * 24 * time.Hour
+	pruneInterval     = time.Hour
+)
+
+// Dependencies configures a Dispatcher.
+type Dependencies struct {
+	Store  store.WebhookStore
+	Logger *slog.Logger
+	Now    func() time.Time
+	// Client sends deliveries. It defaults to a client that times out after
+	// requestTimeout, does not follow redirects and refuses internal
+	// addresses outside AllowedNetworks.
+	Client *http.Client
+	// AllowedNetworks are internal networks endpoints may be on.
+	AllowedNetworks []netip.Prefix
+}
+
+// Dispatcher sends due webhook deliveries.
+type Dispatcher struct {
+	store  store.WebhookStore
+	logger *slog.Logger
+	now    func() time.Time
+	client *http.Client
+}
+
+// New constructs a Dispatcher without starting it.
+func New(deps Dependencies) (*Dispatcher, error) {
+	if deps.Store == nil {
+		return nil, errors.E("webhook.new", errors.FailedPrecondition, "webhook store is required")
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	client := deps.Client
+	if client == nil {
+		client = egress.NewClient(requestTimeout, deps.AllowedNetworks)
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+	return &Dispatcher{store: deps.Store, logger: logger.With("component", "webhook"), now: now, client: client}, nil
+}
+
+// Run blocks while sending due deliveries every pollInterval and pruning
+// the delivery log every pruneInterval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Warn("failed to dispatch webhook deliveries", "error", err)
+		}
+		if d.now().Sub(pruned) >= pruneInterval {
+			pruned = d.now()
+			if removed, err := d.store.PruneWebhookDeliveries(ctx, pruned.Add(-deliveryRetention)); err != nil {
+				d.logger.Warn("failed to prune webhook deliveries", "error", err)
+			} else if removed > 0 {
+				d.logger.Info("pruned webhook deliveries", "removed", removed)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends the deliveries that are due, claimed in batches until
+// none are left, and reports how many it sent.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		dispatches, err := d.store.ClaimWebhookDeliveries(ctx, claimBatch, claimLease)
+		if err != nil {
+			return sent, errors.E("webhook.dispatch", err)
+		}
+		var wg sync.WaitGroup
+		for _, dispatch := range dispatches {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.send(ctx, dispatch)
+			}()
+		}
+		wg.Wait()
+		sent += len(dispatches)
+		if len(dispatches) < claimBatch {
+			break
+		}
+	}
+	return sent, nil
+}
+
+// send makes one attempt at a claimed delivery and records its result.
+func (d *Dispatcher) send(ctx context.Context, dispatch store.WebhookDispatch) {
+	delivery := dispatch.Delivery
+	attempt := d.attempt(ctx, dispatch)
+	if !attempt.Delivered && delivery.Attempts < MaxAttempts {
+		retryAt := attempt.AttemptedAt.Add(RetryDelay(delivery.Attempts))
+		attempt.RetryAt = &retryAt
+	}
+	// The result is recorded even when shutdown cancelled the request, so
+	// the delivery is retried on schedule rather than after its lease.
+	if err := d.store.CompleteWebhookDelivery(context.WithoutCancel(ctx), delivery.ID, attempt); err != nil {
+		d.logger.Warn("failed to record webhook delivery", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+	if !attempt.Delivered {
+		d.logger.Info("webhook delivery failed",
+			"delivery_id", delivery.ID,
+			"webhook_id", delivery.WebhookID,
+			"attempt", delivery.Attempts,
+			"retrying", attempt.RetryAt != nil,
+			"error", attempt.Error,
+		)
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, dispatch store.WebhookDispatch) store.WebhookAttempt {
+	delivery := dispatch.Delivery
+	attempt := store.WebhookAttempt{AttemptedAt: d.now()}
+	body, err := json.Marshal(envelope{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		TenantID:  delivery.TenantID,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		attempt.Error = "encoding event: " + err.Error()
+		return attempt
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = "building request: " + err.Error()
+		return attempt
+	}
+	timestamp := strconv.FormatInt(attempt.AttemptedAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(dispatch.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	attempt.ResponseStatus = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("endpoint responded %s", resp.Status)
+		return attempt
+	}
+	attempt.Delivered = true
+	return attempt
+}
+
+// envelope is the request body of a delivery.
+type envelope struct {
+	ID        string                 `json:"id"`
+	Type      store.WebhookEventType `json:"type"`
+	TenantID  string                 `json:"tenant_id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      json.RawMessage        `json:"data"`
+}
+
+// Sign returns the HeaderSignature value for a request body sent at
+// timestamp, in Unix seconds.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay is how long to wait after the given failed attempt, counted
+// from one, before the next.
+func RetryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
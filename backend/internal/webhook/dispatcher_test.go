@@ -0,0 +1,214 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ArionMiles/expensor/backend/internal/store"
+)
+
+type fakeStore struct {
+	store.WebhookStore
+	mu        sync.Mutex
+	due       []store.WebhookDispatch
+	completed map[string]store.WebhookAttempt
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(_ context.Context, limit int, _ time.Duration) ([]store.WebhookDispatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(limit, len(s.due))
+	claimed := s.due[:n]
+	s.due = s.due[n:]
+	return claimed, nil
+}
+
+func (s *fakeStore) CompleteWebhookDelivery(_ context.Context, id string, attempt store.WebhookAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.completed == nil {
+		s.completed = map[string]store.WebhookAttempt{}
+	}
+	s.completed[id] = attempt
+	return nil
+}
+
+var now = time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+// loopback allows the test servers, which listen on it.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func newTestDispatcher(t *testing.T, st *fakeStore) *Dispatcher {
+	t.Helper()
+	d, err := New(Dependencies{Store: st, Now: func() time.Time { return now }, AllowedNetworks: loopback})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return d
+}
+
+func dispatchTo(url, id string, attempts int) store.WebhookDispatch {
+	return store.WebhookDispatch{
+		URL:    url,
+		Secret: "shh",
+		Delivery: store.WebhookDelivery{
+			ID:        id,
+			WebhookID: "hook-a",
+			TenantID:  "tenant-a",
+			EventType: store.WebhookTransactionCreated,
+			Payload:   json.RawMessage(`{"id":"txn-a","amount":249.5}`),
+			Attempts:  attempts,
+			CreatedAt: now.Add(-time.Minute),
+		},
+	}
+}
+
+func TestDispatchSignsAndDelivers(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	st := &fakeStore{due: []store.WebhookDispatch{dispatchTo(server.URL, "delivery-a", 1)}}
+
+	sent, err := newTestDispatcher(t, st).DispatchDue(context.Background())
+
+	if err != nil || sent != 1 {
+		t.Fatalf("DispatchDue() = %d, %v; want 1, nil", sent, err)
+	}
+	got := <-requests
+	if got.header.Get(HeaderEvent) != "transaction.created" || got.header.Get(HeaderDelivery) != "delivery-a" {
+		t.Fatalf("headers = %v", got.header)
+	}
+	timestamp := got.header.Get(HeaderTimestamp)
+	if timestamp != "1774958400" {
+		t.Fatalf("timestamp = %q", timestamp)
+	}
+	if want := Sign("shh", timestamp, got.body); got.header.Get(HeaderSignature) != want {
+		t.Fatalf("signature = %q, want %q", got.header.Get(HeaderSignature), want)
+	}
+	var body struct {
+		ID       string          `json:"id"`
+		Type     string          `json:"type"`
+		TenantID string          `json:"tenant_id"`
+		Data     json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(got.body, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.ID != "delivery-a" || body.Type != "transaction.created" || body.TenantID != "tenant-a" ||
+		string(body.Data) != `{"id":"txn-a","amount":249.5}` {
+		t.Fatalf("body = %s", got.body)
+	}
+	attempt := st.completed["delivery-a"]
+	if !attempt.Delivered || attempt.ResponseStatus != http.StatusNoContent || attempt.RetryAt != nil || attempt.Error != "" {
+		t.Fatalf("attempt = %#v", attempt)
+	}
+}
+
+func TestDispatchRetriesWithBackoffThenGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	st := &fakeStore{due: []store.WebhookDispatch{
+		dispatchTo(server.URL, "third", 3),
+		dispatchTo(server.URL, "last", MaxAttempts),
+	}}
+
+	if _, err := newTestDispatcher(t, st).DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue() error = %v", err)
+	}
+
+	third := st.completed["third"]
+	if third.Delivered || third.ResponseStatus != http.StatusBadGateway || third.Error != "endpoint responded 502 Bad Gateway" {
+		t.Fatalf("third attempt = %#v", third)
+	}
+	if third.RetryAt == nil || !third.RetryAt.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("third attempt retries at %v, want %v", third.RetryAt, now.Add(2*time.Minute))
+	}
+	if last := st.completed["last"]; last.Delivered || last.RetryAt != nil {
+		t.Fatalf("last attempt = %#v, want given up", last)
+	}
+}
+
+func TestDispatchRecordsUnreachableEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	st := &fakeStore{due: []store.WebhookDispatch{dispatchTo(url, "delivery-a", 1)}}
+
+	if _, err := newTestDispatcher(t, st).DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue() error = %v", err)
+	}
+
+	attempt := st.completed["delivery-a"]
+	if attempt.Delivered || attempt.ResponseStatus != 0 || attempt.Error == "" || attempt.RetryAt == nil {
+		t.Fatalf("attempt = %#v", attempt)
+	}
+}
+
+func TestDispatchRefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	defer server.Close()
+	st := &fakeStore{due: []store.WebhookDispatch{dispatchTo(server.URL, "delivery-a", 1)}}
+	d, err := New(Dependencies{Store: st, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := d.DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue() error = %v", err)
+	}
+
+	attempt := st.completed["delivery-a"]
+	if called || attempt.Delivered || !strings.Contains(attempt.Error, "not allowed") {
+		t.Fatalf("attempt = %#v, called = %v; want the loopback endpoint refused", attempt, called)
+	}
+}
+
+func TestDispatchDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer server.Close()
+	st := &fakeStore{due: []store.WebhookDispatch{dispatchTo(server.URL, "delivery-a", 1)}}
+
+	if _, err := newTestDispatcher(t, st).DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue() error = %v", err)
+	}
+
+	if attempt := st.completed["delivery-a"]; attempt.Delivered || attempt.ResponseStatus != http.StatusFound {
+		t.Fatalf("attempt = %#v, want the redirect recorded as a failure", attempt)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		8:  64 * time.Minute,
+		9:  2 * time.Hour,
+		40: 2 * time.Hour,
+	} {
+		if got := RetryDelay(attempt); got != want {
+			t.Errorf("RetryDelay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
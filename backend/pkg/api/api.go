@@ -3,7 +3,9 @@ package api
 
 import (
 	"context"
+	"mime"
 	"net/mail"
+	"path"
 	"regexp"
 	"strings"
 	"time"
@@ -14,6 +16,12 @@ const (
 	FailureAmountZero = "amount_zero"
 	// FailureMerchantEmpty indicates extraction produced no merchant text.
 	FailureMerchantEmpty = "merchant_empty"
+	// FailureSplitsRescaled indicates a transaction's amount changed after
+	// it was split, and its splits were scaled to the new amount.
+	FailureSplitsRescaled = "splits_rescaled"
+	// FailureSplitsCleared indicates a transaction's amount changed after it
+	// was split, and its splits could not be scaled so were removed.
+	FailureSplitsCleared = "splits_cleared"
 )
 
 // TransactionDetails holds extracted transaction information.
@@ -27,6 +35,13 @@ type TransactionDetails struct {
 	Source Source `json:"source"`
 	// MessageID is the email message ID (used for marking as read after successful write).
 	MessageID string `json:"-"`
+	// EmailSubject and EmailBody hold the source email as plain text so the
+	// store can index it for search.
+	EmailSubject string `json:"-"`
+	EmailBody    string `json:"-"`
+	// Attachments holds the PDF attachments of the source email, stored
+	// alongside the transaction at ingestion.
+	Attachments []Attachment `json:"-"`
 
 	// Multi-currency support
 	Currency         string   `json:"currency,omitempty"`          // e.g., "INR", "USD", "EUR"
@@ -39,6 +54,25 @@ type TransactionDetails struct {
 	Labels      []string `json:"labels,omitempty"`      // User-added labels
 }
 
+// Attachment is a file carried by the email a transaction was extracted from.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// IsPDFAttachment reports whether an email part is a PDF document. Mail
+// clients often send PDFs as application/octet-stream, so the filename
+// extension is checked too.
+func IsPDFAttachment(filename, contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.EqualFold(mediaType, "application/pdf") {
+		return true
+	}
+	return strings.EqualFold(path.Ext(filename), ".pdf") &&
+		(mediaType == "" || strings.EqualFold(mediaType, "application/octet-stream"))
+}
+
 // Source describes where a transaction originated.
 type Source struct {
 	Type  string `json:"type"`
@@ -208,5 +208,26 @@ func TestExtractionFailureReasons(t *testing.T) {
 	}
 }
 
+func TestIsPDFAttachment(t *testing.T) {
+	tests := []struct {
+		filename    string
+		contentType string
+		want        bool
+	}{
+		{"invoice.pdf", "application/pdf", true},
+		{"", "application/pdf; name=invoice.pdf", true},
+		{"INVOICE.PDF", "application/octet-stream", true},
+		{"invoice.pdf", "", true},
+		{"invoice.pdf", "text/html", false},
+		{"logo.png", "image/png", false},
+		{"notes.txt", "application/octet-stream", false},
+	}
+	for _, tc := range tests {
+		if got := api.IsPDFAttachment(tc.filename, tc.contentType); got != tc.want {
+			t.Errorf("IsPDFAttachment(%q, %q) = %v, want %v", tc.filename, tc.contentType, got, tc.want)
+		}
+	}
+}
+
 // Compile-time check that Rule has the regex fields we expect.
 var _ = api.Rule{Amount: regexp.MustCompile(""), MerchantInfo: regexp.MustCompile("")}
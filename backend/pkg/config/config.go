@@ -2,16 +2,19 @@
 package config
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/BurntSushi/toml"
 	"github.com/go-playground/validator/v10"
@@ -82,6 +85,11 @@ type App struct {
 	Community     Community     `toml:"community"`
 	Persisted     Persisted     `toml:"persisted"`
 	Security      Security      `toml:"security"`
+	OIDC          OIDC          `toml:"oidc"`
+	ProxyAuth     ProxyAuth     `toml:"proxy_auth"`
+	Blob          Blob          `toml:"blob"`
+	Backup        Backup        `toml:"backup"`
+	Notifications Notifications `toml:"notifications"`
 	Observability Observability `toml:"observability"`
 }
 
@@ -186,6 +194,295 @@ type Security struct {
 	SecretKeyFile string        `toml:"secret_key_file" env:"EXPENSOR_SECRET_KEY_FILE"`
 	SessionTTL    time.Duration `toml:"session_ttl" env:"EXPENSOR_SESSION_TTL" default:"168h" validate:"gt=0"`
 	SetupTokenTTL time.Duration `toml:"setup_token_ttl" env:"EXPENSOR_SETUP_TOKEN_TTL" default:"24h" validate:"gt=0"`
+
+	// PreviousSecretKeys still decrypt values sealed before the secret key
+	// was rotated; new values are always sealed with SecretKey. They come
+	// from EXPENSOR_PREVIOUS_SECRET_KEYS or PreviousSecretKeysFile, as
+	// base64 keys separated by commas or whitespace.
+	PreviousSecretKeys     [][]byte `toml:"-"`
+	PreviousSecretKeysFile string   `toml:"previous_secret_keys_file" env:"EXPENSOR_PREVIOUS_SECRET_KEYS_FILE"`
+
+	// TrustedProxies is a comma-separated list of CIDRs or addresses of
+	// reverse proxies whose X-Forwarded-For header gives the client address
+	// used for sign-in throttling and the session list.
+	// Environment variable: EXPENSOR_TRUSTED_PROXIES
+	TrustedProxies string `toml:"trusted_proxies" env:"EXPENSOR_TRUSTED_PROXIES"`
+
+	// OutboundAllowedNetworks is a comma-separated list of CIDRs or
+	// addresses that webhooks and notification channels may send to although
+	// they are private, loopback or link-local, such as a Home Assistant
+	// server on the LAN. Other internal addresses are refused.
+	// Environment variable: EXPENSOR_OUTBOUND_ALLOWED_NETWORKS
+	OutboundAllowedNetworks string `toml:"outbound_allowed_networks" env:"EXPENSOR_OUTBOUND_ALLOWED_NETWORKS"`
+}
+
+// GetTrustedProxies parses TrustedProxies like ProxyAuth.GetTrustedProxies.
+func (c Security) GetTrustedProxies() ([]netip.Prefix, error) {
+	return parsePrefixes(c.TrustedProxies)
+}
+
+// GetOutboundAllowedNetworks parses OutboundAllowedNetworks like
+// ProxyAuth.GetTrustedProxies.
+func (c Security) GetOutboundAllowedNetworks() ([]netip.Prefix, error) {
+	return parsePrefixes(c.OutboundAllowedNetworks)
+}
+
+// OIDC configures single sign-on through an OpenID Connect provider. Sign-in
+// is enabled when Issuer is set; password login keeps working alongside it.
+type OIDC struct {
+	// Issuer is the provider's issuer URL, used for discovery and to check
+	// ID tokens. For Authentik: https://authentik.example.com/application/o/expensor/
+	// Environment variable: EXPENSOR_OIDC_ISSUER
+	Issuer       string `toml:"issuer" env:"EXPENSOR_OIDC_ISSUER" validate:"omitempty,url"`
+	ClientID     string `toml:"client_id" env:"EXPENSOR_OIDC_CLIENT_ID"`
+	ClientSecret string `toml:"client_secret" env:"EXPENSOR_OIDC_CLIENT_SECRET"`
+
+	// Scopes is a comma-separated list of requested scopes.
+	// Environment variable: EXPENSOR_OIDC_SCOPES
+	// Default: openid,email,profile
+	Scopes string `toml:"scopes" env:"EXPENSOR_OIDC_SCOPES" default:"openid,email,profile"`
+
+	// ProviderName labels the sign-in button.
+	// Environment variable: EXPENSOR_OIDC_PROVIDER_NAME
+	// Default: SSO
+	ProviderName string `toml:"provider_name" env:"EXPENSOR_OIDC_PROVIDER_NAME" default:"SSO"`
+
+	EmailClaim string `toml:"email_claim" env:"EXPENSOR_OIDC_EMAIL_CLAIM" default:"email"`
+	NameClaim  string `toml:"name_claim" env:"EXPENSOR_OIDC_NAME_CLAIM" default:"name"`
+
+	// RoleClaim names a string or string-array claim, such as groups. When
+	// set, users whose claim contains one of AdminValues are admins and
+	// everyone else is a regular user, checked at every sign-in.
+	// Environment variable: EXPENSOR_OIDC_ROLE_CLAIM
+	RoleClaim string `toml:"role_claim" env:"EXPENSOR_OIDC_ROLE_CLAIM"`
+
+	// AdminValues is a comma-separated list of RoleClaim values that grant
+	// the admin role.
+	// Environment variable: EXPENSOR_OIDC_ADMIN_VALUES
+	AdminValues string `toml:"admin_values" env:"EXPENSOR_OIDC_ADMIN_VALUES"`
+
+	// AutoProvision creates an account on first sign-in instead of requiring
+	// an admin to create it.
+	// Environment variable: EXPENSOR_OIDC_AUTO_PROVISION
+	// Default: false
+	AutoProvision bool `toml:"auto_provision" env:"EXPENSOR_OIDC_AUTO_PROVISION" default:"false"`
+}
+
+// Enabled reports whether OIDC sign-in is configured.
+func (c OIDC) Enabled() bool {
+	return strings.TrimSpace(c.Issuer) != ""
+}
+
+// GetScopes returns the scopes as a slice, always including openid.
+func (c OIDC) GetScopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range strings.Split(c.Scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope != "" && scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// GetAdminValues returns AdminValues as a slice.
+func (c OIDC) GetAdminValues() []string {
+	var values []string
+	for _, value := range strings.Split(c.AdminValues, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// ProxyAuth trusts an identity header set by an authenticating reverse proxy
+// such as oauth2-proxy, Authelia or Tailscale serve. It is enabled when Header
+// is set, and the header is only honoured on connections from TrustedProxies.
+type ProxyAuth struct {
+	// Header carries the signed-in user's email, for example
+	// X-Forwarded-Email, Remote-Email or Tailscale-User-Login.
+	// Environment variable: EXPENSOR_PROXY_AUTH_HEADER
+	Header string `toml:"header" env:"EXPENSOR_PROXY_AUTH_HEADER"`
+
+	// NameHeader optionally carries the display name used for new accounts.
+	// Environment variable: EXPENSOR_PROXY_AUTH_NAME_HEADER
+	NameHeader string `toml:"name_header" env:"EXPENSOR_PROXY_AUTH_NAME_HEADER"`
+
+	// TrustedProxies is a comma-separated list of CIDRs or addresses the
+	// proxy connects from.
+	// Environment variable: EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES
+	TrustedProxies string `toml:"trusted_proxies" env:"EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES"`
+
+	// Provision decides what happens to an email without an account: none
+	// rejects the request, user creates a regular user. On a fresh instance
+	// the first account created this way becomes the administrator.
+	// Environment variable: EXPENSOR_PROXY_AUTH_PROVISION
+	// Default: none
+	Provision string `toml:"provision" env:"EXPENSOR_PROXY_AUTH_PROVISION" default:"none" validate:"oneof=none user"`
+
+	// ProvisionDomains is a comma-separated list of email domains accounts may
+	// be created for. Empty allows any domain.
+	// Environment variable: EXPENSOR_PROXY_AUTH_PROVISION_DOMAINS
+	ProvisionDomains string `toml:"provision_domains" env:"EXPENSOR_PROXY_AUTH_PROVISION_DOMAINS"`
+}
+
+// Enabled reports whether proxy header authentication is configured.
+func (c ProxyAuth) Enabled() bool {
+	return strings.TrimSpace(c.Header) != ""
+}
+
+// GetTrustedProxies parses TrustedProxies. A bare address is treated as a
+// single-host prefix.
+func (c ProxyAuth) GetTrustedProxies() ([]netip.Prefix, error) {
+	return parsePrefixes(c.TrustedProxies)
+}
+
+func parsePrefixes(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, raw := range strings.Split(list, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(raw); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address or network %q: %w", raw, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// GetProvisionDomains returns ProvisionDomains as lowercase domains.
+func (c ProxyAuth) GetProvisionDomains() []string {
+	var domains []string
+	for _, domain := range strings.Split(c.ProvisionDomains, ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// Blob selects where binary objects such as transaction attachments are
+// stored. Objects are encrypted with the security secret key before they
+// reach the backend.
+type Blob struct {
+	Backend BlobBackend `toml:"backend" env:"EXPENSOR_BLOB_BACKEND" default:"local" validate:"oneof=local s3"`
+
+	// MaxAttachmentSize caps a single transaction attachment, in bytes.
+	// Environment variable: EXPENSOR_MAX_ATTACHMENT_SIZE
+	// Default: 26214400 (25 MiB)
+	MaxAttachmentSize int64 `toml:"max_attachment_size" env:"EXPENSOR_MAX_ATTACHMENT_SIZE" default:"26214400" validate:"gt=0"`
+
+	Local BlobLocal `toml:"local"`
+	S3    BlobS3    `toml:"s3"`
+}
+
+type BlobBackend string
+
+const (
+	BlobBackendLocal BlobBackend = "local"
+	BlobBackendS3    BlobBackend = "s3"
+)
+
+// BlobLocal stores objects below a directory on the local filesystem.
+type BlobLocal struct {
+	Dir string `toml:"dir" env:"EXPENSOR_BLOB_DIR" default:"data/blobs"`
+}
+
+// BlobS3 stores objects in a bucket of an S3-compatible service such as AWS
+// S3 or MinIO.
+type BlobS3 struct {
+	Endpoint        string `toml:"endpoint" env:"EXPENSOR_S3_ENDPOINT"`
+	Region          string `toml:"region" env:"EXPENSOR_S3_REGION" default:"us-east-1"`
+	Bucket          string `toml:"bucket" env:"EXPENSOR_S3_BUCKET"`
+	AccessKeyID     string `toml:"access_key_id" env:"EXPENSOR_S3_ACCESS_KEY_ID"`
+	SecretAccessKey string `toml:"secret_access_key" env:"EXPENSOR_S3_SECRET_ACCESS_KEY"`
+
+	// PathStyle addresses objects as <endpoint>/<bucket>/<key>, which MinIO
+	// and most self-hosted stores require.
+	// Environment variable: EXPENSOR_S3_PATH_STYLE
+	// Default: true
+	PathStyle bool `toml:"path_style" env:"EXPENSOR_S3_PATH_STYLE" default:"true"`
+}
+
+// Backup selects where database backups are written. The schedule and
+// retention are admin settings stored in the database.
+type Backup struct {
+	Backend BlobBackend `toml:"backend" env:"EXPENSOR_BACKUP_BACKEND" default:"local" validate:"oneof=local s3"`
+
+	// Dir holds backups for the local backend. Keep it on a different volume
+	// from the database.
+	// Environment variable: EXPENSOR_BACKUP_DIR
+	// Default: data/backups
+	Dir string `toml:"dir" env:"EXPENSOR_BACKUP_DIR" default:"data/backups"`
+
+	// S3Bucket is the bucket for the s3 backend. The endpoint and credentials
+	// are shared with the blob S3 settings.
+	// Environment variable: EXPENSOR_BACKUP_S3_BUCKET
+	// Default: the attachment bucket
+	S3Bucket string `toml:"s3_bucket" env:"EXPENSOR_BACKUP_S3_BUCKET"`
+
+	// S3Prefix is prepended to backup object keys.
+	// Environment variable: EXPENSOR_BACKUP_S3_PREFIX
+	// Default: backups
+	S3Prefix string `toml:"s3_prefix" env:"EXPENSOR_BACKUP_S3_PREFIX" default:"backups"`
+
+	// PollInterval controls how often the schedule is checked.
+	// Environment variable: EXPENSOR_BACKUP_POLL_INTERVAL
+	// Default: 5m
+	PollInterval time.Duration `toml:"poll_interval" env:"EXPENSOR_BACKUP_POLL_INTERVAL" default:"5m" validate:"gt=0"`
+
+	// Timeout bounds a single backup or restore.
+	// Environment variable: EXPENSOR_BACKUP_TIMEOUT
+	// Default: 30m
+	Timeout time.Duration `toml:"timeout" env:"EXPENSOR_BACKUP_TIMEOUT" default:"30m" validate:"gt=0"`
+}
+
+// Notifications configures the services notification channels send
+// through. Channels themselves are per-user settings stored in the database.
+type Notifications struct {
+	SMTP SMTP `toml:"smtp"`
+
+	// TelegramAPIURL is the Telegram Bot API server Telegram channels send
+	// through.
+	// Environment variable: EXPENSOR_TELEGRAM_API_URL
+	// Default: https://api.telegram.org
+	TelegramAPIURL string `toml:"telegram_api_url" env:"EXPENSOR_TELEGRAM_API_URL" default:"https://api.telegram.org" validate:"url"`
+}
+
+// SMTP is the mail server email channels send through. Email channels are
+// unavailable while Host is empty.
+type SMTP struct {
+	// Environment variable: EXPENSOR_SMTP_HOST
+	Host string `toml:"host" env:"EXPENSOR_SMTP_HOST"`
+
+	// Environment variable: EXPENSOR_SMTP_PORT
+	// Default: 587
+	Port int `toml:"port" env:"EXPENSOR_SMTP_PORT" default:"587" validate:"gt=0,lte=65535"`
+
+	// Username and Password authenticate with PLAIN auth; no auth is
+	// attempted while Username is empty.
+	// Environment variable: EXPENSOR_SMTP_USERNAME
+	Username string `toml:"username" env:"EXPENSOR_SMTP_USERNAME"`
+	// Environment variable: EXPENSOR_SMTP_PASSWORD
+	Password string `toml:"password" env:"EXPENSOR_SMTP_PASSWORD"`
+
+	// From is the sender address of notification emails.
+	// Environment variable: EXPENSOR_SMTP_FROM
+	From string `toml:"from" env:"EXPENSOR_SMTP_FROM"`
+
+	// Security is starttls to upgrade a plain connection, tls for implicit
+	// TLS (usually port 465), or none.
+	// Environment variable: EXPENSOR_SMTP_SECURITY
+	// Default: starttls
+	Security string `toml:"security" env:"EXPENSOR_SMTP_SECURITY" default:"starttls" validate:"oneof=starttls tls none"`
 }
 
 // Observability controls application logging and telemetry export.
@@ -217,6 +514,9 @@ func Load() (App, error) {
 	if err := loadSecretKey(&cfg.Security); err != nil {
 		return App{}, err
 	}
+	if err := loadPreviousSecretKeys(&cfg.Security); err != nil {
+		return App{}, err
+	}
 	if cfg.BaseURL == "" {
 		cfg.BaseURL = fmt.Sprintf("http://localhost:%d", cfg.Port)
 	}
@@ -339,7 +639,7 @@ func setConfigField(field reflect.Value, raw string) error {
 
 	switch field.Kind() {
 	case reflect.String:
-		if field.Type() == reflect.TypeOf(DatabaseBackend("")) {
+		if field.Type() == reflect.TypeOf(DatabaseBackend("")) || field.Type() == reflect.TypeOf(BlobBackend("")) {
 			raw = strings.ToLower(raw)
 		}
 		field.SetString(raw)
@@ -377,6 +677,48 @@ func validate(cfg *App) error {
 			return errors.E("config.validate", errors.InvalidArgument, "POSTGRES_USER is required when EXPENSOR_DB_BACKEND=postgres")
 		}
 	}
+	if cfg.Blob.Backend == BlobBackendS3 {
+		if strings.TrimSpace(cfg.Blob.S3.Endpoint) == "" {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_S3_ENDPOINT is required when EXPENSOR_BLOB_BACKEND=s3")
+		}
+		if strings.TrimSpace(cfg.Blob.S3.Bucket) == "" {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_S3_BUCKET is required when EXPENSOR_BLOB_BACKEND=s3")
+		}
+	}
+	if cfg.Backup.Backend == BlobBackendS3 {
+		if strings.TrimSpace(cfg.Blob.S3.Endpoint) == "" {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_S3_ENDPOINT is required when EXPENSOR_BACKUP_BACKEND=s3")
+		}
+		if strings.TrimSpace(cfg.Backup.S3Bucket) == "" && strings.TrimSpace(cfg.Blob.S3.Bucket) == "" {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_BACKUP_S3_BUCKET or EXPENSOR_S3_BUCKET is required when EXPENSOR_BACKUP_BACKEND=s3")
+		}
+	}
+	if strings.TrimSpace(cfg.Notifications.SMTP.Host) != "" && strings.TrimSpace(cfg.Notifications.SMTP.From) == "" {
+		return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_SMTP_FROM is required when EXPENSOR_SMTP_HOST is set")
+	}
+	if cfg.OIDC.Enabled() {
+		if strings.TrimSpace(cfg.OIDC.ClientID) == "" {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_OIDC_CLIENT_ID is required when EXPENSOR_OIDC_ISSUER is set")
+		}
+		if strings.TrimSpace(cfg.OIDC.RoleClaim) != "" && len(cfg.OIDC.GetAdminValues()) == 0 {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_OIDC_ADMIN_VALUES is required when EXPENSOR_OIDC_ROLE_CLAIM is set")
+		}
+	}
+	if cfg.ProxyAuth.Enabled() {
+		proxies, err := cfg.ProxyAuth.GetTrustedProxies()
+		if err != nil {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES: "+err.Error())
+		}
+		if len(proxies) == 0 {
+			return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES is required when EXPENSOR_PROXY_AUTH_HEADER is set")
+		}
+	}
+	if _, err := cfg.Security.GetTrustedProxies(); err != nil {
+		return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_TRUSTED_PROXIES: "+err.Error())
+	}
+	if _, err := cfg.Security.GetOutboundAllowedNetworks(); err != nil {
+		return errors.E("config.validate", errors.InvalidArgument, "EXPENSOR_OUTBOUND_ALLOWED_NETWORKS: "+err.Error())
+	}
 	if cfg.Scheduler.BaseRetryDelay > cfg.Scheduler.MaxRetryDelay {
 		return errors.E(errors.InvalidArgument, "EXPENSOR_SCHEDULER_BASE_RETRY_DELAY must not exceed EXPENSOR_SCHEDULER_MAX_RETRY_DELAY")
 	}
@@ -439,13 +781,53 @@ func loadSecretKey(security *Security) error {
 		encoded = string(data)
 	}
 
+	key, err := decodeSecretKey("EXPENSOR_SECRET_KEY", encoded)
+	if err != nil {
+		return err
+	}
+	security.SecretKey = key
+	return nil
+}
+
+func loadPreviousSecretKeys(security *Security) error {
+	encoded, hasRawKeys := os.LookupEnv("EXPENSOR_PREVIOUS_SECRET_KEYS")
+	if strings.TrimSpace(security.PreviousSecretKeysFile) != "" {
+		if hasRawKeys {
+			return errors.E("config.security", errors.InvalidArgument,
+				"set at most one of EXPENSOR_PREVIOUS_SECRET_KEYS or EXPENSOR_PREVIOUS_SECRET_KEYS_FILE")
+		}
+		data, err := os.ReadFile(security.PreviousSecretKeysFile)
+		if err != nil {
+			return errors.E("config.security", errors.InvalidArgument, "reading EXPENSOR_PREVIOUS_SECRET_KEYS_FILE", err)
+		}
+		encoded = string(data)
+	}
+	fields := strings.FieldsFunc(encoded, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	for _, field := range fields {
+		key, err := decodeSecretKey("EXPENSOR_PREVIOUS_SECRET_KEYS", field)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(key, security.SecretKey) {
+			return errors.E("config.security", errors.InvalidArgument, "EXPENSOR_PREVIOUS_SECRET_KEYS must not include EXPENSOR_SECRET_KEY")
+		}
+		for _, previous := range security.PreviousSecretKeys {
+			if bytes.Equal(key, previous) {
+				return errors.E("config.security", errors.InvalidArgument, "EXPENSOR_PREVIOUS_SECRET_KEYS lists a key twice")
+			}
+		}
+		security.PreviousSecretKeys = append(security.PreviousSecretKeys, key)
+	}
+	return nil
+}
+
+func decodeSecretKey(name, encoded string) ([]byte, error) {
 	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
 	if err != nil {
-		return errors.E("config.security", errors.InvalidArgument, "EXPENSOR_SECRET_KEY must be base64-encoded 32-byte key material")
+		return nil, errors.E("config.security", errors.InvalidArgument, name+" must be base64-encoded 32-byte key material")
 	}
 	if len(key) != 32 {
-		return errors.E("config.security", errors.InvalidArgument, fmt.Sprintf("EXPENSOR_SECRET_KEY decoded length is %d bytes, want 32", len(key)))
+		return nil, errors.E("config.security", errors.InvalidArgument, fmt.Sprintf("%s decoded length is %d bytes, want 32", name, len(key)))
 	}
-	security.SecretKey = key
-	return nil
+	return key, nil
 }
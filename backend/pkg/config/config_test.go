@@ -3,6 +3,7 @@ package config_test
 import (
 	"bytes"
 	"encoding/base64"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -76,6 +77,14 @@ func TestLoadAppliesDefaults(t *testing.T) {
 	if cfg.Security.SessionTTL != 168*time.Hour || cfg.Security.SetupTokenTTL != 24*time.Hour {
 		t.Fatalf("security defaults: %#v", cfg.Security)
 	}
+	if cfg.Blob.Backend != config.BlobBackendLocal || cfg.Blob.Local.Dir != "data/blobs" || cfg.Blob.MaxAttachmentSize != 25<<20 ||
+		cfg.Blob.S3.Region != "us-east-1" || !cfg.Blob.S3.PathStyle {
+		t.Fatalf("blob defaults: %#v", cfg.Blob)
+	}
+	if cfg.Backup.Backend != config.BlobBackendLocal || cfg.Backup.Dir != "data/backups" || cfg.Backup.S3Prefix != "backups" ||
+		cfg.Backup.PollInterval != 5*time.Minute || cfg.Backup.Timeout != 30*time.Minute {
+		t.Fatalf("backup defaults: %#v", cfg.Backup)
+	}
 	if cfg.Observability.LogLevel != slog.LevelInfo || cfg.Observability.LogJSON ||
 		cfg.Observability.Enabled || cfg.Observability.Exporter != "none" {
 		t.Fatalf("observability defaults: %#v", cfg.Observability)
@@ -143,6 +152,48 @@ func TestLoadRejectsBothSecretKeyInputs(t *testing.T) {
 	}
 }
 
+func TestLoadPreviousSecretKeys(t *testing.T) {
+	setRequiredConfigEnv(t)
+	first := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	second := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	t.Setenv("EXPENSOR_PREVIOUS_SECRET_KEYS", first+", "+second)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	previous := cfg.Security.PreviousSecretKeys
+	if len(previous) != 2 || !bytes.Equal(previous[0], bytes.Repeat([]byte{1}, 32)) || !bytes.Equal(previous[1], bytes.Repeat([]byte{2}, 32)) {
+		t.Fatalf("PreviousSecretKeys = %v, want both keys in order", previous)
+	}
+
+	keysPath := filepath.Join(t.TempDir(), "expensor_previous_secret_keys")
+	if err := os.WriteFile(keysPath, []byte(second+"\n"+first+"\n"), 0o600); err != nil {
+		t.Fatalf("write keys file: %v", err)
+	}
+	t.Setenv("EXPENSOR_PREVIOUS_SECRET_KEYS_FILE", keysPath)
+	if _, err := config.Load(); err == nil {
+		t.Fatal("Load() succeeded with both EXPENSOR_PREVIOUS_SECRET_KEYS and EXPENSOR_PREVIOUS_SECRET_KEYS_FILE")
+	}
+	os.Unsetenv("EXPENSOR_PREVIOUS_SECRET_KEYS")
+	cfg, err = config.Load()
+	if err != nil {
+		t.Fatalf("Load with keys file: %v", err)
+	}
+	if len(cfg.Security.PreviousSecretKeys) != 2 || cfg.Security.PreviousSecretKeys[0][0] != 2 {
+		t.Fatalf("PreviousSecretKeys from file = %v, want file order", cfg.Security.PreviousSecretKeys)
+	}
+}
+
+func TestLoadRejectsActiveKeyAsPreviousKey(t *testing.T) {
+	setRequiredConfigEnv(t)
+	t.Setenv("EXPENSOR_PREVIOUS_SECRET_KEYS", os.Getenv("EXPENSOR_SECRET_KEY"))
+
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "must not include EXPENSOR_SECRET_KEY") {
+		t.Fatalf("Load() error = %v, want active key rejected", err)
+	}
+}
+
 func TestLoadUsesEnvironmentOverrides(t *testing.T) {
 	setRequiredConfigEnv(t)
 	t.Setenv("PORT", "9090")
@@ -269,6 +320,156 @@ func TestLoadRejectsSchedulerRetryRange(t *testing.T) {
 	}
 }
 
+func TestLoadRequiresS3BucketWhenS3BlobBackendConfigured(t *testing.T) {
+	setRequiredConfigEnv(t)
+	t.Setenv("EXPENSOR_BLOB_BACKEND", "S3")
+	t.Setenv("EXPENSOR_S3_ENDPOINT", "http://localhost:9000")
+
+	_, err := config.Load()
+	if err == nil || !strings.Contains(err.Error(), "EXPENSOR_S3_BUCKET") {
+		t.Fatalf("expected missing EXPENSOR_S3_BUCKET error, got %v", err)
+	}
+}
+
+func TestLoadS3BackupsFallBackToBlobBucket(t *testing.T) {
+	setRequiredConfigEnv(t)
+	t.Setenv("EXPENSOR_BACKUP_BACKEND", "s3")
+	t.Setenv("EXPENSOR_S3_ENDPOINT", "http://localhost:9000")
+
+	_, err := config.Load()
+	if err == nil || !strings.Contains(err.Error(), "EXPENSOR_BACKUP_S3_BUCKET") {
+		t.Fatalf("expected missing backup bucket error, got %v", err)
+	}
+
+	t.Setenv("EXPENSOR_S3_BUCKET", "expensor")
+	if _, err := config.Load(); err != nil {
+		t.Fatalf("Load with blob bucket: %v", err)
+	}
+}
+
+func TestLoadSMTPRequiresFrom(t *testing.T) {
+	setRequiredConfigEnv(t)
+	t.Setenv("EXPENSOR_SMTP_HOST", "smtp.example.com")
+
+	_, err := config.Load()
+	if err == nil || !strings.Contains(err.Error(), "EXPENSOR_SMTP_FROM") {
+		t.Fatalf("expected missing EXPENSOR_SMTP_FROM error, got %v", err)
+	}
+
+	t.Setenv("EXPENSOR_SMTP_FROM", "expensor@example.com")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load with sender: %v", err)
+	}
+	smtp := cfg.Notifications.SMTP
+	if smtp.Port != 587 || smtp.Security != "starttls" {
+		t.Fatalf("SMTP defaults = port %d security %q, want 587 starttls", smtp.Port, smtp.Security)
+	}
+	if cfg.Notifications.TelegramAPIURL != "https://api.telegram.org" {
+		t.Fatalf("TelegramAPIURL = %q", cfg.Notifications.TelegramAPIURL)
+	}
+}
+
+func TestLoadOIDC(t *testing.T) {
+	setRequiredConfigEnv(t)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.OIDC.Enabled() {
+		t.Fatal("OIDC enabled without an issuer")
+	}
+
+	t.Setenv("EXPENSOR_OIDC_ISSUER", "https://auth.example.com/application/o/expensor/")
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "EXPENSOR_OIDC_CLIENT_ID") {
+		t.Fatalf("expected missing client ID error, got %v", err)
+	}
+	t.Setenv("EXPENSOR_OIDC_CLIENT_ID", "expensor")
+	t.Setenv("EXPENSOR_OIDC_ROLE_CLAIM", "groups")
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "EXPENSOR_OIDC_ADMIN_VALUES") {
+		t.Fatalf("expected missing admin values error, got %v", err)
+	}
+	t.Setenv("EXPENSOR_OIDC_ADMIN_VALUES", "expensor-admins, family-admins")
+	t.Setenv("EXPENSOR_OIDC_SCOPES", "email,profile,groups")
+	cfg, err = config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.OIDC.Enabled() || cfg.OIDC.ProviderName != "SSO" || cfg.OIDC.EmailClaim != "email" || cfg.OIDC.AutoProvision {
+		t.Fatalf("OIDC = %+v", cfg.OIDC)
+	}
+	if got := strings.Join(cfg.OIDC.GetScopes(), " "); got != "openid email profile groups" {
+		t.Fatalf("scopes = %q", got)
+	}
+	if got := strings.Join(cfg.OIDC.GetAdminValues(), "|"); got != "expensor-admins|family-admins" {
+		t.Fatalf("admin values = %q", got)
+	}
+}
+
+func TestLoadProxyAuth(t *testing.T) {
+	setRequiredConfigEnv(t)
+	t.Setenv("EXPENSOR_PROXY_AUTH_HEADER", "X-Forwarded-Email")
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES") {
+		t.Fatalf("expected missing trusted proxies error, got %v", err)
+	}
+	t.Setenv("EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES", "10.0.0.0/8, not-an-ip")
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "not-an-ip") {
+		t.Fatalf("expected invalid proxy error, got %v", err)
+	}
+	t.Setenv("EXPENSOR_PROXY_AUTH_PROVISION", "admin")
+	t.Setenv("EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES", "10.1.2.3/8, 172.18.0.5, fd7a:115c:a1e0::/48")
+	if _, err := config.Load(); err == nil {
+		t.Fatal("expected invalid provision policy error")
+	}
+	t.Setenv("EXPENSOR_PROXY_AUTH_PROVISION", "user")
+	t.Setenv("EXPENSOR_PROXY_AUTH_PROVISION_DOMAINS", "Example.com,")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	proxies, err := cfg.ProxyAuth.GetTrustedProxies()
+	if err != nil || fmt.Sprint(proxies) != "[10.0.0.0/8 172.18.0.5/32 fd7a:115c:a1e0::/48]" {
+		t.Fatalf("trusted proxies = %v, err = %v", proxies, err)
+	}
+	if got := cfg.ProxyAuth.GetProvisionDomains(); len(got) != 1 || got[0] != "example.com" {
+		t.Fatalf("provision domains = %v", got)
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	setRequiredConfigEnv(t)
+	t.Setenv("EXPENSOR_TRUSTED_PROXIES", "172.18.0.0/16, proxy")
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "EXPENSOR_TRUSTED_PROXIES") {
+		t.Fatalf("expected invalid trusted proxy error, got %v", err)
+	}
+	t.Setenv("EXPENSOR_TRUSTED_PROXIES", "172.18.0.0/16, 127.0.0.1")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	proxies, err := cfg.Security.GetTrustedProxies()
+	if err != nil || fmt.Sprint(proxies) != "[172.18.0.0/16 127.0.0.1/32]" {
+		t.Fatalf("trusted proxies = %v, err = %v", proxies, err)
+	}
+}
+
+func TestLoadOutboundAllowedNetworks(t *testing.T) {
+	setRequiredConfigEnv(t)
+	t.Setenv("EXPENSOR_OUTBOUND_ALLOWED_NETWORKS", "homeassistant")
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "EXPENSOR_OUTBOUND_ALLOWED_NETWORKS") {
+		t.Fatalf("expected invalid outbound network error, got %v", err)
+	}
+	t.Setenv("EXPENSOR_OUTBOUND_ALLOWED_NETWORKS", "192.168.1.0/24, 10.0.0.7")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	networks, err := cfg.Security.GetOutboundAllowedNetworks()
+	if err != nil || fmt.Sprint(networks) != "[192.168.1.0/24 10.0.0.7/32]" {
+		t.Fatalf("outbound allowed networks = %v, err = %v", networks, err)
+	}
+}
+
 func TestLoadReadsTOMLConfigFile(t *testing.T) {
 	clearConfigEnv(t)
 	dir := t.TempDir()
@@ -483,8 +684,48 @@ func clearConfigEnv(t *testing.T) {
 		"EXPENSOR_SCHEDULER_MAX_RETRY_DELAY",
 		"EXPENSOR_SECRET_KEY",
 		"EXPENSOR_SECRET_KEY_FILE",
+		"EXPENSOR_PREVIOUS_SECRET_KEYS",
+		"EXPENSOR_PREVIOUS_SECRET_KEYS_FILE",
 		"EXPENSOR_SESSION_TTL",
 		"EXPENSOR_SETUP_TOKEN_TTL",
+		"EXPENSOR_BLOB_BACKEND",
+		"EXPENSOR_MAX_ATTACHMENT_SIZE",
+		"EXPENSOR_BLOB_DIR",
+		"EXPENSOR_S3_ENDPOINT",
+		"EXPENSOR_S3_REGION",
+		"EXPENSOR_S3_BUCKET",
+		"EXPENSOR_S3_ACCESS_KEY_ID",
+		"EXPENSOR_S3_SECRET_ACCESS_KEY",
+		"EXPENSOR_S3_PATH_STYLE",
+		"EXPENSOR_BACKUP_BACKEND",
+		"EXPENSOR_BACKUP_DIR",
+		"EXPENSOR_BACKUP_S3_BUCKET",
+		"EXPENSOR_BACKUP_S3_PREFIX",
+		"EXPENSOR_BACKUP_POLL_INTERVAL",
+		"EXPENSOR_BACKUP_TIMEOUT",
+		"EXPENSOR_SMTP_HOST",
+		"EXPENSOR_SMTP_PORT",
+		"EXPENSOR_SMTP_USERNAME",
+		"EXPENSOR_SMTP_PASSWORD",
+		"EXPENSOR_SMTP_FROM",
+		"EXPENSOR_SMTP_SECURITY",
+		"EXPENSOR_TELEGRAM_API_URL",
+		"EXPENSOR_PROXY_AUTH_HEADER",
+		"EXPENSOR_PROXY_AUTH_NAME_HEADER",
+		"EXPENSOR_PROXY_AUTH_TRUSTED_PROXIES",
+		"EXPENSOR_PROXY_AUTH_PROVISION",
+		"EXPENSOR_PROXY_AUTH_PROVISION_DOMAINS",
+		"EXPENSOR_TRUSTED_PROXIES",
+		"EXPENSOR_OIDC_ISSUER",
+		"EXPENSOR_OIDC_CLIENT_ID",
+		"EXPENSOR_OIDC_CLIENT_SECRET",
+		"EXPENSOR_OIDC_SCOPES",
+		"EXPENSOR_OIDC_PROVIDER_NAME",
+		"EXPENSOR_OIDC_EMAIL_CLAIM",
+		"EXPENSOR_OIDC_NAME_CLAIM",
+		"EXPENSOR_OIDC_ROLE_CLAIM",
+		"EXPENSOR_OIDC_ADMIN_VALUES",
+		"EXPENSOR_OIDC_AUTO_PROVISION",
 		"LOG_LEVEL",
 		"LOG_JSON",
 		"EXPENSOR_OBSERVABILITY_ENABLED",
@@ -550,6 +550,9 @@ func (r *Reader) processMessage(ctx context.Context, msgID string, rule api.Rule
 	}
 	transaction.Source = rule.Source
 	transaction.MessageID = msgID // Store message ID for later acknowledgment
+	transaction.EmailSubject = subject
+	transaction.EmailBody = extractor.PlainText(body)
+	transaction.Attachments = r.pdfAttachments(ctx, msg)
 	r.recordExtractionDiagnostic(ctx, gmailExtractionDiagnostic(gmailDiagnosticContext{
 		message:      msg,
 		messageID:    msgID,
@@ -770,3 +773,43 @@ func extractBody(msg *gmail.Message) string {
 
 	return ""
 }
+
+// pdfAttachments collects the PDF attachments of msg. Gmail inlines only small
+// attachment bodies, so larger ones are fetched by attachment ID. Attachments
+// that fail to download are logged and skipped.
+func (r *Reader) pdfAttachments(ctx context.Context, msg *gmail.Message) []api.Attachment {
+	if msg.Payload == nil {
+		return nil
+	}
+	var attachments []api.Attachment
+	var walk func(part *gmail.MessagePart)
+	walk = func(part *gmail.MessagePart) {
+		for _, child := range part.Parts {
+			walk(child)
+		}
+		if part.Filename == "" || part.Body == nil || !api.IsPDFAttachment(part.Filename, part.MimeType) {
+			return
+		}
+		data := part.Body.Data
+		if data == "" && part.Body.AttachmentId != "" {
+			var body *gmail.MessagePartBody
+			err := doWithAuthRetry(func() error {
+				var callErr error
+				body, callErr = r.client.Users.Messages.Attachments.Get("me", msg.Id, part.Body.AttachmentId).Context(ctx).Do()
+				return callErr
+			})
+			if err != nil {
+				r.logger.Warn("failed to download attachment", "message_id", msg.Id, "filename", part.Filename, "error", err)
+				return
+			}
+			data = body.Data
+		}
+		decoded, err := base64.URLEncoding.DecodeString(data)
+		if err != nil || len(decoded) == 0 {
+			return
+		}
+		attachments = append(attachments, api.Attachment{Filename: part.Filename, ContentType: "application/pdf", Data: decoded})
+	}
+	walk(msg.Payload)
+	return attachments
+}
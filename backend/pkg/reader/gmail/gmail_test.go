@@ -442,6 +442,68 @@ func TestProcessMessage_RecordsExtractionDiagnostic(t *testing.T) {
 	}
 }
 
+func TestProcessMessage_CollectsPDFAttachments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var err error
+		switch r.URL.Path {
+		case "/gmail/v1/users/me/messages/msg-invoice":
+			_, err = fmt.Fprintf(w, `{
+				"id": "msg-invoice",
+				"internalDate": "1777298400000",
+				"payload": {
+					"headers": [{"name": "Subject", "value": "Your invoice"}],
+					"parts": [
+						{"mimeType": "text/html", "body": {"data": %q}},
+						{"mimeType": "application/pdf", "filename": "inline.pdf", "body": {"data": %q}},
+						{"mimeType": "multipart/mixed", "parts": [
+							{"mimeType": "application/octet-stream", "filename": "fetched.pdf", "body": {"attachmentId": "att-1"}}
+						]},
+						{"mimeType": "image/png", "filename": "logo.png", "body": {"attachmentId": "att-2"}}
+					]
+				}
+			}`, b64("Paid Rs.10 at Shop on card"), b64("%PDF-inline"))
+		case "/gmail/v1/users/me/messages/msg-invoice/attachments/att-1":
+			_, err = fmt.Fprintf(w, `{"data": %q}`, b64("%PDF-fetched"))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+		if err != nil {
+			t.Fatalf("write response: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	svc, err := gmail.NewService(
+		context.Background(),
+		option.WithHTTPClient(server.Client()),
+		option.WithEndpoint(server.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	reader := &Reader{client: svc, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	out := make(chan *api.TransactionDetails, 1)
+
+	if err := reader.processMessage(context.Background(), "msg-invoice", api.Rule{
+		Amount:       regexp.MustCompile(`Rs\.([\d.]+)`),
+		MerchantInfo: regexp.MustCompile(`at (.*?) on`),
+	}, out); err != nil {
+		t.Fatalf("processMessage returned error: %v", err)
+	}
+
+	tx := <-out
+	var got []string
+	for _, attachment := range tx.Attachments {
+		got = append(got, attachment.Filename+"="+string(attachment.Data))
+	}
+	want := []string{"inline.pdf=%PDF-inline", "fetched.pdf=%PDF-fetched"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("attachments = %v, want %v", got, want)
+	}
+}
+
 func TestProcessMessage_BlockingDiagnosticSinkDoesNotDelayEmission(t *testing.T) {
 	bodyText := "Paid at  on card"
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
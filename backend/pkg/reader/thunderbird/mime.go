@@ -14,6 +14,7 @@ import (
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/encoding/unicode"
 
+	"github.com/ArionMiles/expensor/backend/pkg/api"
 	"github.com/ArionMiles/expensor/backend/pkg/errors"
 )
 
@@ -176,6 +177,74 @@ func extractPartBody(part *multipart.Part, mediaType string, params map[string]s
 	return string(data), nil
 }
 
+// ExtractPDFAttachments returns the PDF attachments of a message whose
+// top-level Content-Type is contentType. Parts that cannot be decoded are
+// skipped.
+func ExtractPDFAttachments(contentType string, body io.Reader) ([]api.Attachment, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, nil
+	}
+	boundary, ok := params["boundary"]
+	if !ok {
+		return nil, errors.E(errors.InvalidInput, "multipart message missing boundary")
+	}
+	var attachments []api.Attachment
+	if err := collectPDFAttachments(multipart.NewReader(body, boundary), &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func collectPDFAttachments(mr *multipart.Reader, attachments *[]api.Attachment) error {
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.E("thunderbird.mime.collect_pdf_attachments", "reading part", err)
+		}
+		partContentType := part.Header.Get("Content-Type")
+		partMediaType, partParams, _ := mime.ParseMediaType(partContentType)
+		if strings.HasPrefix(partMediaType, "multipart/") {
+			if boundary, ok := partParams["boundary"]; ok {
+				if err := collectPDFAttachments(multipart.NewReader(part, boundary), attachments); err != nil {
+					_ = part.Close()
+					return err
+				}
+			}
+			_ = part.Close()
+			continue
+		}
+
+		filename := decodeRFC2047(part.FileName())
+		if filename == "" {
+			filename = decodeRFC2047(partParams["name"])
+		}
+		if !api.IsPDFAttachment(filename, partContentType) {
+			_ = part.Close()
+			continue
+		}
+		var reader io.Reader = part
+		switch strings.ToLower(part.Header.Get("Content-Transfer-Encoding")) {
+		case "base64":
+			reader = base64.NewDecoder(base64.StdEncoding, reader)
+		case "quoted-printable":
+			reader = quotedprintable.NewReader(reader)
+		}
+		data, err := io.ReadAll(reader)
+		_ = part.Close()
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		if filename == "" {
+			filename = "attachment.pdf"
+		}
+		*attachments = append(*attachments, api.Attachment{Filename: filename, ContentType: "application/pdf", Data: data})
+	}
+}
+
 // decodeCharset decodes data from the specified charset to UTF-8.
 func decodeCharset(data []byte, charset string) (string, error) {
 	charset = strings.ToLower(charset)
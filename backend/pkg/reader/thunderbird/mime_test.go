@@ -403,3 +403,66 @@ func TestExtractBody_InvalidMultipart(t *testing.T) {
 		t.Error("expected error for multipart without boundary")
 	}
 }
+
+func TestExtractPDFAttachments(t *testing.T) {
+	message := "From: test@example.com\r\n" +
+		"Subject: Invoice\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+		"\r\n" +
+		"--outer\r\n" +
+		"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+		"\r\n" +
+		"--inner\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Your invoice is attached\r\n" +
+		"--inner--\r\n" +
+		"--outer\r\n" +
+		"Content-Type: application/pdf; name=\"invoice.pdf\"\r\n" +
+		"Content-Disposition: attachment; filename=\"invoice.pdf\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"JVBERi0xLjQK\r\n" +
+		"--outer\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=\"=?utf-8?Q?r=C3=A9sum=C3=A9.pdf?=\"\r\n" +
+		"\r\n" +
+		"%PDF-raw\r\n" +
+		"--outer\r\n" +
+		"Content-Type: image/png; name=\"logo.png\"\r\n" +
+		"\r\n" +
+		"PNG\r\n" +
+		"--outer--\r\n"
+
+	msg, err := mail.ReadMessage(strings.NewReader(message))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+
+	attachments, err := ExtractPDFAttachments(msg.Header.Get("Content-Type"), msg.Body)
+	if err != nil {
+		t.Fatalf("ExtractPDFAttachments failed: %v", err)
+	}
+	if len(attachments) != 2 {
+		t.Fatalf("expected 2 PDF attachments, got %d", len(attachments))
+	}
+	if attachments[0].Filename != "invoice.pdf" || string(attachments[0].Data) != "%PDF-1.4\n" {
+		t.Errorf("first attachment = %q %q", attachments[0].Filename, attachments[0].Data)
+	}
+	if attachments[1].Filename != "résumé.pdf" || string(attachments[1].Data) != "%PDF-raw" {
+		t.Errorf("second attachment = %q %q", attachments[1].Filename, attachments[1].Data)
+	}
+	if attachments[0].ContentType != "application/pdf" || attachments[1].ContentType != "application/pdf" {
+		t.Errorf("content types = %q, %q", attachments[0].ContentType, attachments[1].ContentType)
+	}
+}
+
+func TestExtractPDFAttachments_SinglePart(t *testing.T) {
+	attachments, err := ExtractPDFAttachments("text/plain", strings.NewReader("Hello"))
+	if err != nil {
+		t.Fatalf("ExtractPDFAttachments failed: %v", err)
+	}
+	if len(attachments) != 0 {
+		t.Errorf("expected no attachments, got %d", len(attachments))
+	}
+}
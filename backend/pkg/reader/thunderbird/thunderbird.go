@@ -2,6 +2,7 @@
 package thunderbird
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -497,11 +498,20 @@ func (r *Reader) matchesRule(msg *mail.Message) (api.Rule, bool) {
 
 // extractTransaction extracts transaction details from a message.
 func (r *Reader) extractTransaction(ctx context.Context, msg *mail.Message, rule api.Rule, msgKey string) (*api.TransactionDetails, error) {
-	// Extract body
+	// The body is parsed twice: once for the text, once for PDF attachments.
+	raw, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return nil, errors.E("thunderbird.extract_transaction", "reading message", err)
+	}
+	msg.Body = bytes.NewReader(raw)
 	body, err := ExtractBody(msg)
 	if err != nil {
 		return nil, errors.E("thunderbird.extract_transaction", "extracting body", err)
 	}
+	attachments, err := ExtractPDFAttachments(msg.Header.Get("Content-Type"), bytes.NewReader(raw))
+	if err != nil {
+		r.logger.Warn("failed to extract attachments", "error", err, "message_key", msgKey)
+	}
 
 	// Parse date
 	dateStr := msg.Header.Get("Date")
@@ -517,6 +527,9 @@ func (r *Reader) extractTransaction(ctx context.Context, msg *mail.Message, rule
 		transaction.Category, transaction.Bucket = r.resolver(transaction.MerchantInfo)
 	}
 	transaction.Source = rule.Source
+	transaction.EmailSubject = decodeRFC2047(msg.Header.Get("Subject"))
+	transaction.EmailBody = extractor.PlainText(body)
+	transaction.Attachments = attachments
 	r.recordExtractionDiagnostic(ctx, thunderbirdExtractionDiagnostic(thunderbirdDiagnosticContext{
 		message:      msg,
 		messageID:    msgKey,